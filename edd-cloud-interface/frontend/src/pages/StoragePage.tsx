@@ -13,6 +13,7 @@ import { NamespaceCard, FileList, FileUploader } from "@/components/storage";
 import { useNamespaces, useFiles } from "@/hooks";
 import { useAuth } from "@/contexts/AuthContext";
 import { buildStorageBase, buildNotificationsBase, getAuthHeaders } from "@/lib/api";
+import { formatBytes } from "@/lib/formatters";
 import { Plus, Settings, EyeOff, Link, Trash2, BellOff, Bell } from "lucide-react";
 import type { NamespaceVisibility, FileEntry, NotificationMute } from "@/types";
 
@@ -72,7 +73,14 @@ export function StoragePage() {
   const [namespaceError, setNamespaceError] = useState("");
   const [showOverwriteConfirm, setShowOverwriteConfirm] = useState(false);
   const [overwriteFileName, setOverwriteFileName] = useState("");
-  const [storageStatus, setStorageStatus] = useState<{ chunkserver_count: number } | null>(null);
+  const [storageStatus, setStorageStatus] = useState<{
+    chunkserver_count: number;
+    capacity_bytes?: number;
+    used_bytes?: number;
+    compression_ratio?: number;
+    read_bytes_per_sec?: number;
+    write_bytes_per_sec?: number;
+  } | null>(null);
   const [nsMuted, setNsMuted] = useState(false);
   const [muteLoading, setMuteLoading] = useState(false);
 
@@ -269,6 +277,15 @@ export function StoragePage() {
             {storageStatus && (
               <div className="flex gap-6 font-mono text-[10.5px] uppercase tracking-[0.12em] text-faint border-t border-border mt-4 pt-3">
                 <span>Chunkservers <span className="text-muted-foreground">{storageStatus.chunkserver_count}</span></span>
+                {!!storageStatus.capacity_bytes && (
+                  <span>Used <span className="text-muted-foreground">{formatBytes(storageStatus.used_bytes ?? 0)} / {formatBytes(storageStatus.capacity_bytes)}</span></span>
+                )}
+                {!!storageStatus.compression_ratio && storageStatus.compression_ratio > 1.01 && (
+                  <span>Compression <span className="text-muted-foreground">{storageStatus.compression_ratio.toFixed(1)}x</span></span>
+                )}
+                {storageStatus.read_bytes_per_sec !== undefined && (
+                  <span>I/O <span className="text-muted-foreground">{formatBytes(storageStatus.read_bytes_per_sec)}/s read · {formatBytes(storageStatus.write_bytes_per_sec ?? 0)}/s write</span></span>
+                )}
                 <span>Online</span>
               </div>
             )}
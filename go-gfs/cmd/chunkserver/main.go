@@ -5,13 +5,20 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"eddisonso.com/go-gfs/internal/chunkserver"
 	"eddisonso.com/go-gfs/internal/chunkserver/chunkstagingtrackingservice"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkstore"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
 	"eddisonso.com/go-gfs/internal/chunkserver/masterclient"
+	"eddisonso.com/go-gfs/internal/chunkserver/replicationclient"
+	"eddisonso.com/go-gfs/internal/chunkserver/scrubber"
+	"eddisonso.com/go-gfs/internal/chunkserver/secrets"
+	"eddisonso.com/go-gfs/internal/datatoken"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"eddisonso.com/go-gfs/pkg/gfslog"
 )
 
@@ -19,11 +26,19 @@ func main() {
 	dataPort := flag.Int("p", 8080, "Port for the chunk server to listen on")
 	replicationPort := flag.Int("r", 8081, "Port for the chunk server replication service")
 	hostname := flag.String("h", "localhost", "Hostname for the chunk server")
-	dir := flag.String("d", "tmp/", "Directory for chunk storage")
+	dir := flag.String("d", "tmp/", "Directory for chunk storage, or comma-separated directories, one per disk")
 	id := flag.String("id", "chunkserver-1", "Chunk server ID")
-	masterAddr := flag.String("master", "", "Master server address (e.g., localhost:9000). If empty, runs standalone.")
+	masterAddr := flag.String("master", "", "Master server address (e.g., localhost:9000), or comma-separated replica addresses. If empty, runs standalone.")
 	heartbeatInterval := flag.Duration("heartbeat", 10*time.Second, "Heartbeat interval to master")
+	fullReportInterval := flag.Duration("full-report-interval", masterclient.DefaultFullReportInterval, "Interval between heartbeats that list every chunk; others report only changes")
 	logServiceAddr := flag.String("log-service", "", "Log service address (e.g., log-service:50051)")
+	scrubInterval := flag.Duration("scrub-interval", 24*time.Hour, "Interval between checksum scrub passes (0 disables scrubbing)")
+	scrubRate := flag.Int64("scrub-rate", 4<<20, "Maximum scrubber read rate in bytes per second")
+	failureDomain := flag.String("failure-domain", "", "Failure domain label for replica placement (e.g., rack or node). Defaults to the server ID.")
+	tlsCert := flag.String("tls-cert", "", "Certificate file for mutual TLS with the master, other chunkservers and clients")
+	tlsKey := flag.String("tls-key", "", "Private key file for -tls-cert")
+	tlsCA := flag.String("tls-ca", "", "CA bundle that peer certificates must chain to")
+	tokenKey := flag.String("token-public-key", "", "Master's Ed25519 public key (PEM); when set, data-plane requests also need a master-issued grant")
 
 	flag.Parse()
 
@@ -40,12 +55,45 @@ func main() {
 
 	slog.Info("starting chunkserver")
 
+	var dirs []string
+	for _, d := range strings.Split(*dir, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	store, err := chunkstore.New(dirs)
+	if err != nil {
+		slog.Error("failed to open data directories", "dirs", *dir, "error", err)
+		os.Exit(1)
+	}
+
+	tlsSource, err := mtls.Load(*tlsCert, *tlsKey, *tlsCA)
+	if err != nil {
+		slog.Error("failed to load TLS credentials", "error", err)
+		os.Exit(1)
+	}
+	replicationclient.SetTLS(tlsSource)
+
+	if !secrets.HasSecret() {
+		slog.Error("GFS_JWT_SECRET is required to authenticate data-plane requests")
+		os.Exit(1)
+	}
+	if *tokenKey != "" {
+		verifier, err := datatoken.LoadVerifier(*tokenKey)
+		if err != nil {
+			slog.Error("failed to load token verification key", "error", err)
+			os.Exit(1)
+		}
+		secrets.SetGrantVerifier(verifier)
+	}
+
 	config := csstructs.ChunkServerConfig{
 		Hostname:        *hostname,
 		DataPort:        *dataPort,
 		ReplicationPort: *replicationPort,
 		Id:              *id,
-		Dir:             *dir,
+		Store:           store,
+		TLS:             tlsSource,
 	}
 
 	// Create and start chunkserver
@@ -60,9 +108,12 @@ func main() {
 			*hostname,
 			*dataPort,
 			*replicationPort,
-			*dir,
+			store,
 			*masterAddr,
+			*failureDomain,
+			tlsSource,
 		)
+		mc.SetFullReportInterval(*fullReportInterval)
 
 		if err := mc.Connect(); err != nil {
 			slog.Error("failed to connect to master", "addr", *masterAddr, "error", err)
@@ -82,6 +133,13 @@ func main() {
 		slog.Warn("running without master (standalone mode)")
 	}
 
+	// Re-verify idle chunks in the background
+	var scrub *scrubber.Scrubber
+	if *scrubInterval > 0 {
+		scrub = scrubber.NewScrubber(store, *scrubInterval, *scrubRate)
+		scrub.Start()
+	}
+
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -92,6 +150,10 @@ func main() {
 	// Abort all staged chunks and clean up staging data
 	chunkstagingtrackingservice.GetChunkStagingTrackingService().AbortAll()
 
+	if scrub != nil {
+		scrub.Stop()
+	}
+
 	if mc != nil {
 		mc.Close()
 	}
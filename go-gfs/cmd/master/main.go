@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log/slog"
 	"net"
 	"os"
@@ -12,7 +13,10 @@ import (
 	"time"
 
 	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/datatoken"
 	"eddisonso.com/go-gfs/internal/master"
+	"eddisonso.com/go-gfs/internal/master/consensus"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"eddisonso.com/go-gfs/pkg/gfslog"
 	"google.golang.org/grpc"
 )
@@ -24,6 +28,19 @@ var (
 	logSource        string
 	snapshotInterval time.Duration
 	snapshotMaxWAL   int
+	replicationCheck time.Duration
+	coldSealAge      time.Duration
+	trashRetention   time.Duration
+	trashInterval    time.Duration
+	watchHistory     int
+	raftID           uint64
+	raftPeers        string
+	raftAdvertise    string
+	tlsCert          string
+	tlsKey           string
+	tlsCA            string
+	tokenKey         string
+	tokenTTL         time.Duration
 )
 
 func init() {
@@ -33,6 +50,32 @@ func init() {
 	flag.StringVar(&logSource, "log-source", "edd-storage", "Log source name")
 	flag.DurationVar(&snapshotInterval, "snapshot-interval", 5*time.Minute, "Interval between snapshot checks")
 	flag.IntVar(&snapshotMaxWAL, "snapshot-max-wal", 1000, "Max WAL entries before forcing snapshot")
+	flag.DurationVar(&replicationCheck, "replication-interval", 30*time.Second, "Interval between under-replication scans")
+	flag.DurationVar(&coldSealAge, "cold-seal-age", master.DefaultColdSealAge, "How long the last chunk of a cold file must go without writes before it is erasure coded")
+	flag.DurationVar(&trashRetention, "trash-retention", master.DefaultTrashRetention, "How long deleted files stay in the trash before their chunks are collected (0 deletes immediately)")
+	flag.DurationVar(&trashInterval, "trash-interval", time.Minute, "Interval between purges of expired trash")
+	flag.IntVar(&watchHistory, "watch-history", master.DefaultWatchHistory, "Namespace events kept in memory for watchers resuming after a disconnect; older ones are read from the WAL")
+	flag.StringVar(&raftPeers, "raft-peers", "", "Master replicas as id=host:port,... (empty runs a standalone master)")
+	flag.Uint64Var(&raftID, "raft-id", 0, "This replica's ID in -raft-peers")
+	flag.StringVar(&raftAdvertise, "raft-advertise", "", "This replica's address in -raft-peers, used to find its ID when -raft-id is unset")
+	flag.StringVar(&tlsCert, "tls-cert", "", "Certificate file for mutual TLS with chunkservers, clients and other masters")
+	flag.StringVar(&tlsKey, "tls-key", "", "Private key file for -tls-cert")
+	flag.StringVar(&tlsCA, "tls-ca", "", "CA bundle that peer certificates must chain to")
+	flag.StringVar(&tokenKey, "token-signing-key", "", "Ed25519 private key (PEM) for signing data-plane grants; every master replica needs the same key")
+	flag.DurationVar(&tokenTTL, "token-ttl", datatoken.DefaultTTL, "How long a data-plane grant stays valid")
+}
+
+// replicaID resolves this master's ID from -raft-id or -raft-advertise
+func replicaID(peers map[uint64]string) (uint64, error) {
+	if raftID != 0 {
+		return raftID, nil
+	}
+	for id, addr := range peers {
+		if addr == raftAdvertise {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("no peer with address %q; set -raft-id or -raft-advertise", raftAdvertise)
 }
 
 func main() {
@@ -59,16 +102,80 @@ func main() {
 		os.Exit(1)
 	}
 	defer m.Close()
+	m.SetColdSealAge(coldSealAge)
+	m.SetTrashRetention(trashRetention)
+	m.SetWatchHistory(watchHistory)
+
+	tlsSource, err := mtls.Load(tlsCert, tlsKey, tlsCA)
+	if err != nil {
+		slog.Error("failed to load TLS credentials", "error", err)
+		os.Exit(1)
+	}
+	m.SetTLS(tlsSource)
+
+	if tokenKey != "" {
+		issuer, err := datatoken.LoadIssuer(tokenKey, tokenTTL)
+		if err != nil {
+			slog.Error("failed to load token signing key", "error", err)
+			os.Exit(1)
+		}
+		m.SetGrantIssuer(issuer)
+	}
+
+	// Join the standby masters when replicated
+	var peers map[uint64]string
+	if raftPeers != "" {
+		peers, err = consensus.ParsePeers(raftPeers)
+		if err != nil {
+			slog.Error("invalid -raft-peers", "error", err)
+			os.Exit(1)
+		}
+		id, err := replicaID(peers)
+		if err != nil {
+			slog.Error("failed to determine replica ID", "error", err)
+			os.Exit(1)
+		}
+		if err := m.EnableReplication(consensus.Config{
+			ID:      id,
+			Peers:   peers,
+			DataDir: filepath.Join(dataDir, "raft"),
+			TLS:     tlsSource,
+		}); err != nil {
+			slog.Error("failed to enable replication", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("replicated master", "id", id, "peers", len(peers))
+	}
 
 	// Start periodic snapshots
 	stopSnapshots := make(chan struct{})
 	m.StartPeriodicSnapshots(snapshotInterval, snapshotMaxWAL, stopSnapshots)
 	defer close(stopSnapshots)
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// Start re-replication of chunks that lost replicas
+	stopReplication := make(chan struct{})
+	m.StartReplicationManager(replicationCheck, stopReplication)
+	defer close(stopReplication)
+
+	// Collect deleted files once their trash retention expires
+	stopTrash := make(chan struct{})
+	m.StartTrashCollector(trashInterval, stopTrash)
+	defer close(stopTrash)
+
+	// Create gRPC server; chunkserver, replica and admin methods need a certificate
+	// with the matching role, and followers redirect everything except replica
+	// traffic to the leader
+	grpcServer := grpc.NewServer(
+		grpc.Creds(tlsSource.ServerCredentials()),
+		grpc.MaxRecvMsgSize(consensus.MaxMessageSize),
+		grpc.ChainUnaryInterceptor(tlsSource.UnaryRoleInterceptor(master.RequiredRole), m.UnaryLeaderInterceptor()),
+		grpc.ChainStreamInterceptor(tlsSource.StreamRoleInterceptor(master.RequiredRole), m.StreamLeaderInterceptor()),
+	)
 	masterService := master.NewGRPCServer(m)
 	pb.RegisterMasterServer(grpcServer, masterService)
+	if peers != nil {
+		pb.RegisterMasterPeerServer(grpcServer, consensus.NewPeerServer(m.Raft()))
+	}
 
 	// Start listening
 	lis, err := net.Listen("tcp", ":"+strconv.Itoa(port))
@@ -83,10 +190,11 @@ func main() {
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 		slog.Info("shutting down master server")
+		m.CloseWatches()
 		grpcServer.GracefulStop()
 	}()
 
-	slog.Info("master server listening", "port", port)
+	slog.Info("master server listening", "port", port, "tls", tlsSource != nil, "grants", tokenKey != "")
 	if err := grpcServer.Serve(lis); err != nil {
 		slog.Error("failed to serve", "error", err)
 		os.Exit(1)
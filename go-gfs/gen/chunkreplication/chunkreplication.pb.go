@@ -387,6 +387,102 @@ func (x *Commit) GetOpId() string {
 	return ""
 }
 
+type Clone struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceHandle  string                 `protobuf:"bytes,1,opt,name=sourceHandle,proto3" json:"sourceHandle,omitempty"`
+	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunkHandle,proto3" json:"chunkHandle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Clone) Reset() {
+	*x = Clone{}
+	mi := &file_chunkreplication_chunkreplication_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Clone) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Clone) ProtoMessage() {}
+
+func (x *Clone) ProtoReflect() protoreflect.Message {
+	mi := &file_chunkreplication_chunkreplication_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Clone.ProtoReflect.Descriptor instead.
+func (*Clone) Descriptor() ([]byte, []int) {
+	return file_chunkreplication_chunkreplication_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Clone) GetSourceHandle() string {
+	if x != nil {
+		return x.SourceHandle
+	}
+	return ""
+}
+
+func (x *Clone) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+type Fetch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkHandle   string                 `protobuf:"bytes,1,opt,name=chunkHandle,proto3" json:"chunkHandle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Fetch) Reset() {
+	*x = Fetch{}
+	mi := &file_chunkreplication_chunkreplication_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Fetch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Fetch) ProtoMessage() {}
+
+func (x *Fetch) ProtoReflect() protoreflect.Message {
+	mi := &file_chunkreplication_chunkreplication_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Fetch.ProtoReflect.Descriptor instead.
+func (*Fetch) Descriptor() ([]byte, []int) {
+	return file_chunkreplication_chunkreplication_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Fetch) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
 var File_chunkreplication_chunkreplication_proto protoreflect.FileDescriptor
 
 const file_chunkreplication_chunkreplication_proto_rawDesc = "" +
@@ -413,13 +509,22 @@ const file_chunkreplication_chunkreplication_proto_rawDesc = "" +
 	"\x04opId\x18\x01 \x01(\tR\x04opId\x12\x18\n" +
 	"\areplica\x18\x02 \x01(\tR\areplica\"\x1c\n" +
 	"\x06Commit\x12\x12\n" +
-	"\x04opId\x18\x01 \x01(\tR\x04opId2\x94\x02\n" +
+	"\x04opId\x18\x01 \x01(\tR\x04opId\"M\n" +
+	"\x05Clone\x12\"\n" +
+	"\fsourceHandle\x18\x01 \x01(\tR\fsourceHandle\x12 \n" +
+	"\vchunkHandle\x18\x02 \x01(\tR\vchunkHandle\")\n" +
+	"\x05Fetch\x12 \n" +
+	"\vchunkHandle\x18\x01 \x01(\tR\vchunkHandle2\xba\x03\n" +
 	"\n" +
 	"Replicator\x12^\n" +
 	"\tReplicate\x12%.chunkreplication.v1.ReplicationFrame\x1a(.chunkreplication.v1.ReplicationResponse(\x01\x12Q\n" +
 	"\tRecvReady\x12\x1a.chunkreplication.v1.Ready\x1a(.chunkreplication.v1.ReplicationResponse\x12S\n" +
 	"\n" +
-	"RecvCommit\x12\x1b.chunkreplication.v1.Commit\x1a(.chunkreplication.v1.ReplicationResponseB>Z<eddisonso.com/go-gfs/proto/chunkreplication;chunkreplicationb\x06proto3"
+	"RecvCommit\x12\x1b.chunkreplication.v1.Commit\x1a(.chunkreplication.v1.ReplicationResponse\x12R\n" +
+	"\n" +
+	"CloneChunk\x12\x1a.chunkreplication.v1.Clone\x1a(.chunkreplication.v1.ReplicationResponse\x12P\n" +
+	"\n" +
+	"FetchChunk\x12\x1a.chunkreplication.v1.Fetch\x1a$.chunkreplication.v1.ReplicationData0\x01B>Z<eddisonso.com/go-gfs/proto/chunkreplication;chunkreplicationb\x06proto3"
 
 var (
 	file_chunkreplication_chunkreplication_proto_rawDescOnce sync.Once
@@ -433,7 +538,7 @@ func file_chunkreplication_chunkreplication_proto_rawDescGZIP() []byte {
 	return file_chunkreplication_chunkreplication_proto_rawDescData
 }
 
-var file_chunkreplication_chunkreplication_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_chunkreplication_chunkreplication_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_chunkreplication_chunkreplication_proto_goTypes = []any{
 	(*ReplicationMetadata)(nil), // 0: chunkreplication.v1.ReplicationMetadata
 	(*ReplicationData)(nil),     // 1: chunkreplication.v1.ReplicationData
@@ -441,6 +546,8 @@ var file_chunkreplication_chunkreplication_proto_goTypes = []any{
 	(*ReplicationResponse)(nil), // 3: chunkreplication.v1.ReplicationResponse
 	(*Ready)(nil),               // 4: chunkreplication.v1.Ready
 	(*Commit)(nil),              // 5: chunkreplication.v1.Commit
+	(*Clone)(nil),               // 6: chunkreplication.v1.Clone
+	(*Fetch)(nil),               // 7: chunkreplication.v1.Fetch
 }
 var file_chunkreplication_chunkreplication_proto_depIdxs = []int32{
 	0, // 0: chunkreplication.v1.ReplicationFrame.meta:type_name -> chunkreplication.v1.ReplicationMetadata
@@ -448,11 +555,15 @@ var file_chunkreplication_chunkreplication_proto_depIdxs = []int32{
 	2, // 2: chunkreplication.v1.Replicator.Replicate:input_type -> chunkreplication.v1.ReplicationFrame
 	4, // 3: chunkreplication.v1.Replicator.RecvReady:input_type -> chunkreplication.v1.Ready
 	5, // 4: chunkreplication.v1.Replicator.RecvCommit:input_type -> chunkreplication.v1.Commit
-	3, // 5: chunkreplication.v1.Replicator.Replicate:output_type -> chunkreplication.v1.ReplicationResponse
-	3, // 6: chunkreplication.v1.Replicator.RecvReady:output_type -> chunkreplication.v1.ReplicationResponse
-	3, // 7: chunkreplication.v1.Replicator.RecvCommit:output_type -> chunkreplication.v1.ReplicationResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
+	6, // 5: chunkreplication.v1.Replicator.CloneChunk:input_type -> chunkreplication.v1.Clone
+	7, // 6: chunkreplication.v1.Replicator.FetchChunk:input_type -> chunkreplication.v1.Fetch
+	3, // 7: chunkreplication.v1.Replicator.Replicate:output_type -> chunkreplication.v1.ReplicationResponse
+	3, // 8: chunkreplication.v1.Replicator.RecvReady:output_type -> chunkreplication.v1.ReplicationResponse
+	3, // 9: chunkreplication.v1.Replicator.RecvCommit:output_type -> chunkreplication.v1.ReplicationResponse
+	3, // 10: chunkreplication.v1.Replicator.CloneChunk:output_type -> chunkreplication.v1.ReplicationResponse
+	1, // 11: chunkreplication.v1.Replicator.FetchChunk:output_type -> chunkreplication.v1.ReplicationData
+	7, // [7:12] is the sub-list for method output_type
+	2, // [2:7] is the sub-list for method input_type
 	2, // [2:2] is the sub-list for extension type_name
 	2, // [2:2] is the sub-list for extension extendee
 	0, // [0:2] is the sub-list for field type_name
@@ -473,7 +584,7 @@ func file_chunkreplication_chunkreplication_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_chunkreplication_chunkreplication_proto_rawDesc), len(file_chunkreplication_chunkreplication_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -22,6 +22,8 @@ const (
 	Replicator_Replicate_FullMethodName  = "/chunkreplication.v1.Replicator/Replicate"
 	Replicator_RecvReady_FullMethodName  = "/chunkreplication.v1.Replicator/RecvReady"
 	Replicator_RecvCommit_FullMethodName = "/chunkreplication.v1.Replicator/RecvCommit"
+	Replicator_CloneChunk_FullMethodName = "/chunkreplication.v1.Replicator/CloneChunk"
+	Replicator_FetchChunk_FullMethodName = "/chunkreplication.v1.Replicator/FetchChunk"
 )
 
 // ReplicatorClient is the client API for Replicator service.
@@ -31,6 +33,8 @@ type ReplicatorClient interface {
 	Replicate(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ReplicationFrame, ReplicationResponse], error)
 	RecvReady(ctx context.Context, in *Ready, opts ...grpc.CallOption) (*ReplicationResponse, error)
 	RecvCommit(ctx context.Context, in *Commit, opts ...grpc.CallOption) (*ReplicationResponse, error)
+	CloneChunk(ctx context.Context, in *Clone, opts ...grpc.CallOption) (*ReplicationResponse, error)
+	FetchChunk(ctx context.Context, in *Fetch, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReplicationData], error)
 }
 
 type replicatorClient struct {
@@ -74,6 +78,35 @@ func (c *replicatorClient) RecvCommit(ctx context.Context, in *Commit, opts ...g
 	return out, nil
 }
 
+func (c *replicatorClient) CloneChunk(ctx context.Context, in *Clone, opts ...grpc.CallOption) (*ReplicationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReplicationResponse)
+	err := c.cc.Invoke(ctx, Replicator_CloneChunk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *replicatorClient) FetchChunk(ctx context.Context, in *Fetch, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReplicationData], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Replicator_ServiceDesc.Streams[1], Replicator_FetchChunk_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Fetch, ReplicationData]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Replicator_FetchChunkClient = grpc.ServerStreamingClient[ReplicationData]
+
 // ReplicatorServer is the server API for Replicator service.
 // All implementations must embed UnimplementedReplicatorServer
 // for forward compatibility.
@@ -81,6 +114,8 @@ type ReplicatorServer interface {
 	Replicate(grpc.ClientStreamingServer[ReplicationFrame, ReplicationResponse]) error
 	RecvReady(context.Context, *Ready) (*ReplicationResponse, error)
 	RecvCommit(context.Context, *Commit) (*ReplicationResponse, error)
+	CloneChunk(context.Context, *Clone) (*ReplicationResponse, error)
+	FetchChunk(*Fetch, grpc.ServerStreamingServer[ReplicationData]) error
 	mustEmbedUnimplementedReplicatorServer()
 }
 
@@ -100,6 +135,12 @@ func (UnimplementedReplicatorServer) RecvReady(context.Context, *Ready) (*Replic
 func (UnimplementedReplicatorServer) RecvCommit(context.Context, *Commit) (*ReplicationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RecvCommit not implemented")
 }
+func (UnimplementedReplicatorServer) CloneChunk(context.Context, *Clone) (*ReplicationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloneChunk not implemented")
+}
+func (UnimplementedReplicatorServer) FetchChunk(*Fetch, grpc.ServerStreamingServer[ReplicationData]) error {
+	return status.Errorf(codes.Unimplemented, "method FetchChunk not implemented")
+}
 func (UnimplementedReplicatorServer) mustEmbedUnimplementedReplicatorServer() {}
 func (UnimplementedReplicatorServer) testEmbeddedByValue()                    {}
 
@@ -164,6 +205,35 @@ func _Replicator_RecvCommit_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Replicator_CloneChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Clone)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReplicatorServer).CloneChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Replicator_CloneChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicatorServer).CloneChunk(ctx, req.(*Clone))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Replicator_FetchChunk_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Fetch)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReplicatorServer).FetchChunk(m, &grpc.GenericServerStream[Fetch, ReplicationData]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Replicator_FetchChunkServer = grpc.ServerStreamingServer[ReplicationData]
+
 // Replicator_ServiceDesc is the grpc.ServiceDesc for Replicator service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -179,6 +249,10 @@ var Replicator_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RecvCommit",
 			Handler:    _Replicator_RecvCommit_Handler,
 		},
+		{
+			MethodName: "CloneChunk",
+			Handler:    _Replicator_CloneChunk_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -186,6 +260,11 @@ var Replicator_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Replicator_Replicate_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "FetchChunk",
+			Handler:       _Replicator_FetchChunk_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "chunkreplication/chunkreplication.proto",
 }
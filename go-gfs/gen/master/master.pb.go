@@ -150,7 +150,11 @@ type ChunkLocationInfo struct {
 	Locations     []*ChunkServerInfo     `protobuf:"bytes,2,rep,name=locations,proto3" json:"locations,omitempty"`
 	Primary       *ChunkServerInfo       `protobuf:"bytes,3,opt,name=primary,proto3" json:"primary,omitempty"`
 	Version       uint64                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
-	Size          uint64                 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"` // Current size of chunk in bytes
+	Size          uint64                 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`                              // Current size of chunk in bytes
+	Shared        bool                   `protobuf:"varint,6,opt,name=shared,proto3" json:"shared,omitempty"`                          // Shared with a snapshot; call PrepareChunkWrite before writing
+	Stripe        *ChunkStripe           `protobuf:"bytes,7,opt,name=stripe,proto3" json:"stripe,omitempty"`                           // Set once the chunk is erasure coded; it then has no replicas and is read-only
+	ReadToken     string                 `protobuf:"bytes,8,opt,name=read_token,json=readToken,proto3" json:"read_token,omitempty"`    // Master-signed data-plane grant to read the chunk; empty unless grants are enabled
+	WriteToken    string                 `protobuf:"bytes,9,opt,name=write_token,json=writeToken,proto3" json:"write_token,omitempty"` // Grant to write the chunk; empty for erasure coded chunks
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -220,6 +224,172 @@ func (x *ChunkLocationInfo) GetSize() uint64 {
 	return 0
 }
 
+func (x *ChunkLocationInfo) GetShared() bool {
+	if x != nil {
+		return x.Shared
+	}
+	return false
+}
+
+func (x *ChunkLocationInfo) GetStripe() *ChunkStripe {
+	if x != nil {
+		return x.Stripe
+	}
+	return nil
+}
+
+func (x *ChunkLocationInfo) GetReadToken() string {
+	if x != nil {
+		return x.ReadToken
+	}
+	return ""
+}
+
+func (x *ChunkLocationInfo) GetWriteToken() string {
+	if x != nil {
+		return x.WriteToken
+	}
+	return ""
+}
+
+// Reed-Solomon stripe of an erasure coded chunk. Data shard i holds chunk bytes
+// [i*shard_size, (i+1)*shard_size), zero padded; parity shards follow the data shards.
+type ChunkStripe struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DataShards    uint32                 `protobuf:"varint,1,opt,name=data_shards,json=dataShards,proto3" json:"data_shards,omitempty"`
+	ParityShards  uint32                 `protobuf:"varint,2,opt,name=parity_shards,json=parityShards,proto3" json:"parity_shards,omitempty"`
+	ShardSize     uint64                 `protobuf:"varint,3,opt,name=shard_size,json=shardSize,proto3" json:"shard_size,omitempty"`
+	Fragments     []*StripeFragment      `protobuf:"bytes,4,rep,name=fragments,proto3" json:"fragments,omitempty"` // Ordered by index
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChunkStripe) Reset() {
+	*x = ChunkStripe{}
+	mi := &file_master_master_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChunkStripe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChunkStripe) ProtoMessage() {}
+
+func (x *ChunkStripe) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChunkStripe.ProtoReflect.Descriptor instead.
+func (*ChunkStripe) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ChunkStripe) GetDataShards() uint32 {
+	if x != nil {
+		return x.DataShards
+	}
+	return 0
+}
+
+func (x *ChunkStripe) GetParityShards() uint32 {
+	if x != nil {
+		return x.ParityShards
+	}
+	return 0
+}
+
+func (x *ChunkStripe) GetShardSize() uint64 {
+	if x != nil {
+		return x.ShardSize
+	}
+	return 0
+}
+
+func (x *ChunkStripe) GetFragments() []*StripeFragment {
+	if x != nil {
+		return x.Fragments
+	}
+	return nil
+}
+
+type StripeFragment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint32                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Handle        string                 `protobuf:"bytes,2,opt,name=handle,proto3" json:"handle,omitempty"` // Stored like a chunk under this handle
+	Locations     []*ChunkServerInfo     `protobuf:"bytes,3,rep,name=locations,proto3" json:"locations,omitempty"`
+	ReadToken     string                 `protobuf:"bytes,4,opt,name=read_token,json=readToken,proto3" json:"read_token,omitempty"` // Grant to read the fragment
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StripeFragment) Reset() {
+	*x = StripeFragment{}
+	mi := &file_master_master_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StripeFragment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StripeFragment) ProtoMessage() {}
+
+func (x *StripeFragment) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StripeFragment.ProtoReflect.Descriptor instead.
+func (*StripeFragment) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StripeFragment) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *StripeFragment) GetHandle() string {
+	if x != nil {
+		return x.Handle
+	}
+	return ""
+}
+
+func (x *StripeFragment) GetLocations() []*ChunkServerInfo {
+	if x != nil {
+		return x.Locations
+	}
+	return nil
+}
+
+func (x *StripeFragment) GetReadToken() string {
+	if x != nil {
+		return x.ReadToken
+	}
+	return ""
+}
+
 // File metadata
 type FileInfoResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -228,15 +398,17 @@ type FileInfoResponse struct {
 	Size          uint64                 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
 	ChunkSize     uint64                 `protobuf:"varint,4,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
 	Namespace     string                 `protobuf:"bytes,5,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	CreatedAt     int64                  `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`    // Unix timestamp (seconds)
-	ModifiedAt    int64                  `protobuf:"varint,7,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"` // Unix timestamp (seconds)
+	CreatedAt     int64                  `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`                                                           // Unix timestamp (seconds)
+	ModifiedAt    int64                  `protobuf:"varint,7,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`                                                        // Unix timestamp (seconds)
+	StorageClass  string                 `protobuf:"bytes,8,opt,name=storage_class,json=storageClass,proto3" json:"storage_class,omitempty"`                                                   // Empty when the file uses its namespace's class
+	Attributes    map[string]string      `protobuf:"bytes,9,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // User-defined metadata
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *FileInfoResponse) Reset() {
 	*x = FileInfoResponse{}
-	mi := &file_master_master_proto_msgTypes[3]
+	mi := &file_master_master_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -248,7 +420,7 @@ func (x *FileInfoResponse) String() string {
 func (*FileInfoResponse) ProtoMessage() {}
 
 func (x *FileInfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[3]
+	mi := &file_master_master_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -261,7 +433,7 @@ func (x *FileInfoResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FileInfoResponse.ProtoReflect.Descriptor instead.
 func (*FileInfoResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{3}
+	return file_master_master_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *FileInfoResponse) GetPath() string {
@@ -313,33 +485,50 @@ func (x *FileInfoResponse) GetModifiedAt() int64 {
 	return 0
 }
 
-type RegisterRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	ServerId        string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	Hostname        string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	DataPort        int32                  `protobuf:"varint,3,opt,name=data_port,json=dataPort,proto3" json:"data_port,omitempty"`
-	ReplicationPort int32                  `protobuf:"varint,4,opt,name=replication_port,json=replicationPort,proto3" json:"replication_port,omitempty"`
-	ChunkHandles    []string               `protobuf:"bytes,5,rep,name=chunk_handles,json=chunkHandles,proto3" json:"chunk_handles,omitempty"` // Chunks this server has
-	BuildInfo       *BuildInfo             `protobuf:"bytes,6,opt,name=build_info,json=buildInfo,proto3" json:"build_info,omitempty"`          // Build information
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+func (x *FileInfoResponse) GetStorageClass() string {
+	if x != nil {
+		return x.StorageClass
+	}
+	return ""
 }
 
-func (x *RegisterRequest) Reset() {
-	*x = RegisterRequest{}
-	mi := &file_master_master_proto_msgTypes[4]
+func (x *FileInfoResponse) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+// Usage of one chunkserver data directory, normally one disk
+type DiskStatus struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Dir              string                 `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"`
+	CapacityBytes    uint64                 `protobuf:"varint,2,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"`
+	FreeBytes        uint64                 `protobuf:"varint,3,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
+	ChunkCount       int32                  `protobuf:"varint,4,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"`
+	Failed           bool                   `protobuf:"varint,5,opt,name=failed,proto3" json:"failed,omitempty"`                                 // Failed its health probe; its chunks were reported lost
+	UsedBytes        uint64                 `protobuf:"varint,6,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`          // Size of the chunks stored in this directory
+	LogicalBytes     uint64                 `protobuf:"varint,7,opt,name=logical_bytes,json=logicalBytes,proto3" json:"logical_bytes,omitempty"` // Size of the chunks before compression
+	CompressedChunks int32                  `protobuf:"varint,8,opt,name=compressed_chunks,json=compressedChunks,proto3" json:"compressed_chunks,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *DiskStatus) Reset() {
+	*x = DiskStatus{}
+	mi := &file_master_master_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterRequest) String() string {
+func (x *DiskStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterRequest) ProtoMessage() {}
+func (*DiskStatus) ProtoMessage() {}
 
-func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[4]
+func (x *DiskStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -350,76 +539,92 @@ func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
-func (*RegisterRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use DiskStatus.ProtoReflect.Descriptor instead.
+func (*DiskStatus) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *RegisterRequest) GetServerId() string {
+func (x *DiskStatus) GetDir() string {
 	if x != nil {
-		return x.ServerId
+		return x.Dir
 	}
 	return ""
 }
 
-func (x *RegisterRequest) GetHostname() string {
+func (x *DiskStatus) GetCapacityBytes() uint64 {
 	if x != nil {
-		return x.Hostname
+		return x.CapacityBytes
 	}
-	return ""
+	return 0
 }
 
-func (x *RegisterRequest) GetDataPort() int32 {
+func (x *DiskStatus) GetFreeBytes() uint64 {
 	if x != nil {
-		return x.DataPort
+		return x.FreeBytes
 	}
 	return 0
 }
 
-func (x *RegisterRequest) GetReplicationPort() int32 {
+func (x *DiskStatus) GetChunkCount() int32 {
 	if x != nil {
-		return x.ReplicationPort
+		return x.ChunkCount
 	}
 	return 0
 }
 
-func (x *RegisterRequest) GetChunkHandles() []string {
+func (x *DiskStatus) GetFailed() bool {
 	if x != nil {
-		return x.ChunkHandles
+		return x.Failed
 	}
-	return nil
+	return false
 }
 
-func (x *RegisterRequest) GetBuildInfo() *BuildInfo {
+func (x *DiskStatus) GetUsedBytes() uint64 {
 	if x != nil {
-		return x.BuildInfo
+		return x.UsedBytes
 	}
-	return nil
+	return 0
 }
 
-type RegisterResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *DiskStatus) GetLogicalBytes() uint64 {
+	if x != nil {
+		return x.LogicalBytes
+	}
+	return 0
 }
 
-func (x *RegisterResponse) Reset() {
-	*x = RegisterResponse{}
-	mi := &file_master_master_proto_msgTypes[5]
+func (x *DiskStatus) GetCompressedChunks() int32 {
+	if x != nil {
+		return x.CompressedChunks
+	}
+	return 0
+}
+
+// Data-plane load on a chunkserver, averaged since its previous heartbeat
+type ServerLoad struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	OpenConnections  int64                  `protobuf:"varint,1,opt,name=open_connections,json=openConnections,proto3" json:"open_connections,omitempty"`
+	ReadBytesPerSec  uint64                 `protobuf:"varint,2,opt,name=read_bytes_per_sec,json=readBytesPerSec,proto3" json:"read_bytes_per_sec,omitempty"`    // Chunk data served to clients and other chunkservers
+	WriteBytesPerSec uint64                 `protobuf:"varint,3,opt,name=write_bytes_per_sec,json=writeBytesPerSec,proto3" json:"write_bytes_per_sec,omitempty"` // Chunk data received from clients and primaries
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ServerLoad) Reset() {
+	*x = ServerLoad{}
+	mi := &file_master_master_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RegisterResponse) String() string {
+func (x *ServerLoad) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RegisterResponse) ProtoMessage() {}
+func (*ServerLoad) ProtoMessage() {}
 
-func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[5]
+func (x *ServerLoad) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -430,48 +635,61 @@ func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
-func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use ServerLoad.ProtoReflect.Descriptor instead.
+func (*ServerLoad) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{7}
 }
 
-func (x *RegisterResponse) GetSuccess() bool {
+func (x *ServerLoad) GetOpenConnections() int64 {
 	if x != nil {
-		return x.Success
+		return x.OpenConnections
 	}
-	return false
+	return 0
 }
 
-func (x *RegisterResponse) GetMessage() string {
+func (x *ServerLoad) GetReadBytesPerSec() uint64 {
 	if x != nil {
-		return x.Message
+		return x.ReadBytesPerSec
 	}
-	return ""
+	return 0
 }
 
-type HeartbeatRequest struct {
+func (x *ServerLoad) GetWriteBytesPerSec() uint64 {
+	if x != nil {
+		return x.WriteBytesPerSec
+	}
+	return 0
+}
+
+// Changes to a chunkserver's chunks since its last acknowledged report. A full
+// report lists every chunk in added; chunks missing from it are not dropped,
+// since one allocated after the server's scan is legitimately absent.
+type ChunkReport struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	ChunkHandles  []string               `protobuf:"bytes,2,rep,name=chunk_handles,json=chunkHandles,proto3" json:"chunk_handles,omitempty"` // Current chunks on this server
+	Full          bool                   `protobuf:"varint,1,opt,name=full,proto3" json:"full,omitempty"`
+	Added         []string               `protobuf:"bytes,2,rep,name=added,proto3" json:"added,omitempty"`                                                                                  // New chunks, or chunks whose version changed
+	Removed       []string               `protobuf:"bytes,3,rep,name=removed,proto3" json:"removed,omitempty"`                                                                              // Chunks deleted or lost since the last report
+	Versions      map[string]uint64      `protobuf:"bytes,4,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Stored version per added chunk; absent for chunks written before versions
+	ChunkCount    int32                  `protobuf:"varint,5,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"`                                                     // Chunks on the server after the change
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *HeartbeatRequest) Reset() {
-	*x = HeartbeatRequest{}
-	mi := &file_master_master_proto_msgTypes[6]
+func (x *ChunkReport) Reset() {
+	*x = ChunkReport{}
+	mi := &file_master_master_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *HeartbeatRequest) String() string {
+func (x *ChunkReport) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*HeartbeatRequest) ProtoMessage() {}
+func (*ChunkReport) ProtoMessage() {}
 
-func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[6]
+func (x *ChunkReport) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -482,48 +700,3820 @@ func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
-func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use ChunkReport.ProtoReflect.Descriptor instead.
+func (*ChunkReport) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *HeartbeatRequest) GetServerId() string {
+func (x *ChunkReport) GetFull() bool {
 	if x != nil {
-		return x.ServerId
+		return x.Full
 	}
-	return ""
+	return false
 }
 
-func (x *HeartbeatRequest) GetChunkHandles() []string {
+func (x *ChunkReport) GetAdded() []string {
 	if x != nil {
-		return x.ChunkHandles
+		return x.Added
 	}
 	return nil
 }
 
-type HeartbeatResponse struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	ChunksToDelete []string               `protobuf:"bytes,2,rep,name=chunks_to_delete,json=chunksToDelete,proto3" json:"chunks_to_delete,omitempty"` // Garbage collection
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
-}
-
-func (x *HeartbeatResponse) Reset() {
-	*x = HeartbeatResponse{}
-	mi := &file_master_master_proto_msgTypes[7]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *ChunkReport) GetRemoved() []string {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
 }
 
-func (x *HeartbeatResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ChunkReport) GetVersions() map[string]uint64 {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+func (x *ChunkReport) GetChunkCount() int32 {
+	if x != nil {
+		return x.ChunkCount
+	}
+	return 0
+}
+
+type RegisterRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ServerId         string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Hostname         string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	DataPort         int32                  `protobuf:"varint,3,opt,name=data_port,json=dataPort,proto3" json:"data_port,omitempty"`
+	ReplicationPort  int32                  `protobuf:"varint,4,opt,name=replication_port,json=replicationPort,proto3" json:"replication_port,omitempty"`
+	ChunkHandles     []string               `protobuf:"bytes,5,rep,name=chunk_handles,json=chunkHandles,proto3" json:"chunk_handles,omitempty"`                                                                                // Chunks this server has
+	BuildInfo        *BuildInfo             `protobuf:"bytes,6,opt,name=build_info,json=buildInfo,proto3" json:"build_info,omitempty"`                                                                                         // Build information
+	FailureDomain    string                 `protobuf:"bytes,7,opt,name=failure_domain,json=failureDomain,proto3" json:"failure_domain,omitempty"`                                                                             // Placement spread label (node, rack, arch...); defaults to server_id
+	CapacityBytes    uint64                 `protobuf:"varint,8,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"`                                                                            // Total size of the chunk storage filesystem
+	FreeBytes        uint64                 `protobuf:"varint,9,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`                                                                                        // Free space available for new chunks
+	ChunkVersions    map[string]uint64      `protobuf:"bytes,10,rep,name=chunk_versions,json=chunkVersions,proto3" json:"chunk_versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Stored version per chunk; absent for chunks written before versions
+	Disks            []*DiskStatus          `protobuf:"bytes,11,rep,name=disks,proto3" json:"disks,omitempty"`                                                                                                                 // Per-directory usage; capacity and free bytes are the healthy totals
+	UsedBytes        uint64                 `protobuf:"varint,12,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`                                                                                       // Size of the chunks stored on this server
+	LogicalBytes     uint64                 `protobuf:"varint,13,opt,name=logical_bytes,json=logicalBytes,proto3" json:"logical_bytes,omitempty"`                                                                              // Size of the chunks before compression
+	CompressedChunks int32                  `protobuf:"varint,14,opt,name=compressed_chunks,json=compressedChunks,proto3" json:"compressed_chunks,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_master_master_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RegisterRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetDataPort() int32 {
+	if x != nil {
+		return x.DataPort
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetReplicationPort() int32 {
+	if x != nil {
+		return x.ReplicationPort
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetChunkHandles() []string {
+	if x != nil {
+		return x.ChunkHandles
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetBuildInfo() *BuildInfo {
+	if x != nil {
+		return x.BuildInfo
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetFailureDomain() string {
+	if x != nil {
+		return x.FailureDomain
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetCapacityBytes() uint64 {
+	if x != nil {
+		return x.CapacityBytes
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetFreeBytes() uint64 {
+	if x != nil {
+		return x.FreeBytes
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetChunkVersions() map[string]uint64 {
+	if x != nil {
+		return x.ChunkVersions
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetDisks() []*DiskStatus {
+	if x != nil {
+		return x.Disks
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetLogicalBytes() uint64 {
+	if x != nil {
+		return x.LogicalBytes
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetCompressedChunks() int32 {
+	if x != nil {
+		return x.CompressedChunks
+	}
+	return 0
+}
+
+type RegisterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_master_master_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RegisterResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type HeartbeatRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ServerId         string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ChunkHandles     []string               `protobuf:"bytes,2,rep,name=chunk_handles,json=chunkHandles,proto3" json:"chunk_handles,omitempty"`                                                                               // Current chunks on this server
+	CapacityBytes    uint64                 `protobuf:"varint,3,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"`                                                                           // Total size of the chunk storage filesystem
+	FreeBytes        uint64                 `protobuf:"varint,4,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`                                                                                       // Free space available for new chunks
+	ChunkVersions    map[string]uint64      `protobuf:"bytes,5,rep,name=chunk_versions,json=chunkVersions,proto3" json:"chunk_versions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Stored version per chunk; absent for chunks written before versions
+	Disks            []*DiskStatus          `protobuf:"bytes,6,rep,name=disks,proto3" json:"disks,omitempty"`                                                                                                                 // Per-directory usage; capacity and free bytes are the healthy totals
+	LostChunks       []string               `protobuf:"bytes,7,rep,name=lost_chunks,json=lostChunks,proto3" json:"lost_chunks,omitempty"`                                                                                     // Chunks and fragments lost with a failed data directory
+	UsedBytes        uint64                 `protobuf:"varint,8,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`                                                                                       // Size of the chunks stored on this server
+	Load             *ServerLoad            `protobuf:"bytes,9,opt,name=load,proto3" json:"load,omitempty"`
+	ChunkReport      *ChunkReport           `protobuf:"bytes,10,opt,name=chunk_report,json=chunkReport,proto3" json:"chunk_report,omitempty"`     // When set, replaces chunk_handles and chunk_versions
+	LogicalBytes     uint64                 `protobuf:"varint,11,opt,name=logical_bytes,json=logicalBytes,proto3" json:"logical_bytes,omitempty"` // Size of the chunks before compression
+	CompressedChunks int32                  `protobuf:"varint,12,opt,name=compressed_chunks,json=compressedChunks,proto3" json:"compressed_chunks,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_master_master_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *HeartbeatRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetChunkHandles() []string {
+	if x != nil {
+		return x.ChunkHandles
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetCapacityBytes() uint64 {
+	if x != nil {
+		return x.CapacityBytes
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetFreeBytes() uint64 {
+	if x != nil {
+		return x.FreeBytes
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetChunkVersions() map[string]uint64 {
+	if x != nil {
+		return x.ChunkVersions
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetDisks() []*DiskStatus {
+	if x != nil {
+		return x.Disks
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetLostChunks() []string {
+	if x != nil {
+		return x.LostChunks
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetLoad() *ServerLoad {
+	if x != nil {
+		return x.Load
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetChunkReport() *ChunkReport {
+	if x != nil {
+		return x.ChunkReport
+	}
+	return nil
+}
+
+func (x *HeartbeatRequest) GetLogicalBytes() uint64 {
+	if x != nil {
+		return x.LogicalBytes
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetCompressedChunks() int32 {
+	if x != nil {
+		return x.CompressedChunks
+	}
+	return 0
+}
+
+type HeartbeatResponse struct {
+	state               protoimpl.MessageState   `protogen:"open.v1"`
+	Success             bool                     `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	ChunksToDelete      []string                 `protobuf:"bytes,2,rep,name=chunks_to_delete,json=chunksToDelete,proto3" json:"chunks_to_delete,omitempty"`                 // Garbage collection
+	ChunksToReplicate   []*ReplicateChunkCommand `protobuf:"bytes,3,rep,name=chunks_to_replicate,json=chunksToReplicate,proto3" json:"chunks_to_replicate,omitempty"`        // Re-replication work
+	ChunksToEncode      []*EncodeChunkCommand    `protobuf:"bytes,4,rep,name=chunks_to_encode,json=chunksToEncode,proto3" json:"chunks_to_encode,omitempty"`                 // Erasure coding and fragment repair work
+	FullReportRequested bool                     `protobuf:"varint,5,opt,name=full_report_requested,json=fullReportRequested,proto3" json:"full_report_requested,omitempty"` // Send a full chunk report next heartbeat
+	ChunksToCompress    []string                 `protobuf:"bytes,6,rep,name=chunks_to_compress,json=chunksToCompress,proto3" json:"chunks_to_compress,omitempty"`           // Sealed chunks to store compressed
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_master_master_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
 func (*HeartbeatResponse) ProtoMessage() {}
 
-func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[7]
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *HeartbeatResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetChunksToDelete() []string {
+	if x != nil {
+		return x.ChunksToDelete
+	}
+	return nil
+}
+
+func (x *HeartbeatResponse) GetChunksToReplicate() []*ReplicateChunkCommand {
+	if x != nil {
+		return x.ChunksToReplicate
+	}
+	return nil
+}
+
+func (x *HeartbeatResponse) GetChunksToEncode() []*EncodeChunkCommand {
+	if x != nil {
+		return x.ChunksToEncode
+	}
+	return nil
+}
+
+func (x *HeartbeatResponse) GetFullReportRequested() bool {
+	if x != nil {
+		return x.FullReportRequested
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetChunksToCompress() []string {
+	if x != nil {
+		return x.ChunksToCompress
+	}
+	return nil
+}
+
+// Instructs a chunkserver to copy one of its chunks to another server
+type ReplicateChunkCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkHandle   string                 `protobuf:"bytes,1,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Target        *ChunkServerInfo       `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateChunkCommand) Reset() {
+	*x = ReplicateChunkCommand{}
+	mi := &file_master_master_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateChunkCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateChunkCommand) ProtoMessage() {}
+
+func (x *ReplicateChunkCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateChunkCommand.ProtoReflect.Descriptor instead.
+func (*ReplicateChunkCommand) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ReplicateChunkCommand) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *ReplicateChunkCommand) GetTarget() *ChunkServerInfo {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+// Instructs a chunkserver to write stripe fragments of a chunk to other servers.
+// Without sources the chunk is encoded from the local replica; otherwise the
+// target fragments are rebuilt from the source fragments.
+type EncodeChunkCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkHandle   string                 `protobuf:"bytes,1,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Size          uint64                 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"` // Chunk size the stripe covers
+	DataShards    uint32                 `protobuf:"varint,3,opt,name=data_shards,json=dataShards,proto3" json:"data_shards,omitempty"`
+	ParityShards  uint32                 `protobuf:"varint,4,opt,name=parity_shards,json=parityShards,proto3" json:"parity_shards,omitempty"`
+	Sources       []*FragmentPlacement   `protobuf:"bytes,5,rep,name=sources,proto3" json:"sources,omitempty"`
+	Targets       []*FragmentPlacement   `protobuf:"bytes,6,rep,name=targets,proto3" json:"targets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncodeChunkCommand) Reset() {
+	*x = EncodeChunkCommand{}
+	mi := &file_master_master_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncodeChunkCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncodeChunkCommand) ProtoMessage() {}
+
+func (x *EncodeChunkCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncodeChunkCommand.ProtoReflect.Descriptor instead.
+func (*EncodeChunkCommand) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *EncodeChunkCommand) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *EncodeChunkCommand) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *EncodeChunkCommand) GetDataShards() uint32 {
+	if x != nil {
+		return x.DataShards
+	}
+	return 0
+}
+
+func (x *EncodeChunkCommand) GetParityShards() uint32 {
+	if x != nil {
+		return x.ParityShards
+	}
+	return 0
+}
+
+func (x *EncodeChunkCommand) GetSources() []*FragmentPlacement {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *EncodeChunkCommand) GetTargets() []*FragmentPlacement {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+type FragmentPlacement struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint32                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Handle        string                 `protobuf:"bytes,2,opt,name=handle,proto3" json:"handle,omitempty"`
+	Server        *ChunkServerInfo       `protobuf:"bytes,3,opt,name=server,proto3" json:"server,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FragmentPlacement) Reset() {
+	*x = FragmentPlacement{}
+	mi := &file_master_master_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FragmentPlacement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FragmentPlacement) ProtoMessage() {}
+
+func (x *FragmentPlacement) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FragmentPlacement.ProtoReflect.Descriptor instead.
+func (*FragmentPlacement) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *FragmentPlacement) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *FragmentPlacement) GetHandle() string {
+	if x != nil {
+		return x.Handle
+	}
+	return ""
+}
+
+func (x *FragmentPlacement) GetServer() *ChunkServerInfo {
+	if x != nil {
+		return x.Server
+	}
+	return nil
+}
+
+// Report the outcome of an EncodeChunkCommand
+type ReportEncodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Success       bool                   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"` // Error detail on failure
+	ShardSize     uint64                 `protobuf:"varint,5,opt,name=shard_size,json=shardSize,proto3" json:"shard_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportEncodeRequest) Reset() {
+	*x = ReportEncodeRequest{}
+	mi := &file_master_master_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportEncodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportEncodeRequest) ProtoMessage() {}
+
+func (x *ReportEncodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportEncodeRequest.ProtoReflect.Descriptor instead.
+func (*ReportEncodeRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ReportEncodeRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ReportEncodeRequest) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *ReportEncodeRequest) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportEncodeRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ReportEncodeRequest) GetShardSize() uint64 {
+	if x != nil {
+		return x.ShardSize
+	}
+	return 0
+}
+
+type ReportEncodeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportEncodeResponse) Reset() {
+	*x = ReportEncodeResponse{}
+	mi := &file_master_master_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportEncodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportEncodeResponse) ProtoMessage() {}
+
+func (x *ReportEncodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportEncodeResponse.ProtoReflect.Descriptor instead.
+func (*ReportEncodeResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReportEncodeResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportEncodeResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Report the outcome of a ReplicateChunkCommand
+type ReportReplicationRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ServerId       string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"` // Source chunkserver
+	ChunkHandle    string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	TargetServerId string                 `protobuf:"bytes,3,opt,name=target_server_id,json=targetServerId,proto3" json:"target_server_id,omitempty"`
+	Success        bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	Message        string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"` // Error detail on failure
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReportReplicationRequest) Reset() {
+	*x = ReportReplicationRequest{}
+	mi := &file_master_master_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportReplicationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportReplicationRequest) ProtoMessage() {}
+
+func (x *ReportReplicationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportReplicationRequest.ProtoReflect.Descriptor instead.
+func (*ReportReplicationRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ReportReplicationRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ReportReplicationRequest) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *ReportReplicationRequest) GetTargetServerId() string {
+	if x != nil {
+		return x.TargetServerId
+	}
+	return ""
+}
+
+func (x *ReportReplicationRequest) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportReplicationRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ReportReplicationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportReplicationResponse) Reset() {
+	*x = ReportReplicationResponse{}
+	mi := &file_master_master_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportReplicationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportReplicationResponse) ProtoMessage() {}
+
+func (x *ReportReplicationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportReplicationResponse.ProtoReflect.Descriptor instead.
+func (*ReportReplicationResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ReportReplicationResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportReplicationResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Report successful chunk commit
+type ReportCommitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Size          uint64                 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`       // Chunk length after the write (offset + bytes written)
+	Version       uint64                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"` // Chunk version the write was stamped with
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCommitRequest) Reset() {
+	*x = ReportCommitRequest{}
+	mi := &file_master_master_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCommitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCommitRequest) ProtoMessage() {}
+
+func (x *ReportCommitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCommitRequest.ProtoReflect.Descriptor instead.
+func (*ReportCommitRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ReportCommitRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ReportCommitRequest) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *ReportCommitRequest) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ReportCommitRequest) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ReportCommitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCommitResponse) Reset() {
+	*x = ReportCommitResponse{}
+	mi := &file_master_master_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCommitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCommitResponse) ProtoMessage() {}
+
+func (x *ReportCommitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCommitResponse.ProtoReflect.Descriptor instead.
+func (*ReportCommitResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ReportCommitResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportCommitResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Lease renewal request from primary chunkserver
+type RenewLeaseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewLeaseRequest) Reset() {
+	*x = RenewLeaseRequest{}
+	mi := &file_master_master_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewLeaseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewLeaseRequest) ProtoMessage() {}
+
+func (x *RenewLeaseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewLeaseRequest.ProtoReflect.Descriptor instead.
+func (*RenewLeaseRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *RenewLeaseRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *RenewLeaseRequest) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+type RenewLeaseResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	LeaseDurationMs uint64                 `protobuf:"varint,3,opt,name=lease_duration_ms,json=leaseDurationMs,proto3" json:"lease_duration_ms,omitempty"` // Duration until lease expires (in milliseconds)
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RenewLeaseResponse) Reset() {
+	*x = RenewLeaseResponse{}
+	mi := &file_master_master_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewLeaseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewLeaseResponse) ProtoMessage() {}
+
+func (x *RenewLeaseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewLeaseResponse.ProtoReflect.Descriptor instead.
+func (*RenewLeaseResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RenewLeaseResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RenewLeaseResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RenewLeaseResponse) GetLeaseDurationMs() uint64 {
+	if x != nil {
+		return x.LeaseDurationMs
+	}
+	return 0
+}
+
+// Claim primary status at start of write operation
+type ClaimPrimaryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClaimPrimaryRequest) Reset() {
+	*x = ClaimPrimaryRequest{}
+	mi := &file_master_master_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClaimPrimaryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimPrimaryRequest) ProtoMessage() {}
+
+func (x *ClaimPrimaryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimPrimaryRequest.ProtoReflect.Descriptor instead.
+func (*ClaimPrimaryRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ClaimPrimaryRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ClaimPrimaryRequest) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+type ClaimPrimaryResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	LeaseDurationMs uint64                 `protobuf:"varint,3,opt,name=lease_duration_ms,json=leaseDurationMs,proto3" json:"lease_duration_ms,omitempty"` // Duration until lease expires (in milliseconds)
+	Version         uint64                 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`                                          // Chunk version under this lease; stamped on every replica by the write
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ClaimPrimaryResponse) Reset() {
+	*x = ClaimPrimaryResponse{}
+	mi := &file_master_master_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClaimPrimaryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimPrimaryResponse) ProtoMessage() {}
+
+func (x *ClaimPrimaryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimPrimaryResponse.ProtoReflect.Descriptor instead.
+func (*ClaimPrimaryResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ClaimPrimaryResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ClaimPrimaryResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ClaimPrimaryResponse) GetLeaseDurationMs() uint64 {
+	if x != nil {
+		return x.LeaseDurationMs
+	}
+	return 0
+}
+
+func (x *ClaimPrimaryResponse) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// Report a replica that failed checksum verification
+type ReportCorruptChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCorruptChunkRequest) Reset() {
+	*x = ReportCorruptChunkRequest{}
+	mi := &file_master_master_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCorruptChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCorruptChunkRequest) ProtoMessage() {}
+
+func (x *ReportCorruptChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCorruptChunkRequest.ProtoReflect.Descriptor instead.
+func (*ReportCorruptChunkRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ReportCorruptChunkRequest) GetServerId() string {
+	if x != nil {
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *ReportCorruptChunkRequest) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *ReportCorruptChunkRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ReportCorruptChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportCorruptChunkResponse) Reset() {
+	*x = ReportCorruptChunkResponse{}
+	mi := &file_master_master_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportCorruptChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportCorruptChunkResponse) ProtoMessage() {}
+
+func (x *ReportCorruptChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportCorruptChunkResponse.ProtoReflect.Descriptor instead.
+func (*ReportCorruptChunkResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ReportCorruptChunkResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReportCorruptChunkResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type CreateFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	StorageClass  string                 `protobuf:"bytes,3,opt,name=storage_class,json=storageClass,proto3" json:"storage_class,omitempty"` // "replicated" or "cold"; empty uses the namespace's class
+	Attributes    map[string]string      `protobuf:"bytes,4,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateFileRequest) Reset() {
+	*x = CreateFileRequest{}
+	mi := &file_master_master_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateFileRequest) ProtoMessage() {}
+
+func (x *CreateFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateFileRequest.ProtoReflect.Descriptor instead.
+func (*CreateFileRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *CreateFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *CreateFileRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *CreateFileRequest) GetStorageClass() string {
+	if x != nil {
+		return x.StorageClass
+	}
+	return ""
+}
+
+func (x *CreateFileRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type CreateFileResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Success            bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message            string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	File               *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	QuotaExceeded      bool                   `protobuf:"varint,4,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"`                // Rejected by the namespace quota
+	PreconditionFailed bool                   `protobuf:"varint,5,opt,name=precondition_failed,json=preconditionFailed,proto3" json:"precondition_failed,omitempty"` // The file already exists
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CreateFileResponse) Reset() {
+	*x = CreateFileResponse{}
+	mi := &file_master_master_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateFileResponse) ProtoMessage() {}
+
+func (x *CreateFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateFileResponse.ProtoReflect.Descriptor instead.
+func (*CreateFileResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CreateFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CreateFileResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CreateFileResponse) GetFile() *FileInfoResponse {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *CreateFileResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+func (x *CreateFileResponse) GetPreconditionFailed() bool {
+	if x != nil {
+		return x.PreconditionFailed
+	}
+	return false
+}
+
+type GetFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFileRequest) Reset() {
+	*x = GetFileRequest{}
+	mi := &file_master_master_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFileRequest) ProtoMessage() {}
+
+func (x *GetFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFileRequest.ProtoReflect.Descriptor instead.
+func (*GetFileRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *GetFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *GetFileRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type GetFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	File          *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFileResponse) Reset() {
+	*x = GetFileResponse{}
+	mi := &file_master_master_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFileResponse) ProtoMessage() {}
+
+func (x *GetFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFileResponse.ProtoReflect.Descriptor instead.
+func (*GetFileResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *GetFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *GetFileResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *GetFileResponse) GetFile() *FileInfoResponse {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+type DeleteFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileRequest) Reset() {
+	*x = DeleteFileRequest{}
+	mi := &file_master_master_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileRequest) ProtoMessage() {}
+
+func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileRequest.ProtoReflect.Descriptor instead.
+func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *DeleteFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *DeleteFileRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type DeleteFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteFileResponse) Reset() {
+	*x = DeleteFileResponse{}
+	mi := &file_master_master_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteFileResponse) ProtoMessage() {}
+
+func (x *DeleteFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteFileResponse.ProtoReflect.Descriptor instead.
+func (*DeleteFileResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *DeleteFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteFileResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type DeleteNamespaceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteNamespaceRequest) Reset() {
+	*x = DeleteNamespaceRequest{}
+	mi := &file_master_master_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteNamespaceRequest) ProtoMessage() {}
+
+func (x *DeleteNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*DeleteNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *DeleteNamespaceRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type DeleteNamespaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FilesDeleted  int32                  `protobuf:"varint,3,opt,name=files_deleted,json=filesDeleted,proto3" json:"files_deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteNamespaceResponse) Reset() {
+	*x = DeleteNamespaceResponse{}
+	mi := &file_master_master_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteNamespaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteNamespaceResponse) ProtoMessage() {}
+
+func (x *DeleteNamespaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteNamespaceResponse.ProtoReflect.Descriptor instead.
+func (*DeleteNamespaceResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DeleteNamespaceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteNamespaceResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *DeleteNamespaceResponse) GetFilesDeleted() int32 {
+	if x != nil {
+		return x.FilesDeleted
+	}
+	return 0
+}
+
+type RenameFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OldPath       string                 `protobuf:"bytes,1,opt,name=old_path,json=oldPath,proto3" json:"old_path,omitempty"`
+	NewPath       string                 `protobuf:"bytes,2,opt,name=new_path,json=newPath,proto3" json:"new_path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Replace       bool                   `protobuf:"varint,4,opt,name=replace,proto3" json:"replace,omitempty"` // Atomically replace a file already at new_path
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameFileRequest) Reset() {
+	*x = RenameFileRequest{}
+	mi := &file_master_master_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileRequest) ProtoMessage() {}
+
+func (x *RenameFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileRequest.ProtoReflect.Descriptor instead.
+func (*RenameFileRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *RenameFileRequest) GetOldPath() string {
+	if x != nil {
+		return x.OldPath
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetNewPath() string {
+	if x != nil {
+		return x.NewPath
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *RenameFileRequest) GetReplace() bool {
+	if x != nil {
+		return x.Replace
+	}
+	return false
+}
+
+type RenameFileResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Success            bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message            string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	PreconditionFailed bool                   `protobuf:"varint,3,opt,name=precondition_failed,json=preconditionFailed,proto3" json:"precondition_failed,omitempty"` // A file already exists at new_path
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *RenameFileResponse) Reset() {
+	*x = RenameFileResponse{}
+	mi := &file_master_master_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameFileResponse) ProtoMessage() {}
+
+func (x *RenameFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameFileResponse.ProtoReflect.Descriptor instead.
+func (*RenameFileResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *RenameFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RenameFileResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RenameFileResponse) GetPreconditionFailed() bool {
+	if x != nil {
+		return x.PreconditionFailed
+	}
+	return false
+}
+
+// Must hold for a transaction to apply. By default the file must exist
+type TransactCondition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Absent        bool                   `protobuf:"varint,2,opt,name=absent,proto3" json:"absent,omitempty"`                        // The path must not exist
+	CheckSize     bool                   `protobuf:"varint,3,opt,name=check_size,json=checkSize,proto3" json:"check_size,omitempty"` // The file must be exactly size bytes
+	Size          uint64                 `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactCondition) Reset() {
+	*x = TransactCondition{}
+	mi := &file_master_master_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactCondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactCondition) ProtoMessage() {}
+
+func (x *TransactCondition) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactCondition.ProtoReflect.Descriptor instead.
+func (*TransactCondition) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *TransactCondition) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *TransactCondition) GetAbsent() bool {
+	if x != nil {
+		return x.Absent
+	}
+	return false
+}
+
+func (x *TransactCondition) GetCheckSize() bool {
+	if x != nil {
+		return x.CheckSize
+	}
+	return false
+}
+
+func (x *TransactCondition) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// One change in a transaction
+type TransactOp struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // "create", "delete", "rename" or "set_attributes"
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	NewPath       string                 `protobuf:"bytes,3,opt,name=new_path,json=newPath,proto3" json:"new_path,omitempty"`                                                                  // rename: destination
+	Replace       bool                   `protobuf:"varint,4,opt,name=replace,proto3" json:"replace,omitempty"`                                                                                // rename: replace a file already at new_path
+	StorageClass  string                 `protobuf:"bytes,5,opt,name=storage_class,json=storageClass,proto3" json:"storage_class,omitempty"`                                                   // create
+	Attributes    map[string]string      `protobuf:"bytes,6,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // create: initial attributes; set_attributes: keys to set
+	Remove        []string               `protobuf:"bytes,7,rep,name=remove,proto3" json:"remove,omitempty"`                                                                                   // set_attributes: keys to delete
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactOp) Reset() {
+	*x = TransactOp{}
+	mi := &file_master_master_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactOp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactOp) ProtoMessage() {}
+
+func (x *TransactOp) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactOp.ProtoReflect.Descriptor instead.
+func (*TransactOp) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *TransactOp) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *TransactOp) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *TransactOp) GetNewPath() string {
+	if x != nil {
+		return x.NewPath
+	}
+	return ""
+}
+
+func (x *TransactOp) GetReplace() bool {
+	if x != nil {
+		return x.Replace
+	}
+	return false
+}
+
+func (x *TransactOp) GetStorageClass() string {
+	if x != nil {
+		return x.StorageClass
+	}
+	return ""
+}
+
+func (x *TransactOp) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *TransactOp) GetRemove() []string {
+	if x != nil {
+		return x.Remove
+	}
+	return nil
+}
+
+// Checks every condition, then applies the ops in order as one WAL record
+type TransactRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Conditions    []*TransactCondition   `protobuf:"bytes,2,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	Ops           []*TransactOp          `protobuf:"bytes,3,rep,name=ops,proto3" json:"ops,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactRequest) Reset() {
+	*x = TransactRequest{}
+	mi := &file_master_master_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactRequest) ProtoMessage() {}
+
+func (x *TransactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactRequest.ProtoReflect.Descriptor instead.
+func (*TransactRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *TransactRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *TransactRequest) GetConditions() []*TransactCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+func (x *TransactRequest) GetOps() []*TransactOp {
+	if x != nil {
+		return x.Ops
+	}
+	return nil
+}
+
+type TransactResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Success            bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message            string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	PreconditionFailed bool                   `protobuf:"varint,3,opt,name=precondition_failed,json=preconditionFailed,proto3" json:"precondition_failed,omitempty"` // A condition didn't hold or an op would overwrite a file
+	QuotaExceeded      bool                   `protobuf:"varint,4,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *TransactResponse) Reset() {
+	*x = TransactResponse{}
+	mi := &file_master_master_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactResponse) ProtoMessage() {}
+
+func (x *TransactResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactResponse.ProtoReflect.Descriptor instead.
+func (*TransactResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *TransactResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *TransactResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *TransactResponse) GetPreconditionFailed() bool {
+	if x != nil {
+		return x.PreconditionFailed
+	}
+	return false
+}
+
+func (x *TransactResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+// Adds or overwrites the keys in set, then deletes the keys in remove
+type SetFileAttributesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Set           map[string]string      `protobuf:"bytes,3,rep,name=set,proto3" json:"set,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Remove        []string               `protobuf:"bytes,4,rep,name=remove,proto3" json:"remove,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetFileAttributesRequest) Reset() {
+	*x = SetFileAttributesRequest{}
+	mi := &file_master_master_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetFileAttributesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetFileAttributesRequest) ProtoMessage() {}
+
+func (x *SetFileAttributesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetFileAttributesRequest.ProtoReflect.Descriptor instead.
+func (*SetFileAttributesRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *SetFileAttributesRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *SetFileAttributesRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SetFileAttributesRequest) GetSet() map[string]string {
+	if x != nil {
+		return x.Set
+	}
+	return nil
+}
+
+func (x *SetFileAttributesRequest) GetRemove() []string {
+	if x != nil {
+		return x.Remove
+	}
+	return nil
+}
+
+type SetFileAttributesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	File          *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetFileAttributesResponse) Reset() {
+	*x = SetFileAttributesResponse{}
+	mi := &file_master_master_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetFileAttributesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetFileAttributesResponse) ProtoMessage() {}
+
+func (x *SetFileAttributesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetFileAttributesResponse.ProtoReflect.Descriptor instead.
+func (*SetFileAttributesResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *SetFileAttributesResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetFileAttributesResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SetFileAttributesResponse) GetFile() *FileInfoResponse {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+// Watches changes to files under prefix in a namespace
+type WatchNamespaceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	ResumeToken   uint64                 `protobuf:"varint,3,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"` // Token of the last event handled; 0 starts from now
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchNamespaceRequest) Reset() {
+	*x = WatchNamespaceRequest{}
+	mi := &file_master_master_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchNamespaceRequest) ProtoMessage() {}
+
+func (x *WatchNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*WatchNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *WatchNamespaceRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *WatchNamespaceRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *WatchNamespaceRequest) GetResumeToken() uint64 {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return 0
+}
+
+type NamespaceEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ResumeToken   uint64                 `protobuf:"varint,1,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"` // Send back to resume after this event
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`                                   // CREATE, COMMIT, RENAME, DELETE, ATTRIBUTES or BOOKMARK
+	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Path          string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`                                  // Empty when the change covers the whole namespace
+	NewPath       string                 `protobuf:"bytes,5,opt,name=new_path,json=newPath,proto3" json:"new_path,omitempty"`             // RENAME only
+	ChunkHandle   string                 `protobuf:"bytes,6,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"` // COMMIT only
+	ChunkSize     uint64                 `protobuf:"varint,7,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`      // COMMIT only: committed size of the chunk
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NamespaceEvent) Reset() {
+	*x = NamespaceEvent{}
+	mi := &file_master_master_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NamespaceEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NamespaceEvent) ProtoMessage() {}
+
+func (x *NamespaceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NamespaceEvent.ProtoReflect.Descriptor instead.
+func (*NamespaceEvent) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *NamespaceEvent) GetResumeToken() uint64 {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return 0
+}
+
+func (x *NamespaceEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *NamespaceEvent) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *NamespaceEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *NamespaceEvent) GetNewPath() string {
+	if x != nil {
+		return x.NewPath
+	}
+	return ""
+}
+
+func (x *NamespaceEvent) GetChunkHandle() string {
+	if x != nil {
+		return x.ChunkHandle
+	}
+	return ""
+}
+
+func (x *NamespaceEvent) GetChunkSize() uint64 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+type ListFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Optional path prefix filter
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesRequest) Reset() {
+	*x = ListFilesRequest{}
+	mi := &file_master_master_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesRequest) ProtoMessage() {}
+
+func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
+func (*ListFilesRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ListFilesRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListFilesRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ListFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FileInfoResponse    `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesResponse) Reset() {
+	*x = ListFilesResponse{}
+	mi := &file_master_master_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesResponse) ProtoMessage() {}
+
+func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
+func (*ListFilesResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *ListFilesResponse) GetFiles() []*FileInfoResponse {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+// Paginated listing in path order. With a delimiter, paths that contain it after
+// the prefix are rolled up into common prefixes, like directories.
+type ListFilesV2Request struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`                        // Optional path prefix filter
+	Delimiter     string                 `protobuf:"bytes,3,opt,name=delimiter,proto3" json:"delimiter,omitempty"`                  // Optional, usually "/"
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                         // Max files plus common prefixes per page; 0 uses the server default
+	PageToken     string                 `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"` // next_page_token from the previous page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFilesV2Request) Reset() {
+	*x = ListFilesV2Request{}
+	mi := &file_master_master_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesV2Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesV2Request) ProtoMessage() {}
+
+func (x *ListFilesV2Request) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesV2Request.ProtoReflect.Descriptor instead.
+func (*ListFilesV2Request) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *ListFilesV2Request) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ListFilesV2Request) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListFilesV2Request) GetDelimiter() string {
+	if x != nil {
+		return x.Delimiter
+	}
+	return ""
+}
+
+func (x *ListFilesV2Request) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListFilesV2Request) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type ListFilesV2Response struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message        string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Files          []*FileInfoResponse    `protobuf:"bytes,3,rep,name=files,proto3" json:"files,omitempty"`
+	CommonPrefixes []string               `protobuf:"bytes,4,rep,name=common_prefixes,json=commonPrefixes,proto3" json:"common_prefixes,omitempty"`
+	NextPageToken  string                 `protobuf:"bytes,5,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Empty on the last page
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListFilesV2Response) Reset() {
+	*x = ListFilesV2Response{}
+	mi := &file_master_master_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFilesV2Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFilesV2Response) ProtoMessage() {}
+
+func (x *ListFilesV2Response) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFilesV2Response.ProtoReflect.Descriptor instead.
+func (*ListFilesV2Response) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *ListFilesV2Response) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ListFilesV2Response) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ListFilesV2Response) GetFiles() []*FileInfoResponse {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListFilesV2Response) GetCommonPrefixes() []string {
+	if x != nil {
+		return x.CommonPrefixes
+	}
+	return nil
+}
+
+func (x *ListFilesV2Response) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// Request a new chunk for writing to a file
+type AllocateChunkRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Path      string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Allocate only if the file is if_size bytes and doesn't end in an empty chunk
+	CheckSize     bool   `protobuf:"varint,3,opt,name=check_size,json=checkSize,proto3" json:"check_size,omitempty"`
+	IfSize        uint64 `protobuf:"varint,4,opt,name=if_size,json=ifSize,proto3" json:"if_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AllocateChunkRequest) Reset() {
+	*x = AllocateChunkRequest{}
+	mi := &file_master_master_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateChunkRequest) ProtoMessage() {}
+
+func (x *AllocateChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateChunkRequest.ProtoReflect.Descriptor instead.
+func (*AllocateChunkRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *AllocateChunkRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *AllocateChunkRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *AllocateChunkRequest) GetCheckSize() bool {
+	if x != nil {
+		return x.CheckSize
+	}
+	return false
+}
+
+func (x *AllocateChunkRequest) GetIfSize() uint64 {
+	if x != nil {
+		return x.IfSize
+	}
+	return 0
+}
+
+type AllocateChunkResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Success            bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message            string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Chunk              *ChunkLocationInfo     `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	QuotaExceeded      bool                   `protobuf:"varint,4,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"`                // Rejected by the namespace quota
+	PreconditionFailed bool                   `protobuf:"varint,5,opt,name=precondition_failed,json=preconditionFailed,proto3" json:"precondition_failed,omitempty"` // The file isn't if_size bytes
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *AllocateChunkResponse) Reset() {
+	*x = AllocateChunkResponse{}
+	mi := &file_master_master_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AllocateChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocateChunkResponse) ProtoMessage() {}
+
+func (x *AllocateChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocateChunkResponse.ProtoReflect.Descriptor instead.
+func (*AllocateChunkResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *AllocateChunkResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AllocateChunkResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *AllocateChunkResponse) GetChunk() *ChunkLocationInfo {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+func (x *AllocateChunkResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+func (x *AllocateChunkResponse) GetPreconditionFailed() bool {
+	if x != nil {
+		return x.PreconditionFailed
+	}
+	return false
+}
+
+// Get chunk locations for reading
+type GetChunkLocationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetChunkLocationsRequest) Reset() {
+	*x = GetChunkLocationsRequest{}
+	mi := &file_master_master_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetChunkLocationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChunkLocationsRequest) ProtoMessage() {}
+
+func (x *GetChunkLocationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChunkLocationsRequest.ProtoReflect.Descriptor instead.
+func (*GetChunkLocationsRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *GetChunkLocationsRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *GetChunkLocationsRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type GetChunkLocationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Chunks        []*ChunkLocationInfo   `protobuf:"bytes,3,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetChunkLocationsResponse) Reset() {
+	*x = GetChunkLocationsResponse{}
+	mi := &file_master_master_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetChunkLocationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetChunkLocationsResponse) ProtoMessage() {}
+
+func (x *GetChunkLocationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetChunkLocationsResponse.ProtoReflect.Descriptor instead.
+func (*GetChunkLocationsResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *GetChunkLocationsResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *GetChunkLocationsResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *GetChunkLocationsResponse) GetChunks() []*ChunkLocationInfo {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+// Copy-on-write snapshot of one file
+type SnapshotFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourcePath    string                 `protobuf:"bytes,1,opt,name=source_path,json=sourcePath,proto3" json:"source_path,omitempty"`
+	DestPath      string                 `protobuf:"bytes,2,opt,name=dest_path,json=destPath,proto3" json:"dest_path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	DestNamespace string                 `protobuf:"bytes,4,opt,name=dest_namespace,json=destNamespace,proto3" json:"dest_namespace,omitempty"` // Defaults to namespace
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotFileRequest) Reset() {
+	*x = SnapshotFileRequest{}
+	mi := &file_master_master_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotFileRequest) ProtoMessage() {}
+
+func (x *SnapshotFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotFileRequest.ProtoReflect.Descriptor instead.
+func (*SnapshotFileRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *SnapshotFileRequest) GetSourcePath() string {
+	if x != nil {
+		return x.SourcePath
+	}
+	return ""
+}
+
+func (x *SnapshotFileRequest) GetDestPath() string {
+	if x != nil {
+		return x.DestPath
+	}
+	return ""
+}
+
+func (x *SnapshotFileRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SnapshotFileRequest) GetDestNamespace() string {
+	if x != nil {
+		return x.DestNamespace
+	}
+	return ""
+}
+
+type SnapshotFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	File          *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	QuotaExceeded bool                   `protobuf:"varint,4,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"` // Rejected by the destination namespace quota
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotFileResponse) Reset() {
+	*x = SnapshotFileResponse{}
+	mi := &file_master_master_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotFileResponse) ProtoMessage() {}
+
+func (x *SnapshotFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotFileResponse.ProtoReflect.Descriptor instead.
+func (*SnapshotFileResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *SnapshotFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SnapshotFileResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SnapshotFileResponse) GetFile() *FileInfoResponse {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *SnapshotFileResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+// Copy-on-write snapshot of every file in a namespace into an empty namespace
+type SnapshotNamespaceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	DestNamespace string                 `protobuf:"bytes,2,opt,name=dest_namespace,json=destNamespace,proto3" json:"dest_namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotNamespaceRequest) Reset() {
+	*x = SnapshotNamespaceRequest{}
+	mi := &file_master_master_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotNamespaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotNamespaceRequest) ProtoMessage() {}
+
+func (x *SnapshotNamespaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotNamespaceRequest.ProtoReflect.Descriptor instead.
+func (*SnapshotNamespaceRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *SnapshotNamespaceRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SnapshotNamespaceRequest) GetDestNamespace() string {
+	if x != nil {
+		return x.DestNamespace
+	}
+	return ""
+}
+
+type SnapshotNamespaceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FilesCopied   int32                  `protobuf:"varint,3,opt,name=files_copied,json=filesCopied,proto3" json:"files_copied,omitempty"`
+	QuotaExceeded bool                   `protobuf:"varint,4,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"` // Rejected by the destination namespace quota
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotNamespaceResponse) Reset() {
+	*x = SnapshotNamespaceResponse{}
+	mi := &file_master_master_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotNamespaceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotNamespaceResponse) ProtoMessage() {}
+
+func (x *SnapshotNamespaceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotNamespaceResponse.ProtoReflect.Descriptor instead.
+func (*SnapshotNamespaceResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *SnapshotNamespaceResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SnapshotNamespaceResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SnapshotNamespaceResponse) GetFilesCopied() int32 {
+	if x != nil {
+		return x.FilesCopied
+	}
+	return 0
+}
+
+func (x *SnapshotNamespaceResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+// Deleted files kept in the trash until their retention expires
+type TrashEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrashId       string                 `protobuf:"bytes,1,opt,name=trash_id,json=trashId,proto3" json:"trash_id,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"` // Namespace the file was deleted from
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`           // Path the file was deleted from
+	Size          uint64                 `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	DeletedAt     int64                  `protobuf:"varint,5,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"` // Unix timestamp
+	ExpiresAt     int64                  `protobuf:"varint,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // Unix timestamp; 0 while the trash is disabled
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrashEntry) Reset() {
+	*x = TrashEntry{}
+	mi := &file_master_master_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrashEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrashEntry) ProtoMessage() {}
+
+func (x *TrashEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrashEntry.ProtoReflect.Descriptor instead.
+func (*TrashEntry) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *TrashEntry) GetTrashId() string {
+	if x != nil {
+		return x.TrashId
+	}
+	return ""
+}
+
+func (x *TrashEntry) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *TrashEntry) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *TrashEntry) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *TrashEntry) GetDeletedAt() int64 {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return 0
+}
+
+func (x *TrashEntry) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+type ListTrashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"` // Empty lists every namespace
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrashRequest) Reset() {
+	*x = ListTrashRequest{}
+	mi := &file_master_master_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrashRequest) ProtoMessage() {}
+
+func (x *ListTrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrashRequest.ProtoReflect.Descriptor instead.
+func (*ListTrashRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *ListTrashRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ListTrashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*TrashEntry          `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrashResponse) Reset() {
+	*x = ListTrashResponse{}
+	mi := &file_master_master_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrashResponse) ProtoMessage() {}
+
+func (x *ListTrashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrashResponse.ProtoReflect.Descriptor instead.
+func (*ListTrashResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *ListTrashResponse) GetEntries() []*TrashEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type RestoreFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrashId       string                 `protobuf:"bytes,1,opt,name=trash_id,json=trashId,proto3" json:"trash_id,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`           // Defaults to the path the file was deleted from
+	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"` // Defaults to the namespace the file was deleted from
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreFileRequest) Reset() {
+	*x = RestoreFileRequest{}
+	mi := &file_master_master_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreFileRequest) ProtoMessage() {}
+
+func (x *RestoreFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreFileRequest.ProtoReflect.Descriptor instead.
+func (*RestoreFileRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *RestoreFileRequest) GetTrashId() string {
+	if x != nil {
+		return x.TrashId
+	}
+	return ""
+}
+
+func (x *RestoreFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *RestoreFileRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type RestoreFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	File          *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	QuotaExceeded bool                   `protobuf:"varint,4,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreFileResponse) Reset() {
+	*x = RestoreFileResponse{}
+	mi := &file_master_master_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreFileResponse) ProtoMessage() {}
+
+func (x *RestoreFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreFileResponse.ProtoReflect.Descriptor instead.
+func (*RestoreFileResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *RestoreFileResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RestoreFileResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RestoreFileResponse) GetFile() *FileInfoResponse {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *RestoreFileResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+// Permanently deletes files from the trash: the listed entries, or everything
+// deleted from namespace, or the whole trash when both are empty
+type PurgeTrashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TrashIds      []string               `protobuf:"bytes,1,rep,name=trash_ids,json=trashIds,proto3" json:"trash_ids,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeTrashRequest) Reset() {
+	*x = PurgeTrashRequest{}
+	mi := &file_master_master_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeTrashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeTrashRequest) ProtoMessage() {}
+
+func (x *PurgeTrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeTrashRequest.ProtoReflect.Descriptor instead.
+func (*PurgeTrashRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{63}
+}
+
+func (x *PurgeTrashRequest) GetTrashIds() []string {
+	if x != nil {
+		return x.TrashIds
+	}
+	return nil
+}
+
+func (x *PurgeTrashRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type PurgeTrashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FilesPurged   int32                  `protobuf:"varint,3,opt,name=files_purged,json=filesPurged,proto3" json:"files_purged,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PurgeTrashResponse) Reset() {
+	*x = PurgeTrashResponse{}
+	mi := &file_master_master_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PurgeTrashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PurgeTrashResponse) ProtoMessage() {}
+
+func (x *PurgeTrashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PurgeTrashResponse.ProtoReflect.Descriptor instead.
+func (*PurgeTrashResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *PurgeTrashResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *PurgeTrashResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PurgeTrashResponse) GetFilesPurged() int32 {
+	if x != nil {
+		return x.FilesPurged
+	}
+	return 0
+}
+
+// Get a private copy of a shared chunk before writing to it
+type PrepareChunkWriteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	ChunkIndex    int32                  `protobuf:"varint,3,opt,name=chunk_index,json=chunkIndex,proto3" json:"chunk_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrepareChunkWriteRequest) Reset() {
+	*x = PrepareChunkWriteRequest{}
+	mi := &file_master_master_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrepareChunkWriteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrepareChunkWriteRequest) ProtoMessage() {}
+
+func (x *PrepareChunkWriteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrepareChunkWriteRequest.ProtoReflect.Descriptor instead.
+func (*PrepareChunkWriteRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *PrepareChunkWriteRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *PrepareChunkWriteRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *PrepareChunkWriteRequest) GetChunkIndex() int32 {
+	if x != nil {
+		return x.ChunkIndex
+	}
+	return 0
+}
+
+type PrepareChunkWriteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Chunk         *ChunkLocationInfo     `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrepareChunkWriteResponse) Reset() {
+	*x = PrepareChunkWriteResponse{}
+	mi := &file_master_master_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrepareChunkWriteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrepareChunkWriteResponse) ProtoMessage() {}
+
+func (x *PrepareChunkWriteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -534,50 +4524,56 @@ func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
-func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use PrepareChunkWriteResponse.ProtoReflect.Descriptor instead.
+func (*PrepareChunkWriteResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{66}
 }
 
-func (x *HeartbeatResponse) GetSuccess() bool {
+func (x *PrepareChunkWriteResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *HeartbeatResponse) GetChunksToDelete() []string {
+func (x *PrepareChunkWriteResponse) GetMessage() string {
 	if x != nil {
-		return x.ChunksToDelete
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PrepareChunkWriteResponse) GetChunk() *ChunkLocationInfo {
+	if x != nil {
+		return x.Chunk
 	}
 	return nil
 }
 
-// Report successful chunk commit
-type ReportCommitRequest struct {
+// Renews the data-plane grant for one chunk or fragment before it expires
+type IssueDataTokenRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
-	Size          uint64                 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"` // Bytes written
+	ChunkHandle   string                 `protobuf:"bytes,1,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Operation     string                 `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"` // "read" or "write"
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ReportCommitRequest) Reset() {
-	*x = ReportCommitRequest{}
-	mi := &file_master_master_proto_msgTypes[8]
+func (x *IssueDataTokenRequest) Reset() {
+	*x = IssueDataTokenRequest{}
+	mi := &file_master_master_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ReportCommitRequest) String() string {
+func (x *IssueDataTokenRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ReportCommitRequest) ProtoMessage() {}
+func (*IssueDataTokenRequest) ProtoMessage() {}
 
-func (x *ReportCommitRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[8]
+func (x *IssueDataTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -588,33 +4584,155 @@ func (x *ReportCommitRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ReportCommitRequest.ProtoReflect.Descriptor instead.
-func (*ReportCommitRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use IssueDataTokenRequest.ProtoReflect.Descriptor instead.
+func (*IssueDataTokenRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{67}
 }
 
-func (x *ReportCommitRequest) GetServerId() string {
+func (x *IssueDataTokenRequest) GetChunkHandle() string {
 	if x != nil {
-		return x.ServerId
+		return x.ChunkHandle
 	}
 	return ""
 }
 
-func (x *ReportCommitRequest) GetChunkHandle() string {
+func (x *IssueDataTokenRequest) GetOperation() string {
 	if x != nil {
-		return x.ChunkHandle
+		return x.Operation
 	}
 	return ""
 }
 
-func (x *ReportCommitRequest) GetSize() uint64 {
+type IssueDataTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Token         string                 `protobuf:"bytes,3,opt,name=token,proto3" json:"token,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // Unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IssueDataTokenResponse) Reset() {
+	*x = IssueDataTokenResponse{}
+	mi := &file_master_master_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IssueDataTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueDataTokenResponse) ProtoMessage() {}
+
+func (x *IssueDataTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[68]
 	if x != nil {
-		return x.Size
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueDataTokenResponse.ProtoReflect.Descriptor instead.
+func (*IssueDataTokenResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *IssueDataTokenResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *IssueDataTokenResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *IssueDataTokenResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *IssueDataTokenResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
 	}
 	return 0
 }
 
-type ReportCommitResponse struct {
+// Namespace quota; zero limits are unlimited
+type SetNamespaceQuotaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	MaxBytes      uint64                 `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxFiles      uint64                 `protobuf:"varint,3,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetNamespaceQuotaRequest) Reset() {
+	*x = SetNamespaceQuotaRequest{}
+	mi := &file_master_master_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetNamespaceQuotaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetNamespaceQuotaRequest) ProtoMessage() {}
+
+func (x *SetNamespaceQuotaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetNamespaceQuotaRequest.ProtoReflect.Descriptor instead.
+func (*SetNamespaceQuotaRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{69}
+}
+
+func (x *SetNamespaceQuotaRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SetNamespaceQuotaRequest) GetMaxBytes() uint64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *SetNamespaceQuotaRequest) GetMaxFiles() uint64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+type SetNamespaceQuotaResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
@@ -622,21 +4740,21 @@ type ReportCommitResponse struct {
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ReportCommitResponse) Reset() {
-	*x = ReportCommitResponse{}
-	mi := &file_master_master_proto_msgTypes[9]
+func (x *SetNamespaceQuotaResponse) Reset() {
+	*x = SetNamespaceQuotaResponse{}
+	mi := &file_master_master_proto_msgTypes[70]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ReportCommitResponse) String() string {
+func (x *SetNamespaceQuotaResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ReportCommitResponse) ProtoMessage() {}
+func (*SetNamespaceQuotaResponse) ProtoMessage() {}
 
-func (x *ReportCommitResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[9]
+func (x *SetNamespaceQuotaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[70]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -647,49 +4765,52 @@ func (x *ReportCommitResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ReportCommitResponse.ProtoReflect.Descriptor instead.
-func (*ReportCommitResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use SetNamespaceQuotaResponse.ProtoReflect.Descriptor instead.
+func (*SetNamespaceQuotaResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{70}
 }
 
-func (x *ReportCommitResponse) GetSuccess() bool {
+func (x *SetNamespaceQuotaResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *ReportCommitResponse) GetMessage() string {
+func (x *SetNamespaceQuotaResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-// Lease renewal request from primary chunkserver
-type RenewLeaseRequest struct {
+// Storage class of a file, or the default of a namespace when path is empty.
+// "cold" files have their sealed chunks erasure coded; an empty class inherits
+// the namespace's class for a file and resets a namespace to "replicated".
+type SetStorageClassRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	StorageClass  string                 `protobuf:"bytes,3,opt,name=storage_class,json=storageClass,proto3" json:"storage_class,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RenewLeaseRequest) Reset() {
-	*x = RenewLeaseRequest{}
-	mi := &file_master_master_proto_msgTypes[10]
+func (x *SetStorageClassRequest) Reset() {
+	*x = SetStorageClassRequest{}
+	mi := &file_master_master_proto_msgTypes[71]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RenewLeaseRequest) String() string {
+func (x *SetStorageClassRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RenewLeaseRequest) ProtoMessage() {}
+func (*SetStorageClassRequest) ProtoMessage() {}
 
-func (x *RenewLeaseRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[10]
+func (x *SetStorageClassRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[71]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -700,49 +4821,160 @@ func (x *RenewLeaseRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RenewLeaseRequest.ProtoReflect.Descriptor instead.
-func (*RenewLeaseRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use SetStorageClassRequest.ProtoReflect.Descriptor instead.
+func (*SetStorageClassRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{71}
 }
 
-func (x *RenewLeaseRequest) GetServerId() string {
+func (x *SetStorageClassRequest) GetNamespace() string {
 	if x != nil {
-		return x.ServerId
+		return x.Namespace
 	}
 	return ""
 }
 
-func (x *RenewLeaseRequest) GetChunkHandle() string {
+func (x *SetStorageClassRequest) GetPath() string {
 	if x != nil {
-		return x.ChunkHandle
+		return x.Path
 	}
 	return ""
 }
 
-type RenewLeaseResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	LeaseDurationMs uint64                 `protobuf:"varint,3,opt,name=lease_duration_ms,json=leaseDurationMs,proto3" json:"lease_duration_ms,omitempty"` // Duration until lease expires (in milliseconds)
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+func (x *SetStorageClassRequest) GetStorageClass() string {
+	if x != nil {
+		return x.StorageClass
+	}
+	return ""
+}
+
+type SetStorageClassResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetStorageClassResponse) Reset() {
+	*x = SetStorageClassResponse{}
+	mi := &file_master_master_proto_msgTypes[72]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetStorageClassResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetStorageClassResponse) ProtoMessage() {}
+
+func (x *SetStorageClassResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[72]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetStorageClassResponse.ProtoReflect.Descriptor instead.
+func (*SetStorageClassResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{72}
+}
+
+func (x *SetStorageClassResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetStorageClassResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Compress the sealed chunks of a namespace on the chunkservers; empty turns it off
+type SetCompressionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Compression   string                 `protobuf:"bytes,2,opt,name=compression,proto3" json:"compression,omitempty"` // "zstd" or empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCompressionRequest) Reset() {
+	*x = SetCompressionRequest{}
+	mi := &file_master_master_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCompressionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCompressionRequest) ProtoMessage() {}
+
+func (x *SetCompressionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[73]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCompressionRequest.ProtoReflect.Descriptor instead.
+func (*SetCompressionRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *SetCompressionRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *SetCompressionRequest) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+type SetCompressionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RenewLeaseResponse) Reset() {
-	*x = RenewLeaseResponse{}
-	mi := &file_master_master_proto_msgTypes[11]
+func (x *SetCompressionResponse) Reset() {
+	*x = SetCompressionResponse{}
+	mi := &file_master_master_proto_msgTypes[74]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RenewLeaseResponse) String() string {
+func (x *SetCompressionResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RenewLeaseResponse) ProtoMessage() {}
+func (*SetCompressionResponse) ProtoMessage() {}
 
-func (x *RenewLeaseResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[11]
+func (x *SetCompressionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[74]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -753,56 +4985,48 @@ func (x *RenewLeaseResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RenewLeaseResponse.ProtoReflect.Descriptor instead.
-func (*RenewLeaseResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use SetCompressionResponse.ProtoReflect.Descriptor instead.
+func (*SetCompressionResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{74}
 }
 
-func (x *RenewLeaseResponse) GetSuccess() bool {
+func (x *SetCompressionResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *RenewLeaseResponse) GetMessage() string {
+func (x *SetCompressionResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-func (x *RenewLeaseResponse) GetLeaseDurationMs() uint64 {
-	if x != nil {
-		return x.LeaseDurationMs
-	}
-	return 0
-}
-
-// Claim primary status at start of write operation
-type ClaimPrimaryRequest struct {
+// Live usage of one namespace, or of all namespaces when empty
+type GetNamespaceUsageRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
-	ChunkHandle   string                 `protobuf:"bytes,2,opt,name=chunk_handle,json=chunkHandle,proto3" json:"chunk_handle,omitempty"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ClaimPrimaryRequest) Reset() {
-	*x = ClaimPrimaryRequest{}
-	mi := &file_master_master_proto_msgTypes[12]
+func (x *GetNamespaceUsageRequest) Reset() {
+	*x = GetNamespaceUsageRequest{}
+	mi := &file_master_master_proto_msgTypes[75]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ClaimPrimaryRequest) String() string {
+func (x *GetNamespaceUsageRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ClaimPrimaryRequest) ProtoMessage() {}
+func (*GetNamespaceUsageRequest) ProtoMessage() {}
 
-func (x *ClaimPrimaryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[12]
+func (x *GetNamespaceUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[75]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -813,49 +5037,46 @@ func (x *ClaimPrimaryRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ClaimPrimaryRequest.ProtoReflect.Descriptor instead.
-func (*ClaimPrimaryRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{12}
-}
-
-func (x *ClaimPrimaryRequest) GetServerId() string {
-	if x != nil {
-		return x.ServerId
-	}
-	return ""
+// Deprecated: Use GetNamespaceUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetNamespaceUsageRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{75}
 }
 
-func (x *ClaimPrimaryRequest) GetChunkHandle() string {
+func (x *GetNamespaceUsageRequest) GetNamespace() string {
 	if x != nil {
-		return x.ChunkHandle
+		return x.Namespace
 	}
 	return ""
 }
 
-type ClaimPrimaryResponse struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Success         bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message         string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	LeaseDurationMs uint64                 `protobuf:"varint,3,opt,name=lease_duration_ms,json=leaseDurationMs,proto3" json:"lease_duration_ms,omitempty"` // Duration until lease expires (in milliseconds)
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type NamespaceUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	UsedBytes     uint64                 `protobuf:"varint,2,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"` // Committed file sizes, before replication
+	FileCount     uint64                 `protobuf:"varint,3,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	MaxBytes      uint64                 `protobuf:"varint,4,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`            // 0 when unlimited
+	MaxFiles      uint64                 `protobuf:"varint,5,opt,name=max_files,json=maxFiles,proto3" json:"max_files,omitempty"`            // 0 when unlimited
+	StorageClass  string                 `protobuf:"bytes,6,opt,name=storage_class,json=storageClass,proto3" json:"storage_class,omitempty"` // Default class of new files
+	Compression   string                 `protobuf:"bytes,7,opt,name=compression,proto3" json:"compression,omitempty"`                       // Compression of sealed chunks; empty when off
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ClaimPrimaryResponse) Reset() {
-	*x = ClaimPrimaryResponse{}
-	mi := &file_master_master_proto_msgTypes[13]
+func (x *NamespaceUsage) Reset() {
+	*x = NamespaceUsage{}
+	mi := &file_master_master_proto_msgTypes[76]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ClaimPrimaryResponse) String() string {
+func (x *NamespaceUsage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ClaimPrimaryResponse) ProtoMessage() {}
+func (*NamespaceUsage) ProtoMessage() {}
 
-func (x *ClaimPrimaryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[13]
+func (x *NamespaceUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[76]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -866,55 +5087,82 @@ func (x *ClaimPrimaryResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ClaimPrimaryResponse.ProtoReflect.Descriptor instead.
-func (*ClaimPrimaryResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use NamespaceUsage.ProtoReflect.Descriptor instead.
+func (*NamespaceUsage) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{76}
 }
 
-func (x *ClaimPrimaryResponse) GetSuccess() bool {
+func (x *NamespaceUsage) GetNamespace() string {
 	if x != nil {
-		return x.Success
+		return x.Namespace
 	}
-	return false
+	return ""
 }
 
-func (x *ClaimPrimaryResponse) GetMessage() string {
+func (x *NamespaceUsage) GetUsedBytes() uint64 {
 	if x != nil {
-		return x.Message
+		return x.UsedBytes
 	}
-	return ""
+	return 0
 }
 
-func (x *ClaimPrimaryResponse) GetLeaseDurationMs() uint64 {
+func (x *NamespaceUsage) GetFileCount() uint64 {
 	if x != nil {
-		return x.LeaseDurationMs
+		return x.FileCount
 	}
 	return 0
 }
 
-type CreateFileRequest struct {
+func (x *NamespaceUsage) GetMaxBytes() uint64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *NamespaceUsage) GetMaxFiles() uint64 {
+	if x != nil {
+		return x.MaxFiles
+	}
+	return 0
+}
+
+func (x *NamespaceUsage) GetStorageClass() string {
+	if x != nil {
+		return x.StorageClass
+	}
+	return ""
+}
+
+func (x *NamespaceUsage) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+type GetNamespaceUsageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Namespaces    []*NamespaceUsage      `protobuf:"bytes,1,rep,name=namespaces,proto3" json:"namespaces,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateFileRequest) Reset() {
-	*x = CreateFileRequest{}
-	mi := &file_master_master_proto_msgTypes[14]
+func (x *GetNamespaceUsageResponse) Reset() {
+	*x = GetNamespaceUsageResponse{}
+	mi := &file_master_master_proto_msgTypes[77]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateFileRequest) String() string {
+func (x *GetNamespaceUsageResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateFileRequest) ProtoMessage() {}
+func (*GetNamespaceUsageResponse) ProtoMessage() {}
 
-func (x *CreateFileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[14]
+func (x *GetNamespaceUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[77]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -925,49 +5173,55 @@ func (x *CreateFileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateFileRequest.ProtoReflect.Descriptor instead.
-func (*CreateFileRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{14}
-}
-
-func (x *CreateFileRequest) GetPath() string {
-	if x != nil {
-		return x.Path
-	}
-	return ""
+// Deprecated: Use GetNamespaceUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetNamespaceUsageResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{77}
 }
 
-func (x *CreateFileRequest) GetNamespace() string {
+func (x *GetNamespaceUsageResponse) GetNamespaces() []*NamespaceUsage {
 	if x != nil {
-		return x.Namespace
+		return x.Namespaces
 	}
-	return ""
+	return nil
 }
 
-type CreateFileResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	File          *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// Chunkserver status
+type ChunkServerStatus struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Server           *ChunkServerInfo       `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	ChunkCount       int32                  `protobuf:"varint,2,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"`          // Number of chunks on this server
+	IsAlive          bool                   `protobuf:"varint,3,opt,name=is_alive,json=isAlive,proto3" json:"is_alive,omitempty"`                   // Whether server is responding to heartbeats
+	BuildInfo        *BuildInfo             `protobuf:"bytes,4,opt,name=build_info,json=buildInfo,proto3" json:"build_info,omitempty"`              // Build information
+	FailureDomain    string                 `protobuf:"bytes,5,opt,name=failure_domain,json=failureDomain,proto3" json:"failure_domain,omitempty"`  // Placement spread label
+	Draining         bool                   `protobuf:"varint,6,opt,name=draining,proto3" json:"draining,omitempty"`                                // Being emptied for removal
+	Disks            []*DiskStatus          `protobuf:"bytes,7,rep,name=disks,proto3" json:"disks,omitempty"`                                       // Data directories from the last heartbeat
+	CapacityBytes    uint64                 `protobuf:"varint,8,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"` // Healthy data directories only
+	FreeBytes        uint64                 `protobuf:"varint,9,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
+	UsedBytes        uint64                 `protobuf:"varint,10,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`                  // Size of the chunks stored on this server
+	Load             *ServerLoad            `protobuf:"bytes,11,opt,name=load,proto3" json:"load,omitempty"`                                              // From the last heartbeat
+	LastHeartbeat    int64                  `protobuf:"varint,12,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`      // Unix timestamp
+	LastFullReport   int64                  `protobuf:"varint,13,opt,name=last_full_report,json=lastFullReport,proto3" json:"last_full_report,omitempty"` // Unix timestamp of the last full chunk report
+	LogicalBytes     uint64                 `protobuf:"varint,14,opt,name=logical_bytes,json=logicalBytes,proto3" json:"logical_bytes,omitempty"`         // Size of the chunks before compression; used_bytes is after
+	CompressedChunks int32                  `protobuf:"varint,15,opt,name=compressed_chunks,json=compressedChunks,proto3" json:"compressed_chunks,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *CreateFileResponse) Reset() {
-	*x = CreateFileResponse{}
-	mi := &file_master_master_proto_msgTypes[15]
+func (x *ChunkServerStatus) Reset() {
+	*x = ChunkServerStatus{}
+	mi := &file_master_master_proto_msgTypes[78]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateFileResponse) String() string {
+func (x *ChunkServerStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateFileResponse) ProtoMessage() {}
+func (*ChunkServerStatus) ProtoMessage() {}
 
-func (x *CreateFileResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[15]
+func (x *ChunkServerStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[78]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -978,108 +5232,179 @@ func (x *CreateFileResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateFileResponse.ProtoReflect.Descriptor instead.
-func (*CreateFileResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{15}
+// Deprecated: Use ChunkServerStatus.ProtoReflect.Descriptor instead.
+func (*ChunkServerStatus) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{78}
 }
 
-func (x *CreateFileResponse) GetSuccess() bool {
+func (x *ChunkServerStatus) GetServer() *ChunkServerInfo {
 	if x != nil {
-		return x.Success
+		return x.Server
+	}
+	return nil
+}
+
+func (x *ChunkServerStatus) GetChunkCount() int32 {
+	if x != nil {
+		return x.ChunkCount
+	}
+	return 0
+}
+
+func (x *ChunkServerStatus) GetIsAlive() bool {
+	if x != nil {
+		return x.IsAlive
 	}
 	return false
 }
 
-func (x *CreateFileResponse) GetMessage() string {
+func (x *ChunkServerStatus) GetBuildInfo() *BuildInfo {
 	if x != nil {
-		return x.Message
+		return x.BuildInfo
+	}
+	return nil
+}
+
+func (x *ChunkServerStatus) GetFailureDomain() string {
+	if x != nil {
+		return x.FailureDomain
 	}
 	return ""
 }
 
-func (x *CreateFileResponse) GetFile() *FileInfoResponse {
+func (x *ChunkServerStatus) GetDraining() bool {
 	if x != nil {
-		return x.File
+		return x.Draining
+	}
+	return false
+}
+
+func (x *ChunkServerStatus) GetDisks() []*DiskStatus {
+	if x != nil {
+		return x.Disks
 	}
 	return nil
 }
 
-type GetFileRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *ChunkServerStatus) GetCapacityBytes() uint64 {
+	if x != nil {
+		return x.CapacityBytes
+	}
+	return 0
 }
 
-func (x *GetFileRequest) Reset() {
-	*x = GetFileRequest{}
-	mi := &file_master_master_proto_msgTypes[16]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *ChunkServerStatus) GetFreeBytes() uint64 {
+	if x != nil {
+		return x.FreeBytes
+	}
+	return 0
 }
 
-func (x *GetFileRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ChunkServerStatus) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
 }
 
-func (*GetFileRequest) ProtoMessage() {}
+func (x *ChunkServerStatus) GetLoad() *ServerLoad {
+	if x != nil {
+		return x.Load
+	}
+	return nil
+}
 
-func (x *GetFileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[16]
+func (x *ChunkServerStatus) GetLastHeartbeat() int64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.LastHeartbeat
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use GetFileRequest.ProtoReflect.Descriptor instead.
-func (*GetFileRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{16}
+func (x *ChunkServerStatus) GetLastFullReport() int64 {
+	if x != nil {
+		return x.LastFullReport
+	}
+	return 0
 }
 
-func (x *GetFileRequest) GetPath() string {
+func (x *ChunkServerStatus) GetLogicalBytes() uint64 {
 	if x != nil {
-		return x.Path
+		return x.LogicalBytes
 	}
-	return ""
+	return 0
+}
+
+func (x *ChunkServerStatus) GetCompressedChunks() int32 {
+	if x != nil {
+		return x.CompressedChunks
+	}
+	return 0
+}
+
+// Cluster status request/response
+type GetClusterStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClusterStatusRequest) Reset() {
+	*x = GetClusterStatusRequest{}
+	mi := &file_master_master_proto_msgTypes[79]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClusterStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *GetFileRequest) GetNamespace() string {
+func (*GetClusterStatusRequest) ProtoMessage() {}
+
+func (x *GetClusterStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[79]
 	if x != nil {
-		return x.Namespace
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-type GetFileResponse struct {
+// Deprecated: Use GetClusterStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetClusterStatusRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{79}
+}
+
+type GetClusterStatusResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	File          *FileInfoResponse      `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	Servers       []*ChunkServerStatus   `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
+	CapacityBytes uint64                 `protobuf:"varint,2,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"` // Totals over live servers
+	FreeBytes     uint64                 `protobuf:"varint,3,opt,name=free_bytes,json=freeBytes,proto3" json:"free_bytes,omitempty"`
+	UsedBytes     uint64                 `protobuf:"varint,4,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`
+	LogicalBytes  uint64                 `protobuf:"varint,5,opt,name=logical_bytes,json=logicalBytes,proto3" json:"logical_bytes,omitempty"` // Before compression; logical / used is the compression ratio
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetFileResponse) Reset() {
-	*x = GetFileResponse{}
-	mi := &file_master_master_proto_msgTypes[17]
+func (x *GetClusterStatusResponse) Reset() {
+	*x = GetClusterStatusResponse{}
+	mi := &file_master_master_proto_msgTypes[80]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetFileResponse) String() string {
+func (x *GetClusterStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetFileResponse) ProtoMessage() {}
+func (*GetClusterStatusResponse) ProtoMessage() {}
 
-func (x *GetFileResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[17]
+func (x *GetClusterStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[80]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1090,55 +5415,70 @@ func (x *GetFileResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetFileResponse.ProtoReflect.Descriptor instead.
-func (*GetFileResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use GetClusterStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetClusterStatusResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{80}
 }
 
-func (x *GetFileResponse) GetSuccess() bool {
+func (x *GetClusterStatusResponse) GetServers() []*ChunkServerStatus {
 	if x != nil {
-		return x.Success
+		return x.Servers
 	}
-	return false
+	return nil
 }
 
-func (x *GetFileResponse) GetMessage() string {
+func (x *GetClusterStatusResponse) GetCapacityBytes() uint64 {
 	if x != nil {
-		return x.Message
+		return x.CapacityBytes
 	}
-	return ""
+	return 0
 }
 
-func (x *GetFileResponse) GetFile() *FileInfoResponse {
+func (x *GetClusterStatusResponse) GetFreeBytes() uint64 {
 	if x != nil {
-		return x.File
+		return x.FreeBytes
 	}
-	return nil
+	return 0
 }
 
-type DeleteFileRequest struct {
+func (x *GetClusterStatusResponse) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *GetClusterStatusResponse) GetLogicalBytes() uint64 {
+	if x != nil {
+		return x.LogicalBytes
+	}
+	return 0
+}
+
+// Drain a chunkserver before removing it, or return it to service
+type DrainChunkServerRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	Cancel        bool                   `protobuf:"varint,2,opt,name=cancel,proto3" json:"cancel,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteFileRequest) Reset() {
-	*x = DeleteFileRequest{}
-	mi := &file_master_master_proto_msgTypes[18]
+func (x *DrainChunkServerRequest) Reset() {
+	*x = DrainChunkServerRequest{}
+	mi := &file_master_master_proto_msgTypes[81]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteFileRequest) String() string {
+func (x *DrainChunkServerRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteFileRequest) ProtoMessage() {}
+func (*DrainChunkServerRequest) ProtoMessage() {}
 
-func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[18]
+func (x *DrainChunkServerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[81]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1149,26 +5489,26 @@ func (x *DeleteFileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteFileRequest.ProtoReflect.Descriptor instead.
-func (*DeleteFileRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use DrainChunkServerRequest.ProtoReflect.Descriptor instead.
+func (*DrainChunkServerRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{81}
 }
 
-func (x *DeleteFileRequest) GetPath() string {
+func (x *DrainChunkServerRequest) GetServerId() string {
 	if x != nil {
-		return x.Path
+		return x.ServerId
 	}
 	return ""
 }
 
-func (x *DeleteFileRequest) GetNamespace() string {
+func (x *DrainChunkServerRequest) GetCancel() bool {
 	if x != nil {
-		return x.Namespace
+		return x.Cancel
 	}
-	return ""
+	return false
 }
 
-type DeleteFileResponse struct {
+type DrainChunkServerResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
@@ -1176,21 +5516,21 @@ type DeleteFileResponse struct {
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteFileResponse) Reset() {
-	*x = DeleteFileResponse{}
-	mi := &file_master_master_proto_msgTypes[19]
+func (x *DrainChunkServerResponse) Reset() {
+	*x = DrainChunkServerResponse{}
+	mi := &file_master_master_proto_msgTypes[82]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteFileResponse) String() string {
+func (x *DrainChunkServerResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteFileResponse) ProtoMessage() {}
+func (*DrainChunkServerResponse) ProtoMessage() {}
 
-func (x *DeleteFileResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[19]
+func (x *DrainChunkServerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[82]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1201,47 +5541,48 @@ func (x *DeleteFileResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteFileResponse.ProtoReflect.Descriptor instead.
-func (*DeleteFileResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{19}
+// Deprecated: Use DrainChunkServerResponse.ProtoReflect.Descriptor instead.
+func (*DrainChunkServerResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{82}
 }
 
-func (x *DeleteFileResponse) GetSuccess() bool {
+func (x *DrainChunkServerResponse) GetSuccess() bool {
 	if x != nil {
 		return x.Success
 	}
 	return false
 }
 
-func (x *DeleteFileResponse) GetMessage() string {
+func (x *DrainChunkServerResponse) GetMessage() string {
 	if x != nil {
 		return x.Message
 	}
 	return ""
 }
 
-type DeleteNamespaceRequest struct {
+// Drain progress of one server, or of all draining servers when empty
+type GetDrainStatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteNamespaceRequest) Reset() {
-	*x = DeleteNamespaceRequest{}
-	mi := &file_master_master_proto_msgTypes[20]
+func (x *GetDrainStatusRequest) Reset() {
+	*x = GetDrainStatusRequest{}
+	mi := &file_master_master_proto_msgTypes[83]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteNamespaceRequest) String() string {
+func (x *GetDrainStatusRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteNamespaceRequest) ProtoMessage() {}
+func (*GetDrainStatusRequest) ProtoMessage() {}
 
-func (x *DeleteNamespaceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[20]
+func (x *GetDrainStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[83]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1252,42 +5593,46 @@ func (x *DeleteNamespaceRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteNamespaceRequest.ProtoReflect.Descriptor instead.
-func (*DeleteNamespaceRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{20}
+// Deprecated: Use GetDrainStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetDrainStatusRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{83}
 }
 
-func (x *DeleteNamespaceRequest) GetNamespace() string {
+func (x *GetDrainStatusRequest) GetServerId() string {
 	if x != nil {
-		return x.Namespace
+		return x.ServerId
 	}
 	return ""
 }
 
-type DeleteNamespaceResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	FilesDeleted  int32                  `protobuf:"varint,3,opt,name=files_deleted,json=filesDeleted,proto3" json:"files_deleted,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type DrainStatus struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ServerId        string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	IsAlive         bool                   `protobuf:"varint,2,opt,name=is_alive,json=isAlive,proto3" json:"is_alive,omitempty"`
+	StartedAt       int64                  `protobuf:"varint,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"` // Unix seconds
+	MovedChunks     int32                  `protobuf:"varint,4,opt,name=moved_chunks,json=movedChunks,proto3" json:"moved_chunks,omitempty"`
+	RemainingChunks int32                  `protobuf:"varint,5,opt,name=remaining_chunks,json=remainingChunks,proto3" json:"remaining_chunks,omitempty"` // Replicas still on the server
+	InflightMoves   int32                  `protobuf:"varint,6,opt,name=inflight_moves,json=inflightMoves,proto3" json:"inflight_moves,omitempty"`
+	Complete        bool                   `protobuf:"varint,7,opt,name=complete,proto3" json:"complete,omitempty"` // Nothing left; the server can be removed
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *DeleteNamespaceResponse) Reset() {
-	*x = DeleteNamespaceResponse{}
-	mi := &file_master_master_proto_msgTypes[21]
+func (x *DrainStatus) Reset() {
+	*x = DrainStatus{}
+	mi := &file_master_master_proto_msgTypes[84]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteNamespaceResponse) String() string {
+func (x *DrainStatus) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteNamespaceResponse) ProtoMessage() {}
+func (*DrainStatus) ProtoMessage() {}
 
-func (x *DeleteNamespaceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[21]
+func (x *DrainStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[84]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1298,56 +5643,84 @@ func (x *DeleteNamespaceResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteNamespaceResponse.ProtoReflect.Descriptor instead.
-func (*DeleteNamespaceResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{21}
+// Deprecated: Use DrainStatus.ProtoReflect.Descriptor instead.
+func (*DrainStatus) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{84}
 }
 
-func (x *DeleteNamespaceResponse) GetSuccess() bool {
+func (x *DrainStatus) GetServerId() string {
 	if x != nil {
-		return x.Success
+		return x.ServerId
+	}
+	return ""
+}
+
+func (x *DrainStatus) GetIsAlive() bool {
+	if x != nil {
+		return x.IsAlive
 	}
 	return false
 }
 
-func (x *DeleteNamespaceResponse) GetMessage() string {
+func (x *DrainStatus) GetStartedAt() int64 {
 	if x != nil {
-		return x.Message
+		return x.StartedAt
 	}
-	return ""
+	return 0
 }
 
-func (x *DeleteNamespaceResponse) GetFilesDeleted() int32 {
+func (x *DrainStatus) GetMovedChunks() int32 {
 	if x != nil {
-		return x.FilesDeleted
+		return x.MovedChunks
 	}
 	return 0
 }
 
-type RenameFileRequest struct {
+func (x *DrainStatus) GetRemainingChunks() int32 {
+	if x != nil {
+		return x.RemainingChunks
+	}
+	return 0
+}
+
+func (x *DrainStatus) GetInflightMoves() int32 {
+	if x != nil {
+		return x.InflightMoves
+	}
+	return 0
+}
+
+func (x *DrainStatus) GetComplete() bool {
+	if x != nil {
+		return x.Complete
+	}
+	return false
+}
+
+type GetDrainStatusResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	OldPath       string                 `protobuf:"bytes,1,opt,name=old_path,json=oldPath,proto3" json:"old_path,omitempty"`
-	NewPath       string                 `protobuf:"bytes,2,opt,name=new_path,json=newPath,proto3" json:"new_path,omitempty"`
-	Namespace     string                 `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Servers       []*DrainStatus         `protobuf:"bytes,3,rep,name=servers,proto3" json:"servers,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RenameFileRequest) Reset() {
-	*x = RenameFileRequest{}
-	mi := &file_master_master_proto_msgTypes[22]
+func (x *GetDrainStatusResponse) Reset() {
+	*x = GetDrainStatusResponse{}
+	mi := &file_master_master_proto_msgTypes[85]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RenameFileRequest) String() string {
+func (x *GetDrainStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RenameFileRequest) ProtoMessage() {}
+func (*GetDrainStatusResponse) ProtoMessage() {}
 
-func (x *RenameFileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[22]
+func (x *GetDrainStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[85]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1358,55 +5731,56 @@ func (x *RenameFileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RenameFileRequest.ProtoReflect.Descriptor instead.
-func (*RenameFileRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{22}
+// Deprecated: Use GetDrainStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetDrainStatusResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{85}
 }
 
-func (x *RenameFileRequest) GetOldPath() string {
+func (x *GetDrainStatusResponse) GetSuccess() bool {
 	if x != nil {
-		return x.OldPath
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-func (x *RenameFileRequest) GetNewPath() string {
+func (x *GetDrainStatusResponse) GetMessage() string {
 	if x != nil {
-		return x.NewPath
+		return x.Message
 	}
 	return ""
 }
 
-func (x *RenameFileRequest) GetNamespace() string {
+func (x *GetDrainStatusResponse) GetServers() []*DrainStatus {
 	if x != nil {
-		return x.Namespace
+		return x.Servers
 	}
-	return ""
+	return nil
 }
 
-type RenameFileResponse struct {
+// Move replicas from the fullest servers to the emptiest until within threshold
+type RebalanceRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,1,opt,name=threshold,proto3" json:"threshold,omitempty"` // Max fill ratio spread, 0 < threshold < 1; 0 uses 0.10
+	Cancel        bool                   `protobuf:"varint,2,opt,name=cancel,proto3" json:"cancel,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *RenameFileResponse) Reset() {
-	*x = RenameFileResponse{}
-	mi := &file_master_master_proto_msgTypes[23]
+func (x *RebalanceRequest) Reset() {
+	*x = RebalanceRequest{}
+	mi := &file_master_master_proto_msgTypes[86]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *RenameFileResponse) String() string {
+func (x *RebalanceRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*RenameFileResponse) ProtoMessage() {}
+func (*RebalanceRequest) ProtoMessage() {}
 
-func (x *RenameFileResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[23]
+func (x *RebalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[86]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1417,48 +5791,48 @@ func (x *RenameFileResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use RenameFileResponse.ProtoReflect.Descriptor instead.
-func (*RenameFileResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{23}
+// Deprecated: Use RebalanceRequest.ProtoReflect.Descriptor instead.
+func (*RebalanceRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{86}
 }
 
-func (x *RenameFileResponse) GetSuccess() bool {
+func (x *RebalanceRequest) GetThreshold() float64 {
 	if x != nil {
-		return x.Success
+		return x.Threshold
 	}
-	return false
+	return 0
 }
 
-func (x *RenameFileResponse) GetMessage() string {
+func (x *RebalanceRequest) GetCancel() bool {
 	if x != nil {
-		return x.Message
+		return x.Cancel
 	}
-	return ""
+	return false
 }
 
-type ListFilesRequest struct {
+type RebalanceResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Optional path prefix filter
-	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListFilesRequest) Reset() {
-	*x = ListFilesRequest{}
-	mi := &file_master_master_proto_msgTypes[24]
+func (x *RebalanceResponse) Reset() {
+	*x = RebalanceResponse{}
+	mi := &file_master_master_proto_msgTypes[87]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListFilesRequest) String() string {
+func (x *RebalanceResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListFilesRequest) ProtoMessage() {}
+func (*RebalanceResponse) ProtoMessage() {}
 
-func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[24]
+func (x *RebalanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[87]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1469,47 +5843,46 @@ func (x *ListFilesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListFilesRequest.ProtoReflect.Descriptor instead.
-func (*ListFilesRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{24}
+// Deprecated: Use RebalanceResponse.ProtoReflect.Descriptor instead.
+func (*RebalanceResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{87}
 }
 
-func (x *ListFilesRequest) GetPrefix() string {
+func (x *RebalanceResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Prefix
+		return x.Success
 	}
-	return ""
+	return false
 }
 
-func (x *ListFilesRequest) GetNamespace() string {
+func (x *RebalanceResponse) GetMessage() string {
 	if x != nil {
-		return x.Namespace
+		return x.Message
 	}
 	return ""
 }
 
-type ListFilesResponse struct {
+type GetRebalanceStatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Files         []*FileInfoResponse    `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListFilesResponse) Reset() {
-	*x = ListFilesResponse{}
-	mi := &file_master_master_proto_msgTypes[25]
+func (x *GetRebalanceStatusRequest) Reset() {
+	*x = GetRebalanceStatusRequest{}
+	mi := &file_master_master_proto_msgTypes[88]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListFilesResponse) String() string {
+func (x *GetRebalanceStatusRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListFilesResponse) ProtoMessage() {}
+func (*GetRebalanceStatusRequest) ProtoMessage() {}
 
-func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[25]
+func (x *GetRebalanceStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[88]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1520,42 +5893,34 @@ func (x *ListFilesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListFilesResponse.ProtoReflect.Descriptor instead.
-func (*ListFilesResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{25}
-}
-
-func (x *ListFilesResponse) GetFiles() []*FileInfoResponse {
-	if x != nil {
-		return x.Files
-	}
-	return nil
-}
-
-// Request a new chunk for writing to a file
-type AllocateChunkRequest struct {
+// Deprecated: Use GetRebalanceStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetRebalanceStatusRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{88}
+}
+
+type ServerFill struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	ServerId      string                 `protobuf:"bytes,1,opt,name=server_id,json=serverId,proto3" json:"server_id,omitempty"`
+	FillRatio     float64                `protobuf:"fixed64,2,opt,name=fill_ratio,json=fillRatio,proto3" json:"fill_ratio,omitempty"` // Used fraction of disk capacity
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AllocateChunkRequest) Reset() {
-	*x = AllocateChunkRequest{}
-	mi := &file_master_master_proto_msgTypes[26]
+func (x *ServerFill) Reset() {
+	*x = ServerFill{}
+	mi := &file_master_master_proto_msgTypes[89]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AllocateChunkRequest) String() string {
+func (x *ServerFill) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AllocateChunkRequest) ProtoMessage() {}
+func (*ServerFill) ProtoMessage() {}
 
-func (x *AllocateChunkRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[26]
+func (x *ServerFill) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[89]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1566,49 +5931,53 @@ func (x *AllocateChunkRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AllocateChunkRequest.ProtoReflect.Descriptor instead.
-func (*AllocateChunkRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{26}
+// Deprecated: Use ServerFill.ProtoReflect.Descriptor instead.
+func (*ServerFill) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{89}
 }
 
-func (x *AllocateChunkRequest) GetPath() string {
+func (x *ServerFill) GetServerId() string {
 	if x != nil {
-		return x.Path
+		return x.ServerId
 	}
 	return ""
 }
 
-func (x *AllocateChunkRequest) GetNamespace() string {
+func (x *ServerFill) GetFillRatio() float64 {
 	if x != nil {
-		return x.Namespace
+		return x.FillRatio
 	}
-	return ""
+	return 0
 }
 
-type AllocateChunkResponse struct {
+type GetRebalanceStatusResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	Chunk         *ChunkLocationInfo     `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Active        bool                   `protobuf:"varint,1,opt,name=active,proto3" json:"active,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	StartedAt     int64                  `protobuf:"varint,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"` // Unix seconds, 0 when idle
+	MovedChunks   int32                  `protobuf:"varint,4,opt,name=moved_chunks,json=movedChunks,proto3" json:"moved_chunks,omitempty"`
+	InflightMoves int32                  `protobuf:"varint,5,opt,name=inflight_moves,json=inflightMoves,proto3" json:"inflight_moves,omitempty"`
+	Spread        float64                `protobuf:"fixed64,6,opt,name=spread,proto3" json:"spread,omitempty"` // Fullest minus emptiest fill ratio
+	Servers       []*ServerFill          `protobuf:"bytes,7,rep,name=servers,proto3" json:"servers,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AllocateChunkResponse) Reset() {
-	*x = AllocateChunkResponse{}
-	mi := &file_master_master_proto_msgTypes[27]
+func (x *GetRebalanceStatusResponse) Reset() {
+	*x = GetRebalanceStatusResponse{}
+	mi := &file_master_master_proto_msgTypes[90]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AllocateChunkResponse) String() string {
+func (x *GetRebalanceStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AllocateChunkResponse) ProtoMessage() {}
+func (*GetRebalanceStatusResponse) ProtoMessage() {}
 
-func (x *AllocateChunkResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[27]
+func (x *GetRebalanceStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[90]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1619,56 +5988,84 @@ func (x *AllocateChunkResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AllocateChunkResponse.ProtoReflect.Descriptor instead.
-func (*AllocateChunkResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{27}
+// Deprecated: Use GetRebalanceStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetRebalanceStatusResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{90}
 }
 
-func (x *AllocateChunkResponse) GetSuccess() bool {
+func (x *GetRebalanceStatusResponse) GetActive() bool {
 	if x != nil {
-		return x.Success
+		return x.Active
 	}
 	return false
 }
 
-func (x *AllocateChunkResponse) GetMessage() string {
+func (x *GetRebalanceStatusResponse) GetThreshold() float64 {
 	if x != nil {
-		return x.Message
+		return x.Threshold
 	}
-	return ""
+	return 0
 }
 
-func (x *AllocateChunkResponse) GetChunk() *ChunkLocationInfo {
+func (x *GetRebalanceStatusResponse) GetStartedAt() int64 {
 	if x != nil {
-		return x.Chunk
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *GetRebalanceStatusResponse) GetMovedChunks() int32 {
+	if x != nil {
+		return x.MovedChunks
+	}
+	return 0
+}
+
+func (x *GetRebalanceStatusResponse) GetInflightMoves() int32 {
+	if x != nil {
+		return x.InflightMoves
+	}
+	return 0
+}
+
+func (x *GetRebalanceStatusResponse) GetSpread() float64 {
+	if x != nil {
+		return x.Spread
+	}
+	return 0
+}
+
+func (x *GetRebalanceStatusResponse) GetServers() []*ServerFill {
+	if x != nil {
+		return x.Servers
 	}
 	return nil
 }
 
-// Get chunk locations for reading
-type GetChunkLocationsRequest struct {
+// Master replica
+type MasterReplica struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
-	Namespace     string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetChunkLocationsRequest) Reset() {
-	*x = GetChunkLocationsRequest{}
-	mi := &file_master_master_proto_msgTypes[28]
+func (x *MasterReplica) Reset() {
+	*x = MasterReplica{}
+	mi := &file_master_master_proto_msgTypes[91]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetChunkLocationsRequest) String() string {
+func (x *MasterReplica) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetChunkLocationsRequest) ProtoMessage() {}
+func (*MasterReplica) ProtoMessage() {}
 
-func (x *GetChunkLocationsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[28]
+func (x *MasterReplica) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[91]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1679,49 +6076,47 @@ func (x *GetChunkLocationsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetChunkLocationsRequest.ProtoReflect.Descriptor instead.
-func (*GetChunkLocationsRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{28}
+// Deprecated: Use MasterReplica.ProtoReflect.Descriptor instead.
+func (*MasterReplica) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{91}
 }
 
-func (x *GetChunkLocationsRequest) GetPath() string {
+func (x *MasterReplica) GetId() uint64 {
 	if x != nil {
-		return x.Path
+		return x.Id
 	}
-	return ""
+	return 0
 }
 
-func (x *GetChunkLocationsRequest) GetNamespace() string {
+func (x *MasterReplica) GetAddress() string {
 	if x != nil {
-		return x.Namespace
+		return x.Address
 	}
 	return ""
 }
 
-type GetChunkLocationsResponse struct {
+// Leader lookup request/response (answered by any replica)
+type GetLeaderRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	Chunks        []*ChunkLocationInfo   `protobuf:"bytes,3,rep,name=chunks,proto3" json:"chunks,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetChunkLocationsResponse) Reset() {
-	*x = GetChunkLocationsResponse{}
-	mi := &file_master_master_proto_msgTypes[29]
+func (x *GetLeaderRequest) Reset() {
+	*x = GetLeaderRequest{}
+	mi := &file_master_master_proto_msgTypes[92]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetChunkLocationsResponse) String() string {
+func (x *GetLeaderRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetChunkLocationsResponse) ProtoMessage() {}
+func (*GetLeaderRequest) ProtoMessage() {}
 
-func (x *GetChunkLocationsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[29]
+func (x *GetLeaderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[92]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1732,58 +6127,37 @@ func (x *GetChunkLocationsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetChunkLocationsResponse.ProtoReflect.Descriptor instead.
-func (*GetChunkLocationsResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{29}
-}
-
-func (x *GetChunkLocationsResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *GetChunkLocationsResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
-}
-
-func (x *GetChunkLocationsResponse) GetChunks() []*ChunkLocationInfo {
-	if x != nil {
-		return x.Chunks
-	}
-	return nil
+// Deprecated: Use GetLeaderRequest.ProtoReflect.Descriptor instead.
+func (*GetLeaderRequest) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{92}
 }
 
-// Chunkserver status
-type ChunkServerStatus struct {
+type GetLeaderResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Server        *ChunkServerInfo       `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
-	ChunkCount    int32                  `protobuf:"varint,2,opt,name=chunk_count,json=chunkCount,proto3" json:"chunk_count,omitempty"` // Number of chunks on this server
-	IsAlive       bool                   `protobuf:"varint,3,opt,name=is_alive,json=isAlive,proto3" json:"is_alive,omitempty"`          // Whether server is responding to heartbeats
-	BuildInfo     *BuildInfo             `protobuf:"bytes,4,opt,name=build_info,json=buildInfo,proto3" json:"build_info,omitempty"`     // Build information
+	Replicated    bool                   `protobuf:"varint,1,opt,name=replicated,proto3" json:"replicated,omitempty"`             // False for a standalone master
+	LeaderId      uint64                 `protobuf:"varint,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"` // 0 while an election is in progress
+	LeaderAddress string                 `protobuf:"bytes,3,opt,name=leader_address,json=leaderAddress,proto3" json:"leader_address,omitempty"`
+	ReplicaId     uint64                 `protobuf:"varint,4,opt,name=replica_id,json=replicaId,proto3" json:"replica_id,omitempty"` // Replica that answered
+	Replicas      []*MasterReplica       `protobuf:"bytes,5,rep,name=replicas,proto3" json:"replicas,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ChunkServerStatus) Reset() {
-	*x = ChunkServerStatus{}
-	mi := &file_master_master_proto_msgTypes[30]
+func (x *GetLeaderResponse) Reset() {
+	*x = GetLeaderResponse{}
+	mi := &file_master_master_proto_msgTypes[93]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ChunkServerStatus) String() string {
+func (x *GetLeaderResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ChunkServerStatus) ProtoMessage() {}
+func (*GetLeaderResponse) ProtoMessage() {}
 
-func (x *ChunkServerStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[30]
+func (x *GetLeaderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[93]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1794,61 +6168,69 @@ func (x *ChunkServerStatus) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ChunkServerStatus.ProtoReflect.Descriptor instead.
-func (*ChunkServerStatus) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{30}
+// Deprecated: Use GetLeaderResponse.ProtoReflect.Descriptor instead.
+func (*GetLeaderResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{93}
 }
 
-func (x *ChunkServerStatus) GetServer() *ChunkServerInfo {
+func (x *GetLeaderResponse) GetReplicated() bool {
 	if x != nil {
-		return x.Server
+		return x.Replicated
 	}
-	return nil
+	return false
 }
 
-func (x *ChunkServerStatus) GetChunkCount() int32 {
+func (x *GetLeaderResponse) GetLeaderId() uint64 {
 	if x != nil {
-		return x.ChunkCount
+		return x.LeaderId
 	}
 	return 0
 }
 
-func (x *ChunkServerStatus) GetIsAlive() bool {
+func (x *GetLeaderResponse) GetLeaderAddress() string {
 	if x != nil {
-		return x.IsAlive
+		return x.LeaderAddress
 	}
-	return false
+	return ""
 }
 
-func (x *ChunkServerStatus) GetBuildInfo() *BuildInfo {
+func (x *GetLeaderResponse) GetReplicaId() uint64 {
 	if x != nil {
-		return x.BuildInfo
+		return x.ReplicaId
+	}
+	return 0
+}
+
+func (x *GetLeaderResponse) GetReplicas() []*MasterReplica {
+	if x != nil {
+		return x.Replicas
 	}
 	return nil
 }
 
-// Cluster status request/response
-type GetClusterStatusRequest struct {
+// Consensus message between master replicas
+type RaftMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"` // Marshaled raftpb.Message
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetClusterStatusRequest) Reset() {
-	*x = GetClusterStatusRequest{}
-	mi := &file_master_master_proto_msgTypes[31]
+func (x *RaftMessage) Reset() {
+	*x = RaftMessage{}
+	mi := &file_master_master_proto_msgTypes[94]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetClusterStatusRequest) String() string {
+func (x *RaftMessage) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetClusterStatusRequest) ProtoMessage() {}
+func (*RaftMessage) ProtoMessage() {}
 
-func (x *GetClusterStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[31]
+func (x *RaftMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[94]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1859,33 +6241,39 @@ func (x *GetClusterStatusRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetClusterStatusRequest.ProtoReflect.Descriptor instead.
-func (*GetClusterStatusRequest) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{31}
+// Deprecated: Use RaftMessage.ProtoReflect.Descriptor instead.
+func (*RaftMessage) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{94}
 }
 
-type GetClusterStatusResponse struct {
+func (x *RaftMessage) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type RaftMessageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Servers       []*ChunkServerStatus   `protobuf:"bytes,1,rep,name=servers,proto3" json:"servers,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetClusterStatusResponse) Reset() {
-	*x = GetClusterStatusResponse{}
-	mi := &file_master_master_proto_msgTypes[32]
+func (x *RaftMessageResponse) Reset() {
+	*x = RaftMessageResponse{}
+	mi := &file_master_master_proto_msgTypes[95]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetClusterStatusResponse) String() string {
+func (x *RaftMessageResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetClusterStatusResponse) ProtoMessage() {}
+func (*RaftMessageResponse) ProtoMessage() {}
 
-func (x *GetClusterStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_master_master_proto_msgTypes[32]
+func (x *RaftMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_master_master_proto_msgTypes[95]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1896,16 +6284,9 @@ func (x *GetClusterStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetClusterStatusResponse.ProtoReflect.Descriptor instead.
-func (*GetClusterStatusResponse) Descriptor() ([]byte, []int) {
-	return file_master_master_proto_rawDescGZIP(), []int{32}
-}
-
-func (x *GetClusterStatusResponse) GetServers() []*ChunkServerStatus {
-	if x != nil {
-		return x.Servers
-	}
-	return nil
+// Deprecated: Use RaftMessageResponse.ProtoReflect.Descriptor instead.
+func (*RaftMessageResponse) Descriptor() ([]byte, []int) {
+	return file_master_master_proto_rawDescGZIP(), []int{95}
 }
 
 var File_master_master_proto protoreflect.FileDescriptor
@@ -1921,13 +6302,32 @@ const file_master_master_proto_rawDesc = "" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x1b\n" +
 	"\tdata_port\x18\x03 \x01(\x05R\bdataPort\x12)\n" +
-	"\x10replication_port\x18\x04 \x01(\x05R\x0freplicationPort\"\xd4\x01\n" +
+	"\x10replication_port\x18\x04 \x01(\x05R\x0freplicationPort\"\xdc\x02\n" +
 	"\x11ChunkLocationInfo\x12!\n" +
 	"\fchunk_handle\x18\x01 \x01(\tR\vchunkHandle\x128\n" +
 	"\tlocations\x18\x02 \x03(\v2\x1a.master.v1.ChunkServerInfoR\tlocations\x124\n" +
 	"\aprimary\x18\x03 \x01(\v2\x1a.master.v1.ChunkServerInfoR\aprimary\x12\x18\n" +
 	"\aversion\x18\x04 \x01(\x04R\aversion\x12\x12\n" +
-	"\x04size\x18\x05 \x01(\x04R\x04size\"\xdc\x01\n" +
+	"\x04size\x18\x05 \x01(\x04R\x04size\x12\x16\n" +
+	"\x06shared\x18\x06 \x01(\bR\x06shared\x12.\n" +
+	"\x06stripe\x18\a \x01(\v2\x16.master.v1.ChunkStripeR\x06stripe\x12\x1d\n" +
+	"\n" +
+	"read_token\x18\b \x01(\tR\treadToken\x12\x1f\n" +
+	"\vwrite_token\x18\t \x01(\tR\n" +
+	"writeToken\"\xab\x01\n" +
+	"\vChunkStripe\x12\x1f\n" +
+	"\vdata_shards\x18\x01 \x01(\rR\n" +
+	"dataShards\x12#\n" +
+	"\rparity_shards\x18\x02 \x01(\rR\fparityShards\x12\x1d\n" +
+	"\n" +
+	"shard_size\x18\x03 \x01(\x04R\tshardSize\x127\n" +
+	"\tfragments\x18\x04 \x03(\v2\x19.master.v1.StripeFragmentR\tfragments\"\x97\x01\n" +
+	"\x0eStripeFragment\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x16\n" +
+	"\x06handle\x18\x02 \x01(\tR\x06handle\x128\n" +
+	"\tlocations\x18\x03 \x03(\v2\x1a.master.v1.ChunkServerInfoR\tlocations\x12\x1d\n" +
+	"\n" +
+	"read_token\x18\x04 \x01(\tR\treadToken\"\x8d\x03\n" +
 	"\x10FileInfoResponse\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12#\n" +
 	"\rchunk_handles\x18\x02 \x03(\tR\fchunkHandles\x12\x12\n" +
@@ -1938,7 +6338,42 @@ const file_master_master_proto_rawDesc = "" +
 	"\n" +
 	"created_at\x18\x06 \x01(\x03R\tcreatedAt\x12\x1f\n" +
 	"\vmodified_at\x18\a \x01(\x03R\n" +
-	"modifiedAt\"\xec\x01\n" +
+	"modifiedAt\x12#\n" +
+	"\rstorage_class\x18\b \x01(\tR\fstorageClass\x12K\n" +
+	"\n" +
+	"attributes\x18\t \x03(\v2+.master.v1.FileInfoResponse.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x8e\x02\n" +
+	"\n" +
+	"DiskStatus\x12\x10\n" +
+	"\x03dir\x18\x01 \x01(\tR\x03dir\x12%\n" +
+	"\x0ecapacity_bytes\x18\x02 \x01(\x04R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"free_bytes\x18\x03 \x01(\x04R\tfreeBytes\x12\x1f\n" +
+	"\vchunk_count\x18\x04 \x01(\x05R\n" +
+	"chunkCount\x12\x16\n" +
+	"\x06failed\x18\x05 \x01(\bR\x06failed\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x06 \x01(\x04R\tusedBytes\x12#\n" +
+	"\rlogical_bytes\x18\a \x01(\x04R\flogicalBytes\x12+\n" +
+	"\x11compressed_chunks\x18\b \x01(\x05R\x10compressedChunks\"\x93\x01\n" +
+	"\n" +
+	"ServerLoad\x12)\n" +
+	"\x10open_connections\x18\x01 \x01(\x03R\x0fopenConnections\x12+\n" +
+	"\x12read_bytes_per_sec\x18\x02 \x01(\x04R\x0freadBytesPerSec\x12-\n" +
+	"\x13write_bytes_per_sec\x18\x03 \x01(\x04R\x10writeBytesPerSec\"\xf1\x01\n" +
+	"\vChunkReport\x12\x12\n" +
+	"\x04full\x18\x01 \x01(\bR\x04full\x12\x14\n" +
+	"\x05added\x18\x02 \x03(\tR\x05added\x12\x18\n" +
+	"\aremoved\x18\x03 \x03(\tR\aremoved\x12@\n" +
+	"\bversions\x18\x04 \x03(\v2$.master.v1.ChunkReport.VersionsEntryR\bversions\x12\x1f\n" +
+	"\vchunk_count\x18\x05 \x01(\x05R\n" +
+	"chunkCount\x1a;\n" +
+	"\rVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\x8f\x05\n" +
 	"\x0fRegisterRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x1b\n" +
@@ -1946,20 +6381,90 @@ const file_master_master_proto_rawDesc = "" +
 	"\x10replication_port\x18\x04 \x01(\x05R\x0freplicationPort\x12#\n" +
 	"\rchunk_handles\x18\x05 \x03(\tR\fchunkHandles\x123\n" +
 	"\n" +
-	"build_info\x18\x06 \x01(\v2\x14.master.v1.BuildInfoR\tbuildInfo\"F\n" +
+	"build_info\x18\x06 \x01(\v2\x14.master.v1.BuildInfoR\tbuildInfo\x12%\n" +
+	"\x0efailure_domain\x18\a \x01(\tR\rfailureDomain\x12%\n" +
+	"\x0ecapacity_bytes\x18\b \x01(\x04R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"free_bytes\x18\t \x01(\x04R\tfreeBytes\x12T\n" +
+	"\x0echunk_versions\x18\n" +
+	" \x03(\v2-.master.v1.RegisterRequest.ChunkVersionsEntryR\rchunkVersions\x12+\n" +
+	"\x05disks\x18\v \x03(\v2\x15.master.v1.DiskStatusR\x05disks\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\f \x01(\x04R\tusedBytes\x12#\n" +
+	"\rlogical_bytes\x18\r \x01(\x04R\flogicalBytes\x12+\n" +
+	"\x11compressed_chunks\x18\x0e \x01(\x05R\x10compressedChunks\x1a@\n" +
+	"\x12ChunkVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"F\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"T\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xd8\x04\n" +
 	"\x10HeartbeatRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12#\n" +
-	"\rchunk_handles\x18\x02 \x03(\tR\fchunkHandles\"W\n" +
+	"\rchunk_handles\x18\x02 \x03(\tR\fchunkHandles\x12%\n" +
+	"\x0ecapacity_bytes\x18\x03 \x01(\x04R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"free_bytes\x18\x04 \x01(\x04R\tfreeBytes\x12U\n" +
+	"\x0echunk_versions\x18\x05 \x03(\v2..master.v1.HeartbeatRequest.ChunkVersionsEntryR\rchunkVersions\x12+\n" +
+	"\x05disks\x18\x06 \x03(\v2\x15.master.v1.DiskStatusR\x05disks\x12\x1f\n" +
+	"\vlost_chunks\x18\a \x03(\tR\n" +
+	"lostChunks\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\b \x01(\x04R\tusedBytes\x12)\n" +
+	"\x04load\x18\t \x01(\v2\x15.master.v1.ServerLoadR\x04load\x129\n" +
+	"\fchunk_report\x18\n" +
+	" \x01(\v2\x16.master.v1.ChunkReportR\vchunkReport\x12#\n" +
+	"\rlogical_bytes\x18\v \x01(\x04R\flogicalBytes\x12+\n" +
+	"\x11compressed_chunks\x18\f \x01(\x05R\x10compressedChunks\x1a@\n" +
+	"\x12ChunkVersionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\xd4\x02\n" +
 	"\x11HeartbeatResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12(\n" +
-	"\x10chunks_to_delete\x18\x02 \x03(\tR\x0echunksToDelete\"i\n" +
+	"\x10chunks_to_delete\x18\x02 \x03(\tR\x0echunksToDelete\x12P\n" +
+	"\x13chunks_to_replicate\x18\x03 \x03(\v2 .master.v1.ReplicateChunkCommandR\x11chunksToReplicate\x12G\n" +
+	"\x10chunks_to_encode\x18\x04 \x03(\v2\x1d.master.v1.EncodeChunkCommandR\x0echunksToEncode\x122\n" +
+	"\x15full_report_requested\x18\x05 \x01(\bR\x13fullReportRequested\x12,\n" +
+	"\x12chunks_to_compress\x18\x06 \x03(\tR\x10chunksToCompress\"n\n" +
+	"\x15ReplicateChunkCommand\x12!\n" +
+	"\fchunk_handle\x18\x01 \x01(\tR\vchunkHandle\x122\n" +
+	"\x06target\x18\x02 \x01(\v2\x1a.master.v1.ChunkServerInfoR\x06target\"\x81\x02\n" +
+	"\x12EncodeChunkCommand\x12!\n" +
+	"\fchunk_handle\x18\x01 \x01(\tR\vchunkHandle\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x04R\x04size\x12\x1f\n" +
+	"\vdata_shards\x18\x03 \x01(\rR\n" +
+	"dataShards\x12#\n" +
+	"\rparity_shards\x18\x04 \x01(\rR\fparityShards\x126\n" +
+	"\asources\x18\x05 \x03(\v2\x1c.master.v1.FragmentPlacementR\asources\x126\n" +
+	"\atargets\x18\x06 \x03(\v2\x1c.master.v1.FragmentPlacementR\atargets\"u\n" +
+	"\x11FragmentPlacement\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\x12\x16\n" +
+	"\x06handle\x18\x02 \x01(\tR\x06handle\x122\n" +
+	"\x06server\x18\x03 \x01(\v2\x1a.master.v1.ChunkServerInfoR\x06server\"\xa8\x01\n" +
+	"\x13ReportEncodeRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
+	"\fchunk_handle\x18\x02 \x01(\tR\vchunkHandle\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"shard_size\x18\x05 \x01(\x04R\tshardSize\"J\n" +
+	"\x14ReportEncodeResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xb8\x01\n" +
+	"\x18ReportReplicationRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
+	"\fchunk_handle\x18\x02 \x01(\tR\vchunkHandle\x12(\n" +
+	"\x10target_server_id\x18\x03 \x01(\tR\x0etargetServerId\x12\x18\n" +
+	"\asuccess\x18\x04 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"O\n" +
+	"\x19ReportReplicationResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x83\x01\n" +
 	"\x13ReportCommitRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
 	"\fchunk_handle\x18\x02 \x01(\tR\vchunkHandle\x12\x12\n" +
-	"\x04size\x18\x03 \x01(\x04R\x04size\"J\n" +
+	"\x04size\x18\x03 \x01(\x04R\x04size\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x04R\aversion\"J\n" +
 	"\x14ReportCommitResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\"S\n" +
@@ -1972,18 +6477,35 @@ const file_master_master_proto_rawDesc = "" +
 	"\x11lease_duration_ms\x18\x03 \x01(\x04R\x0fleaseDurationMs\"U\n" +
 	"\x13ClaimPrimaryRequest\x12\x1b\n" +
 	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
-	"\fchunk_handle\x18\x02 \x01(\tR\vchunkHandle\"v\n" +
+	"\fchunk_handle\x18\x02 \x01(\tR\vchunkHandle\"\x90\x01\n" +
 	"\x14ClaimPrimaryResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12*\n" +
-	"\x11lease_duration_ms\x18\x03 \x01(\x04R\x0fleaseDurationMs\"E\n" +
+	"\x11lease_duration_ms\x18\x03 \x01(\x04R\x0fleaseDurationMs\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x04R\aversion\"s\n" +
+	"\x19ReportCorruptChunkRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12!\n" +
+	"\fchunk_handle\x18\x02 \x01(\tR\vchunkHandle\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"P\n" +
+	"\x1aReportCorruptChunkResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xf7\x01\n" +
 	"\x11CreateFileRequest\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
-	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"y\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12#\n" +
+	"\rstorage_class\x18\x03 \x01(\tR\fstorageClass\x12L\n" +
+	"\n" +
+	"attributes\x18\x04 \x03(\v2,.master.v1.CreateFileRequest.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd1\x01\n" +
 	"\x12CreateFileResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
-	"\x04file\x18\x03 \x01(\v2\x1b.master.v1.FileInfoResponseR\x04file\"B\n" +
+	"\x04file\x18\x03 \x01(\v2\x1b.master.v1.FileInfoResponseR\x04file\x12%\n" +
+	"\x0equota_exceeded\x18\x04 \x01(\bR\rquotaExceeded\x12/\n" +
+	"\x13precondition_failed\x18\x05 \x01(\bR\x12preconditionFailed\"B\n" +
 	"\x0eGetFileRequest\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
 	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"v\n" +
@@ -2002,50 +6524,310 @@ const file_master_master_proto_rawDesc = "" +
 	"\x17DeleteNamespaceResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12#\n" +
-	"\rfiles_deleted\x18\x03 \x01(\x05R\ffilesDeleted\"g\n" +
+	"\rfiles_deleted\x18\x03 \x01(\x05R\ffilesDeleted\"\x81\x01\n" +
 	"\x11RenameFileRequest\x12\x19\n" +
 	"\bold_path\x18\x01 \x01(\tR\aoldPath\x12\x19\n" +
 	"\bnew_path\x18\x02 \x01(\tR\anewPath\x12\x1c\n" +
-	"\tnamespace\x18\x03 \x01(\tR\tnamespace\"H\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x18\n" +
+	"\areplace\x18\x04 \x01(\bR\areplace\"y\n" +
 	"\x12RenameFileResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"H\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\x13precondition_failed\x18\x03 \x01(\bR\x12preconditionFailed\"r\n" +
+	"\x11TransactCondition\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x16\n" +
+	"\x06absent\x18\x02 \x01(\bR\x06absent\x12\x1d\n" +
+	"\n" +
+	"check_size\x18\x03 \x01(\bR\tcheckSize\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x04R\x04size\"\xac\x02\n" +
+	"\n" +
+	"TransactOp\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x19\n" +
+	"\bnew_path\x18\x03 \x01(\tR\anewPath\x12\x18\n" +
+	"\areplace\x18\x04 \x01(\bR\areplace\x12#\n" +
+	"\rstorage_class\x18\x05 \x01(\tR\fstorageClass\x12E\n" +
+	"\n" +
+	"attributes\x18\x06 \x03(\v2%.master.v1.TransactOp.AttributesEntryR\n" +
+	"attributes\x12\x16\n" +
+	"\x06remove\x18\a \x03(\tR\x06remove\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x96\x01\n" +
+	"\x0fTransactRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12<\n" +
+	"\n" +
+	"conditions\x18\x02 \x03(\v2\x1c.master.v1.TransactConditionR\n" +
+	"conditions\x12'\n" +
+	"\x03ops\x18\x03 \x03(\v2\x15.master.v1.TransactOpR\x03ops\"\x9e\x01\n" +
+	"\x10TransactResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\x13precondition_failed\x18\x03 \x01(\bR\x12preconditionFailed\x12%\n" +
+	"\x0equota_exceeded\x18\x04 \x01(\bR\rquotaExceeded\"\xdc\x01\n" +
+	"\x18SetFileAttributesRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12>\n" +
+	"\x03set\x18\x03 \x03(\v2,.master.v1.SetFileAttributesRequest.SetEntryR\x03set\x12\x16\n" +
+	"\x06remove\x18\x04 \x03(\tR\x06remove\x1a6\n" +
+	"\bSetEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x80\x01\n" +
+	"\x19SetFileAttributesResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\x04file\x18\x03 \x01(\v2\x1b.master.v1.FileInfoResponseR\x04file\"p\n" +
+	"\x15WatchNamespaceRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12!\n" +
+	"\fresume_token\x18\x03 \x01(\x04R\vresumeToken\"\xd6\x01\n" +
+	"\x0eNamespaceEvent\x12!\n" +
+	"\fresume_token\x18\x01 \x01(\x04R\vresumeToken\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12\x19\n" +
+	"\bnew_path\x18\x05 \x01(\tR\anewPath\x12!\n" +
+	"\fchunk_handle\x18\x06 \x01(\tR\vchunkHandle\x12\x1d\n" +
+	"\n" +
+	"chunk_size\x18\a \x01(\x04R\tchunkSize\"H\n" +
 	"\x10ListFilesRequest\x12\x16\n" +
 	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1c\n" +
 	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"F\n" +
 	"\x11ListFilesResponse\x121\n" +
-	"\x05files\x18\x01 \x03(\v2\x1b.master.v1.FileInfoResponseR\x05files\"H\n" +
+	"\x05files\x18\x01 \x03(\v2\x1b.master.v1.FileInfoResponseR\x05files\"\x9d\x01\n" +
+	"\x12ListFilesV2Request\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12\x1c\n" +
+	"\tdelimiter\x18\x03 \x01(\tR\tdelimiter\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\"\xcd\x01\n" +
+	"\x13ListFilesV2Response\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x121\n" +
+	"\x05files\x18\x03 \x03(\v2\x1b.master.v1.FileInfoResponseR\x05files\x12'\n" +
+	"\x0fcommon_prefixes\x18\x04 \x03(\tR\x0ecommonPrefixes\x12&\n" +
+	"\x0fnext_page_token\x18\x05 \x01(\tR\rnextPageToken\"\x80\x01\n" +
 	"\x14AllocateChunkRequest\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
-	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"\x7f\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x1d\n" +
+	"\n" +
+	"check_size\x18\x03 \x01(\bR\tcheckSize\x12\x17\n" +
+	"\aif_size\x18\x04 \x01(\x04R\x06ifSize\"\xd7\x01\n" +
 	"\x15AllocateChunkResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x122\n" +
-	"\x05chunk\x18\x03 \x01(\v2\x1c.master.v1.ChunkLocationInfoR\x05chunk\"L\n" +
+	"\x05chunk\x18\x03 \x01(\v2\x1c.master.v1.ChunkLocationInfoR\x05chunk\x12%\n" +
+	"\x0equota_exceeded\x18\x04 \x01(\bR\rquotaExceeded\x12/\n" +
+	"\x13precondition_failed\x18\x05 \x01(\bR\x12preconditionFailed\"L\n" +
 	"\x18GetChunkLocationsRequest\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
 	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"\x85\x01\n" +
 	"\x19GetChunkLocationsResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x124\n" +
-	"\x06chunks\x18\x03 \x03(\v2\x1c.master.v1.ChunkLocationInfoR\x06chunks\"\xb8\x01\n" +
+	"\x06chunks\x18\x03 \x03(\v2\x1c.master.v1.ChunkLocationInfoR\x06chunks\"\x98\x01\n" +
+	"\x13SnapshotFileRequest\x12\x1f\n" +
+	"\vsource_path\x18\x01 \x01(\tR\n" +
+	"sourcePath\x12\x1b\n" +
+	"\tdest_path\x18\x02 \x01(\tR\bdestPath\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12%\n" +
+	"\x0edest_namespace\x18\x04 \x01(\tR\rdestNamespace\"\xa2\x01\n" +
+	"\x14SnapshotFileResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\x04file\x18\x03 \x01(\v2\x1b.master.v1.FileInfoResponseR\x04file\x12%\n" +
+	"\x0equota_exceeded\x18\x04 \x01(\bR\rquotaExceeded\"_\n" +
+	"\x18SnapshotNamespaceRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12%\n" +
+	"\x0edest_namespace\x18\x02 \x01(\tR\rdestNamespace\"\x99\x01\n" +
+	"\x19SnapshotNamespaceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12!\n" +
+	"\ffiles_copied\x18\x03 \x01(\x05R\vfilesCopied\x12%\n" +
+	"\x0equota_exceeded\x18\x04 \x01(\bR\rquotaExceeded\"\xab\x01\n" +
+	"\n" +
+	"TrashEntry\x12\x19\n" +
+	"\btrash_id\x18\x01 \x01(\tR\atrashId\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x04R\x04size\x12\x1d\n" +
+	"\n" +
+	"deleted_at\x18\x05 \x01(\x03R\tdeletedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x06 \x01(\x03R\texpiresAt\"0\n" +
+	"\x10ListTrashRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\"D\n" +
+	"\x11ListTrashResponse\x12/\n" +
+	"\aentries\x18\x01 \x03(\v2\x15.master.v1.TrashEntryR\aentries\"a\n" +
+	"\x12RestoreFileRequest\x12\x19\n" +
+	"\btrash_id\x18\x01 \x01(\tR\atrashId\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\"\xa1\x01\n" +
+	"\x13RestoreFileResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\x04file\x18\x03 \x01(\v2\x1b.master.v1.FileInfoResponseR\x04file\x12%\n" +
+	"\x0equota_exceeded\x18\x04 \x01(\bR\rquotaExceeded\"N\n" +
+	"\x11PurgeTrashRequest\x12\x1b\n" +
+	"\ttrash_ids\x18\x01 \x03(\tR\btrashIds\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\"k\n" +
+	"\x12PurgeTrashResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12!\n" +
+	"\ffiles_purged\x18\x03 \x01(\x05R\vfilesPurged\"m\n" +
+	"\x18PrepareChunkWriteRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x1f\n" +
+	"\vchunk_index\x18\x03 \x01(\x05R\n" +
+	"chunkIndex\"\x83\x01\n" +
+	"\x19PrepareChunkWriteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x122\n" +
+	"\x05chunk\x18\x03 \x01(\v2\x1c.master.v1.ChunkLocationInfoR\x05chunk\"X\n" +
+	"\x15IssueDataTokenRequest\x12!\n" +
+	"\fchunk_handle\x18\x01 \x01(\tR\vchunkHandle\x12\x1c\n" +
+	"\toperation\x18\x02 \x01(\tR\toperation\"\x81\x01\n" +
+	"\x16IssueDataTokenResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x14\n" +
+	"\x05token\x18\x03 \x01(\tR\x05token\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\"r\n" +
+	"\x18SetNamespaceQuotaRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x04R\bmaxBytes\x12\x1b\n" +
+	"\tmax_files\x18\x03 \x01(\x04R\bmaxFiles\"O\n" +
+	"\x19SetNamespaceQuotaResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"o\n" +
+	"\x16SetStorageClassRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04path\x18\x02 \x01(\tR\x04path\x12#\n" +
+	"\rstorage_class\x18\x03 \x01(\tR\fstorageClass\"M\n" +
+	"\x17SetStorageClassResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"W\n" +
+	"\x15SetCompressionRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12 \n" +
+	"\vcompression\x18\x02 \x01(\tR\vcompression\"L\n" +
+	"\x16SetCompressionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"8\n" +
+	"\x18GetNamespaceUsageRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\"\xed\x01\n" +
+	"\x0eNamespaceUsage\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x02 \x01(\x04R\tusedBytes\x12\x1d\n" +
+	"\n" +
+	"file_count\x18\x03 \x01(\x04R\tfileCount\x12\x1b\n" +
+	"\tmax_bytes\x18\x04 \x01(\x04R\bmaxBytes\x12\x1b\n" +
+	"\tmax_files\x18\x05 \x01(\x04R\bmaxFiles\x12#\n" +
+	"\rstorage_class\x18\x06 \x01(\tR\fstorageClass\x12 \n" +
+	"\vcompression\x18\a \x01(\tR\vcompression\"V\n" +
+	"\x19GetNamespaceUsageResponse\x129\n" +
+	"\n" +
+	"namespaces\x18\x01 \x03(\v2\x19.master.v1.NamespaceUsageR\n" +
+	"namespaces\"\xdb\x04\n" +
 	"\x11ChunkServerStatus\x122\n" +
 	"\x06server\x18\x01 \x01(\v2\x1a.master.v1.ChunkServerInfoR\x06server\x12\x1f\n" +
 	"\vchunk_count\x18\x02 \x01(\x05R\n" +
 	"chunkCount\x12\x19\n" +
 	"\bis_alive\x18\x03 \x01(\bR\aisAlive\x123\n" +
 	"\n" +
-	"build_info\x18\x04 \x01(\v2\x14.master.v1.BuildInfoR\tbuildInfo\"\x19\n" +
-	"\x17GetClusterStatusRequest\"R\n" +
+	"build_info\x18\x04 \x01(\v2\x14.master.v1.BuildInfoR\tbuildInfo\x12%\n" +
+	"\x0efailure_domain\x18\x05 \x01(\tR\rfailureDomain\x12\x1a\n" +
+	"\bdraining\x18\x06 \x01(\bR\bdraining\x12+\n" +
+	"\x05disks\x18\a \x03(\v2\x15.master.v1.DiskStatusR\x05disks\x12%\n" +
+	"\x0ecapacity_bytes\x18\b \x01(\x04R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"free_bytes\x18\t \x01(\x04R\tfreeBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\n" +
+	" \x01(\x04R\tusedBytes\x12)\n" +
+	"\x04load\x18\v \x01(\v2\x15.master.v1.ServerLoadR\x04load\x12%\n" +
+	"\x0elast_heartbeat\x18\f \x01(\x03R\rlastHeartbeat\x12(\n" +
+	"\x10last_full_report\x18\r \x01(\x03R\x0elastFullReport\x12#\n" +
+	"\rlogical_bytes\x18\x0e \x01(\x04R\flogicalBytes\x12+\n" +
+	"\x11compressed_chunks\x18\x0f \x01(\x05R\x10compressedChunks\"\x19\n" +
+	"\x17GetClusterStatusRequest\"\xdc\x01\n" +
 	"\x18GetClusterStatusResponse\x126\n" +
-	"\aservers\x18\x01 \x03(\v2\x1c.master.v1.ChunkServerStatusR\aservers2\xd8\b\n" +
+	"\aservers\x18\x01 \x03(\v2\x1c.master.v1.ChunkServerStatusR\aservers\x12%\n" +
+	"\x0ecapacity_bytes\x18\x02 \x01(\x04R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"free_bytes\x18\x03 \x01(\x04R\tfreeBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x04 \x01(\x04R\tusedBytes\x12#\n" +
+	"\rlogical_bytes\x18\x05 \x01(\x04R\flogicalBytes\"N\n" +
+	"\x17DrainChunkServerRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x16\n" +
+	"\x06cancel\x18\x02 \x01(\bR\x06cancel\"N\n" +
+	"\x18DrainChunkServerResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"4\n" +
+	"\x15GetDrainStatusRequest\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\"\xf5\x01\n" +
+	"\vDrainStatus\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x19\n" +
+	"\bis_alive\x18\x02 \x01(\bR\aisAlive\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\x03R\tstartedAt\x12!\n" +
+	"\fmoved_chunks\x18\x04 \x01(\x05R\vmovedChunks\x12)\n" +
+	"\x10remaining_chunks\x18\x05 \x01(\x05R\x0fremainingChunks\x12%\n" +
+	"\x0einflight_moves\x18\x06 \x01(\x05R\rinflightMoves\x12\x1a\n" +
+	"\bcomplete\x18\a \x01(\bR\bcomplete\"~\n" +
+	"\x16GetDrainStatusResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x120\n" +
+	"\aservers\x18\x03 \x03(\v2\x16.master.v1.DrainStatusR\aservers\"H\n" +
+	"\x10RebalanceRequest\x12\x1c\n" +
+	"\tthreshold\x18\x01 \x01(\x01R\tthreshold\x12\x16\n" +
+	"\x06cancel\x18\x02 \x01(\bR\x06cancel\"G\n" +
+	"\x11RebalanceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x1b\n" +
+	"\x19GetRebalanceStatusRequest\"H\n" +
+	"\n" +
+	"ServerFill\x12\x1b\n" +
+	"\tserver_id\x18\x01 \x01(\tR\bserverId\x12\x1d\n" +
+	"\n" +
+	"fill_ratio\x18\x02 \x01(\x01R\tfillRatio\"\x84\x02\n" +
+	"\x1aGetRebalanceStatusResponse\x12\x16\n" +
+	"\x06active\x18\x01 \x01(\bR\x06active\x12\x1c\n" +
+	"\tthreshold\x18\x02 \x01(\x01R\tthreshold\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\x03R\tstartedAt\x12!\n" +
+	"\fmoved_chunks\x18\x04 \x01(\x05R\vmovedChunks\x12%\n" +
+	"\x0einflight_moves\x18\x05 \x01(\x05R\rinflightMoves\x12\x16\n" +
+	"\x06spread\x18\x06 \x01(\x01R\x06spread\x12/\n" +
+	"\aservers\x18\a \x03(\v2\x15.master.v1.ServerFillR\aservers\"9\n" +
+	"\rMasterReplica\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\"\x12\n" +
+	"\x10GetLeaderRequest\"\xcc\x01\n" +
+	"\x11GetLeaderResponse\x12\x1e\n" +
+	"\n" +
+	"replicated\x18\x01 \x01(\bR\n" +
+	"replicated\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\x04R\bleaderId\x12%\n" +
+	"\x0eleader_address\x18\x03 \x01(\tR\rleaderAddress\x12\x1d\n" +
+	"\n" +
+	"replica_id\x18\x04 \x01(\x04R\treplicaId\x124\n" +
+	"\breplicas\x18\x05 \x03(\v2\x18.master.v1.MasterReplicaR\breplicas\"!\n" +
+	"\vRaftMessage\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\x15\n" +
+	"\x13RaftMessageResponse2\x91\x18\n" +
 	"\x06Master\x12C\n" +
 	"\bRegister\x12\x1a.master.v1.RegisterRequest\x1a\x1b.master.v1.RegisterResponse\x12F\n" +
 	"\tHeartbeat\x12\x1b.master.v1.HeartbeatRequest\x1a\x1c.master.v1.HeartbeatResponse\x12O\n" +
 	"\fReportCommit\x12\x1e.master.v1.ReportCommitRequest\x1a\x1f.master.v1.ReportCommitResponse\x12I\n" +
 	"\n" +
 	"RenewLease\x12\x1c.master.v1.RenewLeaseRequest\x1a\x1d.master.v1.RenewLeaseResponse\x12O\n" +
-	"\fClaimPrimary\x12\x1e.master.v1.ClaimPrimaryRequest\x1a\x1f.master.v1.ClaimPrimaryResponse\x12I\n" +
+	"\fClaimPrimary\x12\x1e.master.v1.ClaimPrimaryRequest\x1a\x1f.master.v1.ClaimPrimaryResponse\x12^\n" +
+	"\x11ReportReplication\x12#.master.v1.ReportReplicationRequest\x1a$.master.v1.ReportReplicationResponse\x12a\n" +
+	"\x12ReportCorruptChunk\x12$.master.v1.ReportCorruptChunkRequest\x1a%.master.v1.ReportCorruptChunkResponse\x12O\n" +
+	"\fReportEncode\x12\x1e.master.v1.ReportEncodeRequest\x1a\x1f.master.v1.ReportEncodeResponse\x12I\n" +
 	"\n" +
 	"CreateFile\x12\x1c.master.v1.CreateFileRequest\x1a\x1d.master.v1.CreateFileResponse\x12@\n" +
 	"\aGetFile\x12\x19.master.v1.GetFileRequest\x1a\x1a.master.v1.GetFileResponse\x12I\n" +
@@ -2053,11 +6835,35 @@ const file_master_master_proto_rawDesc = "" +
 	"DeleteFile\x12\x1c.master.v1.DeleteFileRequest\x1a\x1d.master.v1.DeleteFileResponse\x12X\n" +
 	"\x0fDeleteNamespace\x12!.master.v1.DeleteNamespaceRequest\x1a\".master.v1.DeleteNamespaceResponse\x12I\n" +
 	"\n" +
-	"RenameFile\x12\x1c.master.v1.RenameFileRequest\x1a\x1d.master.v1.RenameFileResponse\x12F\n" +
-	"\tListFiles\x12\x1b.master.v1.ListFilesRequest\x1a\x1c.master.v1.ListFilesResponse\x12R\n" +
+	"RenameFile\x12\x1c.master.v1.RenameFileRequest\x1a\x1d.master.v1.RenameFileResponse\x12C\n" +
+	"\bTransact\x12\x1a.master.v1.TransactRequest\x1a\x1b.master.v1.TransactResponse\x12^\n" +
+	"\x11SetFileAttributes\x12#.master.v1.SetFileAttributesRequest\x1a$.master.v1.SetFileAttributesResponse\x12O\n" +
+	"\x0eWatchNamespace\x12 .master.v1.WatchNamespaceRequest\x1a\x19.master.v1.NamespaceEvent0\x01\x12F\n" +
+	"\tListFiles\x12\x1b.master.v1.ListFilesRequest\x1a\x1c.master.v1.ListFilesResponse\x12L\n" +
+	"\vListFilesV2\x12\x1d.master.v1.ListFilesV2Request\x1a\x1e.master.v1.ListFilesV2Response\x12O\n" +
+	"\fSnapshotFile\x12\x1e.master.v1.SnapshotFileRequest\x1a\x1f.master.v1.SnapshotFileResponse\x12^\n" +
+	"\x11SnapshotNamespace\x12#.master.v1.SnapshotNamespaceRequest\x1a$.master.v1.SnapshotNamespaceResponse\x12F\n" +
+	"\tListTrash\x12\x1b.master.v1.ListTrashRequest\x1a\x1c.master.v1.ListTrashResponse\x12L\n" +
+	"\vRestoreFile\x12\x1d.master.v1.RestoreFileRequest\x1a\x1e.master.v1.RestoreFileResponse\x12I\n" +
+	"\n" +
+	"PurgeTrash\x12\x1c.master.v1.PurgeTrashRequest\x1a\x1d.master.v1.PurgeTrashResponse\x12R\n" +
 	"\rAllocateChunk\x12\x1f.master.v1.AllocateChunkRequest\x1a .master.v1.AllocateChunkResponse\x12^\n" +
-	"\x11GetChunkLocations\x12#.master.v1.GetChunkLocationsRequest\x1a$.master.v1.GetChunkLocationsResponse\x12[\n" +
-	"\x10GetClusterStatus\x12\".master.v1.GetClusterStatusRequest\x1a#.master.v1.GetClusterStatusResponseB(Z&eddisonso.com/go-gfs/gen/master;masterb\x06proto3"
+	"\x11GetChunkLocations\x12#.master.v1.GetChunkLocationsRequest\x1a$.master.v1.GetChunkLocationsResponse\x12^\n" +
+	"\x11PrepareChunkWrite\x12#.master.v1.PrepareChunkWriteRequest\x1a$.master.v1.PrepareChunkWriteResponse\x12U\n" +
+	"\x0eIssueDataToken\x12 .master.v1.IssueDataTokenRequest\x1a!.master.v1.IssueDataTokenResponse\x12^\n" +
+	"\x11SetNamespaceQuota\x12#.master.v1.SetNamespaceQuotaRequest\x1a$.master.v1.SetNamespaceQuotaResponse\x12^\n" +
+	"\x11GetNamespaceUsage\x12#.master.v1.GetNamespaceUsageRequest\x1a$.master.v1.GetNamespaceUsageResponse\x12X\n" +
+	"\x0fSetStorageClass\x12!.master.v1.SetStorageClassRequest\x1a\".master.v1.SetStorageClassResponse\x12U\n" +
+	"\x0eSetCompression\x12 .master.v1.SetCompressionRequest\x1a!.master.v1.SetCompressionResponse\x12[\n" +
+	"\x10GetClusterStatus\x12\".master.v1.GetClusterStatusRequest\x1a#.master.v1.GetClusterStatusResponse\x12[\n" +
+	"\x10DrainChunkServer\x12\".master.v1.DrainChunkServerRequest\x1a#.master.v1.DrainChunkServerResponse\x12U\n" +
+	"\x0eGetDrainStatus\x12 .master.v1.GetDrainStatusRequest\x1a!.master.v1.GetDrainStatusResponse\x12F\n" +
+	"\tRebalance\x12\x1b.master.v1.RebalanceRequest\x1a\x1c.master.v1.RebalanceResponse\x12a\n" +
+	"\x12GetRebalanceStatus\x12$.master.v1.GetRebalanceStatusRequest\x1a%.master.v1.GetRebalanceStatusResponse\x12F\n" +
+	"\tGetLeader\x12\x1b.master.v1.GetLeaderRequest\x1a\x1c.master.v1.GetLeaderResponse2L\n" +
+	"\n" +
+	"MasterPeer\x12>\n" +
+	"\x04Step\x12\x16.master.v1.RaftMessage\x1a\x1e.master.v1.RaftMessageResponseB(Z&eddisonso.com/go-gfs/gen/master;masterb\x06proto3"
 
 var (
 	file_master_master_proto_rawDescOnce sync.Once
@@ -2071,87 +6877,239 @@ func file_master_master_proto_rawDescGZIP() []byte {
 	return file_master_master_proto_rawDescData
 }
 
-var file_master_master_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
+var file_master_master_proto_msgTypes = make([]protoimpl.MessageInfo, 103)
 var file_master_master_proto_goTypes = []any{
-	(*BuildInfo)(nil),                 // 0: master.v1.BuildInfo
-	(*ChunkServerInfo)(nil),           // 1: master.v1.ChunkServerInfo
-	(*ChunkLocationInfo)(nil),         // 2: master.v1.ChunkLocationInfo
-	(*FileInfoResponse)(nil),          // 3: master.v1.FileInfoResponse
-	(*RegisterRequest)(nil),           // 4: master.v1.RegisterRequest
-	(*RegisterResponse)(nil),          // 5: master.v1.RegisterResponse
-	(*HeartbeatRequest)(nil),          // 6: master.v1.HeartbeatRequest
-	(*HeartbeatResponse)(nil),         // 7: master.v1.HeartbeatResponse
-	(*ReportCommitRequest)(nil),       // 8: master.v1.ReportCommitRequest
-	(*ReportCommitResponse)(nil),      // 9: master.v1.ReportCommitResponse
-	(*RenewLeaseRequest)(nil),         // 10: master.v1.RenewLeaseRequest
-	(*RenewLeaseResponse)(nil),        // 11: master.v1.RenewLeaseResponse
-	(*ClaimPrimaryRequest)(nil),       // 12: master.v1.ClaimPrimaryRequest
-	(*ClaimPrimaryResponse)(nil),      // 13: master.v1.ClaimPrimaryResponse
-	(*CreateFileRequest)(nil),         // 14: master.v1.CreateFileRequest
-	(*CreateFileResponse)(nil),        // 15: master.v1.CreateFileResponse
-	(*GetFileRequest)(nil),            // 16: master.v1.GetFileRequest
-	(*GetFileResponse)(nil),           // 17: master.v1.GetFileResponse
-	(*DeleteFileRequest)(nil),         // 18: master.v1.DeleteFileRequest
-	(*DeleteFileResponse)(nil),        // 19: master.v1.DeleteFileResponse
-	(*DeleteNamespaceRequest)(nil),    // 20: master.v1.DeleteNamespaceRequest
-	(*DeleteNamespaceResponse)(nil),   // 21: master.v1.DeleteNamespaceResponse
-	(*RenameFileRequest)(nil),         // 22: master.v1.RenameFileRequest
-	(*RenameFileResponse)(nil),        // 23: master.v1.RenameFileResponse
-	(*ListFilesRequest)(nil),          // 24: master.v1.ListFilesRequest
-	(*ListFilesResponse)(nil),         // 25: master.v1.ListFilesResponse
-	(*AllocateChunkRequest)(nil),      // 26: master.v1.AllocateChunkRequest
-	(*AllocateChunkResponse)(nil),     // 27: master.v1.AllocateChunkResponse
-	(*GetChunkLocationsRequest)(nil),  // 28: master.v1.GetChunkLocationsRequest
-	(*GetChunkLocationsResponse)(nil), // 29: master.v1.GetChunkLocationsResponse
-	(*ChunkServerStatus)(nil),         // 30: master.v1.ChunkServerStatus
-	(*GetClusterStatusRequest)(nil),   // 31: master.v1.GetClusterStatusRequest
-	(*GetClusterStatusResponse)(nil),  // 32: master.v1.GetClusterStatusResponse
+	(*BuildInfo)(nil),                  // 0: master.v1.BuildInfo
+	(*ChunkServerInfo)(nil),            // 1: master.v1.ChunkServerInfo
+	(*ChunkLocationInfo)(nil),          // 2: master.v1.ChunkLocationInfo
+	(*ChunkStripe)(nil),                // 3: master.v1.ChunkStripe
+	(*StripeFragment)(nil),             // 4: master.v1.StripeFragment
+	(*FileInfoResponse)(nil),           // 5: master.v1.FileInfoResponse
+	(*DiskStatus)(nil),                 // 6: master.v1.DiskStatus
+	(*ServerLoad)(nil),                 // 7: master.v1.ServerLoad
+	(*ChunkReport)(nil),                // 8: master.v1.ChunkReport
+	(*RegisterRequest)(nil),            // 9: master.v1.RegisterRequest
+	(*RegisterResponse)(nil),           // 10: master.v1.RegisterResponse
+	(*HeartbeatRequest)(nil),           // 11: master.v1.HeartbeatRequest
+	(*HeartbeatResponse)(nil),          // 12: master.v1.HeartbeatResponse
+	(*ReplicateChunkCommand)(nil),      // 13: master.v1.ReplicateChunkCommand
+	(*EncodeChunkCommand)(nil),         // 14: master.v1.EncodeChunkCommand
+	(*FragmentPlacement)(nil),          // 15: master.v1.FragmentPlacement
+	(*ReportEncodeRequest)(nil),        // 16: master.v1.ReportEncodeRequest
+	(*ReportEncodeResponse)(nil),       // 17: master.v1.ReportEncodeResponse
+	(*ReportReplicationRequest)(nil),   // 18: master.v1.ReportReplicationRequest
+	(*ReportReplicationResponse)(nil),  // 19: master.v1.ReportReplicationResponse
+	(*ReportCommitRequest)(nil),        // 20: master.v1.ReportCommitRequest
+	(*ReportCommitResponse)(nil),       // 21: master.v1.ReportCommitResponse
+	(*RenewLeaseRequest)(nil),          // 22: master.v1.RenewLeaseRequest
+	(*RenewLeaseResponse)(nil),         // 23: master.v1.RenewLeaseResponse
+	(*ClaimPrimaryRequest)(nil),        // 24: master.v1.ClaimPrimaryRequest
+	(*ClaimPrimaryResponse)(nil),       // 25: master.v1.ClaimPrimaryResponse
+	(*ReportCorruptChunkRequest)(nil),  // 26: master.v1.ReportCorruptChunkRequest
+	(*ReportCorruptChunkResponse)(nil), // 27: master.v1.ReportCorruptChunkResponse
+	(*CreateFileRequest)(nil),          // 28: master.v1.CreateFileRequest
+	(*CreateFileResponse)(nil),         // 29: master.v1.CreateFileResponse
+	(*GetFileRequest)(nil),             // 30: master.v1.GetFileRequest
+	(*GetFileResponse)(nil),            // 31: master.v1.GetFileResponse
+	(*DeleteFileRequest)(nil),          // 32: master.v1.DeleteFileRequest
+	(*DeleteFileResponse)(nil),         // 33: master.v1.DeleteFileResponse
+	(*DeleteNamespaceRequest)(nil),     // 34: master.v1.DeleteNamespaceRequest
+	(*DeleteNamespaceResponse)(nil),    // 35: master.v1.DeleteNamespaceResponse
+	(*RenameFileRequest)(nil),          // 36: master.v1.RenameFileRequest
+	(*RenameFileResponse)(nil),         // 37: master.v1.RenameFileResponse
+	(*TransactCondition)(nil),          // 38: master.v1.TransactCondition
+	(*TransactOp)(nil),                 // 39: master.v1.TransactOp
+	(*TransactRequest)(nil),            // 40: master.v1.TransactRequest
+	(*TransactResponse)(nil),           // 41: master.v1.TransactResponse
+	(*SetFileAttributesRequest)(nil),   // 42: master.v1.SetFileAttributesRequest
+	(*SetFileAttributesResponse)(nil),  // 43: master.v1.SetFileAttributesResponse
+	(*WatchNamespaceRequest)(nil),      // 44: master.v1.WatchNamespaceRequest
+	(*NamespaceEvent)(nil),             // 45: master.v1.NamespaceEvent
+	(*ListFilesRequest)(nil),           // 46: master.v1.ListFilesRequest
+	(*ListFilesResponse)(nil),          // 47: master.v1.ListFilesResponse
+	(*ListFilesV2Request)(nil),         // 48: master.v1.ListFilesV2Request
+	(*ListFilesV2Response)(nil),        // 49: master.v1.ListFilesV2Response
+	(*AllocateChunkRequest)(nil),       // 50: master.v1.AllocateChunkRequest
+	(*AllocateChunkResponse)(nil),      // 51: master.v1.AllocateChunkResponse
+	(*GetChunkLocationsRequest)(nil),   // 52: master.v1.GetChunkLocationsRequest
+	(*GetChunkLocationsResponse)(nil),  // 53: master.v1.GetChunkLocationsResponse
+	(*SnapshotFileRequest)(nil),        // 54: master.v1.SnapshotFileRequest
+	(*SnapshotFileResponse)(nil),       // 55: master.v1.SnapshotFileResponse
+	(*SnapshotNamespaceRequest)(nil),   // 56: master.v1.SnapshotNamespaceRequest
+	(*SnapshotNamespaceResponse)(nil),  // 57: master.v1.SnapshotNamespaceResponse
+	(*TrashEntry)(nil),                 // 58: master.v1.TrashEntry
+	(*ListTrashRequest)(nil),           // 59: master.v1.ListTrashRequest
+	(*ListTrashResponse)(nil),          // 60: master.v1.ListTrashResponse
+	(*RestoreFileRequest)(nil),         // 61: master.v1.RestoreFileRequest
+	(*RestoreFileResponse)(nil),        // 62: master.v1.RestoreFileResponse
+	(*PurgeTrashRequest)(nil),          // 63: master.v1.PurgeTrashRequest
+	(*PurgeTrashResponse)(nil),         // 64: master.v1.PurgeTrashResponse
+	(*PrepareChunkWriteRequest)(nil),   // 65: master.v1.PrepareChunkWriteRequest
+	(*PrepareChunkWriteResponse)(nil),  // 66: master.v1.PrepareChunkWriteResponse
+	(*IssueDataTokenRequest)(nil),      // 67: master.v1.IssueDataTokenRequest
+	(*IssueDataTokenResponse)(nil),     // 68: master.v1.IssueDataTokenResponse
+	(*SetNamespaceQuotaRequest)(nil),   // 69: master.v1.SetNamespaceQuotaRequest
+	(*SetNamespaceQuotaResponse)(nil),  // 70: master.v1.SetNamespaceQuotaResponse
+	(*SetStorageClassRequest)(nil),     // 71: master.v1.SetStorageClassRequest
+	(*SetStorageClassResponse)(nil),    // 72: master.v1.SetStorageClassResponse
+	(*SetCompressionRequest)(nil),      // 73: master.v1.SetCompressionRequest
+	(*SetCompressionResponse)(nil),     // 74: master.v1.SetCompressionResponse
+	(*GetNamespaceUsageRequest)(nil),   // 75: master.v1.GetNamespaceUsageRequest
+	(*NamespaceUsage)(nil),             // 76: master.v1.NamespaceUsage
+	(*GetNamespaceUsageResponse)(nil),  // 77: master.v1.GetNamespaceUsageResponse
+	(*ChunkServerStatus)(nil),          // 78: master.v1.ChunkServerStatus
+	(*GetClusterStatusRequest)(nil),    // 79: master.v1.GetClusterStatusRequest
+	(*GetClusterStatusResponse)(nil),   // 80: master.v1.GetClusterStatusResponse
+	(*DrainChunkServerRequest)(nil),    // 81: master.v1.DrainChunkServerRequest
+	(*DrainChunkServerResponse)(nil),   // 82: master.v1.DrainChunkServerResponse
+	(*GetDrainStatusRequest)(nil),      // 83: master.v1.GetDrainStatusRequest
+	(*DrainStatus)(nil),                // 84: master.v1.DrainStatus
+	(*GetDrainStatusResponse)(nil),     // 85: master.v1.GetDrainStatusResponse
+	(*RebalanceRequest)(nil),           // 86: master.v1.RebalanceRequest
+	(*RebalanceResponse)(nil),          // 87: master.v1.RebalanceResponse
+	(*GetRebalanceStatusRequest)(nil),  // 88: master.v1.GetRebalanceStatusRequest
+	(*ServerFill)(nil),                 // 89: master.v1.ServerFill
+	(*GetRebalanceStatusResponse)(nil), // 90: master.v1.GetRebalanceStatusResponse
+	(*MasterReplica)(nil),              // 91: master.v1.MasterReplica
+	(*GetLeaderRequest)(nil),           // 92: master.v1.GetLeaderRequest
+	(*GetLeaderResponse)(nil),          // 93: master.v1.GetLeaderResponse
+	(*RaftMessage)(nil),                // 94: master.v1.RaftMessage
+	(*RaftMessageResponse)(nil),        // 95: master.v1.RaftMessageResponse
+	nil,                                // 96: master.v1.FileInfoResponse.AttributesEntry
+	nil,                                // 97: master.v1.ChunkReport.VersionsEntry
+	nil,                                // 98: master.v1.RegisterRequest.ChunkVersionsEntry
+	nil,                                // 99: master.v1.HeartbeatRequest.ChunkVersionsEntry
+	nil,                                // 100: master.v1.CreateFileRequest.AttributesEntry
+	nil,                                // 101: master.v1.TransactOp.AttributesEntry
+	nil,                                // 102: master.v1.SetFileAttributesRequest.SetEntry
 }
 var file_master_master_proto_depIdxs = []int32{
-	1,  // 0: master.v1.ChunkLocationInfo.locations:type_name -> master.v1.ChunkServerInfo
-	1,  // 1: master.v1.ChunkLocationInfo.primary:type_name -> master.v1.ChunkServerInfo
-	0,  // 2: master.v1.RegisterRequest.build_info:type_name -> master.v1.BuildInfo
-	3,  // 3: master.v1.CreateFileResponse.file:type_name -> master.v1.FileInfoResponse
-	3,  // 4: master.v1.GetFileResponse.file:type_name -> master.v1.FileInfoResponse
-	3,  // 5: master.v1.ListFilesResponse.files:type_name -> master.v1.FileInfoResponse
-	2,  // 6: master.v1.AllocateChunkResponse.chunk:type_name -> master.v1.ChunkLocationInfo
-	2,  // 7: master.v1.GetChunkLocationsResponse.chunks:type_name -> master.v1.ChunkLocationInfo
-	1,  // 8: master.v1.ChunkServerStatus.server:type_name -> master.v1.ChunkServerInfo
-	0,  // 9: master.v1.ChunkServerStatus.build_info:type_name -> master.v1.BuildInfo
-	30, // 10: master.v1.GetClusterStatusResponse.servers:type_name -> master.v1.ChunkServerStatus
-	4,  // 11: master.v1.Master.Register:input_type -> master.v1.RegisterRequest
-	6,  // 12: master.v1.Master.Heartbeat:input_type -> master.v1.HeartbeatRequest
-	8,  // 13: master.v1.Master.ReportCommit:input_type -> master.v1.ReportCommitRequest
-	10, // 14: master.v1.Master.RenewLease:input_type -> master.v1.RenewLeaseRequest
-	12, // 15: master.v1.Master.ClaimPrimary:input_type -> master.v1.ClaimPrimaryRequest
-	14, // 16: master.v1.Master.CreateFile:input_type -> master.v1.CreateFileRequest
-	16, // 17: master.v1.Master.GetFile:input_type -> master.v1.GetFileRequest
-	18, // 18: master.v1.Master.DeleteFile:input_type -> master.v1.DeleteFileRequest
-	20, // 19: master.v1.Master.DeleteNamespace:input_type -> master.v1.DeleteNamespaceRequest
-	22, // 20: master.v1.Master.RenameFile:input_type -> master.v1.RenameFileRequest
-	24, // 21: master.v1.Master.ListFiles:input_type -> master.v1.ListFilesRequest
-	26, // 22: master.v1.Master.AllocateChunk:input_type -> master.v1.AllocateChunkRequest
-	28, // 23: master.v1.Master.GetChunkLocations:input_type -> master.v1.GetChunkLocationsRequest
-	31, // 24: master.v1.Master.GetClusterStatus:input_type -> master.v1.GetClusterStatusRequest
-	5,  // 25: master.v1.Master.Register:output_type -> master.v1.RegisterResponse
-	7,  // 26: master.v1.Master.Heartbeat:output_type -> master.v1.HeartbeatResponse
-	9,  // 27: master.v1.Master.ReportCommit:output_type -> master.v1.ReportCommitResponse
-	11, // 28: master.v1.Master.RenewLease:output_type -> master.v1.RenewLeaseResponse
-	13, // 29: master.v1.Master.ClaimPrimary:output_type -> master.v1.ClaimPrimaryResponse
-	15, // 30: master.v1.Master.CreateFile:output_type -> master.v1.CreateFileResponse
-	17, // 31: master.v1.Master.GetFile:output_type -> master.v1.GetFileResponse
-	19, // 32: master.v1.Master.DeleteFile:output_type -> master.v1.DeleteFileResponse
-	21, // 33: master.v1.Master.DeleteNamespace:output_type -> master.v1.DeleteNamespaceResponse
-	23, // 34: master.v1.Master.RenameFile:output_type -> master.v1.RenameFileResponse
-	25, // 35: master.v1.Master.ListFiles:output_type -> master.v1.ListFilesResponse
-	27, // 36: master.v1.Master.AllocateChunk:output_type -> master.v1.AllocateChunkResponse
-	29, // 37: master.v1.Master.GetChunkLocations:output_type -> master.v1.GetChunkLocationsResponse
-	32, // 38: master.v1.Master.GetClusterStatus:output_type -> master.v1.GetClusterStatusResponse
-	25, // [25:39] is the sub-list for method output_type
-	11, // [11:25] is the sub-list for method input_type
-	11, // [11:11] is the sub-list for extension type_name
-	11, // [11:11] is the sub-list for extension extendee
-	0,  // [0:11] is the sub-list for field type_name
+	1,   // 0: master.v1.ChunkLocationInfo.locations:type_name -> master.v1.ChunkServerInfo
+	1,   // 1: master.v1.ChunkLocationInfo.primary:type_name -> master.v1.ChunkServerInfo
+	3,   // 2: master.v1.ChunkLocationInfo.stripe:type_name -> master.v1.ChunkStripe
+	4,   // 3: master.v1.ChunkStripe.fragments:type_name -> master.v1.StripeFragment
+	1,   // 4: master.v1.StripeFragment.locations:type_name -> master.v1.ChunkServerInfo
+	96,  // 5: master.v1.FileInfoResponse.attributes:type_name -> master.v1.FileInfoResponse.AttributesEntry
+	97,  // 6: master.v1.ChunkReport.versions:type_name -> master.v1.ChunkReport.VersionsEntry
+	0,   // 7: master.v1.RegisterRequest.build_info:type_name -> master.v1.BuildInfo
+	98,  // 8: master.v1.RegisterRequest.chunk_versions:type_name -> master.v1.RegisterRequest.ChunkVersionsEntry
+	6,   // 9: master.v1.RegisterRequest.disks:type_name -> master.v1.DiskStatus
+	99,  // 10: master.v1.HeartbeatRequest.chunk_versions:type_name -> master.v1.HeartbeatRequest.ChunkVersionsEntry
+	6,   // 11: master.v1.HeartbeatRequest.disks:type_name -> master.v1.DiskStatus
+	7,   // 12: master.v1.HeartbeatRequest.load:type_name -> master.v1.ServerLoad
+	8,   // 13: master.v1.HeartbeatRequest.chunk_report:type_name -> master.v1.ChunkReport
+	13,  // 14: master.v1.HeartbeatResponse.chunks_to_replicate:type_name -> master.v1.ReplicateChunkCommand
+	14,  // 15: master.v1.HeartbeatResponse.chunks_to_encode:type_name -> master.v1.EncodeChunkCommand
+	1,   // 16: master.v1.ReplicateChunkCommand.target:type_name -> master.v1.ChunkServerInfo
+	15,  // 17: master.v1.EncodeChunkCommand.sources:type_name -> master.v1.FragmentPlacement
+	15,  // 18: master.v1.EncodeChunkCommand.targets:type_name -> master.v1.FragmentPlacement
+	1,   // 19: master.v1.FragmentPlacement.server:type_name -> master.v1.ChunkServerInfo
+	100, // 20: master.v1.CreateFileRequest.attributes:type_name -> master.v1.CreateFileRequest.AttributesEntry
+	5,   // 21: master.v1.CreateFileResponse.file:type_name -> master.v1.FileInfoResponse
+	5,   // 22: master.v1.GetFileResponse.file:type_name -> master.v1.FileInfoResponse
+	101, // 23: master.v1.TransactOp.attributes:type_name -> master.v1.TransactOp.AttributesEntry
+	38,  // 24: master.v1.TransactRequest.conditions:type_name -> master.v1.TransactCondition
+	39,  // 25: master.v1.TransactRequest.ops:type_name -> master.v1.TransactOp
+	102, // 26: master.v1.SetFileAttributesRequest.set:type_name -> master.v1.SetFileAttributesRequest.SetEntry
+	5,   // 27: master.v1.SetFileAttributesResponse.file:type_name -> master.v1.FileInfoResponse
+	5,   // 28: master.v1.ListFilesResponse.files:type_name -> master.v1.FileInfoResponse
+	5,   // 29: master.v1.ListFilesV2Response.files:type_name -> master.v1.FileInfoResponse
+	2,   // 30: master.v1.AllocateChunkResponse.chunk:type_name -> master.v1.ChunkLocationInfo
+	2,   // 31: master.v1.GetChunkLocationsResponse.chunks:type_name -> master.v1.ChunkLocationInfo
+	5,   // 32: master.v1.SnapshotFileResponse.file:type_name -> master.v1.FileInfoResponse
+	58,  // 33: master.v1.ListTrashResponse.entries:type_name -> master.v1.TrashEntry
+	5,   // 34: master.v1.RestoreFileResponse.file:type_name -> master.v1.FileInfoResponse
+	2,   // 35: master.v1.PrepareChunkWriteResponse.chunk:type_name -> master.v1.ChunkLocationInfo
+	76,  // 36: master.v1.GetNamespaceUsageResponse.namespaces:type_name -> master.v1.NamespaceUsage
+	1,   // 37: master.v1.ChunkServerStatus.server:type_name -> master.v1.ChunkServerInfo
+	0,   // 38: master.v1.ChunkServerStatus.build_info:type_name -> master.v1.BuildInfo
+	6,   // 39: master.v1.ChunkServerStatus.disks:type_name -> master.v1.DiskStatus
+	7,   // 40: master.v1.ChunkServerStatus.load:type_name -> master.v1.ServerLoad
+	78,  // 41: master.v1.GetClusterStatusResponse.servers:type_name -> master.v1.ChunkServerStatus
+	84,  // 42: master.v1.GetDrainStatusResponse.servers:type_name -> master.v1.DrainStatus
+	89,  // 43: master.v1.GetRebalanceStatusResponse.servers:type_name -> master.v1.ServerFill
+	91,  // 44: master.v1.GetLeaderResponse.replicas:type_name -> master.v1.MasterReplica
+	9,   // 45: master.v1.Master.Register:input_type -> master.v1.RegisterRequest
+	11,  // 46: master.v1.Master.Heartbeat:input_type -> master.v1.HeartbeatRequest
+	20,  // 47: master.v1.Master.ReportCommit:input_type -> master.v1.ReportCommitRequest
+	22,  // 48: master.v1.Master.RenewLease:input_type -> master.v1.RenewLeaseRequest
+	24,  // 49: master.v1.Master.ClaimPrimary:input_type -> master.v1.ClaimPrimaryRequest
+	18,  // 50: master.v1.Master.ReportReplication:input_type -> master.v1.ReportReplicationRequest
+	26,  // 51: master.v1.Master.ReportCorruptChunk:input_type -> master.v1.ReportCorruptChunkRequest
+	16,  // 52: master.v1.Master.ReportEncode:input_type -> master.v1.ReportEncodeRequest
+	28,  // 53: master.v1.Master.CreateFile:input_type -> master.v1.CreateFileRequest
+	30,  // 54: master.v1.Master.GetFile:input_type -> master.v1.GetFileRequest
+	32,  // 55: master.v1.Master.DeleteFile:input_type -> master.v1.DeleteFileRequest
+	34,  // 56: master.v1.Master.DeleteNamespace:input_type -> master.v1.DeleteNamespaceRequest
+	36,  // 57: master.v1.Master.RenameFile:input_type -> master.v1.RenameFileRequest
+	40,  // 58: master.v1.Master.Transact:input_type -> master.v1.TransactRequest
+	42,  // 59: master.v1.Master.SetFileAttributes:input_type -> master.v1.SetFileAttributesRequest
+	44,  // 60: master.v1.Master.WatchNamespace:input_type -> master.v1.WatchNamespaceRequest
+	46,  // 61: master.v1.Master.ListFiles:input_type -> master.v1.ListFilesRequest
+	48,  // 62: master.v1.Master.ListFilesV2:input_type -> master.v1.ListFilesV2Request
+	54,  // 63: master.v1.Master.SnapshotFile:input_type -> master.v1.SnapshotFileRequest
+	56,  // 64: master.v1.Master.SnapshotNamespace:input_type -> master.v1.SnapshotNamespaceRequest
+	59,  // 65: master.v1.Master.ListTrash:input_type -> master.v1.ListTrashRequest
+	61,  // 66: master.v1.Master.RestoreFile:input_type -> master.v1.RestoreFileRequest
+	63,  // 67: master.v1.Master.PurgeTrash:input_type -> master.v1.PurgeTrashRequest
+	50,  // 68: master.v1.Master.AllocateChunk:input_type -> master.v1.AllocateChunkRequest
+	52,  // 69: master.v1.Master.GetChunkLocations:input_type -> master.v1.GetChunkLocationsRequest
+	65,  // 70: master.v1.Master.PrepareChunkWrite:input_type -> master.v1.PrepareChunkWriteRequest
+	67,  // 71: master.v1.Master.IssueDataToken:input_type -> master.v1.IssueDataTokenRequest
+	69,  // 72: master.v1.Master.SetNamespaceQuota:input_type -> master.v1.SetNamespaceQuotaRequest
+	75,  // 73: master.v1.Master.GetNamespaceUsage:input_type -> master.v1.GetNamespaceUsageRequest
+	71,  // 74: master.v1.Master.SetStorageClass:input_type -> master.v1.SetStorageClassRequest
+	73,  // 75: master.v1.Master.SetCompression:input_type -> master.v1.SetCompressionRequest
+	79,  // 76: master.v1.Master.GetClusterStatus:input_type -> master.v1.GetClusterStatusRequest
+	81,  // 77: master.v1.Master.DrainChunkServer:input_type -> master.v1.DrainChunkServerRequest
+	83,  // 78: master.v1.Master.GetDrainStatus:input_type -> master.v1.GetDrainStatusRequest
+	86,  // 79: master.v1.Master.Rebalance:input_type -> master.v1.RebalanceRequest
+	88,  // 80: master.v1.Master.GetRebalanceStatus:input_type -> master.v1.GetRebalanceStatusRequest
+	92,  // 81: master.v1.Master.GetLeader:input_type -> master.v1.GetLeaderRequest
+	94,  // 82: master.v1.MasterPeer.Step:input_type -> master.v1.RaftMessage
+	10,  // 83: master.v1.Master.Register:output_type -> master.v1.RegisterResponse
+	12,  // 84: master.v1.Master.Heartbeat:output_type -> master.v1.HeartbeatResponse
+	21,  // 85: master.v1.Master.ReportCommit:output_type -> master.v1.ReportCommitResponse
+	23,  // 86: master.v1.Master.RenewLease:output_type -> master.v1.RenewLeaseResponse
+	25,  // 87: master.v1.Master.ClaimPrimary:output_type -> master.v1.ClaimPrimaryResponse
+	19,  // 88: master.v1.Master.ReportReplication:output_type -> master.v1.ReportReplicationResponse
+	27,  // 89: master.v1.Master.ReportCorruptChunk:output_type -> master.v1.ReportCorruptChunkResponse
+	17,  // 90: master.v1.Master.ReportEncode:output_type -> master.v1.ReportEncodeResponse
+	29,  // 91: master.v1.Master.CreateFile:output_type -> master.v1.CreateFileResponse
+	31,  // 92: master.v1.Master.GetFile:output_type -> master.v1.GetFileResponse
+	33,  // 93: master.v1.Master.DeleteFile:output_type -> master.v1.DeleteFileResponse
+	35,  // 94: master.v1.Master.DeleteNamespace:output_type -> master.v1.DeleteNamespaceResponse
+	37,  // 95: master.v1.Master.RenameFile:output_type -> master.v1.RenameFileResponse
+	41,  // 96: master.v1.Master.Transact:output_type -> master.v1.TransactResponse
+	43,  // 97: master.v1.Master.SetFileAttributes:output_type -> master.v1.SetFileAttributesResponse
+	45,  // 98: master.v1.Master.WatchNamespace:output_type -> master.v1.NamespaceEvent
+	47,  // 99: master.v1.Master.ListFiles:output_type -> master.v1.ListFilesResponse
+	49,  // 100: master.v1.Master.ListFilesV2:output_type -> master.v1.ListFilesV2Response
+	55,  // 101: master.v1.Master.SnapshotFile:output_type -> master.v1.SnapshotFileResponse
+	57,  // 102: master.v1.Master.SnapshotNamespace:output_type -> master.v1.SnapshotNamespaceResponse
+	60,  // 103: master.v1.Master.ListTrash:output_type -> master.v1.ListTrashResponse
+	62,  // 104: master.v1.Master.RestoreFile:output_type -> master.v1.RestoreFileResponse
+	64,  // 105: master.v1.Master.PurgeTrash:output_type -> master.v1.PurgeTrashResponse
+	51,  // 106: master.v1.Master.AllocateChunk:output_type -> master.v1.AllocateChunkResponse
+	53,  // 107: master.v1.Master.GetChunkLocations:output_type -> master.v1.GetChunkLocationsResponse
+	66,  // 108: master.v1.Master.PrepareChunkWrite:output_type -> master.v1.PrepareChunkWriteResponse
+	68,  // 109: master.v1.Master.IssueDataToken:output_type -> master.v1.IssueDataTokenResponse
+	70,  // 110: master.v1.Master.SetNamespaceQuota:output_type -> master.v1.SetNamespaceQuotaResponse
+	77,  // 111: master.v1.Master.GetNamespaceUsage:output_type -> master.v1.GetNamespaceUsageResponse
+	72,  // 112: master.v1.Master.SetStorageClass:output_type -> master.v1.SetStorageClassResponse
+	74,  // 113: master.v1.Master.SetCompression:output_type -> master.v1.SetCompressionResponse
+	80,  // 114: master.v1.Master.GetClusterStatus:output_type -> master.v1.GetClusterStatusResponse
+	82,  // 115: master.v1.Master.DrainChunkServer:output_type -> master.v1.DrainChunkServerResponse
+	85,  // 116: master.v1.Master.GetDrainStatus:output_type -> master.v1.GetDrainStatusResponse
+	87,  // 117: master.v1.Master.Rebalance:output_type -> master.v1.RebalanceResponse
+	90,  // 118: master.v1.Master.GetRebalanceStatus:output_type -> master.v1.GetRebalanceStatusResponse
+	93,  // 119: master.v1.Master.GetLeader:output_type -> master.v1.GetLeaderResponse
+	95,  // 120: master.v1.MasterPeer.Step:output_type -> master.v1.RaftMessageResponse
+	83,  // [83:121] is the sub-list for method output_type
+	45,  // [45:83] is the sub-list for method input_type
+	45,  // [45:45] is the sub-list for extension type_name
+	45,  // [45:45] is the sub-list for extension extendee
+	0,   // [0:45] is the sub-list for field type_name
 }
 
 func init() { file_master_master_proto_init() }
@@ -2165,9 +7123,9 @@ func file_master_master_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_master_master_proto_rawDesc), len(file_master_master_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   33,
+			NumMessages:   103,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_master_master_proto_goTypes,
 		DependencyIndexes: file_master_master_proto_depIdxs,
@@ -19,20 +19,43 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Master_Register_FullMethodName          = "/master.v1.Master/Register"
-	Master_Heartbeat_FullMethodName         = "/master.v1.Master/Heartbeat"
-	Master_ReportCommit_FullMethodName      = "/master.v1.Master/ReportCommit"
-	Master_RenewLease_FullMethodName        = "/master.v1.Master/RenewLease"
-	Master_ClaimPrimary_FullMethodName      = "/master.v1.Master/ClaimPrimary"
-	Master_CreateFile_FullMethodName        = "/master.v1.Master/CreateFile"
-	Master_GetFile_FullMethodName           = "/master.v1.Master/GetFile"
-	Master_DeleteFile_FullMethodName        = "/master.v1.Master/DeleteFile"
-	Master_DeleteNamespace_FullMethodName   = "/master.v1.Master/DeleteNamespace"
-	Master_RenameFile_FullMethodName        = "/master.v1.Master/RenameFile"
-	Master_ListFiles_FullMethodName         = "/master.v1.Master/ListFiles"
-	Master_AllocateChunk_FullMethodName     = "/master.v1.Master/AllocateChunk"
-	Master_GetChunkLocations_FullMethodName = "/master.v1.Master/GetChunkLocations"
-	Master_GetClusterStatus_FullMethodName  = "/master.v1.Master/GetClusterStatus"
+	Master_Register_FullMethodName           = "/master.v1.Master/Register"
+	Master_Heartbeat_FullMethodName          = "/master.v1.Master/Heartbeat"
+	Master_ReportCommit_FullMethodName       = "/master.v1.Master/ReportCommit"
+	Master_RenewLease_FullMethodName         = "/master.v1.Master/RenewLease"
+	Master_ClaimPrimary_FullMethodName       = "/master.v1.Master/ClaimPrimary"
+	Master_ReportReplication_FullMethodName  = "/master.v1.Master/ReportReplication"
+	Master_ReportCorruptChunk_FullMethodName = "/master.v1.Master/ReportCorruptChunk"
+	Master_ReportEncode_FullMethodName       = "/master.v1.Master/ReportEncode"
+	Master_CreateFile_FullMethodName         = "/master.v1.Master/CreateFile"
+	Master_GetFile_FullMethodName            = "/master.v1.Master/GetFile"
+	Master_DeleteFile_FullMethodName         = "/master.v1.Master/DeleteFile"
+	Master_DeleteNamespace_FullMethodName    = "/master.v1.Master/DeleteNamespace"
+	Master_RenameFile_FullMethodName         = "/master.v1.Master/RenameFile"
+	Master_Transact_FullMethodName           = "/master.v1.Master/Transact"
+	Master_SetFileAttributes_FullMethodName  = "/master.v1.Master/SetFileAttributes"
+	Master_WatchNamespace_FullMethodName     = "/master.v1.Master/WatchNamespace"
+	Master_ListFiles_FullMethodName          = "/master.v1.Master/ListFiles"
+	Master_ListFilesV2_FullMethodName        = "/master.v1.Master/ListFilesV2"
+	Master_SnapshotFile_FullMethodName       = "/master.v1.Master/SnapshotFile"
+	Master_SnapshotNamespace_FullMethodName  = "/master.v1.Master/SnapshotNamespace"
+	Master_ListTrash_FullMethodName          = "/master.v1.Master/ListTrash"
+	Master_RestoreFile_FullMethodName        = "/master.v1.Master/RestoreFile"
+	Master_PurgeTrash_FullMethodName         = "/master.v1.Master/PurgeTrash"
+	Master_AllocateChunk_FullMethodName      = "/master.v1.Master/AllocateChunk"
+	Master_GetChunkLocations_FullMethodName  = "/master.v1.Master/GetChunkLocations"
+	Master_PrepareChunkWrite_FullMethodName  = "/master.v1.Master/PrepareChunkWrite"
+	Master_IssueDataToken_FullMethodName     = "/master.v1.Master/IssueDataToken"
+	Master_SetNamespaceQuota_FullMethodName  = "/master.v1.Master/SetNamespaceQuota"
+	Master_GetNamespaceUsage_FullMethodName  = "/master.v1.Master/GetNamespaceUsage"
+	Master_SetStorageClass_FullMethodName    = "/master.v1.Master/SetStorageClass"
+	Master_SetCompression_FullMethodName     = "/master.v1.Master/SetCompression"
+	Master_GetClusterStatus_FullMethodName   = "/master.v1.Master/GetClusterStatus"
+	Master_DrainChunkServer_FullMethodName   = "/master.v1.Master/DrainChunkServer"
+	Master_GetDrainStatus_FullMethodName     = "/master.v1.Master/GetDrainStatus"
+	Master_Rebalance_FullMethodName          = "/master.v1.Master/Rebalance"
+	Master_GetRebalanceStatus_FullMethodName = "/master.v1.Master/GetRebalanceStatus"
+	Master_GetLeader_FullMethodName          = "/master.v1.Master/GetLeader"
 )
 
 // MasterClient is the client API for Master service.
@@ -47,18 +70,46 @@ type MasterClient interface {
 	ReportCommit(ctx context.Context, in *ReportCommitRequest, opts ...grpc.CallOption) (*ReportCommitResponse, error)
 	RenewLease(ctx context.Context, in *RenewLeaseRequest, opts ...grpc.CallOption) (*RenewLeaseResponse, error)
 	ClaimPrimary(ctx context.Context, in *ClaimPrimaryRequest, opts ...grpc.CallOption) (*ClaimPrimaryResponse, error)
+	ReportReplication(ctx context.Context, in *ReportReplicationRequest, opts ...grpc.CallOption) (*ReportReplicationResponse, error)
+	ReportCorruptChunk(ctx context.Context, in *ReportCorruptChunkRequest, opts ...grpc.CallOption) (*ReportCorruptChunkResponse, error)
+	ReportEncode(ctx context.Context, in *ReportEncodeRequest, opts ...grpc.CallOption) (*ReportEncodeResponse, error)
 	// File operations
 	CreateFile(ctx context.Context, in *CreateFileRequest, opts ...grpc.CallOption) (*CreateFileResponse, error)
 	GetFile(ctx context.Context, in *GetFileRequest, opts ...grpc.CallOption) (*GetFileResponse, error)
 	DeleteFile(ctx context.Context, in *DeleteFileRequest, opts ...grpc.CallOption) (*DeleteFileResponse, error)
 	DeleteNamespace(ctx context.Context, in *DeleteNamespaceRequest, opts ...grpc.CallOption) (*DeleteNamespaceResponse, error)
 	RenameFile(ctx context.Context, in *RenameFileRequest, opts ...grpc.CallOption) (*RenameFileResponse, error)
+	Transact(ctx context.Context, in *TransactRequest, opts ...grpc.CallOption) (*TransactResponse, error)
+	SetFileAttributes(ctx context.Context, in *SetFileAttributesRequest, opts ...grpc.CallOption) (*SetFileAttributesResponse, error)
+	WatchNamespace(ctx context.Context, in *WatchNamespaceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NamespaceEvent], error)
 	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	ListFilesV2(ctx context.Context, in *ListFilesV2Request, opts ...grpc.CallOption) (*ListFilesV2Response, error)
+	SnapshotFile(ctx context.Context, in *SnapshotFileRequest, opts ...grpc.CallOption) (*SnapshotFileResponse, error)
+	SnapshotNamespace(ctx context.Context, in *SnapshotNamespaceRequest, opts ...grpc.CallOption) (*SnapshotNamespaceResponse, error)
+	// Trash
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error)
+	RestoreFile(ctx context.Context, in *RestoreFileRequest, opts ...grpc.CallOption) (*RestoreFileResponse, error)
+	PurgeTrash(ctx context.Context, in *PurgeTrashRequest, opts ...grpc.CallOption) (*PurgeTrashResponse, error)
 	// Chunk operations
 	AllocateChunk(ctx context.Context, in *AllocateChunkRequest, opts ...grpc.CallOption) (*AllocateChunkResponse, error)
 	GetChunkLocations(ctx context.Context, in *GetChunkLocationsRequest, opts ...grpc.CallOption) (*GetChunkLocationsResponse, error)
+	PrepareChunkWrite(ctx context.Context, in *PrepareChunkWriteRequest, opts ...grpc.CallOption) (*PrepareChunkWriteResponse, error)
+	IssueDataToken(ctx context.Context, in *IssueDataTokenRequest, opts ...grpc.CallOption) (*IssueDataTokenResponse, error)
+	// Namespace quotas and usage
+	SetNamespaceQuota(ctx context.Context, in *SetNamespaceQuotaRequest, opts ...grpc.CallOption) (*SetNamespaceQuotaResponse, error)
+	GetNamespaceUsage(ctx context.Context, in *GetNamespaceUsageRequest, opts ...grpc.CallOption) (*GetNamespaceUsageResponse, error)
+	// Storage classes
+	SetStorageClass(ctx context.Context, in *SetStorageClassRequest, opts ...grpc.CallOption) (*SetStorageClassResponse, error)
+	// Chunk compression
+	SetCompression(ctx context.Context, in *SetCompressionRequest, opts ...grpc.CallOption) (*SetCompressionResponse, error)
 	// Cluster status
 	GetClusterStatus(ctx context.Context, in *GetClusterStatusRequest, opts ...grpc.CallOption) (*GetClusterStatusResponse, error)
+	// Decommission and rebalance
+	DrainChunkServer(ctx context.Context, in *DrainChunkServerRequest, opts ...grpc.CallOption) (*DrainChunkServerResponse, error)
+	GetDrainStatus(ctx context.Context, in *GetDrainStatusRequest, opts ...grpc.CallOption) (*GetDrainStatusResponse, error)
+	Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*RebalanceResponse, error)
+	GetRebalanceStatus(ctx context.Context, in *GetRebalanceStatusRequest, opts ...grpc.CallOption) (*GetRebalanceStatusResponse, error)
+	GetLeader(ctx context.Context, in *GetLeaderRequest, opts ...grpc.CallOption) (*GetLeaderResponse, error)
 }
 
 type masterClient struct {
@@ -119,6 +170,36 @@ func (c *masterClient) ClaimPrimary(ctx context.Context, in *ClaimPrimaryRequest
 	return out, nil
 }
 
+func (c *masterClient) ReportReplication(ctx context.Context, in *ReportReplicationRequest, opts ...grpc.CallOption) (*ReportReplicationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportReplicationResponse)
+	err := c.cc.Invoke(ctx, Master_ReportReplication_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) ReportCorruptChunk(ctx context.Context, in *ReportCorruptChunkRequest, opts ...grpc.CallOption) (*ReportCorruptChunkResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportCorruptChunkResponse)
+	err := c.cc.Invoke(ctx, Master_ReportCorruptChunk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) ReportEncode(ctx context.Context, in *ReportEncodeRequest, opts ...grpc.CallOption) (*ReportEncodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportEncodeResponse)
+	err := c.cc.Invoke(ctx, Master_ReportEncode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *masterClient) CreateFile(ctx context.Context, in *CreateFileRequest, opts ...grpc.CallOption) (*CreateFileResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(CreateFileResponse)
@@ -169,6 +250,45 @@ func (c *masterClient) RenameFile(ctx context.Context, in *RenameFileRequest, op
 	return out, nil
 }
 
+func (c *masterClient) Transact(ctx context.Context, in *TransactRequest, opts ...grpc.CallOption) (*TransactResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransactResponse)
+	err := c.cc.Invoke(ctx, Master_Transact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SetFileAttributes(ctx context.Context, in *SetFileAttributesRequest, opts ...grpc.CallOption) (*SetFileAttributesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetFileAttributesResponse)
+	err := c.cc.Invoke(ctx, Master_SetFileAttributes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) WatchNamespace(ctx context.Context, in *WatchNamespaceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NamespaceEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Master_ServiceDesc.Streams[0], Master_WatchNamespace_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchNamespaceRequest, NamespaceEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Master_WatchNamespaceClient = grpc.ServerStreamingClient[NamespaceEvent]
+
 func (c *masterClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListFilesResponse)
@@ -179,6 +299,66 @@ func (c *masterClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts
 	return out, nil
 }
 
+func (c *masterClient) ListFilesV2(ctx context.Context, in *ListFilesV2Request, opts ...grpc.CallOption) (*ListFilesV2Response, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFilesV2Response)
+	err := c.cc.Invoke(ctx, Master_ListFilesV2_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SnapshotFile(ctx context.Context, in *SnapshotFileRequest, opts ...grpc.CallOption) (*SnapshotFileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SnapshotFileResponse)
+	err := c.cc.Invoke(ctx, Master_SnapshotFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SnapshotNamespace(ctx context.Context, in *SnapshotNamespaceRequest, opts ...grpc.CallOption) (*SnapshotNamespaceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SnapshotNamespaceResponse)
+	err := c.cc.Invoke(ctx, Master_SnapshotNamespace_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTrashResponse)
+	err := c.cc.Invoke(ctx, Master_ListTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) RestoreFile(ctx context.Context, in *RestoreFileRequest, opts ...grpc.CallOption) (*RestoreFileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreFileResponse)
+	err := c.cc.Invoke(ctx, Master_RestoreFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) PurgeTrash(ctx context.Context, in *PurgeTrashRequest, opts ...grpc.CallOption) (*PurgeTrashResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PurgeTrashResponse)
+	err := c.cc.Invoke(ctx, Master_PurgeTrash_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *masterClient) AllocateChunk(ctx context.Context, in *AllocateChunkRequest, opts ...grpc.CallOption) (*AllocateChunkResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(AllocateChunkResponse)
@@ -199,6 +379,66 @@ func (c *masterClient) GetChunkLocations(ctx context.Context, in *GetChunkLocati
 	return out, nil
 }
 
+func (c *masterClient) PrepareChunkWrite(ctx context.Context, in *PrepareChunkWriteRequest, opts ...grpc.CallOption) (*PrepareChunkWriteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PrepareChunkWriteResponse)
+	err := c.cc.Invoke(ctx, Master_PrepareChunkWrite_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) IssueDataToken(ctx context.Context, in *IssueDataTokenRequest, opts ...grpc.CallOption) (*IssueDataTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IssueDataTokenResponse)
+	err := c.cc.Invoke(ctx, Master_IssueDataToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SetNamespaceQuota(ctx context.Context, in *SetNamespaceQuotaRequest, opts ...grpc.CallOption) (*SetNamespaceQuotaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetNamespaceQuotaResponse)
+	err := c.cc.Invoke(ctx, Master_SetNamespaceQuota_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) GetNamespaceUsage(ctx context.Context, in *GetNamespaceUsageRequest, opts ...grpc.CallOption) (*GetNamespaceUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetNamespaceUsageResponse)
+	err := c.cc.Invoke(ctx, Master_GetNamespaceUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SetStorageClass(ctx context.Context, in *SetStorageClassRequest, opts ...grpc.CallOption) (*SetStorageClassResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetStorageClassResponse)
+	err := c.cc.Invoke(ctx, Master_SetStorageClass_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) SetCompression(ctx context.Context, in *SetCompressionRequest, opts ...grpc.CallOption) (*SetCompressionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetCompressionResponse)
+	err := c.cc.Invoke(ctx, Master_SetCompression_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *masterClient) GetClusterStatus(ctx context.Context, in *GetClusterStatusRequest, opts ...grpc.CallOption) (*GetClusterStatusResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetClusterStatusResponse)
@@ -209,6 +449,56 @@ func (c *masterClient) GetClusterStatus(ctx context.Context, in *GetClusterStatu
 	return out, nil
 }
 
+func (c *masterClient) DrainChunkServer(ctx context.Context, in *DrainChunkServerRequest, opts ...grpc.CallOption) (*DrainChunkServerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainChunkServerResponse)
+	err := c.cc.Invoke(ctx, Master_DrainChunkServer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) GetDrainStatus(ctx context.Context, in *GetDrainStatusRequest, opts ...grpc.CallOption) (*GetDrainStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDrainStatusResponse)
+	err := c.cc.Invoke(ctx, Master_GetDrainStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) Rebalance(ctx context.Context, in *RebalanceRequest, opts ...grpc.CallOption) (*RebalanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RebalanceResponse)
+	err := c.cc.Invoke(ctx, Master_Rebalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) GetRebalanceStatus(ctx context.Context, in *GetRebalanceStatusRequest, opts ...grpc.CallOption) (*GetRebalanceStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRebalanceStatusResponse)
+	err := c.cc.Invoke(ctx, Master_GetRebalanceStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *masterClient) GetLeader(ctx context.Context, in *GetLeaderRequest, opts ...grpc.CallOption) (*GetLeaderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLeaderResponse)
+	err := c.cc.Invoke(ctx, Master_GetLeader_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MasterServer is the server API for Master service.
 // All implementations must embed UnimplementedMasterServer
 // for forward compatibility.
@@ -221,18 +511,46 @@ type MasterServer interface {
 	ReportCommit(context.Context, *ReportCommitRequest) (*ReportCommitResponse, error)
 	RenewLease(context.Context, *RenewLeaseRequest) (*RenewLeaseResponse, error)
 	ClaimPrimary(context.Context, *ClaimPrimaryRequest) (*ClaimPrimaryResponse, error)
+	ReportReplication(context.Context, *ReportReplicationRequest) (*ReportReplicationResponse, error)
+	ReportCorruptChunk(context.Context, *ReportCorruptChunkRequest) (*ReportCorruptChunkResponse, error)
+	ReportEncode(context.Context, *ReportEncodeRequest) (*ReportEncodeResponse, error)
 	// File operations
 	CreateFile(context.Context, *CreateFileRequest) (*CreateFileResponse, error)
 	GetFile(context.Context, *GetFileRequest) (*GetFileResponse, error)
 	DeleteFile(context.Context, *DeleteFileRequest) (*DeleteFileResponse, error)
 	DeleteNamespace(context.Context, *DeleteNamespaceRequest) (*DeleteNamespaceResponse, error)
 	RenameFile(context.Context, *RenameFileRequest) (*RenameFileResponse, error)
+	Transact(context.Context, *TransactRequest) (*TransactResponse, error)
+	SetFileAttributes(context.Context, *SetFileAttributesRequest) (*SetFileAttributesResponse, error)
+	WatchNamespace(*WatchNamespaceRequest, grpc.ServerStreamingServer[NamespaceEvent]) error
 	ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
+	ListFilesV2(context.Context, *ListFilesV2Request) (*ListFilesV2Response, error)
+	SnapshotFile(context.Context, *SnapshotFileRequest) (*SnapshotFileResponse, error)
+	SnapshotNamespace(context.Context, *SnapshotNamespaceRequest) (*SnapshotNamespaceResponse, error)
+	// Trash
+	ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error)
+	RestoreFile(context.Context, *RestoreFileRequest) (*RestoreFileResponse, error)
+	PurgeTrash(context.Context, *PurgeTrashRequest) (*PurgeTrashResponse, error)
 	// Chunk operations
 	AllocateChunk(context.Context, *AllocateChunkRequest) (*AllocateChunkResponse, error)
 	GetChunkLocations(context.Context, *GetChunkLocationsRequest) (*GetChunkLocationsResponse, error)
+	PrepareChunkWrite(context.Context, *PrepareChunkWriteRequest) (*PrepareChunkWriteResponse, error)
+	IssueDataToken(context.Context, *IssueDataTokenRequest) (*IssueDataTokenResponse, error)
+	// Namespace quotas and usage
+	SetNamespaceQuota(context.Context, *SetNamespaceQuotaRequest) (*SetNamespaceQuotaResponse, error)
+	GetNamespaceUsage(context.Context, *GetNamespaceUsageRequest) (*GetNamespaceUsageResponse, error)
+	// Storage classes
+	SetStorageClass(context.Context, *SetStorageClassRequest) (*SetStorageClassResponse, error)
+	// Chunk compression
+	SetCompression(context.Context, *SetCompressionRequest) (*SetCompressionResponse, error)
 	// Cluster status
 	GetClusterStatus(context.Context, *GetClusterStatusRequest) (*GetClusterStatusResponse, error)
+	// Decommission and rebalance
+	DrainChunkServer(context.Context, *DrainChunkServerRequest) (*DrainChunkServerResponse, error)
+	GetDrainStatus(context.Context, *GetDrainStatusRequest) (*GetDrainStatusResponse, error)
+	Rebalance(context.Context, *RebalanceRequest) (*RebalanceResponse, error)
+	GetRebalanceStatus(context.Context, *GetRebalanceStatusRequest) (*GetRebalanceStatusResponse, error)
+	GetLeader(context.Context, *GetLeaderRequest) (*GetLeaderResponse, error)
 	mustEmbedUnimplementedMasterServer()
 }
 
@@ -258,6 +576,15 @@ func (UnimplementedMasterServer) RenewLease(context.Context, *RenewLeaseRequest)
 func (UnimplementedMasterServer) ClaimPrimary(context.Context, *ClaimPrimaryRequest) (*ClaimPrimaryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ClaimPrimary not implemented")
 }
+func (UnimplementedMasterServer) ReportReplication(context.Context, *ReportReplicationRequest) (*ReportReplicationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportReplication not implemented")
+}
+func (UnimplementedMasterServer) ReportCorruptChunk(context.Context, *ReportCorruptChunkRequest) (*ReportCorruptChunkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportCorruptChunk not implemented")
+}
+func (UnimplementedMasterServer) ReportEncode(context.Context, *ReportEncodeRequest) (*ReportEncodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportEncode not implemented")
+}
 func (UnimplementedMasterServer) CreateFile(context.Context, *CreateFileRequest) (*CreateFileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateFile not implemented")
 }
@@ -273,18 +600,78 @@ func (UnimplementedMasterServer) DeleteNamespace(context.Context, *DeleteNamespa
 func (UnimplementedMasterServer) RenameFile(context.Context, *RenameFileRequest) (*RenameFileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RenameFile not implemented")
 }
+func (UnimplementedMasterServer) Transact(context.Context, *TransactRequest) (*TransactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transact not implemented")
+}
+func (UnimplementedMasterServer) SetFileAttributes(context.Context, *SetFileAttributesRequest) (*SetFileAttributesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFileAttributes not implemented")
+}
+func (UnimplementedMasterServer) WatchNamespace(*WatchNamespaceRequest, grpc.ServerStreamingServer[NamespaceEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchNamespace not implemented")
+}
 func (UnimplementedMasterServer) ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
 }
+func (UnimplementedMasterServer) ListFilesV2(context.Context, *ListFilesV2Request) (*ListFilesV2Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFilesV2 not implemented")
+}
+func (UnimplementedMasterServer) SnapshotFile(context.Context, *SnapshotFileRequest) (*SnapshotFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SnapshotFile not implemented")
+}
+func (UnimplementedMasterServer) SnapshotNamespace(context.Context, *SnapshotNamespaceRequest) (*SnapshotNamespaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SnapshotNamespace not implemented")
+}
+func (UnimplementedMasterServer) ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedMasterServer) RestoreFile(context.Context, *RestoreFileRequest) (*RestoreFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreFile not implemented")
+}
+func (UnimplementedMasterServer) PurgeTrash(context.Context, *PurgeTrashRequest) (*PurgeTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeTrash not implemented")
+}
 func (UnimplementedMasterServer) AllocateChunk(context.Context, *AllocateChunkRequest) (*AllocateChunkResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AllocateChunk not implemented")
 }
 func (UnimplementedMasterServer) GetChunkLocations(context.Context, *GetChunkLocationsRequest) (*GetChunkLocationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetChunkLocations not implemented")
 }
+func (UnimplementedMasterServer) PrepareChunkWrite(context.Context, *PrepareChunkWriteRequest) (*PrepareChunkWriteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrepareChunkWrite not implemented")
+}
+func (UnimplementedMasterServer) IssueDataToken(context.Context, *IssueDataTokenRequest) (*IssueDataTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueDataToken not implemented")
+}
+func (UnimplementedMasterServer) SetNamespaceQuota(context.Context, *SetNamespaceQuotaRequest) (*SetNamespaceQuotaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNamespaceQuota not implemented")
+}
+func (UnimplementedMasterServer) GetNamespaceUsage(context.Context, *GetNamespaceUsageRequest) (*GetNamespaceUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNamespaceUsage not implemented")
+}
+func (UnimplementedMasterServer) SetStorageClass(context.Context, *SetStorageClassRequest) (*SetStorageClassResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStorageClass not implemented")
+}
+func (UnimplementedMasterServer) SetCompression(context.Context, *SetCompressionRequest) (*SetCompressionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCompression not implemented")
+}
 func (UnimplementedMasterServer) GetClusterStatus(context.Context, *GetClusterStatusRequest) (*GetClusterStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetClusterStatus not implemented")
 }
+func (UnimplementedMasterServer) DrainChunkServer(context.Context, *DrainChunkServerRequest) (*DrainChunkServerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainChunkServer not implemented")
+}
+func (UnimplementedMasterServer) GetDrainStatus(context.Context, *GetDrainStatusRequest) (*GetDrainStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDrainStatus not implemented")
+}
+func (UnimplementedMasterServer) Rebalance(context.Context, *RebalanceRequest) (*RebalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rebalance not implemented")
+}
+func (UnimplementedMasterServer) GetRebalanceStatus(context.Context, *GetRebalanceStatusRequest) (*GetRebalanceStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRebalanceStatus not implemented")
+}
+func (UnimplementedMasterServer) GetLeader(context.Context, *GetLeaderRequest) (*GetLeaderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeader not implemented")
+}
 func (UnimplementedMasterServer) mustEmbedUnimplementedMasterServer() {}
 func (UnimplementedMasterServer) testEmbeddedByValue()                {}
 
@@ -396,6 +783,60 @@ func _Master_ClaimPrimary_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Master_ReportReplication_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportReplicationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).ReportReplication(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_ReportReplication_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).ReportReplication(ctx, req.(*ReportReplicationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_ReportCorruptChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportCorruptChunkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).ReportCorruptChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_ReportCorruptChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).ReportCorruptChunk(ctx, req.(*ReportCorruptChunkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_ReportEncode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportEncodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).ReportEncode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_ReportEncode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).ReportEncode(ctx, req.(*ReportEncodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Master_CreateFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateFileRequest)
 	if err := dec(in); err != nil {
@@ -486,74 +927,427 @@ func _Master_RenameFile_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Master_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListFilesRequest)
+func _Master_Transact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MasterServer).ListFiles(ctx, in)
+		return srv.(MasterServer).Transact(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Master_ListFiles_FullMethodName,
+		FullMethod: Master_Transact_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MasterServer).ListFiles(ctx, req.(*ListFilesRequest))
+		return srv.(MasterServer).Transact(ctx, req.(*TransactRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Master_AllocateChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(AllocateChunkRequest)
+func _Master_SetFileAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFileAttributesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MasterServer).AllocateChunk(ctx, in)
+		return srv.(MasterServer).SetFileAttributes(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Master_AllocateChunk_FullMethodName,
+		FullMethod: Master_SetFileAttributes_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MasterServer).AllocateChunk(ctx, req.(*AllocateChunkRequest))
+		return srv.(MasterServer).SetFileAttributes(ctx, req.(*SetFileAttributesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Master_GetChunkLocations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetChunkLocationsRequest)
+func _Master_WatchNamespace_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchNamespaceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MasterServer).WatchNamespace(m, &grpc.GenericServerStream[WatchNamespaceRequest, NamespaceEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Master_WatchNamespaceServer = grpc.ServerStreamingServer[NamespaceEvent]
+
+func _Master_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MasterServer).GetChunkLocations(ctx, in)
+		return srv.(MasterServer).ListFiles(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Master_GetChunkLocations_FullMethodName,
+		FullMethod: Master_ListFiles_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MasterServer).GetChunkLocations(ctx, req.(*GetChunkLocationsRequest))
+		return srv.(MasterServer).ListFiles(ctx, req.(*ListFilesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _Master_GetClusterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetClusterStatusRequest)
+func _Master_ListFilesV2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesV2Request)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MasterServer).GetClusterStatus(ctx, in)
+		return srv.(MasterServer).ListFilesV2(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: Master_GetClusterStatus_FullMethodName,
+		FullMethod: Master_ListFilesV2_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MasterServer).GetClusterStatus(ctx, req.(*GetClusterStatusRequest))
+		return srv.(MasterServer).ListFilesV2(ctx, req.(*ListFilesV2Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_SnapshotFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).SnapshotFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_SnapshotFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).SnapshotFile(ctx, req.(*SnapshotFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_SnapshotNamespace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotNamespaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).SnapshotNamespace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_SnapshotNamespace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).SnapshotNamespace(ctx, req.(*SnapshotNamespaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_RestoreFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).RestoreFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_RestoreFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).RestoreFile(ctx, req.(*RestoreFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_PurgeTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).PurgeTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_PurgeTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).PurgeTrash(ctx, req.(*PurgeTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_AllocateChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateChunkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).AllocateChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_AllocateChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).AllocateChunk(ctx, req.(*AllocateChunkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_GetChunkLocations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChunkLocationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetChunkLocations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_GetChunkLocations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetChunkLocations(ctx, req.(*GetChunkLocationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_PrepareChunkWrite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrepareChunkWriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).PrepareChunkWrite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_PrepareChunkWrite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).PrepareChunkWrite(ctx, req.(*PrepareChunkWriteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_IssueDataToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueDataTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).IssueDataToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_IssueDataToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).IssueDataToken(ctx, req.(*IssueDataTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_SetNamespaceQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetNamespaceQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).SetNamespaceQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_SetNamespaceQuota_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).SetNamespaceQuota(ctx, req.(*SetNamespaceQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_GetNamespaceUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNamespaceUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetNamespaceUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_GetNamespaceUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetNamespaceUsage(ctx, req.(*GetNamespaceUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_SetStorageClass_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetStorageClassRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).SetStorageClass(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_SetStorageClass_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).SetStorageClass(ctx, req.(*SetStorageClassRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_SetCompression_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCompressionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).SetCompression(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_SetCompression_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).SetCompression(ctx, req.(*SetCompressionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_GetClusterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClusterStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetClusterStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_GetClusterStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetClusterStatus(ctx, req.(*GetClusterStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_DrainChunkServer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainChunkServerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).DrainChunkServer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_DrainChunkServer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).DrainChunkServer(ctx, req.(*DrainChunkServerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_GetDrainStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDrainStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetDrainStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_GetDrainStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetDrainStatus(ctx, req.(*GetDrainStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_Rebalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).Rebalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_Rebalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).Rebalance(ctx, req.(*RebalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_GetRebalanceStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRebalanceStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetRebalanceStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_GetRebalanceStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetRebalanceStatus(ctx, req.(*GetRebalanceStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Master_GetLeader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLeaderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServer).GetLeader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Master_GetLeader_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServer).GetLeader(ctx, req.(*GetLeaderRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -585,6 +1379,18 @@ var Master_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ClaimPrimary",
 			Handler:    _Master_ClaimPrimary_Handler,
 		},
+		{
+			MethodName: "ReportReplication",
+			Handler:    _Master_ReportReplication_Handler,
+		},
+		{
+			MethodName: "ReportCorruptChunk",
+			Handler:    _Master_ReportCorruptChunk_Handler,
+		},
+		{
+			MethodName: "ReportEncode",
+			Handler:    _Master_ReportEncode_Handler,
+		},
 		{
 			MethodName: "CreateFile",
 			Handler:    _Master_CreateFile_Handler,
@@ -605,10 +1411,42 @@ var Master_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RenameFile",
 			Handler:    _Master_RenameFile_Handler,
 		},
+		{
+			MethodName: "Transact",
+			Handler:    _Master_Transact_Handler,
+		},
+		{
+			MethodName: "SetFileAttributes",
+			Handler:    _Master_SetFileAttributes_Handler,
+		},
 		{
 			MethodName: "ListFiles",
 			Handler:    _Master_ListFiles_Handler,
 		},
+		{
+			MethodName: "ListFilesV2",
+			Handler:    _Master_ListFilesV2_Handler,
+		},
+		{
+			MethodName: "SnapshotFile",
+			Handler:    _Master_SnapshotFile_Handler,
+		},
+		{
+			MethodName: "SnapshotNamespace",
+			Handler:    _Master_SnapshotNamespace_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _Master_ListTrash_Handler,
+		},
+		{
+			MethodName: "RestoreFile",
+			Handler:    _Master_RestoreFile_Handler,
+		},
+		{
+			MethodName: "PurgeTrash",
+			Handler:    _Master_PurgeTrash_Handler,
+		},
 		{
 			MethodName: "AllocateChunk",
 			Handler:    _Master_AllocateChunk_Handler,
@@ -617,10 +1455,166 @@ var Master_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetChunkLocations",
 			Handler:    _Master_GetChunkLocations_Handler,
 		},
+		{
+			MethodName: "PrepareChunkWrite",
+			Handler:    _Master_PrepareChunkWrite_Handler,
+		},
+		{
+			MethodName: "IssueDataToken",
+			Handler:    _Master_IssueDataToken_Handler,
+		},
+		{
+			MethodName: "SetNamespaceQuota",
+			Handler:    _Master_SetNamespaceQuota_Handler,
+		},
+		{
+			MethodName: "GetNamespaceUsage",
+			Handler:    _Master_GetNamespaceUsage_Handler,
+		},
+		{
+			MethodName: "SetStorageClass",
+			Handler:    _Master_SetStorageClass_Handler,
+		},
+		{
+			MethodName: "SetCompression",
+			Handler:    _Master_SetCompression_Handler,
+		},
 		{
 			MethodName: "GetClusterStatus",
 			Handler:    _Master_GetClusterStatus_Handler,
 		},
+		{
+			MethodName: "DrainChunkServer",
+			Handler:    _Master_DrainChunkServer_Handler,
+		},
+		{
+			MethodName: "GetDrainStatus",
+			Handler:    _Master_GetDrainStatus_Handler,
+		},
+		{
+			MethodName: "Rebalance",
+			Handler:    _Master_Rebalance_Handler,
+		},
+		{
+			MethodName: "GetRebalanceStatus",
+			Handler:    _Master_GetRebalanceStatus_Handler,
+		},
+		{
+			MethodName: "GetLeader",
+			Handler:    _Master_GetLeader_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchNamespace",
+			Handler:       _Master_WatchNamespace_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "master/master.proto",
+}
+
+const (
+	MasterPeer_Step_FullMethodName = "/master.v1.MasterPeer/Step"
+)
+
+// MasterPeerClient is the client API for MasterPeer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Replication transport between master replicas
+type MasterPeerClient interface {
+	Step(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessageResponse, error)
+}
+
+type masterPeerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMasterPeerClient(cc grpc.ClientConnInterface) MasterPeerClient {
+	return &masterPeerClient{cc}
+}
+
+func (c *masterPeerClient) Step(ctx context.Context, in *RaftMessage, opts ...grpc.CallOption) (*RaftMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RaftMessageResponse)
+	err := c.cc.Invoke(ctx, MasterPeer_Step_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MasterPeerServer is the server API for MasterPeer service.
+// All implementations must embed UnimplementedMasterPeerServer
+// for forward compatibility.
+//
+// Replication transport between master replicas
+type MasterPeerServer interface {
+	Step(context.Context, *RaftMessage) (*RaftMessageResponse, error)
+	mustEmbedUnimplementedMasterPeerServer()
+}
+
+// UnimplementedMasterPeerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMasterPeerServer struct{}
+
+func (UnimplementedMasterPeerServer) Step(context.Context, *RaftMessage) (*RaftMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Step not implemented")
+}
+func (UnimplementedMasterPeerServer) mustEmbedUnimplementedMasterPeerServer() {}
+func (UnimplementedMasterPeerServer) testEmbeddedByValue()                    {}
+
+// UnsafeMasterPeerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MasterPeerServer will
+// result in compilation errors.
+type UnsafeMasterPeerServer interface {
+	mustEmbedUnimplementedMasterPeerServer()
+}
+
+func RegisterMasterPeerServer(s grpc.ServiceRegistrar, srv MasterPeerServer) {
+	// If the following call pancis, it indicates UnimplementedMasterPeerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MasterPeer_ServiceDesc, srv)
+}
+
+func _MasterPeer_Step_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RaftMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterPeerServer).Step(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterPeer_Step_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterPeerServer).Step(ctx, req.(*RaftMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MasterPeer_ServiceDesc is the grpc.ServiceDesc for MasterPeer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MasterPeer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "master.v1.MasterPeer",
+	HandlerType: (*MasterPeerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Step",
+			Handler:    _MasterPeer_Step_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "master/master.proto",
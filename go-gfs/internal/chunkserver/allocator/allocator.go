@@ -18,6 +18,14 @@ func NewAllocator(chunksize uint64) *Allocator {
 	}
 }
 
+// NewAllocatorAt returns an allocator for a chunk that already holds offset bytes
+func NewAllocatorAt(chunksize uint64, offset uint64) *Allocator {
+	return &Allocator{
+		offset: offset,
+		chunksize: chunksize,
+	}
+}
+
 // Allocate returns (offset, sequence, error) for an append operation
 func (a *Allocator) Allocate(amount uint64) (uint64, uint64, error) {
 	a.mu.Lock()
@@ -0,0 +1,298 @@
+// Package checksum maintains per-block CRC32C checksums for chunk files.
+//
+// Each chunk file has a sidecar "<handle>.crc" next to it holding a header
+// followed by one big-endian CRC32C per BlockSize bytes of chunk data. The
+// last block may be short. Writers update the sidecar after committing data,
+// readers verify the blocks they are about to serve. Checksums cover the
+// uncompressed data, so they hold across compressing a chunk.
+package checksum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+)
+
+// BlockSize is the number of chunk bytes covered by one checksum
+const BlockSize = 64 << 10
+
+// SidecarSuffix is appended to a chunk file path to get its checksum file
+const SidecarSuffix = ".crc"
+
+var (
+	// ErrMismatch means chunk data does not match its stored checksums
+	ErrMismatch = errors.New("checksum mismatch")
+	// ErrNoChecksums means the chunk has no sidecar (written before checksums existed)
+	ErrNoChecksums = errors.New("no checksums for chunk")
+	// ErrCorruptSidecar means the sidecar exists but can't be parsed. The data it
+	// covers can't be trusted either, so it also matches ErrMismatch.
+	ErrCorruptSidecar = fmt.Errorf("%w: corrupt checksum file", ErrMismatch)
+)
+
+var sidecarMagic = [4]byte{'G', 'C', 'R', 'C'}
+
+const headerSize = 8 // magic + block size
+
+var table = crc32.MakeTable(crc32.Castagnoli)
+
+// Per-chunk locks keep data and sidecar consistent between writers and verifiers
+var (
+	locks   = make(map[string]*sync.RWMutex)
+	locksMu sync.Mutex
+)
+
+func chunkLock(chunkPath string) *sync.RWMutex {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	l, ok := locks[chunkPath]
+	if !ok {
+		l = &sync.RWMutex{}
+		locks[chunkPath] = l
+	}
+	return l
+}
+
+// Lock takes the exclusive lock for a chunk while its data and checksums change.
+// Returns the unlock function.
+func Lock(chunkPath string) func() {
+	l := chunkLock(chunkPath)
+	l.Lock()
+	return l.Unlock
+}
+
+// RLock takes the shared lock for a chunk while it is verified.
+// Returns the unlock function.
+func RLock(chunkPath string) func() {
+	l := chunkLock(chunkPath)
+	l.RLock()
+	return l.RUnlock
+}
+
+// SidecarPath returns the checksum file path for a chunk file
+func SidecarPath(chunkPath string) string {
+	return chunkPath + SidecarSuffix
+}
+
+// IsSidecar reports whether a file name in the storage directory is a checksum file
+func IsSidecar(name string) bool {
+	return strings.HasSuffix(name, SidecarSuffix) || strings.HasSuffix(name, SidecarSuffix+".tmp")
+}
+
+// Remove deletes the sidecar for a chunk, ignoring a missing file
+func Remove(chunkPath string) error {
+	err := os.Remove(SidecarPath(chunkPath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	locksMu.Lock()
+	delete(locks, chunkPath)
+	locksMu.Unlock()
+	return nil
+}
+
+// Load reads the stored checksums for a chunk
+func Load(chunkPath string) ([]uint32, error) {
+	data, err := os.ReadFile(SidecarPath(chunkPath))
+	if os.IsNotExist(err) {
+		return nil, ErrNoChecksums
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize || !bytes.Equal(data[:4], sidecarMagic[:]) {
+		return nil, fmt.Errorf("%w: bad header in %s", ErrCorruptSidecar, chunkPath)
+	}
+	if bs := binary.BigEndian.Uint32(data[4:8]); bs != BlockSize {
+		return nil, fmt.Errorf("unsupported checksum block size %d", bs)
+	}
+
+	body := data[headerSize:]
+	if len(body)%4 != 0 {
+		return nil, fmt.Errorf("%w: truncated %s", ErrCorruptSidecar, chunkPath)
+	}
+	sums := make([]uint32, len(body)/4)
+	for i := range sums {
+		sums[i] = binary.BigEndian.Uint32(body[i*4:])
+	}
+	return sums, nil
+}
+
+// store atomically replaces the sidecar for a chunk
+func store(chunkPath string, sums []uint32) error {
+	buf := make([]byte, headerSize+4*len(sums))
+	copy(buf, sidecarMagic[:])
+	binary.BigEndian.PutUint32(buf[4:8], BlockSize)
+	for i, s := range sums {
+		binary.BigEndian.PutUint32(buf[headerSize+i*4:], s)
+	}
+
+	path := SidecarPath(chunkPath)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Copy gives dstPath the checksums of srcPath, for a chunk copied byte for byte.
+// A source without a sidecar leaves dst without one; the scrubber adds it later.
+// The caller must hold RLock for the source and Lock for the destination.
+func Copy(srcPath, dstPath string) error {
+	sums, err := Load(srcPath)
+	if errors.Is(err, ErrNoChecksums) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return store(dstPath, sums)
+}
+
+// blockCount returns how many checksum blocks cover size bytes
+func blockCount(size int64) int {
+	return int((size + BlockSize - 1) / BlockSize)
+}
+
+// Update recomputes the checksums of every block touched by a write of length bytes at offset.
+// The caller must hold Lock for the chunk, which must be stored raw. A chunk without
+// a sidecar is checksummed in full; an unreadable sidecar is an error, since
+// rebuilding it would bless whatever is on disk now.
+func Update(chunkPath string, offset, length int64) error {
+	file, err := os.Open(chunkPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	sums, err := Load(chunkPath)
+	if errors.Is(err, ErrNoChecksums) {
+		// Chunk predates checksums: start over from the whole file
+		sums = nil
+		offset, length = 0, size
+	} else if err != nil {
+		return err
+	}
+
+	first := int(offset / BlockSize)
+	last := blockCount(offset + length)
+	// Blocks past the old sidecar (e.g. a hole left by a write beyond EOF) need
+	// computing too, and so does the old last block, which the hole may have filled out
+	if len(sums) <= first {
+		first = max(len(sums)-1, 0)
+	}
+	total := blockCount(size)
+	if last > total {
+		last = total
+	}
+
+	if len(sums) < total {
+		sums = append(sums, make([]uint32, total-len(sums))...)
+	}
+	sums = sums[:total]
+
+	buf := make([]byte, BlockSize)
+	for i := first; i < last; i++ {
+		n, err := file.ReadAt(buf, int64(i)*BlockSize)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read block %d: %w", i, err)
+		}
+		sums[i] = crc32.Checksum(buf[:n], table)
+	}
+
+	return store(chunkPath, sums)
+}
+
+// Rebuild recomputes the checksums for a whole chunk file.
+// The caller must hold Lock for the chunk.
+func Rebuild(chunkPath string) error {
+	if err := os.Remove(SidecarPath(chunkPath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return Update(chunkPath, 0, 0)
+}
+
+// Verify checks every block of a chunk file against its sidecar.
+// The caller must hold RLock for the chunk.
+func Verify(chunkPath string) error {
+	info, err := chunkcompress.Stat(chunkPath)
+	if errors.Is(err, chunkcompress.ErrCorrupt) {
+		return fmt.Errorf("%w: %v", ErrMismatch, err)
+	}
+	if err != nil {
+		return err
+	}
+	return VerifyRange(chunkPath, 0, info.Size)
+}
+
+// VerifyRange checks the blocks covering length bytes at offset against the sidecar.
+// The caller must hold RLock for the chunk.
+func VerifyRange(chunkPath string, offset, length int64) error {
+	sums, err := Load(chunkPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := chunkcompress.Open(chunkPath)
+	if errors.Is(err, chunkcompress.ErrCorrupt) {
+		return fmt.Errorf("%w: %v", ErrMismatch, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if blockCount(file.Size()) != len(sums) {
+		return fmt.Errorf("%w: %d blocks on disk, %d checksums", ErrMismatch, blockCount(file.Size()), len(sums))
+	}
+
+	first := int(offset / BlockSize)
+	last := blockCount(offset + length)
+	if last > len(sums) {
+		last = len(sums)
+	}
+
+	buf := make([]byte, BlockSize)
+	for i := first; i < last; i++ {
+		n, err := file.ReadAt(buf, int64(i)*BlockSize)
+		if errors.Is(err, chunkcompress.ErrCorrupt) {
+			return fmt.Errorf("%w: block %d: %v", ErrMismatch, i, err)
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read block %d: %w", i, err)
+		}
+		if crc32.Checksum(buf[:n], table) != sums[i] {
+			return fmt.Errorf("%w: block %d", ErrMismatch, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package checksum
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeAt writes p at off in the chunk file, as a committed write would, and
+// updates the checksums
+func writeAt(t *testing.T, path string, p []byte, off int64) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, err := f.WriteAt(p, off); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	f.Close()
+	if err := Update(path, off, int64(len(p))); err != nil {
+		t.Fatalf("Update(%d, %d): %v", off, len(p), err)
+	}
+}
+
+func randomBytes(n int, seed int64) []byte {
+	p := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(p)
+	return p
+}
+
+func TestUpdate(t *testing.T) {
+	type write struct {
+		off int64
+		n   int
+	}
+	tests := []struct {
+		name   string
+		writes []write
+	}{
+		{name: "short last block", writes: []write{{0, 2*BlockSize + 100}}},
+		{name: "exact blocks", writes: []write{{0, 2 * BlockSize}}},
+		{name: "appends growing the short block", writes: []write{{0, 100}, {100, 200}, {300, BlockSize}}},
+		{name: "write past EOF", writes: []write{{0, 100}, {3*BlockSize + 7, 50}}},
+		{name: "partial block overwrite", writes: []write{{0, 3 * BlockSize}, {BlockSize + 10, 20}}},
+		{name: "overwrite across a boundary", writes: []write{{0, 3 * BlockSize}, {BlockSize - 5, 10}}},
+		{name: "overwrite extending the file", writes: []write{{0, BlockSize + 10}, {BlockSize, BlockSize}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chunk")
+			for i, w := range tt.writes {
+				writeAt(t, path, randomBytes(w.n, int64(i)), w.off)
+			}
+
+			// Incremental updates agree with checksumming the file from scratch
+			got, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			unlock := Lock(path)
+			err = Rebuild(path)
+			unlock()
+			if err != nil {
+				t.Fatalf("Rebuild: %v", err)
+			}
+			want, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load after Rebuild: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%d checksums, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("block %d checksum %08x, want %08x", i, got[i], want[i])
+				}
+			}
+			if err := Verify(path); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRange(t *testing.T) {
+	const size = 3*BlockSize + 1000
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, path string)
+		off     int64
+		length  int64
+		wantErr error
+	}{
+		{name: "intact", off: 0, length: size},
+		{name: "intact short last block", off: size - 10, length: 10},
+		{
+			name:    "flipped byte",
+			corrupt: func(t *testing.T, path string) { flipByte(t, path, BlockSize+3) },
+			off:     BlockSize, length: 10,
+			wantErr: ErrMismatch,
+		},
+		{
+			// Only the blocks read are checked
+			name:    "flipped byte outside the range",
+			corrupt: func(t *testing.T, path string) { flipByte(t, path, BlockSize+3) },
+			off:     2 * BlockSize, length: BlockSize,
+		},
+		{
+			name:    "flipped byte in the short last block",
+			corrupt: func(t *testing.T, path string) { flipByte(t, path, size-1) },
+			off:     0, length: size,
+			wantErr: ErrMismatch,
+		},
+		{
+			name:    "chunk grew behind the sidecar",
+			corrupt: func(t *testing.T, path string) { appendBytes(t, path, BlockSize) },
+			off:     0, length: 10,
+			wantErr: ErrMismatch,
+		},
+		{
+			name:    "missing sidecar",
+			corrupt: func(t *testing.T, path string) { os.Remove(SidecarPath(path)) },
+			off:     0, length: size,
+			wantErr: ErrNoChecksums,
+		},
+		{
+			name:    "truncated sidecar",
+			corrupt: func(t *testing.T, path string) { truncate(t, SidecarPath(path), headerSize+5) },
+			off:     0, length: size,
+			wantErr: ErrCorruptSidecar,
+		},
+		{
+			name:    "sidecar with a bad header",
+			corrupt: func(t *testing.T, path string) { flipByte(t, SidecarPath(path), 0) },
+			off:     0, length: size,
+			wantErr: ErrCorruptSidecar,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "chunk")
+			writeAt(t, path, randomBytes(size, 1), 0)
+			if tt.corrupt != nil {
+				tt.corrupt(t, path)
+			}
+			err := VerifyRange(path, tt.off, tt.length)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifyRange: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyRange err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// A damaged sidecar must not be rebuilt from data it no longer vouches for
+func TestUpdateCorruptSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunk")
+	writeAt(t, path, randomBytes(2*BlockSize, 1), 0)
+	truncate(t, SidecarPath(path), headerSize+6)
+	damaged, err := os.ReadFile(SidecarPath(path))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	if err := Update(path, 0, 10); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Update err = %v, want ErrMismatch", err)
+	}
+	after, err := os.ReadFile(SidecarPath(path))
+	if err != nil || !bytes.Equal(after, damaged) {
+		t.Errorf("Update replaced the damaged sidecar: %v", err)
+	}
+}
+
+// A chunk without a sidecar is checksummed in full on its next write
+func TestUpdateWithoutSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunk")
+	if err := os.WriteFile(path, randomBytes(2*BlockSize+5, 1), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	writeAt(t, path, []byte("tail"), 2*BlockSize+5)
+	if err := Verify(path); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func flipByte(t *testing.T, path string, off int64) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	data[off] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func appendBytes(t *testing.T, path string, n int) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(make([]byte, n)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+func truncate(t *testing.T, path string, size int64) {
+	t.Helper()
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+}
@@ -0,0 +1,463 @@
+// Package chunkcompress stores sealed chunks compressed with zstd.
+//
+// A compressed chunk replaces the file "<handle>" with "<handle>.zst": the chunk
+// data cut into SegmentSize pieces, each compressed as its own zstd frame, then
+// the offset where each frame ends and a footer holding the chunk's size. Reads
+// decompress only the frames they touch, so range reads stay cheap. Checksums
+// and versions keep describing the uncompressed data, and a chunk is restored
+// to a raw file before it is written again, so compression never shows outside
+// the chunkserver's storage.
+package chunkcompress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Suffix is appended to a chunk file path to get its compressed file
+const Suffix = ".zst"
+
+// SegmentSize is the number of chunk bytes compressed into one zstd frame
+const SegmentSize = 1 << 20
+
+var (
+	// ErrIncompressible means compressing a chunk would not save enough space to keep
+	ErrIncompressible = errors.New("chunk does not compress")
+	// ErrCorrupt means a compressed chunk file can't be decoded
+	ErrCorrupt = errors.New("corrupt compressed chunk")
+)
+
+var footerMagic = [4]byte{'G', 'Z', 'S', 'T'}
+
+const footerSize = 16 // magic + segment size + chunk size
+
+// Shared coders; EncodeAll and DecodeAll are safe for concurrent use
+var (
+	encoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return enc
+	})
+	decoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(SegmentSize))
+		return dec
+	})
+)
+
+// Cache of uncompressed sizes so chunk scans don't read every footer
+var (
+	sizes   = make(map[string]int64)
+	sizesMu sync.Mutex
+)
+
+// Path returns the compressed file path for a chunk file
+func Path(chunkPath string) string {
+	return chunkPath + Suffix
+}
+
+// IsCompressedFile reports whether a file name in the storage directory is a compressed chunk
+func IsCompressedFile(name string) bool {
+	return strings.HasSuffix(name, Suffix)
+}
+
+// Info describes how a chunk is stored
+type Info struct {
+	Size       int64 // Chunk data size
+	StoredSize int64 // Bytes the chunk takes on disk
+	Compressed bool
+}
+
+// Stat returns how a chunk is stored. A missing chunk returns the not-exist
+// error of its raw file.
+func Stat(chunkPath string) (Info, error) {
+	info, err := os.Stat(chunkPath)
+	if err == nil {
+		return Info{Size: info.Size(), StoredSize: info.Size()}, nil
+	}
+	if !os.IsNotExist(err) {
+		return Info{}, err
+	}
+	zinfo, zerr := os.Stat(Path(chunkPath))
+	if os.IsNotExist(zerr) {
+		return Info{}, err
+	}
+	if zerr != nil {
+		return Info{}, zerr
+	}
+	size, zerr := Size(chunkPath)
+	if zerr != nil {
+		return Info{}, zerr
+	}
+	return Info{Size: size, StoredSize: zinfo.Size(), Compressed: true}, nil
+}
+
+// Size returns the uncompressed size of a chunk's compressed file
+func Size(chunkPath string) (int64, error) {
+	sizesMu.Lock()
+	size, ok := sizes[chunkPath]
+	sizesMu.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	file, err := os.Open(Path(chunkPath))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size, err = readFooter(file, info.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	sizesMu.Lock()
+	sizes[chunkPath] = size
+	sizesMu.Unlock()
+	return size, nil
+}
+
+// File reads the data of a chunk, decompressing it if the chunk is compressed
+type File interface {
+	io.ReaderAt
+	io.Closer
+	// Size is the chunk data size when the file was opened
+	Size() int64
+}
+
+// Open opens a chunk for reading, whether it is stored raw or compressed. A raw
+// file wins over a compressed one, which is only left next to it by a crash.
+// The caller must hold the chunk's checksum lock while opening; the returned file
+// keeps reading the same data after the lock is released.
+func Open(chunkPath string) (File, error) {
+	file, err := os.Open(chunkPath)
+	if err == nil {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &rawFile{File: file, size: info.Size()}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	zfile, zerr := os.Open(Path(chunkPath))
+	if os.IsNotExist(zerr) {
+		return nil, err
+	}
+	if zerr != nil {
+		return nil, zerr
+	}
+	f, zerr := openCompressed(zfile)
+	if zerr != nil {
+		zfile.Close()
+		return nil, zerr
+	}
+	return f, nil
+}
+
+// rawFile is a chunk stored as plain data
+type rawFile struct {
+	*os.File
+	size int64
+}
+
+func (f *rawFile) Size() int64 {
+	return f.size
+}
+
+// compressedFile is a chunk stored as zstd frames
+type compressedFile struct {
+	file *os.File
+	size int64
+	ends []int64 // Offset where each frame ends
+
+	mu     sync.Mutex
+	cached int // Frame held in buf, -1 for none
+	buf    []byte
+}
+
+// openCompressed reads the frame index of a compressed chunk file
+func openCompressed(file *os.File) (*compressedFile, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	stored := info.Size()
+	size, err := readFooter(file, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := (size + SegmentSize - 1) / SegmentSize
+	indexStart := stored - footerSize - 8*frames
+	if indexStart < 0 {
+		return nil, fmt.Errorf("%w: %s is too short for its index", ErrCorrupt, file.Name())
+	}
+	index := make([]byte, 8*frames)
+	if _, err := file.ReadAt(index, indexStart); err != nil {
+		return nil, fmt.Errorf("failed to read frame index: %w", err)
+	}
+	ends := make([]int64, frames)
+	var prev int64
+	for i := range ends {
+		ends[i] = int64(binary.BigEndian.Uint64(index[i*8:]))
+		if ends[i] <= prev || ends[i] > indexStart {
+			return nil, fmt.Errorf("%w: bad frame index in %s", ErrCorrupt, file.Name())
+		}
+		prev = ends[i]
+	}
+	if prev != indexStart {
+		return nil, fmt.Errorf("%w: bad frame index in %s", ErrCorrupt, file.Name())
+	}
+
+	return &compressedFile{file: file, size: size, ends: ends, cached: -1}, nil
+}
+
+// readFooter checks the footer of a compressed chunk file and returns the chunk size
+func readFooter(file *os.File, stored int64) (int64, error) {
+	if stored < footerSize {
+		return 0, fmt.Errorf("%w: %s has no footer", ErrCorrupt, file.Name())
+	}
+	footer := make([]byte, footerSize)
+	if _, err := file.ReadAt(footer, stored-footerSize); err != nil {
+		return 0, fmt.Errorf("failed to read footer: %w", err)
+	}
+	if !bytes.Equal(footer[:4], footerMagic[:]) {
+		return 0, fmt.Errorf("%w: %s has no footer", ErrCorrupt, file.Name())
+	}
+	if segment := binary.BigEndian.Uint32(footer[4:8]); segment != SegmentSize {
+		return 0, fmt.Errorf("unsupported compression segment size %d", segment)
+	}
+	return int64(binary.BigEndian.Uint64(footer[8:16])), nil
+}
+
+func (f *compressedFile) Size() int64 {
+	return f.size
+}
+
+func (f *compressedFile) Close() error {
+	return f.file.Close()
+}
+
+// ReadAt decompresses the frames covering len(p) bytes at off. The last frame
+// read is kept, so sequential reads decompress each frame once.
+func (f *compressedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for n < len(p) && off < f.size {
+		i := int(off / SegmentSize)
+		data, err := f.frameLocked(i)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], data[off-int64(i)*SegmentSize:])
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// frameLocked returns the decompressed data of frame i
+// Must be called with mu held
+func (f *compressedFile) frameLocked(i int) ([]byte, error) {
+	if i == f.cached {
+		return f.buf, nil
+	}
+	f.cached = -1
+
+	var start int64
+	if i > 0 {
+		start = f.ends[i-1]
+	}
+	frame := make([]byte, f.ends[i]-start)
+	if _, err := f.file.ReadAt(frame, start); err != nil {
+		return nil, fmt.Errorf("failed to read frame %d: %w", i, err)
+	}
+
+	data, err := decoder().DecodeAll(frame, f.buf[:0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: frame %d: %v", ErrCorrupt, i, err)
+	}
+	if want := min(SegmentSize, f.size-int64(i)*SegmentSize); int64(len(data)) != want {
+		return nil, fmt.Errorf("%w: frame %d is %d bytes, expected %d", ErrCorrupt, i, len(data), want)
+	}
+	f.buf, f.cached = data, i
+	return data, nil
+}
+
+// Compress replaces the raw file of a chunk with its compressed form and returns
+// how the chunk is now stored. A chunk that would not shrink by at least a tenth
+// is left raw and ErrIncompressible returned; one already compressed is left as it is.
+// The caller must hold the chunk's exclusive checksum lock.
+func Compress(chunkPath string) (Info, error) {
+	src, err := os.Open(chunkPath)
+	if os.IsNotExist(err) {
+		if info, statErr := Stat(chunkPath); statErr == nil && info.Compressed {
+			return info, nil
+		}
+		return Info{}, err
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return Info{}, ErrIncompressible
+	}
+
+	// Staged prefix keeps the partial file out of chunk reports and scrubs
+	tmp, err := os.CreateTemp(filepath.Dir(chunkPath), "staged_compress_*")
+	if err != nil {
+		return Info{}, err
+	}
+	tmpPath := tmp.Name()
+	stored, err := writeCompressed(tmp, src, size)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && stored*10 > size*9 {
+		err = ErrIncompressible
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return Info{}, err
+	}
+
+	if err := os.Rename(tmpPath, Path(chunkPath)); err != nil {
+		os.Remove(tmpPath)
+		return Info{}, err
+	}
+	sizesMu.Lock()
+	sizes[chunkPath] = size
+	sizesMu.Unlock()
+	// A crash before this leaves both files; readers use the raw one until it is gone
+	if err := os.Remove(chunkPath); err != nil {
+		return Info{}, err
+	}
+	return Info{Size: size, StoredSize: stored, Compressed: true}, nil
+}
+
+// writeCompressed writes size bytes of src to dst as a compressed chunk file and
+// returns the bytes written
+func writeCompressed(dst *os.File, src io.Reader, size int64) (int64, error) {
+	w := bufio.NewWriter(dst)
+	buf := make([]byte, SegmentSize)
+	var frame []byte
+	var index []byte
+	var pos int64
+	for done := int64(0); done < size; {
+		n, err := io.ReadFull(src, buf[:min(SegmentSize, size-done)])
+		if err != nil {
+			return 0, fmt.Errorf("failed to read chunk: %w", err)
+		}
+		frame = encoder().EncodeAll(buf[:n], frame[:0])
+		if _, err := w.Write(frame); err != nil {
+			return 0, err
+		}
+		pos += int64(len(frame))
+		done += int64(n)
+		index = binary.BigEndian.AppendUint64(index, uint64(pos))
+	}
+
+	footer := make([]byte, footerSize)
+	copy(footer, footerMagic[:])
+	binary.BigEndian.PutUint32(footer[4:8], SegmentSize)
+	binary.BigEndian.PutUint64(footer[8:16], uint64(size))
+	if _, err := w.Write(index); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(footer); err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	if err := dst.Sync(); err != nil {
+		return 0, err
+	}
+	return pos + int64(len(index)) + footerSize, nil
+}
+
+// Decompress restores the raw file of a compressed chunk so it can be written.
+// A raw chunk is left as it is, less any compressed copy a crash left next to it,
+// and a chunk with no file yet is not an error.
+// The caller must hold the chunk's exclusive checksum lock.
+func Decompress(chunkPath string) error {
+	if _, err := os.Stat(chunkPath); err == nil {
+		return Remove(chunkPath)
+	}
+
+	src, err := Open(chunkPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(chunkPath), "staged_decompress_*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = io.Copy(tmp, io.NewSectionReader(src, 0, src.Size()))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, chunkPath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to decompress chunk: %w", err)
+	}
+	return Remove(chunkPath)
+}
+
+// Remove deletes the compressed file of a chunk, ignoring a missing file
+func Remove(chunkPath string) error {
+	sizesMu.Lock()
+	delete(sizes, chunkPath)
+	sizesMu.Unlock()
+
+	err := os.Remove(Path(chunkPath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package chunkcompress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestChunk writes a raw chunk of size bytes. Compressible chunks repeat a
+// short run of random lines; the rest are random throughout.
+func writeTestChunk(t *testing.T, size int, compressible bool) (string, []byte) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(int64(size)))
+	data := make([]byte, size)
+	if compressible {
+		var lines bytes.Buffer
+		for i := 0; i < 64; i++ {
+			fmt.Fprintf(&lines, "line %d value %d\n", i, rng.Intn(1000))
+		}
+		for n := 0; n < size; n += lines.Len() {
+			copy(data[n:], lines.Bytes())
+		}
+	} else {
+		rng.Read(data)
+	}
+	path := filepath.Join(t.TempDir(), "chunk")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path, data
+}
+
+// forgetSize drops a cached chunk size so the next Stat reads the footer
+func forgetSize(chunkPath string) {
+	sizesMu.Lock()
+	delete(sizes, chunkPath)
+	sizesMu.Unlock()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, size := range []int{1000, SegmentSize - 1, SegmentSize, SegmentSize + 1, 2*SegmentSize + 12345} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			path, data := writeTestChunk(t, size, true)
+
+			info, err := Compress(path)
+			if err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+			zinfo, err := os.Stat(Path(path))
+			if err != nil {
+				t.Fatalf("compressed file: %v", err)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("raw file left after Compress: %v", err)
+			}
+			want := Info{Size: int64(size), StoredSize: zinfo.Size(), Compressed: true}
+			if info != want {
+				t.Errorf("Compress = %+v, want %+v", info, want)
+			}
+
+			// Stat must be exact with or without the cached size: the downloader
+			// reports it as the chunk's committed length
+			for _, cached := range []bool{true, false} {
+				if !cached {
+					forgetSize(path)
+				}
+				got, err := Stat(path)
+				if err != nil {
+					t.Fatalf("Stat: %v", err)
+				}
+				if got != want {
+					t.Errorf("Stat (cached %v) = %+v, want %+v", cached, got, want)
+				}
+			}
+
+			// Compressing again leaves the chunk as it is
+			if info, err := Compress(path); err != nil || info != want {
+				t.Errorf("second Compress = %+v, %v; want %+v", info, err, want)
+			}
+
+			f, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			got, err := io.ReadAll(io.NewSectionReader(f, 0, f.Size()))
+			f.Close()
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("compressed chunk reads back different data")
+			}
+
+			if err := Decompress(path); err != nil {
+				t.Fatalf("Decompress: %v", err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if !bytes.Equal(raw, data) {
+				t.Errorf("decompressed chunk differs")
+			}
+			if _, err := os.Stat(Path(path)); !os.IsNotExist(err) {
+				t.Errorf("compressed file left after Decompress: %v", err)
+			}
+			if info, err := Stat(path); err != nil || info != (Info{Size: int64(size), StoredSize: int64(size)}) {
+				t.Errorf("Stat after Decompress = %+v, %v", info, err)
+			}
+		})
+	}
+}
+
+func TestReadAtAcrossFrames(t *testing.T) {
+	const size = 3*SegmentSize + 777
+	path, data := writeTestChunk(t, size, true)
+	if _, err := Compress(path); err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		off    int64
+		length int
+		eof    bool
+	}{
+		{off: 0, length: 10},
+		{off: SegmentSize - 5, length: 10},              // Across one frame boundary
+		{off: SegmentSize - 1, length: SegmentSize + 2}, // Touches three frames
+		{off: 10, length: 3*SegmentSize - 20},           // Spans whole frames
+		{off: 2 * SegmentSize, length: SegmentSize},     // Exactly one frame
+		{off: size - 100, length: 100},                  // Tail of the short last frame
+		{off: size - 100, length: 200, eof: true},       // Past the end
+		{off: size, length: 1, eof: true},               // At the end
+		{off: SegmentSize + 3, length: 5},               // Back to an earlier frame
+		{off: 3*SegmentSize - 1, length: SegmentSize, eof: true},
+	}
+	for _, tt := range tests {
+		p := make([]byte, tt.length)
+		n, err := f.ReadAt(p, tt.off)
+		want := data[min(tt.off, size):min(tt.off+int64(tt.length), size)]
+		if tt.eof && err != io.EOF {
+			t.Errorf("ReadAt(%d, %d) err = %v, want EOF", tt.off, tt.length, err)
+		}
+		if !tt.eof && err != nil {
+			t.Errorf("ReadAt(%d, %d): %v", tt.off, tt.length, err)
+		}
+		if n != len(want) || !bytes.Equal(p[:n], want) {
+			t.Errorf("ReadAt(%d, %d) read %d bytes not matching the chunk, want %d", tt.off, tt.length, n, len(want))
+		}
+	}
+}
+
+func TestCompressIncompressible(t *testing.T) {
+	path, data := writeTestChunk(t, SegmentSize+10, false)
+	if _, err := Compress(path); !errors.Is(err, ErrIncompressible) {
+		t.Fatalf("Compress of random data: err = %v, want ErrIncompressible", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(raw, data) {
+		t.Errorf("raw chunk changed: %v", err)
+	}
+	if info, err := Stat(path); err != nil || info.Compressed || info.Size != int64(len(data)) {
+		t.Errorf("Stat = %+v, %v; want the raw chunk", info, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("%d files left in the chunk directory, want 1", len(entries))
+	}
+}
+
+func TestCorruptCompressedChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(stored []byte) []byte
+	}{
+		{name: "truncated footer", corrupt: func(b []byte) []byte { return b[:len(b)-3] }},
+		{name: "bad magic", corrupt: func(b []byte) []byte { b[len(b)-footerSize] ^= 0xff; return b }},
+		{name: "missing frame", corrupt: func(b []byte) []byte {
+			// Drop the frame bytes and keep the index and footer
+			return b[len(b)-footerSize-16:]
+		}},
+		{name: "damaged frame", corrupt: func(b []byte) []byte { b[len(b)/3] ^= 0xff; return b }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := writeTestChunk(t, SegmentSize+10, true)
+			if _, err := Compress(path); err != nil {
+				t.Fatalf("Compress: %v", err)
+			}
+			stored, err := os.ReadFile(Path(path))
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if err := os.WriteFile(Path(path), tt.corrupt(stored), 0644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			forgetSize(path)
+
+			f, err := Open(path)
+			if err == nil {
+				_, err = io.ReadAll(io.NewSectionReader(f, 0, f.Size()))
+				f.Close()
+			}
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("reading a corrupt chunk: err = %v, want ErrCorrupt", err)
+			}
+		})
+	}
+}
+
+// A crash between writing the compressed file and removing the raw one leaves
+// both; the raw file wins
+func TestRawFileWins(t *testing.T) {
+	path, data := writeTestChunk(t, SegmentSize/2, true)
+	if _, err := Compress(path); err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if info, err := Stat(path); err != nil || info.Compressed || info.Size != int64(len(data)) {
+		t.Errorf("Stat = %+v, %v; want the raw chunk", info, err)
+	}
+	if err := Decompress(path); err != nil {
+		t.Fatalf("Decompress: %v", err)
+	}
+	if _, err := os.Stat(Path(path)); !os.IsNotExist(err) {
+		t.Errorf("compressed copy left after Decompress: %v", err)
+	}
+}
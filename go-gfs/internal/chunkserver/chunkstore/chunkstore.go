@@ -0,0 +1,325 @@
+// Package chunkstore spreads a chunkserver's chunks across its data directories.
+//
+// Each directory is expected to be its own disk. A new chunk goes to the healthy
+// directory with the most free space, and stays there for the rest of its life.
+// Every scan probes each directory with a small write; a directory that fails is
+// taken out of service and the chunks it held are reported lost, so the server
+// keeps running on its remaining disks while the master re-replicates them.
+package chunkstore
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkversion"
+)
+
+// ErrNoDisk is returned when every data directory has failed
+var ErrNoDisk = errors.New("no healthy data directory")
+
+// DiskStatus is the state of one data directory
+type DiskStatus struct {
+	Dir           string
+	CapacityBytes uint64
+	FreeBytes     uint64
+	UsedBytes     uint64 // Total size of the chunk files in Dir
+	ChunkCount    int
+	Failed        bool
+
+	LogicalBytes     uint64 // Total size of the chunks in Dir before compression
+	CompressedChunks int
+}
+
+// Store tracks which data directory holds each chunk
+type Store struct {
+	disks []*disk
+
+	mu     sync.Mutex
+	chunks map[string]*disk
+	next   int // Rotates the first disk tried, so ties in free space spread out
+
+	usage func(dir string) (capacity, free uint64) // diskUsage outside tests
+}
+
+type disk struct {
+	dir    string
+	failed bool // Guarded by Store.mu
+}
+
+// New opens the data directories, creating any that are missing, and indexes
+// the chunks already on them. Directories that can't be used start out failed;
+// it is an error only if none can be used.
+func New(dirs []string) (*Store, error) {
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no data directories")
+	}
+
+	s := &Store{chunks: make(map[string]*disk), usage: diskUsage}
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			return nil, fmt.Errorf("data directory %s listed twice", dir)
+		}
+		seen[dir] = true
+
+		d := &disk{dir: dir}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			slog.Error("failed to create data directory", "dir", dir, "error", err)
+			d.failed = true
+		}
+		s.disks = append(s.disks, d)
+	}
+
+	_, _, disks := s.Scan()
+	healthy := 0
+	for _, d := range disks {
+		if !d.Failed {
+			healthy++
+		}
+	}
+	if healthy == 0 {
+		return nil, ErrNoDisk
+	}
+	slog.Info("opened data directories", "dirs", len(disks), "healthy", healthy, "chunks", len(s.chunks))
+	return s, nil
+}
+
+// Path returns the file path of a chunk, and false if no healthy directory holds it
+func (s *Store) Path(handle string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.chunks[handle]
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(d.dir, handle), true
+}
+
+// Place returns the directory a chunk is written to. A known chunk stays in its
+// directory; a new one goes to the healthy directory with the most free space
+// and is recorded there, so concurrent writes to it agree on the directory.
+func (s *Store) Place(handle string) (string, error) {
+	s.mu.Lock()
+	if d, ok := s.chunks[handle]; ok {
+		s.mu.Unlock()
+		return d.dir, nil
+	}
+	healthy := s.healthyLocked()
+	start := s.next
+	s.next++
+	s.mu.Unlock()
+
+	if len(healthy) == 0 {
+		return "", ErrNoDisk
+	}
+
+	// Stat outside the lock; placement only needs to be roughly balanced
+	best := healthy[start%len(healthy)]
+	var bestFree uint64
+	for i := range healthy {
+		d := healthy[(start+i)%len(healthy)]
+		if _, free := s.usage(d.dir); free > bestFree {
+			best, bestFree = d, free
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.chunks[handle]; ok {
+		return d.dir, nil
+	}
+	if best.failed {
+		return "", ErrNoDisk
+	}
+	s.chunks[handle] = best
+	return best.dir, nil
+}
+
+// Forget drops a deleted chunk from the index
+func (s *Store) Forget(handle string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.chunks, handle)
+}
+
+// Dirs returns the healthy data directories
+func (s *Store) Dirs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	healthy := s.healthyLocked()
+	dirs := make([]string, len(healthy))
+	for i, d := range healthy {
+		dirs[i] = d.dir
+	}
+	return dirs
+}
+
+func (s *Store) healthyLocked() []*disk {
+	healthy := make([]*disk, 0, len(s.disks))
+	for _, d := range s.disks {
+		if !d.failed {
+			healthy = append(healthy, d)
+		}
+	}
+	return healthy
+}
+
+// Scan probes every data directory and lists the chunks on the healthy ones.
+// lost holds the chunks of directories that failed since the last scan; they
+// are dropped from the index and only reported once. A failed directory that
+// passes the probe again is returned to service with whatever chunks it still has.
+func (s *Store) Scan() (chunks, lost []string, disks []DiskStatus) {
+	for _, d := range s.disks {
+		files, err := probe(d.dir)
+
+		s.mu.Lock()
+		if err != nil {
+			if !d.failed {
+				d.failed = true
+				n := 0
+				for handle, holder := range s.chunks {
+					if holder == d {
+						lost = append(lost, handle)
+						delete(s.chunks, handle)
+						n++
+					}
+				}
+				slog.Error("data directory failed, reporting its chunks lost", "dir", d.dir, "lostChunks", n, "error", err)
+			}
+			s.mu.Unlock()
+			disks = append(disks, DiskStatus{Dir: d.dir, Failed: true})
+			continue
+		}
+		if d.failed {
+			d.failed = false
+			slog.Info("data directory back in service", "dir", d.dir, "chunks", len(files))
+		}
+		count, compressed := 0, 0
+		var used, logical uint64
+		for _, file := range files {
+			handle := file.handle
+			holder, ok := s.chunks[handle]
+			if !ok {
+				s.chunks[handle] = d
+				holder = d
+			}
+			if holder != d {
+				slog.Warn("chunk found in two data directories, ignoring the extra copy", "chunk", handle, "dir", d.dir, "using", holder.dir)
+				continue
+			}
+			chunks = append(chunks, handle)
+			count++
+			used += file.size
+			logical += file.logical
+			if file.compressed {
+				compressed++
+			}
+		}
+		s.mu.Unlock()
+
+		capacity, free := s.usage(d.dir)
+		disks = append(disks, DiskStatus{
+			Dir:           d.dir,
+			CapacityBytes: capacity,
+			FreeBytes:     free,
+			UsedBytes:     used,
+			ChunkCount:    count,
+
+			LogicalBytes:     logical,
+			CompressedChunks: compressed,
+		})
+	}
+	return chunks, lost, disks
+}
+
+// chunkFile is a chunk found on disk by probe
+type chunkFile struct {
+	handle     string
+	size       uint64 // Bytes on disk
+	logical    uint64 // Chunk data size
+	compressed bool
+}
+
+// probe checks that dir can still be written and lists the chunk files in it
+func probe(dir string) ([]chunkFile, error) {
+	// Staged prefix keeps the probe out of chunk listings if it is left behind
+	f, err := os.CreateTemp(dir, "staged_probe_*")
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write([]byte{0})
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []chunkFile
+	seen := make(map[string]int)
+	for _, entry := range entries {
+		name := entry.Name()
+		handle, ok := ChunkHandle(name)
+		if entry.IsDir() || !ok {
+			continue
+		}
+		file := chunkFile{handle: handle, compressed: chunkcompress.IsCompressedFile(name)}
+		// A chunk deleted since ReadDir still counts; it is gone by the next scan
+		if info, err := entry.Info(); err == nil {
+			file.size = uint64(info.Size())
+		}
+		file.logical = file.size
+		if file.compressed {
+			if size, err := chunkcompress.Size(filepath.Join(dir, handle)); err == nil {
+				file.logical = uint64(size)
+			}
+		}
+
+		// A raw file wins over a compressed copy a crash left next to it
+		if i, dup := seen[handle]; dup {
+			if !file.compressed {
+				files[i] = file
+			}
+			continue
+		}
+		seen[handle] = len(files)
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// IsChunkFile reports whether a file name in a data directory is a chunk, raw
+// or compressed, rather than a staging temp file or a checksum or version sidecar
+func IsChunkFile(name string) bool {
+	return !strings.HasPrefix(name, "staged_") && !checksum.IsSidecar(name) && !chunkversion.IsVersionFile(name)
+}
+
+// ChunkHandle returns the handle of the chunk a file in a data directory holds,
+// and false if the file isn't a chunk
+func ChunkHandle(name string) (string, bool) {
+	if !IsChunkFile(name) {
+		return "", false
+	}
+	return strings.TrimSuffix(name, chunkcompress.Suffix), true
+}
@@ -0,0 +1,175 @@
+package chunkstore
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestStore opens a store over n fresh directories whose free space is read from free
+func newTestStore(t *testing.T, n int, free map[string]uint64) (*Store, []string) {
+	t.Helper()
+	root := t.TempDir()
+	var dirs []string
+	for i := 0; i < n; i++ {
+		dirs = append(dirs, filepath.Join(root, string(rune('a'+i))))
+	}
+	s, err := New(dirs)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	s.usage = func(dir string) (uint64, uint64) {
+		return 1000, free[dir]
+	}
+	return s, dirs
+}
+
+// writeChunk places a chunk and creates its file
+func writeChunk(t *testing.T, s *Store, handle string) string {
+	t.Helper()
+	dir, err := s.Place(handle)
+	if err != nil {
+		t.Fatalf("Place(%s): %v", handle, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, handle), []byte(handle), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return dir
+}
+
+func TestPlaceByFreeSpace(t *testing.T) {
+	free := make(map[string]uint64)
+	s, dirs := newTestStore(t, 3, free)
+
+	tests := []struct {
+		name string
+		free []uint64
+		want int // Index of the directory chosen
+	}{
+		{name: "most free first", free: []uint64{500, 100, 50}, want: 0},
+		{name: "most free last", free: []uint64{10, 20, 30}, want: 2},
+		{name: "most free in the middle", free: []uint64{10, 900, 30}, want: 1},
+	}
+	for i, tt := range tests {
+		for j, dir := range dirs {
+			free[dir] = tt.free[j]
+		}
+		handle := string(rune('p' + i))
+		if got := writeChunk(t, s, handle); got != dirs[tt.want] {
+			t.Errorf("%s: placed in %s, want %s", tt.name, got, dirs[tt.want])
+		}
+
+		// A placed chunk stays put however free space changes
+		for j, dir := range dirs {
+			free[dir] = uint64(1000 * (j + 1))
+		}
+		if got, err := s.Place(handle); err != nil || got != dirs[tt.want] {
+			t.Errorf("%s: placed again in %s, %v; want %s", tt.name, got, err, dirs[tt.want])
+		}
+		if path, ok := s.Path(handle); !ok || path != filepath.Join(dirs[tt.want], handle) {
+			t.Errorf("%s: Path = %s, %v", tt.name, path, ok)
+		}
+	}
+
+	// Equal free space spreads new chunks over every directory
+	for _, dir := range dirs {
+		free[dir] = 100
+	}
+	used := make(map[string]bool)
+	for i := 0; i < len(dirs); i++ {
+		used[writeChunk(t, s, "tie"+string(rune('0'+i)))] = true
+	}
+	if len(used) != len(dirs) {
+		t.Errorf("ties placed in %d directories, want %d", len(used), len(dirs))
+	}
+}
+
+func TestFailedDirectory(t *testing.T) {
+	free := make(map[string]uint64)
+	s, dirs := newTestStore(t, 2, free)
+	free[dirs[0]], free[dirs[1]] = 100, 200
+	writeChunk(t, s, "b1")
+	writeChunk(t, s, "b2")
+	free[dirs[0]], free[dirs[1]] = 200, 100
+	writeChunk(t, s, "a1")
+
+	// The second disk disappears
+	if err := os.RemoveAll(dirs[1]); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	chunks, lost, disks := s.Scan()
+	slices.Sort(lost)
+	if !slices.Equal(chunks, []string{"a1"}) || !slices.Equal(lost, []string{"b1", "b2"}) {
+		t.Errorf("Scan = chunks %v, lost %v; want a1 and lost b1, b2", chunks, lost)
+	}
+	if len(disks) != 2 || disks[0].Failed || !disks[1].Failed || disks[0].ChunkCount != 1 {
+		t.Errorf("disks = %+v, want the second failed", disks)
+	}
+	if _, ok := s.Path("b1"); ok {
+		t.Error("chunk on a failed directory still has a path")
+	}
+	if !slices.Equal(s.Dirs(), dirs[:1]) {
+		t.Errorf("Dirs = %v, want %v", s.Dirs(), dirs[:1])
+	}
+
+	// Lost chunks are reported once, and new chunks avoid the failed disk
+	if _, lost, _ := s.Scan(); len(lost) != 0 {
+		t.Errorf("lost reported again: %v", lost)
+	}
+	free[dirs[0]], free[dirs[1]] = 100, 900
+	if dir := writeChunk(t, s, "a2"); dir != dirs[0] {
+		t.Errorf("placed in %s, want the healthy %s", dir, dirs[0])
+	}
+
+	// A repaired disk returns to service
+	if err := os.MkdirAll(dirs[1], 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if _, _, disks := s.Scan(); disks[1].Failed {
+		t.Error("repaired directory still failed")
+	}
+	if dir := writeChunk(t, s, "b3"); dir != dirs[1] {
+		t.Errorf("placed in %s, want the repaired %s", dir, dirs[1])
+	}
+
+	// With every disk gone there is nowhere to write
+	for _, dir := range dirs {
+		os.RemoveAll(dir)
+	}
+	s.Scan()
+	if _, err := s.Place("none"); err != ErrNoDisk {
+		t.Errorf("Place with no disks: err = %v, want ErrNoDisk", err)
+	}
+}
+
+func TestNewIndexesExistingChunks(t *testing.T) {
+	root := t.TempDir()
+	dirs := []string{filepath.Join(root, "a"), filepath.Join(root, "b")}
+	for i, dir := range dirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		for _, name := range []string{"c" + string(rune('0'+i)), "staged_x", "dup"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+	}
+	s, err := New(dirs)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for handle, dir := range map[string]string{"c0": dirs[0], "c1": dirs[1], "dup": dirs[0]} {
+		if path, ok := s.Path(handle); !ok || path != filepath.Join(dir, handle) {
+			t.Errorf("Path(%s) = %s, %v; want in %s", handle, path, ok, dir)
+		}
+	}
+	if _, ok := s.Path("staged_x"); ok {
+		t.Error("staging file indexed as a chunk")
+	}
+
+	if _, err := New([]string{dirs[0], dirs[0] + "/"}); err == nil {
+		t.Error("New accepted a directory listed twice")
+	}
+}
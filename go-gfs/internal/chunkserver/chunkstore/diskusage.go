@@ -0,0 +1,16 @@
+package chunkstore
+
+import (
+	"log/slog"
+	"syscall"
+)
+
+// diskUsage returns the capacity and free bytes of the filesystem holding dir
+func diskUsage(dir string) (capacity, free uint64) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		slog.Warn("failed to stat storage filesystem", "dir", dir, "error", err)
+		return 0, 0
+	}
+	return st.Blocks * uint64(st.Bsize), st.Bavail * uint64(st.Bsize)
+}
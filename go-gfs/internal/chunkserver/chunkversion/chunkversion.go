@@ -0,0 +1,142 @@
+// Package chunkversion persists the version of each chunk replica.
+//
+// The master bumps a chunk's version whenever it grants a new lease; the
+// primary stamps that version on the write and every replica stores it in a
+// "<handle>.ver" sidecar when the write commits. A replica that missed writes
+// keeps an older version, which the master detects from chunk reports.
+package chunkversion
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Suffix is appended to a chunk file path to get its version file
+const Suffix = ".ver"
+
+var magic = [4]byte{'G', 'V', 'E', 'R'}
+
+const fileSize = 12 // magic + version
+
+// Cache of stored versions so chunk reports don't read every sidecar
+var (
+	cache   = make(map[string]uint64)
+	cacheMu sync.Mutex
+)
+
+// Path returns the version file path for a chunk file
+func Path(chunkPath string) string {
+	return chunkPath + Suffix
+}
+
+// IsVersionFile reports whether a file name in the storage directory is a version file
+func IsVersionFile(name string) bool {
+	return strings.HasSuffix(name, Suffix) || strings.HasSuffix(name, Suffix+".tmp")
+}
+
+// Load returns the stored version of a chunk, or 0 for a chunk written before
+// versions were recorded
+func Load(chunkPath string) (uint64, error) {
+	cacheMu.Lock()
+	v, ok := cache[chunkPath]
+	cacheMu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	data, err := os.ReadFile(Path(chunkPath))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != fileSize || !bytes.Equal(data[:4], magic[:]) {
+		return 0, fmt.Errorf("malformed version file for %s", chunkPath)
+	}
+	v = binary.BigEndian.Uint64(data[4:])
+
+	cacheMu.Lock()
+	cache[chunkPath] = v
+	cacheMu.Unlock()
+	return v, nil
+}
+
+// Raise stores version for a chunk unless it already has a newer one.
+// Callers hold the chunk's checksum lock, which serializes writers.
+func Raise(chunkPath string, version uint64) error {
+	current, err := Load(chunkPath)
+	if err != nil {
+		return err
+	}
+	if version <= current {
+		return nil
+	}
+	return store(chunkPath, version)
+}
+
+// store atomically replaces the version file for a chunk
+func store(chunkPath string, version uint64) error {
+	buf := make([]byte, fileSize)
+	copy(buf, magic[:])
+	binary.BigEndian.PutUint64(buf[4:], version)
+
+	path := Path(chunkPath)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	cacheMu.Lock()
+	cache[chunkPath] = version
+	cacheMu.Unlock()
+	return nil
+}
+
+// Copy gives dstPath the stored version of srcPath
+func Copy(srcPath, dstPath string) error {
+	version, err := Load(srcPath)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return Remove(dstPath)
+	}
+	return store(dstPath, version)
+}
+
+// Remove deletes the version file for a chunk, ignoring a missing file
+func Remove(chunkPath string) error {
+	cacheMu.Lock()
+	delete(cache, chunkPath)
+	cacheMu.Unlock()
+
+	err := os.Remove(Path(chunkPath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package csstructs
 
 import (
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkstore"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -9,7 +11,8 @@ type ChunkServerConfig struct {
 	DataPort int
 	ReplicationPort int
 	Id 	 string
-	Dir 	 string
+	Store 	 *chunkstore.Store
+	TLS 	 *mtls.Source // nil serves plaintext
 }
 
 type ReplicaIdentifier struct {
@@ -24,11 +27,20 @@ type DownloadRequestClaims struct {
 	Operation   string              `json:"operation"`
 	Filesize    uint64              `json:"file_size"`
 	Offset      int64               `json:"offset"` // -1 means auto-allocate (append), >= 0 means random write at offset
+	IfOffset    *uint64             `json:"if_offset,omitempty"` // Write only if the chunk holds exactly this many committed bytes; appends unless Offset is set
 	Replicas    []ReplicaIdentifier `json:"replicas"`
 	Primary     ReplicaIdentifier   `json:"primary"`
+	Grant       string              `json:"grant,omitempty"` // Master-signed write grant for the chunk
 	jwt.RegisteredClaims
 }
 
+func (c *DownloadRequestClaims) Handle() string     { return c.ChunkHandle }
+func (c *DownloadRequestClaims) GrantToken() string { return c.Grant }
+
+// OffsetMismatch is sent in place of the write offset when a conditional write
+// finds the chunk at a different length; the client sends no data
+const OffsetMismatch = ^uint64(0)
+
 
 
 type Action int
@@ -46,13 +58,20 @@ const (
 
 type UploadRequestClaims struct {
 	ChunkHandle string `json:"chunk_handle"`
-	Operation   string `json:"operation"`
+	Operation   string `json:"operation"`        // "upload" for the whole chunk, "upload_range" for Offset/Length
+	Offset      int64  `json:"offset,omitempty"` // Start of the range within the chunk
+	Length      int64  `json:"length,omitempty"` // Bytes to read; 0 means to the end of the chunk
+	Grant       string `json:"grant,omitempty"`  // Master-signed read grant for the chunk
 	jwt.RegisteredClaims
 }
 
+func (c *UploadRequestClaims) Handle() string     { return c.ChunkHandle }
+func (c *UploadRequestClaims) GrantToken() string { return c.Grant }
+
 type ReadErrorCode uint32
 const (
 	ErrChunkNotFound ReadErrorCode = iota + 1
 	ErrReadFailure
 	ErrInvalidRequest
+	ErrChecksumMismatch
 )
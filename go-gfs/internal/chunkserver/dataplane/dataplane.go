@@ -1,6 +1,7 @@
 package dataplane
 
 import (
+	"io"
 	"net"
 	"strconv"
 	"log/slog"
@@ -8,6 +9,7 @@ import (
 	"eddisonso.com/go-gfs/internal/chunkserver/downloader"
 	"eddisonso.com/go-gfs/internal/chunkserver/uploader"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/chunkserver/loadstats"
 )
 
 type DataPlane struct {
@@ -34,27 +36,39 @@ func (cs *DataPlane) Start() {
 
 	uploader := uploader.NewFileUploadService(cs.config)
 
-	slog.Info("DataPlane is listening", "address", cs.config.Hostname + ":" + strconv.Itoa(cs.config.DataPort))
+	ln = cs.config.TLS.Listener(ln)
+
+	slog.Info("DataPlane is listening", "address", cs.config.Hostname + ":" + strconv.Itoa(cs.config.DataPort), "tls", cs.config.TLS != nil)
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
 			slog.Error("Failed to accept connection", "error", err)
 			continue
 		}
-		buf := make([]byte, 4)
-		n, err := conn.Read(buf)
-		if err != nil || n != 4 {
-			slog.Error("Failed to read action from connection", "error", err)
-			conn.Close()
-			continue
-		}
+		// The TLS handshake runs on the first read, so keep it off the accept loop
+		go cs.dispatch(conn, downloader, uploader)
+	}
+}
 
-		action := csstructs.Action(binary.BigEndian.Uint32(buf))
-		switch action {
-		case csstructs.Download:
-			go downloader.HandleDownload(conn)
-		case csstructs.Upload:
-			go uploader.HandleUpload(conn)
-		}
+// dispatch reads the action a connection opens with and hands it to its handler
+func (cs *DataPlane) dispatch(conn net.Conn, downloader *downloader.FileDownloadService, uploader *uploader.FileUploadService) {
+	loadstats.ConnOpened()
+	defer loadstats.ConnClosed()
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		slog.Error("Failed to read action from connection", "error", err)
+		conn.Close()
+		return
+	}
+
+	action := csstructs.Action(binary.BigEndian.Uint32(buf))
+	switch action {
+	case csstructs.Download:
+		downloader.HandleDownload(conn)
+	case csstructs.Upload:
+		uploader.HandleUpload(conn)
+	default:
+		conn.Close()
 	}
 }
@@ -3,15 +3,16 @@ package downloader
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
 
 	"eddisonso.com/go-gfs/internal/chunkserver/allocator"
 	"eddisonso.com/go-gfs/internal/chunkserver/allocatortrackingservice"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
 	"eddisonso.com/go-gfs/internal/chunkserver/chunkstagingtrackingservice"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
 	"eddisonso.com/go-gfs/internal/chunkserver/fanoutcoordinator"
@@ -19,9 +20,12 @@ import (
 	"eddisonso.com/go-gfs/internal/chunkserver/replicationclient"
 	"eddisonso.com/go-gfs/internal/chunkserver/secrets"
 	"eddisonso.com/go-gfs/internal/chunkserver/stagedchunk"
-	"github.com/golang-jwt/jwt/v5"
+	"eddisonso.com/go-gfs/internal/datatoken"
 )
 
+// maxTokenSize bounds the request token a client may send
+const maxTokenSize = 64 << 10
+
 type FileDownloadService struct {
 	ChunkServerConfig csstructs.ChunkServerConfig
 	ChunkStagingTrackingService *chunkstagingtrackingservice.ChunkStagingTrackingService
@@ -35,11 +39,6 @@ func NewFileDownloadService(config csstructs.ChunkServerConfig, timeout int) (*F
 		ChunkStagingTrackingService: chunkstagingtrackingservice.GetChunkStagingTrackingService(),
 		timeout: timeout,
 	}
-	if err := os.MkdirAll(fds.ChunkServerConfig.Dir, 0o755); err != nil {
-		slog.Error("Failed to create directory", "dir", fds.ChunkServerConfig.Dir, "error", err)
-		return nil, err
-	}
-	
 	return fds, nil
 }
 
@@ -47,44 +46,28 @@ func (fds *FileDownloadService) HandleDownload(conn net.Conn) {
 	defer conn.Close()
 
 	nBytes := make([]byte, 4)
-	n, err := conn.Read(nBytes)
-
-	if err != nil {
+	if _, err := io.ReadFull(conn, nBytes); err != nil {
 		slog.Error("Failed to read JWT length", "error", err)
 		return
 	}
 
-	if n != 4 {
-		slog.Error("Failed to read JWT length", "bytes_read", n)
+	tokenSize := binary.BigEndian.Uint32(nBytes)
+	if tokenSize > maxTokenSize {
+		slog.Error("JWT token too large", "size", tokenSize)
 		return
 	}
 
-	tokenSize := binary.BigEndian.Uint32(nBytes)
-	
 	jwtToken := make([]byte, tokenSize)
-	n, err = conn.Read(jwtToken)
-	if err != nil {
+	if _, err := io.ReadFull(conn, jwtToken); err != nil {
 		slog.Error("Failed to read JWT token", "error", err)
 		return
 	}
 
-	if n != int(tokenSize) {
-		slog.Error("JWT token size mismatch", "expected", tokenSize, "actual", n)
-		return
-	}
-
 	jwtTokenString := string(jwtToken)
 
-	token, err := jwt.ParseWithClaims(jwtTokenString, &csstructs.DownloadRequestClaims{}, secrets.GetSecret)
-
-	if err != nil {
-		slog.Error("Failed to parse JWT token", "error", err)
-		return
-	}
-
-	claims, ok := token.Claims.(*csstructs.DownloadRequestClaims)
-	if !ok || !token.Valid {
-		slog.Error("Invalid JWT token")
+	claims := &csstructs.DownloadRequestClaims{}
+	if err := secrets.ParseRequest(jwtTokenString, claims, datatoken.OpWrite); err != nil {
+		slog.Error("Failed to authorize write", "chunk", claims.ChunkHandle, "error", err)
 		return
 	}
 
@@ -100,8 +83,11 @@ func (fds *FileDownloadService) HandleDownload(conn net.Conn) {
 
 	// Claim primary status before starting the write
 	// This verifies with the master that we're the primary and extends our lease
+	// The lease's chunk version is stamped on every replica that commits the write
+	var version uint64
+	var err error
 	if mc := masterclient.GetInstance(); mc != nil {
-		if _, err := mc.ClaimPrimary(claims.ChunkHandle); err != nil {
+		if _, version, err = mc.ClaimPrimary(claims.ChunkHandle); err != nil {
 			slog.Error("failed to claim primary status", "chunk", claims.ChunkHandle, "error", err)
 			conn.Write([]byte{0}) // 0 = failure
 			return
@@ -128,7 +114,30 @@ func (fds *FileDownloadService) HandleDownload(conn net.Conn) {
 	var offset uint64
 	var sequence uint64
 
-	if claims.Offset >= 0 {
+	if claims.IfOffset != nil {
+		// Conditional write: only while the chunk's committed data ends at IfOffset.
+		// It lands at Offset if one is given, rewriting the tail, and where the data ends otherwise
+		length := fds.committedLength(claims.ChunkHandle)
+		if length != *claims.IfOffset {
+			slog.Debug("conditional write rejected", "opID", opId, "chunk", claims.ChunkHandle, "expected", *claims.IfOffset, "length", length)
+			binary.Write(conn, binary.BigEndian, csstructs.OffsetMismatch)
+			return
+		}
+		offset = length
+		if claims.Offset >= 0 {
+			if uint64(claims.Offset) > length {
+				slog.Error("conditional write past the end of the chunk", "chunk", claims.ChunkHandle, "offset", claims.Offset, "length", length)
+				return
+			}
+			offset = uint64(claims.Offset)
+		}
+		sequence, err = currAllocator.AllocateAt(offset, claims.Filesize)
+		if err != nil {
+			slog.Error("Failed to allocate at offset", "offset", offset, "error", err)
+			return
+		}
+		slog.Debug("conditional write", "opID", opId, "offset", offset, "sequence", sequence)
+	} else if claims.Offset >= 0 {
 		// Random write at specified offset
 		offset = uint64(claims.Offset)
 		sequence, err = currAllocator.AllocateAt(offset, claims.Filesize)
@@ -147,18 +156,25 @@ func (fds *FileDownloadService) HandleDownload(conn net.Conn) {
 		slog.Debug("append write", "opID", opId, "offset", offset, "sequence", sequence)
 	}
 
+	dir, err := fds.ChunkServerConfig.Store.Place(claims.ChunkHandle)
+	if err != nil {
+		slog.Error("Failed to place chunk", "chunk", claims.ChunkHandle, "error", err)
+		return
+	}
+
 	sc := stagedchunk.NewStagedChunk(
 		claims.ChunkHandle,
 		opId,
 		claims.Filesize,
 		offset,
 		sequence,
-		fds.ChunkServerConfig.Dir,
+		dir,
 	)
 	if sc == nil {
 		slog.Error("failed to create staged chunk", "opId", opId)
 		return
 	}
+	sc.Version = version
 
 	fds.ChunkStagingTrackingService.AddStagedChunk(sc)
 
@@ -238,9 +254,9 @@ func (fds *FileDownloadService) HandleDownload(conn net.Conn) {
 	// Remove from tracking after successful commit
 	fds.ChunkStagingTrackingService.RemoveStagedChunk(opId)
 
-	// Report commit to master (if connected)
+	// Report commit to master (if connected) with the chunk's new length
 	if mc := masterclient.GetInstance(); mc != nil {
-		if err := mc.ReportCommit(claims.ChunkHandle, claims.Filesize); err != nil {
+		if err := mc.ReportCommit(claims.ChunkHandle, offset+claims.Filesize, version); err != nil {
 			slog.Warn("failed to report commit to master", "error", err)
 			// Don't fail the write - master can discover via heartbeat
 		}
@@ -250,6 +266,20 @@ func (fds *FileDownloadService) HandleDownload(conn net.Conn) {
 	conn.Write([]byte{1}) // 1 = success
 }
 
+// committedLength returns how many bytes of a chunk are on disk, which is 0 for a new chunk.
+// Unlike the allocator it doesn't count appends that failed before committing
+func (fds *FileDownloadService) committedLength(handle string) uint64 {
+	path, ok := fds.ChunkServerConfig.Store.Path(handle)
+	if !ok {
+		return 0
+	}
+	info, err := chunkcompress.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return uint64(info.Size)
+}
+
 // waitForQuorum waits for the staged chunk to reach quorum
 // replicationFactor: number of total replicas (e.g., 3)
 // timeoutSeconds: max time to wait
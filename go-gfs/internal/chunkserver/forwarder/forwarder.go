@@ -9,9 +9,8 @@ import (
 
 	pb "eddisonso.com/go-gfs/gen/chunkreplication"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/chunkserver/replicationclient"
 	"eddisonso.com/go-gfs/internal/chunkserver/stagedchunk"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Forwarder struct {
@@ -45,7 +44,7 @@ func NewForwarder(replica csstructs.ReplicaIdentifier, opId string, chunkHandle
 func (f *Forwarder) StartForward() error {
 	slog.Debug("Starting forwarder", "replica", f.replica.Hostname, "opId", f.opId, "chunkHandle", f.chunkHandle)
 
-	conn, err := grpc.NewClient(f.replica.Hostname + ":" + strconv.Itoa(f.replica.ReplicationPort), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := replicationclient.Dial(f.replica.Hostname + ":" + strconv.Itoa(f.replica.ReplicationPort))
 	if err != nil {
 		slog.Error("Failed to connect to replica", "replica", f.replica.Hostname, "opId", f.opId, "chunkHandle", f.chunkHandle, "error", err)
 		return err
@@ -67,7 +66,7 @@ func (f *Forwarder) StartForward() error {
 		ChunkHandle: f.chunkHandle,
 		Length:      f.chunkSize,
 		Offset:      f.offset,
-		Epoch:       1,
+		Epoch:       f.stagedchunk.Version,
 		Sequence:    f.sequence,
 	}
 
@@ -0,0 +1,68 @@
+// Package loadstats counts the load on a chunkserver so heartbeats can report it.
+// Counters are process-wide and only ever grow, except open connections;
+// throughput is the difference between two samples.
+package loadstats
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var (
+	openConns    atomic.Int64
+	bytesRead    atomic.Uint64
+	bytesWritten atomic.Uint64
+)
+
+// ConnOpened records a data-plane connection being accepted
+func ConnOpened() {
+	openConns.Add(1)
+}
+
+// ConnClosed records a data-plane connection finishing
+func ConnClosed() {
+	openConns.Add(-1)
+}
+
+// AddRead records chunk bytes sent to a client or another chunkserver
+func AddRead(n int64) {
+	if n > 0 {
+		bytesRead.Add(uint64(n))
+	}
+}
+
+// AddWritten records chunk bytes received for a write or a replica copy
+func AddWritten(n int) {
+	if n > 0 {
+		bytesWritten.Add(uint64(n))
+	}
+}
+
+// Sample is the counters at one moment
+type Sample struct {
+	Time            time.Time
+	OpenConnections int64
+	BytesRead       uint64
+	BytesWritten    uint64
+}
+
+// Take samples the counters
+func Take() Sample {
+	return Sample{
+		Time:            time.Now(),
+		OpenConnections: openConns.Load(),
+		BytesRead:       bytesRead.Load(),
+		BytesWritten:    bytesWritten.Load(),
+	}
+}
+
+// Rates returns the bytes per second read and written between two samples
+func Rates(prev, cur Sample) (readPerSec, writePerSec uint64) {
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+	if prev.Time.IsZero() || elapsed <= 0 {
+		return 0, 0
+	}
+	readPerSec = uint64(float64(cur.BytesRead-prev.BytesRead) / elapsed)
+	writePerSec = uint64(float64(cur.BytesWritten-prev.BytesWritten) / elapsed)
+	return readPerSec, writePerSec
+}
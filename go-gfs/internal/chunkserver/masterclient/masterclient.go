@@ -2,17 +2,27 @@ package masterclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
 	pb "eddisonso.com/go-gfs/gen/master"
 	"eddisonso.com/go-gfs/internal/buildinfo"
+	"eddisonso.com/go-gfs/internal/chunkserver/allocatortrackingservice"
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkstore"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkversion"
+	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/chunkserver/loadstats"
+	"eddisonso.com/go-gfs/internal/chunkserver/replicationclient"
+	"eddisonso.com/go-gfs/internal/chunkserver/stripeencoder"
+	"eddisonso.com/go-gfs/internal/masterconn"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Singleton instance for accessing master client from other packages
@@ -33,39 +43,80 @@ func GetInstance() *MasterClient {
 	return instance
 }
 
+// DefaultFullReportInterval is how often a heartbeat lists every chunk instead of only the changes
+const DefaultFullReportInterval = 5 * time.Minute
+
 // MasterClient handles communication with the master server
 type MasterClient struct {
 	serverID        string
 	hostname        string
 	dataPort        int
 	replicationPort int
-	storageDir      string
+	store           *chunkstore.Store
 	masterAddr      string
+	failureDomain   string
+	tls             *mtls.Source // nil dials the master in plaintext
 
-	conn   *grpc.ClientConn
+	conn   *masterconn.Conn
 	client pb.MasterClient
 
 	// Heartbeat control
 	stopHeartbeat chan struct{}
 	wg            sync.WaitGroup
+
+	// Chunks lost with a failed data directory, kept until a heartbeat delivers them
+	lostChunks []string
+
+	// Chunk versions the master has acknowledged, the baseline for incremental
+	// chunk reports; nil sends a full report on the next heartbeat
+	reported           map[string]uint64
+	lastFullReport     time.Time
+	fullReportInterval time.Duration
+
+	// Load counters at the previous heartbeat, for throughput
+	lastLoad loadstats.Sample
+
+	// Re-replication copies and stripe encodes currently running, by chunk handle
+	replicating   map[string]bool
+	replicatingMu sync.Mutex
+
+	// Sealed chunks the master asked to store compressed, worked through one at a time
+	compressQueue  []string
+	compressQueued map[string]bool
+	compressMu     sync.Mutex
+	compressWake   chan struct{}
 }
 
 // NewMasterClient creates a new master client
-func NewMasterClient(serverID, hostname string, dataPort, replicationPort int, storageDir, masterAddr string) *MasterClient {
+func NewMasterClient(serverID, hostname string, dataPort, replicationPort int, store *chunkstore.Store, masterAddr, failureDomain string, tlsSource *mtls.Source) *MasterClient {
 	return &MasterClient{
-		serverID:        serverID,
-		hostname:        hostname,
-		dataPort:        dataPort,
-		replicationPort: replicationPort,
-		storageDir:      storageDir,
-		masterAddr:      masterAddr,
-		stopHeartbeat:   make(chan struct{}),
+		serverID:           serverID,
+		hostname:           hostname,
+		dataPort:           dataPort,
+		replicationPort:    replicationPort,
+		store:              store,
+		masterAddr:         masterAddr,
+		failureDomain:      failureDomain,
+		tls:                tlsSource,
+		stopHeartbeat:      make(chan struct{}),
+		replicating:        make(map[string]bool),
+		compressQueued:     make(map[string]bool),
+		compressWake:       make(chan struct{}, 1),
+		fullReportInterval: DefaultFullReportInterval,
 	}
 }
 
+// SetFullReportInterval sets how often a heartbeat lists every chunk. Full reports
+// let the master settle replicas it could not place from an earlier report and
+// expire orphaned chunks, so the interval bounds how long those take.
+func (mc *MasterClient) SetFullReportInterval(interval time.Duration) {
+	mc.fullReportInterval = interval
+}
+
 // Connect establishes connection to the master
+// masterAddr may list every master replica; calls follow the leader
 func (mc *MasterClient) Connect() error {
-	conn, err := grpc.NewClient(mc.masterAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := masterconn.Dial(mc.masterAddr, grpc.WithTransportCredentials(mc.tls.ClientCredentials()))
 	if err != nil {
 		return err
 	}
@@ -86,8 +137,10 @@ func (mc *MasterClient) Close() {
 
 // Register registers this chunkserver with the master
 func (mc *MasterClient) Register() error {
-	// Scan storage directory for existing chunks
-	chunks := mc.scanChunks()
+	// Scan data directories for existing chunks
+	chunks, disks := mc.scanChunks()
+	usage := totalUsage(disks)
+	versions := mc.chunkVersions(chunks)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -102,6 +155,14 @@ func (mc *MasterClient) Register() error {
 			BuildId:   buildinfo.BuildID,
 			BuildTime: buildinfo.BuildTime,
 		},
+		FailureDomain:    mc.failureDomain,
+		CapacityBytes:    usage.CapacityBytes,
+		FreeBytes:        usage.FreeBytes,
+		UsedBytes:        usage.UsedBytes,
+		LogicalBytes:     usage.LogicalBytes,
+		CompressedChunks: int32(usage.CompressedChunks),
+		ChunkVersions:    versions,
+		Disks:            disksToProto(disks),
 	})
 
 	if err != nil {
@@ -111,6 +172,10 @@ func (mc *MasterClient) Register() error {
 	if !resp.Success {
 		slog.Error("registration failed", "message", resp.Message)
 	} else {
+		// Registration lists every chunk, so heartbeats continue from it
+		mc.reported = reportedVersions(chunks, versions)
+		mc.lastFullReport = time.Now()
+		mc.lastLoad = loadstats.Take()
 		slog.Info("registered with master", "serverID", mc.serverID, "chunks", len(chunks), "build", buildinfo.BuildID)
 	}
 
@@ -135,19 +200,43 @@ func (mc *MasterClient) StartHeartbeat(interval time.Duration) {
 			}
 		}
 	}()
+
+	mc.wg.Add(1)
+	go func() {
+		defer mc.wg.Done()
+		mc.runCompressions()
+	}()
 	slog.Info("started heartbeat", "interval", interval)
 }
 
 // sendHeartbeat sends a single heartbeat to the master
 func (mc *MasterClient) sendHeartbeat() {
-	chunks := mc.scanChunks()
+	chunks, disks := mc.scanChunks()
+	usage := totalUsage(disks)
+	report, current := mc.chunkReport(chunks, mc.chunkVersions(chunks))
+
+	sample := loadstats.Take()
+	readRate, writeRate := loadstats.Rates(mc.lastLoad, sample)
+	mc.lastLoad = sample
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	resp, err := mc.client.Heartbeat(ctx, &pb.HeartbeatRequest{
-		ServerId:     mc.serverID,
-		ChunkHandles: chunks,
+		ServerId:         mc.serverID,
+		CapacityBytes:    usage.CapacityBytes,
+		FreeBytes:        usage.FreeBytes,
+		UsedBytes:        usage.UsedBytes,
+		LogicalBytes:     usage.LogicalBytes,
+		CompressedChunks: int32(usage.CompressedChunks),
+		Disks:            disksToProto(disks),
+		LostChunks:       mc.lostChunks,
+		Load: &pb.ServerLoad{
+			OpenConnections:  sample.OpenConnections,
+			ReadBytesPerSec:  readRate,
+			WriteBytesPerSec: writeRate,
+		},
+		ChunkReport: report,
 	})
 
 	if err != nil {
@@ -163,6 +252,20 @@ func (mc *MasterClient) sendHeartbeat() {
 		return
 	}
 
+	if len(mc.lostChunks) > 0 {
+		slog.Warn("reported chunks lost with a failed data directory", "count", len(mc.lostChunks))
+		mc.lostChunks = nil
+	}
+
+	mc.reported = current
+	if report.Full {
+		mc.lastFullReport = time.Now()
+	}
+	if resp.FullReportRequested {
+		slog.Debug("master requested a full chunk report")
+		mc.reported = nil
+	}
+
 	// Handle any chunks master wants us to delete
 	if len(resp.ChunksToDelete) > 0 {
 		slog.Info("master requested chunk deletion", "count", len(resp.ChunksToDelete))
@@ -171,7 +274,62 @@ func (mc *MasterClient) sendHeartbeat() {
 		}
 	}
 
-	slog.Debug("heartbeat sent", "chunks", len(chunks))
+	// Copy chunks to other servers for re-replication
+	for _, cmd := range resp.ChunksToReplicate {
+		mc.startReplication(cmd)
+	}
+
+	// Erasure code cold chunks and rebuild lost fragments
+	for _, cmd := range resp.ChunksToEncode {
+		mc.startEncode(cmd)
+	}
+
+	// Store sealed chunks of compressed namespaces compressed
+	if len(resp.ChunksToCompress) > 0 {
+		mc.queueCompressions(resp.ChunksToCompress)
+	}
+
+	slog.Debug("heartbeat sent", "chunks", len(chunks), "full", report.Full, "added", len(report.Added), "removed", len(report.Removed))
+}
+
+// chunkReport compares the chunks on disk with what the master last acknowledged,
+// returning the report to send and the baseline to keep once it is delivered
+func (mc *MasterClient) chunkReport(chunks []string, versions map[string]uint64) (*pb.ChunkReport, map[string]uint64) {
+	current := reportedVersions(chunks, versions)
+	report := &pb.ChunkReport{ChunkCount: int32(len(chunks))}
+
+	if mc.reported == nil || time.Since(mc.lastFullReport) >= mc.fullReportInterval {
+		report.Full = true
+		report.Added = chunks
+		report.Versions = versions
+		return report, current
+	}
+
+	report.Versions = make(map[string]uint64)
+	for handle, version := range current {
+		if prev, ok := mc.reported[handle]; ok && prev == version {
+			continue
+		}
+		report.Added = append(report.Added, handle)
+		if version > 0 {
+			report.Versions[handle] = version
+		}
+	}
+	for handle := range mc.reported {
+		if _, ok := current[handle]; !ok {
+			report.Removed = append(report.Removed, handle)
+		}
+	}
+	return report, current
+}
+
+// reportedVersions maps every chunk to its stored version, 0 for chunks written before versions
+func reportedVersions(chunks []string, versions map[string]uint64) map[string]uint64 {
+	out := make(map[string]uint64, len(chunks))
+	for _, handle := range chunks {
+		out[handle] = versions[handle]
+	}
+	return out
 }
 
 // tryReregister attempts to re-register with the master
@@ -183,40 +341,387 @@ func (mc *MasterClient) tryReregister() {
 	}
 }
 
-// scanChunks scans the storage directory for chunk files
-func (mc *MasterClient) scanChunks() []string {
-	var chunks []string
+// scanChunks lists the chunks on the healthy data directories and the usage of each directory.
+// Chunks on a directory that just failed are queued for the next heartbeat to report lost.
+func (mc *MasterClient) scanChunks() ([]string, []chunkstore.DiskStatus) {
+	chunks, lost, disks := mc.store.Scan()
+	mc.lostChunks = append(mc.lostChunks, lost...)
+	return chunks, disks
+}
 
-	entries, err := os.ReadDir(mc.storageDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return chunks
+// totalUsage sums the usage of the healthy data directories
+func totalUsage(disks []chunkstore.DiskStatus) chunkstore.DiskStatus {
+	var total chunkstore.DiskStatus
+	for _, d := range disks {
+		if !d.Failed {
+			total.CapacityBytes += d.CapacityBytes
+			total.FreeBytes += d.FreeBytes
+			total.UsedBytes += d.UsedBytes
+			total.LogicalBytes += d.LogicalBytes
+			total.CompressedChunks += d.CompressedChunks
 		}
-		slog.Error("failed to scan storage directory", "dir", mc.storageDir, "error", err)
-		return chunks
 	}
+	return total
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			chunks = append(chunks, entry.Name())
+// disksToProto converts data directory usage for the master
+func disksToProto(disks []chunkstore.DiskStatus) []*pb.DiskStatus {
+	out := make([]*pb.DiskStatus, len(disks))
+	for i, d := range disks {
+		out[i] = &pb.DiskStatus{
+			Dir:           d.Dir,
+			CapacityBytes: d.CapacityBytes,
+			FreeBytes:     d.FreeBytes,
+			UsedBytes:     d.UsedBytes,
+			ChunkCount:    int32(d.ChunkCount),
+			Failed:        d.Failed,
+
+			LogicalBytes:     d.LogicalBytes,
+			CompressedChunks: int32(d.CompressedChunks),
 		}
 	}
+	return out
+}
 
-	return chunks
+// chunkVersions returns the stored version of each chunk that has one
+func (mc *MasterClient) chunkVersions(chunks []string) map[string]uint64 {
+	versions := make(map[string]uint64, len(chunks))
+	for _, handle := range chunks {
+		path, ok := mc.store.Path(handle)
+		if !ok {
+			continue
+		}
+		version, err := chunkversion.Load(path)
+		if err != nil {
+			slog.Warn("failed to read chunk version", "chunk", handle, "error", err)
+			continue
+		}
+		if version > 0 {
+			versions[handle] = version
+		}
+	}
+	return versions
 }
 
 // deleteChunk deletes a chunk file (garbage collection)
 func (mc *MasterClient) deleteChunk(chunkHandle string) {
-	path := filepath.Join(mc.storageDir, chunkHandle)
-	if err := os.Remove(path); err != nil {
+	path, ok := mc.store.Path(chunkHandle)
+	if !ok {
+		slog.Debug("chunk to delete not found", "chunk", chunkHandle)
+		return
+	}
+	mc.store.Forget(chunkHandle)
+	unlock := checksum.Lock(path)
+	err := os.Remove(path)
+	// A compressed chunk has no raw file
+	if compressErr := chunkcompress.Remove(path); os.IsNotExist(err) {
+		err = compressErr
+	}
+	unlock()
+	if err != nil {
 		slog.Error("failed to delete chunk", "chunk", chunkHandle, "error", err)
 	} else {
 		slog.Debug("deleted chunk", "chunk", chunkHandle)
 	}
+	if err := checksum.Remove(path); err != nil {
+		slog.Warn("failed to delete chunk checksums", "chunk", chunkHandle, "error", err)
+	}
+	if err := chunkversion.Remove(path); err != nil {
+		slog.Warn("failed to delete chunk version", "chunk", chunkHandle, "error", err)
+	}
+}
+
+// startReplication copies a chunk to the target named by the master in the background
+func (mc *MasterClient) startReplication(cmd *pb.ReplicateChunkCommand) {
+	handle := cmd.ChunkHandle
+	target := cmd.Target
+	if target == nil {
+		slog.Warn("replication command without target", "chunk", handle)
+		return
+	}
+
+	mc.replicatingMu.Lock()
+	if mc.replicating[handle] {
+		mc.replicatingMu.Unlock()
+		slog.Debug("replication already running", "chunk", handle)
+		return
+	}
+	mc.replicating[handle] = true
+	mc.replicatingMu.Unlock()
+
+	mc.wg.Add(1)
+	go func() {
+		defer mc.wg.Done()
+		defer func() {
+			mc.replicatingMu.Lock()
+			delete(mc.replicating, handle)
+			mc.replicatingMu.Unlock()
+		}()
+
+		replica := csstructs.ReplicaIdentifier{
+			ID:              target.ServerId,
+			Hostname:        target.Hostname,
+			DataPort:        int(target.DataPort),
+			ReplicationPort: int(target.ReplicationPort),
+		}
+
+		slog.Info("replicating chunk", "chunk", handle, "target", replica.ID)
+
+		// Hold the write lock so an append can't change the chunk mid-copy
+		ats := allocatortrackingservice.GetAllocatorTrackingService()
+		ats.AcquireWriteLock(handle)
+		var err error
+		if path, ok := mc.store.Path(handle); ok {
+			err = replicationclient.CopyChunkToReplica(replica, handle, path)
+		} else {
+			err = fmt.Errorf("chunk %s not found", handle)
+		}
+		ats.ReleaseWriteLock(handle)
+
+		if err != nil {
+			slog.Error("chunk replication failed", "chunk", handle, "target", replica.ID, "error", err)
+		} else {
+			slog.Info("chunk replicated", "chunk", handle, "target", replica.ID)
+		}
+		mc.ReportReplication(handle, replica.ID, err)
+	}()
 }
 
-// ReportCommit notifies the master that a chunk was successfully committed
-func (mc *MasterClient) ReportCommit(chunkHandle string, size uint64) error {
+// ReportReplication tells the master how a re-replication copy ended
+func (mc *MasterClient) ReportReplication(chunkHandle, targetID string, copyErr error) {
+	if mc.client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.ReportReplicationRequest{
+		ServerId:       mc.serverID,
+		ChunkHandle:    chunkHandle,
+		TargetServerId: targetID,
+		Success:        copyErr == nil,
+	}
+	if copyErr != nil {
+		req.Message = copyErr.Error()
+	}
+
+	if _, err := mc.client.ReportReplication(ctx, req); err != nil {
+		slog.Error("failed to report replication to master", "chunk", chunkHandle, "error", err)
+	}
+}
+
+// startEncode writes the stripe fragments named by the master in the background
+func (mc *MasterClient) startEncode(cmd *pb.EncodeChunkCommand) {
+	handle := cmd.ChunkHandle
+
+	mc.replicatingMu.Lock()
+	if mc.replicating[handle] {
+		mc.replicatingMu.Unlock()
+		slog.Debug("encode already running", "chunk", handle)
+		return
+	}
+	mc.replicating[handle] = true
+	mc.replicatingMu.Unlock()
+
+	task := stripeencoder.Task{
+		ChunkHandle:  handle,
+		Size:         cmd.Size,
+		DataShards:   int(cmd.DataShards),
+		ParityShards: int(cmd.ParityShards),
+		Sources:      toFragments(cmd.Sources),
+		Targets:      toFragments(cmd.Targets),
+	}
+
+	mc.wg.Add(1)
+	go func() {
+		defer mc.wg.Done()
+		defer func() {
+			mc.replicatingMu.Lock()
+			delete(mc.replicating, handle)
+			mc.replicatingMu.Unlock()
+		}()
+
+		slog.Info("encoding chunk", "chunk", handle, "targets", len(task.Targets), "rebuild", len(task.Sources) > 0)
+
+		// Encoding reads the local replica; hold the write lock so it can't change mid-read
+		if len(task.Sources) == 0 {
+			ats := allocatortrackingservice.GetAllocatorTrackingService()
+			ats.AcquireWriteLock(handle)
+			defer ats.ReleaseWriteLock(handle)
+		}
+		shardSize, err := stripeencoder.Run(mc.store, task)
+		if err != nil {
+			slog.Error("chunk encode failed", "chunk", handle, "error", err)
+		}
+		mc.ReportEncode(handle, shardSize, err)
+	}()
+}
+
+// toFragments converts fragment placements from the master
+func toFragments(placements []*pb.FragmentPlacement) []stripeencoder.Fragment {
+	fragments := make([]stripeencoder.Fragment, 0, len(placements))
+	for _, p := range placements {
+		if p.Server == nil {
+			continue
+		}
+		fragments = append(fragments, stripeencoder.Fragment{
+			Index:  int(p.Index),
+			Handle: p.Handle,
+			Server: csstructs.ReplicaIdentifier{
+				ID:              p.Server.ServerId,
+				Hostname:        p.Server.Hostname,
+				DataPort:        int(p.Server.DataPort),
+				ReplicationPort: int(p.Server.ReplicationPort),
+			},
+		})
+	}
+	return fragments
+}
+
+// ReportEncode tells the master how an encode command ended
+func (mc *MasterClient) ReportEncode(chunkHandle string, shardSize uint64, encodeErr error) {
+	if mc.client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pb.ReportEncodeRequest{
+		ServerId:    mc.serverID,
+		ChunkHandle: chunkHandle,
+		Success:     encodeErr == nil,
+		ShardSize:   shardSize,
+	}
+	if encodeErr != nil {
+		req.Message = encodeErr.Error()
+	}
+
+	if _, err := mc.client.ReportEncode(ctx, req); err != nil {
+		slog.Error("failed to report encode to master", "chunk", chunkHandle, "error", err)
+	}
+}
+
+// queueCompressions adds chunks to the compression queue, skipping those already in it
+func (mc *MasterClient) queueCompressions(handles []string) {
+	mc.compressMu.Lock()
+	for _, handle := range handles {
+		if !mc.compressQueued[handle] {
+			mc.compressQueued[handle] = true
+			mc.compressQueue = append(mc.compressQueue, handle)
+		}
+	}
+	mc.compressMu.Unlock()
+
+	select {
+	case mc.compressWake <- struct{}{}:
+	default:
+	}
+}
+
+// runCompressions compresses queued chunks until the heartbeat stops
+func (mc *MasterClient) runCompressions() {
+	for {
+		select {
+		case <-mc.stopHeartbeat:
+			return
+		case <-mc.compressWake:
+		}
+
+		for {
+			mc.compressMu.Lock()
+			if len(mc.compressQueue) == 0 {
+				mc.compressMu.Unlock()
+				break
+			}
+			handle := mc.compressQueue[0]
+			mc.compressQueue = mc.compressQueue[1:]
+			delete(mc.compressQueued, handle)
+			mc.compressMu.Unlock()
+
+			mc.compressChunk(handle)
+
+			select {
+			case <-mc.stopHeartbeat:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// compressChunk stores a sealed chunk compressed. The chunk is verified first so a
+// corrupt replica is reported instead of preserved. Reads of the chunk wait while
+// it is compressed.
+func (mc *MasterClient) compressChunk(handle string) {
+	path, ok := mc.store.Path(handle)
+	if !ok {
+		slog.Debug("chunk to compress not found", "chunk", handle)
+		return
+	}
+
+	ats := allocatortrackingservice.GetAllocatorTrackingService()
+	ats.AcquireWriteLock(handle)
+	defer ats.ReleaseWriteLock(handle)
+	unlock := checksum.Lock(path)
+	defer unlock()
+
+	// A master that restarted asks again for chunks compressed before
+	if info, err := chunkcompress.Stat(path); err == nil && info.Compressed {
+		return
+	}
+
+	err := checksum.Verify(path)
+	if errors.Is(err, checksum.ErrNoChecksums) {
+		// Chunk predates checksums: adopt its current contents, as the scrubber does
+		err = checksum.Rebuild(path)
+	}
+	if errors.Is(err, checksum.ErrMismatch) {
+		slog.Error("chunk to compress failed checksum verification", "chunk", handle, "error", err)
+		go mc.ReportCorruptChunk(handle, err.Error())
+		return
+	}
+	if err != nil {
+		slog.Warn("failed to verify chunk before compressing", "chunk", handle, "error", err)
+		return
+	}
+
+	info, err := chunkcompress.Compress(path)
+	switch {
+	case errors.Is(err, chunkcompress.ErrIncompressible):
+		slog.Debug("chunk does not compress, keeping it raw", "chunk", handle)
+	case err != nil:
+		slog.Error("failed to compress chunk", "chunk", handle, "error", err)
+	default:
+		slog.Debug("compressed chunk", "chunk", handle, "size", info.Size, "storedSize", info.StoredSize)
+	}
+}
+
+// ReportCorruptChunk tells the master this server's copy of a chunk failed verification
+func (mc *MasterClient) ReportCorruptChunk(chunkHandle, reason string) {
+	if mc.client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := mc.client.ReportCorruptChunk(ctx, &pb.ReportCorruptChunkRequest{
+		ServerId:    mc.serverID,
+		ChunkHandle: chunkHandle,
+		Reason:      reason,
+	})
+	if err != nil {
+		slog.Error("failed to report corrupt chunk to master", "chunk", chunkHandle, "error", err)
+		return
+	}
+
+	slog.Warn("reported corrupt chunk to master", "chunk", chunkHandle, "message", resp.Message)
+}
+
+// ReportCommit notifies the master that a chunk was successfully committed at version
+func (mc *MasterClient) ReportCommit(chunkHandle string, size, version uint64) error {
 	if mc.client == nil {
 		slog.Debug("no master connection, skipping commit report")
 		return nil
@@ -229,6 +734,7 @@ func (mc *MasterClient) ReportCommit(chunkHandle string, size uint64) error {
 		ServerId:    mc.serverID,
 		ChunkHandle: chunkHandle,
 		Size:        size,
+		Version:     version,
 	})
 
 	if err != nil {
@@ -239,7 +745,7 @@ func (mc *MasterClient) ReportCommit(chunkHandle string, size uint64) error {
 	if !resp.Success {
 		slog.Warn("master rejected commit report", "chunk", chunkHandle, "message", resp.Message)
 	} else {
-		slog.Debug("reported commit to master", "chunk", chunkHandle, "size", size)
+		slog.Debug("reported commit to master", "chunk", chunkHandle, "size", size, "version", version)
 	}
 
 	return nil
@@ -277,10 +783,11 @@ func (mc *MasterClient) RenewLease(chunkHandle string) (uint64, error) {
 
 // ClaimPrimary claims primary status for a chunk at the start of a write operation.
 // This should be called before accepting data from the client.
-// Returns the lease duration in milliseconds, or error if claim failed.
-func (mc *MasterClient) ClaimPrimary(chunkHandle string) (uint64, error) {
+// Returns the lease duration in milliseconds and the chunk version the write must
+// carry, or error if claim failed.
+func (mc *MasterClient) ClaimPrimary(chunkHandle string) (uint64, uint64, error) {
 	if mc.client == nil {
-		return 0, fmt.Errorf("no master connection")
+		return 0, 0, fmt.Errorf("no master connection")
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -292,13 +799,13 @@ func (mc *MasterClient) ClaimPrimary(chunkHandle string) (uint64, error) {
 	})
 
 	if err != nil {
-		return 0, fmt.Errorf("claim primary RPC failed: %w", err)
+		return 0, 0, fmt.Errorf("claim primary RPC failed: %w", err)
 	}
 
 	if !resp.Success {
-		return 0, fmt.Errorf("claim primary rejected: %s", resp.Message)
+		return 0, 0, fmt.Errorf("claim primary rejected: %s", resp.Message)
 	}
 
-	slog.Debug("claimed primary", "chunk", chunkHandle, "duration_ms", resp.LeaseDurationMs)
-	return resp.LeaseDurationMs, nil
+	slog.Debug("claimed primary", "chunk", chunkHandle, "duration_ms", resp.LeaseDurationMs, "version", resp.Version)
+	return resp.LeaseDurationMs, resp.Version, nil
 }
@@ -0,0 +1,145 @@
+package masterclient
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkstore"
+	"google.golang.org/grpc"
+)
+
+// fakeMaster records heartbeats, failing them while down is set
+type fakeMaster struct {
+	pb.MasterClient
+	heartbeats []*pb.HeartbeatRequest
+	down       bool
+}
+
+func (f *fakeMaster) Register(ctx context.Context, req *pb.RegisterRequest, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+	return &pb.RegisterResponse{Success: !f.down}, nil
+}
+
+func (f *fakeMaster) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error) {
+	f.heartbeats = append(f.heartbeats, req)
+	if f.down {
+		return nil, errors.New("unavailable")
+	}
+	return &pb.HeartbeatResponse{Success: true}, nil
+}
+
+func (f *fakeMaster) last() *pb.HeartbeatRequest {
+	return f.heartbeats[len(f.heartbeats)-1]
+}
+
+func TestHeartbeatReportsFailedDirectory(t *testing.T) {
+	root := t.TempDir()
+	dirs := []string{filepath.Join(root, "a"), filepath.Join(root, "b")}
+	for i, dir := range dirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		for _, handle := range []string{"x", "y"} {
+			name := handle + string(rune('0'+i))
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+		}
+	}
+	store, err := chunkstore.New(dirs)
+	if err != nil {
+		t.Fatalf("chunkstore.New: %v", err)
+	}
+	master := &fakeMaster{}
+	mc := NewMasterClient("cs1", "localhost", 0, 0, store, "", "", nil)
+	mc.client = master
+	if err := mc.Register(); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	mc.sendHeartbeat()
+	if hb := master.last(); len(hb.LostChunks) != 0 || len(hb.Disks) != 2 || hb.Disks[1].Failed {
+		t.Fatalf("heartbeat = %+v, want both disks healthy and nothing lost", hb)
+	}
+
+	if err := os.RemoveAll(dirs[1]); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	// Lost chunks are held until a heartbeat gets through
+	master.down = true
+	mc.sendHeartbeat()
+	master.down = false
+	mc.sendHeartbeat()
+
+	for i, hb := range master.heartbeats[1:] {
+		lost := slices.Sorted(slices.Values(hb.LostChunks))
+		if !slices.Equal(lost, []string{"x1", "y1"}) {
+			t.Errorf("heartbeat %d lost chunks = %v, want x1, y1", i+1, lost)
+		}
+		if len(hb.Disks) != 2 || hb.Disks[0].Failed || !hb.Disks[1].Failed || hb.Disks[0].ChunkCount != 2 {
+			t.Errorf("heartbeat %d disks = %+v, want the second failed", i+1, hb.Disks)
+		}
+	}
+	if hb := master.last(); hb.ChunkReport.ChunkCount != 2 {
+		t.Errorf("chunk count = %d, want 2", hb.ChunkReport.ChunkCount)
+	}
+
+	mc.sendHeartbeat()
+	if hb := master.last(); len(hb.LostChunks) != 0 {
+		t.Errorf("lost chunks reported again: %v", hb.LostChunks)
+	}
+}
+
+func TestChunkReport(t *testing.T) {
+	mc := NewMasterClient("cs1", "localhost", 0, 0, nil, "", "", nil)
+	steps := []struct {
+		name     string
+		versions map[string]uint64 // Chunks on disk
+		expire   bool              // The full report interval has passed
+		full     bool
+		added    []string
+		removed  []string
+	}{
+		{name: "first report", versions: map[string]uint64{"a": 1, "b": 0}, full: true, added: []string{"a", "b"}},
+		{name: "unchanged", versions: map[string]uint64{"a": 1, "b": 0}},
+		{name: "added and removed", versions: map[string]uint64{"a": 1, "c": 2}, added: []string{"c"}, removed: []string{"b"}},
+		{name: "new version", versions: map[string]uint64{"a": 2, "c": 2}, added: []string{"a"}},
+		{name: "interval passed", versions: map[string]uint64{"a": 2}, expire: true, full: true, added: []string{"a"}},
+	}
+	for _, step := range steps {
+		chunks := slices.Sorted(maps.Keys(step.versions))
+		versions := make(map[string]uint64)
+		for handle, version := range step.versions {
+			if version > 0 {
+				versions[handle] = version
+			}
+		}
+		if step.expire {
+			mc.lastFullReport = time.Now().Add(-mc.fullReportInterval)
+		}
+
+		report, current := mc.chunkReport(chunks, versions)
+		added := slices.Sorted(slices.Values(report.Added))
+		if report.Full != step.full || !slices.Equal(added, step.added) || !slices.Equal(report.Removed, step.removed) {
+			t.Errorf("%s: report = full %v, added %v, removed %v; want full %v, added %v, removed %v",
+				step.name, report.Full, added, report.Removed, step.full, step.added, step.removed)
+		}
+		for _, handle := range added {
+			if report.Versions[handle] != versions[handle] {
+				t.Errorf("%s: %s reported at version %d, want %d", step.name, handle, report.Versions[handle], versions[handle])
+			}
+		}
+
+		// The heartbeat was delivered
+		mc.reported = current
+		if report.Full {
+			mc.lastFullReport = time.Now()
+		}
+	}
+}
@@ -1,23 +1,42 @@
 package replicationclient
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"sync"
 	"time"
 
 	pb "eddisonso.com/go-gfs/gen/chunkreplication"
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkversion"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"github.com/google/uuid"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// tlsSource secures connections to other chunkservers; nil is plaintext
+var tlsSource *mtls.Source
+
+// SetTLS sets the credentials used to reach other chunkservers
+func SetTLS(source *mtls.Source) {
+	tlsSource = source
+}
+
+// Dial connects to a chunkserver's replication plane at addr
+func Dial(addr string) (*grpc.ClientConn, error) {
+	return grpc.NewClient(addr, grpc.WithTransportCredentials(tlsSource.ClientCredentials()))
+}
+
 // SendCommitToReplica sends a COMMIT message to a single replica
 func SendCommitToReplica(replica csstructs.ReplicaIdentifier, opID string) error {
 	addr := fmt.Sprintf("%s:%d", replica.Hostname, replica.ReplicationPort)
 
-	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := Dial(addr)
 	if err != nil {
 		return fmt.Errorf("failed to connect to %s: %w", addr, err)
 	}
@@ -69,3 +88,139 @@ func SendCommitToAllReplicas(replicas []csstructs.ReplicaIdentifier, opID string
 	wg.Wait()
 	return errors
 }
+
+// copyFrameSize is the DATA frame size used when copying a whole chunk
+const copyFrameSize = 1 << 20
+
+// CopyChunkToReplica streams a committed chunk file to a replica and commits it there.
+// Used for re-replication: the replica ends up with a byte-identical copy at offset 0.
+// A compressed chunk is sent decompressed.
+func CopyChunkToReplica(replica csstructs.ReplicaIdentifier, chunkHandle, path string) error {
+	unlock := checksum.RLock(path)
+	file, err := chunkcompress.Open(path)
+	unlock()
+	if err != nil {
+		return fmt.Errorf("failed to open chunk: %w", err)
+	}
+	defer file.Close()
+
+	// The copy carries the source's version so it isn't mistaken for a stale replica
+	version, err := chunkversion.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to read chunk version: %w", err)
+	}
+
+	return sendChunk(replica, chunkHandle, io.NewSectionReader(file, 0, file.Size()), uint64(file.Size()), version)
+}
+
+// SendFragment stores an erasure coded fragment on a replica as a committed chunk
+func SendFragment(replica csstructs.ReplicaIdentifier, handle string, data []byte) error {
+	return sendChunk(replica, handle, bytes.NewReader(data), uint64(len(data)), 0)
+}
+
+// sendChunk streams size bytes from r to a replica as a new chunk and commits it there
+func sendChunk(replica csstructs.ReplicaIdentifier, chunkHandle string, r io.Reader, size, version uint64) error {
+	addr := fmt.Sprintf("%s:%d", replica.Hostname, replica.ReplicationPort)
+	conn, err := Dial(addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	client := pb.NewReplicatorClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	stream, err := client.Replicate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open replication stream to %s: %w", replica.ID, err)
+	}
+
+	opID := uuid.New().String()
+	slog.Debug("copying chunk to replica", "replica", replica.ID, "chunkHandle", chunkHandle, "opID", opID, "size", size)
+
+	err = stream.Send(&pb.ReplicationFrame{
+		Kind: &pb.ReplicationFrame_Meta{Meta: &pb.ReplicationMetadata{
+			OpId:        opID,
+			ChunkHandle: chunkHandle,
+			Length:      size,
+			Offset:      0,
+			Epoch:       version,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send metadata to %s: %w", replica.ID, err)
+	}
+
+	reader := io.LimitReader(r, int64(size))
+	buf := make([]byte, copyFrameSize)
+	var sent uint64
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			sendErr := stream.Send(&pb.ReplicationFrame{
+				Kind: &pb.ReplicationFrame_Data{Data: &pb.ReplicationData{Data: buf[:n]}},
+			})
+			if sendErr != nil {
+				return fmt.Errorf("failed to send data to %s: %w", replica.ID, sendErr)
+			}
+			sent += uint64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chunk: %w", err)
+		}
+	}
+
+	if sent != size {
+		return fmt.Errorf("chunk changed during copy: sent %d bytes, expected %d", sent, size)
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("replication stream to %s failed: %w", replica.ID, err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("replication rejected by %s: %s", replica.ID, resp.Message)
+	}
+
+	if err := SendCommitToReplica(replica, opID); err != nil {
+		return err
+	}
+
+	slog.Debug("chunk copied to replica", "replica", replica.ID, "chunkHandle", chunkHandle, "bytes", sent)
+	return nil
+}
+
+// FetchChunk reads a whole committed chunk from a replica over the replication plane
+func FetchChunk(replica csstructs.ReplicaIdentifier, chunkHandle string) ([]byte, error) {
+	addr := fmt.Sprintf("%s:%d", replica.Hostname, replica.ReplicationPort)
+	conn, err := Dial(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	stream, err := pb.NewReplicatorClient(conn).FetchChunk(ctx, &pb.Fetch{ChunkHandle: chunkHandle})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from %s: %w", chunkHandle, replica.ID, err)
+	}
+
+	var buf bytes.Buffer
+	for {
+		frame, err := stream.Recv()
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s from %s: %w", chunkHandle, replica.ID, err)
+		}
+		buf.Write(frame.GetData())
+	}
+}
@@ -0,0 +1,122 @@
+package replicationplane
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	pb "eddisonso.com/go-gfs/gen/chunkreplication"
+	"eddisonso.com/go-gfs/internal/chunkserver/allocator"
+	"eddisonso.com/go-gfs/internal/chunkserver/allocatortrackingservice"
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkversion"
+)
+
+// CloneChunk copies a local chunk to a new handle.
+// The master calls it on every replica of a chunk shared by a snapshot before the chunk is written.
+func (rp *ReplicationPlane) CloneChunk(ctx context.Context, req *pb.Clone) (*pb.ReplicationResponse, error) {
+	source := req.GetSourceHandle()
+	handle := req.GetChunkHandle()
+
+	if !validHandle(source) || !validHandle(handle) || source == handle {
+		return &pb.ReplicationResponse{
+			Success: false,
+			Message: "invalid chunk handle",
+		}, nil
+	}
+
+	// Hold the source's write lock so no append lands mid-copy
+	ats := allocatortrackingservice.GetAllocatorTrackingService()
+	ats.AcquireWriteLock(source)
+	defer ats.ReleaseWriteLock(source)
+
+	srcPath, ok := rp.config.Store.Path(source)
+	if !ok {
+		return &pb.ReplicationResponse{
+			Success: false,
+			Message: "failed to clone: source chunk not found",
+		}, nil
+	}
+	dir, err := rp.config.Store.Place(handle)
+	if err != nil {
+		return &pb.ReplicationResponse{
+			Success: false,
+			Message: "failed to clone: " + err.Error(),
+		}, nil
+	}
+
+	size, err := cloneChunkFile(srcPath, filepath.Join(dir, handle), dir)
+	if err != nil {
+		slog.Error("failed to clone chunk", "source", source, "chunkHandle", handle, "error", err)
+		return &pb.ReplicationResponse{
+			Success: false,
+			Message: "failed to clone: " + err.Error(),
+		}, nil
+	}
+
+	// Appends to the copy continue where the source ended
+	ats.AddAllocator(handle, allocator.NewAllocatorAt(64<<20, uint64(size)))
+
+	slog.Info("cloned chunk", "source", source, "chunkHandle", handle, "size", size)
+	return &pb.ReplicationResponse{
+		Success: true,
+		Message: "clone successful",
+	}, nil
+}
+
+// cloneChunkFile copies a chunk file with its checksums and version, returning the chunk size
+func cloneChunkFile(srcPath, dstPath, dir string) (int64, error) {
+	unlockSrc := checksum.RLock(srcPath)
+	defer unlockSrc()
+	unlockDst := checksum.Lock(dstPath)
+	defer unlockDst()
+
+	src, err := chunkcompress.Open(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	// Staged prefix keeps the partial copy out of chunk reports and scrubs
+	tmp, err := os.CreateTemp(dir, "staged_clone_*")
+	if err != nil {
+		return 0, err
+	}
+	tmpPath := tmp.Name()
+
+	// The copy is stored raw; it is about to be written
+	size, err := io.Copy(tmp, io.NewSectionReader(src, 0, src.Size()))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to copy chunk data: %w", err)
+	}
+
+	if err := checksum.Copy(srcPath, dstPath); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to copy checksums: %w", err)
+	}
+	if err := chunkversion.Copy(srcPath, dstPath); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to copy chunk version: %w", err)
+	}
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+	return size, nil
+}
+
+// validHandle rejects handles that would escape the storage directory
+func validHandle(handle string) bool {
+	return handle != "" && handle != "." && handle != ".." && filepath.Base(handle) == handle
+}
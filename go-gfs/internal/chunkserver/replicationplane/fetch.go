@@ -0,0 +1,70 @@
+package replicationplane
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	pb "eddisonso.com/go-gfs/gen/chunkreplication"
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/loadstats"
+	"eddisonso.com/go-gfs/internal/chunkserver/masterclient"
+)
+
+// fetchFrameSize is the DATA frame size used when streaming a chunk to a peer
+const fetchFrameSize = 1 << 20
+
+// FetchChunk streams a whole committed chunk to another chunkserver.
+// Fragment rebuilds use it to gather the surviving fragments of a stripe.
+func (rp *ReplicationPlane) FetchChunk(req *pb.Fetch, stream pb.Replicator_FetchChunkServer) error {
+	handle := req.GetChunkHandle()
+	if !validHandle(handle) {
+		return errors.New("invalid chunk handle")
+	}
+	path, ok := rp.config.Store.Path(handle)
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	// Verify before sending so a corrupt copy is never used to rebuild another
+	unlock := checksum.RLock(path)
+	file, err := chunkcompress.Open(path)
+	if err != nil {
+		unlock()
+		return err
+	}
+	defer file.Close()
+	err = checksum.VerifyRange(path, 0, file.Size())
+	unlock()
+	if errors.Is(err, checksum.ErrMismatch) {
+		slog.Error("chunk failed checksum verification", "chunk", handle, "error", err)
+		if mc := masterclient.GetInstance(); mc != nil {
+			go mc.ReportCorruptChunk(handle, err.Error())
+		}
+		return err
+	}
+	if err != nil && !errors.Is(err, checksum.ErrNoChecksums) {
+		return fmt.Errorf("failed to verify chunk: %w", err)
+	}
+
+	reader := io.NewSectionReader(file, 0, file.Size())
+	buf := make([]byte, fetchFrameSize)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&pb.ReplicationData{Data: buf[:n]}); sendErr != nil {
+				return sendErr
+			}
+			loadstats.AddRead(int64(n))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -57,10 +57,17 @@ func (rp *ReplicationPlane) Replicate(stream pb.Replicator_ReplicateServer) erro
 
 			slog.Debug("starting replication", "chunkHandle", chunkHandle, "opID", opID, "length", length, "offset", offset, "sequence", sequence)
 
-			sc = stagedchunk.NewStagedChunk(chunkHandle, opID, length, offset, sequence, rp.config.Dir)
+			dir, err := rp.config.Store.Place(chunkHandle)
+			if err != nil {
+				slog.Error("failed to place chunk", "chunkHandle", chunkHandle, "opID", opID, "error", err)
+				return err
+			}
+
+			sc = stagedchunk.NewStagedChunk(chunkHandle, opID, length, offset, sequence, dir)
 			if sc == nil {
 				return errors.New("failed to create staged chunk")
 			}
+			sc.Version = meta.GetEpoch()
 			chunkstagingtrackingservice.GetChunkStagingTrackingService().AddStagedChunk(sc)
 		case *pb.ReplicationFrame_Data:
 			if sc == nil {
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	pb "eddisonso.com/go-gfs/gen/chunkreplication"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/mtls"
 )
 
 type ReplicationServer struct {
@@ -22,7 +23,17 @@ func NewReplicationServer(config csstructs.ChunkServerConfig) *ReplicationServer
 func (rs *ReplicationServer) Start() {
 	lis, _ := net.Listen("tcp", ":" + strconv.Itoa(rs.config.ReplicationPort))
 	slog.Info("Starting Replication Server on port " + strconv.Itoa(rs.config.ReplicationPort))
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.Creds(rs.config.TLS.ServerCredentials()),
+		grpc.UnaryInterceptor(rs.config.TLS.UnaryRoleInterceptor(peerRole)),
+		grpc.StreamInterceptor(rs.config.TLS.StreamRoleInterceptor(peerRole)),
+	)
 	pb.RegisterReplicatorServer(grpcServer, NewReplicationPlane(rs.config))
 	grpcServer.Serve(lis)
 }
+
+// peerRole is the certificate role every replication call needs: only masters and
+// other chunkservers may push, fetch or clone chunks, never SDK clients
+func peerRole(method string) string {
+	return mtls.RoleServer
+}
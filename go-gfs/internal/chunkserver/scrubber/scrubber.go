@@ -0,0 +1,162 @@
+package scrubber
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkstore"
+	"eddisonso.com/go-gfs/internal/chunkserver/masterclient"
+)
+
+// idleThreshold skips chunks written recently; they were just checksummed and may still be growing
+const idleThreshold = 10 * time.Minute
+
+// Scrubber periodically re-verifies chunk checksums in the background
+type Scrubber struct {
+	store          *chunkstore.Store
+	interval       time.Duration
+	bytesPerSecond int64
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScrubber creates a scrubber for the store's data directories that starts
+// a pass every interval and reads at most bytesPerSecond from disk
+func NewScrubber(store *chunkstore.Store, interval time.Duration, bytesPerSecond int64) *Scrubber {
+	return &Scrubber{
+		store:          store,
+		interval:       interval,
+		bytesPerSecond: bytesPerSecond,
+		stop:           make(chan struct{}),
+	}
+}
+
+// Start runs scrub passes in a goroutine until Stop is called
+func (s *Scrubber) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.scrubPass()
+			}
+		}
+	}()
+	slog.Info("started chunk scrubber", "interval", s.interval, "bytesPerSecond", s.bytesPerSecond)
+}
+
+// Stop ends the current pass and waits for the scrubber to exit
+func (s *Scrubber) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// scrubPass verifies every idle chunk once
+func (s *Scrubber) scrubPass() {
+	start := time.Now()
+	var checked, corrupt int
+	for _, dir := range s.store.Dirs() {
+		c, bad, ok := s.scrubDir(dir)
+		checked += c
+		corrupt += bad
+		if !ok {
+			return
+		}
+	}
+
+	slog.Info("scrub pass complete", "checked", checked, "corrupt", corrupt, "duration", time.Since(start))
+}
+
+// scrubDir verifies the idle chunks in one data directory.
+// ok is false if the scrubber was stopped part way.
+func (s *Scrubber) scrubDir(dir string) (checked, corrupt int, ok bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("scrubber failed to list data directory", "dir", dir, "error", err)
+		}
+		return 0, 0, true
+	}
+
+	for _, entry := range entries {
+		// Only chunk files: skip staging temp files and checksum sidecars
+		handle, ok := chunkstore.ChunkHandle(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < idleThreshold {
+			continue
+		}
+
+		if !s.scrubChunk(dir, handle) {
+			corrupt++
+		}
+		checked++
+
+		// Throttle so scrubbing stays low priority next to client I/O
+		if s.bytesPerSecond > 0 {
+			pause := time.Duration(float64(info.Size()) / float64(s.bytesPerSecond) * float64(time.Second))
+			select {
+			case <-s.stop:
+				return checked, corrupt, false
+			case <-time.After(pause):
+			}
+		}
+	}
+	return checked, corrupt, true
+}
+
+// scrubChunk verifies one chunk, returning false if it is corrupt
+func (s *Scrubber) scrubChunk(dir, handle string) bool {
+	path := filepath.Join(dir, handle)
+
+	unlock := checksum.RLock(path)
+	err := checksum.Verify(path)
+	unlock()
+
+	switch {
+	case err == nil:
+		return true
+
+	case errors.Is(err, checksum.ErrNoChecksums):
+		// Chunk predates checksums: adopt its current contents
+		unlock := checksum.Lock(path)
+		err := checksum.Rebuild(path)
+		unlock()
+		if err != nil {
+			slog.Warn("scrubber failed to build checksums", "chunk", handle, "error", err)
+		} else {
+			slog.Info("scrubber built checksums for legacy chunk", "chunk", handle)
+		}
+		return true
+
+	case errors.Is(err, checksum.ErrMismatch):
+		slog.Error("scrubber found corrupt chunk", "chunk", handle, "error", err)
+		if mc := masterclient.GetInstance(); mc != nil {
+			mc.ReportCorruptChunk(handle, err.Error())
+		}
+		return false
+
+	case os.IsNotExist(err):
+		// Deleted while the pass was running
+		return true
+
+	default:
+		slog.Warn("scrubber failed to verify chunk", "chunk", handle, "error", err)
+		return true
+	}
+}
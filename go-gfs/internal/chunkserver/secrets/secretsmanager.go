@@ -1,20 +1,35 @@
 package secrets
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"eddisonso.com/go-gfs/internal/datatoken"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrNoSecret is returned when GFS_JWT_SECRET is not set
+var ErrNoSecret = errors.New("GFS_JWT_SECRET environment variable is required")
+
 var jwtSecret []byte
 
+// grantVerifier, when set, makes requests need a master grant as well as the shared secret
+var grantVerifier *datatoken.Verifier
+
 func init() {
-	secret := os.Getenv("GFS_JWT_SECRET")
-	if secret == "" {
-		panic("GFS_JWT_SECRET environment variable is required")
-	}
-	jwtSecret = []byte(secret)
+	jwtSecret = []byte(os.Getenv("GFS_JWT_SECRET"))
+}
+
+// HasSecret reports whether the shared secret is configured
+func HasSecret() bool {
+	return len(jwtSecret) > 0
+}
+
+// SetGrantVerifier requires every data-plane request to carry a master grant
+// checked with v, on top of being signed with the shared secret
+func SetGrantVerifier(v *datatoken.Verifier) {
+	grantVerifier = v
 }
 
 // GetSecret retrieves the shared secret for signing and verifying JWTs.
@@ -26,5 +41,33 @@ func GetSecret(token *jwt.Token) (any, error) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 	}
+	if len(jwtSecret) == 0 {
+		return nil, ErrNoSecret
+	}
 	return jwtSecret, nil
 }
+
+// RequestClaims are the claims of a data-plane request token
+type RequestClaims interface {
+	jwt.Claims
+	Handle() string
+	GrantToken() string
+}
+
+// ParseRequest parses a data-plane request token into claims and checks that
+// the client may perform op on the chunk it names. The token must be signed
+// with the shared secret, which authenticates its parameters; with a grant
+// verifier it must also carry the master's grant for the chunk and op.
+func ParseRequest(tokenString string, claims RequestClaims, op string) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, GetSecret)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	if grantVerifier == nil {
+		return nil
+	}
+	return grantVerifier.Verify(claims.GrantToken(), claims.Handle(), op)
+}
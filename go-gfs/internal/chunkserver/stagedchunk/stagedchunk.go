@@ -2,6 +2,7 @@ package stagedchunk
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -10,7 +11,12 @@ import (
 	"sync"
 	"time"
 
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkversion"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/chunkserver/loadstats"
+	"eddisonso.com/go-gfs/internal/chunkserver/masterclient"
 )
 
 // Threshold for using temp file vs memory buffer (1MB)
@@ -30,6 +36,7 @@ type StagedChunk struct {
 	mux        sync.Mutex
 	Offset     uint64
 	Sequence   uint64
+	Version    uint64 // Chunk version under the primary's lease; 0 if unknown
 	Status     csstructs.Status
 	ready      uint8
 	storageDir string
@@ -87,6 +94,7 @@ func (sc *StagedChunk) Write(p []byte) (int, error) {
 			return n, err
 		}
 		sc.written += int64(n)
+		loadstats.AddWritten(n)
 		return n, nil
 	}
 
@@ -96,6 +104,7 @@ func (sc *StagedChunk) Write(p []byte) (int, error) {
 	}
 	n := copy(sc.buf[sc.pos:], p)
 	sc.pos += n
+	loadstats.AddWritten(n)
 	return n, nil
 }
 
@@ -159,6 +168,46 @@ func (sc *StagedChunk) Pos() uint64 {
 }
 
 func (sc *StagedChunk) Commit() error {
+	chunkFilePath := filepath.Join(sc.storageDir, sc.ChunkHandle)
+
+	// Data and checksums change together so readers never see one without the other
+	unlock := checksum.Lock(chunkFilePath)
+	defer unlock()
+
+	// A compressed chunk takes writes again once it is restored to a raw file
+	if err := chunkcompress.Decompress(chunkFilePath); err != nil {
+		slog.Error("failed to decompress chunk for write", "opID", sc.OpId, "chunkHandle", sc.ChunkHandle, "error", err)
+		return err
+	}
+
+	bytesWritten, err := sc.commitData(chunkFilePath)
+	if err != nil {
+		return err
+	}
+
+	if err := checksum.Update(chunkFilePath, int64(sc.Offset), bytesWritten); err != nil {
+		slog.Error("failed to update checksums", "opID", sc.OpId, "chunkHandle", sc.ChunkHandle, "error", err)
+		if errors.Is(err, checksum.ErrMismatch) {
+			// The sidecar is damaged, so nothing vouches for the rest of the chunk
+			if mc := masterclient.GetInstance(); mc != nil {
+				go mc.ReportCorruptChunk(sc.ChunkHandle, err.Error())
+			}
+		}
+		return fmt.Errorf("failed to update checksums: %w", err)
+	}
+
+	if sc.Version > 0 {
+		if err := chunkversion.Raise(chunkFilePath, sc.Version); err != nil {
+			slog.Error("failed to store chunk version", "opID", sc.OpId, "chunkHandle", sc.ChunkHandle, "version", sc.Version, "error", err)
+			return fmt.Errorf("failed to store chunk version: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// commitData writes the staged bytes into the chunk file and returns how many were written
+func (sc *StagedChunk) commitData(chunkFilePath string) (int64, error) {
 	sc.mux.Lock()
 	defer sc.mux.Unlock()
 
@@ -174,11 +223,9 @@ func (sc *StagedChunk) Commit() error {
 	// Create storage directory if it doesn't exist
 	if err := os.MkdirAll(sc.storageDir, 0755); err != nil {
 		slog.Error("failed to create storage directory", "dir", sc.storageDir, "error", err)
-		return fmt.Errorf("failed to create storage directory: %w", err)
+		return 0, fmt.Errorf("failed to create storage directory: %w", err)
 	}
 
-	// Chunk file path
-	chunkFilePath := filepath.Join(sc.storageDir, sc.ChunkHandle)
 	slog.Debug("writing to file", "path", chunkFilePath)
 
 	var bytesWritten int64
@@ -186,7 +233,7 @@ func (sc *StagedChunk) Commit() error {
 	// Optimization: rename temp file instead of copying for new chunks at offset 0
 	if sc.useFile && sc.Offset == 0 {
 		if sc.tempFile == nil {
-			return fmt.Errorf("temp file not initialized")
+			return 0, fmt.Errorf("temp file not initialized")
 		}
 
 		tempPath := sc.tempFile.Name()
@@ -203,14 +250,14 @@ func (sc *StagedChunk) Commit() error {
 
 			if err := os.Rename(tempPath, chunkFilePath); err != nil {
 				slog.Error("failed to rename temp file", "from", tempPath, "to", chunkFilePath, "error", err)
-				return fmt.Errorf("failed to rename temp file: %w", err)
+				return 0, fmt.Errorf("failed to rename temp file: %w", err)
 			}
 
 			bytesWritten = dataSize
 
 			sc.Status = csstructs.COMMIT
 			slog.Debug("COMMIT SUCCESSFUL - renamed temp file", "opID", sc.OpId, "chunkHandle", sc.ChunkHandle, "file", chunkFilePath, "bytesWritten", bytesWritten)
-			return nil
+			return bytesWritten, nil
 		}
 	}
 
@@ -220,20 +267,20 @@ func (sc *StagedChunk) Commit() error {
 	file, err := os.OpenFile(chunkFilePath, os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		slog.Error("failed to open chunk file", "path", chunkFilePath, "error", err)
-		return fmt.Errorf("failed to open chunk file: %w", err)
+		return 0, fmt.Errorf("failed to open chunk file: %w", err)
 	}
 	defer file.Close()
 
 	// Seek to the offset position
 	if _, err := file.Seek(int64(sc.Offset), io.SeekStart); err != nil {
 		slog.Error("failed to seek", "offset", sc.Offset, "error", err)
-		return fmt.Errorf("failed to seek to offset %d: %w", sc.Offset, err)
+		return 0, fmt.Errorf("failed to seek to offset %d: %w", sc.Offset, err)
 	}
 
 	if sc.useFile {
 		// Stream from temp file to chunk file
 		if sc.tempFile == nil {
-			return fmt.Errorf("temp file not initialized")
+			return 0, fmt.Errorf("temp file not initialized")
 		}
 
 		tempPath := sc.tempFile.Name()
@@ -241,13 +288,13 @@ func (sc *StagedChunk) Commit() error {
 		// Seek temp file to beginning for reading
 		if _, err := sc.tempFile.Seek(0, io.SeekStart); err != nil {
 			slog.Error("failed to seek temp file", "error", err)
-			return fmt.Errorf("failed to seek temp file: %w", err)
+			return 0, fmt.Errorf("failed to seek temp file: %w", err)
 		}
 
 		bytesWritten, err = io.Copy(file, sc.tempFile)
 		if err != nil {
 			slog.Error("failed to write data", "error", err)
-			return fmt.Errorf("failed to write data to disk: %w", err)
+			return 0, fmt.Errorf("failed to write data to disk: %w", err)
 		}
 
 		// Close and remove temp file
@@ -261,7 +308,7 @@ func (sc *StagedChunk) Commit() error {
 		n, err := file.Write(sc.buf[:sc.pos])
 		if err != nil {
 			slog.Error("failed to write data", "error", err)
-			return fmt.Errorf("failed to write data to disk: %w", err)
+			return 0, fmt.Errorf("failed to write data to disk: %w", err)
 		}
 		bytesWritten = int64(n)
 		// Clear buffer to free memory
@@ -270,13 +317,13 @@ func (sc *StagedChunk) Commit() error {
 
 	if bytesWritten != dataSize {
 		slog.Error("incomplete write", "wrote", bytesWritten, "expected", dataSize)
-		return fmt.Errorf("incomplete write: wrote %d bytes, expected %d", bytesWritten, dataSize)
+		return 0, fmt.Errorf("incomplete write: wrote %d bytes, expected %d", bytesWritten, dataSize)
 	}
 
 	// Sync to ensure durability
 	if err := file.Sync(); err != nil {
 		slog.Error("failed to sync", "error", err)
-		return fmt.Errorf("failed to sync data to disk: %w", err)
+		return 0, fmt.Errorf("failed to sync data to disk: %w", err)
 	}
 
 	// Update status to COMMIT
@@ -284,7 +331,7 @@ func (sc *StagedChunk) Commit() error {
 
 	slog.Debug("COMMIT SUCCESSFUL - data written to disk", "opID", sc.OpId, "chunkHandle", sc.ChunkHandle, "file", chunkFilePath, "bytesWritten", bytesWritten, "offset", sc.Offset)
 
-	return nil
+	return bytesWritten, nil
 }
 
 // Close cleans up resources if commit was not called
@@ -0,0 +1,159 @@
+// Package stripeencoder turns chunk replicas into erasure coded fragments and
+// rebuilds lost fragments, on instruction from the master.
+package stripeencoder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkstore"
+	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/chunkserver/replicationclient"
+	"eddisonso.com/go-gfs/internal/erasure"
+)
+
+// Fragment is one shard of a stripe on a chunkserver
+type Fragment struct {
+	Index  int
+	Handle string
+	Server csstructs.ReplicaIdentifier
+}
+
+// Task writes the Targets fragments of a chunk's stripe.
+// Without Sources the stripe is encoded from the local replica of the chunk;
+// otherwise the targets are rebuilt from the source fragments.
+type Task struct {
+	ChunkHandle  string
+	Size         uint64
+	DataShards   int
+	ParityShards int
+	Sources      []Fragment
+	Targets      []Fragment
+}
+
+// Run carries out a task and returns the stripe's shard size
+func Run(store *chunkstore.Store, task Task) (uint64, error) {
+	enc, err := erasure.NewCoder(task.DataShards, task.ParityShards)
+	if err != nil {
+		return 0, err
+	}
+	total := task.DataShards + task.ParityShards
+	for _, t := range task.Targets {
+		if t.Index < 0 || t.Index >= total {
+			return 0, fmt.Errorf("fragment index %d out of range", t.Index)
+		}
+	}
+	shardSize := erasure.ShardSize(task.Size, task.DataShards)
+
+	var shards [][]byte
+	if len(task.Sources) == 0 {
+		path, ok := store.Path(task.ChunkHandle)
+		if !ok {
+			return 0, fmt.Errorf("chunk %s not found", task.ChunkHandle)
+		}
+		data, err := readChunk(path, task.Size)
+		if err != nil {
+			return 0, err
+		}
+		shards = erasure.Split(data, task.DataShards, task.ParityShards, shardSize)
+		if err := enc.Encode(shards); err != nil {
+			return 0, fmt.Errorf("failed to encode stripe: %w", err)
+		}
+	} else {
+		shards = fetchShards(task.Sources, total, shardSize)
+		if err := enc.Reconstruct(shards); err != nil {
+			return 0, fmt.Errorf("failed to rebuild stripe: %w", err)
+		}
+	}
+
+	if err := sendFragments(task.Targets, shards); err != nil {
+		return 0, err
+	}
+	slog.Info("wrote stripe fragments",
+		"chunk", task.ChunkHandle,
+		"fragments", len(task.Targets),
+		"rebuilt", len(task.Sources) > 0,
+		"shardSize", shardSize)
+	return shardSize, nil
+}
+
+// readChunk reads and verifies a whole local chunk, which must be size bytes
+func readChunk(path string, size uint64) ([]byte, error) {
+	unlock := checksum.RLock(path)
+	defer unlock()
+
+	err := checksum.VerifyRange(path, 0, int64(size))
+	if err != nil && !errors.Is(err, checksum.ErrNoChecksums) {
+		return nil, fmt.Errorf("failed to verify chunk: %w", err)
+	}
+
+	file, err := chunkcompress.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if uint64(file.Size()) != size {
+		return nil, fmt.Errorf("chunk is %d bytes, expected %d", file.Size(), size)
+	}
+	data := make([]byte, size)
+	if _, err := file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fetchShards gathers the source fragments; unreachable ones are left nil for reconstruction
+func fetchShards(sources []Fragment, total int, shardSize uint64) [][]byte {
+	shards := make([][]byte, total)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, src := range sources {
+		if src.Index < 0 || src.Index >= total {
+			continue
+		}
+		wg.Add(1)
+		go func(src Fragment) {
+			defer wg.Done()
+			data, err := replicationclient.FetchChunk(src.Server, src.Handle)
+			if err == nil && uint64(len(data)) != shardSize {
+				err = fmt.Errorf("fragment is %d bytes, expected %d", len(data), shardSize)
+			}
+			if err != nil {
+				slog.Warn("failed to fetch fragment", "fragment", src.Handle, "server", src.Server.ID, "error", err)
+				return
+			}
+			mu.Lock()
+			if shards[src.Index] == nil {
+				shards[src.Index] = data
+			}
+			mu.Unlock()
+		}(src)
+	}
+	wg.Wait()
+	return shards
+}
+
+// sendFragments stores each target's shard on its server
+func sendFragments(targets []Fragment, shards [][]byte) error {
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Fragment) {
+			defer wg.Done()
+			if err := replicationclient.SendFragment(t.Server, t.Handle, shards[t.Index]); err != nil {
+				errs[i] = fmt.Errorf("fragment %d to %s: %w", t.Index, t.Server.ID, err)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
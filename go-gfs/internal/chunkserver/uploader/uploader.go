@@ -2,17 +2,24 @@ package uploader
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
 	"log/slog"
 	"net"
 	"os"
-	"path/filepath"
 
+	"eddisonso.com/go-gfs/internal/chunkserver/checksum"
+	"eddisonso.com/go-gfs/internal/chunkserver/chunkcompress"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/chunkserver/loadstats"
+	"eddisonso.com/go-gfs/internal/chunkserver/masterclient"
 	"eddisonso.com/go-gfs/internal/chunkserver/secrets"
-	"github.com/golang-jwt/jwt/v5"
+	"eddisonso.com/go-gfs/internal/datatoken"
 )
 
+// maxTokenSize bounds the request token a client may send
+const maxTokenSize = 64 << 10
+
 type FileUploadService struct {
 	ChunkServerConfig csstructs.ChunkServerConfig
 }
@@ -28,81 +35,101 @@ func (fus *FileUploadService) HandleUpload(conn net.Conn) {
 
 	// Read JWT length
 	nBytes := make([]byte, 4)
-	n, err := conn.Read(nBytes)
-	if err != nil {
+	if _, err := io.ReadFull(conn, nBytes); err != nil {
 		slog.Error("Failed to read JWT length", "error", err)
 		fus.sendError(conn, csstructs.ErrInvalidRequest, "failed to read JWT length")
 		return
 	}
 
-	if n != 4 {
-		slog.Error("Failed to read JWT length", "bytes_read", n)
-		fus.sendError(conn, csstructs.ErrInvalidRequest, "failed to read JWT length")
+	tokenSize := binary.BigEndian.Uint32(nBytes)
+	if tokenSize > maxTokenSize {
+		slog.Error("JWT token too large", "size", tokenSize)
+		fus.sendError(conn, csstructs.ErrInvalidRequest, "JWT token too large")
 		return
 	}
 
-	tokenSize := binary.BigEndian.Uint32(nBytes)
-
 	// Read JWT token
 	jwtToken := make([]byte, tokenSize)
-	n, err = conn.Read(jwtToken)
-	if err != nil {
+	if _, err := io.ReadFull(conn, jwtToken); err != nil {
 		slog.Error("Failed to read JWT token", "error", err)
 		fus.sendError(conn, csstructs.ErrInvalidRequest, "failed to read JWT token")
 		return
 	}
 
-	if n != int(tokenSize) {
-		slog.Error("JWT token size mismatch", "expected", tokenSize, "actual", n)
-		fus.sendError(conn, csstructs.ErrInvalidRequest, "JWT token size mismatch")
+	// Parse JWT and check the client may read this chunk
+	claims := &csstructs.UploadRequestClaims{}
+	if err := secrets.ParseRequest(string(jwtToken), claims, datatoken.OpRead); err != nil {
+		slog.Error("Failed to authorize read", "chunk", claims.ChunkHandle, "error", err)
+		fus.sendError(conn, csstructs.ErrInvalidRequest, "unauthorized: "+err.Error())
 		return
 	}
 
-	// Parse JWT
-	token, err := jwt.ParseWithClaims(string(jwtToken), &csstructs.UploadRequestClaims{}, secrets.GetSecret)
-	if err != nil {
-		slog.Error("Failed to parse JWT token", "error", err)
-		fus.sendError(conn, csstructs.ErrInvalidRequest, "invalid JWT token")
-		return
-	}
-
-	claims, ok := token.Claims.(*csstructs.UploadRequestClaims)
-	if !ok || !token.Valid {
-		slog.Error("Invalid JWT claims")
-		fus.sendError(conn, csstructs.ErrInvalidRequest, "invalid JWT claims")
-		return
-	}
-
-	if claims.Operation != "upload" {
+	// Whole-chunk reads ignore the range fields so older clients keep working
+	var offset, length int64
+	switch claims.Operation {
+	case "upload":
+	case "upload_range":
+		offset, length = claims.Offset, claims.Length
+		if offset < 0 || length < 0 {
+			slog.Error("Invalid read range", "offset", offset, "length", length)
+			fus.sendError(conn, csstructs.ErrInvalidRequest, "invalid read range")
+			return
+		}
+	default:
 		slog.Error("Invalid operation", "operation", claims.Operation)
 		fus.sendError(conn, csstructs.ErrInvalidRequest, "invalid operation")
 		return
 	}
 
-	// Build file path
-	chunkFilePath := filepath.Join(fus.ChunkServerConfig.Dir, claims.ChunkHandle)
-
-	// Check file exists and get size
-	fileInfo, err := os.Stat(chunkFilePath)
-	if os.IsNotExist(err) {
-		slog.Error("Chunk not found", "path", chunkFilePath)
+	// Find the data directory holding the chunk
+	chunkFilePath, ok := fus.ChunkServerConfig.Store.Path(claims.ChunkHandle)
+	if !ok {
+		slog.Error("Chunk not found", "chunk", claims.ChunkHandle)
 		fus.sendError(conn, csstructs.ErrChunkNotFound, "chunk not found")
 		return
 	}
+
+	// Open the chunk and verify checksums of the requested blocks before sending
+	// anything, so a corrupt replica fails cleanly and the client can retry on
+	// another one. The open file keeps the verified data stable after unlocking.
+	// Compressed chunks are decompressed as they are read.
+	unlock := checksum.RLock(chunkFilePath)
+	file, err := chunkcompress.Open(chunkFilePath)
 	if err != nil {
-		slog.Error("Failed to stat chunk file", "error", err)
-		fus.sendError(conn, csstructs.ErrReadFailure, "failed to access chunk")
+		unlock()
+		switch {
+		case os.IsNotExist(err):
+			slog.Error("Chunk not found", "path", chunkFilePath)
+			fus.sendError(conn, csstructs.ErrChunkNotFound, "chunk not found")
+		case errors.Is(err, chunkcompress.ErrCorrupt):
+			fus.reportCorrupt(conn, claims.ChunkHandle, err)
+		default:
+			slog.Error("Failed to open chunk file", "error", err)
+			fus.sendError(conn, csstructs.ErrReadFailure, "failed to open chunk")
+		}
 		return
 	}
+	defer file.Close()
 
-	// Open file for reading
-	file, err := os.Open(chunkFilePath)
+	end, err := clampRange(offset, length, file.Size())
 	if err != nil {
-		slog.Error("Failed to open chunk file", "error", err)
-		fus.sendError(conn, csstructs.ErrReadFailure, "failed to open chunk")
+		unlock()
+		slog.Error("Invalid read range", "offset", offset, "size", file.Size(), "error", err)
+		fus.sendError(conn, csstructs.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	err = checksum.VerifyRange(chunkFilePath, offset, end-offset)
+	unlock()
+	if errors.Is(err, checksum.ErrMismatch) {
+		fus.reportCorrupt(conn, claims.ChunkHandle, err)
+		return
+	}
+	if err != nil && !errors.Is(err, checksum.ErrNoChecksums) {
+		slog.Error("Failed to verify chunk checksums", "chunk", claims.ChunkHandle, "error", err)
+		fus.sendError(conn, csstructs.ErrReadFailure, "failed to verify chunk")
 		return
 	}
-	defer file.Close()
 
 	// Send success response
 	if _, err := conn.Write([]byte{1}); err != nil {
@@ -110,22 +137,46 @@ func (fus *FileUploadService) HandleUpload(conn net.Conn) {
 		return
 	}
 
-	// Send file size
+	// Send the number of bytes that follow
 	sizeBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(sizeBytes, uint64(fileInfo.Size()))
+	binary.BigEndian.PutUint64(sizeBytes, uint64(end-offset))
 	if _, err := conn.Write(sizeBytes); err != nil {
 		slog.Error("Failed to send file size", "error", err)
 		return
 	}
 
-	// Stream file contents
-	_, err = io.Copy(conn, file)
+	// Stream the range, stopping at the advertised size even if an append lands meanwhile
+	sent, err := io.Copy(conn, io.NewSectionReader(file, offset, end-offset))
+	loadstats.AddRead(sent)
 	if err != nil {
 		slog.Error("Failed to stream chunk data", "error", err)
 		return
 	}
 }
 
+// clampRange returns where a read of length bytes at offset ends in a chunk of
+// size bytes. A length of 0 reads to the end, a length past the end stops
+// there, and an offset past the end is a client error.
+func clampRange(offset, length, size int64) (int64, error) {
+	if offset > size {
+		return 0, errors.New("offset beyond end of chunk")
+	}
+	if length > 0 && length < size-offset {
+		return offset + length, nil
+	}
+	return size, nil
+}
+
+// reportCorrupt fails a read of a chunk that failed verification and tells the
+// master, which replaces the replica
+func (fus *FileUploadService) reportCorrupt(conn net.Conn, handle string, err error) {
+	slog.Error("Chunk failed checksum verification", "chunk", handle, "error", err)
+	fus.sendError(conn, csstructs.ErrChecksumMismatch, "chunk checksum mismatch")
+	if mc := masterclient.GetInstance(); mc != nil {
+		go mc.ReportCorruptChunk(handle, err.Error())
+	}
+}
+
 func (fus *FileUploadService) sendError(conn net.Conn, code csstructs.ReadErrorCode, message string) {
 	// Status: failure
 	conn.Write([]byte{0})
@@ -0,0 +1,42 @@
+package uploader
+
+import "testing"
+
+func TestClampRange(t *testing.T) {
+	const size = 1000
+	tests := []struct {
+		name           string
+		offset, length int64
+		wantEnd        int64
+		wantErr        bool
+	}{
+		{name: "whole chunk", offset: 0, length: 0, wantEnd: size},
+		{name: "inside", offset: 10, length: 20, wantEnd: 30},
+		{name: "up to the end", offset: 900, length: 100, wantEnd: size},
+		{name: "length past EOF", offset: 900, length: 500, wantEnd: size},
+		{name: "length 0 reads to the end", offset: 400, length: 0, wantEnd: size},
+		{name: "offset at the end", offset: size, length: 10, wantEnd: size},
+		{name: "offset at the end, length 0", offset: size, length: 0, wantEnd: size},
+		{name: "offset past the end", offset: size + 1, length: 10, wantErr: true},
+		{name: "huge length", offset: 1, length: 1<<63 - 1, wantEnd: size},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, err := clampRange(tt.offset, tt.length, size)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("clampRange(%d, %d) = %d, want an error", tt.offset, tt.length, end)
+				}
+				return
+			}
+			if err != nil || end != tt.wantEnd {
+				t.Errorf("clampRange(%d, %d) = %d, %v; want %d", tt.offset, tt.length, end, err, tt.wantEnd)
+			}
+		})
+	}
+
+	// An empty chunk reads as nothing
+	if end, err := clampRange(0, 0, 0); err != nil || end != 0 {
+		t.Errorf("clampRange on an empty chunk = %d, %v", end, err)
+	}
+}
@@ -1,6 +1,7 @@
 package clientcli
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -9,14 +10,18 @@ import (
 	"time"
 
 	"github.com/chzyer/readline"
+	"golang.org/x/term"
 
 	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
 )
 
-var commands = []string{"ls", "cat", "read", "write", "rm", "mv", "rename", "info", "help", "exit", "quit"}
+var commands = []string{"ls", "cat", "read", "write", "cp", "sync", "rm", "mv", "rename", "stat", "du", "chunks", "snapshot", "quota", "usage", "class", "compress", "status", "drain", "rebalance", "attr", "watch", "trash", "mount", "info", "help", "exit", "quit"}
 
 type App struct {
 	masterAddr string
+	tlsCert    string
+	tlsKey     string
+	tlsCA      string
 	client     *gfs.Client
 }
 
@@ -24,12 +29,20 @@ func Run(args []string) error {
 	app := &App{
 		masterAddr: "localhost:9000",
 	}
-	app.parseFlags(args)
+	command := app.parseFlags(args)
 	if err := app.connect(); err != nil {
 		return err
 	}
 	defer app.client.Close()
 
+	// A command on the command line runs once instead of the shell
+	if len(command) > 0 {
+		return app.dispatch(command[0], command[1:])
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return runBatch(os.Stdin, app.dispatch)
+	}
+
 	fmt.Printf("Connected to %s\n", app.masterAddr)
 	fmt.Println("Type 'help' for commands, 'exit' to quit")
 	fmt.Println()
@@ -39,9 +52,23 @@ func Run(args []string) error {
 		readline.PcItem("cat", readline.PcItemDynamic(app.completeGFSPath)),
 		readline.PcItem("read", readline.PcItemDynamic(app.completeGFSPath)),
 		readline.PcItem("write", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("cp", readline.PcItem("-r")),
+		readline.PcItem("sync", readline.PcItem("--delete")),
 		readline.PcItem("rm", readline.PcItemDynamic(app.completeGFSPath)),
 		readline.PcItem("mv", readline.PcItemDynamic(app.completeGFSPath)),
 		readline.PcItem("rename", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("snapshot", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("stat", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("du", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("chunks", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("quota"),
+		readline.PcItem("usage"),
+		readline.PcItem("class", readline.PcItemDynamic(app.completeGFSPath)),
+		readline.PcItem("compress", readline.PcItem("zstd"), readline.PcItem("off")),
+		readline.PcItem("status", readline.PcItem("--json")),
+		readline.PcItem("drain", readline.PcItem("--status"), readline.PcItem("--cancel")),
+		readline.PcItem("rebalance", readline.PcItem("--status"), readline.PcItem("--stop"), readline.PcItem("--threshold")),
+		readline.PcItem("mount", readline.PcItem("--allow-other")),
 		readline.PcItem("info", readline.PcItemDynamic(app.completeGFSPath)),
 		readline.PcItem("help"),
 		readline.PcItem("exit"),
@@ -89,6 +116,26 @@ func Run(args []string) error {
 	return nil
 }
 
+// runBatch runs one command per line of r, as when a script is piped in. Blank
+// lines and lines starting with # are skipped; the first failing command stops the run.
+func runBatch(r io.Reader, run func(cmd string, args []string) error) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args := parseArgs(line)
+		if len(args) == 0 {
+			continue
+		}
+		if err := run(args[0], args[1:]); err != nil {
+			return fmt.Errorf("line %d: %s: %w", lineNum, args[0], err)
+		}
+	}
+	return scanner.Err()
+}
+
 func (a *App) completeGFSPath(line string) []string {
 	// Extract the path prefix being typed
 	parts := strings.Fields(line)
@@ -113,18 +160,38 @@ func (a *App) completeGFSPath(line string) []string {
 	return completions
 }
 
-func (a *App) parseFlags(args []string) {
-	for i := 0; i < len(args); i++ {
-		if args[i] == "-master" && i+1 < len(args) {
+// parseFlags reads the connection flags and returns the arguments after them,
+// if any, as a command to run
+func (a *App) parseFlags(args []string) []string {
+	for i := 1; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			return args[i:]
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		switch args[i] {
+		case "-master":
 			a.masterAddr = args[i+1]
+		case "-tls-cert":
+			a.tlsCert = args[i+1]
+		case "-tls-key":
+			a.tlsKey = args[i+1]
+		case "-tls-ca":
+			a.tlsCA = args[i+1]
+		default:
+			continue
 		}
+		i++
 	}
+	return nil
 }
 
 func (a *App) connect() error {
-	fmt.Printf("Connecting to master at %s...\n", a.masterAddr)
+	// Stdout is left to command output, which scripts may capture
+	fmt.Fprintf(os.Stderr, "Connecting to master at %s...\n", a.masterAddr)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	client, err := gfs.New(ctx, a.masterAddr)
+	client, err := gfs.New(ctx, a.masterAddr, gfs.WithTLS(a.tlsCert, a.tlsKey, a.tlsCA))
 	cancel()
 	if err != nil {
 		return fmt.Errorf("failed to connect to master: %w", err)
@@ -149,10 +216,44 @@ func (a *App) dispatch(cmd string, args []string) error {
 		return a.cmdRead(args)
 	case "write":
 		return a.cmdWrite(args)
+	case "cp":
+		return a.cmdCp(args)
+	case "sync":
+		return a.cmdSync(args)
 	case "mv", "rename":
 		return a.cmdMv(args)
 	case "rm":
 		return a.cmdRm(args)
+	case "snapshot":
+		return a.cmdSnapshot(args)
+	case "stat":
+		return a.cmdStat(args)
+	case "du":
+		return a.cmdDu(args)
+	case "chunks":
+		return a.cmdChunks(args)
+	case "quota":
+		return a.cmdQuota(args)
+	case "usage":
+		return a.cmdUsage(args)
+	case "class":
+		return a.cmdClass(args)
+	case "compress":
+		return a.cmdCompress(args)
+	case "status":
+		return a.cmdStatus(args)
+	case "drain":
+		return a.cmdDrain(args)
+	case "rebalance":
+		return a.cmdRebalance(args)
+	case "attr":
+		return a.cmdAttr(args)
+	case "watch":
+		return a.cmdWatch(args)
+	case "trash":
+		return a.cmdTrash(args)
+	case "mount":
+		return a.cmdMount(args)
 	case "info":
 		return a.cmdInfo(args)
 	case "help":
@@ -0,0 +1,60 @@
+package clientcli
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		fail  string // Command that fails
+		ran   []string
+		err   string
+	}{
+		{
+			name:  "commands in order",
+			input: "ls /\nstat /f\n",
+			ran:   []string{"ls /", "stat /f"},
+		},
+		{
+			name:  "blank lines and comments",
+			input: "# setup\n\n   \nls\n  # indented comment\nstat '/a b'",
+			ran:   []string{"ls", "stat /a b"},
+		},
+		{
+			name:  "first failure stops the run",
+			input: "ls\n\nrm /f\nstat /f\n",
+			fail:  "rm",
+			ran:   []string{"ls", "rm /f"},
+			err:   "line 3: rm: failed",
+		},
+		{name: "empty input"},
+	}
+	for _, tt := range tests {
+		var ran []string
+		err := runBatch(strings.NewReader(tt.input), func(cmd string, args []string) error {
+			ran = append(ran, strings.Join(append([]string{cmd}, args...), " "))
+			if cmd == tt.fail {
+				return errors.New("failed")
+			}
+			return nil
+		})
+		if !slices.Equal(ran, tt.ran) {
+			t.Errorf("%s: ran %q, want %q", tt.name, ran, tt.ran)
+		}
+		if got := errString(err); got != tt.err {
+			t.Errorf("%s: err = %q, want %q", tt.name, got, tt.err)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -2,12 +2,22 @@ package clientcli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/gfsmount"
 	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
 )
 
@@ -235,14 +245,19 @@ func (a *App) cmdRm(args []string) error {
 
 func (a *App) cmdMv(args []string) error {
 	if len(args) < 2 {
-		return fmt.Errorf("usage: mv [--namespace <name>] <source> <destination>")
+		return fmt.Errorf("usage: mv [--namespace <name>] [--force] <source> <destination>")
 	}
 	namespace, remaining, err := extractNamespace(args)
 	if err != nil {
 		return fmt.Errorf("usage error: %w", err)
 	}
+	force := false
+	if len(remaining) > 0 && (remaining[0] == "--force" || remaining[0] == "-f") {
+		force = true
+		remaining = remaining[1:]
+	}
 	if len(remaining) < 2 {
-		return fmt.Errorf("usage: mv [--namespace <name>] <source> <destination>")
+		return fmt.Errorf("usage: mv [--namespace <name>] [--force] <source> <destination>")
 	}
 	oldPath := remaining[0]
 	newPath := remaining[1]
@@ -250,7 +265,12 @@ func (a *App) cmdMv(args []string) error {
 	ctx, cancel := getContext()
 	defer cancel()
 
-	if err := a.client.RenameFileWithNamespace(ctx, oldPath, newPath, namespace); err != nil {
+	if force {
+		err = a.client.ReplaceFileWithNamespace(ctx, oldPath, newPath, namespace)
+	} else {
+		err = a.client.RenameFileWithNamespace(ctx, oldPath, newPath, namespace)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -258,19 +278,511 @@ func (a *App) cmdMv(args []string) error {
 	return nil
 }
 
-func (a *App) cmdInfo(args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: info [--namespace <name>] <path>")
+func (a *App) cmdSnapshot(args []string) error {
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+	destNamespace, remaining, err := extractDestNamespace(remaining)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	// Handle wildcard to snapshot an entire namespace
+	if len(remaining) == 1 && remaining[0] == "*" {
+		if destNamespace == "" {
+			return fmt.Errorf("snapshotting a namespace needs --to-namespace")
+		}
+		count, err := a.client.SnapshotNamespace(ctx, namespace, destNamespace)
+		if err != nil {
+			return err
+		}
+		if namespace == "" {
+			namespace = gfs.DefaultNamespace
+		}
+		fmt.Printf("Snapshotted %d files from namespace '%s' to '%s'\n", count, namespace, destNamespace)
+		return nil
+	}
+
+	if len(remaining) < 2 {
+		return fmt.Errorf("usage: snapshot [--namespace <name>] [--to-namespace <name>] <source> <destination>  OR  snapshot --namespace <name> --to-namespace <name> *")
+	}
+	sourcePath := remaining[0]
+	destPath := remaining[1]
+
+	if _, err := a.client.SnapshotFileWithNamespace(ctx, sourcePath, destPath, namespace, destNamespace); err != nil {
+		return err
 	}
+
+	fmt.Printf("Snapshotted %s -> %s\n", sourcePath, destPath)
+	return nil
+}
+
+func (a *App) cmdQuota(args []string) error {
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+	if len(remaining) != 2 {
+		return fmt.Errorf("usage: quota [--namespace <name>] <max-bytes> <max-files>  (0 is unlimited)")
+	}
+
+	maxBytes, err := parseSize(remaining[0])
+	if err != nil {
+		return err
+	}
+	maxFiles, err := strconv.ParseUint(remaining[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid file count: %s", remaining[1])
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	if err := a.client.SetNamespaceQuota(ctx, namespace, maxBytes, maxFiles); err != nil {
+		return err
+	}
+
+	if namespace == "" {
+		namespace = gfs.DefaultNamespace
+	}
+	fmt.Printf("Set quota for namespace '%s'\n", namespace)
+	return nil
+}
+
+func (a *App) cmdUsage(args []string) error {
+	namespace, _, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	var usages []*pb.NamespaceUsage
+	if namespace != "" {
+		usage, err := a.client.GetNamespaceUsage(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		usages = append(usages, usage)
+	} else {
+		usages, err = a.client.ListNamespaceUsage(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(usages) == 0 {
+		fmt.Println("No namespaces found")
+		return nil
+	}
+
+	renderUsageTable(os.Stdout, usages)
+	return nil
+}
+
+func (a *App) cmdClass(args []string) error {
+	const usage = "usage: class [--namespace <name>] [path] <replicated|cold|inherit>"
+
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+	if len(remaining) < 1 || len(remaining) > 2 {
+		return errors.New(usage)
+	}
+
+	class := remaining[len(remaining)-1]
+	if class == "inherit" {
+		class = ""
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	if namespace == "" {
+		namespace = gfs.DefaultNamespace
+	}
+	if len(remaining) == 2 {
+		path := remaining[0]
+		if err := a.client.SetStorageClass(ctx, path, namespace, class); err != nil {
+			return err
+		}
+		fmt.Printf("Set storage class of %s to %s\n", path, remaining[1])
+		return nil
+	}
+
+	if err := a.client.SetNamespaceStorageClass(ctx, namespace, class); err != nil {
+		return err
+	}
+	fmt.Printf("Set storage class of namespace '%s' to %s\n", namespace, remaining[0])
+	return nil
+}
+
+func (a *App) cmdCompress(args []string) error {
+	const usage = "usage: compress [--namespace <name>] <zstd|off>"
+
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+	if len(remaining) != 1 {
+		return errors.New(usage)
+	}
+
+	compression := remaining[0]
+	if compression == "off" {
+		compression = ""
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	if namespace == "" {
+		namespace = gfs.DefaultNamespace
+	}
+	if err := a.client.SetNamespaceCompression(ctx, namespace, compression); err != nil {
+		return err
+	}
+	fmt.Printf("Set compression of namespace '%s' to %s\n", namespace, remaining[0])
+	return nil
+}
+
+func (a *App) cmdStatus(args []string) error {
+	asJSON, args := cutFlag(args, "--json")
+	if len(args) != 0 {
+		return errors.New("usage: status [--json]")
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	servers, err := a.client.GetClusterStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return writeProtoJSON(os.Stdout, servers)
+	}
+	if len(servers) == 0 {
+		fmt.Println("No chunkservers registered")
+		return nil
+	}
+
+	renderClusterStatus(os.Stdout, servers)
+	return nil
+}
+
+func (a *App) cmdDrain(args []string) error {
+	const usage = "usage: drain <server-id>  OR  drain --cancel <server-id>  OR  drain --status [server-id]"
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	if len(args) >= 1 && args[0] == "--status" {
+		serverID := ""
+		if len(args) > 1 {
+			serverID = args[1]
+		}
+		statuses, err := a.client.GetDrainStatus(ctx, serverID)
+		if err != nil {
+			return err
+		}
+		if len(statuses) == 0 {
+			fmt.Println("No chunkservers are draining")
+			return nil
+		}
+		renderDrainTable(os.Stdout, statuses)
+		return nil
+	}
+
+	if len(args) == 2 && args[0] == "--cancel" {
+		if err := a.client.CancelDrain(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Cancelled drain of %s\n", args[1])
+		return nil
+	}
+
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return errors.New(usage)
+	}
+	if err := a.client.DrainChunkServer(ctx, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Draining %s; check progress with 'drain --status %s'\n", args[0], args[0])
+	return nil
+}
+
+func (a *App) cmdRebalance(args []string) error {
+	const usage = "usage: rebalance [--threshold <0-1>]  OR  rebalance --status  OR  rebalance --stop"
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	switch {
+	case len(args) == 1 && args[0] == "--status":
+		status, err := a.client.GetRebalanceStatus(ctx)
+		if err != nil {
+			return err
+		}
+		renderRebalanceStatus(os.Stdout, status)
+		return nil
+
+	case len(args) == 1 && args[0] == "--stop":
+		if err := a.client.StopRebalance(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Rebalance stopped")
+		return nil
+	}
+
+	threshold := 0.0
+	switch {
+	case len(args) == 0:
+	case len(args) == 2 && args[0] == "--threshold":
+		value, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid threshold: %s", args[1])
+		}
+		threshold = value
+	default:
+		return errors.New(usage)
+	}
+
+	if err := a.client.StartRebalance(ctx, threshold); err != nil {
+		return err
+	}
+	fmt.Println("Rebalance started; check progress with 'rebalance --status'")
+	return nil
+}
+
+func (a *App) cmdTrash(args []string) error {
+	const usage = "usage: trash [--namespace <name>]  OR  trash restore [--to-namespace <name>] <trash-id> [path]  OR  trash purge [--namespace <name>] [trash-id...]"
+
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	if len(remaining) == 0 {
+		entries, err := a.client.ListTrash(ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("Trash is empty")
+			return nil
+		}
+		renderTrashTable(os.Stdout, entries)
+		return nil
+	}
+
+	switch remaining[0] {
+	case "restore":
+		destNamespace, rest, err := extractDestNamespace(remaining[1:])
+		if err != nil {
+			return fmt.Errorf("usage error: %w", err)
+		}
+		if len(rest) < 1 || len(rest) > 2 {
+			return errors.New(usage)
+		}
+		path := ""
+		if len(rest) == 2 {
+			path = rest[1]
+		}
+		file, err := a.client.RestoreFileTo(ctx, rest[0], path, destNamespace)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s to namespace '%s'\n", file.Path, file.Namespace)
+		return nil
+
+	case "purge":
+		count, err := a.client.PurgeTrash(ctx, namespace, remaining[1:]...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d files from the trash\n", count)
+		return nil
+	}
+
+	return errors.New(usage)
+}
+
+func (a *App) cmdAttr(args []string) error {
+	const usage = "usage: attr [--namespace <name>] <path> [key=value...] [-key...]"
+
 	namespace, remaining, err := extractNamespace(args)
 	if err != nil {
 		return fmt.Errorf("usage error: %w", err)
 	}
 	if len(remaining) < 1 {
-		return fmt.Errorf("usage: info [--namespace <name>] <path>")
+		return errors.New(usage)
 	}
 	path := remaining[0]
 
+	set := make(map[string]string)
+	var remove []string
+	for _, arg := range remaining[1:] {
+		if key, ok := strings.CutPrefix(arg, "-"); ok && key != "" {
+			remove = append(remove, key)
+			continue
+		}
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return errors.New(usage)
+		}
+		set[key] = value
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	var f *pb.FileInfoResponse
+	if len(set) == 0 && len(remove) == 0 {
+		f, err = a.client.GetFileWithNamespace(ctx, path, namespace)
+	} else {
+		f, err = a.client.SetFileAttributesWithNamespace(ctx, path, namespace, set, remove...)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(f.Attributes) == 0 {
+		fmt.Println("No attributes")
+		return nil
+	}
+	for _, key := range slices.Sorted(maps.Keys(f.Attributes)) {
+		fmt.Printf("%s=%s\n", key, f.Attributes[key])
+	}
+	return nil
+}
+
+func (a *App) cmdWatch(args []string) error {
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+	opts := gfs.WatchOptions{Namespace: namespace}
+	switch len(remaining) {
+	case 0:
+	case 1:
+		opts.Prefix = remaining[0]
+	default:
+		return fmt.Errorf("usage: watch [--namespace <name>] [prefix]")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Println("Watching for changes; press Ctrl-C to stop")
+	for event, err := range a.client.WatchNamespace(ctx, opts) {
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		path := event.Path
+		if path == "" {
+			path = "*"
+		}
+		switch event.Type {
+		case gfs.EventRename:
+			fmt.Printf("[%d] %-10s %s/%s -> %s\n", event.ResumeToken, event.Type, event.Namespace, path, event.NewPath)
+		case gfs.EventCommit:
+			fmt.Printf("[%d] %-10s %s/%s (%s)\n", event.ResumeToken, event.Type, event.Namespace, path, formatBytes(int64(event.ChunkSize)))
+		default:
+			fmt.Printf("[%d] %-10s %s/%s\n", event.ResumeToken, event.Type, event.Namespace, path)
+		}
+	}
+	return nil
+}
+
+func (a *App) cmdMount(args []string) error {
+	const usage = "usage: mount [--namespace <name>] [--allow-other] <dir>"
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return fmt.Errorf("usage error: %w", err)
+	}
+	allowOther := false
+	var dirs []string
+	for _, arg := range remaining {
+		if arg == "--allow-other" {
+			allowOther = true
+			continue
+		}
+		dirs = append(dirs, arg)
+	}
+	if len(dirs) != 1 {
+		return errors.New(usage)
+	}
+
+	server, err := gfsmount.Mount(a.client, namespace, dirs[0], allowOther)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Serve() }()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if namespace == "" {
+		namespace = "default"
+	}
+	fmt.Printf("Mounted namespace %s on %s; press Ctrl-C to unmount\n", namespace, server.Dir())
+	select {
+	case err := <-done:
+		// Unmounted from outside, e.g. by umount
+		return err
+	case <-ctx.Done():
+	}
+	if err := server.Unmount(); err != nil {
+		return err
+	}
+	if err := <-done; err != nil {
+		return err
+	}
+	fmt.Println("Unmounted")
+	return nil
+}
+
+func (a *App) cmdInfo(args []string) error {
+	if err := a.cmdStat(args); err != nil {
+		return err
+	}
+	fmt.Println()
+	return a.cmdChunks(args)
+}
+
+// parseFileArgs parses the arguments of stat and chunks
+func parseFileArgs(args []string, usage string) (namespace, path string, asJSON bool, err error) {
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return "", "", false, fmt.Errorf("usage error: %w", err)
+	}
+	asJSON, remaining = cutFlag(remaining, "--json")
+	if len(remaining) != 1 {
+		return "", "", false, errors.New(usage)
+	}
+	return namespace, remaining[0], asJSON, nil
+}
+
+func (a *App) cmdStat(args []string) error {
+	namespace, path, asJSON, err := parseFileArgs(args, "usage: stat [--namespace <name>] [--json] <path>")
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := getContext()
 	defer cancel()
 
@@ -278,6 +790,9 @@ func (a *App) cmdInfo(args []string) error {
 	if err != nil {
 		return err
 	}
+	if asJSON {
+		return writeProtoJSON(os.Stdout, []*pb.FileInfoResponse{f})
+	}
 
 	displayNamespace := f.Namespace
 	if displayNamespace == "" {
@@ -289,22 +804,245 @@ func (a *App) cmdInfo(args []string) error {
 	fmt.Printf("Size:       %d bytes\n", f.Size)
 	fmt.Printf("Chunk Size: %d bytes\n", f.ChunkSize)
 	fmt.Printf("Chunks:     %d\n", len(f.ChunkHandles))
+	if f.StorageClass != "" {
+		fmt.Printf("Class:      %s\n", f.StorageClass)
+	}
+	fmt.Printf("Created:    %s\n", formatTime(f.CreatedAt))
+	fmt.Printf("Modified:   %s\n", formatTime(f.ModifiedAt))
+	if len(f.Attributes) > 0 {
+		fmt.Println("Attributes:")
+		for _, key := range slices.Sorted(maps.Keys(f.Attributes)) {
+			fmt.Printf("  %s=%s\n", key, f.Attributes[key])
+		}
+	}
+	return nil
+}
+
+func (a *App) cmdChunks(args []string) error {
+	namespace, path, asJSON, err := parseFileArgs(args, "usage: chunks [--namespace <name>] [--json] <path>")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	chunks, err := a.client.GetChunkLocationsWithNamespace(ctx, path, namespace)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return writeProtoJSON(os.Stdout, chunks)
+	}
+	if len(chunks) == 0 {
+		fmt.Println("No chunks")
+		return nil
+	}
+	renderChunkTable(os.Stdout, chunks)
+	return nil
+}
+
+// duArgs are the parsed arguments of du
+type duArgs struct {
+	namespace string
+	prefix    string // Ends in "/"
+	summarize bool
+	asJSON    bool
+}
+
+func parseDuArgs(args []string) (duArgs, error) {
+	const usage = "usage: du [--namespace <name>] [-s] [--json] [prefix]"
+	namespace, remaining, err := extractNamespace(args)
+	if err != nil {
+		return duArgs{}, fmt.Errorf("usage error: %w", err)
+	}
+	du := duArgs{namespace: namespace, prefix: "/"}
+	du.summarize, remaining = cutFlag(remaining, "-s", "--summarize")
+	du.asJSON, remaining = cutFlag(remaining, "--json")
+	if len(remaining) > 1 {
+		return duArgs{}, errors.New(usage)
+	}
+	if len(remaining) == 1 {
+		du.prefix = strings.TrimSuffix(remaining[0], "/") + "/"
+	}
+	return du, nil
+}
+
+func (a *App) cmdDu(args []string) error {
+	du, err := parseDuArgs(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := getContext()
+	defer cancel()
+
+	// Sum each file into the entry for its first path element under the prefix
+	entries := make(map[string]*diskUsage)
+	total := &diskUsage{Path: du.prefix}
+	for f, err := range a.client.IterFiles(ctx, gfs.ListOptions{Namespace: du.namespace, Prefix: du.prefix}) {
+		if err != nil {
+			return err
+		}
+		total.add(f)
+		if du.summarize {
+			continue
+		}
+		name, _, isDir := strings.Cut(strings.TrimPrefix(f.Path, du.prefix), "/")
+		key := du.prefix + name
+		if isDir {
+			key += "/"
+		}
+		if entries[key] == nil {
+			entries[key] = &diskUsage{Path: key}
+		}
+		entries[key].add(f)
+	}
+
+	var usages []*diskUsage
+	for _, key := range slices.Sorted(maps.Keys(entries)) {
+		usages = append(usages, entries[key])
+	}
+	usages = append(usages, total)
+	if du.asJSON {
+		return writeJSON(os.Stdout, usages)
+	}
+	renderDiskUsage(os.Stdout, usages)
+	return nil
+}
+
+func (a *App) cmdCp(args []string) error {
+	const usage = "usage: cp [-r] [-j <jobs>] <source> <destination>  (GFS paths are namespace:/path)"
+	parsed, recursive, err := parseTransferArgs(args, usage, "-r", "-R", "--recursive")
+	if err != nil {
+		return err
+	}
+	src, dst := parsed.src, parsed.dst
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var transfers []transfer
+	if recursive {
+		files, err := a.listFiles(ctx, src)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no files under %s", src)
+		}
+		for _, rel := range slices.Sorted(maps.Keys(files)) {
+			f := files[rel]
+			transfers = append(transfers, transfer{src: src.join(rel), dst: dst.join(rel), size: f.size, modTime: f.modTime})
+		}
+	} else {
+		t, err := a.fileTransfer(ctx, src, dst)
+		if err != nil {
+			return err
+		}
+		transfers = append(transfers, t)
+	}
 
-	chunks, err := a.client.GetChunkLocationsWithNamespace(ctx, path, displayNamespace)
-	if err == nil && len(chunks) > 0 {
-		fmt.Println("\nChunk Details:")
-		for i, chunk := range chunks {
-			fmt.Printf("  [%d] %s (%d bytes)\n", i, chunk.ChunkHandle, chunk.Size)
-			if chunk.Primary != nil {
-				fmt.Printf("      Primary: %s:%d\n", chunk.Primary.Hostname, chunk.Primary.DataPort)
+	if err := a.runTransfers(ctx, transfers, parsed.jobs); err != nil {
+		return err
+	}
+	fmt.Printf("Copied %d files (%s) to %s\n", len(transfers), formatBytes(transferSize(transfers)), dst)
+	return nil
+}
+
+// fileTransfer plans the copy of a single file. A destination ending in a
+// separator, or an existing local directory, receives the file under its own name
+func (a *App) fileTransfer(ctx context.Context, src, dst location) (transfer, error) {
+	t := transfer{src: src, dst: dst}
+	if src.remote {
+		f, err := a.client.GetFileWithNamespace(ctx, src.path, src.namespace)
+		if err != nil {
+			return t, err
+		}
+		t.size, t.modTime = int64(f.Size), time.Unix(f.ModifiedAt, 0)
+		if info, err := os.Stat(dst.path); (err == nil && info.IsDir()) || strings.HasSuffix(dst.path, string(filepath.Separator)) {
+			t.dst = dst.join(path.Base(src.path))
+		}
+		return t, nil
+	}
+
+	info, err := os.Stat(src.path)
+	if err != nil {
+		return t, err
+	}
+	if info.IsDir() {
+		return t, fmt.Errorf("%s is a directory (use cp -r)", src.path)
+	}
+	t.size, t.modTime = info.Size(), info.ModTime()
+	if strings.HasSuffix(dst.path, "/") {
+		t.dst = dst.join(filepath.Base(src.path))
+	}
+	return t, nil
+}
+
+func (a *App) cmdSync(args []string) error {
+	const usage = "usage: sync [--delete] [-j <jobs>] <source-dir> <destination-dir>  (GFS prefixes are namespace:/prefix)"
+	parsed, deleteExtra, err := parseTransferArgs(args, usage, "--delete")
+	if err != nil {
+		return err
+	}
+	src, dst := parsed.src, parsed.dst
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	srcFiles, err := a.listFiles(ctx, src)
+	if err != nil {
+		return err
+	}
+	dstFiles, err := a.listFiles(ctx, dst)
+	if err != nil {
+		return err
+	}
+
+	transfers, unchanged := planSync(src, dst, srcFiles, dstFiles)
+	if err := a.runTransfers(ctx, transfers, parsed.jobs); err != nil {
+		return err
+	}
+
+	deleted := 0
+	if deleteExtra {
+		for _, rel := range slices.Sorted(maps.Keys(dstFiles)) {
+			if _, ok := srcFiles[rel]; ok {
+				continue
+			}
+			target := dst.join(rel)
+			if target.remote {
+				err = a.client.DeleteFileWithNamespace(ctx, target.path, target.namespace)
+			} else {
+				err = os.Remove(target.path)
 			}
-			for _, loc := range chunk.Locations {
-				if chunk.Primary == nil || loc.ServerId != chunk.Primary.ServerId {
-					fmt.Printf("      Replica: %s:%d\n", loc.Hostname, loc.DataPort)
-				}
+			if err != nil {
+				return fmt.Errorf("failed to delete %s: %w", target, err)
 			}
+			deleted++
 		}
 	}
 
+	fmt.Printf("Copied %d files (%s), deleted %d, %d up to date\n", len(transfers), formatBytes(transferSize(transfers)), deleted, unchanged)
+	return nil
+}
+
+// checkTransfer rejects cp and sync arguments that aren't one local path and one GFS path
+func checkTransfer(src, dst location) error {
+	switch {
+	case src.remote && dst.remote:
+		return errors.New("both paths are in GFS; use snapshot to copy within GFS")
+	case !src.remote && !dst.remote:
+		return errors.New("one path must be in GFS, written namespace:/path (:/path for the default namespace)")
+	}
 	return nil
 }
+
+func transferSize(transfers []transfer) int64 {
+	var total int64
+	for _, t := range transfers {
+		total += t.size
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package clientcli
+
+import "testing"
+
+func TestParseFileArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		namespace string
+		path      string
+		asJSON    bool
+		ok        bool
+	}{
+		{args: []string{"/f"}, path: "/f", ok: true},
+		{args: []string{"-n", "ns", "/f"}, namespace: "ns", path: "/f", ok: true},
+		{args: []string{"/f", "--namespace=ns", "--json"}, namespace: "ns", path: "/f", asJSON: true, ok: true},
+		{args: []string{"--json", "-n=ns", "/f"}, namespace: "ns", path: "/f", asJSON: true, ok: true},
+		{args: nil},
+		{args: []string{"--json"}},
+		{args: []string{"/f", "/g"}},
+		{args: []string{"/f", "--namespace"}},
+	}
+	for _, tt := range tests {
+		namespace, path, asJSON, err := parseFileArgs(tt.args, "usage")
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: err = %v, want ok %v", tt.args, err, tt.ok)
+			continue
+		}
+		if namespace != tt.namespace || path != tt.path || asJSON != tt.asJSON {
+			t.Errorf("%q = %q, %q, %v; want %q, %q, %v", tt.args, namespace, path, asJSON, tt.namespace, tt.path, tt.asJSON)
+		}
+	}
+}
+
+func TestParseDuArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want duArgs
+		ok   bool
+	}{
+		{args: nil, want: duArgs{prefix: "/"}, ok: true},
+		{args: []string{"/logs"}, want: duArgs{prefix: "/logs/"}, ok: true},
+		{args: []string{"/logs/"}, want: duArgs{prefix: "/logs/"}, ok: true},
+		{args: []string{"-s", "-n", "ns", "/a"}, want: duArgs{namespace: "ns", prefix: "/a/", summarize: true}, ok: true},
+		{args: []string{"--summarize", "--json"}, want: duArgs{prefix: "/", summarize: true, asJSON: true}, ok: true},
+		{args: []string{"/a", "/b"}},
+		{args: []string{"-n"}},
+	}
+	for _, tt := range tests {
+		got, err := parseDuArgs(tt.args)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: err = %v, want ok %v", tt.args, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
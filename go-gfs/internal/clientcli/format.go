@@ -1,12 +1,19 @@
 package clientcli
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	pb "eddisonso.com/go-gfs/gen/master"
+	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 func formatBytes(b int64) string {
@@ -32,6 +39,36 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
 }
 
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Format(time.RFC3339)
+}
+
+// writeJSON prints v as indented JSON, for scripts
+func writeJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// writeProtoJSON prints messages as a JSON array using the proto field names
+func writeProtoJSON[M proto.Message](w io.Writer, msgs []M) error {
+	raw := make([]json.RawMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, data)
+	}
+	return writeJSON(w, raw)
+}
+
 func renderFileTable(w io.Writer, files []*pb.FileInfoResponse) {
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 	fmt.Fprintln(tw, "NAMESPACE\tNAME\tCHUNKS\tSIZE")
@@ -49,3 +86,187 @@ func renderFileTable(w io.Writer, files []*pb.FileInfoResponse) {
 	}
 	tw.Flush()
 }
+
+func renderUsageTable(w io.Writer, usages []*pb.NamespaceUsage) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tFILES\tMAX FILES\tUSED\tMAX BYTES\tCLASS\tCOMPRESSION")
+	for _, u := range usages {
+		maxFiles := "unlimited"
+		if u.MaxFiles > 0 {
+			maxFiles = strconv.FormatUint(u.MaxFiles, 10)
+		}
+		maxBytes := "unlimited"
+		if u.MaxBytes > 0 {
+			maxBytes = formatBytes(int64(u.MaxBytes))
+		}
+		compression := u.Compression
+		if compression == "" {
+			compression = "off"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			u.Namespace,
+			u.FileCount,
+			maxFiles,
+			formatBytes(int64(u.UsedBytes)),
+			maxBytes,
+			u.StorageClass,
+			compression,
+		)
+	}
+	tw.Flush()
+}
+
+func renderClusterStatus(w io.Writer, servers []*pb.ChunkServerStatus) {
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Server.GetServerId() < servers[j].Server.GetServerId()
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tALIVE\tCHUNKS\tCOMPRESSED\tUSED\tLOGICAL\tFREE\tCAPACITY")
+	var capacity, free, used, logical uint64
+	for _, s := range servers {
+		fmt.Fprintf(tw, "%s\t%t\t%d\t%d\t%s\t%s\t%s\t%s\n",
+			s.Server.GetServerId(),
+			s.IsAlive,
+			s.ChunkCount,
+			s.CompressedChunks,
+			formatBytes(int64(s.UsedBytes)),
+			formatBytes(int64(s.LogicalBytes)),
+			formatBytes(int64(s.FreeBytes)),
+			formatBytes(int64(s.CapacityBytes)),
+		)
+		if s.IsAlive {
+			capacity += s.CapacityBytes
+			free += s.FreeBytes
+			used += s.UsedBytes
+			logical += s.LogicalBytes
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nUsed %s of %s (%s free), %s before compression (%.2fx)\n",
+		formatBytes(int64(used)),
+		formatBytes(int64(capacity)),
+		formatBytes(int64(free)),
+		formatBytes(int64(logical)),
+		gfs.CompressionRatio(servers),
+	)
+}
+
+func renderDrainTable(w io.Writer, statuses []*pb.DrainStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tALIVE\tMOVED\tREMAINING\tIN FLIGHT\tRUNNING\tSTATE")
+	for _, s := range statuses {
+		state := "draining"
+		if s.Complete {
+			state = "complete"
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%d\t%d\t%d\t%s\t%s\n",
+			s.ServerId,
+			s.IsAlive,
+			s.MovedChunks,
+			s.RemainingChunks,
+			s.InflightMoves,
+			formatDuration(time.Since(time.Unix(s.StartedAt, 0))),
+			state,
+		)
+	}
+	tw.Flush()
+}
+
+func renderRebalanceStatus(w io.Writer, status *pb.GetRebalanceStatusResponse) {
+	if status.Active {
+		fmt.Fprintf(w, "Rebalance running for %s: %d chunks moved, %d in flight, threshold %.0f%%\n",
+			formatDuration(time.Since(time.Unix(status.StartedAt, 0))),
+			status.MovedChunks,
+			status.InflightMoves,
+			status.Threshold*100,
+		)
+	} else {
+		fmt.Fprintln(w, "No rebalance running")
+	}
+	fmt.Fprintf(w, "Spread: %.1f%%\n\n", status.Spread*100)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tFILL")
+	for _, s := range status.Servers {
+		fmt.Fprintf(tw, "%s\t%.1f%%\n", s.ServerId, s.FillRatio*100)
+	}
+	tw.Flush()
+}
+
+func renderTrashTable(w io.Writer, entries []*pb.TrashEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TRASH ID\tNAMESPACE\tPATH\tSIZE\tDELETED\tEXPIRES IN")
+	for _, e := range entries {
+		expires := "never"
+		if e.ExpiresAt > 0 {
+			expires = formatDuration(max(time.Until(time.Unix(e.ExpiresAt, 0)), 0))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s ago\t%s\n",
+			e.TrashId,
+			e.Namespace,
+			e.Path,
+			formatBytes(int64(e.Size)),
+			formatDuration(time.Since(time.Unix(e.DeletedAt, 0))),
+			expires,
+		)
+	}
+	tw.Flush()
+}
+
+func renderChunkTable(w io.Writer, chunks []*pb.ChunkLocationInfo) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tHANDLE\tVERSION\tSIZE\tPRIMARY\tREPLICAS")
+	for i, chunk := range chunks {
+		primary := "-"
+		if chunk.Primary != nil {
+			primary = chunk.Primary.ServerId
+		}
+		var replicas []string
+		if chunk.Stripe != nil {
+			// Erasure coded chunks list each fragment's servers in fragment order
+			primary = fmt.Sprintf("stripe %d+%d", chunk.Stripe.DataShards, chunk.Stripe.ParityShards)
+			for _, fragment := range chunk.Stripe.Fragments {
+				for _, loc := range fragment.Locations {
+					replicas = append(replicas, fmt.Sprintf("%d:%s", fragment.Index, loc.ServerId))
+				}
+			}
+		}
+		for _, loc := range chunk.Locations {
+			replicas = append(replicas, loc.ServerId)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\t%s\n",
+			i,
+			chunk.ChunkHandle,
+			chunk.Version,
+			formatBytes(int64(chunk.Size)),
+			primary,
+			strings.Join(replicas, ","),
+		)
+	}
+	tw.Flush()
+}
+
+// diskUsage is the size of the files under a path, as shown by du
+type diskUsage struct {
+	Path   string `json:"path"`
+	Files  int    `json:"files"`
+	Bytes  uint64 `json:"bytes"`
+	Chunks int    `json:"chunks"`
+}
+
+func (u *diskUsage) add(f *pb.FileInfoResponse) {
+	u.Files++
+	u.Bytes += f.Size
+	u.Chunks += len(f.ChunkHandles)
+}
+
+func renderDiskUsage(w io.Writer, usages []*diskUsage) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tFILES\tCHUNKS\tPATH")
+	for _, u := range usages {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", formatBytes(int64(u.Bytes)), u.Files, u.Chunks, u.Path)
+	}
+	tw.Flush()
+}
@@ -9,9 +9,48 @@ func printHelp() {
   read [--namespace <name>] <path> > <file>   Read file to local file
   write [--namespace <name>] <path> <data>   Write data to file
   write [--namespace <name>] <path> < <file> Write local file to GFS
-  mv [--namespace <name>] <src> <dst>         Rename/move a file
+  cp [-r] [-j <jobs>] <src> <dst>             Copy files between local disk and GFS, with GFS
+                                              paths written namespace:/path (:/path for default);
+                                              -r copies a directory or prefix, <jobs> at a time
+  sync [--delete] [-j <jobs>] <src> <dst>     Copy new and changed files from a directory to a
+                                              prefix or back; --delete removes files not in <src>
+  mv [--namespace <name>] [--force] <src> <dst>
+                                              Rename/move a file; --force replaces <dst>
   rm [--namespace <name>] <path>              Delete a file (use * to delete all in namespace)
-  info [--namespace <name>] <path>            Show file information
+  trash [--namespace <name>]                  List deleted files that can still be restored
+  trash restore [--to-namespace <name>] <trash-id> [path]
+                                              Restore a deleted file, by default where it was
+  trash purge [--namespace <name>] [trash-id...]
+                                              Permanently delete files from the trash
+  snapshot [--namespace <name>] [--to-namespace <name>] <src> <dst>
+                                              Copy-on-write snapshot of a file
+  snapshot --namespace <name> --to-namespace <name> *
+                                              Snapshot every file into an empty namespace
+  stat [--namespace <name>] [--json] <path>   Show file metadata
+  chunks [--namespace <name>] [--json] <path> Show each chunk of a file and its replica locations
+  du [--namespace <name>] [-s] [--json] [prefix]
+                                              Show space used under each entry of a prefix
+  quota [--namespace <name>] <bytes> <files>  Set namespace quota (e.g. 10G 5000, 0 is unlimited)
+  usage [--namespace <name>]                  Show namespace usage and quotas
+  class [--namespace <name>] [path] <class>   Set storage class of a file, or of the namespace
+                                              without a path (replicated, cold or inherit)
+  compress [--namespace <name>] <zstd|off>    Store the namespace's sealed chunks compressed
+  status [--json]                             Show chunkserver usage and compression ratio
+  drain <server-id>                           Move all chunks off a chunkserver before removal
+  drain --status [server-id] | --cancel <id>  Show drain progress or return a server to service
+  rebalance [--threshold <0-1>]               Even out disk usage across chunkservers
+  rebalance --status | --stop                 Show rebalance progress or stop it
+  attr [--namespace <name>] <path> [key=value...] [-key...]
+                                              Show, set or remove file attributes
+  watch [--namespace <name>] [prefix]         Print changes to files as they happen (Ctrl-C to stop)
+  mount [--namespace <name>] [--allow-other] <dir>
+                                              Mount the namespace read-write on a local
+                                              directory through FUSE (Ctrl-C to unmount)
+  info [--namespace <name>] <path>            Show file metadata and chunks
   help                    Show this help
-  exit                    Quit the client`)
+  exit                    Quit the client
+
+Any command can also be given after the flags to run it once, e.g.
+  client -master <addr> mount --namespace <name> /mnt/gfs
+Commands piped to stdin run one per line, stopping at the first that fails.`)
 }
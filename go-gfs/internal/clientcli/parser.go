@@ -2,6 +2,7 @@ package clientcli
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -65,3 +66,53 @@ func extractNamespace(args []string) (string, []string, error) {
 
 	return namespace, remaining, nil
 }
+
+func extractDestNamespace(args []string) (string, []string, error) {
+	var namespace string
+	remaining := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--to-namespace" || arg == "-t" {
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("missing destination namespace value")
+			}
+			namespace = args[i+1]
+			i++
+			continue
+		}
+		if strings.HasPrefix(arg, "--to-namespace=") {
+			namespace = strings.TrimPrefix(arg, "--to-namespace=")
+			continue
+		}
+		remaining = append(remaining, arg)
+	}
+
+	return namespace, remaining, nil
+}
+
+// parseSize parses a byte count with an optional binary suffix (K, M, G, T)
+func parseSize(s string) (uint64, error) {
+	upper := strings.ToUpper(strings.TrimSuffix(strings.ToUpper(s), "B"))
+	multiplier := uint64(1)
+	if n := len(upper); n > 0 {
+		switch upper[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			upper = upper[:n-1]
+		}
+	}
+	value, err := strconv.ParseUint(upper, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return value * multiplier, nil
+}
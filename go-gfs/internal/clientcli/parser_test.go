@@ -0,0 +1,27 @@
+package clientcli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  ls  ", []string{"ls"}},
+		{"cp -r dir ns:/p", []string{"cp", "-r", "dir", "ns:/p"}},
+		{"write\t/f  \"hello world\"", []string{"write", "/f", "hello world"}},
+		{`attr /f 'k=a "quoted" value'`, []string{"attr", "/f", `k=a "quoted" value`}},
+		{`rm "/with space"/tail`, []string{"rm", "/with space/tail"}},
+		{`write /f ""`, []string{"write", "/f"}},
+		{`write /f "unterminated`, []string{"write", "/f", "unterminated"}},
+	}
+	for _, tt := range tests {
+		if got := parseArgs(tt.line); !slices.Equal(got, tt.want) {
+			t.Errorf("parseArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package clientcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
+)
+
+const defaultTransferJobs = 4
+
+// location is a cp or sync argument: a local path, or a GFS path written as
+// namespace:/path (":/path" for the default namespace)
+type location struct {
+	remote    bool
+	namespace string
+	path      string
+}
+
+func parseLocation(arg string) location {
+	ns, p, ok := strings.Cut(arg, ":")
+	if ok && strings.HasPrefix(p, "/") && !strings.ContainsAny(ns, `/\`) {
+		return location{remote: true, namespace: ns, path: p}
+	}
+	return location{path: arg}
+}
+
+func (l location) String() string {
+	if !l.remote {
+		return l.path
+	}
+	return l.namespace + ":" + l.path
+}
+
+// join returns the location of rel, a "/"-separated path, under l
+func (l location) join(rel string) location {
+	if l.remote {
+		l.path = path.Join(l.path, rel)
+	} else {
+		l.path = filepath.Join(l.path, filepath.FromSlash(rel))
+	}
+	return l
+}
+
+// transferArgs are the arguments cp and sync share
+type transferArgs struct {
+	src, dst location
+	jobs     int
+}
+
+// parseTransferArgs parses cp or sync arguments and reports whether one of
+// flags was given
+func parseTransferArgs(args []string, usage string, flags ...string) (transferArgs, bool, error) {
+	jobs, remaining, err := parseJobs(args)
+	if err != nil {
+		return transferArgs{}, false, fmt.Errorf("usage error: %w", err)
+	}
+	found, remaining := cutFlag(remaining, flags...)
+	if len(remaining) != 2 {
+		return transferArgs{}, false, errors.New(usage)
+	}
+	t := transferArgs{src: parseLocation(remaining[0]), dst: parseLocation(remaining[1]), jobs: jobs}
+	if err := checkTransfer(t.src, t.dst); err != nil {
+		return transferArgs{}, false, err
+	}
+	return t, found, nil
+}
+
+// fileEntry is a file found under a cp or sync source or destination
+type fileEntry struct {
+	rel     string // "/"-separated, relative to the listed directory or prefix
+	size    int64
+	modTime time.Time
+}
+
+// listFiles lists every file under a local directory or GFS prefix, keyed by relative path
+func (a *App) listFiles(ctx context.Context, dir location) (map[string]fileEntry, error) {
+	files := make(map[string]fileEntry)
+	if dir.remote {
+		prefix := strings.TrimSuffix(dir.path, "/") + "/"
+		for f, err := range a.client.IterFiles(ctx, gfs.ListOptions{Namespace: dir.namespace, Prefix: prefix}) {
+			if err != nil {
+				return nil, err
+			}
+			rel := strings.TrimPrefix(f.Path, prefix)
+			files[rel] = fileEntry{rel: rel, size: int64(f.Size), modTime: time.Unix(f.ModifiedAt, 0)}
+		}
+		return files, nil
+	}
+
+	err := filepath.WalkDir(dir.path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir.path && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir.path, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files[rel] = fileEntry{rel: rel, size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// planSync returns the copies that bring dst up to date with src: files that
+// are missing, differ in size, or changed since the copy. It also counts the
+// files left alone.
+func planSync(src, dst location, srcFiles, dstFiles map[string]fileEntry) ([]transfer, int) {
+	var transfers []transfer
+	unchanged := 0
+	for _, rel := range slices.Sorted(maps.Keys(srcFiles)) {
+		s := srcFiles[rel]
+		// GFS keeps whole-second times, so compare local times at that precision
+		if d, ok := dstFiles[rel]; ok && d.size == s.size && !s.modTime.Truncate(time.Second).After(d.modTime) {
+			unchanged++
+			continue
+		}
+		transfers = append(transfers, transfer{src: src.join(rel), dst: dst.join(rel), size: s.size, modTime: s.modTime})
+	}
+	return transfers, unchanged
+}
+
+// transfer copies one file between the local disk and GFS
+type transfer struct {
+	src, dst location
+	size     int64
+	modTime  time.Time
+}
+
+// runTransfers copies files with up to jobs in parallel behind one progress bar.
+// A failed file doesn't stop the others; every failure is reported and the
+// first is returned.
+func (a *App) runTransfers(ctx context.Context, transfers []transfer, jobs int) error {
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	operation := "Uploading"
+	if transfers[0].src.remote {
+		operation = "Downloading"
+	}
+	progress := NewTransferProgress(transferSize(transfers), operation)
+	progress.Start()
+
+	queue := make(chan transfer)
+	var mu sync.Mutex
+	var failures []error
+	var wg sync.WaitGroup
+	for range min(jobs, len(transfers)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				if err := a.copyFile(ctx, t, progress); err != nil {
+					mu.Lock()
+					failures = append(failures, fmt.Errorf("%s: %w", t.src, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, t := range transfers {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- t
+	}
+	close(queue)
+	wg.Wait()
+	progress.Finish()
+
+	for _, err := range failures {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d files failed: %w", len(failures), len(transfers), failures[0])
+	}
+	return ctx.Err()
+}
+
+func (a *App) copyFile(ctx context.Context, t transfer, progress *TransferProgress) error {
+	if t.src.remote {
+		return a.downloadFile(ctx, t, progress)
+	}
+	return a.uploadFile(ctx, t, progress)
+}
+
+// uploadFile writes a local file to a temporary GFS path and renames it over
+// the destination, so readers never see a partial file
+func (a *App) uploadFile(ctx context.Context, t transfer, progress *TransferProgress) error {
+	file, err := os.Open(t.src.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tmp := t.dst.path + ".cp-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	prepared, err := a.client.PrepareUploadWithNamespace(ctx, tmp, t.dst.namespace, t.size)
+	if err != nil {
+		return fmt.Errorf("failed to prepare upload: %w", err)
+	}
+	_, err = prepared.AppendFrom(ctx, &ProgressReader{r: file, progress: progress})
+	if err == nil {
+		err = a.client.ReplaceFileWithNamespace(ctx, tmp, t.dst.path, t.dst.namespace)
+	}
+	if err != nil {
+		// Don't leave the temporary file behind, even if ctx was canceled
+		cleanupCtx, cancel := getContext()
+		a.client.DeleteFileWithNamespace(cleanupCtx, tmp, t.dst.namespace)
+		cancel()
+		return err
+	}
+	return nil
+}
+
+// downloadFile reads a GFS file into a temporary file next to the destination
+// and renames it into place, keeping the GFS modification time
+func (a *App) downloadFile(ctx context.Context, t transfer, progress *TransferProgress) error {
+	if err := os.MkdirAll(filepath.Dir(t.dst.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(t.dst.path), "."+filepath.Base(t.dst.path)+".part-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// An empty file has no chunks to read
+	if t.size > 0 {
+		_, err = a.client.ReadToWithNamespace(ctx, t.src.path, t.src.namespace, &ProgressWriter{w: file, progress: progress})
+	}
+	if err == nil {
+		err = file.Chmod(0o644)
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if !t.modTime.IsZero() {
+		os.Chtimes(file.Name(), t.modTime, t.modTime)
+	}
+	return os.Rename(file.Name(), t.dst.path)
+}
+
+// parseJobs removes -j/--jobs from args and returns its value
+func parseJobs(args []string) (int, []string, error) {
+	jobs := defaultTransferJobs
+	remaining := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		value, ok := "", false
+		switch arg := args[i]; {
+		case arg == "-j" || arg == "--jobs":
+			if i+1 >= len(args) {
+				return 0, nil, fmt.Errorf("missing jobs value")
+			}
+			value, ok = args[i+1], true
+			i++
+		case strings.HasPrefix(arg, "--jobs="):
+			value, ok = strings.TrimPrefix(arg, "--jobs="), true
+		}
+		if !ok {
+			remaining = append(remaining, args[i])
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, nil, fmt.Errorf("invalid jobs value: %s", value)
+		}
+		jobs = n
+	}
+	return jobs, remaining, nil
+}
+
+// cutFlag removes every occurrence of the given flag names from args and
+// reports whether one was present
+func cutFlag(args []string, names ...string) (bool, []string) {
+	found := false
+	remaining := make([]string, 0, len(args))
+	for _, arg := range args {
+		if slices.Contains(names, arg) {
+			found = true
+			continue
+		}
+		remaining = append(remaining, arg)
+	}
+	return found, remaining
+}
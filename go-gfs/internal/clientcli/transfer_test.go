@@ -0,0 +1,122 @@
+package clientcli
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want location
+	}{
+		{"ns:/a/b", location{remote: true, namespace: "ns", path: "/a/b"}},
+		{":/a", location{remote: true, path: "/a"}},
+		{"./dir", location{path: "./dir"}},
+		{"/abs/path", location{path: "/abs/path"}},
+		{"ns:rel", location{path: "ns:rel"}},       // GFS paths start with /
+		{"dir/ns:/x", location{path: "dir/ns:/x"}}, // A local path with a colon
+		{`C:\x`, location{path: `C:\x`}},           // Not a GFS path either
+		{"a:/b:/c", location{remote: true, namespace: "a", path: "/b:/c"}},
+	}
+	for _, tt := range tests {
+		if got := parseLocation(tt.arg); got != tt.want {
+			t.Errorf("parseLocation(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestParseTransferArgs(t *testing.T) {
+	cpFlags := []string{"-r", "-R", "--recursive"}
+	local, remote := location{path: "dir"}, location{remote: true, namespace: "ns", path: "/p"}
+	tests := []struct {
+		name  string
+		args  []string
+		flags []string
+		want  transferArgs
+		found bool
+		ok    bool
+	}{
+		{name: "cp upload", args: []string{"dir", "ns:/p"}, flags: cpFlags, want: transferArgs{local, remote, defaultTransferJobs}, ok: true},
+		{name: "cp -r download", args: []string{"-r", "ns:/p", "dir"}, flags: cpFlags, want: transferArgs{remote, local, defaultTransferJobs}, found: true, ok: true},
+		{name: "cp --recursive after paths", args: []string{"dir", "ns:/p", "--recursive"}, flags: cpFlags, want: transferArgs{local, remote, defaultTransferJobs}, found: true, ok: true},
+		{name: "cp -R with jobs", args: []string{"-R", "-j", "8", "dir", "ns:/p"}, flags: cpFlags, want: transferArgs{local, remote, 8}, found: true, ok: true},
+		{name: "sync --delete", args: []string{"--delete", "--jobs=2", "dir", "ns:/p"}, flags: []string{"--delete"}, want: transferArgs{local, remote, 2}, found: true, ok: true},
+		{name: "sync without --delete", args: []string{"ns:/p", "dir"}, flags: []string{"--delete"}, want: transferArgs{remote, local, defaultTransferJobs}, ok: true},
+		{name: "-r is not a sync flag", args: []string{"-r", "dir", "ns:/p"}, flags: []string{"--delete"}},
+		{name: "missing destination", args: []string{"-r", "dir"}, flags: cpFlags},
+		{name: "extra argument", args: []string{"dir", "ns:/p", "more"}, flags: cpFlags},
+		{name: "missing jobs value", args: []string{"dir", "ns:/p", "-j"}, flags: cpFlags},
+		{name: "zero jobs", args: []string{"-j", "0", "dir", "ns:/p"}, flags: cpFlags},
+		{name: "jobs not a number", args: []string{"--jobs=many", "dir", "ns:/p"}, flags: cpFlags},
+		{name: "both local", args: []string{"dir", "other"}, flags: cpFlags},
+		{name: "both in GFS", args: []string{"ns:/a", ":/b"}, flags: cpFlags},
+	}
+	for _, tt := range tests {
+		got, found, err := parseTransferArgs(tt.args, "usage", tt.flags...)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if got != tt.want || found != tt.found {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	src := location{path: "local"}
+	dst := location{remote: true, namespace: "ns", path: "/backup"}
+	entry := func(rel string, size int64, modTime time.Time) fileEntry {
+		return fileEntry{rel: rel, size: size, modTime: modTime}
+	}
+
+	tests := []struct {
+		name   string
+		src    fileEntry
+		dst    *fileEntry
+		copied bool
+	}{
+		{name: "missing", src: entry("a", 10, now), copied: true},
+		{name: "same size and time", src: entry("a", 10, now), dst: &fileEntry{size: 10, modTime: now}},
+		{name: "copy is newer", src: entry("a", 10, now), dst: &fileEntry{size: 10, modTime: now.Add(time.Hour)}},
+		{name: "size differs", src: entry("a", 10, now), dst: &fileEntry{size: 11, modTime: now.Add(time.Hour)}, copied: true},
+		{name: "source changed since", src: entry("a", 10, now.Add(time.Second)), dst: &fileEntry{size: 10, modTime: now}, copied: true},
+		// GFS drops the fraction of a second the local file has
+		{name: "within the same second", src: entry("a", 10, now.Add(900*time.Millisecond)), dst: &fileEntry{size: 10, modTime: now}},
+		{name: "empty files", src: entry("a", 0, now), dst: &fileEntry{size: 0, modTime: now}},
+	}
+	for _, tt := range tests {
+		srcFiles := map[string]fileEntry{tt.src.rel: tt.src}
+		dstFiles := map[string]fileEntry{}
+		if tt.dst != nil {
+			dstFiles[tt.src.rel] = *tt.dst
+		}
+		transfers, unchanged := planSync(src, dst, srcFiles, dstFiles)
+		if copied := len(transfers) == 1; copied != tt.copied || unchanged != 1-len(transfers) {
+			t.Errorf("%s: %d copies and %d unchanged, want copied %v", tt.name, len(transfers), unchanged, tt.copied)
+		}
+	}
+
+	// Copies keep the source's relative paths, sizes and times
+	srcFiles := map[string]fileEntry{
+		"b/2":  entry("b/2", 2, now),
+		"a":    entry("a", 1, now),
+		"same": entry("same", 3, now),
+	}
+	dstFiles := map[string]fileEntry{
+		"same":  entry("same", 3, now),
+		"extra": entry("extra", 4, now),
+	}
+	transfers, unchanged := planSync(src, dst, srcFiles, dstFiles)
+	want := []transfer{
+		{src: location{path: filepath.Join("local", "a")}, dst: location{remote: true, namespace: "ns", path: "/backup/a"}, size: 1, modTime: now},
+		{src: location{path: filepath.Join("local", "b", "2")}, dst: location{remote: true, namespace: "ns", path: "/backup/b/2"}, size: 2, modTime: now},
+	}
+	if !slices.Equal(transfers, want) || unchanged != 1 {
+		t.Errorf("planSync = %+v, %d unchanged; want %+v, 1", transfers, unchanged, want)
+	}
+}
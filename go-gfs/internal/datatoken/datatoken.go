@@ -0,0 +1,125 @@
+// Package datatoken issues and checks the grants that authorize data-plane
+// requests.
+//
+// The master signs a short-lived grant for one chunk and one operation with
+// its Ed25519 private key whenever it hands a chunk's locations to a client.
+// Chunkservers hold only the public key, so a client that can read one chunk
+// cannot mint a grant for any other, and a compromised chunkserver cannot
+// mint grants either.
+package datatoken
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Operations a grant can allow
+const (
+	OpRead  = "read"
+	OpWrite = "write"
+)
+
+// DefaultTTL is how long a grant stays valid
+const DefaultTTL = 10 * time.Minute
+
+// Claims is the body of a grant
+type Claims struct {
+	ChunkHandle string `json:"chunk_handle"`
+	Operation   string `json:"operation"`
+	jwt.RegisteredClaims
+}
+
+// Issuer signs grants with the master's private key
+type Issuer struct {
+	key ed25519.PrivateKey
+	ttl time.Duration
+}
+
+// LoadIssuer reads a PEM encoded PKCS #8 Ed25519 private key
+func LoadIssuer(path string, ttl time.Duration) (*Issuer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Issuer{key: key.(ed25519.PrivateKey), ttl: ttl}, nil
+}
+
+// Issue returns a grant allowing op on one chunk, and when it expires
+func (i *Issuer) Issue(handle, op string) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(i.ttl)
+	claims := Claims{
+		ChunkHandle: handle,
+		Operation:   op,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(i.key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// Verifier checks grants with the master's public key
+type Verifier struct {
+	key ed25519.PublicKey
+}
+
+// LoadVerifier reads a PEM encoded PKIX Ed25519 public key
+func LoadVerifier(path string) (*Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseEdPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse verification key %s: %w", path, err)
+	}
+	return &Verifier{key: key.(ed25519.PublicKey)}, nil
+}
+
+// Verify checks that grant is a valid, unexpired grant for op on handle
+func (v *Verifier) Verify(grant, handle, op string) error {
+	if grant == "" {
+		return errors.New("missing data-plane grant")
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(grant, claims, func(*jwt.Token) (any, error) {
+		return v.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return fmt.Errorf("invalid data-plane grant: %w", err)
+	}
+	if claims.ChunkHandle != handle || claims.Operation != op {
+		return fmt.Errorf("grant for %s on %s does not cover %s on %s", claims.Operation, claims.ChunkHandle, op, handle)
+	}
+	return nil
+}
+
+// Expiry reads a grant's expiry time without verifying it, so clients can
+// renew grants before they lapse
+func Expiry(grant string) (time.Time, error) {
+	claims := &Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(grant, claims); err != nil {
+		return time.Time{}, err
+	}
+	if claims.ExpiresAt == nil {
+		return time.Time{}, errors.New("grant has no expiry")
+	}
+	return claims.ExpiresAt.Time, nil
+}
@@ -0,0 +1,76 @@
+// Package erasure holds the Reed-Solomon stripe layout shared by the master,
+// chunkservers and the SDK.
+//
+// A chunk is split into DataShards contiguous shards of ShardSize bytes, the
+// last ones zero padded, and ParityShards parity shards are computed over them.
+// Each shard is stored as a fragment: an ordinary chunk file named
+// "<chunk handle>.ec<index>".
+package erasure
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// Default stripe shape for the cold storage class: survives the loss of any
+// two fragments at 1.5x storage instead of 3x
+const (
+	DataShards   = 4
+	ParityShards = 2
+)
+
+const fragmentSep = ".ec"
+
+// FragmentHandle returns the handle a chunk's fragment is stored under
+func FragmentHandle(chunkHandle string, index int) string {
+	return chunkHandle + fragmentSep + strconv.Itoa(index)
+}
+
+// ParseFragmentHandle splits a fragment handle into its chunk handle and index
+func ParseFragmentHandle(handle string) (string, int, bool) {
+	i := strings.LastIndex(handle, fragmentSep)
+	if i <= 0 {
+		return "", 0, false
+	}
+	index, err := strconv.Atoi(handle[i+len(fragmentSep):])
+	if err != nil || index < 0 {
+		return "", 0, false
+	}
+	return handle[:i], index, true
+}
+
+// ShardSize returns the fragment size for a chunk of size bytes
+func ShardSize(size uint64, dataShards int) uint64 {
+	if dataShards <= 0 {
+		return 0
+	}
+	return (size + uint64(dataShards) - 1) / uint64(dataShards)
+}
+
+// NewCoder returns a Reed-Solomon coder for a stripe shape
+func NewCoder(dataShards, parityShards int) (reedsolomon.Encoder, error) {
+	if dataShards <= 0 || parityShards <= 0 {
+		return nil, fmt.Errorf("invalid stripe shape %d+%d", dataShards, parityShards)
+	}
+	return reedsolomon.New(dataShards, parityShards)
+}
+
+// Split divides chunk data into data shards of shardSize bytes followed by empty
+// parity shards, ready for Encode
+func Split(data []byte, dataShards, parityShards int, shardSize uint64) [][]byte {
+	shards := make([][]byte, dataShards+parityShards)
+	for i := range shards {
+		shard := make([]byte, shardSize)
+		if i < dataShards {
+			start := uint64(i) * shardSize
+			if start < uint64(len(data)) {
+				copy(shard, data[start:])
+			}
+		}
+		shards[i] = shard
+	}
+	return shards
+}
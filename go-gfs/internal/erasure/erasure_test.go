@@ -0,0 +1,159 @@
+package erasure
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// encodeTestStripe splits and encodes size bytes of random data into a stripe
+func encodeTestStripe(t *testing.T, size int) ([]byte, [][]byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	enc, err := NewCoder(DataShards, ParityShards)
+	if err != nil {
+		t.Fatalf("NewCoder: %v", err)
+	}
+	shards := Split(data, DataShards, ParityShards, ShardSize(uint64(size), DataShards))
+	if err := enc.Encode(shards); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return data, shards
+}
+
+func TestShardSize(t *testing.T) {
+	tests := []struct {
+		size       uint64
+		dataShards int
+		want       uint64
+	}{
+		{size: 0, dataShards: 4, want: 0},
+		{size: 1, dataShards: 4, want: 1},
+		{size: 4, dataShards: 4, want: 1},
+		{size: 5, dataShards: 4, want: 2},
+		{size: 64 << 20, dataShards: 4, want: 16 << 20},
+		{size: 10, dataShards: 0, want: 0},
+	}
+	for _, tt := range tests {
+		if got := ShardSize(tt.size, tt.dataShards); got != tt.want {
+			t.Errorf("ShardSize(%d, %d) = %d, want %d", tt.size, tt.dataShards, got, tt.want)
+		}
+	}
+}
+
+func TestFragmentHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		chunk  string
+		index  int
+		ok     bool
+	}{
+		{handle: FragmentHandle("abc", 0), chunk: "abc", index: 0, ok: true},
+		{handle: FragmentHandle("abc", 5), chunk: "abc", index: 5, ok: true},
+		{handle: FragmentHandle("a.ec1", 2), chunk: "a.ec1", index: 2, ok: true},
+		{handle: "abc", ok: false},
+		{handle: ".ec1", ok: false},
+		{handle: "abc.ec", ok: false},
+		{handle: "abc.ec-1", ok: false},
+		{handle: "abc.ecx", ok: false},
+	}
+	for _, tt := range tests {
+		chunk, index, ok := ParseFragmentHandle(tt.handle)
+		if ok != tt.ok || chunk != tt.chunk || index != tt.index {
+			t.Errorf("ParseFragmentHandle(%q) = %q, %d, %v, want %q, %d, %v",
+				tt.handle, chunk, index, ok, tt.chunk, tt.index, tt.ok)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	for _, size := range []int{1, 3, 4, 1000, 1001} {
+		data, shards := encodeTestStripe(t, size)
+		shardSize := int(ShardSize(uint64(size), DataShards))
+		if len(shards) != DataShards+ParityShards {
+			t.Fatalf("size %d: %d shards, want %d", size, len(shards), DataShards+ParityShards)
+		}
+		var joined []byte
+		for i, shard := range shards {
+			if len(shard) != shardSize {
+				t.Fatalf("size %d: shard %d is %d bytes, want %d", size, i, len(shard), shardSize)
+			}
+			if i < DataShards {
+				joined = append(joined, shard...)
+			}
+		}
+		// Data shards hold the chunk in order, zero padded at the end
+		if !bytes.Equal(joined[:size], data) {
+			t.Errorf("size %d: data shards don't hold the chunk", size)
+		}
+		if !bytes.Equal(joined[size:], make([]byte, len(joined)-size)) {
+			t.Errorf("size %d: padding is not zero", size)
+		}
+	}
+}
+
+// TestReconstructRange drops up to ParityShards fragments and rebuilds ranges of
+// them from the same range of the others, as the SDK does for degraded reads
+func TestReconstructRange(t *testing.T) {
+	const size = 4099 // Pads the last data shard
+	data, shards := encodeTestStripe(t, size)
+	shardSize := len(shards[0])
+	enc, err := NewCoder(DataShards, ParityShards)
+	if err != nil {
+		t.Fatalf("NewCoder: %v", err)
+	}
+
+	tests := []struct {
+		dropped        []int
+		offset, length int
+	}{
+		{dropped: nil, offset: 0, length: shardSize},
+		{dropped: []int{0}, offset: 0, length: shardSize},
+		{dropped: []int{3}, offset: 100, length: 1},
+		{dropped: []int{1, 2}, offset: 17, length: 500},
+		{dropped: []int{0, 5}, offset: shardSize - 10, length: 10},
+		{dropped: []int{4, 5}, offset: 0, length: shardSize},
+		{dropped: []int{2, 4}, offset: 1, length: shardSize - 2},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("drop %v at %d+%d", tt.dropped, tt.offset, tt.length), func(t *testing.T) {
+			ranged := make([][]byte, len(shards))
+			for i, shard := range shards {
+				ranged[i] = bytes.Clone(shard[tt.offset : tt.offset+tt.length])
+			}
+			for _, i := range tt.dropped {
+				ranged[i] = nil
+			}
+			if err := enc.Reconstruct(ranged); err != nil {
+				t.Fatalf("Reconstruct: %v", err)
+			}
+			for i := range shards {
+				if !bytes.Equal(ranged[i], shards[i][tt.offset:tt.offset+tt.length]) {
+					t.Errorf("fragment %d range rebuilt wrong", i)
+				}
+			}
+			// The rebuilt data shards hold the chunk's bytes at the range
+			for i := range DataShards {
+				start := i*shardSize + tt.offset
+				end := min(start+tt.length, size)
+				if start >= size {
+					continue
+				}
+				if !bytes.Equal(ranged[i][:end-start], data[start:end]) {
+					t.Errorf("data shard %d doesn't match chunk bytes %d-%d", i, start, end)
+				}
+			}
+		})
+	}
+
+	// Three lost fragments are more than the parity covers
+	ranged := make([][]byte, len(shards))
+	copy(ranged, shards)
+	ranged[0], ranged[1], ranged[2] = nil, nil, nil
+	if err := enc.Reconstruct(ranged); err == nil {
+		t.Error("Reconstruct with 3 of 6 fragments lost succeeded")
+	}
+}
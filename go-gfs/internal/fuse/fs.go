@@ -0,0 +1,98 @@
+// Package fuse serves a path-based file system through the Linux FUSE kernel
+// protocol, by reading requests from /dev/fuse directly. It mounts with the
+// mount system call when it has CAP_SYS_ADMIN, and through the setuid
+// fusermount3 helper otherwise.
+//
+// The server assigns node IDs and tracks the path of each, so a FileSystem only
+// deals in absolute, "/"-separated paths: "/" is the root.
+package fuse
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"syscall"
+	"time"
+)
+
+// FileSystem is the file system served by a mount. Methods are called
+// concurrently. An error wrapping a syscall.Errno is returned to the kernel as
+// is; fs.ErrNotExist, fs.ErrExist and fs.ErrPermission map to ENOENT, EEXIST
+// and EACCES, a canceled context to EINTR, and anything else to EIO.
+type FileSystem interface {
+	Stat(ctx context.Context, path string) (Attr, error)
+	ReadDir(ctx context.Context, path string) ([]DirEntry, error)
+	Mkdir(ctx context.Context, path string) error
+	Rmdir(ctx context.Context, path string) error
+	Remove(ctx context.Context, path string) error
+	// Rename moves a file or directory, replacing newPath unless noReplace is set
+	Rename(ctx context.Context, oldPath, newPath string, noReplace bool) error
+	Truncate(ctx context.Context, path string, size uint64) error
+	// Open opens an existing file; flags are the open(2) flags
+	Open(ctx context.Context, path string, flags int) (Handle, error)
+	// Create creates a file that doesn't exist and opens it
+	Create(ctx context.Context, path string, flags int) (Handle, error)
+	StatFS(ctx context.Context) (StatFS, error)
+}
+
+// Handle is an open file. Writes are not required to be visible to other
+// handles until Flush.
+type Handle interface {
+	ReadAt(ctx context.Context, p []byte, off int64) (int, error)
+	WriteAt(ctx context.Context, p []byte, off int64) (int, error)
+	// Flush is called on every close of a file descriptor and on fsync
+	Flush(ctx context.Context) error
+	// Release is called once the kernel drops the handle
+	Release(ctx context.Context) error
+}
+
+// Attr describes a file or directory.
+type Attr struct {
+	Dir     bool
+	Size    uint64
+	ModTime time.Time
+}
+
+// DirEntry is one name in a directory.
+type DirEntry struct {
+	Name string
+	Dir  bool
+}
+
+// StatFS reports the capacity of the file system, in bytes.
+type StatFS struct {
+	Total uint64
+	Free  uint64
+}
+
+// Options configure a mount.
+type Options struct {
+	// Name is shown as the mount's source and file system subtype
+	Name string
+	// AllowOther lets users other than the mounting one access the mount
+	AllowOther bool
+	// UID and GID own every file; they default to the mounting process's
+	UID, GID uint32
+}
+
+// errno maps a FileSystem error to the errno returned to the kernel.
+func errno(err error) syscall.Errno {
+	var e syscall.Errno
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, fs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, fs.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, fs.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, context.Canceled):
+		return syscall.EINTR
+	}
+	slog.Warn("fuse request failed", "error", err)
+	return syscall.EIO
+}
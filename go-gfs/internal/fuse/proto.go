@@ -0,0 +1,280 @@
+package fuse
+
+// Kernel FUSE protocol definitions, from include/uapi/linux/fuse.h. Only the
+// operations the server handles are listed.
+
+const (
+	kernelVersion      = 7
+	kernelMinorVersion = 31
+
+	// rootID is the node ID of the mount's root directory
+	rootID = 1
+)
+
+const (
+	opLookup      = 1
+	opForget      = 2
+	opGetattr     = 3
+	opSetattr     = 4
+	opMkdir       = 9
+	opUnlink      = 10
+	opRmdir       = 11
+	opRename      = 12
+	opOpen        = 14
+	opRead        = 15
+	opWrite       = 16
+	opStatfs      = 17
+	opRelease     = 18
+	opFsync       = 20
+	opFlush       = 25
+	opInit        = 26
+	opOpendir     = 27
+	opReaddir     = 28
+	opReleasedir  = 29
+	opFsyncdir    = 30
+	opCreate      = 35
+	opInterrupt   = 36
+	opDestroy     = 38
+	opBatchForget = 42
+	opRename2     = 45
+)
+
+// Init flags
+const (
+	initAsyncRead     = 1 << 0
+	initAtomicOTrunc  = 1 << 3
+	initBigWrites     = 1 << 5
+	initMaxPages      = 1 << 22
+	initParallelDirOp = 1 << 18
+)
+
+// Setattr valid bits; mode, ownership and time changes are ignored
+const (
+	setattrSize = 1 << 3
+	setattrFh   = 1 << 6
+)
+
+// Rename2 flags
+const (
+	renameNoReplace = 1 << 0
+	renameExchange  = 1 << 1
+)
+
+const (
+	inHeaderSize  = 40
+	outHeaderSize = 16
+
+	// maxWrite is the largest WRITE the kernel is told to send; requests are
+	// read into buffers this size plus room for the header
+	maxWrite      = 1 << 20
+	maxPages      = maxWrite / 4096
+	readBufSize   = maxWrite + 4096
+	maxReadahead  = 1 << 20
+	maxBackground = 64
+)
+
+type inHeader struct {
+	Len         uint32
+	Opcode      uint32
+	Unique      uint64
+	NodeID      uint64
+	UID         uint32
+	GID         uint32
+	PID         uint32
+	TotalExtlen uint16
+	Padding     uint16
+}
+
+type outHeader struct {
+	Len    uint32
+	Error  int32
+	Unique uint64
+}
+
+type initIn struct {
+	Major        uint32
+	Minor        uint32
+	MaxReadahead uint32
+	Flags        uint32
+}
+
+type initOut struct {
+	Major               uint32
+	Minor               uint32
+	MaxReadahead        uint32
+	Flags               uint32
+	MaxBackground       uint16
+	CongestionThreshold uint16
+	MaxWrite            uint32
+	TimeGran            uint32
+	MaxPages            uint16
+	MapAlignment        uint16
+	Flags2              uint32
+	Unused              [7]uint32
+}
+
+type attr struct {
+	Ino       uint64
+	Size      uint64
+	Blocks    uint64
+	Atime     uint64
+	Mtime     uint64
+	Ctime     uint64
+	Atimensec uint32
+	Mtimensec uint32
+	Ctimensec uint32
+	Mode      uint32
+	Nlink     uint32
+	UID       uint32
+	GID       uint32
+	Rdev      uint32
+	Blksize   uint32
+	Flags     uint32
+}
+
+type entryOut struct {
+	NodeID         uint64
+	Generation     uint64
+	EntryValid     uint64
+	AttrValid      uint64
+	EntryValidNsec uint32
+	AttrValidNsec  uint32
+	Attr           attr
+}
+
+type attrOut struct {
+	AttrValid     uint64
+	AttrValidNsec uint32
+	Dummy         uint32
+	Attr          attr
+}
+
+type forgetIn struct {
+	Nlookup uint64
+}
+
+type batchForgetIn struct {
+	Count uint32
+	Dummy uint32
+}
+
+type forgetOne struct {
+	NodeID  uint64
+	Nlookup uint64
+}
+
+type interruptIn struct {
+	Unique uint64
+}
+
+type setattrIn struct {
+	Valid     uint32
+	Padding   uint32
+	Fh        uint64
+	Size      uint64
+	LockOwner uint64
+	Atime     uint64
+	Mtime     uint64
+	Ctime     uint64
+	Atimensec uint32
+	Mtimensec uint32
+	Ctimensec uint32
+	Mode      uint32
+	Unused4   uint32
+	UID       uint32
+	GID       uint32
+	Unused5   uint32
+}
+
+type mkdirIn struct {
+	Mode  uint32
+	Umask uint32
+}
+
+type renameIn struct {
+	Newdir uint64
+}
+
+type rename2In struct {
+	Newdir  uint64
+	Flags   uint32
+	Padding uint32
+}
+
+type openIn struct {
+	Flags     uint32
+	OpenFlags uint32
+}
+
+type createIn struct {
+	Flags     uint32
+	Mode      uint32
+	Umask     uint32
+	OpenFlags uint32
+}
+
+type openOut struct {
+	Fh        uint64
+	OpenFlags uint32
+	Padding   uint32
+}
+
+type readIn struct {
+	Fh        uint64
+	Offset    uint64
+	Size      uint32
+	ReadFlags uint32
+	LockOwner uint64
+	Flags     uint32
+	Padding   uint32
+}
+
+type writeIn struct {
+	Fh         uint64
+	Offset     uint64
+	Size       uint32
+	WriteFlags uint32
+	LockOwner  uint64
+	Flags      uint32
+	Padding    uint32
+}
+
+type writeOut struct {
+	Size    uint32
+	Padding uint32
+}
+
+type releaseIn struct {
+	Fh           uint64
+	Flags        uint32
+	ReleaseFlags uint32
+	LockOwner    uint64
+}
+
+type flushIn struct {
+	Fh        uint64
+	Unused    uint32
+	Padding   uint32
+	LockOwner uint64
+}
+
+type kstatfs struct {
+	Blocks  uint64
+	Bfree   uint64
+	Bavail  uint64
+	Files   uint64
+	Ffree   uint64
+	Bsize   uint32
+	Namelen uint32
+	Frsize  uint32
+	Padding uint32
+	Spare   [6]uint32
+}
+
+// direntHeader precedes each name in a READDIR reply
+type direntHeader struct {
+	Ino     uint64
+	Off     uint64
+	Namelen uint32
+	Type    uint32
+}
@@ -0,0 +1,47 @@
+package fuse
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// The structs are copied to and from the kernel byte for byte, so their sizes
+// have to match include/uapi/linux/fuse.h
+func TestProtocolSizes(t *testing.T) {
+	tests := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{"fuse_in_header", unsafe.Sizeof(inHeader{}), inHeaderSize},
+		{"fuse_out_header", unsafe.Sizeof(outHeader{}), outHeaderSize},
+		{"fuse_init_in", unsafe.Sizeof(initIn{}), 16},
+		{"fuse_init_out", unsafe.Sizeof(initOut{}), 64},
+		{"fuse_attr", unsafe.Sizeof(attr{}), 88},
+		{"fuse_entry_out", unsafe.Sizeof(entryOut{}), 128},
+		{"fuse_attr_out", unsafe.Sizeof(attrOut{}), 104},
+		{"fuse_forget_in", unsafe.Sizeof(forgetIn{}), 8},
+		{"fuse_batch_forget_in", unsafe.Sizeof(batchForgetIn{}), 8},
+		{"fuse_forget_one", unsafe.Sizeof(forgetOne{}), 16},
+		{"fuse_interrupt_in", unsafe.Sizeof(interruptIn{}), 8},
+		{"fuse_setattr_in", unsafe.Sizeof(setattrIn{}), 88},
+		{"fuse_mkdir_in", unsafe.Sizeof(mkdirIn{}), 8},
+		{"fuse_rename_in", unsafe.Sizeof(renameIn{}), 8},
+		{"fuse_rename2_in", unsafe.Sizeof(rename2In{}), 16},
+		{"fuse_open_in", unsafe.Sizeof(openIn{}), 8},
+		{"fuse_create_in", unsafe.Sizeof(createIn{}), 16},
+		{"fuse_open_out", unsafe.Sizeof(openOut{}), 16},
+		{"fuse_read_in", unsafe.Sizeof(readIn{}), 40},
+		{"fuse_write_in", unsafe.Sizeof(writeIn{}), 40},
+		{"fuse_write_out", unsafe.Sizeof(writeOut{}), 8},
+		{"fuse_release_in", unsafe.Sizeof(releaseIn{}), 24},
+		{"fuse_flush_in", unsafe.Sizeof(flushIn{}), 24},
+		{"fuse_kstatfs", unsafe.Sizeof(kstatfs{}), 80},
+		{"fuse_dirent", unsafe.Sizeof(direntHeader{}), 24},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s is %d bytes, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,822 @@
+//go:build linux
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// entryTimeout is how long the kernel caches names and attributes
+const entryTimeout = time.Second
+
+// Server is a mounted FileSystem.
+type Server struct {
+	fsys FileSystem
+	dir  string
+	fd   int
+	// fusermount is the helper that mounted dir, empty for a direct mount
+	fusermount string
+	uid        uint32
+	gid        uint32
+	// mounted stands in for the time of entries without one, like derived directories
+	mounted time.Time
+
+	bufs sync.Pool
+
+	mu       sync.Mutex
+	nodes    map[uint64]*node
+	paths    map[string]uint64
+	nextNode uint64
+	handles  map[uint64]*openHandle
+	nextFh   uint64
+	inflight map[uint64]context.CancelFunc // Requests by unique ID, for INTERRUPT
+
+	wg sync.WaitGroup
+}
+
+// node is a path the kernel holds a reference to
+type node struct {
+	path    string // Empty once unlinked or replaced
+	lookups uint64
+}
+
+// openHandle is an open file or directory
+type openHandle struct {
+	file    Handle
+	entries []DirEntry // Directory listing, snapshotted on open
+}
+
+// Mount mounts fsys on dir. Call Serve to answer requests. Without
+// CAP_SYS_ADMIN it falls back to fusermount3 (or fusermount), which mounts as
+// the calling user.
+func Mount(dir string, fsys FileSystem, opts Options) (*Server, error) {
+	dir, err := absDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Name == "" {
+		opts.Name = "gfs"
+	}
+	if opts.UID == 0 && opts.GID == 0 {
+		opts.UID, opts.GID = uint32(os.Getuid()), uint32(os.Getgid())
+	}
+
+	fd, err := mountDirect(dir, opts)
+	var fusermount string
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		// Unprivileged: let the setuid fusermount helper mount for us
+		fusermount, err = findFusermount()
+		if err == nil {
+			fd, err = mountFusermount(fusermount, dir, opts)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := newServer(fsys, fd, opts)
+	s.dir, s.fusermount = dir, fusermount
+	return s, nil
+}
+
+// newServer returns a server for the requests read from fd
+func newServer(fsys FileSystem, fd int, opts Options) *Server {
+	s := &Server{
+		fsys:     fsys,
+		fd:       fd,
+		uid:      opts.UID,
+		gid:      opts.GID,
+		mounted:  time.Now(),
+		nodes:    map[uint64]*node{rootID: {path: "/", lookups: 1}},
+		paths:    map[string]uint64{"/": rootID},
+		nextNode: rootID + 1,
+		handles:  make(map[uint64]*openHandle),
+		nextFh:   1,
+		inflight: make(map[uint64]context.CancelFunc),
+	}
+	s.bufs.New = func() any { return make([]byte, readBufSize) }
+	return s
+}
+
+// mountDirect opens /dev/fuse and mounts it on dir with the mount system call,
+// which needs CAP_SYS_ADMIN
+func mountDirect(dir string, opts Options) (int, error) {
+	fd, err := unix.Open("/dev/fuse", unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("open /dev/fuse: %w", err)
+	}
+	data := fmt.Sprintf("fd=%d,rootmode=40000,user_id=%d,group_id=%d,default_permissions", fd, opts.UID, opts.GID)
+	if opts.AllowOther {
+		data += ",allow_other"
+	}
+	if err := unix.Mount(opts.Name, dir, "fuse."+opts.Name, unix.MS_NOSUID|unix.MS_NODEV, data); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("mount %s: %w", dir, err)
+	}
+	return fd, nil
+}
+
+// findFusermount looks up the fusermount helper, preferring the FUSE 3 one
+func findFusermount() (string, error) {
+	for _, name := range []string{"fusermount3", "fusermount"} {
+		if bin, err := exec.LookPath(name); err == nil {
+			return bin, nil
+		}
+	}
+	return "", errors.New("mounting needs CAP_SYS_ADMIN or fusermount3 on PATH")
+}
+
+// mountFusermount has the fusermount helper mount dir and returns the /dev/fuse
+// descriptor it passes back over a socket. The helper mounts as the calling
+// user, so opts.UID and opts.GID are ignored, and it adds nosuid and nodev itself.
+func mountFusermount(bin, dir string, opts Options) (int, error) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("socketpair: %w", err)
+	}
+	local := os.NewFile(uintptr(fds[0]), "fusermount-local")
+	remote := os.NewFile(uintptr(fds[1]), "fusermount-remote")
+	defer local.Close()
+
+	mountOpts := "default_permissions,fsname=" + opts.Name + ",subtype=" + opts.Name
+	if opts.AllowOther {
+		mountOpts += ",allow_other"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin, "-o", mountOpts, "--", dir)
+	cmd.Env = append(os.Environ(), "_FUSE_COMMFD=3")
+	cmd.ExtraFiles = []*os.File{remote} // fd 3 in the helper
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	remote.Close()
+	if err != nil {
+		return -1, fmt.Errorf("%s %s: %v: %s", filepath.Base(bin), dir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	buf := make([]byte, 4)
+	oob := make([]byte, unix.CmsgSpace(4))
+	_, oobn, _, _, err := unix.Recvmsg(int(local.Fd()), buf, oob, 0)
+	if err != nil {
+		return -1, fmt.Errorf("receive /dev/fuse from %s: %w", filepath.Base(bin), err)
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		return -1, fmt.Errorf("%s sent no /dev/fuse descriptor", filepath.Base(bin))
+	}
+	rights, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil || len(rights) != 1 {
+		return -1, fmt.Errorf("%s sent no /dev/fuse descriptor", filepath.Base(bin))
+	}
+	unix.CloseOnExec(rights[0])
+	return rights[0], nil
+}
+
+func absDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+// Dir returns the mount point.
+func (s *Server) Dir() string { return s.dir }
+
+// Unmount detaches the mount, lazily if it is busy. Serve returns once the
+// kernel has let go of it.
+func (s *Server) Unmount() error {
+	if s.fusermount != "" {
+		return s.unmountFusermount()
+	}
+	err := unix.Unmount(s.dir, 0)
+	if errors.Is(err, unix.EBUSY) {
+		err = unix.Unmount(s.dir, unix.MNT_DETACH)
+	}
+	if err != nil {
+		return fmt.Errorf("unmount %s: %w", s.dir, err)
+	}
+	return nil
+}
+
+// unmountFusermount has the helper that mounted dir unmount it, lazily if plain
+// unmounting fails
+func (s *Server) unmountFusermount() error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.fusermount, "-u", "--", s.dir)
+	cmd.Stderr = &stderr
+	if cmd.Run() == nil {
+		return nil
+	}
+	stderr.Reset()
+	cmd = exec.Command(s.fusermount, "-u", "-z", "--", s.dir)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unmount %s: %v: %s", s.dir, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Serve answers requests until the file system is unmounted, then releases any
+// handles the kernel didn't.
+func (s *Server) Serve() error {
+	defer unix.Close(s.fd)
+	defer s.releaseAll()
+
+	for {
+		buf := s.bufs.Get().([]byte)
+		n, err := unix.Read(s.fd, buf)
+		if err != nil {
+			s.bufs.Put(buf)
+			switch {
+			case errors.Is(err, unix.EINTR), errors.Is(err, unix.ENOENT), errors.Is(err, unix.EAGAIN):
+				// Interrupted, or the request was aborted before we read it
+				continue
+			case errors.Is(err, unix.ENODEV):
+				s.wg.Wait()
+				return nil
+			}
+			s.wg.Wait()
+			return fmt.Errorf("read /dev/fuse: %w", err)
+		}
+		if n < inHeaderSize {
+			s.bufs.Put(buf)
+			continue
+		}
+
+		hdr := decode[inHeader](buf)
+		body := buf[inHeaderSize:n]
+		switch hdr.Opcode {
+		case opInit:
+			s.handleInit(hdr, body)
+		case opForget, opBatchForget, opInterrupt:
+			// Answered in order, without a reply
+			s.handleNoReply(hdr, body)
+		case opDestroy:
+			s.reply(hdr, 0, nil)
+		default:
+			ctx, cancel := context.WithCancel(context.Background())
+			s.mu.Lock()
+			s.inflight[hdr.Unique] = cancel
+			s.mu.Unlock()
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer s.bufs.Put(buf)
+				errno, out := s.dispatch(ctx, hdr, body)
+				s.mu.Lock()
+				delete(s.inflight, hdr.Unique)
+				s.mu.Unlock()
+				cancel()
+				s.reply(hdr, errno, out)
+			}()
+			continue
+		}
+		s.bufs.Put(buf)
+	}
+}
+
+func (s *Server) releaseAll() {
+	s.mu.Lock()
+	handles := s.handles
+	s.handles = make(map[uint64]*openHandle)
+	s.mu.Unlock()
+	for _, h := range handles {
+		if h.file == nil {
+			continue
+		}
+		ctx := context.Background()
+		if err := h.file.Flush(ctx); err != nil {
+			slog.Error("flush on unmount failed", "error", err)
+		}
+		h.file.Release(ctx)
+	}
+}
+
+func (s *Server) handleInit(hdr inHeader, body []byte) {
+	in := decode[initIn](body)
+	if in.Major != kernelVersion {
+		slog.Error("unsupported FUSE protocol", "major", in.Major, "minor", in.Minor)
+		s.reply(hdr, unix.EPROTO, nil)
+		return
+	}
+	out := initOut{
+		Major:               kernelVersion,
+		Minor:               min(in.Minor, kernelMinorVersion),
+		MaxReadahead:        min(in.MaxReadahead, maxReadahead),
+		Flags:               in.Flags & (initAsyncRead | initAtomicOTrunc | initBigWrites | initParallelDirOp),
+		MaxBackground:       maxBackground,
+		CongestionThreshold: maxBackground * 3 / 4,
+		MaxWrite:            128 << 10,
+		TimeGran:            1,
+	}
+	if in.Flags&initMaxPages != 0 {
+		out.Flags |= initMaxPages
+		out.MaxPages = maxPages
+		out.MaxWrite = maxWrite
+	}
+	s.reply(hdr, 0, bytesOf(&out))
+}
+
+func (s *Server) handleNoReply(hdr inHeader, body []byte) {
+	switch hdr.Opcode {
+	case opForget:
+		s.forget(hdr.NodeID, decode[forgetIn](body).Nlookup)
+	case opBatchForget:
+		in := decode[batchForgetIn](body)
+		body = body[unsafe.Sizeof(in):]
+		for i := uint32(0); i < in.Count && len(body) >= int(unsafe.Sizeof(forgetOne{})); i++ {
+			one := decode[forgetOne](body)
+			s.forget(one.NodeID, one.Nlookup)
+			body = body[unsafe.Sizeof(one):]
+		}
+	case opInterrupt:
+		unique := decode[interruptIn](body).Unique
+		s.mu.Lock()
+		cancel := s.inflight[unique]
+		s.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	}
+}
+
+// dispatch answers one request, returning an errno or the reply body
+func (s *Server) dispatch(ctx context.Context, hdr inHeader, body []byte) (syscall.Errno, []byte) {
+	switch hdr.Opcode {
+	case opLookup:
+		p, ok := s.child(hdr.NodeID, cstring(body))
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		return s.entry(ctx, p)
+
+	case opGetattr:
+		p, ok := s.path(hdr.NodeID)
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		a, err := s.fsys.Stat(ctx, p)
+		if err != nil {
+			return errno(err), nil
+		}
+		out := attrOut{AttrValid: uint64(entryTimeout / time.Second), Attr: s.attr(hdr.NodeID, a)}
+		return 0, bytesOf(&out)
+
+	case opSetattr:
+		in := decode[setattrIn](body)
+		p, ok := s.path(hdr.NodeID)
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		if in.Valid&setattrSize != 0 {
+			if err := s.truncate(ctx, p, in); err != nil {
+				return errno(err), nil
+			}
+		}
+		// Mode, ownership and times are fixed; accept and ignore changes so
+		// that touch and cp -p work
+		a, err := s.fsys.Stat(ctx, p)
+		if err != nil {
+			return errno(err), nil
+		}
+		out := attrOut{AttrValid: uint64(entryTimeout / time.Second), Attr: s.attr(hdr.NodeID, a)}
+		return 0, bytesOf(&out)
+
+	case opMkdir:
+		p, ok := s.child(hdr.NodeID, cstring(body[unsafe.Sizeof(mkdirIn{}):]))
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		if err := s.fsys.Mkdir(ctx, p); err != nil {
+			return errno(err), nil
+		}
+		return s.entry(ctx, p)
+
+	case opUnlink, opRmdir:
+		p, ok := s.child(hdr.NodeID, cstring(body))
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		var err error
+		if hdr.Opcode == opUnlink {
+			err = s.fsys.Remove(ctx, p)
+		} else {
+			err = s.fsys.Rmdir(ctx, p)
+		}
+		if err != nil {
+			return errno(err), nil
+		}
+		s.unlinked(p)
+		return 0, nil
+
+	case opRename, opRename2:
+		var newdir uint64
+		var flags uint32
+		if hdr.Opcode == opRename {
+			in := decode[renameIn](body)
+			newdir, body = in.Newdir, body[unsafe.Sizeof(in):]
+		} else {
+			in := decode[rename2In](body)
+			newdir, flags, body = in.Newdir, in.Flags, body[unsafe.Sizeof(in):]
+		}
+		if flags&renameExchange != 0 || flags&^renameNoReplace != 0 {
+			return unix.EINVAL, nil
+		}
+		oldName, rest, _ := strings.Cut(string(body), "\x00")
+		oldPath, ok1 := s.child(hdr.NodeID, oldName)
+		newPath, ok2 := s.child(newdir, cstring([]byte(rest)))
+		if !ok1 || !ok2 {
+			return unix.ENOENT, nil
+		}
+		if err := s.fsys.Rename(ctx, oldPath, newPath, flags&renameNoReplace != 0); err != nil {
+			return errno(err), nil
+		}
+		s.renamed(oldPath, newPath)
+		return 0, nil
+
+	case opOpen:
+		in := decode[openIn](body)
+		p, ok := s.path(hdr.NodeID)
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		h, err := s.fsys.Open(ctx, p, int(in.Flags))
+		if err != nil {
+			return errno(err), nil
+		}
+		out := openOut{Fh: s.addHandle(&openHandle{file: h})}
+		return 0, bytesOf(&out)
+
+	case opCreate:
+		in := decode[createIn](body)
+		p, ok := s.child(hdr.NodeID, cstring(body[unsafe.Sizeof(in):]))
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		h, err := s.fsys.Create(ctx, p, int(in.Flags))
+		if err != nil {
+			return errno(err), nil
+		}
+		fh := s.addHandle(&openHandle{file: h})
+		e, entry := s.entry(ctx, p)
+		if e != 0 {
+			s.removeHandle(fh)
+			h.Release(ctx)
+			return e, nil
+		}
+		open := openOut{Fh: fh}
+		return 0, append(entry, bytesOf(&open)...)
+
+	case opRead:
+		in := decode[readIn](body)
+		h := s.getHandle(in.Fh)
+		if h == nil || h.file == nil {
+			return unix.EBADF, nil
+		}
+		out := make([]byte, in.Size)
+		n, err := h.file.ReadAt(ctx, out, int64(in.Offset))
+		if n == 0 && err != nil && !isEOF(err) {
+			return errno(err), nil
+		}
+		return 0, out[:n]
+
+	case opWrite:
+		in := decode[writeIn](body)
+		h := s.getHandle(in.Fh)
+		if h == nil || h.file == nil {
+			return unix.EBADF, nil
+		}
+		data := body[unsafe.Sizeof(in):]
+		data = data[:min(len(data), int(in.Size))]
+		n, err := h.file.WriteAt(ctx, data, int64(in.Offset))
+		if err != nil {
+			return errno(err), nil
+		}
+		out := writeOut{Size: uint32(n)}
+		return 0, bytesOf(&out)
+
+	case opFlush, opFsync:
+		// fsync_in also starts with the handle
+		fh := decode[flushIn](body).Fh
+		if h := s.getHandle(fh); h != nil && h.file != nil {
+			return errno(h.file.Flush(ctx)), nil
+		}
+		return 0, nil
+
+	case opRelease:
+		fh := decode[releaseIn](body).Fh
+		h := s.removeHandle(fh)
+		if h != nil && h.file != nil {
+			return errno(h.file.Release(ctx)), nil
+		}
+		return 0, nil
+
+	case opOpendir:
+		p, ok := s.path(hdr.NodeID)
+		if !ok {
+			return unix.ENOENT, nil
+		}
+		entries, err := s.fsys.ReadDir(ctx, p)
+		if err != nil {
+			return errno(err), nil
+		}
+		entries = append([]DirEntry{{Name: ".", Dir: true}, {Name: "..", Dir: true}}, entries...)
+		out := openOut{Fh: s.addHandle(&openHandle{entries: entries})}
+		return 0, bytesOf(&out)
+
+	case opReaddir:
+		in := decode[readIn](body)
+		h := s.getHandle(in.Fh)
+		if h == nil {
+			return unix.EBADF, nil
+		}
+		p, _ := s.path(hdr.NodeID)
+		return 0, s.dirents(p, h.entries, in.Offset, int(in.Size))
+
+	case opReleasedir:
+		s.removeHandle(decode[releaseIn](body).Fh)
+		return 0, nil
+
+	case opFsyncdir:
+		return 0, nil
+
+	case opStatfs:
+		st, err := s.fsys.StatFS(ctx)
+		if err != nil {
+			return errno(err), nil
+		}
+		const bsize = 4096
+		out := kstatfs{
+			Blocks:  st.Total / bsize,
+			Bfree:   st.Free / bsize,
+			Bavail:  st.Free / bsize,
+			Bsize:   bsize,
+			Frsize:  bsize,
+			Namelen: 255,
+		}
+		return 0, bytesOf(&out)
+	}
+
+	// Including ACCESS: ENOSYS tells the kernel not to ask again
+	return unix.ENOSYS, nil
+}
+
+// truncate applies a size change, flushing the open handle it came through
+// first so the file system sees every write
+func (s *Server) truncate(ctx context.Context, p string, in setattrIn) error {
+	if in.Valid&setattrFh != 0 {
+		if h := s.getHandle(in.Fh); h != nil && h.file != nil {
+			if err := h.file.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return s.fsys.Truncate(ctx, p, in.Size)
+}
+
+// entry stats p and returns a LOOKUP reply, taking a reference on its node
+func (s *Server) entry(ctx context.Context, p string) (syscall.Errno, []byte) {
+	a, err := s.fsys.Stat(ctx, p)
+	if err != nil {
+		return errno(err), nil
+	}
+	id := s.lookup(p)
+	out := entryOut{
+		NodeID:     id,
+		EntryValid: uint64(entryTimeout / time.Second),
+		AttrValid:  uint64(entryTimeout / time.Second),
+		Attr:       s.attr(id, a),
+	}
+	return 0, bytesOf(&out)
+}
+
+func (s *Server) attr(id uint64, a Attr) attr {
+	out := attr{
+		Ino:     id,
+		Size:    a.Size,
+		Blocks:  (a.Size + 511) / 512,
+		Blksize: maxWrite,
+		UID:     s.uid,
+		GID:     s.gid,
+	}
+	mtime := a.ModTime
+	if mtime.IsZero() {
+		mtime = s.mounted
+	}
+	out.Mtime = uint64(mtime.Unix())
+	out.Ctime = out.Mtime
+	out.Atime = out.Mtime
+	if a.Dir {
+		out.Mode = unix.S_IFDIR | 0755
+		out.Nlink = 2
+	} else {
+		out.Mode = unix.S_IFREG | 0644
+		out.Nlink = 1
+	}
+	return out
+}
+
+// dirents packs entries from offset into at most size bytes
+func (s *Server) dirents(dir string, entries []DirEntry, offset uint64, size int) []byte {
+	var out []byte
+	for i := offset; i < uint64(len(entries)); i++ {
+		e := entries[i]
+		hdr := direntHeader{
+			Ino:     inodeHint(path.Join(dir, e.Name)),
+			Off:     i + 1,
+			Namelen: uint32(len(e.Name)),
+			Type:    unix.DT_REG,
+		}
+		if e.Dir {
+			hdr.Type = unix.DT_DIR
+		}
+		recLen := (int(unsafe.Sizeof(hdr)) + len(e.Name) + 7) &^ 7
+		if len(out)+recLen > size {
+			break
+		}
+		rec := make([]byte, recLen)
+		copy(rec, bytesOf(&hdr))
+		copy(rec[unsafe.Sizeof(hdr):], e.Name)
+		out = append(out, rec...)
+	}
+	return out
+}
+
+// inodeHint numbers a directory entry; the kernel only passes it on to readdir
+// callers, which expect it to be non-zero
+func inodeHint(p string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(p))
+	return h.Sum64() | 1
+}
+
+func (s *Server) path(id uint64) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.nodes[id]
+	if n == nil || n.path == "" {
+		return "", false
+	}
+	return n.path, true
+}
+
+func (s *Server) child(parent uint64, name string) (string, bool) {
+	dir, ok := s.path(parent)
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return path.Join(dir, name), true
+}
+
+// lookup returns the node for p, creating it, and counts a kernel reference
+func (s *Server) lookup(p string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.paths[p]
+	if !ok {
+		id = s.nextNode
+		s.nextNode++
+		s.nodes[id] = &node{path: p}
+		s.paths[p] = id
+	}
+	s.nodes[id].lookups++
+	return id
+}
+
+func (s *Server) forget(id, nlookup uint64) {
+	if id == rootID {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.nodes[id]
+	if n == nil {
+		return
+	}
+	n.lookups -= min(nlookup, n.lookups)
+	if n.lookups == 0 {
+		delete(s.nodes, id)
+		if n.path != "" && s.paths[n.path] == id {
+			delete(s.paths, n.path)
+		}
+	}
+}
+
+// unlinked detaches the node at p so a new file there gets a new node
+func (s *Server) unlinked(p string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.paths[p]; ok {
+		s.nodes[id].path = ""
+		delete(s.paths, p)
+	}
+}
+
+// renamed moves the nodes at and below oldPath under newPath
+func (s *Server) renamed(oldPath, newPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.paths[newPath]; ok {
+		s.nodes[id].path = ""
+		delete(s.paths, newPath)
+	}
+	for p, id := range s.paths {
+		var moved string
+		switch {
+		case p == oldPath:
+			moved = newPath
+		case strings.HasPrefix(p, oldPath+"/"):
+			moved = newPath + strings.TrimPrefix(p, oldPath)
+		default:
+			continue
+		}
+		delete(s.paths, p)
+		s.paths[moved] = id
+		s.nodes[id].path = moved
+	}
+}
+
+func (s *Server) addHandle(h *openHandle) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fh := s.nextFh
+	s.nextFh++
+	s.handles[fh] = h
+	return fh
+}
+
+func (s *Server) getHandle(fh uint64) *openHandle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handles[fh]
+}
+
+func (s *Server) removeHandle(fh uint64) *openHandle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := s.handles[fh]
+	delete(s.handles, fh)
+	return h
+}
+
+// reply writes one response; a write failing with ENOENT means the request was
+// interrupted and the kernel no longer wants it
+func (s *Server) reply(hdr inHeader, errno syscall.Errno, body []byte) {
+	out := outHeader{Len: uint32(outHeaderSize + len(body)), Error: -int32(errno), Unique: hdr.Unique}
+	if errno != 0 {
+		out.Len, body = outHeaderSize, nil
+	}
+	_, err := unix.Writev(s.fd, [][]byte{bytesOf(&out), body})
+	if err != nil && !errors.Is(err, unix.ENOENT) {
+		slog.Error("fuse reply failed", "opcode", hdr.Opcode, "error", err)
+	}
+}
+
+func isEOF(err error) bool {
+	return errors.Is(err, io.EOF)
+}
+
+// cstring returns b up to its first NUL
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// bytesOf views a protocol struct as the bytes the kernel expects, in host order
+func bytesOf[T any](v *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(v)), unsafe.Sizeof(*v))
+}
+
+// decode copies a protocol struct out of b, zero-filling what b lacks
+func decode[T any](b []byte) T {
+	var v T
+	copy(bytesOf(&v), b)
+	return v
+}
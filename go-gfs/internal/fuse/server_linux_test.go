@@ -0,0 +1,569 @@
+//go:build linux
+
+package fuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// memFS is a FileSystem in memory
+type memFS struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	dirs    map[string]bool
+	flushes int
+}
+
+func newMemFS(files ...string) *memFS {
+	m := &memFS{files: make(map[string][]byte), dirs: map[string]bool{"/": true}}
+	for _, p := range files {
+		m.files[p] = []byte("contents of " + p)
+		for dir := path.Dir(p); dir != "/"; dir = path.Dir(dir) {
+			m.dirs[dir] = true
+		}
+	}
+	return m
+}
+
+func (m *memFS) Stat(ctx context.Context, p string) (Attr, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dirs[p] {
+		return Attr{Dir: true}, nil
+	}
+	if data, ok := m.files[p]; ok {
+		return Attr{Size: uint64(len(data))}, nil
+	}
+	return Attr{}, fs.ErrNotExist
+}
+
+func (m *memFS) ReadDir(ctx context.Context, p string) ([]DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []DirEntry
+	for dir := range m.dirs {
+		if dir != "/" && path.Dir(dir) == p {
+			entries = append(entries, DirEntry{Name: path.Base(dir), Dir: true})
+		}
+	}
+	for file := range m.files {
+		if path.Dir(file) == p {
+			entries = append(entries, DirEntry{Name: path.Base(file)})
+		}
+	}
+	slices.SortFunc(entries, func(a, b DirEntry) int { return strings.Compare(a.Name, b.Name) })
+	return entries, nil
+}
+
+func (m *memFS) Mkdir(ctx context.Context, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dirs[p] = true
+	return nil
+}
+
+func (m *memFS) Rmdir(ctx context.Context, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.dirs, p)
+	return nil
+}
+
+func (m *memFS) Remove(ctx context.Context, p string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[p]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(m.files, p)
+	return nil
+}
+
+func (m *memFS) Rename(ctx context.Context, oldPath, newPath string, noReplace bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[oldPath]
+	if !ok {
+		return fs.ErrNotExist
+	}
+	if _, exists := m.files[newPath]; exists && noReplace {
+		return fs.ErrExist
+	}
+	delete(m.files, oldPath)
+	m.files[newPath] = data
+	return nil
+}
+
+func (m *memFS) Truncate(ctx context.Context, p string, size uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := m.files[p]
+	if size <= uint64(len(data)) {
+		m.files[p] = data[:size]
+	} else {
+		m.files[p] = append(data, make([]byte, size-uint64(len(data)))...)
+	}
+	return nil
+}
+
+func (m *memFS) Open(ctx context.Context, p string, flags int) (Handle, error) {
+	if _, err := m.Stat(ctx, p); err != nil {
+		return nil, err
+	}
+	return &memHandle{fs: m, path: p}, nil
+}
+
+func (m *memFS) Create(ctx context.Context, p string, flags int) (Handle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[p]; ok {
+		return nil, fs.ErrExist
+	}
+	m.files[p] = nil
+	return &memHandle{fs: m, path: p}, nil
+}
+
+func (m *memFS) StatFS(ctx context.Context) (StatFS, error) {
+	return StatFS{Total: 1 << 30, Free: 1 << 20}, nil
+}
+
+type memHandle struct {
+	fs   *memFS
+	path string
+}
+
+func (h *memHandle) ReadAt(ctx context.Context, p []byte, off int64) (int, error) {
+	h.fs.mu.Lock()
+	defer h.fs.mu.Unlock()
+	data := h.fs.files[h.path]
+	if off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	return copy(p, data[off:]), nil
+}
+
+func (h *memHandle) WriteAt(ctx context.Context, p []byte, off int64) (int, error) {
+	h.fs.mu.Lock()
+	defer h.fs.mu.Unlock()
+	data := h.fs.files[h.path]
+	if end := off + int64(len(p)); end > int64(len(data)) {
+		data = append(data, make([]byte, end-int64(len(data)))...)
+	}
+	copy(data[off:], p)
+	h.fs.files[h.path] = data
+	return len(p), nil
+}
+
+func (h *memHandle) Flush(ctx context.Context) error {
+	h.fs.mu.Lock()
+	defer h.fs.mu.Unlock()
+	h.fs.flushes++
+	return nil
+}
+
+func (h *memHandle) Release(ctx context.Context) error { return nil }
+
+// testConn is a server with the kernel's end of its connection
+type testConn struct {
+	s      *Server
+	kernel int
+	unique uint64
+}
+
+func newTestConn(t *testing.T, fsys FileSystem) *testConn {
+	t.Helper()
+	// Like /dev/fuse, a packet socket keeps each reply in one read
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	t.Cleanup(func() {
+		unix.Close(fds[0])
+		unix.Close(fds[1])
+	})
+	return &testConn{s: newServer(fsys, fds[0], Options{UID: 1000, GID: 1000}), kernel: fds[1]}
+}
+
+// call encodes a request, answers it as Serve does and decodes the reply, if
+// the request has one
+func (c *testConn) call(t *testing.T, opcode uint32, nodeID uint64, body ...[]byte) (syscall.Errno, []byte) {
+	t.Helper()
+	c.unique++
+	hdr := inHeader{Opcode: opcode, Unique: c.unique, NodeID: nodeID}
+	msg := slices.Concat(append([][]byte{bytesOf(&hdr)}, body...)...)
+	hdr.Len = uint32(len(msg))
+	copy(msg, bytesOf(&hdr))
+
+	hdr = decode[inHeader](msg)
+	switch hdr.Opcode {
+	case opInit:
+		c.s.handleInit(hdr, msg[inHeaderSize:])
+	case opForget, opBatchForget, opInterrupt:
+		c.s.handleNoReply(hdr, msg[inHeaderSize:])
+		return 0, nil
+	default:
+		errno, out := c.s.dispatch(context.Background(), hdr, msg[inHeaderSize:])
+		c.s.reply(hdr, errno, out)
+	}
+
+	buf := make([]byte, readBufSize)
+	n, err := unix.Read(c.kernel, buf)
+	if err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	out := decode[outHeader](buf[:n])
+	if out.Len != uint32(n) || out.Unique != c.unique {
+		t.Fatalf("reply header %+v for a %d byte reply to request %d", out, n, c.unique)
+	}
+	return syscall.Errno(-out.Error), buf[outHeaderSize:n]
+}
+
+// name encodes a NUL-terminated name, as the kernel sends them
+func name(s string) []byte { return append([]byte(s), 0) }
+
+func (c *testConn) lookup(t *testing.T, parent uint64, child string) entryOut {
+	t.Helper()
+	errno, out := c.call(t, opLookup, parent, name(child))
+	if errno != 0 {
+		t.Fatalf("LOOKUP %s: %v", child, errno)
+	}
+	return decode[entryOut](out)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       initIn
+		errno    syscall.Errno
+		minor    uint32
+		flags    uint32
+		maxWrite uint32
+	}{
+		{
+			name:  "older kernel",
+			in:    initIn{Major: 7, Minor: 26, MaxReadahead: 4096, Flags: initAsyncRead | initBigWrites},
+			minor: 26, flags: initAsyncRead | initBigWrites, maxWrite: 128 << 10,
+		},
+		{
+			name:  "newer kernel with max pages",
+			in:    initIn{Major: 7, Minor: 38, MaxReadahead: 1 << 24, Flags: initAsyncRead | initMaxPages | 1<<2},
+			minor: kernelMinorVersion, flags: initAsyncRead | initMaxPages, maxWrite: maxWrite,
+		},
+		{name: "other major version", in: initIn{Major: 8}, errno: unix.EPROTO},
+	}
+	for _, tt := range tests {
+		c := newTestConn(t, newMemFS())
+		errno, body := c.call(t, opInit, 0, bytesOf(&tt.in))
+		if errno != tt.errno {
+			t.Errorf("%s: errno = %v, want %v", tt.name, errno, tt.errno)
+			continue
+		}
+		if errno != 0 {
+			continue
+		}
+		if len(body) != int(unsafe.Sizeof(initOut{})) {
+			t.Fatalf("%s: %d byte reply", tt.name, len(body))
+		}
+		out := decode[initOut](body)
+		if out.Major != kernelVersion || out.Minor != tt.minor || out.Flags != tt.flags || out.MaxWrite != tt.maxWrite {
+			t.Errorf("%s: reply = %+v", tt.name, out)
+		}
+		if out.MaxReadahead > tt.in.MaxReadahead || out.MaxReadahead > maxReadahead {
+			t.Errorf("%s: read-ahead %d above the kernel's %d", tt.name, out.MaxReadahead, tt.in.MaxReadahead)
+		}
+	}
+}
+
+func TestLookupAndForget(t *testing.T) {
+	c := newTestConn(t, newMemFS("/a/f.txt"))
+
+	dir := c.lookup(t, rootID, "a")
+	if dir.NodeID == rootID || dir.Attr.Ino != dir.NodeID || dir.Attr.Mode != unix.S_IFDIR|0755 || dir.EntryValid == 0 {
+		t.Errorf("directory entry = %+v", dir)
+	}
+	file := c.lookup(t, dir.NodeID, "f.txt")
+	if file.Attr.Mode != unix.S_IFREG|0644 || file.Attr.Size != uint64(len("contents of /a/f.txt")) || file.Attr.UID != 1000 {
+		t.Errorf("file entry = %+v", file.Attr)
+	}
+	if again := c.lookup(t, dir.NodeID, "f.txt"); again.NodeID != file.NodeID {
+		t.Errorf("second lookup gave node %d, want %d", again.NodeID, file.NodeID)
+	}
+
+	for _, bad := range []string{"missing", "", "a/f.txt"} {
+		if errno, _ := c.call(t, opLookup, rootID, name(bad)); errno != unix.ENOENT {
+			t.Errorf("LOOKUP %q: errno = %v, want ENOENT", bad, errno)
+		}
+	}
+	if errno, _ := c.call(t, opLookup, 99, name("f.txt")); errno != unix.ENOENT {
+		t.Errorf("LOOKUP under an unknown node: errno = %v, want ENOENT", errno)
+	}
+
+	errno, body := c.call(t, opGetattr, file.NodeID, make([]byte, 16))
+	if out := decode[attrOut](body); errno != 0 || out.Attr != file.Attr {
+		t.Errorf("GETATTR = %v, %+v; want %+v", errno, out.Attr, file.Attr)
+	}
+
+	// The node lives until the kernel forgets both lookups
+	forget := forgetIn{Nlookup: 1}
+	c.call(t, opForget, file.NodeID, bytesOf(&forget))
+	if errno, _ := c.call(t, opGetattr, file.NodeID, make([]byte, 16)); errno != 0 {
+		t.Errorf("GETATTR after forgetting one lookup: errno = %v", errno)
+	}
+	batch := batchForgetIn{Count: 2}
+	ones := []forgetOne{{NodeID: file.NodeID, Nlookup: 1}, {NodeID: rootID, Nlookup: 1}}
+	c.call(t, opBatchForget, 0, bytesOf(&batch), bytesOf(&ones[0]), bytesOf(&ones[1]))
+	if errno, _ := c.call(t, opGetattr, file.NodeID, make([]byte, 16)); errno != unix.ENOENT {
+		t.Errorf("GETATTR of a forgotten node: errno = %v, want ENOENT", errno)
+	}
+	if errno, _ := c.call(t, opGetattr, rootID, make([]byte, 16)); errno != 0 {
+		t.Errorf("GETATTR of the root after forgetting it: errno = %v", errno)
+	}
+	if refound := c.lookup(t, dir.NodeID, "f.txt"); refound.NodeID == file.NodeID {
+		t.Error("forgotten node ID reused")
+	}
+}
+
+// dirent is one decoded READDIR record
+type dirent struct {
+	name string
+	off  uint64
+	typ  uint32
+}
+
+func parseDirents(t *testing.T, b []byte) []dirent {
+	t.Helper()
+	var out []dirent
+	for len(b) > 0 {
+		hdr := decode[direntHeader](b)
+		recLen := (int(unsafe.Sizeof(hdr)) + int(hdr.Namelen) + 7) &^ 7
+		if hdr.Ino == 0 || recLen > len(b) {
+			t.Fatalf("bad dirent %+v in %d bytes", hdr, len(b))
+		}
+		name := b[unsafe.Sizeof(hdr) : int(unsafe.Sizeof(hdr))+int(hdr.Namelen)]
+		out = append(out, dirent{name: string(name), off: hdr.Off, typ: hdr.Type})
+		b = b[recLen:]
+	}
+	return out
+}
+
+func TestReaddir(t *testing.T) {
+	var files []string
+	for i := range 20 {
+		files = append(files, fmt.Sprintf("/file-with-a-longer-name-%02d", i))
+	}
+	c := newTestConn(t, newMemFS(append(files, "/sub/x")...))
+
+	errno, body := c.call(t, opOpendir, rootID, make([]byte, 8))
+	if errno != 0 {
+		t.Fatalf("OPENDIR: %v", errno)
+	}
+	fh := decode[openOut](body).Fh
+
+	// Page through with small buffers, resuming at the last offset
+	var got []dirent
+	var offset uint64
+	for {
+		in := readIn{Fh: fh, Offset: offset, Size: 200}
+		errno, body := c.call(t, opReaddir, rootID, bytesOf(&in))
+		if errno != 0 {
+			t.Fatalf("READDIR at %d: %v", offset, errno)
+		}
+		if len(body) > 200 {
+			t.Fatalf("READDIR returned %d bytes for 200", len(body))
+		}
+		page := parseDirents(t, body)
+		if len(page) == 0 {
+			break
+		}
+		got = append(got, page...)
+		offset = page[len(page)-1].off
+	}
+
+	want := []string{".", ".."}
+	for _, f := range files {
+		want = append(want, f[1:])
+	}
+	want = append(want, "sub")
+	var names []string
+	for i, e := range got {
+		names = append(names, e.name)
+		if e.off != uint64(i+1) {
+			t.Errorf("%s at offset %d, want %d", e.name, e.off, i+1)
+		}
+		wantType := uint32(unix.DT_REG)
+		if e.name == "." || e.name == ".." || e.name == "sub" {
+			wantType = unix.DT_DIR
+		}
+		if e.typ != wantType {
+			t.Errorf("%s has type %d, want %d", e.name, e.typ, wantType)
+		}
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("entries = %v, want %v", names, want)
+	}
+
+	release := releaseIn{Fh: fh}
+	if errno, _ := c.call(t, opReleasedir, rootID, bytesOf(&release)); errno != 0 {
+		t.Errorf("RELEASEDIR: %v", errno)
+	}
+	in := readIn{Fh: fh, Size: 200}
+	if errno, _ := c.call(t, opReaddir, rootID, bytesOf(&in)); errno != unix.EBADF {
+		t.Errorf("READDIR after RELEASEDIR: errno = %v, want EBADF", errno)
+	}
+}
+
+func TestCreateWriteRead(t *testing.T) {
+	fsys := newMemFS()
+	c := newTestConn(t, fsys)
+
+	create := createIn{Flags: unix.O_RDWR, Mode: 0644}
+	errno, body := c.call(t, opCreate, rootID, bytesOf(&create), name("f"))
+	if errno != 0 || len(body) != int(unsafe.Sizeof(entryOut{})+unsafe.Sizeof(openOut{})) {
+		t.Fatalf("CREATE = %v, %d bytes", errno, len(body))
+	}
+	entry := decode[entryOut](body)
+	fh := decode[openOut](body[unsafe.Sizeof(entry):]).Fh
+	if errno, _ := c.call(t, opCreate, rootID, bytesOf(&create), name("f")); errno != unix.EEXIST {
+		t.Errorf("CREATE of an existing file: errno = %v, want EEXIST", errno)
+	}
+
+	writes := []struct {
+		offset uint64
+		data   string
+	}{
+		{0, "hello"},
+		{5, ", world"},
+		{0, "J"},
+	}
+	for _, w := range writes {
+		in := writeIn{Fh: fh, Offset: w.offset, Size: uint32(len(w.data))}
+		errno, body := c.call(t, opWrite, entry.NodeID, bytesOf(&in), []byte(w.data))
+		if out := decode[writeOut](body); errno != 0 || out.Size != uint32(len(w.data)) {
+			t.Fatalf("WRITE %q = %v, %d", w.data, errno, out.Size)
+		}
+	}
+
+	flush := flushIn{Fh: fh}
+	if errno, _ := c.call(t, opFlush, entry.NodeID, bytesOf(&flush)); errno != 0 || fsys.flushes != 1 {
+		t.Errorf("FLUSH = %v, %d flushes", errno, fsys.flushes)
+	}
+
+	reads := []struct {
+		offset uint64
+		size   uint32
+		want   string
+	}{
+		{0, 100, "Jello, world"},
+		{7, 3, "wor"},
+		{12, 10, ""},
+		{50, 10, ""},
+	}
+	for _, r := range reads {
+		in := readIn{Fh: fh, Offset: r.offset, Size: r.size}
+		if errno, body := c.call(t, opRead, entry.NodeID, bytesOf(&in)); errno != 0 || string(body) != r.want {
+			t.Errorf("READ %d+%d = %v, %q; want %q", r.offset, r.size, errno, body, r.want)
+		}
+	}
+
+	// Truncating through the handle flushes it first
+	setattr := setattrIn{Valid: setattrSize | setattrFh, Fh: fh, Size: 5}
+	errno, body = c.call(t, opSetattr, entry.NodeID, bytesOf(&setattr))
+	if out := decode[attrOut](body); errno != 0 || out.Attr.Size != 5 || fsys.flushes != 2 {
+		t.Errorf("SETATTR size = %v, size %d, %d flushes", errno, out.Attr.Size, fsys.flushes)
+	}
+
+	release := releaseIn{Fh: fh}
+	if errno, _ := c.call(t, opRelease, entry.NodeID, bytesOf(&release)); errno != 0 {
+		t.Errorf("RELEASE: %v", errno)
+	}
+	in := readIn{Fh: fh, Size: 10}
+	if errno, _ := c.call(t, opRead, entry.NodeID, bytesOf(&in)); errno != unix.EBADF {
+		t.Errorf("READ after RELEASE: errno = %v, want EBADF", errno)
+	}
+}
+
+func TestRename(t *testing.T) {
+	c := newTestConn(t, newMemFS("/a", "/b", "/d/c"))
+	a := c.lookup(t, rootID, "a")
+	b := c.lookup(t, rootID, "b")
+	d := c.lookup(t, rootID, "d")
+
+	noReplace := rename2In{Newdir: rootID, Flags: renameNoReplace}
+	if errno, _ := c.call(t, opRename2, rootID, bytesOf(&noReplace), name("a"), name("b")); errno != unix.EEXIST {
+		t.Errorf("RENAME2 onto an existing file without replacing: errno = %v, want EEXIST", errno)
+	}
+	exchange := rename2In{Newdir: rootID, Flags: renameExchange}
+	if errno, _ := c.call(t, opRename2, rootID, bytesOf(&exchange), name("a"), name("b")); errno != unix.EINVAL {
+		t.Errorf("RENAME2 exchange: errno = %v, want EINVAL", errno)
+	}
+
+	// The node follows the file, and the replaced file's node is detached
+	rename := renameIn{Newdir: d.NodeID}
+	if errno, _ := c.call(t, opRename, rootID, bytesOf(&rename), name("a"), name("c")); errno != 0 {
+		t.Fatalf("RENAME: %v", errno)
+	}
+	if p, _ := c.s.path(a.NodeID); p != "/d/c" {
+		t.Errorf("renamed node's path = %q, want /d/c", p)
+	}
+	if moved := c.lookup(t, d.NodeID, "c"); moved.NodeID != a.NodeID {
+		t.Errorf("lookup of the new name gave node %d, want %d", moved.NodeID, a.NodeID)
+	}
+	if errno, _ := c.call(t, opLookup, rootID, name("a")); errno != unix.ENOENT {
+		t.Errorf("LOOKUP of the old name: errno = %v, want ENOENT", errno)
+	}
+
+	if errno, _ := c.call(t, opUnlink, rootID, name("b")); errno != 0 {
+		t.Fatalf("UNLINK: %v", errno)
+	}
+	if errno, _ := c.call(t, opGetattr, b.NodeID, make([]byte, 16)); errno != unix.ENOENT {
+		t.Errorf("GETATTR of an unlinked node: errno = %v, want ENOENT", errno)
+	}
+	if errno, _ := c.call(t, opUnlink, rootID, name("b")); errno != unix.ENOENT {
+		t.Errorf("second UNLINK: errno = %v, want ENOENT", errno)
+	}
+}
+
+func TestUnsupportedAndStatfs(t *testing.T) {
+	c := newTestConn(t, newMemFS())
+	const opAccess = 34
+	if errno, _ := c.call(t, opAccess, rootID, make([]byte, 8)); errno != unix.ENOSYS {
+		t.Errorf("ACCESS: errno = %v, want ENOSYS", errno)
+	}
+	errno, body := c.call(t, opStatfs, rootID)
+	if out := decode[kstatfs](body); errno != 0 || out.Blocks != (1<<30)/4096 || out.Bavail != (1<<20)/4096 || out.Bsize != 4096 {
+		t.Errorf("STATFS = %v, %+v", errno, out)
+	}
+}
+
+func TestErrno(t *testing.T) {
+	tests := []struct {
+		err  error
+		want syscall.Errno
+	}{
+		{nil, 0},
+		{fmt.Errorf("wrapped: %w", syscall.ENOTEMPTY), syscall.ENOTEMPTY},
+		{fs.ErrNotExist, syscall.ENOENT},
+		{&fs.PathError{Op: "open", Path: "/x", Err: fs.ErrExist}, syscall.EEXIST},
+		{fs.ErrPermission, syscall.EACCES},
+		{context.Canceled, syscall.EINTR},
+		{errors.New("anything else"), syscall.EIO},
+	}
+	for _, tt := range tests {
+		if got := errno(tt.err); got != tt.want {
+			t.Errorf("errno(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
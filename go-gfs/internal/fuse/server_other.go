@@ -0,0 +1,22 @@
+//go:build !linux
+
+package fuse
+
+import "errors"
+
+// Server is a mounted FileSystem.
+type Server struct{}
+
+// Mount is only supported on Linux.
+func Mount(dir string, fsys FileSystem, opts Options) (*Server, error) {
+	return nil, errors.New("FUSE mounts are only supported on Linux")
+}
+
+// Dir returns the mount point.
+func (s *Server) Dir() string { return "" }
+
+// Unmount detaches the mount.
+func (s *Server) Unmount() error { return nil }
+
+// Serve answers requests until the file system is unmounted.
+func (s *Server) Serve() error { return nil }
@@ -0,0 +1,517 @@
+// Package gfsmount serves a GFS namespace read-write as a FUSE file system.
+//
+// Files are the namespace's paths that start with "/", and directories are
+// derived from "/"-separated paths, as with gfs.FS. Directories made with mkdir
+// exist only in the mount until a file is written under them.
+//
+// GFS files grow by appending, so sequential writes are buffered and appended
+// when the file is closed or synced, and writes over existing bytes are sent as
+// in-place writes. A file can be truncated to zero or extended, but not
+// shortened to any other size.
+package gfsmount
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+
+	"eddisonso.com/go-gfs/internal/fuse"
+	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
+)
+
+// maxRenameFiles bounds a directory rename, which moves every file under the
+// directory in one transaction; the master allows 1000 operations in one.
+// Larger renames fail with EXDEV, which makes mv fall back to copying.
+const maxRenameFiles = 1000
+
+// zeroFill is the most zeros written at once when a write or truncate extends
+// a file past its end.
+const zeroFill = 1 << 20
+
+// FS is a namespace served over FUSE.
+type FS struct {
+	client    *gfs.Client
+	namespace string
+	fsys      *gfs.FileSystem
+
+	mu      sync.Mutex
+	dirs    map[string]bool      // Made with mkdir; may hold no files yet
+	handles map[*handle]struct{} // Open files, for their buffered sizes
+}
+
+// New returns the file system for a namespace.
+func New(client *gfs.Client, namespace string) *FS {
+	return &FS{
+		client:    client,
+		namespace: namespace,
+		fsys:      gfs.FS(client, namespace),
+		dirs:      make(map[string]bool),
+		handles:   make(map[*handle]struct{}),
+	}
+}
+
+// Mount mounts a namespace on dir. Serve the returned server until it is unmounted.
+func Mount(client *gfs.Client, namespace, dir string, allowOther bool) (*fuse.Server, error) {
+	return fuse.Mount(dir, New(client, namespace), fuse.Options{Name: "gfs", AllowOther: allowOther})
+}
+
+// name converts a mount path to a gfs.FileSystem name
+func name(p string) string {
+	if p == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+func (m *FS) Stat(ctx context.Context, p string) (fuse.Attr, error) {
+	info, err := m.fsys.WithContext(ctx).Stat(name(p))
+	if err != nil {
+		m.mu.Lock()
+		dir := m.dirs[p]
+		m.mu.Unlock()
+		if dir && errors.Is(err, fs.ErrNotExist) {
+			return fuse.Attr{Dir: true}, nil
+		}
+		return fuse.Attr{}, mapError(err)
+	}
+
+	attr := fuse.Attr{Dir: info.IsDir(), Size: uint64(info.Size()), ModTime: info.ModTime()}
+	if !attr.Dir {
+		// Count bytes still buffered in open handles
+		m.mu.Lock()
+		for h := range m.handles {
+			if size, ok := h.pendingSize(p); ok && size > attr.Size {
+				attr.Size = size
+			}
+		}
+		m.mu.Unlock()
+	}
+	return attr, nil
+}
+
+func (m *FS) ReadDir(ctx context.Context, p string) ([]fuse.DirEntry, error) {
+	entries, err := m.fsys.WithContext(ctx).ReadDir(name(p))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, mapError(err)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	out := make([]fuse.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		seen[e.Name()] = true
+		out = append(out, fuse.DirEntry{Name: e.Name(), Dir: e.IsDir()})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := range m.dirs {
+		if path.Dir(dir) == p && !seen[path.Base(dir)] {
+			out = append(out, fuse.DirEntry{Name: path.Base(dir), Dir: true})
+		}
+	}
+	if err != nil && len(out) == 0 && !m.dirs[p] && p != "/" {
+		return nil, syscall.ENOENT
+	}
+	return out, nil
+}
+
+func (m *FS) Mkdir(ctx context.Context, p string) error {
+	if _, err := m.Stat(ctx, p); err == nil {
+		return syscall.EEXIST
+	} else if !errors.Is(err, syscall.ENOENT) {
+		return err
+	}
+	m.mu.Lock()
+	m.dirs[p] = true
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *FS) Rmdir(ctx context.Context, p string) error {
+	entries, err := m.ReadDir(ctx, p)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return syscall.ENOTEMPTY
+	}
+	m.mu.Lock()
+	delete(m.dirs, p)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *FS) Remove(ctx context.Context, p string) error {
+	return mapError(m.client.DeleteFileWithNamespace(ctx, p, m.namespace))
+}
+
+func (m *FS) Rename(ctx context.Context, oldPath, newPath string, noReplace bool) error {
+	attr, err := m.Stat(ctx, oldPath)
+	if err != nil {
+		return err
+	}
+	if !attr.Dir {
+		// Commit buffered writes so they move with the file
+		m.flushPath(ctx, oldPath)
+		if noReplace {
+			err = m.client.RenameFileWithNamespace(ctx, oldPath, newPath, m.namespace)
+		} else {
+			err = m.client.ReplaceFileWithNamespace(ctx, oldPath, newPath, m.namespace)
+		}
+		if err != nil {
+			return mapError(err)
+		}
+		m.renamed(oldPath, newPath)
+		return nil
+	}
+
+	if target, err := m.Stat(ctx, newPath); err == nil {
+		if noReplace {
+			return syscall.EEXIST
+		}
+		if !target.Dir {
+			return syscall.ENOTDIR
+		}
+		if entries, err := m.ReadDir(ctx, newPath); err != nil {
+			return err
+		} else if len(entries) > 0 {
+			return syscall.ENOTEMPTY
+		}
+	}
+
+	tx := &gfs.Transaction{Namespace: m.namespace}
+	count := 0
+	for file, err := range m.client.IterFiles(ctx, gfs.ListOptions{Namespace: m.namespace, Prefix: oldPath + "/"}) {
+		if err != nil {
+			return mapError(err)
+		}
+		if count++; count > maxRenameFiles {
+			return syscall.EXDEV
+		}
+		tx.Rename(file.Path, newPath+strings.TrimPrefix(file.Path, oldPath), false)
+	}
+	if count > 0 {
+		m.flushPrefix(ctx, oldPath+"/")
+		if err := m.client.Transact(ctx, tx); err != nil {
+			return mapError(err)
+		}
+	}
+	m.renamed(oldPath, newPath)
+	return nil
+}
+
+// renamed moves mkdir'd directories and open handles at or below oldPath
+func (m *FS) renamed(oldPath, newPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	move := func(p string) (string, bool) {
+		if p == oldPath {
+			return newPath, true
+		}
+		if strings.HasPrefix(p, oldPath+"/") {
+			return newPath + strings.TrimPrefix(p, oldPath), true
+		}
+		return "", false
+	}
+	for dir := range m.dirs {
+		if moved, ok := move(dir); ok {
+			delete(m.dirs, dir)
+			m.dirs[moved] = true
+		}
+	}
+	for h := range m.handles {
+		h.mu.Lock()
+		if moved, ok := move(h.path); ok {
+			h.path = moved
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (m *FS) Truncate(ctx context.Context, p string, size uint64) error {
+	m.flushPath(ctx, p)
+	file, err := m.client.GetFileWithNamespace(ctx, p, m.namespace)
+	if err != nil {
+		return mapError(err)
+	}
+
+	switch {
+	case size == file.Size:
+		return nil
+	case size == 0:
+		// Replace the file with an empty one; the old contents go to the trash
+		tx := (&gfs.Transaction{Namespace: m.namespace}).IfSize(p, file.Size).Delete(p).Create(p, file.Attributes)
+		if err := m.client.Transact(ctx, tx); err != nil {
+			return mapError(err)
+		}
+	case size > file.Size:
+		if err := m.appendZeros(ctx, p, int64(size-file.Size)); err != nil {
+			return mapError(err)
+		}
+	default:
+		return syscall.EOPNOTSUPP
+	}
+
+	m.mu.Lock()
+	for h := range m.handles {
+		h.truncated(p, int64(size))
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *FS) appendZeros(ctx context.Context, p string, n int64) error {
+	zeros := make([]byte, min(n, zeroFill))
+	for n > 0 {
+		chunk := zeros[:min(n, int64(len(zeros)))]
+		if _, err := m.client.AppendWithNamespace(ctx, p, m.namespace, chunk); err != nil {
+			return err
+		}
+		n -= int64(len(chunk))
+	}
+	return nil
+}
+
+func (m *FS) Open(ctx context.Context, p string, flags int) (fuse.Handle, error) {
+	file, err := m.client.GetFileWithNamespace(ctx, p, m.namespace)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	size := int64(file.Size)
+	if flags&os.O_TRUNC != 0 && size > 0 && flags&(os.O_WRONLY|os.O_RDWR) != 0 {
+		if err := m.Truncate(ctx, p, 0); err != nil {
+			return nil, err
+		}
+		size = 0
+	}
+	return m.addHandle(p, size, flags), nil
+}
+
+func (m *FS) Create(ctx context.Context, p string, flags int) (fuse.Handle, error) {
+	if _, err := m.client.CreateFileWithNamespace(ctx, p, m.namespace); err != nil {
+		return nil, mapError(err)
+	}
+	return m.addHandle(p, 0, flags), nil
+}
+
+func (m *FS) StatFS(ctx context.Context) (fuse.StatFS, error) {
+	usage, err := m.client.GetNamespaceUsage(ctx, m.namespace)
+	if err == nil && usage.MaxBytes > 0 {
+		return fuse.StatFS{Total: usage.MaxBytes, Free: usage.MaxBytes - min(usage.UsedBytes, usage.MaxBytes)}, nil
+	}
+
+	servers, err := m.client.GetClusterStatus(ctx)
+	if err != nil {
+		return fuse.StatFS{}, mapError(err)
+	}
+	var st fuse.StatFS
+	for _, s := range servers {
+		if s.IsAlive {
+			st.Total += s.CapacityBytes
+			st.Free += s.FreeBytes
+		}
+	}
+	return st, nil
+}
+
+func (m *FS) addHandle(p string, size int64, flags int) *handle {
+	h := &handle{fs: m, path: p, size: size, appendOnly: flags&os.O_APPEND != 0}
+	m.mu.Lock()
+	m.handles[h] = struct{}{}
+	m.mu.Unlock()
+	return h
+}
+
+// flushPath commits the buffered writes of every handle open on p
+func (m *FS) flushPath(ctx context.Context, p string) {
+	m.flushMatching(ctx, func(hp string) bool { return hp == p })
+}
+
+// flushPrefix commits the buffered writes of every handle open below prefix
+func (m *FS) flushPrefix(ctx context.Context, prefix string) {
+	m.flushMatching(ctx, func(hp string) bool { return strings.HasPrefix(hp, prefix) })
+}
+
+func (m *FS) flushMatching(ctx context.Context, match func(string) bool) {
+	m.mu.Lock()
+	var matched []*handle
+	for h := range m.handles {
+		h.mu.Lock()
+		if match(h.path) {
+			matched = append(matched, h)
+		}
+		h.mu.Unlock()
+	}
+	m.mu.Unlock()
+	for _, h := range matched {
+		if err := h.Flush(ctx); err != nil {
+			slog.Warn("flush failed", "path", h.path, "error", err)
+		}
+	}
+}
+
+// handle is an open file. Writes at its end go through a gfs.Writer until the
+// next flush; reads go through a gfs.File for its read-ahead.
+type handle struct {
+	fs         *FS
+	appendOnly bool
+
+	mu     sync.Mutex
+	path   string
+	size   int64 // Including buffered writes
+	writer *gfs.Writer
+	reader *gfs.File
+}
+
+// pendingSize returns the handle's size if it holds buffered writes to p
+// Must be called with the FS's mu held
+func (h *handle) pendingSize(p string) (uint64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.path != p || h.writer == nil {
+		return 0, false
+	}
+	return uint64(h.size), true
+}
+
+// truncated records that p was truncated to size
+// Must be called with the FS's mu held
+func (h *handle) truncated(p string, size int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.path == p {
+		h.size = size
+		h.closeReaderLocked()
+	}
+}
+
+func (h *handle) ReadAt(ctx context.Context, p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.flushLocked(); err != nil {
+		return 0, err
+	}
+	if h.reader == nil || off >= h.reader.Size() {
+		// Open, or reopen to see bytes appended since
+		h.closeReaderLocked()
+		reader, err := h.fs.client.Open(context.Background(), h.fs.namespace, h.path)
+		if err != nil {
+			return 0, mapError(err)
+		}
+		h.reader = reader
+	}
+	if _, err := h.reader.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(h.reader, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, mapError(err)
+}
+
+func (h *handle) WriteAt(ctx context.Context, p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeReaderLocked()
+
+	if h.appendOnly {
+		off = h.size
+	}
+	written := 0
+	if off < h.size {
+		// Overwrite in place up to the end, committing buffered writes first
+		if err := h.flushLocked(); err != nil {
+			return 0, err
+		}
+		n := int(min(int64(len(p)), h.size-off))
+		if _, err := h.fs.client.WriteAtWithNamespace(ctx, h.path, h.fs.namespace, p[:n], off); err != nil {
+			return 0, mapError(err)
+		}
+		written, p = n, p[n:]
+		if len(p) == 0 {
+			return written, nil
+		}
+		off += int64(n)
+	}
+
+	if h.writer == nil {
+		w, err := h.fs.client.OpenAppend(context.Background(), h.fs.namespace, h.path)
+		if err != nil {
+			return written, mapError(err)
+		}
+		h.writer = w
+	}
+	// Fill a gap past the end with zeros
+	for off > h.size {
+		n, err := h.writer.Write(make([]byte, min(off-h.size, zeroFill)))
+		h.size += int64(n)
+		if err != nil {
+			return written, mapError(err)
+		}
+	}
+	n, err := h.writer.Write(p)
+	h.size += int64(n)
+	return written + n, mapError(err)
+}
+
+func (h *handle) Flush(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.flushLocked()
+}
+
+// flushLocked appends the buffered writes to the file
+// Must be called with mu held
+func (h *handle) flushLocked() error {
+	if h.writer == nil {
+		return nil
+	}
+	err := h.writer.Close()
+	h.writer = nil
+	return mapError(err)
+}
+
+// Must be called with mu held
+func (h *handle) closeReaderLocked() {
+	if h.reader != nil {
+		h.reader.Close()
+		h.reader = nil
+	}
+}
+
+func (h *handle) Release(ctx context.Context) error {
+	h.fs.mu.Lock()
+	delete(h.fs.handles, h)
+	h.fs.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closeReaderLocked()
+	return h.flushLocked()
+}
+
+// mapError converts SDK errors to the errno the kernel should see.
+func mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case gfs.IsNotFound(err):
+		return syscall.ENOENT
+	case errors.Is(err, gfs.ErrPreconditionFailed):
+		return syscall.EEXIST
+	case errors.Is(err, gfs.ErrQuotaExceeded):
+		return syscall.EDQUOT
+	case errors.Is(err, gfs.ErrErasureCoded):
+		return syscall.EROFS
+	}
+	return err
+}
@@ -0,0 +1,443 @@
+package gfsmount
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/fuse"
+	"eddisonso.com/go-gfs/internal/master"
+	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+)
+
+// fakeChunkserver keeps chunks in memory, serving reads and writes and
+// committing each write to the master as a primary would
+type fakeChunkserver struct {
+	lis    net.Listener
+	master *master.Master
+
+	mu     sync.Mutex
+	chunks map[string][]byte
+}
+
+func (s *fakeChunkserver) serve(conn net.Conn) {
+	defer conn.Close()
+	var action, tokenLen uint32
+	if binary.Read(conn, binary.BigEndian, &action) != nil || binary.Read(conn, binary.BigEndian, &tokenLen) != nil {
+		return
+	}
+	token := make([]byte, tokenLen)
+	if _, err := io.ReadFull(conn, token); err != nil {
+		return
+	}
+	if action == uint32(csstructs.Download) {
+		s.serveWrite(conn, string(token))
+		return
+	}
+
+	var claims csstructs.UploadRequestClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(string(token), &claims); err != nil {
+		return
+	}
+	s.mu.Lock()
+	data, ok := s.chunks[claims.ChunkHandle]
+	s.mu.Unlock()
+	if !ok || claims.Offset+claims.Length > int64(len(data)) {
+		msg := "chunk not found"
+		conn.Write([]byte{0})
+		binary.Write(conn, binary.BigEndian, uint32(1))
+		binary.Write(conn, binary.BigEndian, uint32(len(msg)))
+		conn.Write([]byte(msg))
+		return
+	}
+	data = data[claims.Offset:]
+	if claims.Length > 0 {
+		data = data[:claims.Length]
+	}
+	conn.Write([]byte{1})
+	binary.Write(conn, binary.BigEndian, uint64(len(data)))
+	conn.Write(data)
+}
+
+func (s *fakeChunkserver) serveWrite(conn net.Conn, token string) {
+	var claims csstructs.DownloadRequestClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := s.chunks[claims.ChunkHandle]
+	length := uint64(len(data))
+	if claims.IfOffset != nil && *claims.IfOffset != length {
+		binary.Write(conn, binary.BigEndian, csstructs.OffsetMismatch)
+		return
+	}
+	offset := length
+	if claims.Offset >= 0 {
+		offset = uint64(claims.Offset)
+	}
+	binary.Write(conn, binary.BigEndian, offset)
+	buf := make([]byte, claims.Filesize)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	data = append([]byte(nil), data...)
+	if end := offset + claims.Filesize; end > length {
+		data = append(data, make([]byte, end-length)...)
+	}
+	copy(data[offset:], buf)
+	s.chunks[claims.ChunkHandle] = data
+	if err := s.master.ConfirmChunkCommit("cs1", master.ChunkHandle(claims.ChunkHandle), uint64(len(data)), 1); err != nil {
+		conn.Write([]byte{0})
+		return
+	}
+	conn.Write([]byte{1})
+}
+
+// newTestFS serves a master whose chunkservers are one fake, and returns the
+// master and a mount's file system for namespace "ns"
+func newTestFS(t *testing.T) (*master.Master, *FS) {
+	t.Helper()
+	m, err := master.NewMaster(filepath.Join(t.TempDir(), "wal.log"))
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	s := &fakeChunkserver{lis: lis, master: m, chunks: make(map[string][]byte)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	addr := lis.Addr().(*net.TCPAddr)
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		m.RegisterChunkServer(master.ChunkServerID(id), addr.IP.String(), addr.Port, 0, id, nil)
+	}
+
+	glis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterMasterServer(server, master.NewGRPCServer(m))
+	go server.Serve(glis)
+	t.Cleanup(server.Stop)
+
+	c, err := gfs.New(context.Background(), glis.Addr().String(),
+		gfs.WithSecretProvider(func(*jwt.Token) (any, error) { return []byte("secret"), nil }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return m, New(c, "ns")
+}
+
+// writeFile creates a file holding data
+func writeFile(t *testing.T, m *FS, p string, data string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := m.client.CreateFileWithNamespace(ctx, p, m.namespace); err != nil {
+		t.Fatalf("CreateFile %s: %v", p, err)
+	}
+	if data != "" {
+		if _, err := m.client.AppendWithNamespace(ctx, p, m.namespace, []byte(data)); err != nil {
+			t.Fatalf("Append %s: %v", p, err)
+		}
+	}
+}
+
+// readFile returns a file's committed contents
+func readFile(t *testing.T, m *FS, p string) string {
+	t.Helper()
+	file, err := m.client.GetFileWithNamespace(context.Background(), p, m.namespace)
+	if err != nil {
+		t.Fatalf("GetFile %s: %v", p, err)
+	}
+	if file.Size == 0 {
+		return ""
+	}
+	data, err := m.client.ReadWithNamespace(context.Background(), p, m.namespace)
+	if err != nil {
+		t.Fatalf("Read %s: %v", p, err)
+	}
+	return string(data)
+}
+
+func TestStat(t *testing.T) {
+	_, m := newTestFS(t)
+	ctx := context.Background()
+	writeFile(t, m, "/a/b/file", "12345")
+	writeFile(t, m, "/top", "")
+	if err := m.Mkdir(ctx, "/made"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		attr fuse.Attr
+		err  error
+	}{
+		{path: "/", attr: fuse.Attr{Dir: true}},
+		{path: "/a", attr: fuse.Attr{Dir: true}},
+		{path: "/a/b", attr: fuse.Attr{Dir: true}},
+		{path: "/a/b/file", attr: fuse.Attr{Size: 5}},
+		{path: "/top", attr: fuse.Attr{}},
+		{path: "/made", attr: fuse.Attr{Dir: true}},
+		{path: "/missing", err: syscall.ENOENT},
+		{path: "/a/missing", err: syscall.ENOENT},
+		{path: "/a/b/file/below", err: syscall.ENOENT},
+	}
+	for _, tt := range tests {
+		attr, err := m.Stat(ctx, tt.path)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Stat(%s): err = %v, want %v", tt.path, err, tt.err)
+			continue
+		}
+		if attr.Dir != tt.attr.Dir || attr.Size != tt.attr.Size {
+			t.Errorf("Stat(%s) = %+v, want %+v", tt.path, attr, tt.attr)
+		}
+	}
+
+	for _, p := range []string{"/a", "/top", "/made"} {
+		if err := m.Mkdir(ctx, p); !errors.Is(err, syscall.EEXIST) {
+			t.Errorf("Mkdir(%s): err = %v, want EEXIST", p, err)
+		}
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	_, m := newTestFS(t)
+	ctx := context.Background()
+	for _, p := range []string{"/a/b/file", "/a/c", "/top"} {
+		writeFile(t, m, p, "")
+	}
+	for _, p := range []string{"/made", "/made/inner", "/a/b"} {
+		if err := m.Mkdir(ctx, p); err != nil && !errors.Is(err, syscall.EEXIST) {
+			t.Fatalf("Mkdir(%s): %v", p, err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want []fuse.DirEntry
+		err  error
+	}{
+		{path: "/", want: []fuse.DirEntry{{Name: "a", Dir: true}, {Name: "made", Dir: true}, {Name: "top"}}},
+		{path: "/a", want: []fuse.DirEntry{{Name: "b", Dir: true}, {Name: "c"}}},
+		{path: "/a/b", want: []fuse.DirEntry{{Name: "file"}}},
+		{path: "/made", want: []fuse.DirEntry{{Name: "inner", Dir: true}}},
+		{path: "/made/inner", want: []fuse.DirEntry{}},
+		{path: "/missing", err: syscall.ENOENT},
+	}
+	for _, tt := range tests {
+		entries, err := m.ReadDir(ctx, tt.path)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ReadDir(%s): err = %v, want %v", tt.path, err, tt.err)
+			continue
+		}
+		slices.SortFunc(entries, func(a, b fuse.DirEntry) int { return strings.Compare(a.Name, b.Name) })
+		if tt.err == nil && !slices.Equal(entries, tt.want) {
+			t.Errorf("ReadDir(%s) = %v, want %v", tt.path, entries, tt.want)
+		}
+	}
+
+	if err := m.Rmdir(ctx, "/made"); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Errorf("Rmdir of a directory with a subdirectory: err = %v, want ENOTEMPTY", err)
+	}
+	if err := m.Rmdir(ctx, "/made/inner"); err != nil {
+		t.Errorf("Rmdir of an empty directory: %v", err)
+	}
+	if _, err := m.Stat(ctx, "/made/inner"); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("Stat after Rmdir: err = %v, want ENOENT", err)
+	}
+}
+
+// A directory rename moves every file below it in one transaction, up to the
+// master's limit
+func TestRenameDirectory(t *testing.T) {
+	mst, m := newTestFS(t)
+	ctx := context.Background()
+	for i := range maxRenameFiles {
+		if _, err := mst.CreateFile(fmt.Sprintf("/full/%04d", i), "ns", "", nil); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+	for i := range maxRenameFiles + 1 {
+		if _, err := mst.CreateFile(fmt.Sprintf("/over/%04d", i), "ns", "", nil); err != nil {
+			t.Fatalf("CreateFile: %v", err)
+		}
+	}
+	writeFile(t, m, "/small/sub/f", "data")
+	writeFile(t, m, "/taken/f", "")
+
+	if err := m.Rename(ctx, "/over", "/over-moved", false); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("rename of %d files: err = %v, want EXDEV", maxRenameFiles+1, err)
+	}
+	if _, err := m.Stat(ctx, "/over-moved"); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("files moved by a failed rename: err = %v", err)
+	}
+	if entries, _ := m.ReadDir(ctx, "/over"); len(entries) != maxRenameFiles+1 {
+		t.Errorf("%d files left after a failed rename, want %d", len(entries), maxRenameFiles+1)
+	}
+
+	if err := m.Rename(ctx, "/full", "/full-moved", false); err != nil {
+		t.Fatalf("rename of %d files: %v", maxRenameFiles, err)
+	}
+	if entries, _ := m.ReadDir(ctx, "/full-moved"); len(entries) != maxRenameFiles {
+		t.Errorf("%d files moved, want %d", len(entries), maxRenameFiles)
+	}
+	if _, err := m.Stat(ctx, "/full"); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("Stat of the old directory: err = %v, want ENOENT", err)
+	}
+
+	if err := m.Rename(ctx, "/small", "/taken", false); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Errorf("rename onto a directory with files: err = %v, want ENOTEMPTY", err)
+	}
+	if err := m.Rename(ctx, "/small", "/taken", true); !errors.Is(err, syscall.EEXIST) {
+		t.Errorf("rename without replacing onto a directory: err = %v, want EEXIST", err)
+	}
+	if err := m.Rename(ctx, "/small", "/moved/small", false); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if got := readFile(t, m, "/moved/small/sub/f"); got != "data" {
+		t.Errorf("moved file holds %q, want data", got)
+	}
+
+	// Directories made with mkdir move with the rename
+	if err := m.Mkdir(ctx, "/empty"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := m.Rename(ctx, "/empty", "/empty-moved", false); err != nil {
+		t.Fatalf("rename of an empty directory: %v", err)
+	}
+	if attr, err := m.Stat(ctx, "/empty-moved"); err != nil || !attr.Dir {
+		t.Errorf("Stat of the moved empty directory = %+v, %v", attr, err)
+	}
+}
+
+// Sequential writes are held until a flush, and other writes land in place
+func TestWriteAndFlush(t *testing.T) {
+	_, m := newTestFS(t)
+	ctx := context.Background()
+	h, err := m.Create(ctx, "/f", os.O_WRONLY)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	steps := []struct {
+		name   string
+		do     func() error
+		stat   uint64 // Size Stat reports
+		stored string // Contents committed to GFS
+	}{
+		{"buffered write", func() error { return writeAt(h, "hello", 0) }, 5, ""},
+		{"second buffered write", func() error { return writeAt(h, " world", 5) }, 11, ""},
+		{"flush", func() error { return h.Flush(ctx) }, 11, "hello world"},
+		{"overwrite in place", func() error { return writeAt(h, "J", 0) }, 11, "Jello world"},
+		{"overwrite past the end", func() error { return writeAt(h, "dly!", 9) }, 13, "Jello wordl"},
+		{"flush again", func() error { return h.Flush(ctx) }, 13, "Jello wordly!"},
+		{"write past a gap", func() error { return writeAt(h, "x", 15) }, 16, "Jello wordly!"},
+		{"release", func() error { return h.Release(ctx) }, 16, "Jello wordly!\x00\x00x"},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if attr, err := m.Stat(ctx, "/f"); err != nil || attr.Size != step.stat {
+			t.Errorf("%s: Stat = %+v, %v; want size %d", step.name, attr, err, step.stat)
+		}
+		if got := readFile(t, m, "/f"); got != step.stored {
+			t.Errorf("%s: stored %q, want %q", step.name, got, step.stored)
+		}
+	}
+
+	// Appending handles write at the end whatever the offset, and reads flush first
+	h, err = m.Open(ctx, "/f", os.O_RDWR|os.O_APPEND)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := writeAt(h, "++", 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	p := make([]byte, 10)
+	n, err := h.ReadAt(ctx, p, 10)
+	if err != nil || string(p[:n]) != "ly!\x00\x00x++" {
+		t.Errorf("ReadAt = %q, %v; want the flushed tail", p[:n], err)
+	}
+	if err := h.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	// Opening with O_TRUNC empties the file; truncating extends it with zeros
+	h, err = m.Open(ctx, "/f", os.O_WRONLY|os.O_TRUNC)
+	if err != nil {
+		t.Fatalf("Open with O_TRUNC: %v", err)
+	}
+	if err := writeAt(h, "new", 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if err := m.Truncate(ctx, "/f", 5); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if err := h.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if got := readFile(t, m, "/f"); got != "new\x00\x00" {
+		t.Errorf("after truncating stored %q, want new and two zeros", got)
+	}
+	if err := m.Truncate(ctx, "/f", 2); !errors.Is(err, syscall.EOPNOTSUPP) {
+		t.Errorf("shortening: err = %v, want EOPNOTSUPP", err)
+	}
+}
+
+func writeAt(h fuse.Handle, data string, off int64) error {
+	n, err := h.WriteAt(context.Background(), []byte(data), off)
+	if err == nil && n != len(data) {
+		err = fmt.Errorf("wrote %d of %d bytes", n, len(data))
+	}
+	return err
+}
+
+func TestMapError(t *testing.T) {
+	other := errors.New("other")
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{nil, nil},
+		{fmt.Errorf("get: %w", os.ErrNotExist), syscall.ENOENT},
+		{gfs.ErrPreconditionFailed, syscall.EEXIST},
+		{gfs.ErrQuotaExceeded, syscall.EDQUOT},
+		{gfs.ErrErasureCoded, syscall.EROFS},
+		{other, other},
+	}
+	for _, tt := range tests {
+		if got := mapError(tt.err); got != tt.want {
+			t.Errorf("mapError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package master
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+)
+
+// Limits on file attributes, which are kept in memory and in every snapshot
+const (
+	MaxFileAttributes    = 64       // Attributes per file
+	MaxAttributeKeyLen   = 128      // Bytes in an attribute key
+	MaxFileAttributeSize = 16 << 10 // Bytes of keys and values per file
+)
+
+// validateAttributes checks a file's complete attributes against the limits
+func validateAttributes(attributes map[string]string) error {
+	if len(attributes) > MaxFileAttributes {
+		return fmt.Errorf("too many attributes: %d (max %d)", len(attributes), MaxFileAttributes)
+	}
+	total := 0
+	for key, value := range attributes {
+		if key == "" {
+			return fmt.Errorf("attribute key must not be empty")
+		}
+		if len(key) > MaxAttributeKeyLen {
+			return fmt.Errorf("attribute key too long: %d bytes (max %d)", len(key), MaxAttributeKeyLen)
+		}
+		total += len(key) + len(value)
+	}
+	if total > MaxFileAttributeSize {
+		return fmt.Errorf("attributes too large: %d bytes (max %d)", total, MaxFileAttributeSize)
+	}
+	return nil
+}
+
+// cloneAttributes copies attributes so the caller's map can't change them, or
+// returns nil when there are none
+func cloneAttributes(attributes map[string]string) map[string]string {
+	if len(attributes) == 0 {
+		return nil
+	}
+	return maps.Clone(attributes)
+}
+
+// SetFileAttributes changes the attributes of a file: keys in set are added or
+// overwritten, then keys in remove are deleted. Returns the updated file.
+func (m *Master) SetFileAttributes(path, namespace string, set map[string]string, remove []string) (*FileInfo, error) {
+	if err := checkUserNamespace(namespace); err != nil {
+		return nil, err
+	}
+	namespace = normalizeNamespace(namespace)
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	file, exists := m.files[makeFileKey(namespace, path)]
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+
+	// Build a new map rather than changing the one readers may hold
+	attributes := maps.Clone(file.Attributes)
+	if attributes == nil {
+		attributes = make(map[string]string, len(set))
+	}
+	maps.Copy(attributes, set)
+	for _, key := range remove {
+		delete(attributes, key)
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	if err := validateAttributes(attributes); err != nil {
+		return nil, err
+	}
+
+	// Log to WAL before applying
+	seq, err := m.wal.LogSetFileAttributes(path, namespace, attributes)
+	if err != nil {
+		return nil, fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+	file.Attributes = attributes
+
+	slog.Debug("set file attributes", "path", path, "namespace", namespace, "attributes", len(attributes))
+	return file, nil
+}
+
+// replaySetFileAttributes replaces a file's attributes from WAL (no WAL logging)
+func (m *Master) replaySetFileAttributes(path, namespace string, attributes map[string]string) {
+	if file, exists := m.files[makeFileKey(normalizeNamespace(namespace), path)]; exists {
+		file.Attributes = cloneAttributes(attributes)
+	}
+}
@@ -0,0 +1,186 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// manyAttributes returns n distinct attributes
+func manyAttributes(n int) map[string]string {
+	attributes := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		attributes[fmt.Sprintf("k%d", i)] = "v"
+	}
+	return attributes
+}
+
+func TestValidateAttributes(t *testing.T) {
+	long := strings.Repeat("x", MaxAttributeKeyLen)
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		ok         bool
+	}{
+		{name: "none", attributes: nil, ok: true},
+		{name: "empty value", attributes: map[string]string{"k": ""}, ok: true},
+		{name: "longest key", attributes: map[string]string{long: "v"}, ok: true},
+		{name: "key too long", attributes: map[string]string{long + "x": "v"}},
+		{name: "empty key", attributes: map[string]string{"": "v"}},
+		{name: "most attributes", attributes: manyAttributes(MaxFileAttributes), ok: true},
+		{name: "too many attributes", attributes: manyAttributes(MaxFileAttributes + 1)},
+		{name: "largest total", attributes: map[string]string{"k": strings.Repeat("v", MaxFileAttributeSize-1)}, ok: true},
+		{name: "total too large", attributes: map[string]string{"k": strings.Repeat("v", MaxFileAttributeSize)}},
+		{name: "total too large across keys", attributes: map[string]string{
+			"a": strings.Repeat("v", MaxFileAttributeSize/2),
+			"b": strings.Repeat("v", MaxFileAttributeSize/2),
+		}},
+	}
+	for _, tt := range tests {
+		if err := validateAttributes(tt.attributes); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestSetFileAttributes(t *testing.T) {
+	m := newTestMaster(t)
+	if _, err := m.CreateFile("/f", "ns", "", map[string]string{"a": "1", "b": "2"}); err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		set    map[string]string
+		remove []string
+		want   map[string]string
+		ok     bool
+	}{
+		{name: "add and overwrite", set: map[string]string{"b": "3", "c": "4"}, want: map[string]string{"a": "1", "b": "3", "c": "4"}, ok: true},
+		{name: "remove", remove: []string{"a", "missing"}, want: map[string]string{"b": "3", "c": "4"}, ok: true},
+		{name: "remove wins over set", set: map[string]string{"b": "5", "d": "6"}, remove: []string{"b"}, want: map[string]string{"c": "4", "d": "6"}, ok: true},
+		{name: "no changes", want: map[string]string{"c": "4", "d": "6"}, ok: true},
+		{name: "invalid update", set: map[string]string{"e": strings.Repeat("v", MaxFileAttributeSize)}, want: map[string]string{"c": "4", "d": "6"}},
+		{name: "too many after merging", set: manyAttributes(MaxFileAttributes - 1), want: map[string]string{"c": "4", "d": "6"}},
+		{name: "remove all", remove: []string{"c", "d"}, want: nil, ok: true},
+	}
+	for _, tt := range tests {
+		file, err := m.SetFileAttributes("/f", "ns", tt.set, tt.remove)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok %v", tt.name, err, tt.ok)
+		}
+		if tt.ok && !maps.Equal(file.Attributes, tt.want) {
+			t.Errorf("%s: returned %v, want %v", tt.name, file.Attributes, tt.want)
+		}
+		if got := getAttributes(t, m, "ns", "/f"); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: attributes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := getAttributes(t, m, "ns", "/f"); got != nil {
+		t.Errorf("no attributes left, but the map is %#v, want nil", got)
+	}
+
+	// The caller's map is copied
+	set := map[string]string{"k": "v"}
+	if _, err := m.SetFileAttributes("/f", "ns", set, nil); err != nil {
+		t.Fatalf("SetFileAttributes: %v", err)
+	}
+	set["k"] = "changed"
+	if got := getAttributes(t, m, "ns", "/f")["k"]; got != "v" {
+		t.Errorf("attribute changed through the caller's map: %q", got)
+	}
+
+	if _, err := m.SetFileAttributes("/missing", "ns", set, nil); err == nil {
+		t.Error("attributes set on a missing file")
+	}
+	if _, err := m.SetFileAttributes("/f", TrashNamespace, set, nil); !errors.Is(err, ErrReservedNamespace) {
+		t.Errorf("attributes in the trash: err = %v, want ErrReservedNamespace", err)
+	}
+	if _, err := m.CreateFile("/g", "ns", "", map[string]string{"": "v"}); err == nil {
+		t.Error("file created with an invalid attribute")
+	}
+}
+
+// getAttributes returns a file's attributes
+func getAttributes(t *testing.T, m *Master, namespace, path string) map[string]string {
+	t.Helper()
+	file, err := m.GetFile(path, namespace)
+	if err != nil {
+		t.Fatalf("GetFile(%s): %v", path, err)
+	}
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	return file.Attributes
+}
+
+// Attributes stay with a file as it moves, is copied, and is rebuilt after a restart
+func TestAttributesPreserved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	m, err := NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	addTestServer(m, "cs1", "")
+	writeTestChunks(t, m, "ns", "/f", 10)
+	if _, err := m.SetFileAttributes("/f", "ns", map[string]string{"owner": "alice", "tag": "x"}, nil); err != nil {
+		t.Fatalf("SetFileAttributes: %v", err)
+	}
+	want := map[string]string{"owner": "alice", "tag": "x"}
+
+	if err := m.RenameFile("/f", "/renamed", "ns", false); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	if got := getAttributes(t, m, "ns", "/renamed"); !maps.Equal(got, want) {
+		t.Errorf("after rename = %v, want %v", got, want)
+	}
+
+	if _, err := m.SnapshotFile("/renamed", "/copy", "ns", "other"); err != nil {
+		t.Fatalf("SnapshotFile: %v", err)
+	}
+	if got := getAttributes(t, m, "other", "/copy"); !maps.Equal(got, want) {
+		t.Errorf("snapshot copy = %v, want %v", got, want)
+	}
+	// The copy's attributes are its own
+	if _, err := m.SetFileAttributes("/copy", "other", map[string]string{"tag": "y"}, nil); err != nil {
+		t.Fatalf("SetFileAttributes: %v", err)
+	}
+	if got := getAttributes(t, m, "ns", "/renamed"); !maps.Equal(got, want) {
+		t.Errorf("source after changing the copy = %v, want %v", got, want)
+	}
+	wantCopy := map[string]string{"owner": "alice", "tag": "y"}
+
+	if err := m.DeleteFile("/renamed", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if _, err := m.RestoreFile(trashEntry(t, m, "ns", "/renamed").ID, "", ""); err != nil {
+		t.Fatalf("RestoreFile: %v", err)
+	}
+	if got := getAttributes(t, m, "ns", "/renamed"); !maps.Equal(got, want) {
+		t.Errorf("after trash and restore = %v, want %v", got, want)
+	}
+
+	// Part of the history is in a metadata snapshot, the rest in the WAL after it
+	if err := m.TakeSnapshot(); err != nil {
+		t.Fatalf("TakeSnapshot: %v", err)
+	}
+	if _, err := m.SetFileAttributes("/renamed", "ns", map[string]string{"after": "snapshot"}, []string{"tag"}); err != nil {
+		t.Fatalf("SetFileAttributes: %v", err)
+	}
+	want = map[string]string{"owner": "alice", "after": "snapshot"}
+	m.Close()
+
+	m, err = NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster after restart: %v", err)
+	}
+	defer m.Close()
+	if got := getAttributes(t, m, "ns", "/renamed"); !maps.Equal(got, want) {
+		t.Errorf("after replay = %v, want %v", got, want)
+	}
+	if got := getAttributes(t, m, "other", "/copy"); !maps.Equal(got, wantCopy) {
+		t.Errorf("copy after replay = %v, want %v", got, wantCopy)
+	}
+}
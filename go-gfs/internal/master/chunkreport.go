@@ -0,0 +1,56 @@
+package master
+
+import (
+	"log/slog"
+	"time"
+)
+
+// ChunkReport is the chunk list a chunkserver sends with a heartbeat. A full report
+// lists every chunk in Added; otherwise Added and Removed are the changes since the
+// last report the server had acknowledged.
+type ChunkReport struct {
+	Full     bool
+	Added    []ChunkHandle
+	Removed  []ChunkHandle
+	Versions map[string]uint64 // Stored version per added chunk; absent for chunks written before versions
+}
+
+// ApplyChunkReport records a chunkserver's chunk report and returns whether the
+// master wants a full report with the next heartbeat.
+// A full report does not drop locations missing from it: a chunk allocated or
+// copied to the server after its scan is legitimately absent.
+func (m *Master) ApplyChunkReport(serverID ChunkServerID, report ChunkReport) bool {
+	for _, handle := range report.Added {
+		m.ReportChunk(serverID, handle, report.Versions[string(handle)])
+	}
+	if len(report.Removed) > 0 {
+		m.DropRemovedReplicas(serverID, report.Removed)
+	}
+
+	if report.Full {
+		m.csMu.Lock()
+		if loc, ok := m.chunkservers[serverID]; ok {
+			loc.LastFullReport = time.Now()
+		}
+		m.csMu.Unlock()
+		slog.Debug("full chunk report", "serverID", serverID, "chunks", len(report.Added))
+	}
+	return m.takeFullReportRequest(serverID)
+}
+
+// requestFullReport asks a chunkserver to list every chunk with its next heartbeat
+func (m *Master) requestFullReport(serverID ChunkServerID) {
+	m.fullReportRequestsMu.Lock()
+	m.fullReportRequests[serverID] = true
+	m.fullReportRequestsMu.Unlock()
+}
+
+// takeFullReportRequest returns and clears a pending full report request
+func (m *Master) takeFullReportRequest(serverID ChunkServerID) bool {
+	m.fullReportRequestsMu.Lock()
+	defer m.fullReportRequestsMu.Unlock()
+
+	requested := m.fullReportRequests[serverID]
+	delete(m.fullReportRequests, serverID)
+	return requested
+}
@@ -0,0 +1,134 @@
+package master
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+// diskReport lists the chunks on a server's disk, by handle, with their stored versions
+type diskReport map[string]uint64
+
+// incrementalReport is what a chunkserver sends when its disk changed from prev to cur
+func incrementalReport(prev, cur diskReport) ChunkReport {
+	report := ChunkReport{Versions: make(map[string]uint64)}
+	for _, handle := range slices.Sorted(maps.Keys(cur)) {
+		if version, ok := prev[handle]; ok && version == cur[handle] {
+			continue
+		}
+		report.Added = append(report.Added, ChunkHandle(handle))
+		report.Versions[handle] = cur[handle]
+	}
+	for _, handle := range slices.Sorted(maps.Keys(prev)) {
+		if _, ok := cur[handle]; !ok {
+			report.Removed = append(report.Removed, ChunkHandle(handle))
+		}
+	}
+	return report
+}
+
+func fullReport(cur diskReport) ChunkReport {
+	report := incrementalReport(nil, cur)
+	report.Full = true
+	return report
+}
+
+// reportTestMaster has chunks c1 to c5 on cs2 at version 1, and cs1 holding none of them
+func reportTestMaster(t *testing.T) *Master {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2"} {
+		addTestServer(m, id, "")
+	}
+	for _, handle := range []string{"c1", "c2", "c3", "c4", "c5"} {
+		chunk := addTestChunk(m, handle, "cs2")
+		chunk.Primary = &chunk.Locations[0]
+		m.chunkMu.Lock()
+		m.noteCommittedVersionLocked(chunk, 1)
+		chunk.committedAt = time.Now().Add(-VersionReportGrace - time.Second)
+		m.chunkMu.Unlock()
+	}
+	return m
+}
+
+// chunkState is what the master knows about one chunk
+type chunkState struct {
+	servers   []ChunkServerID
+	committed uint64
+}
+
+func chunkStates(m *Master) map[ChunkHandle]chunkState {
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+	states := make(map[ChunkHandle]chunkState)
+	for handle, chunk := range m.chunks {
+		var servers []ChunkServerID
+		for _, loc := range chunk.Locations {
+			servers = append(servers, loc.ServerID)
+		}
+		slices.Sort(servers)
+		states[handle] = chunkState{servers: servers, committed: chunk.CommittedVersion}
+	}
+	return states
+}
+
+func TestIncrementalReportsMatchFullReport(t *testing.T) {
+	disks := []diskReport{
+		{"c1": 1, "c2": 1, "c3": 1},
+		{"c1": 1, "c3": 2, "c4": 1}, // c2 deleted, c3 written at a newer version
+		{"c2": 1, "c3": 2, "c4": 0}, // c1 lost, c2 copied back, c4 without a version
+		{"c2": 1, "c3": 2, "c4": 0, "c5": 1},
+	}
+
+	incremental := reportTestMaster(t)
+	var prev diskReport
+	for i, disk := range disks {
+		if incremental.ApplyChunkReport("cs1", incrementalReport(prev, disk)) {
+			t.Fatalf("report %d: full report requested", i)
+		}
+		prev = disk
+
+		// After every step, the master matches one that only saw a full report
+		full := reportTestMaster(t)
+		full.ApplyChunkReport("cs1", fullReport(disk))
+		got, want := chunkStates(incremental), chunkStates(full)
+		for handle, w := range want {
+			if g := got[handle]; !slices.Equal(g.servers, w.servers) || g.committed != w.committed {
+				t.Errorf("report %d: %s = %+v, want %+v", i, handle, g, w)
+			}
+		}
+	}
+
+	// A full report of the same disk changes nothing
+	before := chunkStates(incremental)
+	incremental.ApplyChunkReport("cs1", fullReport(prev))
+	for handle, state := range chunkStates(incremental) {
+		if !slices.Equal(state.servers, before[handle].servers) {
+			t.Errorf("%s after full report = %v, want %v", handle, state.servers, before[handle].servers)
+		}
+	}
+	if deletes := incremental.GetPendingDeletes("cs1"); len(deletes) != 0 {
+		t.Errorf("deletes queued for reported chunks: %v", deletes)
+	}
+}
+
+// A replica behind the last commit is held back until a full report settles it
+func TestReportBehindCommitRequestsFullReport(t *testing.T) {
+	m := reportTestMaster(t)
+	m.chunkMu.Lock()
+	m.noteCommittedVersionLocked(m.chunks["c1"], 3)
+	m.chunkMu.Unlock()
+
+	if !m.ApplyChunkReport("cs1", incrementalReport(nil, diskReport{"c1": 2})) {
+		t.Error("no full report requested for a replica behind the last commit")
+	}
+	if servers := chunkStates(m)["c1"].servers; slices.Contains(servers, "cs1") {
+		t.Errorf("replica behind the last commit listed: %v", servers)
+	}
+	if m.ApplyChunkReport("cs1", fullReport(diskReport{"c1": 3})) {
+		t.Error("full report requested twice")
+	}
+	if servers := chunkStates(m)["c1"].servers; !slices.Contains(servers, "cs1") {
+		t.Errorf("caught up replica not listed: %v", servers)
+	}
+}
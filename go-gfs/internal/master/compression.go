@@ -0,0 +1,139 @@
+package master
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CompressionZstd stores sealed chunks as zstd frames on the chunkservers
+const CompressionZstd = "zstd"
+
+// maxPendingCompresses caps the compress commands queued for one chunkserver;
+// the rest wait for later rounds
+const maxPendingCompresses = 64
+
+// compressibleChunk is a sealed chunk of a compressed namespace
+type compressibleChunk struct {
+	handle ChunkHandle
+	live   []ChunkServerID
+}
+
+// validateCompression accepts the known compression formats, and empty for raw
+func validateCompression(compression string) error {
+	switch compression {
+	case "", CompressionZstd:
+		return nil
+	}
+	return fmt.Errorf("unknown compression: %s", compression)
+}
+
+// SetCompression sets how the sealed chunks of a namespace are stored. Chunks are
+// compressed by their chunkservers once they take no more writes, and read back
+// decompressed. Empty stores new chunks raw; chunks already compressed stay compressed
+func (m *Master) SetCompression(namespace, compression string) error {
+	if err := validateCompression(compression); err != nil {
+		return err
+	}
+	namespace = normalizeNamespace(namespace)
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogSetCompression(namespace, compression); err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	m.replaySetCompression(namespace, compression)
+
+	slog.Info("set compression", "namespace", namespace, "compression", compression)
+	return nil
+}
+
+// replaySetCompression sets a namespace's compression from WAL (no WAL logging)
+func (m *Master) replaySetCompression(namespace, compression string) {
+	namespace = normalizeNamespace(namespace)
+	if compression == "" {
+		delete(m.compression, namespace)
+		return
+	}
+	m.compression[namespace] = compression
+}
+
+// findCompressible returns sealed chunks of files in compressed namespaces that
+// have live replicas. Cold files are skipped; their chunks are erasure coded instead
+func (m *Master) findCompressible(live map[ChunkServerID]ChunkLocation) []compressibleChunk {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+
+	if len(m.compression) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	seen := make(map[ChunkHandle]bool)
+	var candidates []compressibleChunk
+	for _, file := range m.files {
+		if m.compression[normalizeNamespace(file.Namespace)] == "" || m.storageClassLocked(file) == StorageClassCold {
+			continue
+		}
+		for i, handle := range file.Chunks {
+			chunk, ok := m.chunks[handle]
+			if !ok || seen[handle] || chunk.encoding || !m.quietLocked(file, i, chunk, now) {
+				continue
+			}
+			seen[handle] = true
+			c := compressibleChunk{handle: handle}
+			for _, loc := range chunk.Locations {
+				if _, ok := live[loc.ServerID]; ok {
+					c.live = append(c.live, loc.ServerID)
+				}
+			}
+			if len(c.live) > 0 {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// scheduleCompressesLocked asks each live replica of a compressible chunk to compress
+// it, once for as long as the chunk stays sealed. A chunk written to again is asked
+// for again when it next seals, since writes restore it to a raw file.
+// Returns how many commands were queued
+// Must be called with replicationMu held
+func (m *Master) scheduleCompressesLocked(compressible []compressibleChunk) int {
+	sent := make(map[ChunkHandle]map[ChunkServerID]bool, len(compressible))
+	scheduled := 0
+	for _, c := range compressible {
+		previous := m.compressSent[c.handle]
+		servers := make(map[ChunkServerID]bool, len(c.live))
+		for _, id := range c.live {
+			if previous[id] {
+				servers[id] = true
+				continue
+			}
+			if len(m.pendingCompresses[id]) >= maxPendingCompresses {
+				continue
+			}
+			m.pendingCompresses[id] = append(m.pendingCompresses[id], c.handle)
+			servers[id] = true
+			scheduled++
+		}
+		sent[c.handle] = servers
+	}
+	m.compressSent = sent
+	return scheduled
+}
+
+// GetPendingCompresses returns and clears the compress commands queued for a chunkserver
+func (m *Master) GetPendingCompresses(id ChunkServerID) []ChunkHandle {
+	m.replicationMu.Lock()
+	defer m.replicationMu.Unlock()
+
+	handles := m.pendingCompresses[id]
+	delete(m.pendingCompresses, id)
+	return handles
+}
@@ -0,0 +1,476 @@
+// Package consensus replicates master metadata changes across master replicas with Raft.
+//
+// Every replica runs a Node. The leader proposes WAL entries, and every replica applies
+// them in log order once a majority has persisted them. Clients and chunkservers talk
+// only to the leader; followers stay warm so one can take over when the leader is lost.
+package consensus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/mtls"
+	"go.etcd.io/raft/v3"
+	"go.etcd.io/raft/v3/raftpb"
+)
+
+// Raft timing: a replica that hears nothing from the leader for ElectionTicks ticks starts an election
+const (
+	TickInterval   = 100 * time.Millisecond
+	ElectionTicks  = 10
+	HeartbeatTicks = 1
+)
+
+// MaxMessageSize bounds a single consensus message, which may carry a full metadata snapshot
+const MaxMessageSize = 256 << 20
+
+// snapshotCatchUpEntries are kept after compaction so briefly lagging replicas don't need a snapshot
+const snapshotCatchUpEntries = 1000
+
+// proposalHeaderSize is the origin replica ID and proposal ID prepended to every entry
+const proposalHeaderSize = 16
+
+var (
+	// ErrNotLeader means the proposal was made on a replica that is not the leader
+	ErrNotLeader = errors.New("not the leader")
+	// ErrStopped means the node shut down before the proposal committed
+	ErrStopped = errors.New("consensus node stopped")
+	// ErrInterrupted means the proposal stopped waiting so an entry no caller on this
+	// replica applies could be applied first. It may still commit, and is then applied
+	// like an entry proposed elsewhere.
+	ErrInterrupted = errors.New("proposal interrupted by another entry")
+)
+
+// StateMachine receives committed entries in log order
+type StateMachine interface {
+	// Apply is called for every committed entry. local is true when a caller on this
+	// replica proposed the entry with Propose and is waiting to apply it to memory
+	// itself; no such caller is waiting when local is false, so Apply may take the
+	// state those callers hold while they wait.
+	Apply(index uint64, data []byte, local bool)
+	// Restore replaces the state with a snapshot taken at index
+	Restore(index uint64, data []byte) error
+}
+
+// Config describes this replica and its peers
+type Config struct {
+	ID      uint64            // This replica's ID, non-zero
+	Peers   map[uint64]string // Replica ID -> gRPC address, including this replica
+	DataDir string            // Directory for the raft log and snapshot
+	TLS     *mtls.Source      // Credentials for dialing peers; nil is plaintext
+}
+
+// Node is one master replica's membership in the consensus group
+type Node struct {
+	id    uint64
+	peers map[uint64]string
+	tls   *mtls.Source
+
+	node      raft.Node
+	storage   *Storage
+	transport *transport
+	sm        StateMachine
+
+	confState   raftpb.ConfState
+	confStateMu sync.Mutex
+
+	applied    atomic.Uint64
+	lead       atomic.Uint64
+	readyIndex atomic.Uint64 // First index of the current leader term; reads wait for it
+
+	nextProposal atomic.Uint64
+	waiters      map[uint64]*waiter
+	waitersMu    sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// waiter is a caller waiting for its proposal to be applied
+type waiter struct {
+	done    chan error // Receives nil once the entry is handed over, or why it never will be
+	applies bool       // The caller applies the entry to memory itself (Propose)
+	index   uint64     // Log index of the entry, set before it is handed over
+}
+
+// ParsePeers parses "id=host:port,id=host:port" into a peer map
+func ParsePeers(spec string) (map[uint64]string, error) {
+	peers := make(map[uint64]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		idStr, addr, ok := strings.Cut(part, "=")
+		if !ok || addr == "" {
+			return nil, fmt.Errorf("invalid peer %q, expected id=host:port", part)
+		}
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid peer ID %q", idStr)
+		}
+		if _, dup := peers[id]; dup {
+			return nil, fmt.Errorf("duplicate peer ID %d", id)
+		}
+		peers[id] = addr
+	}
+	if len(peers) == 0 {
+		return nil, errors.New("no peers given")
+	}
+	return peers, nil
+}
+
+// Start opens the raft storage in cfg.DataDir and joins the group.
+// applied is the last log index already reflected in the state machine (from its own WAL and snapshot).
+func Start(cfg Config, sm StateMachine, applied uint64) (*Node, error) {
+	if cfg.ID == 0 {
+		return nil, errors.New("replica ID must be non-zero")
+	}
+	if _, ok := cfg.Peers[cfg.ID]; !ok {
+		return nil, fmt.Errorf("replica %d is not in the peer list", cfg.ID)
+	}
+
+	raft.SetLogger(slogLogger{})
+
+	storage, err := OpenStorage(cfg.DataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		id:      cfg.ID,
+		peers:   cfg.Peers,
+		tls:     cfg.TLS,
+		storage: storage,
+		sm:      sm,
+		waiters: make(map[uint64]*waiter),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	// Proposal IDs only need to be unique per replica lifetime; seed from the clock
+	// so entries proposed before a restart never match a new waiter
+	n.nextProposal.Store(uint64(time.Now().UnixNano()))
+
+	// Catch the state machine up to the raft snapshot if it is behind
+	snap, err := storage.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if !raft.IsEmptySnap(snap) {
+		if snap.Metadata.Index > applied {
+			if err := sm.Restore(snap.Metadata.Index, snap.Data); err != nil {
+				return nil, fmt.Errorf("failed to restore raft snapshot: %w", err)
+			}
+			applied = snap.Metadata.Index
+		}
+	}
+	hs, cs, _ := storage.InitialState()
+	n.confState = cs
+	if applied > hs.Commit {
+		slog.Warn("state machine is ahead of raft commit index, replaying from commit", "applied", applied, "commit", hs.Commit)
+		applied = hs.Commit
+	}
+	n.applied.Store(applied)
+
+	c := &raft.Config{
+		ID:              cfg.ID,
+		ElectionTick:    ElectionTicks,
+		HeartbeatTick:   HeartbeatTicks,
+		Storage:         storage,
+		Applied:         applied,
+		MaxSizePerMsg:   1 << 20,
+		MaxInflightMsgs: 256,
+		CheckQuorum:     true,
+		PreVote:         true,
+	}
+
+	if storage.IsEmpty() {
+		peers := make([]raft.Peer, 0, len(cfg.Peers))
+		for id := range cfg.Peers {
+			peers = append(peers, raft.Peer{ID: id})
+		}
+		sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+		n.node = raft.StartNode(c, peers)
+		slog.Info("bootstrapped consensus group", "id", cfg.ID, "peers", len(peers))
+	} else {
+		n.node = raft.RestartNode(c)
+		slog.Info("rejoined consensus group", "id", cfg.ID, "applied", applied, "commit", hs.Commit)
+	}
+
+	n.transport = newTransport(n)
+	go n.run()
+	return n, nil
+}
+
+// Stop leaves the group and closes storage
+func (n *Node) Stop() {
+	close(n.stop)
+	<-n.done
+	n.transport.close()
+	n.storage.Close()
+}
+
+// ID returns this replica's ID
+func (n *Node) ID() uint64 {
+	return n.id
+}
+
+// Peers returns the replica ID -> address map
+func (n *Node) Peers() map[uint64]string {
+	return n.peers
+}
+
+// Leader returns the current leader's ID and address, or 0 and "" during an election
+func (n *Node) Leader() (uint64, string) {
+	lead := n.lead.Load()
+	return lead, n.peers[lead]
+}
+
+// IsLeader reports whether this replica is the leader and has applied every earlier entry,
+// so its in-memory state is current
+func (n *Node) IsLeader() bool {
+	return n.lead.Load() == n.id && n.applied.Load() >= n.readyIndex.Load()
+}
+
+// Propose replicates data and waits until it is committed, for the caller to apply it
+// to memory itself: the state machine is called with local set. Callers may hold state
+// that Apply takes for other entries; the proposal fails with ErrInterrupted rather than
+// wait on it. Only a leader that has applied every earlier entry proposes, since the
+// caller checked its change against memory. Returns the entry's log index.
+func (n *Node) Propose(ctx context.Context, data []byte) (uint64, error) {
+	return n.submit(ctx, data, true)
+}
+
+// Submit replicates data from any replica and waits until the state machine has applied
+// it; followers forward the entry to the leader. Callers must not hold state Apply takes.
+func (n *Node) Submit(ctx context.Context, data []byte) error {
+	_, err := n.submit(ctx, data, false)
+	return err
+}
+
+func (n *Node) submit(ctx context.Context, data []byte, applies bool) (uint64, error) {
+	id := n.nextProposal.Add(1)
+	buf := make([]byte, proposalHeaderSize+len(data))
+	binary.BigEndian.PutUint64(buf[0:8], n.id)
+	binary.BigEndian.PutUint64(buf[8:16], id)
+	copy(buf[proposalHeaderSize:], data)
+
+	// Checked under the lock interrupt takes, so a proposal either waits where
+	// interrupt finds it or is refused until the interrupting entry is applied
+	w := &waiter{done: make(chan error, 1), applies: applies}
+	n.waitersMu.Lock()
+	if applies && !n.IsLeader() {
+		n.waitersMu.Unlock()
+		return 0, ErrNotLeader
+	}
+	n.waiters[id] = w
+	n.waitersMu.Unlock()
+
+	if err := n.node.Propose(ctx, buf); err != nil {
+		n.forget(id)
+		return 0, err
+	}
+
+	var err error
+	select {
+	case err = <-w.done:
+	case <-ctx.Done():
+		if n.forget(id) {
+			return 0, ctx.Err()
+		}
+		// The entry was handed over as the wait ended; the caller has to apply it
+		err = <-w.done
+	case <-n.stop:
+		if n.forget(id) {
+			return 0, ErrStopped
+		}
+		err = <-w.done
+	}
+	if err != nil {
+		return 0, err
+	}
+	return w.index, nil
+}
+
+// forget stops waiting for proposal id, reporting false if it was already handed over
+func (n *Node) forget(id uint64) bool {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+	_, ok := n.waiters[id]
+	delete(n.waiters, id)
+	return ok
+}
+
+// claim removes the waiter for an entry proposed on this replica, if any
+func (n *Node) claim(origin, id uint64) *waiter {
+	if origin != n.id {
+		return nil
+	}
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+	w := n.waiters[id]
+	delete(n.waiters, id)
+	return w
+}
+
+// interrupt fails the proposals whose callers apply their own entries, before the state
+// machine is given an entry none of them will apply: they may hold what it needs.
+// New proposals wait until the entries already in the log are applied, since
+// ones from interrupted callers are among them.
+func (n *Node) interrupt(index uint64) {
+	n.waitersMu.Lock()
+	defer n.waitersMu.Unlock()
+
+	last, _ := n.storage.LastIndex()
+	if ready := max(index, last); ready > n.readyIndex.Load() {
+		n.readyIndex.Store(ready)
+	}
+	for id, w := range n.waiters {
+		if w.applies {
+			delete(n.waiters, id)
+			w.done <- ErrInterrupted
+		}
+	}
+}
+
+// Compact records a state machine snapshot covering index and trims the raft log behind it
+func (n *Node) Compact(index uint64, data []byte) error {
+	n.confStateMu.Lock()
+	cs := n.confState
+	n.confStateMu.Unlock()
+	return n.storage.Compact(index, cs, data, snapshotCatchUpEntries)
+}
+
+// step delivers a message received from a peer
+func (n *Node) step(ctx context.Context, msg raftpb.Message) error {
+	return n.node.Step(ctx, msg)
+}
+
+// run drives raft: ticks, persistence, message sending and applying committed entries
+func (n *Node) run() {
+	defer close(n.done)
+	ticker := time.NewTicker(TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			n.node.Stop()
+			return
+
+		case <-ticker.C:
+			n.node.Tick()
+
+		case rd := <-n.node.Ready():
+			if err := n.storage.Save(rd.HardState, rd.Entries, rd.Snapshot); err != nil {
+				// Acknowledging entries that aren't durable would break the group's guarantees
+				slog.Error("failed to persist raft state, exiting", "error", err)
+				os.Exit(1)
+			}
+			if rd.SoftState != nil {
+				n.updateLeader(rd.SoftState.Lead)
+			}
+
+			if !raft.IsEmptySnap(rd.Snapshot) {
+				n.interrupt(rd.Snapshot.Metadata.Index)
+				if err := n.sm.Restore(rd.Snapshot.Metadata.Index, rd.Snapshot.Data); err != nil {
+					slog.Error("failed to restore snapshot from leader, exiting", "error", err)
+					os.Exit(1)
+				}
+				n.confStateMu.Lock()
+				n.confState = rd.Snapshot.Metadata.ConfState
+				n.confStateMu.Unlock()
+				n.applied.Store(rd.Snapshot.Metadata.Index)
+				slog.Info("installed snapshot from leader", "index", rd.Snapshot.Metadata.Index)
+			}
+
+			n.transport.send(rd.Messages)
+			n.applyEntries(rd.CommittedEntries)
+			n.node.Advance()
+		}
+	}
+}
+
+// updateLeader tracks leadership changes
+func (n *Node) updateLeader(lead uint64) {
+	prev := n.lead.Load()
+	if lead == prev {
+		return
+	}
+
+	if lead == n.id {
+		// The entry raft appends on election is the last in the log; once it is applied
+		// every entry from earlier terms has been applied too
+		last, _ := n.storage.LastIndex()
+		n.readyIndex.Store(last)
+	}
+	n.lead.Store(lead)
+
+	switch {
+	case lead == 0:
+		slog.Warn("lost master leader, election in progress", "id", n.id)
+	case lead == n.id:
+		slog.Info("became master leader", "id", n.id)
+	default:
+		slog.Info("following master leader", "id", n.id, "leader", lead, "address", n.peers[lead])
+	}
+}
+
+// applyEntries hands committed entries to the state machine and wakes their proposers
+func (n *Node) applyEntries(entries []raftpb.Entry) {
+	for _, e := range entries {
+		if e.Index <= n.applied.Load() {
+			continue
+		}
+
+		switch e.Type {
+		case raftpb.EntryNormal:
+			// Empty entries are appended by new leaders. Proposals from earlier terms
+			// that aren't committed by then never will be.
+			if len(e.Data) < proposalHeaderSize {
+				n.interrupt(e.Index)
+			} else {
+				origin := binary.BigEndian.Uint64(e.Data[0:8])
+				id := binary.BigEndian.Uint64(e.Data[8:16])
+
+				w := n.claim(origin, id)
+				local := w != nil && w.applies
+				if !local {
+					n.interrupt(e.Index)
+				}
+				n.sm.Apply(e.Index, e.Data[proposalHeaderSize:], local)
+				if w != nil {
+					w.index = e.Index
+					w.done <- nil
+				}
+			}
+
+		case raftpb.EntryConfChange:
+			var cc raftpb.ConfChange
+			if err := cc.Unmarshal(e.Data); err != nil {
+				slog.Error("failed to decode membership change", "index", e.Index, "error", err)
+				break
+			}
+			cs := n.node.ApplyConfChange(cc)
+			n.confStateMu.Lock()
+			n.confState = *cs
+			n.confStateMu.Unlock()
+			if err := n.storage.SetConfState(*cs); err != nil {
+				slog.Error("failed to persist membership, exiting", "error", err)
+				os.Exit(1)
+			}
+		}
+
+		n.applied.Store(e.Index)
+	}
+}
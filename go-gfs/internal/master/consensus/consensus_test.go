@@ -0,0 +1,303 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"google.golang.org/grpc"
+)
+
+// testMachine applies entries the way the master does: proposers hold mu from
+// Propose until they have applied their own entry, and Apply takes mu for the rest
+type testMachine struct {
+	mu      sync.Mutex
+	applied []string
+}
+
+func (s *testMachine) Apply(index uint64, data []byte, local bool) {
+	if local {
+		return
+	}
+	s.mu.Lock()
+	s.applied = append(s.applied, string(data))
+	s.mu.Unlock()
+}
+
+func (s *testMachine) Restore(index uint64, data []byte) error {
+	return errors.New("unexpected snapshot")
+}
+
+// propose proposes data under mu and applies it once it commits
+func (s *testMachine) propose(ctx context.Context, n *Node, data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := n.Propose(ctx, []byte(data)); err != nil {
+		return err
+	}
+	s.applied = append(s.applied, data)
+	return nil
+}
+
+func (s *testMachine) snapshot() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.applied)
+}
+
+type testReplica struct {
+	node *Node
+	sm   *testMachine
+}
+
+// startTestGroup starts a consensus group of size replicas on loopback ports
+func startTestGroup(t *testing.T, size int) []*testReplica {
+	t.Helper()
+	peers := make(map[uint64]string, size)
+	listeners := make([]net.Listener, size)
+	for i := range listeners {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		listeners[i] = lis
+		peers[uint64(i+1)] = lis.Addr().String()
+	}
+
+	replicas := make([]*testReplica, size)
+	for i, lis := range listeners {
+		sm := &testMachine{}
+		node, err := Start(Config{ID: uint64(i + 1), Peers: peers, DataDir: t.TempDir()}, sm, 0)
+		if err != nil {
+			t.Fatalf("Start replica %d: %v", i+1, err)
+		}
+		srv := grpc.NewServer()
+		pb.RegisterMasterPeerServer(srv, NewPeerServer(node))
+		go srv.Serve(lis)
+		t.Cleanup(func() {
+			srv.Stop()
+			node.Stop()
+		})
+		replicas[i] = &testReplica{node: node, sm: sm}
+	}
+	return replicas
+}
+
+// waitForLeader returns the replica that leads and has caught up
+func waitForLeader(t *testing.T, replicas []*testReplica) *testReplica {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, r := range replicas {
+			if r.node.IsLeader() {
+				return r
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no leader elected")
+	return nil
+}
+
+// transferLeadership moves leadership from leader to target and waits for target to catch up
+func transferLeadership(t *testing.T, leader, target *testReplica) {
+	t.Helper()
+	leader.node.node.TransferLeadership(context.Background(), leader.node.id, target.node.id)
+	deadline := time.Now().Add(10 * time.Second)
+	for !target.node.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica %d did not take over leadership", target.node.id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestProposersAcrossLeaderChange has a follower submit an entry while a proposer on
+// the leader holds the state Apply needs for it, then proposes on the leader. Neither
+// may wait on the other, before or after leadership moves to the follower.
+func TestProposersAcrossLeaderChange(t *testing.T) {
+	const maxWait = 5 * time.Second
+	replicas := startTestGroup(t, 3)
+	leader := waitForLeader(t, replicas)
+
+	for round := range 2 {
+		var follower *testReplica
+		for _, r := range replicas {
+			if r != leader {
+				follower = r
+				break
+			}
+		}
+
+		leader.sm.mu.Lock()
+		submitted := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			submitted <- follower.node.Submit(ctx, []byte(fmt.Sprintf("forwarded-%d", round)))
+		}()
+		// Let the forwarded entry commit and reach the leader's apply loop
+		time.Sleep(300 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		start := time.Now()
+		data := fmt.Sprintf("local-%d", round)
+		_, err := leader.node.Propose(ctx, []byte(data))
+		if err == nil {
+			leader.sm.applied = append(leader.sm.applied, data)
+		}
+		leader.sm.mu.Unlock()
+		cancel()
+		if elapsed := time.Since(start); elapsed > maxWait {
+			t.Fatalf("round %d: proposal behind a forwarded entry waited %v (err %v)", round, elapsed, err)
+		}
+
+		select {
+		case err := <-submitted:
+			if err != nil {
+				t.Fatalf("round %d: Submit: %v", round, err)
+			}
+		case <-time.After(maxWait):
+			t.Fatalf("round %d: forwarded entry not applied", round)
+		}
+
+		// Once caught up, the leader takes proposals again
+		waitForLeader(t, replicas)
+		if err := leader.sm.propose(context.Background(), leader.node, fmt.Sprintf("after-%d", round)); err != nil {
+			t.Fatalf("round %d: Propose after catching up: %v", round, err)
+		}
+
+		transferLeadership(t, leader, follower)
+		leader = follower
+	}
+
+	logs := waitForAgreement(t, replicas)
+	for _, want := range []string{"forwarded-0", "after-0", "forwarded-1", "after-1"} {
+		if !slices.Contains(logs, want) {
+			t.Errorf("%s missing from applied entries %v", want, logs)
+		}
+	}
+}
+
+// waitForAgreement waits for every replica to have applied the same entries in
+// the same order and returns them
+func waitForAgreement(t *testing.T, replicas []*testReplica) []string {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		first := replicas[0].sm.snapshot()
+		agreed := true
+		for _, r := range replicas[1:] {
+			if !slices.Equal(r.sm.snapshot(), first) {
+				agreed = false
+			}
+		}
+		if agreed {
+			return first
+		}
+		if time.Now().After(deadline) {
+			for _, r := range replicas {
+				t.Logf("replica %d applied %v", r.node.id, r.sm.snapshot())
+			}
+			t.Fatal("replicas applied different entries")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestConcurrentProposalsConverge runs two proposers on every replica while
+// leadership moves around. A proposer waiting under mu must never keep its
+// replica from applying entries proposed elsewhere, and every replica must end
+// up with the same entries in the same order.
+func TestConcurrentProposalsConverge(t *testing.T) {
+	replicas := startTestGroup(t, 3)
+	waitForLeader(t, replicas)
+
+	// Well under the proposal timeout: a proposal stuck behind another entry's apply waits out the full timeout
+	const (
+		proposalTimeout = 30 * time.Second
+		maxWait         = 5 * time.Second
+	)
+
+	var (
+		mu        sync.Mutex
+		committed []string
+		failures  []string
+	)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, r := range replicas {
+		for p := range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for k := 0; ; k++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					data := fmt.Sprintf("r%d-p%d-%d", i+1, p, k)
+					ctx, cancel := context.WithTimeout(context.Background(), proposalTimeout)
+					start := time.Now()
+					err := r.sm.propose(ctx, r.node, data)
+					elapsed := time.Since(start)
+					cancel()
+
+					mu.Lock()
+					if elapsed > maxWait {
+						failures = append(failures, fmt.Sprintf("%s waited %v (err %v)", data, elapsed, err))
+					}
+					if err == nil {
+						committed = append(committed, data)
+					}
+					mu.Unlock()
+					if err != nil {
+						time.Sleep(5 * time.Millisecond)
+					}
+				}
+			}()
+		}
+	}
+
+	for range 4 {
+		time.Sleep(300 * time.Millisecond)
+		leader := waitForLeader(t, replicas)
+		target := replicas[(int(leader.node.id))%len(replicas)]
+		transferLeadership(t, leader, target)
+	}
+	time.Sleep(300 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	for _, f := range failures {
+		t.Error(f)
+	}
+	if len(committed) == 0 {
+		t.Fatal("no proposal committed")
+	}
+
+	// Interrupted proposals may still commit, so only wait for the replicas to agree
+	logs := waitForAgreement(t, replicas)
+
+	seen := make(map[string]int, len(logs))
+	for _, data := range logs {
+		seen[data]++
+	}
+	for data, count := range seen {
+		if count > 1 {
+			t.Errorf("%s applied %d times", data, count)
+		}
+	}
+	for _, data := range committed {
+		if seen[data] == 0 {
+			t.Errorf("%s committed but not applied", data)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package consensus
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// slogLogger routes raft's internal logging through slog
+type slogLogger struct{}
+
+func (slogLogger) Debug(v ...interface{}) { slog.Debug(fmt.Sprint(v...), "component", "raft") }
+func (slogLogger) Debugf(format string, v ...interface{}) {
+	slog.Debug(fmt.Sprintf(format, v...), "component", "raft")
+}
+func (slogLogger) Info(v ...interface{}) { slog.Info(fmt.Sprint(v...), "component", "raft") }
+func (slogLogger) Infof(format string, v ...interface{}) {
+	slog.Info(fmt.Sprintf(format, v...), "component", "raft")
+}
+func (slogLogger) Warning(v ...interface{}) { slog.Warn(fmt.Sprint(v...), "component", "raft") }
+func (slogLogger) Warningf(format string, v ...interface{}) {
+	slog.Warn(fmt.Sprintf(format, v...), "component", "raft")
+}
+func (slogLogger) Error(v ...interface{}) { slog.Error(fmt.Sprint(v...), "component", "raft") }
+func (slogLogger) Errorf(format string, v ...interface{}) {
+	slog.Error(fmt.Sprintf(format, v...), "component", "raft")
+}
+
+func (slogLogger) Fatal(v ...interface{}) {
+	slog.Error(fmt.Sprint(v...), "component", "raft")
+	os.Exit(1)
+}
+
+func (slogLogger) Fatalf(format string, v ...interface{}) {
+	slog.Error(fmt.Sprintf(format, v...), "component", "raft")
+	os.Exit(1)
+}
+
+func (slogLogger) Panic(v ...interface{})                 { panic(fmt.Sprint(v...)) }
+func (slogLogger) Panicf(format string, v ...interface{}) { panic(fmt.Sprintf(format, v...)) }
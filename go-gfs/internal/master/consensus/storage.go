@@ -0,0 +1,444 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.etcd.io/raft/v3"
+	"go.etcd.io/raft/v3/raftpb"
+)
+
+// Record types in the raft log file
+const (
+	recordEntry     byte = 1
+	recordHardState byte = 2
+	recordConfState byte = 3
+)
+
+// recordHeaderSize is type + payload length + CRC32
+const recordHeaderSize = 9
+
+// maxRecordSize bounds a single record so a damaged length can't force a huge allocation
+const maxRecordSize = MaxMessageSize
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Storage is a raft.MemoryStorage backed by an append-only log file and a snapshot file.
+// Entries and hard state are synced before raft is told they are stable.
+type Storage struct {
+	*raft.MemoryStorage
+
+	dir  string
+	file *os.File
+	mu   sync.Mutex
+
+	// Membership is only in the snapshot for MemoryStorage; it is also logged
+	// here so a restart before the first snapshot still knows the voters
+	confState raftpb.ConfState
+}
+
+// OpenStorage loads raft state from dir, creating it if needed
+func OpenStorage(dir string) (*Storage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create raft directory: %w", err)
+	}
+
+	s := &Storage{
+		MemoryStorage: raft.NewMemoryStorage(),
+		dir:           dir,
+	}
+
+	snap, err := s.readSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if !raft.IsEmptySnap(snap) {
+		if err := s.MemoryStorage.ApplySnapshot(snap); err != nil {
+			return nil, fmt.Errorf("failed to load raft snapshot: %w", err)
+		}
+	}
+
+	s.confState = snap.Metadata.ConfState
+
+	entries, hs, cs, err := s.readLog()
+	if err != nil {
+		return nil, err
+	}
+	if cs != nil {
+		s.confState = *cs
+	}
+	if err := s.MemoryStorage.Append(entries); err != nil {
+		return nil, fmt.Errorf("failed to load raft entries: %w", err)
+	}
+	if !raft.IsEmptyHardState(hs) {
+		if err := s.MemoryStorage.SetHardState(hs); err != nil {
+			return nil, fmt.Errorf("failed to load raft hard state: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(s.logPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft log: %w", err)
+	}
+	s.file = file
+
+	slog.Info("raft storage loaded", "dir", dir, "snapshotIndex", snap.Metadata.Index, "entries", len(entries), "commit", hs.Commit)
+	return s, nil
+}
+
+func (s *Storage) logPath() string  { return filepath.Join(s.dir, "raft.log") }
+func (s *Storage) snapPath() string { return filepath.Join(s.dir, "raft.snap") }
+
+// InitialState returns the saved hard state and membership
+func (s *Storage) InitialState() (raftpb.HardState, raftpb.ConfState, error) {
+	hs, _, err := s.MemoryStorage.InitialState()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return hs, s.confState, err
+}
+
+// SetConfState persists membership after a configuration change is applied
+func (s *Storage) SetConfState(cs raftpb.ConfState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := cs.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal conf state: %w", err)
+	}
+	if err := writeRecord(s.file, recordConfState, data); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync raft log: %w", err)
+	}
+	s.confState = cs
+	return nil
+}
+
+// IsEmpty reports whether the storage holds no raft state (first boot of this replica)
+func (s *Storage) IsEmpty() bool {
+	hs, _, _ := s.MemoryStorage.InitialState()
+	last, _ := s.MemoryStorage.LastIndex()
+	return raft.IsEmptyHardState(hs) && last == 0
+}
+
+// Close closes the raft log file
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// Save persists the output of a raft Ready before it is acted on
+func (s *Storage) Save(hs raftpb.HardState, entries []raftpb.Entry, snap raftpb.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !raft.IsEmptySnap(snap) {
+		if err := s.writeSnapshot(snap); err != nil {
+			return err
+		}
+		if err := s.MemoryStorage.ApplySnapshot(snap); err != nil {
+			return err
+		}
+		s.confState = snap.Metadata.ConfState
+		// Everything in the log file predates the snapshot
+		if err := s.rewriteLocked(); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(s.file)
+	for i := range entries {
+		data, err := entries[i].Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal raft entry: %w", err)
+		}
+		if err := writeRecord(w, recordEntry, data); err != nil {
+			return err
+		}
+	}
+	if !raft.IsEmptyHardState(hs) {
+		data, err := hs.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal hard state: %w", err)
+		}
+		if err := writeRecord(w, recordHardState, data); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write raft log: %w", err)
+	}
+	if len(entries) > 0 || !raft.IsEmptyHardState(hs) {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync raft log: %w", err)
+		}
+	}
+
+	if err := s.MemoryStorage.Append(entries); err != nil {
+		return err
+	}
+	if !raft.IsEmptyHardState(hs) {
+		return s.MemoryStorage.SetHardState(hs)
+	}
+	return nil
+}
+
+// Compact records a state machine snapshot at index and discards log entries before it,
+// keeping the last keep entries so slightly lagging replicas can catch up without a snapshot
+func (s *Storage) Compact(index uint64, cs raftpb.ConfState, data []byte, keep uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.MemoryStorage.CreateSnapshot(index, &cs, data)
+	if errors.Is(err, raft.ErrSnapOutOfDate) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create raft snapshot: %w", err)
+	}
+	if err := s.writeSnapshot(snap); err != nil {
+		return err
+	}
+
+	if index > keep {
+		first, _ := s.MemoryStorage.FirstIndex()
+		if compactIndex := index - keep; compactIndex > first {
+			if err := s.MemoryStorage.Compact(compactIndex); err != nil && !errors.Is(err, raft.ErrCompacted) {
+				return fmt.Errorf("failed to compact raft log: %w", err)
+			}
+		}
+	}
+
+	return s.rewriteLocked()
+}
+
+// rewriteLocked replaces the log file with the entries and hard state currently in memory
+// Must be called with mu held
+func (s *Storage) rewriteLocked() error {
+	first, _ := s.MemoryStorage.FirstIndex()
+	last, _ := s.MemoryStorage.LastIndex()
+	var entries []raftpb.Entry
+	if last >= first {
+		var err error
+		entries, err = s.MemoryStorage.Entries(first, last+1, ^uint64(0))
+		if err != nil {
+			return fmt.Errorf("failed to read raft entries: %w", err)
+		}
+	}
+	hs, _, _ := s.MemoryStorage.InitialState()
+
+	tempPath := s.logPath() + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create raft log: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	for i := range entries {
+		data, err := entries[i].Marshal()
+		if err == nil {
+			err = writeRecord(w, recordEntry, data)
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(tempPath)
+			return err
+		}
+	}
+	if !raft.IsEmptyHardState(hs) {
+		data, err := hs.Marshal()
+		if err == nil {
+			err = writeRecord(w, recordHardState, data)
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(tempPath)
+			return err
+		}
+	}
+	data, err := s.confState.Marshal()
+	if err == nil {
+		err = writeRecord(w, recordConfState, data)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write raft log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync raft log: %w", err)
+	}
+	if err := os.Rename(tempPath, s.logPath()); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename raft log: %w", err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = file
+	return nil
+}
+
+// writeSnapshot atomically replaces the snapshot file
+func (s *Storage) writeSnapshot(snap raftpb.Snapshot) error {
+	data, err := snap.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal raft snapshot: %w", err)
+	}
+
+	tempPath := s.snapPath() + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create raft snapshot: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write raft snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync raft snapshot: %w", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tempPath, s.snapPath()); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename raft snapshot: %w", err)
+	}
+	return nil
+}
+
+// readSnapshot loads the snapshot file, returning an empty snapshot if there is none
+func (s *Storage) readSnapshot() (raftpb.Snapshot, error) {
+	var snap raftpb.Snapshot
+	data, err := os.ReadFile(s.snapPath())
+	if os.IsNotExist(err) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, fmt.Errorf("failed to read raft snapshot: %w", err)
+	}
+	if err := snap.Unmarshal(data); err != nil {
+		return snap, fmt.Errorf("failed to decode raft snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// readLog loads entries and the latest hard state and membership from the log file.
+// A torn or corrupt record ends the log; everything after it is discarded.
+func (s *Storage) readLog() ([]raftpb.Entry, raftpb.HardState, *raftpb.ConfState, error) {
+	var hs raftpb.HardState
+	var cs *raftpb.ConfState
+	file, err := os.Open(s.logPath())
+	if os.IsNotExist(err) {
+		return nil, hs, nil, nil
+	}
+	if err != nil {
+		return nil, hs, nil, fmt.Errorf("failed to open raft log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []raftpb.Entry
+	r := bufio.NewReader(file)
+	var valid int64
+	for {
+		typ, data, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			slog.Warn("truncating raft log at damaged record", "offset", valid, "error", err)
+			if err := os.Truncate(s.logPath(), valid); err != nil {
+				return nil, hs, nil, fmt.Errorf("failed to truncate raft log: %w", err)
+			}
+			break
+		}
+		valid += int64(recordHeaderSize + len(data))
+
+		switch typ {
+		case recordEntry:
+			var e raftpb.Entry
+			if err := e.Unmarshal(data); err != nil {
+				return nil, hs, nil, fmt.Errorf("failed to decode raft entry: %w", err)
+			}
+			// A later entry at an existing index replaces the conflicting suffix
+			if n := len(entries); n > 0 && e.Index <= entries[n-1].Index {
+				keep := 0
+				for keep < n && entries[keep].Index < e.Index {
+					keep++
+				}
+				entries = entries[:keep]
+			}
+			entries = append(entries, e)
+		case recordHardState:
+			if err := hs.Unmarshal(data); err != nil {
+				return nil, hs, nil, fmt.Errorf("failed to decode hard state: %w", err)
+			}
+		case recordConfState:
+			cs = &raftpb.ConfState{}
+			if err := cs.Unmarshal(data); err != nil {
+				return nil, hs, nil, fmt.Errorf("failed to decode conf state: %w", err)
+			}
+		}
+	}
+	return entries, hs, cs, nil
+}
+
+// writeRecord frames a record as type, length and CRC32C followed by the payload
+func writeRecord(w io.Writer, typ byte, data []byte) error {
+	var header [recordHeaderSize]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(data)))
+	binary.BigEndian.PutUint32(header[5:9], crc32.Checksum(data, crcTable))
+	if _, err := w.Write(header[:]); err != nil {
+		return fmt.Errorf("failed to write raft log: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write raft log: %w", err)
+	}
+	return nil
+}
+
+// readRecord reads one framed record, returning io.EOF at a clean end of file
+func readRecord(r io.Reader) (byte, []byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, fmt.Errorf("torn record header: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[1:5])
+	if length > maxRecordSize {
+		return 0, nil, fmt.Errorf("record length %d exceeds limit", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, fmt.Errorf("torn record: %w", err)
+	}
+	if crc32.Checksum(data, crcTable) != binary.BigEndian.Uint32(header[5:9]) {
+		return 0, nil, errors.New("record checksum mismatch")
+	}
+	return header[0], data, nil
+}
@@ -0,0 +1,168 @@
+package consensus
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"go.etcd.io/raft/v3"
+	"go.etcd.io/raft/v3/raftpb"
+	"google.golang.org/grpc"
+)
+
+// peerQueueSize is how many messages may wait for a slow peer before new ones are dropped
+const peerQueueSize = 4096
+
+// stepTimeout bounds delivery of one message; snapshots get longer
+const (
+	stepTimeout     = 5 * time.Second
+	snapshotTimeout = 2 * time.Minute
+)
+
+// transport sends raft messages to peers over the MasterPeer gRPC service.
+// Each peer has its own queue and sender so one slow replica doesn't delay the others.
+type transport struct {
+	node  *Node
+	peers map[uint64]*peer
+}
+
+type peer struct {
+	id     uint64
+	addr   string
+	conn   *grpc.ClientConn
+	client pb.MasterPeerClient
+	queue  chan raftpb.Message
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newTransport(n *Node) *transport {
+	t := &transport{
+		node:  n,
+		peers: make(map[uint64]*peer),
+	}
+	for id, addr := range n.peers {
+		if id == n.id {
+			continue
+		}
+		p := &peer{
+			id:    id,
+			addr:  addr,
+			queue: make(chan raftpb.Message, peerQueueSize),
+			stop:  make(chan struct{}),
+		}
+		conn, err := grpc.NewClient(addr,
+			grpc.WithTransportCredentials(n.tls.ClientCredentials()),
+			grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(MaxMessageSize)),
+		)
+		if err != nil {
+			// Only malformed addresses fail here; messages to this peer are dropped
+			slog.Error("invalid master peer address", "peer", id, "address", addr, "error", err)
+		} else {
+			p.conn = conn
+			p.client = pb.NewMasterPeerClient(conn)
+		}
+		t.peers[id] = p
+
+		p.wg.Add(1)
+		go t.sendLoop(p)
+	}
+	return t
+}
+
+// send queues messages for delivery
+func (t *transport) send(msgs []raftpb.Message) {
+	for _, msg := range msgs {
+		p, ok := t.peers[msg.To]
+		if !ok {
+			continue
+		}
+		select {
+		case p.queue <- msg:
+		default:
+			t.report(msg, false)
+		}
+	}
+}
+
+// sendLoop delivers a peer's messages in order
+func (t *transport) sendLoop(p *peer) {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case msg := <-p.queue:
+			if p.client == nil {
+				t.report(msg, false)
+				continue
+			}
+			data, err := msg.Marshal()
+			if err != nil {
+				slog.Error("failed to marshal raft message", "peer", p.id, "error", err)
+				continue
+			}
+
+			timeout := stepTimeout
+			if msg.Type == raftpb.MsgSnap {
+				timeout = snapshotTimeout
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			_, err = p.client.Step(ctx, &pb.RaftMessage{Data: data})
+			cancel()
+			if err != nil {
+				slog.Debug("failed to send raft message", "peer", p.id, "type", msg.Type, "error", err)
+			}
+			t.report(msg, err == nil)
+		}
+	}
+}
+
+// report tells raft about unreachable peers and snapshot delivery
+func (t *transport) report(msg raftpb.Message, ok bool) {
+	if !ok {
+		t.node.node.ReportUnreachable(msg.To)
+	}
+	if msg.Type == raftpb.MsgSnap {
+		status := raft.SnapshotFinish
+		if !ok {
+			status = raft.SnapshotFailure
+		}
+		t.node.node.ReportSnapshot(msg.To, status)
+	}
+}
+
+func (t *transport) close() {
+	for _, p := range t.peers {
+		close(p.stop)
+		p.wg.Wait()
+		if p.conn != nil {
+			p.conn.Close()
+		}
+	}
+}
+
+// PeerServer implements the MasterPeer gRPC service for a node
+type PeerServer struct {
+	pb.UnimplementedMasterPeerServer
+	node *Node
+}
+
+// NewPeerServer creates the MasterPeer service that feeds messages from other replicas into n
+func NewPeerServer(n *Node) *PeerServer {
+	return &PeerServer{node: n}
+}
+
+// Step delivers a message from another replica
+func (s *PeerServer) Step(ctx context.Context, req *pb.RaftMessage) (*pb.RaftMessageResponse, error) {
+	var msg raftpb.Message
+	if err := msg.Unmarshal(req.Data); err != nil {
+		return nil, err
+	}
+	if err := s.node.step(ctx, msg); err != nil {
+		return nil, err
+	}
+	return &pb.RaftMessageResponse{}, nil
+}
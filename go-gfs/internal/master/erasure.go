@@ -0,0 +1,692 @@
+package master
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/erasure"
+)
+
+// Storage classes a file or namespace can use
+const (
+	StorageClassReplicated = "replicated" // Every chunk kept as full replicas
+	StorageClassCold       = "cold"       // Sealed chunks converted to Reed-Solomon stripes
+)
+
+// DefaultColdSealAge is how long the last chunk of a cold file must go without
+// writes before it is encoded; earlier chunks are encoded once committed
+const DefaultColdSealAge = time.Hour
+
+// EncodeTaskTimeout is how long an issued encode or rebuild may run before it is rescheduled
+const EncodeTaskTimeout = 10 * time.Minute
+
+// maxInflightEncodes caps concurrent encodes and rebuilds; each moves a whole chunk across the network
+const maxInflightEncodes = 4
+
+// StripeInfo describes an erasure coded chunk. Its replicas are gone; the data
+// lives in DataShards+ParityShards fragments, any DataShards of which rebuild it.
+type StripeInfo struct {
+	DataShards   int
+	ParityShards int
+	ShardSize    uint64
+	Fragments    [][]ChunkLocation // Servers holding each fragment, by index
+}
+
+// FragmentPlacement is one fragment of a stripe on a chunkserver
+type FragmentPlacement struct {
+	Index  int
+	Server ChunkLocation
+}
+
+// EncodeTask instructs Source to write stripe fragments of a chunk to Targets.
+// Without Sources it encodes its own replica of the chunk; otherwise it rebuilds
+// the targets from the source fragments.
+type EncodeTask struct {
+	Handle       ChunkHandle
+	Source       ChunkServerID
+	Size         uint64
+	DataShards   int
+	ParityShards int
+	Sources      []FragmentPlacement
+	Targets      []FragmentPlacement
+	IssuedAt     time.Time
+}
+
+// encodableChunk is a sealed chunk of a cold file that is still replicated
+type encodableChunk struct {
+	handle ChunkHandle
+	size   uint64
+	live   []ChunkServerID
+}
+
+// degradedStripe is an encoded chunk with fragments that need rebuilding
+type degradedStripe struct {
+	handle       ChunkHandle
+	size         uint64
+	dataShards   int
+	parityShards int
+	sources      []FragmentPlacement    // One live copy of each surviving fragment
+	missing      []int                  // Fragments without a live copy off draining servers
+	holders      map[ChunkServerID]bool // Every server listed for any fragment
+}
+
+// validateStorageClass accepts the known classes, and empty for "inherit"
+func validateStorageClass(class string) error {
+	switch class {
+	case "", StorageClassReplicated, StorageClassCold:
+		return nil
+	}
+	return fmt.Errorf("unknown storage class: %s", class)
+}
+
+// SetColdSealAge sets how long the last chunk of a cold file must go without writes before it is encoded
+func (m *Master) SetColdSealAge(age time.Duration) {
+	m.coldSealAge = age
+}
+
+// SetStorageClass sets the storage class of a file, or the default class of a
+// namespace when path is empty. An empty class makes a file follow its namespace
+// again, and a namespace go back to replicated.
+// Chunks already encoded stay encoded when a file leaves the cold class.
+func (m *Master) SetStorageClass(namespace, path, class string) error {
+	if err := validateStorageClass(class); err != nil {
+		return err
+	}
+	namespace = normalizeNamespace(namespace)
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	if path != "" {
+		if _, exists := m.files[makeFileKey(namespace, path)]; !exists {
+			return fmt.Errorf("file not found: %s", path)
+		}
+	}
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogSetStorageClass(namespace, path, class); err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	m.replaySetStorageClass(namespace, path, class)
+
+	slog.Info("set storage class", "namespace", namespace, "path", path, "storageClass", class)
+	return nil
+}
+
+// replaySetStorageClass sets a file or namespace storage class from WAL (no WAL logging)
+func (m *Master) replaySetStorageClass(namespace, path, class string) {
+	namespace = normalizeNamespace(namespace)
+	if path != "" {
+		if file, exists := m.files[makeFileKey(namespace, path)]; exists {
+			file.StorageClass = class
+		}
+		return
+	}
+	if class == "" {
+		delete(m.storageClasses, namespace)
+		return
+	}
+	m.storageClasses[namespace] = class
+}
+
+// storageClassLocked returns the class a file's chunks are stored with
+// Must be called with fileMu held
+func (m *Master) storageClassLocked(file *FileInfo) string {
+	if file.StorageClass != "" {
+		return file.StorageClass
+	}
+	if class, ok := m.storageClasses[normalizeNamespace(file.Namespace)]; ok {
+		return class
+	}
+	return StorageClassReplicated
+}
+
+// sealedLocked reports whether the chunk at index in a file takes no more writes
+// and can be encoded
+// Must be called with chunkMu held
+func (m *Master) sealedLocked(file *FileInfo, index int, chunk *ChunkInfo, now time.Time) bool {
+	// Shared chunks are left replicated; the files sharing them need not all be cold
+	if chunk.encoding || chunk.Refs > 1 {
+		return false
+	}
+	return m.quietLocked(file, index, chunk, now)
+}
+
+// quietLocked reports whether the chunk at index in a file is committed, replicated
+// and takes no more writes
+// Must be called with chunkMu held
+func (m *Master) quietLocked(file *FileInfo, index int, chunk *ChunkInfo, now time.Time) bool {
+	if chunk.Status != ChunkCommitted || chunk.Stripe != nil || chunk.Size == 0 {
+		return false
+	}
+	if now.Before(chunk.LeaseExpiration) {
+		return false
+	}
+	if index < len(file.Chunks)-1 {
+		return true
+	}
+	// Appends go to the last chunk until the file has been quiet for a while
+	return now.Sub(file.ModifiedAt) >= m.coldSealAge && now.Sub(chunk.committedAt) >= m.coldSealAge
+}
+
+// findEncodable returns sealed chunks of cold files that still have live replicas
+func (m *Master) findEncodable(live map[ChunkServerID]ChunkLocation) []encodableChunk {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+
+	now := time.Now()
+	var candidates []encodableChunk
+	for _, file := range m.files {
+		if m.storageClassLocked(file) != StorageClassCold {
+			continue
+		}
+		for i, handle := range file.Chunks {
+			chunk, ok := m.chunks[handle]
+			if !ok || !m.sealedLocked(file, i, chunk, now) {
+				continue
+			}
+			c := encodableChunk{handle: handle, size: chunk.Size}
+			for _, loc := range chunk.Locations {
+				if _, ok := live[loc.ServerID]; ok {
+					c.live = append(c.live, loc.ServerID)
+				}
+			}
+			if len(c.live) > 0 {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// findDegradedStripes returns encoded chunks with fragments that have no live copy,
+// or only copies on draining servers, ordered so the stripes closest to loss come first.
+// Also returns how many stripes have too few fragments left to rebuild.
+func (m *Master) findDegradedStripes(live map[ChunkServerID]ChunkLocation, draining map[ChunkServerID]bool) ([]degradedStripe, int) {
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+
+	var candidates []degradedStripe
+	lost := 0
+	for handle, chunk := range m.chunks {
+		stripe := chunk.Stripe
+		if stripe == nil {
+			continue
+		}
+
+		c := degradedStripe{
+			handle:       handle,
+			size:         chunk.Size,
+			dataShards:   stripe.DataShards,
+			parityShards: stripe.ParityShards,
+			holders:      make(map[ChunkServerID]bool),
+		}
+		for i, locations := range stripe.Fragments {
+			var source *ChunkLocation
+			healthy := false
+			for _, loc := range locations {
+				c.holders[loc.ServerID] = true
+				server, ok := live[loc.ServerID]
+				if !ok {
+					continue
+				}
+				// Read from draining servers only when nothing else has the fragment
+				if source == nil || (!draining[loc.ServerID] && draining[source.ServerID]) {
+					source = &server
+				}
+				if !draining[loc.ServerID] {
+					healthy = true
+				}
+			}
+			if source != nil {
+				c.sources = append(c.sources, FragmentPlacement{Index: i, Server: *source})
+			}
+			if !healthy {
+				c.missing = append(c.missing, i)
+			}
+		}
+
+		if len(c.missing) == 0 {
+			continue
+		}
+		if len(c.sources) < c.dataShards {
+			lost++
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return len(candidates[i].sources) < len(candidates[j].sources)
+	})
+	return candidates, lost
+}
+
+// scheduleEncodesLocked queues fragment rebuilds, then encodes of sealed cold chunks.
+// Returns the number of tasks queued.
+// Must be called with replicationMu held
+func (m *Master) scheduleEncodesLocked(encodable []encodableChunk, degraded []degradedStripe, now time.Time) int {
+	// Drop tasks that never reported back so they can be retried
+	for handle, task := range m.inflightEncodes {
+		if now.Sub(task.IssuedAt) > EncodeTaskTimeout {
+			slog.Warn("chunk encode timed out, rescheduling", "chunk", handle, "source", task.Source)
+			delete(m.inflightEncodes, handle)
+			if len(task.Sources) == 0 {
+				m.clearEncoding(handle)
+			}
+		}
+	}
+
+	load := make(map[ChunkServerID]int)
+	for _, task := range m.inflightEncodes {
+		load[task.Source]++
+	}
+
+	scheduled := 0
+	for _, c := range degraded {
+		if len(m.inflightEncodes) >= maxInflightEncodes {
+			return scheduled
+		}
+		if _, busy := m.inflightEncodes[c.handle]; busy {
+			continue
+		}
+
+		existing := make([]ChunkLocation, 0, len(c.sources))
+		for _, src := range c.sources {
+			existing = append(existing, src.Server)
+		}
+		targets := m.placeReplicas(len(c.missing), existing, c.holders)
+		if len(targets) == 0 {
+			slog.Debug("no target available for fragment rebuild", "chunk", c.handle, "missing", len(c.missing))
+			continue
+		}
+
+		source := c.sources[0].Server.ServerID
+		for _, src := range c.sources[1:] {
+			if load[src.Server.ServerID] < load[source] {
+				source = src.Server.ServerID
+			}
+		}
+
+		task := &EncodeTask{
+			Handle:       c.handle,
+			Source:       source,
+			Size:         c.size,
+			DataShards:   c.dataShards,
+			ParityShards: c.parityShards,
+			Sources:      c.sources,
+			IssuedAt:     now,
+		}
+		for i, target := range targets {
+			task.Targets = append(task.Targets, FragmentPlacement{Index: c.missing[i], Server: target})
+		}
+		m.queueEncodeLocked(task)
+		load[source]++
+		scheduled++
+
+		slog.Info("scheduled fragment rebuild",
+			"chunk", c.handle,
+			"fragments", len(c.sources),
+			"rebuilding", len(task.Targets),
+			"source", source)
+	}
+
+	shards := erasure.DataShards + erasure.ParityShards
+	for _, c := range encodable {
+		if len(m.inflightEncodes) >= maxInflightEncodes {
+			break
+		}
+		if _, busy := m.inflightEncodes[c.handle]; busy {
+			continue
+		}
+
+		targets := m.placeReplicas(shards, nil, nil)
+		if len(targets) < shards {
+			slog.Debug("not enough chunkservers to encode chunk", "chunk", c.handle, "needed", shards, "available", len(targets))
+			break
+		}
+
+		source := c.live[0]
+		for _, id := range c.live[1:] {
+			if load[id] < load[source] {
+				source = id
+			}
+		}
+
+		// A write may have started since the candidates were gathered
+		if !m.markEncoding(c.handle, c.size) {
+			continue
+		}
+
+		task := &EncodeTask{
+			Handle:       c.handle,
+			Source:       source,
+			Size:         c.size,
+			DataShards:   erasure.DataShards,
+			ParityShards: erasure.ParityShards,
+			IssuedAt:     now,
+		}
+		for i, target := range targets {
+			task.Targets = append(task.Targets, FragmentPlacement{Index: i, Server: target})
+		}
+		m.queueEncodeLocked(task)
+		load[source]++
+		scheduled++
+
+		slog.Info("scheduled chunk encode",
+			"chunk", c.handle,
+			"size", c.size,
+			"stripe", fmt.Sprintf("%d+%d", erasure.DataShards, erasure.ParityShards),
+			"source", source)
+	}
+	return scheduled
+}
+
+// queueEncodeLocked records an encode task and queues it for its source's next heartbeat
+// Must be called with replicationMu held
+func (m *Master) queueEncodeLocked(task *EncodeTask) {
+	m.inflightEncodes[task.Handle] = task
+	m.pendingEncodes[task.Source] = append(m.pendingEncodes[task.Source], *task)
+}
+
+// markEncoding blocks new leases on a chunk while it is encoded.
+// Returns false if the chunk changed or took a lease since it was found sealed.
+func (m *Master) markEncoding(handle ChunkHandle, size uint64) bool {
+	m.chunkMu.Lock()
+	defer m.chunkMu.Unlock()
+
+	chunk, exists := m.chunks[handle]
+	if !exists || chunk.encoding || chunk.Stripe != nil || chunk.Refs > 1 {
+		return false
+	}
+	if chunk.Size != size || time.Now().Before(chunk.LeaseExpiration) {
+		return false
+	}
+	chunk.encoding = true
+	return true
+}
+
+// clearEncoding lets a chunk take leases again after its encode failed
+func (m *Master) clearEncoding(handle ChunkHandle) {
+	m.chunkMu.Lock()
+	defer m.chunkMu.Unlock()
+
+	if chunk, exists := m.chunks[handle]; exists {
+		chunk.encoding = false
+	}
+}
+
+// GetPendingEncodes returns and clears the encode commands queued for a chunkserver
+func (m *Master) GetPendingEncodes(id ChunkServerID) []EncodeTask {
+	m.replicationMu.Lock()
+	defer m.replicationMu.Unlock()
+
+	tasks := m.pendingEncodes[id]
+	delete(m.pendingEncodes, id)
+	return tasks
+}
+
+// CompleteEncode records the outcome of an encode or rebuild reported by the chunkserver that ran it
+func (m *Master) CompleteEncode(source ChunkServerID, handle ChunkHandle, success bool, message string, shardSize uint64) {
+	var task *EncodeTask
+	m.replicationMu.Lock()
+	if t, ok := m.inflightEncodes[handle]; ok && t.Source == source {
+		delete(m.inflightEncodes, handle)
+		task = t
+	}
+	m.replicationMu.Unlock()
+
+	if task == nil {
+		slog.Warn("encode report for unknown task", "chunk", handle, "source", source)
+		return
+	}
+
+	// Fragments a failed task already wrote are reported as orphans and cleaned up
+	if !success {
+		slog.Warn("chunk encode failed", "chunk", handle, "source", source, "rebuild", len(task.Sources) > 0, "error", message)
+		if len(task.Sources) == 0 {
+			m.clearEncoding(handle)
+		}
+		return
+	}
+
+	if len(task.Sources) == 0 {
+		m.finishEncode(*task, shardSize)
+	} else {
+		m.finishRebuild(*task)
+	}
+}
+
+// finishEncode switches a chunk from its replicas to the stripe just written and
+// schedules the replicas for deletion
+func (m *Master) finishEncode(task EncodeTask, shardSize uint64) {
+	m.chunkMu.Lock()
+	chunk, exists := m.chunks[task.Handle]
+	if !exists || !chunk.encoding {
+		m.chunkMu.Unlock()
+		// The chunk was deleted while it was encoded
+		m.scheduleFragmentDeletes(task.Handle, task.Targets)
+		return
+	}
+	chunk.encoding = false
+
+	if chunk.Size != task.Size || shardSize != erasure.ShardSize(task.Size, task.DataShards) {
+		m.chunkMu.Unlock()
+		slog.Warn("discarding stripe that doesn't match chunk",
+			"chunk", task.Handle, "size", chunk.Size, "encodedSize", task.Size, "shardSize", shardSize)
+		m.scheduleFragmentDeletes(task.Handle, task.Targets)
+		return
+	}
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogSetChunkStripe(string(task.Handle), task.DataShards, task.ParityShards, shardSize); err != nil {
+		m.chunkMu.Unlock()
+		slog.Error("failed to record chunk stripe", "chunk", task.Handle, "error", fmt.Errorf("WAL write failed: %w", err))
+		m.scheduleFragmentDeletes(task.Handle, task.Targets)
+		return
+	}
+	replicas := chunk.Locations
+	m.replaySetChunkStripe(string(task.Handle), task.DataShards, task.ParityShards, shardSize)
+	for _, target := range task.Targets {
+		m.addFragmentLocationLocked(chunk, target.Index, target.Server)
+	}
+	m.chunkMu.Unlock()
+
+	m.pendingDeletesMu.Lock()
+	for _, loc := range replicas {
+		m.pendingDeletes[loc.ServerID] = append(m.pendingDeletes[loc.ServerID], task.Handle)
+	}
+	m.pendingDeletesMu.Unlock()
+
+	slog.Info("chunk erasure coded",
+		"chunk", task.Handle,
+		"stripe", fmt.Sprintf("%d+%d", task.DataShards, task.ParityShards),
+		"shardSize", shardSize,
+		"replicasDropped", len(replicas))
+}
+
+// finishRebuild records rebuilt fragments and drops the copies left on draining servers
+func (m *Master) finishRebuild(task EncodeTask) {
+	draining := m.drainingServers()
+
+	type drop struct {
+		server ChunkServerID
+		handle ChunkHandle
+	}
+	var drops []drop
+
+	m.chunkMu.Lock()
+	chunk, exists := m.chunks[task.Handle]
+	if !exists || chunk.Stripe == nil {
+		m.chunkMu.Unlock()
+		m.scheduleFragmentDeletes(task.Handle, task.Targets)
+		return
+	}
+	for _, target := range task.Targets {
+		if target.Index >= len(chunk.Stripe.Fragments) {
+			continue
+		}
+		kept := make([]ChunkLocation, 0, len(chunk.Stripe.Fragments[target.Index]))
+		for _, loc := range chunk.Stripe.Fragments[target.Index] {
+			if draining[loc.ServerID] && loc.ServerID != target.Server.ServerID {
+				drops = append(drops, drop{loc.ServerID, fragmentHandle(task.Handle, target.Index)})
+				continue
+			}
+			kept = append(kept, loc)
+		}
+		chunk.Stripe.Fragments[target.Index] = kept
+		m.addFragmentLocationLocked(chunk, target.Index, target.Server)
+	}
+	m.chunkMu.Unlock()
+
+	if len(drops) > 0 {
+		m.pendingDeletesMu.Lock()
+		for _, d := range drops {
+			m.pendingDeletes[d.server] = append(m.pendingDeletes[d.server], d.handle)
+		}
+		m.pendingDeletesMu.Unlock()
+
+		m.drainMu.Lock()
+		for _, d := range drops {
+			if state, ok := m.draining[d.server]; ok {
+				state.Moved++
+			}
+		}
+		m.drainMu.Unlock()
+		for _, d := range drops {
+			m.logDrainComplete(d.server)
+		}
+	}
+
+	slog.Info("rebuilt stripe fragments", "chunk", task.Handle, "fragments", len(task.Targets), "source", task.Source)
+}
+
+// replaySetChunkStripe marks a chunk as erasure coded from WAL (no WAL logging).
+// Fragment locations are filled in by chunkserver reports.
+func (m *Master) replaySetChunkStripe(chunkHandle string, dataShards, parityShards int, shardSize uint64) {
+	chunk, exists := m.chunks[ChunkHandle(chunkHandle)]
+	if !exists {
+		return
+	}
+	chunk.Stripe = &StripeInfo{
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		ShardSize:    shardSize,
+		Fragments:    make([][]ChunkLocation, dataShards+parityShards),
+	}
+	chunk.Locations = []ChunkLocation{}
+	chunk.Primary = nil
+}
+
+// fragmentHandle returns the handle a fragment of a chunk is stored under
+func fragmentHandle(handle ChunkHandle, index int) ChunkHandle {
+	return ChunkHandle(erasure.FragmentHandle(string(handle), index))
+}
+
+// addFragmentLocationLocked records a server holding a fragment
+// Must be called with chunkMu held
+func (m *Master) addFragmentLocationLocked(chunk *ChunkInfo, index int, server ChunkLocation) {
+	if chunk.Stripe == nil || index < 0 || index >= len(chunk.Stripe.Fragments) {
+		return
+	}
+	for _, loc := range chunk.Stripe.Fragments[index] {
+		if loc.ServerID == server.ServerID {
+			return
+		}
+	}
+	chunk.Stripe.Fragments[index] = append(chunk.Stripe.Fragments[index], server)
+}
+
+// reportFragment records a stripe fragment a chunkserver reported holding.
+// Fragments of unknown or unencoded chunks are treated as orphans.
+func (m *Master) reportFragment(serverID ChunkServerID, handle, chunkHandle ChunkHandle, index int) {
+	m.csMu.RLock()
+	server := m.chunkservers[serverID]
+	m.csMu.RUnlock()
+	if server == nil {
+		return
+	}
+
+	orphan := false
+	m.chunkMu.Lock()
+	chunk, exists := m.chunks[chunkHandle]
+	switch {
+	case exists && chunk.encoding:
+		// Written by an encode that hasn't reported back yet
+	case exists && chunk.Stripe != nil && index < len(chunk.Stripe.Fragments):
+		if !m.isPendingDelete(serverID, handle) {
+			m.addFragmentLocationLocked(chunk, index, *server)
+		}
+	default:
+		orphan = true
+	}
+	m.chunkMu.Unlock()
+
+	if !orphan {
+		m.orphanedChunksMu.Lock()
+		delete(m.orphanedChunks, string(serverID)+":"+string(handle))
+		m.orphanedChunksMu.Unlock()
+		return
+	}
+	if m.isPendingDelete(serverID, handle) {
+		return
+	}
+	m.handleOrphanedChunk(serverID, handle)
+}
+
+// dropCorruptFragment removes a fragment copy that failed checksum verification and
+// schedules its deletion; the stripe is rebuilt from the other fragments
+func (m *Master) dropCorruptFragment(serverID ChunkServerID, handle, chunkHandle ChunkHandle, index int, reason string) error {
+	m.chunkMu.Lock()
+	chunk, exists := m.chunks[chunkHandle]
+	if !exists || chunk.Stripe == nil || index >= len(chunk.Stripe.Fragments) {
+		m.chunkMu.Unlock()
+		return fmt.Errorf("fragment not found: %s", handle)
+	}
+	locations := chunk.Stripe.Fragments[index]
+	kept := make([]ChunkLocation, 0, len(locations))
+	for _, loc := range locations {
+		if loc.ServerID != serverID {
+			kept = append(kept, loc)
+		}
+	}
+	if len(kept) == len(locations) {
+		m.chunkMu.Unlock()
+		return fmt.Errorf("server %s does not hold fragment %s", serverID, handle)
+	}
+	chunk.Stripe.Fragments[index] = kept
+	m.chunkMu.Unlock()
+
+	m.pendingDeletesMu.Lock()
+	m.pendingDeletes[serverID] = append(m.pendingDeletes[serverID], handle)
+	m.pendingDeletesMu.Unlock()
+
+	slog.Warn("dropped corrupt fragment", "fragment", handle, "serverID", serverID, "reason", reason)
+	return nil
+}
+
+// scheduleFragmentDeletes schedules deletion of fragments written for a stripe that was not kept
+func (m *Master) scheduleFragmentDeletes(handle ChunkHandle, fragments []FragmentPlacement) {
+	m.pendingDeletesMu.Lock()
+	defer m.pendingDeletesMu.Unlock()
+
+	for _, f := range fragments {
+		m.pendingDeletes[f.Server.ServerID] = append(m.pendingDeletes[f.Server.ServerID], fragmentHandle(handle, f.Index))
+	}
+}
+
+// stripeLocations returns every fragment copy of an encoded chunk, nil for a replicated one
+func stripeLocations(chunk *ChunkInfo) []ChunkLocation {
+	if chunk.Stripe == nil {
+		return nil
+	}
+	var locations []ChunkLocation
+	for _, fragment := range chunk.Stripe.Fragments {
+		locations = append(locations, fragment...)
+	}
+	return locations
+}
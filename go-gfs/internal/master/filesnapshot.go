@@ -0,0 +1,451 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/chunkreplication"
+	"eddisonso.com/go-gfs/internal/mtls"
+	"google.golang.org/grpc"
+)
+
+// CloneTimeout bounds one chunkserver's local copy of a shared chunk
+const CloneTimeout = 60 * time.Second
+
+// SnapshotFile makes a copy-on-write copy of a file at destPath.
+// The copy shares the source's chunks; a shared chunk is duplicated
+// only when one of the files writes to it (see PrepareChunkWrite).
+func (m *Master) SnapshotFile(sourcePath, destPath, namespace, destNamespace string) (*FileInfo, error) {
+	namespace = normalizeNamespace(namespace)
+	if destNamespace == "" {
+		destNamespace = namespace
+	}
+	if err := checkUserNamespace(destNamespace); err != nil {
+		return nil, err
+	}
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	source, exists := m.files[makeFileKey(namespace, sourcePath)]
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", sourcePath)
+	}
+	if _, exists := m.files[makeFileKey(destNamespace, destPath)]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrFileExists, destPath)
+	}
+	if err := m.checkQuotaLocked(destNamespace, 1, source.Size); err != nil {
+		return nil, err
+	}
+
+	// Log to WAL before applying
+	seq, err := m.wal.LogSnapshotFile(sourcePath, destPath, namespace, destNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+
+	m.chunkMu.Lock()
+	file := m.replaySnapshotFile(sourcePath, destPath, namespace, destNamespace)
+	m.chunkMu.Unlock()
+
+	slog.Info("snapshotted file", "source", sourcePath, "dest", destPath, "namespace", namespace, "destNamespace", destNamespace, "chunks", len(file.Chunks))
+	return file, nil
+}
+
+// SnapshotNamespace makes a copy-on-write copy of every file in a namespace
+// into destNamespace, which must not contain any files
+func (m *Master) SnapshotNamespace(namespace, destNamespace string) (int, error) {
+	namespace = normalizeNamespace(namespace)
+	if destNamespace == "" {
+		return 0, fmt.Errorf("destination namespace is required")
+	}
+	destNamespace = normalizeNamespace(destNamespace)
+	if destNamespace == namespace {
+		return 0, fmt.Errorf("cannot snapshot namespace into itself")
+	}
+	if err := checkUserNamespace(destNamespace); err != nil {
+		return 0, err
+	}
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	count := 0
+	var bytes uint64
+	for _, file := range m.files {
+		if file.Namespace == destNamespace {
+			return 0, fmt.Errorf("namespace not empty: %s", destNamespace)
+		}
+		if file.Namespace == namespace {
+			count++
+			bytes += file.Size
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if err := m.checkQuotaLocked(destNamespace, uint64(count), bytes); err != nil {
+		return 0, err
+	}
+
+	// Log to WAL before applying
+	seq, err := m.wal.LogSnapshotNamespace(namespace, destNamespace)
+	if err != nil {
+		return 0, fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+
+	m.chunkMu.Lock()
+	count = m.replaySnapshotNamespace(namespace, destNamespace)
+	m.chunkMu.Unlock()
+
+	slog.Info("snapshotted namespace", "namespace", namespace, "destNamespace", destNamespace, "files", count)
+	return count, nil
+}
+
+// PrepareChunkWrite returns the chunk at index in a file, ready to be written.
+// A chunk shared with a snapshot is first copied on each of its live replicas
+// and the file is switched to the private copy.
+func (m *Master) PrepareChunkWrite(path, namespace string, index int) (*ChunkInfo, error) {
+	namespace = normalizeNamespace(namespace)
+
+	for {
+		chunk, err := m.chunkAt(path, namespace, index)
+		if err != nil {
+			return nil, err
+		}
+
+		m.chunkMu.RLock()
+		shared := chunk.Refs > 1
+		encoded := chunk.Stripe != nil
+		m.chunkMu.RUnlock()
+		if encoded {
+			return nil, fmt.Errorf("chunk %s is erasure coded and read-only", chunk.Handle)
+		}
+		if !shared {
+			return chunk, nil
+		}
+
+		// One copy per shared chunk at a time; later callers wait and look again
+		m.cowMu.Lock()
+		done, busy := m.cowInflight[chunk.Handle]
+		if busy {
+			m.cowMu.Unlock()
+			<-done
+			continue
+		}
+		done = make(chan struct{})
+		m.cowInflight[chunk.Handle] = done
+		m.cowMu.Unlock()
+
+		copied, err := m.copyOnWrite(path, namespace, index, chunk.Handle)
+
+		m.cowMu.Lock()
+		delete(m.cowInflight, chunk.Handle)
+		close(done)
+		m.cowMu.Unlock()
+
+		return copied, err
+	}
+}
+
+// chunkAt returns the chunk at index in a file
+func (m *Master) chunkAt(path, namespace string, index int) (*ChunkInfo, error) {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+
+	file, exists := m.files[makeFileKey(namespace, path)]
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if index < 0 || index >= len(file.Chunks) {
+		return nil, fmt.Errorf("chunk index %d out of range for %s", index, path)
+	}
+
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+	chunk, exists := m.chunks[file.Chunks[index]]
+	if !exists {
+		return nil, fmt.Errorf("chunk not found: %s", file.Chunks[index])
+	}
+	return chunk, nil
+}
+
+// copyOnWrite gives the file at path a private copy of the shared chunk at index
+func (m *Master) copyOnWrite(path, namespace string, index int, source ChunkHandle) (*ChunkInfo, error) {
+	m.chunkMu.RLock()
+	chunk, exists := m.chunks[source]
+	if !exists {
+		m.chunkMu.RUnlock()
+		return nil, fmt.Errorf("chunk not found: %s", source)
+	}
+	size := chunk.Size
+	locations := make([]ChunkLocation, len(chunk.Locations))
+	copy(locations, chunk.Locations)
+	m.chunkMu.RUnlock()
+
+	handle := m.generateChunkHandle()
+
+	var replicas []ChunkLocation
+	if size == 0 {
+		// Nothing written yet, so there is nothing to copy
+		replicas = m.selectReplicas(m.replicationFactor)
+	} else {
+		replicas = m.cloneOnReplicas(source, handle, locations)
+	}
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no chunkserver could copy chunk %s", source)
+	}
+
+	// Log to WAL OUTSIDE of locks; the copies are invisible until applied
+	m.cutMu.RLock()
+	defer m.cutMu.RUnlock()
+	if _, err := m.wal.LogReplaceChunk(path, namespace, index, string(source), string(handle)); err != nil {
+		m.scheduleChunkDeletes([]*ChunkInfo{{Handle: handle, Locations: replicas}})
+		return nil, fmt.Errorf("WAL write failed: %w", err)
+	}
+
+	m.fileMu.Lock()
+	m.chunkMu.Lock()
+	copied := m.replayReplaceChunk(path, namespace, index, string(source), string(handle))
+	if copied != nil {
+		copied.Locations = replicas
+		copied.Primary = &copied.Locations[0]
+		copied.LeaseExpiration = time.Now().Add(LeaseDuration)
+	}
+	m.chunkMu.Unlock()
+	m.fileMu.Unlock()
+
+	if copied == nil {
+		// The file was deleted or renamed while copying
+		m.scheduleChunkDeletes([]*ChunkInfo{{Handle: handle, Locations: replicas}})
+		return nil, fmt.Errorf("file changed while copying chunk: %s", path)
+	}
+
+	slog.Info("copied shared chunk for write", "path", path, "namespace", namespace, "index", index, "source", source, "chunk", handle, "replicas", len(replicas))
+	return copied, nil
+}
+
+// cloneOnReplicas asks every live replica of source to copy it locally to handle.
+// Returns the replicas that hold the copy.
+func (m *Master) cloneOnReplicas(source, handle ChunkHandle, locations []ChunkLocation) []ChunkLocation {
+	live := m.liveChunkServers()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		replicas []ChunkLocation
+	)
+	for _, loc := range locations {
+		current, ok := live[loc.ServerID]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(loc ChunkLocation) {
+			defer wg.Done()
+			if err := cloneChunk(m.tls, loc, source, handle); err != nil {
+				slog.Warn("failed to copy shared chunk", "server", loc.ServerID, "source", source, "chunk", handle, "error", err)
+				return
+			}
+			mu.Lock()
+			replicas = append(replicas, loc)
+			mu.Unlock()
+		}(current)
+	}
+	wg.Wait()
+	return replicas
+}
+
+// cloneChunk has one chunkserver copy source to handle on its own disk
+func cloneChunk(tlsSource *mtls.Source, loc ChunkLocation, source, handle ChunkHandle) error {
+	addr := fmt.Sprintf("%s:%d", loc.Hostname, loc.ReplicationPort)
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(tlsSource.ClientCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), CloneTimeout)
+	defer cancel()
+
+	resp, err := pb.NewReplicatorClient(conn).CloneChunk(ctx, &pb.Clone{
+		SourceHandle: string(source),
+		ChunkHandle:  string(handle),
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("%s", resp.Message)
+	}
+	return nil
+}
+
+// scheduleChunkDeletes queues chunks for deletion on every server holding them
+func (m *Master) scheduleChunkDeletes(chunks []*ChunkInfo) {
+	m.pendingDeletesMu.Lock()
+	defer m.pendingDeletesMu.Unlock()
+
+	for _, chunk := range chunks {
+		for _, loc := range chunk.Locations {
+			m.pendingDeletes[loc.ServerID] = append(m.pendingDeletes[loc.ServerID], chunk.Handle)
+		}
+		if chunk.Stripe == nil {
+			continue
+		}
+		for i, locations := range chunk.Stripe.Fragments {
+			for _, loc := range locations {
+				m.pendingDeletes[loc.ServerID] = append(m.pendingDeletes[loc.ServerID], fragmentHandle(chunk.Handle, i))
+			}
+		}
+	}
+}
+
+// releaseChunksLocked drops one file reference from each chunk and removes chunks
+// no file uses any more. Returns the removed chunks.
+// Must be called with fileMu and chunkMu held, after the files are gone from m.files
+func (m *Master) releaseChunksLocked(handles []ChunkHandle) []*ChunkInfo {
+	var released []*ChunkInfo
+	var reowned []ChunkHandle
+	for _, handle := range handles {
+		chunk, ok := m.chunks[handle]
+		if !ok {
+			continue
+		}
+		chunk.Refs--
+		switch {
+		case chunk.Refs <= 0:
+			delete(m.chunks, handle)
+			released = append(released, chunk)
+		case chunk.Refs == 1:
+			reowned = append(reowned, handle)
+		}
+	}
+	m.reownChunksLocked(reowned)
+	return released
+}
+
+// reownChunksLocked points chunks back at the one file still using them,
+// so commits to them update that file's size
+// Must be called with fileMu and chunkMu held
+func (m *Master) reownChunksLocked(handles []ChunkHandle) {
+	if len(handles) == 0 {
+		return
+	}
+
+	pending := make(map[ChunkHandle]bool, len(handles))
+	for _, handle := range handles {
+		pending[handle] = true
+	}
+	for _, file := range m.files {
+		for _, handle := range file.Chunks {
+			if !pending[handle] {
+				continue
+			}
+			if chunk, ok := m.chunks[handle]; ok {
+				chunk.FilePath = file.Path
+				chunk.Namespace = file.Namespace
+			}
+			delete(pending, handle)
+		}
+		if len(pending) == 0 {
+			return
+		}
+	}
+}
+
+// replaySnapshotFile copies a file's metadata to destPath, sharing its chunks (no WAL logging)
+// Must be called with fileMu and chunkMu held
+func (m *Master) replaySnapshotFile(sourcePath, destPath, namespace, destNamespace string) *FileInfo {
+	source, exists := m.files[makeFileKey(namespace, sourcePath)]
+	if !exists {
+		return nil
+	}
+	return m.shareFileLocked(source, destPath, normalizeNamespace(destNamespace))
+}
+
+// replaySnapshotNamespace copies every file in a namespace to destNamespace (no WAL logging)
+// Must be called with fileMu and chunkMu held
+func (m *Master) replaySnapshotNamespace(namespace, destNamespace string) int {
+	namespace = normalizeNamespace(namespace)
+	destNamespace = normalizeNamespace(destNamespace)
+
+	var sources []*FileInfo
+	for _, file := range m.files {
+		if file.Namespace == namespace {
+			sources = append(sources, file)
+		}
+	}
+	for _, source := range sources {
+		m.shareFileLocked(source, source.Path, destNamespace)
+	}
+	return len(sources)
+}
+
+// shareFileLocked adds a file at destPath that references the source's chunks
+func (m *Master) shareFileLocked(source *FileInfo, destPath, destNamespace string) *FileInfo {
+	chunks := make([]ChunkHandle, len(source.Chunks))
+	copy(chunks, source.Chunks)
+	for _, handle := range chunks {
+		if chunk, ok := m.chunks[handle]; ok {
+			chunk.Refs++
+		}
+	}
+
+	now := time.Now()
+	file := &FileInfo{
+		Path:       destPath,
+		Namespace:  destNamespace,
+		Chunks:     chunks,
+		Size:       source.Size,
+		ChunkSize:  source.ChunkSize,
+		CreatedAt:  now,
+		ModifiedAt: now,
+
+		StorageClass: source.StorageClass,
+		Attributes:   source.Attributes,
+	}
+	m.files[makeFileKey(destNamespace, destPath)] = file
+	m.trackFileLocked(file)
+	return file
+}
+
+// replayReplaceChunk switches a file from a shared chunk to its private copy (no WAL logging).
+// Returns the copy, or nil when the file no longer holds oldHandle at index.
+// Must be called with fileMu and chunkMu held
+func (m *Master) replayReplaceChunk(path, namespace string, index int, oldHandle, chunkHandle string) *ChunkInfo {
+	namespace = normalizeNamespace(namespace)
+	file, exists := m.files[makeFileKey(namespace, path)]
+	if !exists || index < 0 || index >= len(file.Chunks) || file.Chunks[index] != ChunkHandle(oldHandle) {
+		return nil
+	}
+	old, exists := m.chunks[ChunkHandle(oldHandle)]
+	if !exists {
+		return nil
+	}
+
+	handle := ChunkHandle(chunkHandle)
+	copied := &ChunkInfo{
+		Handle:    handle,
+		FilePath:  path,
+		Namespace: namespace,
+		Locations: []ChunkLocation{}, // Will be populated by chunkserver heartbeats
+		Version:   old.Version,
+		Status:    old.Status,
+		Size:      old.Size,
+		Refs:      1,
+
+		CommittedVersion: old.CommittedVersion,
+	}
+	m.chunks[handle] = copied
+	file.Chunks[index] = handle
+
+	// The other files keep the original
+	m.releaseChunksLocked([]ChunkHandle{old.Handle})
+	return copied
+}
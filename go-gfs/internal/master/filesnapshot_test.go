@@ -0,0 +1,335 @@
+package master
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	pb "eddisonso.com/go-gfs/gen/chunkreplication"
+	"google.golang.org/grpc"
+)
+
+// fakeReplicator answers CloneChunk for every chunkserver in a test
+type fakeReplicator struct {
+	pb.UnimplementedReplicatorServer
+
+	mu     sync.Mutex
+	clones []string // Destination handles
+}
+
+func (r *fakeReplicator) CloneChunk(ctx context.Context, req *pb.Clone) (*pb.ReplicationResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clones = append(r.clones, req.ChunkHandle)
+	return &pb.ReplicationResponse{Success: true}, nil
+}
+
+func (r *fakeReplicator) cloneCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.clones)
+}
+
+// addClonableServers registers three chunkservers whose replication port is r
+func addClonableServers(t *testing.T, m *Master, r *fakeReplicator) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterReplicatorServer(server, r)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	port := lis.Addr().(*net.TCPAddr).Port
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		m.RegisterChunkServer(ChunkServerID(id), "127.0.0.1", 1, port, id, nil)
+	}
+}
+
+// writeTestChunks creates a file with one committed chunk of each size
+func writeTestChunks(t *testing.T, m *Master, namespace, path string, sizes ...uint64) {
+	t.Helper()
+	if _, err := m.CreateFile(path, namespace, "", nil); err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	for _, size := range sizes {
+		chunk, err := m.AddChunkToFile(path, namespace)
+		if err != nil {
+			t.Fatalf("AddChunkToFile: %v", err)
+		}
+		if err := m.ConfirmChunkCommit(chunk.Locations[0].ServerID, chunk.Handle, size, 1); err != nil {
+			t.Fatalf("ConfirmChunkCommit: %v", err)
+		}
+	}
+}
+
+// fileChunks returns the chunk handles of a file
+func fileChunks(t *testing.T, m *Master, namespace, path string) []ChunkHandle {
+	t.Helper()
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	file, ok := m.files[makeFileKey(namespace, path)]
+	if !ok {
+		t.Fatalf("file %s not found", path)
+	}
+	return slices.Clone(file.Chunks)
+}
+
+// chunkRefs returns a chunk's reference count, or 0 once it is removed
+func chunkRefs(m *Master, handle ChunkHandle) int {
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+	if chunk, ok := m.chunks[handle]; ok {
+		return chunk.Refs
+	}
+	return 0
+}
+
+// chunkOwner returns the file a chunk's commits are credited to
+func chunkOwner(m *Master, handle ChunkHandle) string {
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+	if chunk, ok := m.chunks[handle]; ok {
+		return chunk.Namespace + ":" + chunk.FilePath
+	}
+	return ""
+}
+
+func TestSnapshotFileSharesChunks(t *testing.T) {
+	m := newTestMaster(t)
+	addClonableServers(t, m, &fakeReplicator{})
+	writeTestChunks(t, m, "ns", "/src", 100, 50)
+	chunks := fileChunks(t, m, "ns", "/src")
+
+	copy1, err := m.SnapshotFile("/src", "/copy1", "ns", "")
+	if err != nil {
+		t.Fatalf("SnapshotFile: %v", err)
+	}
+	if _, err := m.SnapshotFile("/src", "/copy2", "ns", "other"); err != nil {
+		t.Fatalf("SnapshotFile into another namespace: %v", err)
+	}
+	if !slices.Equal(copy1.Chunks, chunks) || copy1.Size != 150 {
+		t.Errorf("snapshot has chunks %v, size %d; want %v, 150", copy1.Chunks, copy1.Size, chunks)
+	}
+	for _, handle := range chunks {
+		if refs := chunkRefs(m, handle); refs != 3 {
+			t.Errorf("chunk %s has %d refs, want 3", handle, refs)
+		}
+	}
+
+	if _, err := m.SnapshotFile("/src", "/copy1", "ns", ""); err == nil {
+		t.Error("snapshot over an existing file succeeded")
+	}
+	if _, err := m.SnapshotFile("/missing", "/copy3", "ns", ""); err == nil {
+		t.Error("snapshot of a missing file succeeded")
+	}
+	if refs := chunkRefs(m, chunks[0]); refs != 3 {
+		t.Errorf("failed snapshots changed refs to %d", refs)
+	}
+}
+
+func TestSnapshotNamespace(t *testing.T) {
+	m := newTestMaster(t)
+	addClonableServers(t, m, &fakeReplicator{})
+	writeTestChunks(t, m, "ns", "/a", 10)
+	writeTestChunks(t, m, "ns", "/b", 20, 30)
+
+	n, err := m.SnapshotNamespace("ns", "backup")
+	if err != nil || n != 2 {
+		t.Fatalf("SnapshotNamespace = %d, %v; want 2", n, err)
+	}
+	for _, path := range []string{"/a", "/b"} {
+		src, dst := fileChunks(t, m, "ns", path), fileChunks(t, m, "backup", path)
+		if !slices.Equal(src, dst) {
+			t.Errorf("%s: backup chunks %v, want %v", path, dst, src)
+		}
+		for _, handle := range src {
+			if refs := chunkRefs(m, handle); refs != 2 {
+				t.Errorf("chunk %s has %d refs, want 2", handle, refs)
+			}
+		}
+	}
+
+	if _, err := m.SnapshotNamespace("ns", "backup"); err == nil {
+		t.Error("snapshot into a namespace with files succeeded")
+	}
+	if _, err := m.SnapshotNamespace("ns", "ns"); err == nil {
+		t.Error("snapshot of a namespace into itself succeeded")
+	}
+}
+
+func TestPrepareChunkWriteCopiesSharedChunk(t *testing.T) {
+	m := newTestMaster(t)
+	r := &fakeReplicator{}
+	addClonableServers(t, m, r)
+	writeTestChunks(t, m, "ns", "/src", 100, 50)
+	chunks := fileChunks(t, m, "ns", "/src")
+
+	// An unshared chunk is written in place
+	chunk, err := m.PrepareChunkWrite("/src", "ns", 1)
+	if err != nil || chunk.Handle != chunks[1] {
+		t.Fatalf("PrepareChunkWrite of an unshared chunk = %v, %v; want %s", chunk, err, chunks[1])
+	}
+	if r.cloneCount() != 0 {
+		t.Errorf("unshared chunk was cloned")
+	}
+
+	if _, err := m.SnapshotFile("/src", "/copy", "ns", ""); err != nil {
+		t.Fatalf("SnapshotFile: %v", err)
+	}
+	if _, ok := m.ClaimPrimary(chunks[1], chunk.Locations[0].ServerID); ok {
+		t.Errorf("claimed a lease on a shared chunk")
+	}
+
+	// The first write after the snapshot gets the source a private copy on each replica
+	copied, err := m.PrepareChunkWrite("/src", "ns", 1)
+	if err != nil {
+		t.Fatalf("PrepareChunkWrite: %v", err)
+	}
+	if copied.Handle == chunks[1] || copied.Size != 50 || copied.Refs != 1 {
+		t.Errorf("copy = %s, size %d, refs %d; want a new chunk of 50 bytes with 1 ref", copied.Handle, copied.Size, copied.Refs)
+	}
+	if got := r.cloneCount(); got != len(chunk.Locations) {
+		t.Errorf("%d clones, want one per replica (%d)", got, len(chunk.Locations))
+	}
+	if got := fileChunks(t, m, "ns", "/src"); !slices.Equal(got, []ChunkHandle{chunks[0], copied.Handle}) {
+		t.Errorf("source chunks = %v, want the copy at index 1", got)
+	}
+	if got := fileChunks(t, m, "ns", "/copy"); !slices.Equal(got, chunks) {
+		t.Errorf("snapshot chunks = %v, want the originals %v", got, chunks)
+	}
+
+	// The snapshot alone owns the original now, so its commits size the snapshot
+	if refs := chunkRefs(m, chunks[1]); refs != 1 {
+		t.Errorf("original has %d refs, want 1", refs)
+	}
+	if owner := chunkOwner(m, chunks[1]); owner != "ns:/copy" {
+		t.Errorf("original owned by %s, want ns:/copy", owner)
+	}
+	if refs := chunkRefs(m, chunks[0]); refs != 2 {
+		t.Errorf("untouched chunk has %d refs, want 2", refs)
+	}
+
+	// A second write uses the copy
+	again, err := m.PrepareChunkWrite("/src", "ns", 1)
+	if err != nil || again.Handle != copied.Handle || r.cloneCount() != len(chunk.Locations) {
+		t.Errorf("second PrepareChunkWrite = %v, %v; want the existing copy", again, err)
+	}
+}
+
+// Chunks shared by a snapshot survive deleting one file and are released with the last
+func TestDeleteReleasesSharedChunks(t *testing.T) {
+	m := newTestMaster(t)
+	m.SetTrashRetention(0)
+	addClonableServers(t, m, &fakeReplicator{})
+	writeTestChunks(t, m, "ns", "/src", 100)
+	handle := fileChunks(t, m, "ns", "/src")[0]
+	if _, err := m.SnapshotFile("/src", "/copy", "ns", ""); err != nil {
+		t.Fatalf("SnapshotFile: %v", err)
+	}
+	m.chunkMu.RLock()
+	locations := slices.Clone(m.chunks[handle].Locations)
+	m.chunkMu.RUnlock()
+
+	pending := func() int {
+		n := 0
+		for _, loc := range locations {
+			n += len(m.GetPendingDeletes(loc.ServerID))
+		}
+		return n
+	}
+
+	if err := m.DeleteFile("/src", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if refs := chunkRefs(m, handle); refs != 1 {
+		t.Errorf("chunk has %d refs after deleting the source, want 1", refs)
+	}
+	if owner := chunkOwner(m, handle); owner != "ns:/copy" {
+		t.Errorf("chunk owned by %s, want ns:/copy", owner)
+	}
+	if n := pending(); n != 0 {
+		t.Errorf("%d deletes scheduled for a chunk still in use", n)
+	}
+
+	if err := m.DeleteFile("/copy", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if refs := chunkRefs(m, handle); refs != 0 {
+		t.Errorf("chunk still registered with %d refs", refs)
+	}
+	if n := pending(); n != len(locations) {
+		t.Errorf("%d deletes scheduled, want one per replica (%d)", n, len(locations))
+	}
+}
+
+// Snapshots and copies replay from the WAL to the same files and reference counts
+func TestSnapshotReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	m, err := NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	m.SetTrashRetention(0)
+	addClonableServers(t, m, &fakeReplicator{})
+	writeTestChunks(t, m, "ns", "/a", 10, 20)
+	writeTestChunks(t, m, "ns", "/b", 30)
+	if _, err := m.SnapshotFile("/a", "/a-copy", "ns", ""); err != nil {
+		t.Fatalf("SnapshotFile: %v", err)
+	}
+	if _, err := m.SnapshotNamespace("ns", "backup"); err != nil {
+		t.Fatalf("SnapshotNamespace: %v", err)
+	}
+	if _, err := m.PrepareChunkWrite("/a", "ns", 0); err != nil {
+		t.Fatalf("PrepareChunkWrite: %v", err)
+	}
+	if err := m.DeleteFile("/b", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+
+	state := func(m *Master) (map[string][]ChunkHandle, map[ChunkHandle]int) {
+		m.fileMu.RLock()
+		defer m.fileMu.RUnlock()
+		m.chunkMu.RLock()
+		defer m.chunkMu.RUnlock()
+		files := make(map[string][]ChunkHandle)
+		for _, file := range m.files {
+			files[file.Namespace+":"+file.Path] = slices.Clone(file.Chunks)
+		}
+		refs := make(map[ChunkHandle]int)
+		for handle, chunk := range m.chunks {
+			refs[handle] = chunk.Refs
+		}
+		return files, refs
+	}
+	wantFiles, wantRefs := state(m)
+	m.Close()
+
+	m, err = NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster after restart: %v", err)
+	}
+	defer m.Close()
+	files, refs := state(m)
+	if len(files) != len(wantFiles) {
+		t.Errorf("%d files after replay, want %d", len(files), len(wantFiles))
+	}
+	for name, chunks := range wantFiles {
+		if !slices.Equal(files[name], chunks) {
+			t.Errorf("%s has chunks %v after replay, want %v", name, files[name], chunks)
+		}
+	}
+	if len(refs) != len(wantRefs) {
+		t.Errorf("%d chunks after replay, want %d", len(refs), len(wantRefs))
+	}
+	for handle, want := range wantRefs {
+		if refs[handle] != want {
+			t.Errorf("chunk %s has %d refs after replay, want %d", handle, refs[handle], want)
+		}
+	}
+}
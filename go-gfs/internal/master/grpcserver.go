@@ -2,9 +2,15 @@ package master
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"sort"
 
 	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/datatoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // GRPCServer implements the Master gRPC service
@@ -40,12 +46,21 @@ func (s *GRPCServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb
 		req.Hostname,
 		int(req.DataPort),
 		int(req.ReplicationPort),
+		req.FailureDomain,
 		bi,
 	)
+	s.master.UpdateChunkServerUsage(ChunkServerID(req.ServerId), len(req.ChunkHandles), DiskUsage{
+		CapacityBytes:    req.CapacityBytes,
+		FreeBytes:        req.FreeBytes,
+		UsedBytes:        req.UsedBytes,
+		LogicalBytes:     req.LogicalBytes,
+		CompressedChunks: int(req.CompressedChunks),
+		Disks:            disksFromProto(req.Disks),
+	})
 
 	// Process chunk reports from registration
 	for _, handle := range req.ChunkHandles {
-		s.master.ReportChunk(ChunkServerID(req.ServerId), ChunkHandle(handle))
+		s.master.ReportChunk(ChunkServerID(req.ServerId), ChunkHandle(handle), req.ChunkVersions[handle])
 	}
 
 	return &pb.RegisterResponse{
@@ -63,10 +78,37 @@ func (s *GRPCServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*
 			Success: false,
 		}, nil
 	}
+	report := chunkReportFromProto(req)
+	chunkCount := len(req.ChunkHandles)
+	if req.ChunkReport != nil {
+		chunkCount = int(req.ChunkReport.ChunkCount)
+	}
+	s.master.UpdateChunkServerUsage(ChunkServerID(req.ServerId), chunkCount, DiskUsage{
+		CapacityBytes:    req.CapacityBytes,
+		FreeBytes:        req.FreeBytes,
+		UsedBytes:        req.UsedBytes,
+		LogicalBytes:     req.LogicalBytes,
+		CompressedChunks: int(req.CompressedChunks),
+		Disks:            disksFromProto(req.Disks),
+	})
+	if req.Load != nil {
+		s.master.UpdateChunkServerLoad(ChunkServerID(req.ServerId), ServerLoad{
+			OpenConnections:  req.Load.OpenConnections,
+			ReadBytesPerSec:  req.Load.ReadBytesPerSec,
+			WriteBytesPerSec: req.Load.WriteBytesPerSec,
+		})
+	}
 
 	// Process chunk reports
-	for _, handle := range req.ChunkHandles {
-		s.master.ReportChunk(ChunkServerID(req.ServerId), ChunkHandle(handle))
+	fullReportRequested := s.master.ApplyChunkReport(ChunkServerID(req.ServerId), report)
+
+	// Drop copies lost with a failed disk
+	if len(req.LostChunks) > 0 {
+		lost := make([]ChunkHandle, len(req.LostChunks))
+		for i, h := range req.LostChunks {
+			lost[i] = ChunkHandle(h)
+		}
+		s.master.DropLostReplicas(ChunkServerID(req.ServerId), lost)
 	}
 
 	// Get chunks to delete
@@ -80,19 +122,68 @@ func (s *GRPCServer) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*
 		slog.Info("sending chunks to delete", "serverID", req.ServerId, "chunks", chunksToDelete)
 	}
 
+	// Get chunks to copy to other servers
+	pendingReplications := s.master.GetPendingReplications(ChunkServerID(req.ServerId))
+	chunksToReplicate := make([]*pb.ReplicateChunkCommand, len(pendingReplications))
+	for i, task := range pendingReplications {
+		chunksToReplicate[i] = &pb.ReplicateChunkCommand{
+			ChunkHandle: string(task.Handle),
+			Target:      chunkLocationToProto(&task.Target),
+		}
+	}
+
+	if len(chunksToReplicate) > 0 {
+		slog.Info("sending chunks to replicate", "serverID", req.ServerId, "count", len(chunksToReplicate))
+	}
+
+	// Get stripes to encode or repair
+	pendingEncodes := s.master.GetPendingEncodes(ChunkServerID(req.ServerId))
+	chunksToEncode := make([]*pb.EncodeChunkCommand, len(pendingEncodes))
+	for i, task := range pendingEncodes {
+		chunksToEncode[i] = &pb.EncodeChunkCommand{
+			ChunkHandle:  string(task.Handle),
+			Size:         task.Size,
+			DataShards:   uint32(task.DataShards),
+			ParityShards: uint32(task.ParityShards),
+			Sources:      fragmentPlacementsToProto(task.Handle, task.Sources),
+			Targets:      fragmentPlacementsToProto(task.Handle, task.Targets),
+		}
+	}
+
+	if len(chunksToEncode) > 0 {
+		slog.Info("sending chunks to encode", "serverID", req.ServerId, "count", len(chunksToEncode))
+	}
+
+	// Get sealed chunks to compress
+	pendingCompresses := s.master.GetPendingCompresses(ChunkServerID(req.ServerId))
+	chunksToCompress := make([]string, len(pendingCompresses))
+	for i, h := range pendingCompresses {
+		chunksToCompress[i] = string(h)
+	}
+
+	if len(chunksToCompress) > 0 {
+		slog.Debug("sending chunks to compress", "serverID", req.ServerId, "count", len(chunksToCompress))
+	}
+
 	return &pb.HeartbeatResponse{
-		Success:        true,
-		ChunksToDelete: chunksToDelete,
+		Success:             true,
+		FullReportRequested: fullReportRequested,
+		ChunksToDelete:      chunksToDelete,
+		ChunksToReplicate:   chunksToReplicate,
+		ChunksToEncode:      chunksToEncode,
+		ChunksToCompress:    chunksToCompress,
 	}, nil
 }
 
 // CreateFile creates a new file in the namespace
 func (s *GRPCServer) CreateFile(ctx context.Context, req *pb.CreateFileRequest) (*pb.CreateFileResponse, error) {
-	file, err := s.master.CreateFile(req.Path, req.Namespace)
+	file, err := s.master.CreateFile(req.Path, req.Namespace, req.StorageClass, req.Attributes)
 	if err != nil {
 		return &pb.CreateFileResponse{
-			Success: false,
-			Message: err.Error(),
+			Success:            false,
+			Message:            err.Error(),
+			QuotaExceeded:      errors.Is(err, ErrQuotaExceeded),
+			PreconditionFailed: errors.Is(err, ErrPreconditionFailed),
 		}, nil
 	}
 
@@ -154,11 +245,12 @@ func (s *GRPCServer) DeleteNamespace(ctx context.Context, req *pb.DeleteNamespac
 
 // RenameFile renames/moves a file
 func (s *GRPCServer) RenameFile(ctx context.Context, req *pb.RenameFileRequest) (*pb.RenameFileResponse, error) {
-	err := s.master.RenameFile(req.OldPath, req.NewPath, req.Namespace)
+	err := s.master.RenameFile(req.OldPath, req.NewPath, req.Namespace, req.Replace)
 	if err != nil {
 		return &pb.RenameFileResponse{
-			Success: false,
-			Message: err.Error(),
+			Success:            false,
+			Message:            err.Error(),
+			PreconditionFailed: errors.Is(err, ErrPreconditionFailed),
 		}, nil
 	}
 
@@ -168,6 +260,84 @@ func (s *GRPCServer) RenameFile(ctx context.Context, req *pb.RenameFileRequest)
 	}, nil
 }
 
+// Transact applies several file operations atomically, if every condition holds
+func (s *GRPCServer) Transact(ctx context.Context, req *pb.TransactRequest) (*pb.TransactResponse, error) {
+	conditions := make([]TxCondition, 0, len(req.Conditions))
+	for _, c := range req.Conditions {
+		conditions = append(conditions, TxCondition{
+			Path:      c.Path,
+			Absent:    c.Absent,
+			CheckSize: c.CheckSize,
+			Size:      c.Size,
+		})
+	}
+	ops := make([]TxOp, 0, len(req.Ops))
+	for _, op := range req.Ops {
+		ops = append(ops, TxOp{
+			Type:         TxOpType(op.Type),
+			Path:         op.Path,
+			NewPath:      op.NewPath,
+			Replace:      op.Replace,
+			StorageClass: op.StorageClass,
+			Attributes:   op.Attributes,
+			Remove:       op.Remove,
+		})
+	}
+
+	if err := s.master.Transact(req.Namespace, conditions, ops); err != nil {
+		return &pb.TransactResponse{
+			Success:            false,
+			Message:            err.Error(),
+			PreconditionFailed: errors.Is(err, ErrPreconditionFailed),
+			QuotaExceeded:      errors.Is(err, ErrQuotaExceeded),
+		}, nil
+	}
+
+	return &pb.TransactResponse{
+		Success: true,
+		Message: "transaction applied",
+	}, nil
+}
+
+// SetFileAttributes changes the user-defined attributes of a file
+func (s *GRPCServer) SetFileAttributes(ctx context.Context, req *pb.SetFileAttributesRequest) (*pb.SetFileAttributesResponse, error) {
+	file, err := s.master.SetFileAttributes(req.Path, req.Namespace, req.Set, req.Remove)
+	if err != nil {
+		return &pb.SetFileAttributesResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.SetFileAttributesResponse{
+		Success: true,
+		Message: "attributes updated",
+		File:    fileInfoToProto(file),
+	}, nil
+}
+
+// WatchNamespace streams changes to files in a namespace until the client cancels
+func (s *GRPCServer) WatchNamespace(req *pb.WatchNamespaceRequest, stream grpc.ServerStreamingServer[pb.NamespaceEvent]) error {
+	err := s.master.WatchNamespace(stream.Context(), req.Namespace, req.Prefix, req.ResumeToken, func(e NamespaceEvent) error {
+		return stream.Send(&pb.NamespaceEvent{
+			ResumeToken: e.Seq,
+			Type:        string(e.Type),
+			Namespace:   e.Namespace,
+			Path:        e.Path,
+			NewPath:     e.NewPath,
+			ChunkHandle: e.ChunkHandle,
+			ChunkSize:   e.ChunkSize,
+		})
+	})
+	switch {
+	case errors.Is(err, ErrWatchExpired):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, ErrWatchClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
 // ListFiles returns all files in the namespace
 func (s *GRPCServer) ListFiles(ctx context.Context, req *pb.ListFilesRequest) (*pb.ListFilesResponse, error) {
 	files := s.master.ListFiles(req.Namespace, req.Prefix)
@@ -182,20 +352,229 @@ func (s *GRPCServer) ListFiles(ctx context.Context, req *pb.ListFilesRequest) (*
 	}, nil
 }
 
+// ListFilesV2 returns one page of files and common prefixes in path order
+func (s *GRPCServer) ListFilesV2(ctx context.Context, req *pb.ListFilesV2Request) (*pb.ListFilesV2Response, error) {
+	page, err := s.master.ListFilesPage(ListOptions{
+		Namespace: req.Namespace,
+		Prefix:    req.Prefix,
+		Delimiter: req.Delimiter,
+		Limit:     int(req.Limit),
+		PageToken: req.PageToken,
+	})
+	if err != nil {
+		return &pb.ListFilesV2Response{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	protoFiles := make([]*pb.FileInfoResponse, 0, len(page.Files))
+	for _, f := range page.Files {
+		protoFiles = append(protoFiles, fileInfoToProto(f))
+	}
+
+	return &pb.ListFilesV2Response{
+		Success:        true,
+		Files:          protoFiles,
+		CommonPrefixes: page.CommonPrefixes,
+		NextPageToken:  page.NextPageToken,
+	}, nil
+}
+
+// SnapshotFile makes a copy-on-write copy of a file
+func (s *GRPCServer) SnapshotFile(ctx context.Context, req *pb.SnapshotFileRequest) (*pb.SnapshotFileResponse, error) {
+	file, err := s.master.SnapshotFile(req.SourcePath, req.DestPath, req.Namespace, req.DestNamespace)
+	if err != nil {
+		return &pb.SnapshotFileResponse{
+			Success:       false,
+			Message:       err.Error(),
+			QuotaExceeded: errors.Is(err, ErrQuotaExceeded),
+		}, nil
+	}
+
+	return &pb.SnapshotFileResponse{
+		Success: true,
+		Message: "file snapshotted",
+		File:    fileInfoToProto(file),
+	}, nil
+}
+
+// SnapshotNamespace makes a copy-on-write copy of every file in a namespace
+func (s *GRPCServer) SnapshotNamespace(ctx context.Context, req *pb.SnapshotNamespaceRequest) (*pb.SnapshotNamespaceResponse, error) {
+	count, err := s.master.SnapshotNamespace(req.Namespace, req.DestNamespace)
+	if err != nil {
+		return &pb.SnapshotNamespaceResponse{
+			Success:       false,
+			Message:       err.Error(),
+			QuotaExceeded: errors.Is(err, ErrQuotaExceeded),
+		}, nil
+	}
+
+	return &pb.SnapshotNamespaceResponse{
+		Success:     true,
+		Message:     "namespace snapshotted",
+		FilesCopied: int32(count),
+	}, nil
+}
+
+// ListTrash lists deleted files that can still be restored
+func (s *GRPCServer) ListTrash(ctx context.Context, req *pb.ListTrashRequest) (*pb.ListTrashResponse, error) {
+	entries := s.master.ListTrash(req.Namespace)
+
+	protoEntries := make([]*pb.TrashEntry, len(entries))
+	for i, e := range entries {
+		protoEntries[i] = &pb.TrashEntry{
+			TrashId:   e.ID,
+			Namespace: e.Namespace,
+			Path:      e.Path,
+			Size:      e.Size,
+			DeletedAt: e.DeletedAt.Unix(),
+		}
+		if !e.ExpiresAt.IsZero() {
+			protoEntries[i].ExpiresAt = e.ExpiresAt.Unix()
+		}
+	}
+
+	return &pb.ListTrashResponse{
+		Entries: protoEntries,
+	}, nil
+}
+
+// RestoreFile moves a file out of the trash
+func (s *GRPCServer) RestoreFile(ctx context.Context, req *pb.RestoreFileRequest) (*pb.RestoreFileResponse, error) {
+	file, err := s.master.RestoreFile(req.TrashId, req.Path, req.Namespace)
+	if err != nil {
+		return &pb.RestoreFileResponse{
+			Success:       false,
+			Message:       err.Error(),
+			QuotaExceeded: errors.Is(err, ErrQuotaExceeded),
+		}, nil
+	}
+
+	return &pb.RestoreFileResponse{
+		Success: true,
+		Message: "file restored",
+		File:    fileInfoToProto(file),
+	}, nil
+}
+
+// PurgeTrash permanently deletes files from the trash
+func (s *GRPCServer) PurgeTrash(ctx context.Context, req *pb.PurgeTrashRequest) (*pb.PurgeTrashResponse, error) {
+	count, err := s.master.PurgeTrash(req.TrashIds, req.Namespace)
+	if err != nil {
+		return &pb.PurgeTrashResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.PurgeTrashResponse{
+		Success:     true,
+		Message:     "trash purged",
+		FilesPurged: int32(count),
+	}, nil
+}
+
 // AllocateChunk allocates a new chunk for a file
 func (s *GRPCServer) AllocateChunk(ctx context.Context, req *pb.AllocateChunkRequest) (*pb.AllocateChunkResponse, error) {
-	chunkInfo, err := s.master.AddChunkToFile(req.Path, req.Namespace)
+	var chunkInfo *ChunkInfo
+	var err error
+	if req.CheckSize {
+		chunkInfo, err = s.master.AddChunkToFileIfSize(req.Path, req.Namespace, req.IfSize)
+	} else {
+		chunkInfo, err = s.master.AddChunkToFile(req.Path, req.Namespace)
+	}
 	if err != nil {
 		return &pb.AllocateChunkResponse{
-			Success: false,
-			Message: err.Error(),
+			Success:            false,
+			Message:            err.Error(),
+			QuotaExceeded:      errors.Is(err, ErrQuotaExceeded),
+			PreconditionFailed: errors.Is(err, ErrPreconditionFailed),
 		}, nil
 	}
 
 	return &pb.AllocateChunkResponse{
 		Success: true,
 		Message: "chunk allocated",
-		Chunk:   chunkInfoToProto(chunkInfo),
+		Chunk:   s.chunkInfoWithGrants(chunkInfo),
+	}, nil
+}
+
+// SetNamespaceQuota sets or clears a namespace quota
+func (s *GRPCServer) SetNamespaceQuota(ctx context.Context, req *pb.SetNamespaceQuotaRequest) (*pb.SetNamespaceQuotaResponse, error) {
+	err := s.master.SetNamespaceQuota(req.Namespace, NamespaceQuota{
+		MaxBytes: req.MaxBytes,
+		MaxFiles: req.MaxFiles,
+	})
+	if err != nil {
+		return &pb.SetNamespaceQuotaResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.SetNamespaceQuotaResponse{
+		Success: true,
+		Message: "quota set",
+	}, nil
+}
+
+// GetNamespaceUsage returns live usage and quotas of namespaces
+func (s *GRPCServer) GetNamespaceUsage(ctx context.Context, req *pb.GetNamespaceUsageRequest) (*pb.GetNamespaceUsageResponse, error) {
+	usages := s.master.GetNamespaceUsage(req.Namespace)
+
+	namespaces := make([]*pb.NamespaceUsage, 0, len(usages))
+	for _, u := range usages {
+		namespaces = append(namespaces, &pb.NamespaceUsage{
+			Namespace: u.Namespace,
+			UsedBytes: u.Bytes,
+			FileCount: u.Files,
+			MaxBytes:  u.Quota.MaxBytes,
+			MaxFiles:  u.Quota.MaxFiles,
+
+			StorageClass: u.StorageClass,
+			Compression:  u.Compression,
+		})
+	}
+
+	return &pb.GetNamespaceUsageResponse{
+		Namespaces: namespaces,
+	}, nil
+}
+
+// SetStorageClass sets the storage class of a file, or the default of a namespace
+func (s *GRPCServer) SetStorageClass(ctx context.Context, req *pb.SetStorageClassRequest) (*pb.SetStorageClassResponse, error) {
+	err := s.master.SetStorageClass(req.Namespace, req.Path, req.StorageClass)
+	if err != nil {
+		return &pb.SetStorageClassResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.SetStorageClassResponse{
+		Success: true,
+		Message: "storage class set",
+	}, nil
+}
+
+// SetCompression sets how the sealed chunks of a namespace are stored
+func (s *GRPCServer) SetCompression(ctx context.Context, req *pb.SetCompressionRequest) (*pb.SetCompressionResponse, error) {
+	err := s.master.SetCompression(req.Namespace, req.Compression)
+	if err != nil {
+		return &pb.SetCompressionResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	message := "compression set"
+	if req.Compression == "" {
+		message = "compression disabled"
+	}
+	return &pb.SetCompressionResponse{
+		Success: true,
+		Message: message,
 	}, nil
 }
 
@@ -211,7 +590,7 @@ func (s *GRPCServer) GetChunkLocations(ctx context.Context, req *pb.GetChunkLoca
 
 	protoChunks := make([]*pb.ChunkLocationInfo, 0, len(chunks))
 	for _, c := range chunks {
-		protoChunks = append(protoChunks, chunkInfoToProto(c))
+		protoChunks = append(protoChunks, s.chunkInfoWithGrants(c))
 	}
 
 	return &pb.GetChunkLocationsResponse{
@@ -220,6 +599,40 @@ func (s *GRPCServer) GetChunkLocations(ctx context.Context, req *pb.GetChunkLoca
 	}, nil
 }
 
+// PrepareChunkWrite returns a chunk that can be written, copying it first if a snapshot shares it
+func (s *GRPCServer) PrepareChunkWrite(ctx context.Context, req *pb.PrepareChunkWriteRequest) (*pb.PrepareChunkWriteResponse, error) {
+	chunkInfo, err := s.master.PrepareChunkWrite(req.Path, req.Namespace, int(req.ChunkIndex))
+	if err != nil {
+		return &pb.PrepareChunkWriteResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.PrepareChunkWriteResponse{
+		Success: true,
+		Message: "chunk ready for write",
+		Chunk:   s.chunkInfoWithGrants(chunkInfo),
+	}, nil
+}
+
+// IssueDataToken renews a client's data-plane grant for one chunk or fragment
+func (s *GRPCServer) IssueDataToken(ctx context.Context, req *pb.IssueDataTokenRequest) (*pb.IssueDataTokenResponse, error) {
+	token, expires, err := s.master.IssueGrant(ChunkHandle(req.ChunkHandle), req.Operation)
+	if err != nil {
+		return &pb.IssueDataTokenResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.IssueDataTokenResponse{
+		Success:   true,
+		Token:     token,
+		ExpiresAt: expires.Unix(),
+	}, nil
+}
+
 // ReportCommit is called by chunkservers after successful 2PC commit
 func (s *GRPCServer) ReportCommit(ctx context.Context, req *pb.ReportCommitRequest) (*pb.ReportCommitResponse, error) {
 	slog.Debug("received commit report",
@@ -231,6 +644,7 @@ func (s *GRPCServer) ReportCommit(ctx context.Context, req *pb.ReportCommitReque
 		ChunkServerID(req.ServerId),
 		ChunkHandle(req.ChunkHandle),
 		req.Size,
+		req.Version,
 	)
 	if err != nil {
 		return &pb.ReportCommitResponse{
@@ -255,7 +669,7 @@ func (s *GRPCServer) RenewLease(ctx context.Context, req *pb.RenewLeaseRequest)
 	if !ok {
 		return &pb.RenewLeaseResponse{
 			Success: false,
-			Message: "lease renewal failed: not the current primary or chunk not found",
+			Message: "lease renewal failed: not the current primary, chunk not found or chunk shared by a snapshot",
 		}, nil
 	}
 
@@ -268,7 +682,7 @@ func (s *GRPCServer) RenewLease(ctx context.Context, req *pb.RenewLeaseRequest)
 
 // ClaimPrimary allows a chunkserver to claim primary status at the start of a write
 func (s *GRPCServer) ClaimPrimary(ctx context.Context, req *pb.ClaimPrimaryRequest) (*pb.ClaimPrimaryResponse, error) {
-	ok := s.master.ClaimPrimary(
+	version, ok := s.master.ClaimPrimary(
 		ChunkHandle(req.ChunkHandle),
 		ChunkServerID(req.ServerId),
 	)
@@ -276,7 +690,7 @@ func (s *GRPCServer) ClaimPrimary(ctx context.Context, req *pb.ClaimPrimaryReque
 	if !ok {
 		return &pb.ClaimPrimaryResponse{
 			Success: false,
-			Message: "claim primary failed: another server holds the lease or chunk not found",
+			Message: "claim primary failed: another server holds the lease, chunk not found or chunk shared by a snapshot",
 		}, nil
 	}
 
@@ -284,6 +698,70 @@ func (s *GRPCServer) ClaimPrimary(ctx context.Context, req *pb.ClaimPrimaryReque
 		Success:         true,
 		Message:         "primary claimed",
 		LeaseDurationMs: uint64(LeaseDuration.Milliseconds()),
+		Version:         version,
+	}, nil
+}
+
+// ReportReplication is called by a source chunkserver after copying a chunk to another server
+func (s *GRPCServer) ReportReplication(ctx context.Context, req *pb.ReportReplicationRequest) (*pb.ReportReplicationResponse, error) {
+	slog.Debug("received replication report",
+		"serverID", req.ServerId,
+		"chunkHandle", req.ChunkHandle,
+		"target", req.TargetServerId,
+		"success", req.Success)
+
+	s.master.CompleteReplication(
+		ChunkServerID(req.ServerId),
+		ChunkHandle(req.ChunkHandle),
+		ChunkServerID(req.TargetServerId),
+		req.Success,
+		req.Message,
+	)
+
+	return &pb.ReportReplicationResponse{
+		Success: true,
+		Message: "replication recorded",
+	}, nil
+}
+
+// ReportEncode is called by a chunkserver after running an EncodeChunkCommand
+func (s *GRPCServer) ReportEncode(ctx context.Context, req *pb.ReportEncodeRequest) (*pb.ReportEncodeResponse, error) {
+	slog.Debug("received encode report",
+		"serverID", req.ServerId,
+		"chunkHandle", req.ChunkHandle,
+		"success", req.Success)
+
+	s.master.CompleteEncode(
+		ChunkServerID(req.ServerId),
+		ChunkHandle(req.ChunkHandle),
+		req.Success,
+		req.Message,
+		req.ShardSize,
+	)
+
+	return &pb.ReportEncodeResponse{
+		Success: true,
+		Message: "encode recorded",
+	}, nil
+}
+
+// ReportCorruptChunk is called by a chunkserver whose replica failed checksum verification
+func (s *GRPCServer) ReportCorruptChunk(ctx context.Context, req *pb.ReportCorruptChunkRequest) (*pb.ReportCorruptChunkResponse, error) {
+	err := s.master.DropCorruptReplica(
+		ChunkServerID(req.ServerId),
+		ChunkHandle(req.ChunkHandle),
+		req.Reason,
+	)
+	if err != nil {
+		return &pb.ReportCorruptChunkResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	return &pb.ReportCorruptChunkResponse{
+		Success: true,
+		Message: "replica scheduled for replacement",
 	}, nil
 }
 
@@ -292,16 +770,158 @@ func (s *GRPCServer) GetClusterStatus(ctx context.Context, req *pb.GetClusterSta
 	statuses := s.master.GetClusterStatus()
 
 	protoStatuses := make([]*pb.ChunkServerStatus, 0, len(statuses))
+	var capacity, free, used, logical uint64
 	for _, status := range statuses {
 		protoStatuses = append(protoStatuses, &pb.ChunkServerStatus{
-			Server:     chunkLocationToProto(status.Location),
-			ChunkCount: int32(status.ChunkCount),
-			IsAlive:    status.IsAlive,
+			Server:        chunkLocationToProto(status.Location),
+			ChunkCount:    int32(status.ChunkCount),
+			IsAlive:       status.IsAlive,
+			FailureDomain: status.Location.FailureDomain,
+			Draining:      status.Draining,
+			Disks:         disksToProto(status.Disks),
+			CapacityBytes: status.Usage.CapacityBytes,
+			FreeBytes:     status.Usage.FreeBytes,
+			UsedBytes:     status.Usage.UsedBytes,
+			Load: &pb.ServerLoad{
+				OpenConnections:  status.Load.OpenConnections,
+				ReadBytesPerSec:  status.Load.ReadBytesPerSec,
+				WriteBytesPerSec: status.Load.WriteBytesPerSec,
+			},
+			LastHeartbeat:  status.LastHeartbeat.Unix(),
+			LastFullReport: status.LastFullReport.Unix(),
+
+			LogicalBytes:     status.Usage.LogicalBytes,
+			CompressedChunks: int32(status.Usage.CompressedChunks),
 		})
+		if status.IsAlive {
+			capacity += status.Usage.CapacityBytes
+			free += status.Usage.FreeBytes
+			used += status.Usage.UsedBytes
+			logical += status.Usage.LogicalBytes
+		}
 	}
 
 	return &pb.GetClusterStatusResponse{
-		Servers: protoStatuses,
+		Servers:       protoStatuses,
+		CapacityBytes: capacity,
+		FreeBytes:     free,
+		UsedBytes:     used,
+		LogicalBytes:  logical,
+	}, nil
+}
+
+// DrainChunkServer starts or cancels draining a chunkserver
+func (s *GRPCServer) DrainChunkServer(ctx context.Context, req *pb.DrainChunkServerRequest) (*pb.DrainChunkServerResponse, error) {
+	if err := s.master.SetDraining(ChunkServerID(req.ServerId), !req.Cancel); err != nil {
+		return &pb.DrainChunkServerResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	message := "drain started"
+	if req.Cancel {
+		message = "drain cancelled"
+	}
+	return &pb.DrainChunkServerResponse{
+		Success: true,
+		Message: message,
+	}, nil
+}
+
+// GetDrainStatus reports the progress of draining chunkservers
+func (s *GRPCServer) GetDrainStatus(ctx context.Context, req *pb.GetDrainStatusRequest) (*pb.GetDrainStatusResponse, error) {
+	statuses, err := s.master.GetDrainStatus(ChunkServerID(req.ServerId))
+	if err != nil {
+		return &pb.GetDrainStatusResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	servers := make([]*pb.DrainStatus, 0, len(statuses))
+	for _, status := range statuses {
+		servers = append(servers, &pb.DrainStatus{
+			ServerId:        string(status.ServerID),
+			IsAlive:         status.IsAlive,
+			StartedAt:       status.StartedAt.Unix(),
+			MovedChunks:     int32(status.MovedChunks),
+			RemainingChunks: int32(status.RemainingChunks),
+			InflightMoves:   int32(status.InflightMoves),
+			Complete:        status.Complete,
+		})
+	}
+
+	return &pb.GetDrainStatusResponse{
+		Success: true,
+		Servers: servers,
+	}, nil
+}
+
+// Rebalance starts or stops moving replicas between chunkservers
+func (s *GRPCServer) Rebalance(ctx context.Context, req *pb.RebalanceRequest) (*pb.RebalanceResponse, error) {
+	if req.Cancel {
+		s.master.StopRebalance()
+		return &pb.RebalanceResponse{
+			Success: true,
+			Message: "rebalance stopped",
+		}, nil
+	}
+
+	if err := s.master.StartRebalance(req.Threshold); err != nil {
+		return &pb.RebalanceResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+	return &pb.RebalanceResponse{
+		Success: true,
+		Message: "rebalance started",
+	}, nil
+}
+
+// GetRebalanceStatus reports the progress of a rebalance and current disk fill
+func (s *GRPCServer) GetRebalanceStatus(ctx context.Context, req *pb.GetRebalanceStatusRequest) (*pb.GetRebalanceStatusResponse, error) {
+	status := s.master.GetRebalanceStatus()
+
+	servers := make([]*pb.ServerFill, 0, len(status.Servers))
+	for _, server := range status.Servers {
+		servers = append(servers, &pb.ServerFill{
+			ServerId:  string(server.ServerID),
+			FillRatio: server.FillRatio,
+		})
+	}
+
+	resp := &pb.GetRebalanceStatusResponse{
+		Active:        status.Active,
+		Threshold:     status.Threshold,
+		MovedChunks:   int32(status.MovedChunks),
+		InflightMoves: int32(status.InflightMoves),
+		Spread:        status.Spread,
+		Servers:       servers,
+	}
+	if status.Active {
+		resp.StartedAt = status.StartedAt.Unix()
+	}
+	return resp, nil
+}
+
+// GetLeader reports the consensus group's current leader (served by every replica)
+func (s *GRPCServer) GetLeader(ctx context.Context, req *pb.GetLeaderRequest) (*pb.GetLeaderResponse, error) {
+	info := s.master.GetLeader()
+
+	replicas := make([]*pb.MasterReplica, 0, len(info.Replicas))
+	for id, addr := range info.Replicas {
+		replicas = append(replicas, &pb.MasterReplica{Id: id, Address: addr})
+	}
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Id < replicas[j].Id })
+
+	return &pb.GetLeaderResponse{
+		Replicated:    info.Replicated,
+		LeaderId:      info.LeaderID,
+		LeaderAddress: info.LeaderAddress,
+		ReplicaId:     info.ReplicaID,
+		Replicas:      replicas,
 	}, nil
 }
 
@@ -326,6 +946,8 @@ func fileInfoToProto(f *FileInfo) *pb.FileInfoResponse {
 		Namespace:    namespace,
 		CreatedAt:    f.CreatedAt.Unix(),
 		ModifiedAt:   f.ModifiedAt.Unix(),
+		StorageClass: f.StorageClass,
+		Attributes:   f.Attributes,
 	}
 }
 
@@ -346,9 +968,62 @@ func chunkInfoToProto(c *ChunkInfo) *pb.ChunkLocationInfo {
 		Primary:     primary,
 		Version:     c.Version,
 		Size:        c.Size,
+		Shared:      c.Refs > 1,
+		Stripe:      stripeToProto(c.Handle, c.Stripe),
+	}
+}
+
+// chunkInfoWithGrants converts chunk locations for a client, with the grants
+// its data-plane requests need when grants are enabled
+func (s *GRPCServer) chunkInfoWithGrants(c *ChunkInfo) *pb.ChunkLocationInfo {
+	info := chunkInfoToProto(c)
+	info.ReadToken = s.master.grant(info.ChunkHandle, datatoken.OpRead)
+	if info.Stripe == nil {
+		info.WriteToken = s.master.grant(info.ChunkHandle, datatoken.OpWrite)
+		return info
+	}
+	for _, f := range info.Stripe.Fragments {
+		f.ReadToken = s.master.grant(f.Handle, datatoken.OpRead)
+	}
+	return info
+}
+
+func stripeToProto(handle ChunkHandle, s *StripeInfo) *pb.ChunkStripe {
+	if s == nil {
+		return nil
+	}
+	fragments := make([]*pb.StripeFragment, len(s.Fragments))
+	for i, locs := range s.Fragments {
+		locations := make([]*pb.ChunkServerInfo, len(locs))
+		for j, loc := range locs {
+			locations[j] = chunkLocationToProto(&loc)
+		}
+		fragments[i] = &pb.StripeFragment{
+			Index:     uint32(i),
+			Handle:    string(fragmentHandle(handle, i)),
+			Locations: locations,
+		}
+	}
+	return &pb.ChunkStripe{
+		DataShards:   uint32(s.DataShards),
+		ParityShards: uint32(s.ParityShards),
+		ShardSize:    s.ShardSize,
+		Fragments:    fragments,
 	}
 }
 
+func fragmentPlacementsToProto(handle ChunkHandle, placements []FragmentPlacement) []*pb.FragmentPlacement {
+	out := make([]*pb.FragmentPlacement, len(placements))
+	for i, p := range placements {
+		out[i] = &pb.FragmentPlacement{
+			Index:  uint32(p.Index),
+			Handle: string(fragmentHandle(handle, p.Index)),
+			Server: chunkLocationToProto(&p.Server),
+		}
+	}
+	return out
+}
+
 func chunkLocationToProto(loc *ChunkLocation) *pb.ChunkServerInfo {
 	return &pb.ChunkServerInfo{
 		ServerId:        string(loc.ServerID),
@@ -357,3 +1032,63 @@ func chunkLocationToProto(loc *ChunkLocation) *pb.ChunkServerInfo {
 		ReplicationPort: int32(loc.ReplicationPort),
 	}
 }
+
+func disksFromProto(disks []*pb.DiskStatus) []DiskStatus {
+	out := make([]DiskStatus, len(disks))
+	for i, d := range disks {
+		out[i] = DiskStatus{
+			Dir:              d.Dir,
+			CapacityBytes:    d.CapacityBytes,
+			FreeBytes:        d.FreeBytes,
+			UsedBytes:        d.UsedBytes,
+			LogicalBytes:     d.LogicalBytes,
+			ChunkCount:       int(d.ChunkCount),
+			CompressedChunks: int(d.CompressedChunks),
+			Failed:           d.Failed,
+		}
+	}
+	return out
+}
+
+func disksToProto(disks []DiskStatus) []*pb.DiskStatus {
+	out := make([]*pb.DiskStatus, len(disks))
+	for i, d := range disks {
+		out[i] = &pb.DiskStatus{
+			Dir:              d.Dir,
+			CapacityBytes:    d.CapacityBytes,
+			FreeBytes:        d.FreeBytes,
+			UsedBytes:        d.UsedBytes,
+			LogicalBytes:     d.LogicalBytes,
+			ChunkCount:       int32(d.ChunkCount),
+			CompressedChunks: int32(d.CompressedChunks),
+			Failed:           d.Failed,
+		}
+	}
+	return out
+}
+
+// chunkReportFromProto reads the chunk report of a heartbeat. Chunkservers that
+// predate incremental reports send their full chunk list in chunk_handles.
+func chunkReportFromProto(req *pb.HeartbeatRequest) ChunkReport {
+	if req.ChunkReport == nil {
+		return ChunkReport{
+			Full:     true,
+			Added:    toChunkHandles(req.ChunkHandles),
+			Versions: req.ChunkVersions,
+		}
+	}
+	return ChunkReport{
+		Full:     req.ChunkReport.Full,
+		Added:    toChunkHandles(req.ChunkReport.Added),
+		Removed:  toChunkHandles(req.ChunkReport.Removed),
+		Versions: req.ChunkReport.Versions,
+	}
+}
+
+func toChunkHandles(handles []string) []ChunkHandle {
+	out := make([]ChunkHandle, len(handles))
+	for i, h := range handles {
+		out[i] = ChunkHandle(h)
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package master
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Page size bounds for ListFilesPage
+const (
+	DefaultListLimit = 1000
+	MaxListLimit     = 10000
+)
+
+// ErrInvalidPageToken is returned for a page token this master did not issue
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ListOptions selects one page of a listing
+type ListOptions struct {
+	Namespace string
+	Prefix    string
+	Delimiter string
+	Limit     int
+	PageToken string
+}
+
+// ListPage is one page of a listing in path order
+type ListPage struct {
+	Files          []*FileInfo
+	CommonPrefixes []string
+	NextPageToken  string
+}
+
+// ListFilesPage returns one page of the files under a prefix, sorted by path.
+// With a delimiter, files that contain it after the prefix are returned once as the
+// common prefix up to and including the delimiter, like a directory.
+// The page token holds the last path or prefix returned, so a page never repeats or
+// skips entries that existed for the whole listing.
+func (m *Master) ListFilesPage(opts ListOptions) (*ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	after := ""
+	if opts.PageToken != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(opts.PageToken)
+		// Every name a listing returns starts with its prefix
+		if err != nil || len(decoded) == 0 || !strings.HasPrefix(string(decoded), opts.Prefix) {
+			return nil, ErrInvalidPageToken
+		}
+		after = string(decoded)
+	}
+
+	namespace := normalizeNamespace(opts.Namespace)
+
+	// Keep the limit+1 smallest entries; the extra one tells us there is another page
+	page := &listHeap{}
+	seenPrefixes := make(map[string]bool)
+
+	m.fileMu.RLock()
+	for key, f := range m.files {
+		if key.Namespace != namespace || !strings.HasPrefix(key.Path, opts.Prefix) {
+			continue
+		}
+		name, isPrefix := listName(key.Path, opts.Prefix, opts.Delimiter)
+		if after != "" && name <= after {
+			continue
+		}
+		if isPrefix {
+			if seenPrefixes[name] {
+				continue
+			}
+			seenPrefixes[name] = true
+			f = nil
+		}
+		if page.Len() <= limit {
+			heap.Push(page, listEntry{name: name, file: f})
+		} else if name < (*page)[0].name {
+			(*page)[0] = listEntry{name: name, file: f}
+			heap.Fix(page, 0)
+		}
+	}
+	m.fileMu.RUnlock()
+
+	entries := []listEntry(*page)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	result := &ListPage{}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		result.NextPageToken = base64.RawURLEncoding.EncodeToString([]byte(entries[limit-1].name))
+	}
+	for _, e := range entries {
+		if e.file == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, e.name)
+		} else {
+			result.Files = append(result.Files, e.file)
+		}
+	}
+	return result, nil
+}
+
+// listName returns the name a path is listed under: the path itself, or its
+// common prefix when the delimiter occurs after the listing prefix
+func listName(path, prefix, delimiter string) (string, bool) {
+	if delimiter == "" {
+		return path, false
+	}
+	idx := strings.Index(path[len(prefix):], delimiter)
+	if idx < 0 {
+		return path, false
+	}
+	return path[:len(prefix)+idx+len(delimiter)], true
+}
+
+// listEntry is a file, or a common prefix when file is nil
+type listEntry struct {
+	name string
+	file *FileInfo
+}
+
+// listHeap is a max-heap of entries by name
+type listHeap []listEntry
+
+func (h listHeap) Len() int           { return len(h) }
+func (h listHeap) Less(i, j int) bool { return h[i].name > h[j].name }
+func (h listHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *listHeap) Push(x any)        { *h = append(*h, x.(listEntry)) }
+func (h *listHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,197 @@
+package master
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// createTestFiles creates empty files at paths in namespace
+func createTestFiles(t *testing.T, m *Master, namespace string, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if _, err := m.CreateFile(path, namespace, "", nil); err != nil {
+			t.Fatalf("CreateFile %s: %v", path, err)
+		}
+	}
+}
+
+// listNames returns a page's file paths and common prefixes in path order
+func listNames(page *ListPage) []string {
+	names := slices.Clone(page.CommonPrefixes)
+	for _, f := range page.Files {
+		names = append(names, f.Path)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// listAll pages through a listing, returning every page's names
+func listAll(t *testing.T, m *Master, opts ListOptions) [][]string {
+	t.Helper()
+	var pages [][]string
+	for {
+		page, err := m.ListFilesPage(opts)
+		if err != nil {
+			t.Fatalf("ListFilesPage: %v", err)
+		}
+		pages = append(pages, listNames(page))
+		if page.NextPageToken == "" {
+			return pages
+		}
+		opts.PageToken = page.NextPageToken
+	}
+}
+
+func TestListFilesPageBoundaries(t *testing.T) {
+	tests := []struct {
+		files, limit int
+		want         []int // Page sizes
+	}{
+		{files: 25, limit: 10, want: []int{10, 10, 5}},
+		{files: 20, limit: 10, want: []int{10, 10}},
+		{files: 10, limit: 10, want: []int{10}},
+		{files: 3, limit: 1, want: []int{1, 1, 1}},
+		{files: 0, limit: 10, want: []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d files by %d", tt.files, tt.limit), func(t *testing.T) {
+			m := newTestMaster(t)
+			var want []string
+			for i := range tt.files {
+				want = append(want, fmt.Sprintf("/dir/f%02d", i))
+			}
+			createTestFiles(t, m, "ns", want...)
+			// Outside the prefix or namespace
+			createTestFiles(t, m, "ns", "/other")
+			createTestFiles(t, m, "elsewhere", "/dir/f00")
+
+			pages := listAll(t, m, ListOptions{Namespace: "ns", Prefix: "/dir/", Limit: tt.limit})
+			var sizes []int
+			var got []string
+			for _, page := range pages {
+				sizes = append(sizes, len(page))
+				got = append(got, page...)
+			}
+			if !slices.Equal(sizes, tt.want) {
+				t.Errorf("page sizes = %v, want %v", sizes, tt.want)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("listed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestListFilesPageDelimiter(t *testing.T) {
+	m := newTestMaster(t)
+	createTestFiles(t, m, "ns", "/a/1", "/a/b/2", "/a/b/3", "/a/c/x/4", "/a/d", "/ab", "/b/5")
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want [][]string
+	}{
+		{
+			name: "directories are listed once across pages",
+			opts: ListOptions{Prefix: "/a/", Delimiter: "/", Limit: 2},
+			want: [][]string{{"/a/1", "/a/b/"}, {"/a/c/", "/a/d"}},
+		},
+		{
+			name: "root",
+			opts: ListOptions{Delimiter: "/", Limit: 10},
+			want: [][]string{{"/"}},
+		},
+		{
+			name: "prefix is not a directory",
+			opts: ListOptions{Prefix: "/a", Delimiter: "/", Limit: 10},
+			want: [][]string{{"/a/", "/ab"}},
+		},
+		{
+			name: "no delimiter lists every file",
+			opts: ListOptions{Prefix: "/a/", Limit: 3},
+			want: [][]string{{"/a/1", "/a/b/2", "/a/b/3"}, {"/a/c/x/4", "/a/d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Namespace = "ns"
+			if got := listAll(t, m, tt.opts); !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListFilesPageInvalidToken(t *testing.T) {
+	m := newTestMaster(t)
+	createTestFiles(t, m, "ns", "/a/1", "/a/2")
+
+	token := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "!!not-a-token!!"},
+		{name: "padded", token: token("/a/1") + "=="},
+		{name: "outside the prefix", token: token("/b/1")},
+		{name: "truncated to before the prefix", token: token("/")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.ListFilesPage(ListOptions{Namespace: "ns", Prefix: "/a/", PageToken: tt.token})
+			if !errors.Is(err, ErrInvalidPageToken) {
+				t.Errorf("err = %v, want ErrInvalidPageToken", err)
+			}
+		})
+	}
+
+	// A token naming a path that no longer exists resumes after it
+	page, err := m.ListFilesPage(ListOptions{Namespace: "ns", Prefix: "/a/", PageToken: token("/a/1x")})
+	if err != nil {
+		t.Fatalf("ListFilesPage: %v", err)
+	}
+	if got := listNames(page); !slices.Equal(got, []string{"/a/2"}) {
+		t.Errorf("resumed listing = %v, want [/a/2]", got)
+	}
+}
+
+// Files created or deleted between pages must not make the listing repeat or
+// skip a file that existed throughout
+func TestListFilesPageConcurrentChanges(t *testing.T) {
+	m := newTestMaster(t)
+	m.SetTrashRetention(0)
+	for i := range 10 {
+		createTestFiles(t, m, "ns", fmt.Sprintf("/f%d", i))
+	}
+
+	opts := ListOptions{Namespace: "ns", Limit: 3}
+	first, err := m.ListFilesPage(opts)
+	if err != nil {
+		t.Fatalf("ListFilesPage: %v", err)
+	}
+	if got := listNames(first); !slices.Equal(got, []string{"/f0", "/f1", "/f2"}) {
+		t.Fatalf("first page = %v", got)
+	}
+
+	// Delete one listed file, the last one listed, and one not listed yet;
+	// create files before and after the token
+	for _, path := range []string{"/f1", "/f2", "/f5"} {
+		if err := m.DeleteFile(path, "ns"); err != nil {
+			t.Fatalf("DeleteFile %s: %v", path, err)
+		}
+	}
+	createTestFiles(t, m, "ns", "/f0a", "/f2", "/f4a")
+
+	opts.PageToken = first.NextPageToken
+	var rest []string
+	for _, page := range listAll(t, m, opts) {
+		rest = append(rest, page...)
+	}
+	want := []string{"/f3", "/f4", "/f4a", "/f6", "/f7", "/f8", "/f9"}
+	if !slices.Equal(rest, want) {
+		t.Errorf("remaining pages = %v, want %v", rest, want)
+	}
+}
@@ -3,11 +3,17 @@ package master
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"eddisonso.com/go-gfs/internal/datatoken"
+	"eddisonso.com/go-gfs/internal/erasure"
+	"eddisonso.com/go-gfs/internal/master/consensus"
 	"eddisonso.com/go-gfs/internal/master/wal"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"github.com/google/uuid"
 )
 
@@ -24,7 +30,51 @@ type ChunkLocation struct {
 	DataPort        int
 	ReplicationPort int
 	LastHeartbeat   time.Time
-	BuildInfo       *BuildInfo // Build version info
+	BuildInfo       *BuildInfo   // Build version info
+	FailureDomain   string       // Placement spread label (node, rack, arch...)
+	CapacityBytes   uint64       // Storage capacity reported by the server
+	FreeBytes       uint64       // Free storage reported by the server
+	UsedBytes       uint64       // Size of the chunks stored on the server
+	LogicalBytes    uint64       // Size of the chunks before compression
+	ChunkCount      int          // Chunks reported in the last heartbeat
+	Compressed      int          // Chunks stored compressed
+	Disks           []DiskStatus // Data directories reported in the last heartbeat
+	Load            ServerLoad   // Data-plane load reported in the last heartbeat
+	LastFullReport  time.Time    // When the server last listed every chunk it holds
+}
+
+// DiskUsage is the storage capacity a chunkserver reports
+type DiskUsage struct {
+	CapacityBytes    uint64
+	FreeBytes        uint64
+	UsedBytes        uint64
+	LogicalBytes     uint64 // UsedBytes before compression
+	CompressedChunks int
+	Disks            []DiskStatus
+}
+
+// DiskStatus is the usage of one data directory on a chunkserver
+type DiskStatus struct {
+	Dir              string
+	CapacityBytes    uint64
+	FreeBytes        uint64
+	UsedBytes        uint64
+	LogicalBytes     uint64
+	ChunkCount       int
+	CompressedChunks int
+	Failed           bool
+}
+
+// ServerLoad is the data-plane load a chunkserver reports, averaged over a heartbeat interval
+type ServerLoad struct {
+	OpenConnections  int64
+	ReadBytesPerSec  uint64
+	WriteBytesPerSec uint64
+}
+
+// traffic is the combined read and write throughput
+func (l ServerLoad) traffic() uint64 {
+	return l.ReadBytesPerSec + l.WriteBytesPerSec
 }
 
 // BuildInfo contains build version information
@@ -44,6 +94,7 @@ const (
 // Lease duration for primary assignment
 const LeaseDuration = 60 * time.Second
 const LeaseGracePeriod = 60 * time.Second // Extra time before reassigning primary
+const HeartbeatTimeout = 30 * time.Second // Consider server dead if no heartbeat in 30s
 const defaultNamespace = "default"
 
 type fileKey struct {
@@ -71,11 +122,20 @@ type ChunkInfo struct {
 	FilePath        string          // File this chunk belongs to
 	Namespace       string          // Namespace this chunk belongs to
 	Locations       []ChunkLocation // Replica locations
-	Version         uint64          // Chunk version for consistency
+	Version         uint64          // Chunk version, bumped with every new lease
 	Primary         *ChunkLocation  // Current primary (lease holder)
 	LeaseExpiration time.Time       // When the primary's lease expires
 	Status          ChunkStatus     // Pending or Committed
 	Size            uint64          // Actual size written (set on commit)
+	Refs            int             // Files using this chunk; above 1 it is shared by a snapshot
+
+	// Newest version a write committed with; replicas reporting older ones are stale
+	CommittedVersion uint64
+	committedAt      time.Time
+
+	// Set once the chunk is erasure coded; Locations is then empty
+	Stripe   *StripeInfo
+	encoding bool // An encode is running; no new leases until it finishes
 }
 
 // FileInfo contains metadata about a file
@@ -87,6 +147,14 @@ type FileInfo struct {
 	ChunkSize  uint64        // Size of each chunk (default 64MB)
 	CreatedAt  time.Time
 	ModifiedAt time.Time
+
+	StorageClass string // Empty to use the namespace's class
+
+	// User-defined key/value metadata. Replaced as a whole on every change and never
+	// modified in place, so a map handed out under fileMu can be read after it is released
+	Attributes map[string]string
+
+	Trash *TrashInfo // Where the file was deleted from, for files in the trash
 }
 
 // Master is the central metadata server for GFS
@@ -111,24 +179,99 @@ type Master struct {
 	orphanedChunks   map[string]time.Time
 	orphanedChunksMu sync.Mutex
 
+	// Servers whose next heartbeat should list every chunk, to settle a replica
+	// the master could not place from an incremental report
+	fullReportRequests   map[ChunkServerID]bool
+	fullReportRequestsMu sync.Mutex
+
+	// Re-replication work: queued commands per source server and in-flight copies
+	pendingReplications  map[ChunkServerID][]ReplicationTask
+	inflightReplications map[ChunkHandle]*ReplicationTask
+	replicationMu        sync.Mutex
+
+	// Namespace quotas and running usage totals (guarded by fileMu)
+	quotas map[string]NamespaceQuota
+	usage  map[string]*namespaceTotals
+
+	// Default storage class per namespace (guarded by fileMu)
+	storageClasses map[string]string
+
+	// Compression of sealed chunks per namespace (guarded by fileMu)
+	compression map[string]string
+
+	// Compression work: chunks queued per server, and the replicas already asked
+	// to compress each chunk (guarded by replicationMu)
+	pendingCompresses map[ChunkServerID][]ChunkHandle
+	compressSent      map[ChunkHandle]map[ChunkServerID]bool
+
+	// Erasure coding work: queued encode commands per server and in-flight encodes
+	// (guarded by replicationMu)
+	pendingEncodes  map[ChunkServerID][]EncodeTask
+	inflightEncodes map[ChunkHandle]*EncodeTask
+
+	// Draining chunkservers and the running rebalance
+	draining  map[ChunkServerID]*drainState
+	rebalance *rebalanceState
+	drainMu   sync.Mutex
+
+	// Copy-on-write copies in progress, by shared chunk handle
+	cowInflight map[ChunkHandle]chan struct{}
+	cowMu       sync.Mutex
+
 	// Configuration
 	defaultChunkSize  uint64
 	replicationFactor int
+	coldSealAge       time.Duration
+	trashRetention    time.Duration
+
+	// Credentials for dialing chunkservers (nil is plaintext) and signing
+	// data-plane grants (nil leaves clients on the shared secret)
+	tls    *mtls.Source
+	grants *datatoken.Issuer
 
 	// Write-ahead log for persistence
 	wal *wal.WAL
+
+	// Held shared from a WAL write to its apply by mutations that log outside
+	// fileMu; snapshots hold it exclusively while cutting the WAL
+	cutMu sync.RWMutex
+
+	// Recent namespace changes, fed from the WAL, for WatchNamespace
+	watch *watchHub
+
+	// Consensus group with standby masters (nil when running standalone)
+	raft           *consensus.Node
+	appliedIndex   atomic.Uint64 // Last consensus log index known to be reflected in memory
+	committedIndex atomic.Uint64 // Last consensus log index written to the local WAL
 }
 
 // NewMaster creates a new master server with WAL at the given path
 func NewMaster(walPath string) (*Master, error) {
 	m := &Master{
-		files:             make(map[fileKey]*FileInfo),
-		chunks:            make(map[ChunkHandle]*ChunkInfo),
-		chunkservers:      make(map[ChunkServerID]*ChunkLocation),
-		pendingDeletes:    make(map[ChunkServerID][]ChunkHandle),
-		orphanedChunks:    make(map[string]time.Time),
-		defaultChunkSize:  64 << 20, // 64MB
-		replicationFactor: 3,
+		files:              make(map[fileKey]*FileInfo),
+		chunks:             make(map[ChunkHandle]*ChunkInfo),
+		chunkservers:       make(map[ChunkServerID]*ChunkLocation),
+		pendingDeletes:     make(map[ChunkServerID][]ChunkHandle),
+		orphanedChunks:     make(map[string]time.Time),
+		fullReportRequests: make(map[ChunkServerID]bool),
+		defaultChunkSize:   64 << 20, // 64MB
+		replicationFactor:  3,
+		coldSealAge:        DefaultColdSealAge,
+		trashRetention:     DefaultTrashRetention,
+
+		pendingReplications:  make(map[ChunkServerID][]ReplicationTask),
+		inflightReplications: make(map[ChunkHandle]*ReplicationTask),
+		cowInflight:          make(map[ChunkHandle]chan struct{}),
+		draining:             make(map[ChunkServerID]*drainState),
+		quotas:               make(map[string]NamespaceQuota),
+		usage:                make(map[string]*namespaceTotals),
+		storageClasses:       make(map[string]string),
+		compression:          make(map[string]string),
+		pendingCompresses:    make(map[ChunkServerID][]ChunkHandle),
+		compressSent:         make(map[ChunkHandle]map[ChunkServerID]bool),
+		pendingEncodes:       make(map[ChunkServerID][]EncodeTask),
+		inflightEncodes:      make(map[ChunkHandle]*EncodeTask),
+		watch:                newWatchHub(DefaultWatchHistory),
 	}
 
 	// Initialize WAL
@@ -137,26 +280,40 @@ func NewMaster(walPath string) (*Master, error) {
 		return nil, fmt.Errorf("failed to initialize WAL: %w", err)
 	}
 	m.wal = w
+	m.watch.wal = w
 
-	// Load snapshot first (if exists)
+	// Load snapshot first (if exists); the WAL segments it covers are gone,
+	// so starting without it would lose metadata
 	snapshot, err := w.ReadSnapshot()
 	if err != nil {
-		slog.Warn("failed to read snapshot, starting fresh", "error", err)
-	} else if snapshot != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var fromSegment uint64
+	if snapshot != nil {
 		m.RestoreFromSnapshot(snapshot)
+		m.appliedIndex.Store(snapshot.Index)
+		fromSegment = snapshot.Segment
+
+		// Watchers can only resume from after the snapshot
+		seq := max(snapshot.Seq, snapshot.Index)
+		w.SetSeq(seq)
+		m.watch.reset(seq)
+		m.watch.setHistory(watchHistory{segment: snapshot.Segment, floor: seq})
 	}
 
 	// Replay WAL entries after snapshot
-	if err := m.replayWAL(walPath); err != nil {
+	if err := m.replayWAL(walPath, fromSegment); err != nil {
 		return nil, fmt.Errorf("failed to replay WAL: %w", err)
 	}
+	w.SetObserver(m.watch.logged)
 
 	return m, nil
 }
 
-// replayWAL replays the WAL to restore master state
-func (m *Master) replayWAL(walPath string) error {
-	reader, err := wal.NewReader(walPath)
+// replayWAL replays the WAL from fromSegment on to restore master state
+// Replicated entries already covered by the snapshot are skipped
+func (m *Master) replayWAL(walPath string, fromSegment uint64) error {
+	reader, err := wal.NewReader(walPath, fromSegment)
 	if err != nil {
 		return err
 	}
@@ -166,77 +323,231 @@ func (m *Master) replayWAL(walPath string) error {
 	}
 	defer reader.Close()
 
-	entries, err := reader.ReadAll()
-	if err != nil {
-		return err
-	}
+	slog.Info("replaying WAL", "fromSegment", fromSegment)
 
-	slog.Info("replaying WAL", "entries", len(entries))
+	entries := 0
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		entries++
 
-	for _, entry := range entries {
-		switch entry.Op {
-		case wal.OpCreateFile:
-			var data wal.CreateFileData
-			if err := json.Unmarshal(entry.Data, &data); err != nil {
-				slog.Warn("failed to unmarshal CREATE_FILE", "error", err)
+		if entry.Index != 0 {
+			if entry.Index <= m.appliedIndex.Load() {
 				continue
 			}
-			m.replayCreateFile(data.Path, data.Namespace, data.ChunkSize)
+			m.appliedIndex.Store(entry.Index)
+		}
+		m.applyEntry(entry)
 
-		case wal.OpDeleteFile:
-			var data wal.DeleteFileData
-			if err := json.Unmarshal(entry.Data, &data); err != nil {
-				slog.Warn("failed to unmarshal DELETE_FILE", "error", err)
-				continue
-			}
-			m.replayDeleteFile(data.Path, data.Namespace)
+		seq := wal.Position(entry, m.wal.Seq())
+		m.wal.SetSeq(seq)
+		m.watch.logged(seq, entry)
+		m.watch.applied(seq)
+	}
 
-		case wal.OpDeleteNamespace:
-			var data wal.DeleteNamespaceData
-			if err := json.Unmarshal(entry.Data, &data); err != nil {
-				slog.Warn("failed to unmarshal DELETE_NAMESPACE", "error", err)
-				continue
-			}
-			m.replayDeleteNamespace(data.Namespace)
+	slog.Info("WAL replay complete", "entries", entries, "files", len(m.files), "chunks", len(m.chunks))
+	return nil
+}
 
-		case wal.OpRenameFile:
-			var data wal.RenameFileData
-			if err := json.Unmarshal(entry.Data, &data); err != nil {
-				slog.Warn("failed to unmarshal RENAME_FILE", "error", err)
-				continue
-			}
-			m.replayRenameFile(data.OldPath, data.NewPath, data.Namespace)
+// applyEntry applies one WAL entry to memory (no WAL logging)
+// Callers other than startup replay must hold fileMu and chunkMu
+func (m *Master) applyEntry(entry wal.Entry) {
+	switch entry.Op {
+	case wal.OpCreateFile:
+		var data wal.CreateFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal CREATE_FILE", "error", err)
+			return
+		}
+		m.replayCreateFile(data.Path, data.Namespace, data.ChunkSize, data.StorageClass, data.Attributes)
 
-		case wal.OpAddChunk:
-			var data wal.AddChunkData
-			if err := json.Unmarshal(entry.Data, &data); err != nil {
-				slog.Warn("failed to unmarshal ADD_CHUNK", "error", err)
-				continue
-			}
-			m.replayAddChunk(data.Path, data.Namespace, data.ChunkHandle)
+	case wal.OpDeleteFile:
+		var data wal.DeleteFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal DELETE_FILE", "error", err)
+			return
+		}
+		m.replayDeleteFile(data.Path, data.Namespace)
 
-		case wal.OpCommitChunk:
-			var data wal.CommitChunkData
-			if err := json.Unmarshal(entry.Data, &data); err != nil {
-				slog.Warn("failed to unmarshal COMMIT_CHUNK", "error", err)
-				continue
-			}
-			m.replayCommitChunk(data.ChunkHandle, data.Size)
+	case wal.OpDeleteNamespace:
+		var data wal.DeleteNamespaceData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal DELETE_NAMESPACE", "error", err)
+			return
+		}
+		m.replayDeleteNamespace(data.Namespace)
 
-		case wal.OpSetCounter:
-			// Legacy counter entries are ignored - we now use UUIDs for chunk handles
+	case wal.OpRenameFile:
+		var data wal.RenameFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal RENAME_FILE", "error", err)
+			return
 		}
-	}
+		m.replayRenameFile(data.OldPath, data.NewPath, data.Namespace)
 
-	slog.Info("WAL replay complete", "files", len(m.files), "chunks", len(m.chunks))
-	return nil
+	case wal.OpAddChunk:
+		var data wal.AddChunkData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal ADD_CHUNK", "error", err)
+			return
+		}
+		m.replayAddChunk(data.Path, data.Namespace, data.ChunkHandle)
+
+	case wal.OpCommitChunk:
+		var data wal.CommitChunkData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal COMMIT_CHUNK", "error", err)
+			return
+		}
+		m.replayCommitChunk(data.ChunkHandle, data.Size, data.Version)
+
+	case wal.OpSetCounter:
+		// Legacy counter entries are ignored - we now use UUIDs for chunk handles
+
+	case wal.OpImportSnapshot:
+		var data wal.Snapshot
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal IMPORT_SNAPSHOT", "error", err)
+			return
+		}
+		m.restoreSnapshotLocked(&data)
+
+	case wal.OpSnapshotFile:
+		var data wal.SnapshotFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SNAPSHOT_FILE", "error", err)
+			return
+		}
+		m.replaySnapshotFile(data.SourcePath, data.DestPath, data.Namespace, data.DestNamespace)
+
+	case wal.OpSnapshotNamespace:
+		var data wal.SnapshotNamespaceData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SNAPSHOT_NAMESPACE", "error", err)
+			return
+		}
+		m.replaySnapshotNamespace(data.Namespace, data.DestNamespace)
+
+	case wal.OpReplaceChunk:
+		var data wal.ReplaceChunkData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal REPLACE_CHUNK", "error", err)
+			return
+		}
+		m.replayReplaceChunk(data.Path, data.Namespace, data.Index, data.OldHandle, data.ChunkHandle)
+
+	case wal.OpSetQuota:
+		var data wal.SetQuotaData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_QUOTA", "error", err)
+			return
+		}
+		m.replaySetQuota(data.Namespace, data.MaxBytes, data.MaxFiles)
+
+	case wal.OpSetChunkVersion:
+		var data wal.SetChunkVersionData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_CHUNK_VERSION", "error", err)
+			return
+		}
+		m.replaySetChunkVersion(data.ChunkHandle, data.Version)
+
+	case wal.OpSetDraining:
+		var data wal.SetDrainingData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_DRAINING", "error", err)
+			return
+		}
+		m.replaySetDraining(data.ServerID, data.Draining)
+
+	case wal.OpSetStorageClass:
+		var data wal.SetStorageClassData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_STORAGE_CLASS", "error", err)
+			return
+		}
+		m.replaySetStorageClass(data.Namespace, data.Path, data.StorageClass)
+
+	case wal.OpSetChunkStripe:
+		var data wal.SetChunkStripeData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_CHUNK_STRIPE", "error", err)
+			return
+		}
+		m.replaySetChunkStripe(data.ChunkHandle, data.DataShards, data.ParityShards, data.ShardSize)
+
+	case wal.OpSetFileAttributes:
+		var data wal.SetFileAttributesData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_FILE_ATTRIBUTES", "error", err)
+			return
+		}
+		m.replaySetFileAttributes(data.Path, data.Namespace, data.Attributes)
+
+	case wal.OpTrashFile:
+		var data wal.TrashFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal TRASH_FILE", "error", err)
+			return
+		}
+		m.replayTrashFile(data.Path, data.Namespace, time.Unix(0, data.DeletedAt))
+
+	case wal.OpTrashNamespace:
+		var data wal.TrashNamespaceData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal TRASH_NAMESPACE", "error", err)
+			return
+		}
+		m.replayTrashNamespace(data.Namespace, time.Unix(0, data.DeletedAt))
+
+	case wal.OpRestoreFile:
+		var data wal.RestoreFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal RESTORE_FILE", "error", err)
+			return
+		}
+		m.replayRestoreFile(data.TrashID, data.Path, data.Namespace)
+
+	case wal.OpPurgeTrash:
+		var data wal.PurgeTrashData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal PURGE_TRASH", "error", err)
+			return
+		}
+		m.replayPurgeTrash(data.TrashIDs)
+
+	case wal.OpTransact:
+		var data wal.TransactData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal TRANSACT", "error", err)
+			return
+		}
+		m.applyTransactLocked(data.Entries)
+
+	case wal.OpSetCompression:
+		var data wal.SetCompressionData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal SET_COMPRESSION", "error", err)
+			return
+		}
+		m.replaySetCompression(data.Namespace, data.Compression)
+	}
 }
 
 // replayCreateFile recreates a file from WAL (no WAL logging)
-func (m *Master) replayCreateFile(path, namespace string, chunkSize uint64) {
+func (m *Master) replayCreateFile(path, namespace string, chunkSize uint64, storageClass string, attributes map[string]string) {
 	namespace = normalizeNamespace(namespace)
 	now := time.Now()
-	m.files[makeFileKey(namespace, path)] = &FileInfo{
+	key := makeFileKey(namespace, path)
+	if old, exists := m.files[key]; exists {
+		m.untrackFileLocked(old)
+	}
+	file := &FileInfo{
 		Path:       path,
 		Namespace:  namespace,
 		Chunks:     []ChunkHandle{},
@@ -244,31 +555,38 @@ func (m *Master) replayCreateFile(path, namespace string, chunkSize uint64) {
 		ChunkSize:  chunkSize,
 		CreatedAt:  now,
 		ModifiedAt: now,
+
+		StorageClass: storageClass,
+		Attributes:   attributes,
 	}
+	m.files[key] = file
+	m.trackFileLocked(file)
 }
 
-// replayDeleteFile deletes a file from WAL (no WAL logging)
-func (m *Master) replayDeleteFile(path, namespace string) {
+// replayDeleteFile deletes a file from WAL (no WAL logging) and returns the chunks
+// no other file shares
+func (m *Master) replayDeleteFile(path, namespace string) []*ChunkInfo {
 	key := makeFileKey(namespace, path)
 	if file, exists := m.files[key]; exists {
-		for _, handle := range file.Chunks {
-			delete(m.chunks, handle)
-		}
 		delete(m.files, key)
+		m.untrackFileLocked(file)
+		return m.releaseChunksLocked(file.Chunks)
 	}
+	return nil
 }
 
 // replayDeleteNamespace deletes all files in a namespace from WAL (no WAL logging)
 func (m *Master) replayDeleteNamespace(namespace string) {
 	namespace = normalizeNamespace(namespace)
+	var handles []ChunkHandle
 	for key, file := range m.files {
 		if file.Namespace == namespace {
-			for _, handle := range file.Chunks {
-				delete(m.chunks, handle)
-			}
+			handles = append(handles, file.Chunks...)
 			delete(m.files, key)
+			m.untrackFileLocked(file)
 		}
 	}
+	m.releaseChunksLocked(handles)
 }
 
 // replayRenameFile renames a file from WAL (no WAL logging)
@@ -303,16 +621,19 @@ func (m *Master) replayAddChunk(path, namespace, chunkHandle string) {
 			Locations: []ChunkLocation{},
 			Version:   1,
 			Status:    ChunkPending,
+			Refs:      1,
 		}
 	}
 }
 
 // replayCommitChunk commits a chunk from WAL (no WAL logging)
-func (m *Master) replayCommitChunk(chunkHandle string, size uint64) {
+func (m *Master) replayCommitChunk(chunkHandle string, size, version uint64) {
 	handle := ChunkHandle(chunkHandle)
 	if chunk, exists := m.chunks[handle]; exists {
 		chunk.Status = ChunkCommitted
-		chunk.Size = size
+		// Commits can be reported out of order; a chunk never shrinks
+		chunk.Size = max(chunk.Size, size)
+		m.noteCommittedVersionLocked(chunk, version)
 		// Update file size
 		key := makeFileKey(chunk.Namespace, chunk.FilePath)
 		if file, exists := m.files[key]; exists {
@@ -322,7 +643,7 @@ func (m *Master) replayCommitChunk(chunkHandle string, size uint64) {
 					totalSize += c.Size
 				}
 			}
-			file.Size = totalSize
+			m.setFileSizeLocked(file, totalSize)
 		}
 	}
 }
@@ -334,18 +655,84 @@ func (m *Master) CreateSnapshot() *wal.Snapshot {
 	defer m.fileMu.RUnlock()
 	defer m.chunkMu.RUnlock()
 
+	return m.createSnapshotLocked()
+}
+
+// cutSnapshot creates a snapshot and starts a new WAL segment at the same point,
+// so every earlier segment is covered by the snapshot
+// Metadata is only copied under the locks; the snapshot is written after they are released
+func (m *Master) cutSnapshot() (*wal.Snapshot, error) {
+	m.cutMu.Lock()
+	defer m.cutMu.Unlock()
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+
+	segment, err := m.wal.Rotate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate WAL: %w", err)
+	}
+	// Every mutation holds one of these locks from its WAL write to its apply,
+	// so each committed entry is in memory now
+	if committed := m.committedIndex.Load(); committed > m.appliedIndex.Load() {
+		m.appliedIndex.Store(committed)
+	}
+	snapshot := m.createSnapshotLocked()
+	snapshot.Segment = segment
+	snapshot.Seq = m.wal.Seq()
+	return snapshot, nil
+}
+
+// createSnapshotLocked must be called with fileMu and chunkMu held
+func (m *Master) createSnapshotLocked() *wal.Snapshot {
 	snapshot := &wal.Snapshot{
 		Timestamp: time.Now(),
+		Index:     m.appliedIndex.Load(),
 		Files:     make([]wal.SnapshotFile, 0, len(m.files)),
 		Chunks:    make([]wal.SnapshotChunk, 0, len(m.chunks)),
 	}
 
+	for id := range m.drainingServers() {
+		snapshot.Draining = append(snapshot.Draining, string(id))
+	}
+
+	for namespace, quota := range m.quotas {
+		snapshot.Quotas = append(snapshot.Quotas, wal.SetQuotaData{
+			Namespace: namespace,
+			MaxBytes:  quota.MaxBytes,
+			MaxFiles:  quota.MaxFiles,
+		})
+	}
+
+	for namespace, class := range m.storageClasses {
+		snapshot.StorageClasses = append(snapshot.StorageClasses, wal.SetStorageClassData{
+			Namespace:    namespace,
+			StorageClass: class,
+		})
+	}
+
+	for namespace, compression := range m.compression {
+		snapshot.Compression = append(snapshot.Compression, wal.SetCompressionData{
+			Namespace:   namespace,
+			Compression: compression,
+		})
+	}
+
 	// Export files
 	for _, file := range m.files {
 		chunks := make([]string, len(file.Chunks))
 		for i, h := range file.Chunks {
 			chunks[i] = string(h)
 		}
+		var trash *wal.SnapshotTrash
+		if file.Trash != nil {
+			trash = &wal.SnapshotTrash{
+				Namespace: file.Trash.Namespace,
+				Path:      file.Trash.Path,
+				DeletedAt: file.Trash.DeletedAt.UnixNano(),
+			}
+		}
 		snapshot.Files = append(snapshot.Files, wal.SnapshotFile{
 			Path:       file.Path,
 			Namespace:  file.Namespace,
@@ -354,6 +741,10 @@ func (m *Master) CreateSnapshot() *wal.Snapshot {
 			Size:       file.Size,
 			CreatedAt:  file.CreatedAt.Unix(),
 			ModifiedAt: file.ModifiedAt.Unix(),
+
+			StorageClass: file.StorageClass,
+			Attributes:   file.Attributes,
+			Trash:        trash,
 		})
 	}
 
@@ -363,6 +754,15 @@ func (m *Master) CreateSnapshot() *wal.Snapshot {
 		if chunk.Status == ChunkCommitted {
 			status = "committed"
 		}
+		var stripe *wal.SetChunkStripeData
+		if chunk.Stripe != nil {
+			stripe = &wal.SetChunkStripeData{
+				ChunkHandle:  string(chunk.Handle),
+				DataShards:   chunk.Stripe.DataShards,
+				ParityShards: chunk.Stripe.ParityShards,
+				ShardSize:    chunk.Stripe.ShardSize,
+			}
+		}
 		snapshot.Chunks = append(snapshot.Chunks, wal.SnapshotChunk{
 			Handle:    string(chunk.Handle),
 			FilePath:  chunk.FilePath,
@@ -370,6 +770,9 @@ func (m *Master) CreateSnapshot() *wal.Snapshot {
 			Size:      chunk.Size,
 			Version:   chunk.Version,
 			Status:    status,
+
+			CommittedVersion: chunk.CommittedVersion,
+			Stripe:           stripe,
 		})
 	}
 
@@ -383,9 +786,18 @@ func (m *Master) RestoreFromSnapshot(snapshot *wal.Snapshot) {
 	defer m.fileMu.Unlock()
 	defer m.chunkMu.Unlock()
 
+	m.restoreSnapshotLocked(snapshot)
+}
+
+// restoreSnapshotLocked must be called with fileMu and chunkMu held
+func (m *Master) restoreSnapshotLocked(snapshot *wal.Snapshot) {
 	// Clear existing state
 	m.files = make(map[fileKey]*FileInfo)
 	m.chunks = make(map[ChunkHandle]*ChunkInfo)
+	m.quotas = make(map[string]NamespaceQuota)
+	m.usage = make(map[string]*namespaceTotals)
+	m.storageClasses = make(map[string]string)
+	m.compression = make(map[string]string)
 
 	// Restore files
 	for _, sf := range snapshot.Files {
@@ -393,6 +805,14 @@ func (m *Master) RestoreFromSnapshot(snapshot *wal.Snapshot) {
 		for i, h := range sf.Chunks {
 			chunks[i] = ChunkHandle(h)
 		}
+		var trash *TrashInfo
+		if sf.Trash != nil {
+			trash = &TrashInfo{
+				Namespace: sf.Trash.Namespace,
+				Path:      sf.Trash.Path,
+				DeletedAt: time.Unix(0, sf.Trash.DeletedAt),
+			}
+		}
 		key := makeFileKey(sf.Namespace, sf.Path)
 		m.files[key] = &FileInfo{
 			Path:       sf.Path,
@@ -402,7 +822,29 @@ func (m *Master) RestoreFromSnapshot(snapshot *wal.Snapshot) {
 			ChunkSize:  sf.ChunkSize,
 			CreatedAt:  time.Unix(sf.CreatedAt, 0),
 			ModifiedAt: time.Unix(sf.ModifiedAt, 0),
+
+			StorageClass: sf.StorageClass,
+			Attributes:   sf.Attributes,
+			Trash:        trash,
 		}
+		m.trackFileLocked(m.files[key])
+	}
+
+	for _, sq := range snapshot.Quotas {
+		m.replaySetQuota(sq.Namespace, sq.MaxBytes, sq.MaxFiles)
+	}
+	for _, sc := range snapshot.StorageClasses {
+		m.replaySetStorageClass(sc.Namespace, "", sc.StorageClass)
+	}
+	for _, sc := range snapshot.Compression {
+		m.replaySetCompression(sc.Namespace, sc.Compression)
+	}
+
+	m.drainMu.Lock()
+	m.draining = make(map[ChunkServerID]*drainState)
+	m.drainMu.Unlock()
+	for _, id := range snapshot.Draining {
+		m.replaySetDraining(id, true)
 	}
 
 	// Restore chunks
@@ -419,24 +861,68 @@ func (m *Master) RestoreFromSnapshot(snapshot *wal.Snapshot) {
 			Version:   sc.Version,
 			Status:    status,
 			Locations: []ChunkLocation{}, // Will be populated by chunkserver heartbeats
+
+			CommittedVersion: sc.CommittedVersion,
+		}
+		if sc.Stripe != nil {
+			m.replaySetChunkStripe(sc.Handle, sc.Stripe.DataShards, sc.Stripe.ParityShards, sc.Stripe.ShardSize)
+		}
+	}
+
+	// Reference counts follow from the files; snapshots share chunks between files
+	for _, file := range m.files {
+		for _, handle := range file.Chunks {
+			if chunk, ok := m.chunks[handle]; ok {
+				chunk.Refs++
+			}
 		}
 	}
 
 	slog.Info("restored from snapshot", "timestamp", snapshot.Timestamp, "files", len(m.files), "chunks", len(m.chunks))
 }
 
-// TakeSnapshot creates a snapshot and truncates the WAL
+// TakeSnapshot creates a snapshot and removes the WAL segments it covers
+// When replicated, the consensus log is compacted up to the same point
 func (m *Master) TakeSnapshot() error {
-	snapshot := m.CreateSnapshot()
+	if m.raft == nil {
+		snapshot, err := m.cutSnapshot()
+		if err != nil {
+			return err
+		}
+		if err := m.wal.WriteSnapshot(snapshot); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		m.watch.setHistory(watchHistory{segment: snapshot.Segment, floor: snapshot.Seq})
+		// A crash before this leaves the segments behind; replay starts after them anyway
+		if err := m.wal.RemoveSegmentsBefore(snapshot.Segment); err != nil {
+			return fmt.Errorf("failed to compact WAL: %w", err)
+		}
+		return nil
+	}
 
+	// Mutations that log under cutMu alone must be applied before the snapshot counts them
+	snapshot, err := m.cutSnapshot()
+	if err != nil {
+		return err
+	}
 	if err := m.wal.WriteSnapshot(snapshot); err != nil {
 		return fmt.Errorf("failed to write snapshot: %w", err)
 	}
+	m.watch.setHistory(watchHistory{floor: snapshot.Index, replicated: true})
 
-	if err := m.wal.TruncateAfterSnapshot(); err != nil {
+	// Entries committed while the snapshot was being written stay in the WAL
+	if err := m.wal.TruncateThrough(snapshot.Index); err != nil {
 		return fmt.Errorf("failed to truncate WAL: %w", err)
 	}
 
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := m.raft.Compact(snapshot.Index, data); err != nil {
+		return fmt.Errorf("failed to compact consensus log: %w", err)
+	}
+
 	return nil
 }
 
@@ -476,6 +962,9 @@ func (m *Master) StartPeriodicSnapshots(interval time.Duration, maxEntries int,
 
 // Close closes the master and its WAL
 func (m *Master) Close() error {
+	if m.raft != nil {
+		m.raft.Stop()
+	}
 	if m.wal != nil {
 		return m.wal.Close()
 	}
@@ -483,10 +972,15 @@ func (m *Master) Close() error {
 }
 
 // RegisterChunkServer registers a chunkserver with the master
-func (m *Master) RegisterChunkServer(id ChunkServerID, hostname string, dataPort, replicationPort int, buildInfo *BuildInfo) {
+func (m *Master) RegisterChunkServer(id ChunkServerID, hostname string, dataPort, replicationPort int, failureDomain string, buildInfo *BuildInfo) {
 	m.csMu.Lock()
 	defer m.csMu.Unlock()
 
+	// Without a label every server is its own failure domain
+	if failureDomain == "" {
+		failureDomain = string(id)
+	}
+
 	loc := &ChunkLocation{
 		ServerID:        id,
 		Hostname:        hostname,
@@ -494,13 +988,45 @@ func (m *Master) RegisterChunkServer(id ChunkServerID, hostname string, dataPort
 		ReplicationPort: replicationPort,
 		LastHeartbeat:   time.Now(),
 		BuildInfo:       buildInfo,
+		FailureDomain:   failureDomain,
+		LastFullReport:  time.Now(), // Registration lists every chunk
 	}
 	m.chunkservers[id] = loc
 	buildID := "unknown"
 	if buildInfo != nil {
 		buildID = buildInfo.BuildID
 	}
-	slog.Info("registered chunkserver", "id", id, "hostname", hostname, "dataPort", dataPort, "build", buildID)
+	slog.Info("registered chunkserver", "id", id, "hostname", hostname, "dataPort", dataPort, "failureDomain", failureDomain, "build", buildID)
+}
+
+// UpdateChunkServerUsage records the chunk count and disk usage reported by a chunkserver
+func (m *Master) UpdateChunkServerUsage(id ChunkServerID, chunkCount int, usage DiskUsage) {
+	m.csMu.Lock()
+	defer m.csMu.Unlock()
+
+	if loc, ok := m.chunkservers[id]; ok {
+		loc.ChunkCount = chunkCount
+		loc.CapacityBytes = usage.CapacityBytes
+		loc.FreeBytes = usage.FreeBytes
+		loc.UsedBytes = usage.UsedBytes
+		loc.LogicalBytes = usage.LogicalBytes
+		if usage.LogicalBytes == 0 {
+			// Chunkservers that predate compression store every chunk raw
+			loc.LogicalBytes = usage.UsedBytes
+		}
+		loc.Compressed = usage.CompressedChunks
+		loc.Disks = usage.Disks
+	}
+}
+
+// UpdateChunkServerLoad records the data-plane load reported by a chunkserver
+func (m *Master) UpdateChunkServerLoad(id ChunkServerID, load ServerLoad) {
+	m.csMu.Lock()
+	defer m.csMu.Unlock()
+
+	if loc, ok := m.chunkservers[id]; ok {
+		loc.Load = load
+	}
 }
 
 // Heartbeat updates the last heartbeat time for a chunkserver
@@ -539,13 +1065,21 @@ func (m *Master) GetChunkServers() []*ChunkLocation {
 
 // ChunkServerStatus contains full status information for a chunkserver
 type ChunkServerStatus struct {
-	Location   *ChunkLocation
-	ChunkCount int
-	IsAlive    bool
+	Location       *ChunkLocation
+	ChunkCount     int
+	IsAlive        bool
+	Draining       bool
+	Disks          []DiskStatus
+	Usage          DiskUsage
+	Load           ServerLoad
+	LastHeartbeat  time.Time
+	LastFullReport time.Time
 }
 
 // GetClusterStatus returns status information for all chunkservers
 func (m *Master) GetClusterStatus() []ChunkServerStatus {
+	draining := m.drainingServers()
+
 	m.csMu.RLock()
 	defer m.csMu.RUnlock()
 
@@ -562,13 +1096,24 @@ func (m *Master) GetClusterStatus() []ChunkServerStatus {
 	// Build status list
 	statuses := make([]ChunkServerStatus, 0, len(m.chunkservers))
 	now := time.Now()
-	heartbeatTimeout := 30 * time.Second // Consider server dead if no heartbeat in 30s
 
 	for id, loc := range m.chunkservers {
 		status := ChunkServerStatus{
 			Location:   loc,
 			ChunkCount: chunkCounts[id],
-			IsAlive:    now.Sub(loc.LastHeartbeat) < heartbeatTimeout,
+			IsAlive:    now.Sub(loc.LastHeartbeat) < HeartbeatTimeout,
+			Draining:   draining[id],
+			Disks:      loc.Disks,
+			Usage: DiskUsage{
+				CapacityBytes:    loc.CapacityBytes,
+				FreeBytes:        loc.FreeBytes,
+				UsedBytes:        loc.UsedBytes,
+				LogicalBytes:     loc.LogicalBytes,
+				CompressedChunks: loc.Compressed,
+			},
+			Load:           loc.Load,
+			LastHeartbeat:  loc.LastHeartbeat,
+			LastFullReport: loc.LastFullReport,
 		}
 		statuses = append(statuses, status)
 	}
@@ -581,22 +1126,39 @@ func (m *Master) generateChunkHandle() ChunkHandle {
 	return ChunkHandle(uuid.New().String())
 }
 
-// CreateFile creates a new file in the namespace
-func (m *Master) CreateFile(path, namespace string) (*FileInfo, error) {
+// CreateFile creates a new file in the namespace with optional attributes.
+// An empty storage class makes the file use its namespace's class.
+func (m *Master) CreateFile(path, namespace, storageClass string, attributes map[string]string) (*FileInfo, error) {
+	if err := validateStorageClass(storageClass); err != nil {
+		return nil, err
+	}
+	attributes = cloneAttributes(attributes)
+	if err := validateAttributes(attributes); err != nil {
+		return nil, err
+	}
+	if err := checkUserNamespace(namespace); err != nil {
+		return nil, err
+	}
+
 	m.fileMu.Lock()
 	defer m.fileMu.Unlock()
 
 	key := makeFileKey(namespace, path)
 	if _, exists := m.files[key]; exists {
-		return nil, fmt.Errorf("file already exists: %s", path)
+		return nil, fmt.Errorf("%w: %s", ErrFileExists, path)
 	}
 
 	namespace = normalizeNamespace(namespace)
+	if err := m.checkQuotaLocked(namespace, 1, 0); err != nil {
+		return nil, err
+	}
 
 	// Log to WAL before applying
-	if err := m.wal.LogCreateFile(path, namespace, m.defaultChunkSize); err != nil {
+	seq, err := m.wal.LogCreateFile(path, namespace, m.defaultChunkSize, storageClass, attributes)
+	if err != nil {
 		return nil, fmt.Errorf("WAL write failed: %w", err)
 	}
+	defer m.watch.applied(seq)
 
 	now := time.Now()
 	file := &FileInfo{
@@ -607,8 +1169,12 @@ func (m *Master) CreateFile(path, namespace string) (*FileInfo, error) {
 		ChunkSize:  m.defaultChunkSize,
 		CreatedAt:  now,
 		ModifiedAt: now,
+
+		StorageClass: storageClass,
+		Attributes:   attributes,
 	}
 	m.files[key] = file
+	m.trackFileLocked(file)
 
 	slog.Debug("created file", "path", path, "namespace", namespace)
 	return file, nil
@@ -627,8 +1193,16 @@ func (m *Master) GetFile(path, namespace string) (*FileInfo, error) {
 	return file, nil
 }
 
-// DeleteFile removes a file from the namespace and schedules chunk deletion
+// DeleteFile removes a file from the namespace. The file moves to the trash
+// while trash retention is enabled; otherwise its chunks are scheduled for deletion.
 func (m *Master) DeleteFile(path, namespace string) error {
+	if err := checkUserNamespace(namespace); err != nil {
+		return err
+	}
+	if m.trashRetention > 0 {
+		return m.trashFile(path, namespace)
+	}
+
 	m.fileMu.Lock()
 	key := makeFileKey(namespace, path)
 	file, exists := m.files[key]
@@ -638,43 +1212,42 @@ func (m *Master) DeleteFile(path, namespace string) error {
 	}
 
 	// Log to WAL before applying
-	if err := m.wal.LogDeleteFile(path, normalizeNamespace(namespace)); err != nil {
+	seq, err := m.wal.LogDeleteFile(path, normalizeNamespace(namespace))
+	if err != nil {
 		m.fileMu.Unlock()
 		return fmt.Errorf("WAL write failed: %w", err)
 	}
-
-	// Get chunk handles before deleting file
-	chunkHandles := make([]ChunkHandle, len(file.Chunks))
-	copy(chunkHandles, file.Chunks)
+	defer m.watch.applied(seq)
 
 	delete(m.files, key)
-	m.fileMu.Unlock()
+	m.untrackFileLocked(file)
 
-	// Remove chunks from registry and schedule deletion on chunkservers
+	// Remove chunks no other file shares from the registry
 	m.chunkMu.Lock()
-	m.pendingDeletesMu.Lock()
-	for _, handle := range chunkHandles {
-		if chunk, ok := m.chunks[handle]; ok {
-			// Schedule deletion on each chunkserver that has this chunk
-			for _, loc := range chunk.Locations {
-				m.pendingDeletes[loc.ServerID] = append(m.pendingDeletes[loc.ServerID], handle)
-			}
-			delete(m.chunks, handle)
-		}
-	}
-	m.pendingDeletesMu.Unlock()
+	released := m.releaseChunksLocked(file.Chunks)
 	m.chunkMu.Unlock()
+	m.fileMu.Unlock()
 
-	slog.Info("deleted file", "path", path, "namespace", normalizeNamespace(namespace), "chunks", len(chunkHandles))
+	// Schedule deletion on each chunkserver that has a released chunk
+	m.scheduleChunkDeletes(released)
+
+	slog.Info("deleted file", "path", path, "namespace", normalizeNamespace(namespace), "chunks", len(file.Chunks), "released", len(released))
 	return nil
 }
 
-// DeleteNamespace removes a namespace and all its files
+// DeleteNamespace removes a namespace and all its files, moving them to the
+// trash while trash retention is enabled
 func (m *Master) DeleteNamespace(namespace string) (int, error) {
 	namespace = normalizeNamespace(namespace)
 	if namespace == defaultNamespace {
 		return 0, fmt.Errorf("cannot delete default namespace")
 	}
+	if err := checkUserNamespace(namespace); err != nil {
+		return 0, err
+	}
+	if m.trashRetention > 0 {
+		return m.trashNamespace(namespace)
+	}
 
 	m.fileMu.Lock()
 
@@ -694,37 +1267,42 @@ func (m *Master) DeleteNamespace(namespace string) (int, error) {
 	}
 
 	// Log to WAL
-	if err := m.wal.LogDeleteNamespace(namespace); err != nil {
+	seq, err := m.wal.LogDeleteNamespace(namespace)
+	if err != nil {
 		m.fileMu.Unlock()
 		return 0, fmt.Errorf("WAL write failed: %w", err)
 	}
+	defer m.watch.applied(seq)
 
 	// Delete all files
 	for _, key := range filesToDelete {
+		m.untrackFileLocked(m.files[key])
 		delete(m.files, key)
 	}
-	m.fileMu.Unlock()
 
-	// Schedule chunk deletions
+	// Chunks shared with files outside the namespace stay
 	m.chunkMu.Lock()
-	m.pendingDeletesMu.Lock()
-	for _, handle := range allChunkHandles {
-		if chunk, ok := m.chunks[handle]; ok {
-			for _, loc := range chunk.Locations {
-				m.pendingDeletes[loc.ServerID] = append(m.pendingDeletes[loc.ServerID], handle)
-			}
-			delete(m.chunks, handle)
-		}
-	}
-	m.pendingDeletesMu.Unlock()
+	released := m.releaseChunksLocked(allChunkHandles)
 	m.chunkMu.Unlock()
+	m.fileMu.Unlock()
+
+	// Schedule chunk deletions
+	m.scheduleChunkDeletes(released)
 
 	slog.Info("deleted namespace", "namespace", namespace, "files", len(filesToDelete), "chunks", len(allChunkHandles))
 	return len(filesToDelete), nil
 }
 
-// RenameFile renames/moves a file from oldPath to newPath
-func (m *Master) RenameFile(oldPath, newPath, namespace string) error {
+// RenameFile renames/moves a file from oldPath to newPath. With replace, a file
+// already at newPath is deleted in the same WAL record; otherwise the rename fails
+func (m *Master) RenameFile(oldPath, newPath, namespace string, replace bool) error {
+	if err := checkUserNamespace(namespace); err != nil {
+		return err
+	}
+	if replace {
+		return m.Transact(namespace, nil, []TxOp{{Type: TxRename, Path: oldPath, NewPath: newPath, Replace: true}})
+	}
+
 	m.fileMu.Lock()
 	defer m.fileMu.Unlock()
 
@@ -739,13 +1317,15 @@ func (m *Master) RenameFile(oldPath, newPath, namespace string) error {
 	// Check destination doesn't exist
 	newKey := makeFileKey(namespace, newPath)
 	if _, exists := m.files[newKey]; exists {
-		return fmt.Errorf("file already exists: %s", newPath)
+		return fmt.Errorf("%w: %s", ErrFileExists, newPath)
 	}
 
 	// Log to WAL before applying
-	if err := m.wal.LogRenameFile(oldPath, newPath, namespace); err != nil {
+	seq, err := m.wal.LogRenameFile(oldPath, newPath, namespace)
+	if err != nil {
 		return fmt.Errorf("WAL write failed: %w", err)
 	}
+	defer m.watch.applied(seq)
 
 	// Update file path
 	file.Path = newPath
@@ -778,6 +1358,10 @@ func (m *Master) ListFiles(namespace, prefix string) []*FileInfo {
 		if namespace != "" && f.Namespace != namespace {
 			continue
 		}
+		// Listing every namespace leaves out the trash
+		if namespace == "" && f.Trash != nil {
+			continue
+		}
 		if prefix != "" && len(f.Path) >= len(prefix) {
 			if f.Path[:len(prefix)] != prefix {
 				continue
@@ -790,14 +1374,22 @@ func (m *Master) ListFiles(namespace, prefix string) []*FileInfo {
 
 // AddChunkToFile adds a new chunk to a file and returns chunk info with replica locations
 func (m *Master) AddChunkToFile(path, namespace string) (*ChunkInfo, error) {
+	if err := checkUserNamespace(namespace); err != nil {
+		return nil, err
+	}
+
 	// Check file exists first (brief read lock)
 	m.fileMu.RLock()
 	key := makeFileKey(namespace, path)
 	_, exists := m.files[key]
+	quotaErr := m.checkQuotaLocked(namespace, 0, 0)
 	m.fileMu.RUnlock()
 	if !exists {
 		return nil, fmt.Errorf("file not found: %s", path)
 	}
+	if quotaErr != nil {
+		return nil, quotaErr
+	}
 
 	// Generate chunk handle (thread-safe, no lock needed)
 	handle := m.generateChunkHandle()
@@ -814,7 +1406,9 @@ func (m *Master) AddChunkToFile(path, namespace string) (*ChunkInfo, error) {
 	}
 
 	// Log to WAL OUTSIDE of locks to avoid blocking
-	if err := m.wal.LogAddChunk(path, normalizeNamespace(namespace), string(handle)); err != nil {
+	m.cutMu.RLock()
+	defer m.cutMu.RUnlock()
+	if _, err := m.wal.LogAddChunk(path, normalizeNamespace(namespace), string(handle)); err != nil {
 		return nil, fmt.Errorf("WAL write failed: %w", err)
 	}
 
@@ -828,16 +1422,26 @@ func (m *Master) AddChunkToFile(path, namespace string) (*ChunkInfo, error) {
 		return nil, fmt.Errorf("file not found: %s", path)
 	}
 
+	chunkInfo := m.attachChunkLocked(file, handle, replicas)
+
+	slog.Debug("added chunk to file", "path", path, "namespace", normalizeNamespace(namespace), "chunk", handle, "replicas", len(replicas))
+	return chunkInfo, nil
+}
+
+// attachChunkLocked registers a new chunk with a lease on its first replica and
+// adds it to the end of a file. Must be called with fileMu held
+func (m *Master) attachChunkLocked(file *FileInfo, handle ChunkHandle, replicas []ChunkLocation) *ChunkInfo {
 	// Create chunk info with lease
 	m.chunkMu.Lock()
 	chunkInfo := &ChunkInfo{
 		Handle:          handle,
-		FilePath:        path,
-		Namespace:       normalizeNamespace(namespace),
+		FilePath:        file.Path,
+		Namespace:       file.Namespace,
 		Locations:       replicas,
 		Version:         1,
 		Primary:         &replicas[0], // First replica is primary
 		LeaseExpiration: time.Now().Add(LeaseDuration),
+		Refs:            1,
 	}
 	m.chunks[handle] = chunkInfo
 	m.chunkMu.Unlock()
@@ -845,26 +1449,12 @@ func (m *Master) AddChunkToFile(path, namespace string) (*ChunkInfo, error) {
 	// Add chunk to file
 	file.Chunks = append(file.Chunks, handle)
 	file.ModifiedAt = time.Now()
-
-	slog.Debug("added chunk to file", "path", path, "namespace", normalizeNamespace(namespace), "chunk", handle, "replicas", len(replicas))
-	return chunkInfo, nil
+	return chunkInfo
 }
 
-// selectReplicas selects n chunkservers to hold replicas
+// selectReplicas selects n chunkservers to hold replicas of a new chunk
 func (m *Master) selectReplicas(n int) []ChunkLocation {
-	m.csMu.RLock()
-	defer m.csMu.RUnlock()
-
-	// Simple selection: just pick first n available servers
-	// TODO: implement smarter placement (rack awareness, load balancing)
-	replicas := make([]ChunkLocation, 0, n)
-	for _, loc := range m.chunkservers {
-		if len(replicas) >= n {
-			break
-		}
-		replicas = append(replicas, *loc)
-	}
-	return replicas
+	return m.placeReplicas(n, nil, nil)
 }
 
 // GetChunkInfo returns info about a specific chunk
@@ -934,12 +1524,19 @@ func (m *Master) reassignPrimaryLocked(chunk *ChunkInfo) {
 		if chunk.Primary != nil {
 			oldPrimary = string(chunk.Primary.ServerID)
 		}
+		if err := m.bumpVersionLocked(chunk); err != nil {
+			// Leave the chunk without a primary; the next ClaimPrimary grants the lease
+			slog.Error("failed to bump chunk version", "chunk", chunk.Handle, "error", err)
+			chunk.Primary = nil
+			return
+		}
 		chunk.Primary = bestLoc
 		chunk.LeaseExpiration = time.Now().Add(LeaseDuration)
 		slog.Info("reassigned primary (lease expired)",
 			"chunk", chunk.Handle,
 			"oldPrimary", oldPrimary,
 			"newPrimary", bestLoc.ServerID,
+			"version", chunk.Version,
 			"leaseExpires", chunk.LeaseExpiration)
 	} else {
 		chunk.Primary = nil
@@ -977,6 +1574,12 @@ func (m *Master) RenewLease(handle ChunkHandle, serverID ChunkServerID) bool {
 		return false
 	}
 
+	// A write that started before a snapshot must not change the shared chunk
+	if chunk.Refs > 1 {
+		slog.Warn("lease renewal failed: chunk is shared by a snapshot", "chunk", handle, "requestor", serverID)
+		return false
+	}
+
 	chunk.LeaseExpiration = time.Now().Add(LeaseDuration)
 	slog.Debug("lease renewed", "chunk", handle, "primary", serverID, "expires", chunk.LeaseExpiration)
 	return true
@@ -987,15 +1590,28 @@ func (m *Master) RenewLease(handle ChunkHandle, serverID ChunkServerID) bool {
 // - The requesting server is already the primary
 // - There is no primary assigned
 // - The current primary's lease has expired
-// Returns true if the claim succeeded, false otherwise.
-func (m *Master) ClaimPrimary(handle ChunkHandle, serverID ChunkServerID) bool {
+// Every new lease bumps the chunk version, which the primary stamps on the write.
+// Returns the chunk version and true if the claim succeeded, false otherwise.
+func (m *Master) ClaimPrimary(handle ChunkHandle, serverID ChunkServerID) (uint64, bool) {
 	m.chunkMu.Lock()
 	defer m.chunkMu.Unlock()
 
 	chunk, exists := m.chunks[handle]
 	if !exists {
 		slog.Warn("claim primary failed: chunk not found", "chunk", handle, "requestor", serverID)
-		return false
+		return 0, false
+	}
+
+	// Shared chunks are read-only; writers must get a private copy first
+	if chunk.Refs > 1 {
+		slog.Warn("claim primary failed: chunk is shared by a snapshot", "chunk", handle, "requestor", serverID)
+		return 0, false
+	}
+
+	// Erasure coded chunks are sealed for good
+	if chunk.Stripe != nil || chunk.encoding {
+		slog.Warn("claim primary failed: chunk is erasure coded", "chunk", handle, "requestor", serverID)
+		return 0, false
 	}
 
 	// Verify the requesting server has the chunk
@@ -1011,17 +1627,24 @@ func (m *Master) ClaimPrimary(handle ChunkHandle, serverID ChunkServerID) bool {
 	if !hasChunk {
 		slog.Warn("claim primary failed: server does not have chunk",
 			"chunk", handle, "requestor", serverID)
-		return false
+		return 0, false
 	}
 
 	now := time.Now()
 
 	// Case 1: Already the primary - just extend lease
+	// An expired lease is granted anew, so it gets a new version
 	if chunk.Primary != nil && chunk.Primary.ServerID == serverID {
+		if now.After(chunk.LeaseExpiration) {
+			if err := m.bumpVersionLocked(chunk); err != nil {
+				slog.Error("claim primary failed", "chunk", handle, "requestor", serverID, "error", err)
+				return 0, false
+			}
+		}
 		chunk.LeaseExpiration = now.Add(LeaseDuration)
 		slog.Debug("claim primary: already primary, lease extended",
-			"chunk", handle, "primary", serverID)
-		return true
+			"chunk", handle, "primary", serverID, "version", chunk.Version)
+		return chunk.Version, true
 	}
 
 	// Case 2: No primary or lease expired - claim it
@@ -1030,11 +1653,15 @@ func (m *Master) ClaimPrimary(handle ChunkHandle, serverID ChunkServerID) bool {
 		if chunk.Primary != nil {
 			oldPrimary = string(chunk.Primary.ServerID)
 		}
+		if err := m.bumpVersionLocked(chunk); err != nil {
+			slog.Error("claim primary failed", "chunk", handle, "requestor", serverID, "error", err)
+			return 0, false
+		}
 		chunk.Primary = serverLoc
 		chunk.LeaseExpiration = now.Add(LeaseDuration)
 		slog.Debug("claim primary: assigned new primary",
-			"chunk", handle, "newPrimary", serverID, "oldPrimary", oldPrimary)
-		return true
+			"chunk", handle, "newPrimary", serverID, "oldPrimary", oldPrimary, "version", chunk.Version)
+		return chunk.Version, true
 	}
 
 	// Case 3: Another server is the active primary
@@ -1042,11 +1669,12 @@ func (m *Master) ClaimPrimary(handle ChunkHandle, serverID ChunkServerID) bool {
 		"chunk", handle, "requestor", serverID,
 		"currentPrimary", chunk.Primary.ServerID,
 		"leaseRemaining", chunk.LeaseExpiration.Sub(now))
-	return false
+	return 0, false
 }
 
-// ReportChunk is called by chunkservers to report they have a chunk
-func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle) {
+// ReportChunk is called by chunkservers to report they have a chunk at a version
+// (0 when the replica predates chunk versions)
+func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle, version uint64) {
 	m.csMu.RLock()
 	_, exists := m.chunkservers[serverID]
 	m.csMu.RUnlock()
@@ -1056,24 +1684,18 @@ func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle) {
 		return
 	}
 
+	if chunkHandle, index, ok := erasure.ParseFragmentHandle(string(handle)); ok {
+		m.reportFragment(serverID, handle, ChunkHandle(chunkHandle), index)
+		return
+	}
+
 	m.chunkMu.RLock()
 	chunk, chunkExists := m.chunks[handle]
 	m.chunkMu.RUnlock()
 
 	if !chunkExists {
 		// Check if chunk is already pending deletion (recently deleted file)
-		m.pendingDeletesMu.Lock()
-		pendingForServer := m.pendingDeletes[serverID]
-		alreadyPendingDelete := false
-		for _, h := range pendingForServer {
-			if h == handle {
-				alreadyPendingDelete = true
-				break
-			}
-		}
-		m.pendingDeletesMu.Unlock()
-
-		if alreadyPendingDelete {
+		if m.isPendingDelete(serverID, handle) {
 			// Chunk is scheduled for deletion, not truly orphaned
 			slog.Debug("chunk pending deletion reported by server", "serverID", serverID, "handle", handle)
 			return
@@ -1099,6 +1721,24 @@ func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle) {
 		return
 	}
 
+	// A replica of an encoded chunk is left over from before the encode
+	if chunk.Stripe != nil {
+		if !m.isPendingDelete(serverID, handle) {
+			m.pendingDeletesMu.Lock()
+			m.pendingDeletes[serverID] = append(m.pendingDeletes[serverID], handle)
+			m.pendingDeletesMu.Unlock()
+		}
+		return
+	}
+
+	// A replica that missed committed writes is stale and must not serve reads
+	if m.isStaleReplicaLocked(chunk, serverID, version) {
+		if !m.isPendingDelete(serverID, handle) {
+			m.dropStaleReplicaLocked(chunk, serverID, version)
+		}
+		return
+	}
+
 	// Check if this server is already in the location list
 	for _, loc := range chunk.Locations {
 		if loc.ServerID == serverID {
@@ -1106,6 +1746,18 @@ func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle) {
 		}
 	}
 
+	// A replica dropped as corrupt or stale is reported until the server deletes it
+	if m.isPendingDelete(serverID, handle) {
+		return
+	}
+
+	// A replica behind the last commit may have reported before the commit landed;
+	// it is not served until a later report settles it
+	if version != 0 && version < chunk.CommittedVersion {
+		m.requestFullReport(serverID)
+		return
+	}
+
 	// Add this server to the chunk's location list
 	m.csMu.RLock()
 	serverLoc := m.chunkservers[serverID]
@@ -1116,6 +1768,10 @@ func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle) {
 
 		// If chunk has no primary, assign this server as primary
 		if chunk.Primary == nil {
+			if err := m.bumpVersionLocked(chunk); err != nil {
+				slog.Error("failed to bump chunk version", "chunk", handle, "error", err)
+				return
+			}
 			chunk.Primary = serverLoc
 			chunk.LeaseExpiration = time.Now().Add(LeaseDuration)
 			slog.Debug("assigned primary from reported location", "handle", handle, "primary", serverID)
@@ -1123,6 +1779,19 @@ func (m *Master) ReportChunk(serverID ChunkServerID, handle ChunkHandle) {
 	}
 }
 
+// isPendingDelete reports whether a chunk is scheduled for deletion on a server
+func (m *Master) isPendingDelete(serverID ChunkServerID, handle ChunkHandle) bool {
+	m.pendingDeletesMu.Lock()
+	defer m.pendingDeletesMu.Unlock()
+
+	for _, h := range m.pendingDeletes[serverID] {
+		if h == handle {
+			return true
+		}
+	}
+	return false
+}
+
 // handleOrphanedChunk tracks unknown chunks and schedules deletion after grace period
 func (m *Master) handleOrphanedChunk(serverID ChunkServerID, handle ChunkHandle) {
 	orphanKey := string(serverID) + ":" + string(handle)
@@ -1162,7 +1831,8 @@ func (m *Master) handleOrphanedChunk(serverID ChunkServerID, handle ChunkHandle)
 }
 
 // ConfirmChunkCommit is called by chunkserver after successful 2PC commit
-func (m *Master) ConfirmChunkCommit(serverID ChunkServerID, handle ChunkHandle, size uint64) error {
+// with the chunk version the write was stamped with
+func (m *Master) ConfirmChunkCommit(serverID ChunkServerID, handle ChunkHandle, size, version uint64) error {
 	// Check chunk exists first (brief lock)
 	m.chunkMu.RLock()
 	chunk, exists := m.chunks[handle]
@@ -1175,9 +1845,13 @@ func (m *Master) ConfirmChunkCommit(serverID ChunkServerID, handle ChunkHandle,
 	m.chunkMu.RUnlock()
 
 	// Log to WAL OUTSIDE of locks to avoid blocking other operations
-	if err := m.wal.LogCommitChunk(string(handle), size); err != nil {
+	m.cutMu.RLock()
+	defer m.cutMu.RUnlock()
+	seq, err := m.wal.LogCommitChunk(string(handle), size, version, filePath, fileNamespace)
+	if err != nil {
 		return fmt.Errorf("WAL write failed: %w", err)
 	}
+	defer m.watch.applied(seq)
 
 	// Now update chunk status (brief lock)
 	m.chunkMu.Lock()
@@ -1185,7 +1859,8 @@ func (m *Master) ConfirmChunkCommit(serverID ChunkServerID, handle ChunkHandle,
 	chunk, exists = m.chunks[handle]
 	if exists {
 		chunk.Status = ChunkCommitted
-		chunk.Size = size
+		chunk.Size = max(chunk.Size, size)
+		m.noteCommittedVersionLocked(chunk, version)
 	}
 	m.chunkMu.Unlock()
 
@@ -1201,7 +1876,7 @@ func (m *Master) ConfirmChunkCommit(serverID ChunkServerID, handle ChunkHandle,
 			}
 		}
 		m.chunkMu.RUnlock()
-		file.Size = totalSize
+		m.setFileSizeLocked(file, totalSize)
 	}
 	m.fileMu.Unlock()
 
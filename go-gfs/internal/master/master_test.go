@@ -0,0 +1,42 @@
+package master
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestMaster returns a standalone master logging to a temporary directory
+func newTestMaster(t *testing.T) *Master {
+	t.Helper()
+	m, err := NewMaster(filepath.Join(t.TempDir(), "wal.log"))
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// addTestServer registers a live chunkserver in its own failure domain unless one is given
+func addTestServer(m *Master, id, domain string) {
+	m.RegisterChunkServer(ChunkServerID(id), id, 1, 2, domain, nil)
+}
+
+// setHeartbeat backdates a chunkserver's last heartbeat by age
+func setHeartbeat(m *Master, id string, age time.Duration) {
+	m.csMu.Lock()
+	m.chunkservers[ChunkServerID(id)].LastHeartbeat = time.Now().Add(-age)
+	m.csMu.Unlock()
+}
+
+// addTestChunk adds a committed chunk held by the given servers
+func addTestChunk(m *Master, handle string, servers ...string) *ChunkInfo {
+	chunk := &ChunkInfo{Handle: ChunkHandle(handle), Status: ChunkCommitted}
+	for _, id := range servers {
+		chunk.Locations = append(chunk.Locations, ChunkLocation{ServerID: ChunkServerID(id), FailureDomain: id})
+	}
+	m.chunkMu.Lock()
+	m.chunks[chunk.Handle] = chunk
+	m.chunkMu.Unlock()
+	return chunk
+}
@@ -0,0 +1,120 @@
+package master
+
+import (
+	"sort"
+	"time"
+)
+
+// chunkCountWeight scales how much a server's chunk count matters relative to its disk fill ratio
+const chunkCountWeight = 0.5
+
+// trafficWeight scales how much a server's data-plane throughput matters relative to its disk fill ratio
+const trafficWeight = 0.25
+
+// placementCandidate is a live chunkserver eligible to receive a replica
+type placementCandidate struct {
+	loc   *ChunkLocation
+	score float64 // Lower is better
+}
+
+// placeReplicas picks up to n live chunkservers for new replicas.
+// Servers that are dead, draining, excluded, already in existing, or without room for a chunk are skipped.
+// Candidates are ranked by disk fill ratio, chunk count and throughput, and replicas are spread across
+// failure domains not used by existing before any domain is reused.
+func (m *Master) placeReplicas(n int, existing []ChunkLocation, exclude map[ChunkServerID]bool) []ChunkLocation {
+	draining := m.drainingServers()
+
+	m.csMu.Lock()
+	defer m.csMu.Unlock()
+
+	usedServers := make(map[ChunkServerID]bool, len(existing))
+	usedDomains := make(map[string]bool, len(existing))
+	for _, loc := range existing {
+		usedServers[loc.ServerID] = true
+		usedDomains[loc.FailureDomain] = true
+	}
+
+	now := time.Now()
+	maxChunks := 0
+	var maxTraffic uint64
+	var eligible []*ChunkLocation
+	for id, loc := range m.chunkservers {
+		if exclude[id] || usedServers[id] || draining[id] {
+			continue
+		}
+		if now.Sub(loc.LastHeartbeat) >= HeartbeatTimeout {
+			continue
+		}
+		// Capacity is unknown until the first report; treat that as room available
+		if loc.CapacityBytes > 0 && loc.FreeBytes < m.defaultChunkSize {
+			continue
+		}
+		if loc.ChunkCount > maxChunks {
+			maxChunks = loc.ChunkCount
+		}
+		maxTraffic = max(maxTraffic, loc.Load.traffic())
+		eligible = append(eligible, loc)
+	}
+
+	candidates := make([]placementCandidate, 0, len(eligible))
+	for _, loc := range eligible {
+		candidates = append(candidates, placementCandidate{
+			loc:   loc,
+			score: placementScore(loc, maxChunks, maxTraffic),
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].loc.ServerID < candidates[j].loc.ServerID
+	})
+
+	replicas := make([]ChunkLocation, 0, n)
+	picked := make([]bool, len(candidates))
+
+	// First pass takes the best server from each unused failure domain,
+	// second pass fills the remainder from whatever is left
+	for pass := 0; pass < 2 && len(replicas) < n; pass++ {
+		for i, c := range candidates {
+			if len(replicas) >= n {
+				break
+			}
+			if picked[i] {
+				continue
+			}
+			if pass == 0 && usedDomains[c.loc.FailureDomain] {
+				continue
+			}
+			picked[i] = true
+			usedDomains[c.loc.FailureDomain] = true
+			replicas = append(replicas, *c.loc)
+
+			// Account for the new replica until the next heartbeat corrects the numbers,
+			// so a burst of allocations doesn't land on the same servers
+			c.loc.ChunkCount++
+			if c.loc.FreeBytes >= m.defaultChunkSize {
+				c.loc.FreeBytes -= m.defaultChunkSize
+			}
+		}
+	}
+
+	return replicas
+}
+
+// placementScore ranks a server for new replicas: fuller disks, more chunks and busier servers score higher
+func placementScore(loc *ChunkLocation, maxChunks int, maxTraffic uint64) float64 {
+	var fill float64
+	if loc.CapacityBytes > 0 {
+		fill = 1 - float64(loc.FreeBytes)/float64(loc.CapacityBytes)
+	}
+	var load float64
+	if maxChunks > 0 {
+		load = float64(loc.ChunkCount) / float64(maxChunks)
+	}
+	var traffic float64
+	if maxTraffic > 0 {
+		traffic = float64(loc.Load.traffic()) / float64(maxTraffic)
+	}
+	return fill + chunkCountWeight*load + trafficWeight*traffic
+}
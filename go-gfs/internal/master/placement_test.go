@@ -0,0 +1,154 @@
+package master
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlaceReplicas(t *testing.T) {
+	type server struct {
+		id, domain string
+		free       uint64 // 0 leaves capacity unreported
+		chunks     int
+		dead       bool
+	}
+	tests := []struct {
+		name     string
+		servers  []server
+		n        int
+		existing []string
+		exclude  []string
+		want     []string // Sorted
+	}{
+		{
+			name: "one replica per rack",
+			servers: []server{
+				{id: "a1", domain: "a"}, {id: "a2", domain: "a"},
+				{id: "b1", domain: "b"}, {id: "b2", domain: "b"},
+				{id: "c1", domain: "c"},
+			},
+			n:    3,
+			want: []string{"a1", "b1", "c1"},
+		},
+		{
+			name: "too few racks fills from used ones",
+			servers: []server{
+				{id: "a1", domain: "a"}, {id: "a2", domain: "a"},
+				{id: "b1", domain: "b"},
+			},
+			n:    3,
+			want: []string{"a1", "a2", "b1"},
+		},
+		{
+			name: "avoids racks of existing replicas",
+			servers: []server{
+				{id: "a1", domain: "a"}, {id: "a2", domain: "a"},
+				{id: "b1", domain: "b"}, {id: "c1", domain: "c"},
+			},
+			n:        1,
+			existing: []string{"a1", "b1"},
+			want:     []string{"c1"},
+		},
+		{
+			name: "falls back to existing racks when no other is left",
+			servers: []server{
+				{id: "a1", domain: "a"}, {id: "a2", domain: "a"},
+				{id: "b1", domain: "b"},
+			},
+			n:        1,
+			existing: []string{"a1", "b1"},
+			want:     []string{"a2"},
+		},
+		{
+			name: "prefers emptier servers within the spread",
+			servers: []server{
+				{id: "a1", domain: "a", chunks: 100}, {id: "a2", domain: "a", chunks: 10},
+				{id: "b1", domain: "b", chunks: 50},
+			},
+			n:    2,
+			want: []string{"a2", "b1"},
+		},
+		{
+			name: "skips dead, full and excluded servers",
+			servers: []server{
+				{id: "a1", domain: "a", dead: true},
+				{id: "b1", domain: "b", free: 1},
+				{id: "c1", domain: "c"},
+				{id: "d1", domain: "d"},
+				{id: "e1", domain: "e"},
+			},
+			n:       3,
+			exclude: []string{"d1"},
+			want:    []string{"c1", "e1"},
+		},
+		{
+			name:    "no eligible servers",
+			servers: []server{{id: "a1", domain: "a", dead: true}},
+			n:       3,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMaster(t)
+			for _, s := range tt.servers {
+				addTestServer(m, s.id, s.domain)
+				m.csMu.Lock()
+				loc := m.chunkservers[ChunkServerID(s.id)]
+				loc.ChunkCount = s.chunks
+				if s.free > 0 {
+					loc.CapacityBytes, loc.FreeBytes = 1<<40, s.free
+				}
+				m.csMu.Unlock()
+				if s.dead {
+					setHeartbeat(m, s.id, HeartbeatTimeout+time.Second)
+				}
+			}
+			var existing []ChunkLocation
+			for _, id := range tt.existing {
+				m.csMu.RLock()
+				existing = append(existing, *m.chunkservers[ChunkServerID(id)])
+				m.csMu.RUnlock()
+			}
+			exclude := make(map[ChunkServerID]bool)
+			for _, id := range tt.exclude {
+				exclude[ChunkServerID(id)] = true
+			}
+
+			var got []string
+			for _, loc := range m.placeReplicas(tt.n, existing, exclude) {
+				got = append(got, string(loc.ServerID))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("placed on %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceReplicasSpreadsBurst(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"a1", "a2", "b1", "b2"} {
+		addTestServer(m, id, id[:1])
+	}
+
+	// Back-to-back placements before any heartbeat must not pile onto the same servers
+	counts := make(map[ChunkServerID]int)
+	for range 4 {
+		locs := m.placeReplicas(2, nil, nil)
+		if len(locs) != 2 || locs[0].FailureDomain == locs[1].FailureDomain {
+			t.Fatalf("placement %v is not spread across racks", locs)
+		}
+		for _, loc := range locs {
+			counts[loc.ServerID]++
+		}
+	}
+	for id, n := range counts {
+		if n != 2 {
+			t.Fatalf("server %s got %d of 8 replicas, want 2: %v", id, n, counts)
+		}
+	}
+}
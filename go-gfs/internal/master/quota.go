@@ -0,0 +1,171 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// ErrQuotaExceeded is returned when a change would take a namespace over its quota
+var ErrQuotaExceeded = errors.New("namespace quota exceeded")
+
+// NamespaceQuota limits a namespace; zero means unlimited
+type NamespaceQuota struct {
+	MaxBytes uint64
+	MaxFiles uint64
+}
+
+// NamespaceUsage is the live total of a namespace's files against its quota
+type NamespaceUsage struct {
+	Namespace string
+	Bytes     uint64 // Sum of committed file sizes (logical, before replication)
+	Files     uint64
+	Quota     NamespaceQuota
+
+	StorageClass string // Default class of the namespace's files
+	Compression  string // How sealed chunks are stored; empty for raw
+}
+
+// namespaceTotals is the running usage of one namespace, kept in step with m.files
+type namespaceTotals struct {
+	bytes uint64
+	files uint64
+}
+
+// SetNamespaceQuota sets or clears (with zero limits) the quota of a namespace.
+// Existing files are kept even if the namespace is already over the new quota.
+func (m *Master) SetNamespaceQuota(namespace string, quota NamespaceQuota) error {
+	namespace = normalizeNamespace(namespace)
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogSetQuota(namespace, quota.MaxBytes, quota.MaxFiles); err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	m.replaySetQuota(namespace, quota.MaxBytes, quota.MaxFiles)
+
+	slog.Info("set namespace quota", "namespace", namespace, "maxBytes", quota.MaxBytes, "maxFiles", quota.MaxFiles)
+	return nil
+}
+
+// replaySetQuota sets a namespace quota from WAL (no WAL logging)
+func (m *Master) replaySetQuota(namespace string, maxBytes, maxFiles uint64) {
+	namespace = normalizeNamespace(namespace)
+	if maxBytes == 0 && maxFiles == 0 {
+		delete(m.quotas, namespace)
+		return
+	}
+	m.quotas[namespace] = NamespaceQuota{MaxBytes: maxBytes, MaxFiles: maxFiles}
+}
+
+// GetNamespaceUsage returns usage for one namespace, or for every namespace
+// with files, a quota or a storage class when namespace is empty
+func (m *Master) GetNamespaceUsage(namespace string) []NamespaceUsage {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+
+	if namespace != "" {
+		return []NamespaceUsage{m.namespaceUsageLocked(normalizeNamespace(namespace))}
+	}
+
+	names := make(map[string]bool, len(m.usage)+len(m.quotas))
+	for ns := range m.usage {
+		// The trash is hidden; ask for it by name
+		if ns != TrashNamespace {
+			names[ns] = true
+		}
+	}
+	for ns := range m.quotas {
+		names[ns] = true
+	}
+	for ns := range m.storageClasses {
+		names[ns] = true
+	}
+	for ns := range m.compression {
+		names[ns] = true
+	}
+
+	usages := make([]NamespaceUsage, 0, len(names))
+	for ns := range names {
+		usages = append(usages, m.namespaceUsageLocked(ns))
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Namespace < usages[j].Namespace
+	})
+	return usages
+}
+
+// namespaceUsageLocked must be called with fileMu held
+func (m *Master) namespaceUsageLocked(namespace string) NamespaceUsage {
+	usage := NamespaceUsage{Namespace: namespace, Quota: m.quotas[namespace], StorageClass: StorageClassReplicated}
+	if class, ok := m.storageClasses[namespace]; ok {
+		usage.StorageClass = class
+	}
+	usage.Compression = m.compression[namespace]
+	if totals, ok := m.usage[namespace]; ok {
+		usage.Bytes = totals.bytes
+		usage.Files = totals.files
+	}
+	return usage
+}
+
+// checkQuotaLocked rejects adding files and bytes to a namespace beyond its quota.
+// A namespace already at its byte limit gets no new files or chunks; bytes written
+// into chunks that were allocated before the limit was reached still commit.
+// Must be called with fileMu held
+func (m *Master) checkQuotaLocked(namespace string, files, bytes uint64) error {
+	namespace = normalizeNamespace(namespace)
+	quota, ok := m.quotas[namespace]
+	if !ok {
+		return nil
+	}
+	usage := m.namespaceUsageLocked(namespace)
+
+	if quota.MaxFiles > 0 && usage.Files+files > quota.MaxFiles {
+		return fmt.Errorf("%w: %s is limited to %d files", ErrQuotaExceeded, namespace, quota.MaxFiles)
+	}
+	if quota.MaxBytes > 0 && (usage.Bytes >= quota.MaxBytes || usage.Bytes+bytes > quota.MaxBytes) {
+		return fmt.Errorf("%w: %s is limited to %d bytes", ErrQuotaExceeded, namespace, quota.MaxBytes)
+	}
+	return nil
+}
+
+// trackFileLocked adds a file that was just put in m.files to its namespace usage.
+// Must be called with fileMu held
+func (m *Master) trackFileLocked(file *FileInfo) {
+	namespace := normalizeNamespace(file.Namespace)
+	totals, ok := m.usage[namespace]
+	if !ok {
+		totals = &namespaceTotals{}
+		m.usage[namespace] = totals
+	}
+	totals.files++
+	totals.bytes += file.Size
+}
+
+// untrackFileLocked removes a file that was just taken out of m.files from its namespace usage.
+// Must be called with fileMu held
+func (m *Master) untrackFileLocked(file *FileInfo) {
+	namespace := normalizeNamespace(file.Namespace)
+	totals, ok := m.usage[namespace]
+	if !ok {
+		return
+	}
+	totals.files--
+	totals.bytes -= file.Size
+	if totals.files == 0 {
+		delete(m.usage, namespace)
+	}
+}
+
+// setFileSizeLocked updates a file's size and its namespace usage.
+// Must be called with fileMu held
+func (m *Master) setFileSizeLocked(file *FileInfo, size uint64) {
+	if totals, ok := m.usage[normalizeNamespace(file.Namespace)]; ok {
+		totals.bytes = totals.bytes - file.Size + size
+	}
+	file.Size = size
+}
@@ -0,0 +1,213 @@
+package master
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// usageOf returns a namespace's live bytes and files
+func usageOf(m *Master, namespace string) (uint64, uint64) {
+	usage := m.GetNamespaceUsage(namespace)[0]
+	return usage.Bytes, usage.Files
+}
+
+func TestQuotaFileLimit(t *testing.T) {
+	m := newTestMaster(t)
+	m.SetTrashRetention(0)
+	addTestServer(m, "cs1", "")
+	if err := m.SetNamespaceQuota("ns", NamespaceQuota{MaxFiles: 2}); err != nil {
+		t.Fatalf("SetNamespaceQuota: %v", err)
+	}
+	createTestFiles(t, m, "ns", "/a", "/b")
+
+	if _, err := m.CreateFile("/c", "ns", "", nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("third file: err = %v, want ErrQuotaExceeded", err)
+	}
+	if err := m.Transact("ns", nil, []TxOp{{Type: TxDelete, Path: "/a"}, {Type: TxCreate, Path: "/c"}, {Type: TxCreate, Path: "/d"}}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("transaction adding a file net: err = %v, want ErrQuotaExceeded", err)
+	}
+	writeTestChunks(t, m, "other", "/x")
+	if _, err := m.SnapshotFile("/x", "/x", "other", "ns"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("snapshot into a full namespace: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := m.CreateFile("/c", "other", "", nil); err != nil {
+		t.Errorf("another namespace is not limited: %v", err)
+	}
+
+	// Deleting a file makes room; a rename doesn't use any
+	if err := m.DeleteFile("/a", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if _, err := m.CreateFile("/c", "ns", "", nil); err != nil {
+		t.Errorf("file after a delete: %v", err)
+	}
+	if err := m.RenameFile("/c", "/d", "ns", false); err != nil {
+		t.Errorf("rename in a full namespace: %v", err)
+	}
+	if _, files := usageOf(m, "ns"); files != 2 {
+		t.Errorf("%d files counted, want 2", files)
+	}
+}
+
+func TestQuotaByteLimit(t *testing.T) {
+	m := newTestMaster(t)
+	m.SetTrashRetention(0)
+	addTestServer(m, "cs1", "")
+	if err := m.SetNamespaceQuota("ns", NamespaceQuota{MaxBytes: 100}); err != nil {
+		t.Fatalf("SetNamespaceQuota: %v", err)
+	}
+	writeTestChunks(t, m, "ns", "/a", 60)
+
+	// Under the limit, a new chunk is allowed
+	chunk, err := m.AddChunkToFile("/a", "ns")
+	if err != nil {
+		t.Fatalf("AddChunkToFile under the limit: %v", err)
+	}
+	// Writes into a chunk allocated before the limit was reached still commit
+	if err := m.ConfirmChunkCommit("cs1", chunk.Handle, 50, 1); err != nil {
+		t.Fatalf("ConfirmChunkCommit: %v", err)
+	}
+	if bytes, _ := usageOf(m, "ns"); bytes != 110 {
+		t.Errorf("%d bytes counted, want 110", bytes)
+	}
+
+	// At or over the limit, the namespace gets no new chunks or files
+	if _, err := m.AddChunkToFile("/a", "ns"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("AddChunkToFile over the limit: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := m.AddChunkToFileIfSize("/a", "ns", 110); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("AddChunkToFileIfSize over the limit: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := m.CreateFile("/b", "ns", "", nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CreateFile over the limit: err = %v, want ErrQuotaExceeded", err)
+	}
+
+	// A snapshot that would take another namespace over its limit is refused
+	if err := m.SetNamespaceQuota("small", NamespaceQuota{MaxBytes: 100}); err != nil {
+		t.Fatalf("SetNamespaceQuota: %v", err)
+	}
+	if _, err := m.SnapshotFile("/a", "/a", "ns", "small"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("snapshot of 110 bytes into a 100 byte quota: err = %v, want ErrQuotaExceeded", err)
+	}
+
+	if err := m.DeleteFile("/a", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if bytes, files := usageOf(m, "ns"); bytes != 0 || files != 0 {
+		t.Errorf("usage after delete = %d bytes, %d files; want none", bytes, files)
+	}
+	if _, err := m.CreateFile("/b", "ns", "", nil); err != nil {
+		t.Errorf("CreateFile after freeing space: %v", err)
+	}
+}
+
+// Usage follows files as they move between namespaces
+func TestUsageAcrossNamespaces(t *testing.T) {
+	m := newTestMaster(t)
+	addTestServer(m, "cs1", "")
+	writeTestChunks(t, m, "ns", "/a", 30, 20)
+	writeTestChunks(t, m, "ns", "/b", 5)
+
+	if err := m.RenameFile("/a", "/c", "ns", false); err != nil {
+		t.Fatalf("RenameFile: %v", err)
+	}
+	if bytes, files := usageOf(m, "ns"); bytes != 55 || files != 2 {
+		t.Errorf("usage after rename = %d bytes, %d files; want 55, 2", bytes, files)
+	}
+
+	// Trashed files count against the trash, not their namespace
+	if err := m.DeleteFile("/c", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if bytes, files := usageOf(m, "ns"); bytes != 5 || files != 1 {
+		t.Errorf("usage after delete = %d bytes, %d files; want 5, 1", bytes, files)
+	}
+	if bytes, files := usageOf(m, TrashNamespace); bytes != 50 || files != 1 {
+		t.Errorf("trash usage = %d bytes, %d files; want 50, 1", bytes, files)
+	}
+
+	// A restore has to fit the destination's quota
+	id := m.ListTrash("ns")[0].ID
+	if err := m.SetNamespaceQuota("ns", NamespaceQuota{MaxBytes: 40}); err != nil {
+		t.Fatalf("SetNamespaceQuota: %v", err)
+	}
+	if _, err := m.RestoreFile(id, "", ""); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("restore over quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := m.RestoreFile(id, "/restored", "elsewhere"); err != nil {
+		t.Fatalf("RestoreFile: %v", err)
+	}
+	if bytes, files := usageOf(m, "elsewhere"); bytes != 50 || files != 1 {
+		t.Errorf("usage after restore = %d bytes, %d files; want 50, 1", bytes, files)
+	}
+	if bytes, files := usageOf(m, TrashNamespace); bytes != 0 || files != 0 {
+		t.Errorf("trash usage after restore = %d bytes, %d files; want none", bytes, files)
+	}
+
+	// Commits to a moved file's chunks count where the file is now
+	chunk, err := m.AddChunkToFile("/restored", "elsewhere")
+	if err != nil {
+		t.Fatalf("AddChunkToFile: %v", err)
+	}
+	if err := m.ConfirmChunkCommit("cs1", chunk.Handle, 7, 1); err != nil {
+		t.Fatalf("ConfirmChunkCommit: %v", err)
+	}
+	if bytes, _ := usageOf(m, "elsewhere"); bytes != 57 {
+		t.Errorf("usage after a commit = %d bytes, want 57", bytes)
+	}
+}
+
+// Usage and quotas are rebuilt from the WAL, and a zero quota clears it
+func TestQuotaReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	m, err := NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	addTestServer(m, "cs1", "")
+	writeTestChunks(t, m, "a", "/f", 10, 20)
+	writeTestChunks(t, m, "b", "/g", 5)
+	if err := m.DeleteFile("/g", "b"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	for _, q := range []struct {
+		namespace string
+		quota     NamespaceQuota
+	}{
+		{"a", NamespaceQuota{MaxBytes: 100, MaxFiles: 10}},
+		{"b", NamespaceQuota{MaxFiles: 3}},
+		{"b", NamespaceQuota{}}, // Clears it
+	} {
+		if err := m.SetNamespaceQuota(q.namespace, q.quota); err != nil {
+			t.Fatalf("SetNamespaceQuota: %v", err)
+		}
+	}
+	want := m.GetNamespaceUsage("")
+	wantTrash := m.GetNamespaceUsage(TrashNamespace)[0]
+	m.Close()
+
+	m, err = NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster after restart: %v", err)
+	}
+	defer m.Close()
+	got := m.GetNamespaceUsage("")
+	if len(got) != len(want) {
+		t.Fatalf("usage after replay = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("usage after replay = %+v, want %+v", got[i], want[i])
+		}
+	}
+	if got := m.GetNamespaceUsage(TrashNamespace)[0]; got != wantTrash {
+		t.Errorf("trash usage after replay = %+v, want %+v", got, wantTrash)
+	}
+	if _, ok := m.quotas["b"]; ok {
+		t.Error("cleared quota is back after replay")
+	}
+	if got := m.GetNamespaceUsage("a")[0].Quota; got != (NamespaceQuota{MaxBytes: 100, MaxFiles: 10}) {
+		t.Errorf("quota after replay = %+v", got)
+	}
+}
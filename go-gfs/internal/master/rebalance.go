@@ -0,0 +1,607 @@
+package master
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// DefaultRebalanceThreshold is the disk fill spread, as a fraction of capacity,
+// at which a rebalance stops
+const DefaultRebalanceThreshold = 0.10
+
+// drainState tracks a chunkserver being emptied before removal
+type drainState struct {
+	StartedAt time.Time
+	Moved     int  // Replicas moved off since the drain started
+	Complete  bool // Set once no replicas remain, so completion is logged once
+}
+
+// rebalanceState tracks a running rebalance
+type rebalanceState struct {
+	Threshold float64
+	StartedAt time.Time
+	Moved     int
+}
+
+// DrainStatus reports the progress of a drain
+type DrainStatus struct {
+	ServerID        ChunkServerID
+	IsAlive         bool
+	StartedAt       time.Time
+	MovedChunks     int
+	RemainingChunks int
+	InflightMoves   int
+	Complete        bool
+}
+
+// ServerFill is a chunkserver's disk fill ratio as seen by the rebalancer
+type ServerFill struct {
+	ServerID  ChunkServerID
+	FillRatio float64
+}
+
+// RebalanceStatus reports the progress of a rebalance
+type RebalanceStatus struct {
+	Active        bool
+	Threshold     float64
+	StartedAt     time.Time
+	MovedChunks   int
+	InflightMoves int
+	Spread        float64 // Fullest minus emptiest fill ratio
+	Servers       []ServerFill
+}
+
+// movableChunk is a committed chunk without an active lease, safe to move between servers
+type movableChunk struct {
+	handle    ChunkHandle
+	size      uint64
+	locations []ChunkLocation
+}
+
+// SetDraining marks a chunkserver as draining, or returns it to service.
+// A draining server gets no new replicas and the replication manager moves its
+// existing replicas to other servers; the drain is complete when none remain.
+func (m *Master) SetDraining(id ChunkServerID, draining bool) error {
+	m.csMu.RLock()
+	_, known := m.chunkservers[id]
+	m.csMu.RUnlock()
+	if draining && !known {
+		return fmt.Errorf("unknown chunkserver: %s", id)
+	}
+
+	// Log to WAL before applying so a new leader keeps draining
+	m.cutMu.RLock()
+	defer m.cutMu.RUnlock()
+	if _, err := m.wal.LogSetDraining(string(id), draining); err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	m.replaySetDraining(string(id), draining)
+
+	slog.Info("set chunkserver draining", "serverID", id, "draining", draining)
+	return nil
+}
+
+// replaySetDraining marks a chunkserver draining from WAL (no WAL logging)
+func (m *Master) replaySetDraining(id string, draining bool) {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+
+	if !draining {
+		delete(m.draining, ChunkServerID(id))
+		return
+	}
+	if _, ok := m.draining[ChunkServerID(id)]; !ok {
+		m.draining[ChunkServerID(id)] = &drainState{StartedAt: time.Now()}
+	}
+}
+
+// drainingServers returns the IDs of draining chunkservers
+func (m *Master) drainingServers() map[ChunkServerID]bool {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+
+	ids := make(map[ChunkServerID]bool, len(m.draining))
+	for id := range m.draining {
+		ids[id] = true
+	}
+	return ids
+}
+
+// GetDrainStatus returns progress for one draining server, or all of them when id is empty
+func (m *Master) GetDrainStatus(id ChunkServerID) ([]DrainStatus, error) {
+	m.drainMu.Lock()
+	states := make(map[ChunkServerID]drainState, len(m.draining))
+	for serverID, state := range m.draining {
+		if id == "" || serverID == id {
+			states[serverID] = *state
+		}
+	}
+	m.drainMu.Unlock()
+
+	if id != "" && len(states) == 0 {
+		return nil, fmt.Errorf("chunkserver is not draining: %s", id)
+	}
+
+	remaining := make(map[ChunkServerID]int, len(states))
+	m.chunkMu.RLock()
+	for _, chunk := range m.chunks {
+		for _, loc := range chunk.Locations {
+			if _, ok := states[loc.ServerID]; ok {
+				remaining[loc.ServerID]++
+			}
+		}
+		for _, loc := range stripeLocations(chunk) {
+			if _, ok := states[loc.ServerID]; ok {
+				remaining[loc.ServerID]++
+			}
+		}
+	}
+	m.chunkMu.RUnlock()
+
+	inflight := make(map[ChunkServerID]int)
+	m.replicationMu.Lock()
+	for _, task := range m.inflightReplications {
+		if task.MoveFrom != "" {
+			inflight[task.MoveFrom]++
+		}
+	}
+	m.replicationMu.Unlock()
+
+	live := m.liveChunkServers()
+	statuses := make([]DrainStatus, 0, len(states))
+	for serverID, state := range states {
+		_, alive := live[serverID]
+		statuses = append(statuses, DrainStatus{
+			ServerID:        serverID,
+			IsAlive:         alive,
+			StartedAt:       state.StartedAt,
+			MovedChunks:     state.Moved,
+			RemainingChunks: remaining[serverID],
+			InflightMoves:   inflight[serverID],
+			Complete:        remaining[serverID] == 0,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ServerID < statuses[j].ServerID
+	})
+	return statuses, nil
+}
+
+// StartRebalance starts moving replicas from the fullest chunkservers to the emptiest
+// until their disk fill ratios are within threshold of each other.
+// Restarting a running rebalance only changes its threshold.
+func (m *Master) StartRebalance(threshold float64) error {
+	if threshold <= 0 {
+		threshold = DefaultRebalanceThreshold
+	}
+	if threshold >= 1 {
+		return fmt.Errorf("threshold must be below 1, got %v", threshold)
+	}
+
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.rebalance != nil {
+		m.rebalance.Threshold = threshold
+	} else {
+		m.rebalance = &rebalanceState{Threshold: threshold, StartedAt: time.Now()}
+	}
+
+	slog.Info("started rebalance", "threshold", threshold)
+	return nil
+}
+
+// isRebalancing reports whether a rebalance is running
+func (m *Master) isRebalancing() bool {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	return m.rebalance != nil
+}
+
+// StopRebalance stops scheduling rebalance moves; copies already issued finish
+func (m *Master) StopRebalance() {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.rebalance != nil {
+		slog.Info("stopped rebalance", "moved", m.rebalance.Moved)
+		m.rebalance = nil
+	}
+}
+
+// GetRebalanceStatus returns the state of the current or last computed rebalance
+func (m *Master) GetRebalanceStatus() RebalanceStatus {
+	status := RebalanceStatus{Threshold: DefaultRebalanceThreshold}
+
+	m.drainMu.Lock()
+	if m.rebalance != nil {
+		status.Active = true
+		status.Threshold = m.rebalance.Threshold
+		status.StartedAt = m.rebalance.StartedAt
+		status.MovedChunks = m.rebalance.Moved
+	}
+	m.drainMu.Unlock()
+
+	m.replicationMu.Lock()
+	for _, task := range m.inflightReplications {
+		if task.Rebalance {
+			status.InflightMoves++
+		}
+	}
+	m.replicationMu.Unlock()
+
+	fills := m.serverFills(m.liveChunkServers(), m.drainingServers())
+	for id, fill := range fills {
+		status.Servers = append(status.Servers, ServerFill{ServerID: id, FillRatio: fill.ratio()})
+	}
+	sort.Slice(status.Servers, func(i, j int) bool {
+		return status.Servers[i].FillRatio > status.Servers[j].FillRatio
+	})
+	if n := len(status.Servers); n > 1 {
+		status.Spread = status.Servers[0].FillRatio - status.Servers[n-1].FillRatio
+	}
+	return status
+}
+
+// serverFill is the planned disk usage of a server during a rebalance round
+type serverFill struct {
+	used     uint64
+	capacity uint64
+}
+
+func (f serverFill) ratio() float64 {
+	return float64(f.used) / float64(f.capacity)
+}
+
+// serverFills returns the disk usage of live, non-draining servers that reported capacity
+func (m *Master) serverFills(live map[ChunkServerID]ChunkLocation, draining map[ChunkServerID]bool) map[ChunkServerID]*serverFill {
+	fills := make(map[ChunkServerID]*serverFill, len(live))
+	for id, loc := range live {
+		if draining[id] || loc.CapacityBytes == 0 {
+			continue
+		}
+		fills[id] = &serverFill{used: loc.CapacityBytes - loc.FreeBytes, capacity: loc.CapacityBytes}
+	}
+	return fills
+}
+
+// findMovableChunks returns committed chunks that no client is writing to.
+// Only chunks on draining servers are returned unless a rebalance is running.
+func (m *Master) findMovableChunks(draining map[ChunkServerID]bool, rebalancing bool) []movableChunk {
+	if len(draining) == 0 && !rebalancing {
+		return nil
+	}
+
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+
+	now := time.Now()
+	var chunks []movableChunk
+	for handle, chunk := range m.chunks {
+		// A chunk under lease may still be appended to; a copy taken now would miss the writes.
+		// Fragments of encoded chunks leave draining servers through rebuilds
+		if chunk.Status != ChunkCommitted || now.Before(chunk.LeaseExpiration) || chunk.Stripe != nil {
+			continue
+		}
+		if !rebalancing {
+			onDraining := false
+			for _, loc := range chunk.Locations {
+				if draining[loc.ServerID] {
+					onDraining = true
+					break
+				}
+			}
+			if !onDraining {
+				continue
+			}
+		}
+		locations := make([]ChunkLocation, len(chunk.Locations))
+		copy(locations, chunk.Locations)
+		chunks = append(chunks, movableChunk{handle: handle, size: chunk.Size, locations: locations})
+	}
+	return chunks
+}
+
+// scheduleMovesLocked queues drain and rebalance moves with the copy capacity left after healing.
+// Must be called with replicationMu held
+func (m *Master) scheduleMovesLocked(live map[ChunkServerID]ChunkLocation, draining map[ChunkServerID]bool, movable []movableChunk, now time.Time) int {
+	scheduled := 0
+	for _, c := range movable {
+		if len(m.inflightReplications) >= maxInflightReplications {
+			return scheduled
+		}
+		if _, busy := m.inflightReplications[c.handle]; busy {
+			continue
+		}
+		for _, loc := range c.locations {
+			if !draining[loc.ServerID] {
+				continue
+			}
+			if _, alive := live[loc.ServerID]; !alive {
+				// Healing restores the chunk elsewhere; forget the dead replica once that is done
+				m.dropDeadDrainedReplica(c, loc.ServerID, live)
+				continue
+			}
+			if m.scheduleMoveLocked(c, loc.ServerID, live, draining, now, false) {
+				scheduled++
+			}
+			break
+		}
+	}
+
+	return scheduled + m.scheduleRebalanceLocked(live, draining, movable, now)
+}
+
+// scheduleMoveLocked queues a copy of a chunk off from, placed by the normal policy.
+// Must be called with replicationMu held
+func (m *Master) scheduleMoveLocked(c movableChunk, from ChunkServerID, live map[ChunkServerID]ChunkLocation, draining map[ChunkServerID]bool, now time.Time, rebalance bool) bool {
+	existing := make([]ChunkLocation, 0, len(c.locations))
+	holders := make(map[ChunkServerID]bool, len(c.locations))
+	for _, loc := range c.locations {
+		holders[loc.ServerID] = true
+		if loc.ServerID != from && !draining[loc.ServerID] {
+			if l, ok := live[loc.ServerID]; ok {
+				existing = append(existing, l)
+			}
+		}
+	}
+	for id := range live {
+		if m.isPendingDelete(id, c.handle) {
+			holders[id] = true
+		}
+	}
+
+	targets := m.placeReplicas(1, existing, holders)
+	if len(targets) == 0 {
+		slog.Debug("no target available for chunk move", "chunk", c.handle, "from", from)
+		return false
+	}
+	m.queueMoveLocked(c.handle, from, targets[0], now, rebalance)
+	return true
+}
+
+// queueMoveLocked records and queues a move task.
+// Must be called with replicationMu held
+func (m *Master) queueMoveLocked(handle ChunkHandle, from ChunkServerID, target ChunkLocation, now time.Time, rebalance bool) {
+	task := &ReplicationTask{
+		Handle:    handle,
+		Source:    from,
+		Target:    target,
+		IssuedAt:  now,
+		MoveFrom:  from,
+		Rebalance: rebalance,
+	}
+	m.inflightReplications[handle] = task
+	m.pendingReplications[from] = append(m.pendingReplications[from], *task)
+
+	slog.Info("scheduled chunk move", "chunk", handle, "from", from, "to", target.ServerID, "rebalance", rebalance)
+}
+
+// scheduleRebalanceLocked plans moves from the fullest to the emptiest servers, tracking
+// planned usage so one round doesn't overshoot. Ends the rebalance once the spread is
+// within the threshold and no moves are in flight.
+// Must be called with replicationMu held
+func (m *Master) scheduleRebalanceLocked(live map[ChunkServerID]ChunkLocation, draining map[ChunkServerID]bool, movable []movableChunk, now time.Time) int {
+	m.drainMu.Lock()
+	active := m.rebalance != nil
+	threshold := 0.0
+	if active {
+		threshold = m.rebalance.Threshold
+	}
+	m.drainMu.Unlock()
+	if !active {
+		return 0
+	}
+
+	fills := m.serverFills(live, draining)
+	if len(fills) < 2 {
+		return 0
+	}
+
+	// Count moves already issued as done
+	sizes := make(map[ChunkHandle]uint64, len(movable))
+	onServer := make(map[ChunkServerID][]int)
+	for i, c := range movable {
+		sizes[c.handle] = c.size
+		for _, loc := range c.locations {
+			onServer[loc.ServerID] = append(onServer[loc.ServerID], i)
+		}
+	}
+	inflight := 0
+	for _, task := range m.inflightReplications {
+		if !task.Rebalance {
+			continue
+		}
+		inflight++
+		if f, ok := fills[task.MoveFrom]; ok && f.used >= sizes[task.Handle] {
+			f.used -= sizes[task.Handle]
+		}
+		if f, ok := fills[task.Target.ServerID]; ok {
+			f.used += sizes[task.Handle]
+		}
+	}
+
+	scheduled := 0
+	next := make(map[ChunkServerID]int)
+	for len(m.inflightReplications) < maxInflightReplications {
+		fullest, emptiest := extremeFills(fills)
+		if fills[fullest].ratio()-fills[emptiest].ratio() <= threshold {
+			if inflight == 0 && scheduled == 0 {
+				m.finishRebalance()
+			}
+			break
+		}
+
+		// Next chunk on the fullest server that the emptiest server can take
+		candidates := onServer[fullest]
+		picked := -1
+		for next[fullest] < len(candidates) {
+			i := candidates[next[fullest]]
+			next[fullest]++
+			if _, busy := m.inflightReplications[movable[i].handle]; busy {
+				continue
+			}
+			if canHoldReplica(movable[i], fullest, live[emptiest]) {
+				picked = i
+				break
+			}
+		}
+		if picked < 0 {
+			slog.Debug("no chunk can move between servers", "from", fullest, "to", emptiest)
+			break
+		}
+
+		c := movable[picked]
+		m.queueMoveLocked(c.handle, fullest, live[emptiest], now, true)
+		fills[fullest].used -= min(c.size, fills[fullest].used)
+		fills[emptiest].used += c.size
+		scheduled++
+
+		// A chunk with no data would loop forever without changing the fills
+		if c.size == 0 {
+			break
+		}
+	}
+	return scheduled
+}
+
+// finishRebalance ends the running rebalance
+func (m *Master) finishRebalance() {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.rebalance != nil {
+		slog.Info("rebalance complete", "moved", m.rebalance.Moved, "duration", time.Since(m.rebalance.StartedAt))
+		m.rebalance = nil
+	}
+}
+
+// extremeFills returns the fullest and emptiest servers
+func extremeFills(fills map[ChunkServerID]*serverFill) (ChunkServerID, ChunkServerID) {
+	var fullest, emptiest ChunkServerID
+	for id, f := range fills {
+		if fullest == "" || f.ratio() > fills[fullest].ratio() || (f.ratio() == fills[fullest].ratio() && id < fullest) {
+			fullest = id
+		}
+		if emptiest == "" || f.ratio() < fills[emptiest].ratio() || (f.ratio() == fills[emptiest].ratio() && id < emptiest) {
+			emptiest = id
+		}
+	}
+	return fullest, emptiest
+}
+
+// canHoldReplica reports whether target can take the replica on from without holding
+// the chunk already or sharing a failure domain with the other replicas
+func canHoldReplica(c movableChunk, from ChunkServerID, target ChunkLocation) bool {
+	for _, loc := range c.locations {
+		if loc.ServerID == target.ServerID {
+			return false
+		}
+		if loc.ServerID != from && loc.FailureDomain == target.FailureDomain {
+			return false
+		}
+	}
+	return true
+}
+
+// completeMove drops the source replica of a finished move and schedules its deletion
+func (m *Master) completeMove(task ReplicationTask) {
+	m.chunkMu.Lock()
+	chunk, exists := m.chunks[task.Handle]
+	if !exists {
+		m.chunkMu.Unlock()
+		return
+	}
+	if !m.removeLocationLocked(chunk, task.MoveFrom) {
+		m.chunkMu.Unlock()
+		return
+	}
+	m.chunkMu.Unlock()
+
+	m.pendingDeletesMu.Lock()
+	m.pendingDeletes[task.MoveFrom] = append(m.pendingDeletes[task.MoveFrom], task.Handle)
+	m.pendingDeletesMu.Unlock()
+
+	m.drainMu.Lock()
+	if task.Rebalance && m.rebalance != nil {
+		m.rebalance.Moved++
+	}
+	if state, ok := m.draining[task.MoveFrom]; ok {
+		state.Moved++
+	}
+	m.drainMu.Unlock()
+
+	slog.Info("chunk move complete", "chunk", task.Handle, "from", task.MoveFrom, "to", task.Target.ServerID)
+	m.logDrainComplete(task.MoveFrom)
+}
+
+// dropDeadDrainedReplica forgets a replica on a dead draining server once the
+// chunk is fully replicated elsewhere
+func (m *Master) dropDeadDrainedReplica(c movableChunk, id ChunkServerID, live map[ChunkServerID]ChunkLocation) {
+	others := 0
+	for _, loc := range c.locations {
+		if _, alive := live[loc.ServerID]; alive && loc.ServerID != id {
+			others++
+		}
+	}
+	if others < m.replicationFactor {
+		return
+	}
+
+	m.chunkMu.Lock()
+	if chunk, exists := m.chunks[c.handle]; exists {
+		m.removeLocationLocked(chunk, id)
+	}
+	m.chunkMu.Unlock()
+
+	slog.Info("dropped replica on dead draining server", "chunk", c.handle, "serverID", id)
+	m.logDrainComplete(id)
+}
+
+// removeLocationLocked removes a server from a chunk's replicas, keeping at least one.
+// Must be called with chunkMu held
+func (m *Master) removeLocationLocked(chunk *ChunkInfo, id ChunkServerID) bool {
+	kept := make([]ChunkLocation, 0, len(chunk.Locations))
+	for _, loc := range chunk.Locations {
+		if loc.ServerID != id {
+			kept = append(kept, loc)
+		}
+	}
+	if len(kept) == len(chunk.Locations) || len(kept) == 0 {
+		return false
+	}
+
+	chunk.Locations = kept
+	if chunk.Primary != nil && chunk.Primary.ServerID == id {
+		chunk.Primary = nil
+		m.reassignPrimaryLocked(chunk)
+	}
+	return true
+}
+
+// logDrainComplete logs once when a draining server holds no more replicas
+func (m *Master) logDrainComplete(id ChunkServerID) {
+	m.drainMu.Lock()
+	state, ok := m.draining[id]
+	if !ok || state.Complete {
+		m.drainMu.Unlock()
+		return
+	}
+	m.drainMu.Unlock()
+
+	m.chunkMu.RLock()
+	for _, chunk := range m.chunks {
+		for _, loc := range append(stripeLocations(chunk), chunk.Locations...) {
+			if loc.ServerID == id {
+				m.chunkMu.RUnlock()
+				return
+			}
+		}
+	}
+	m.chunkMu.RUnlock()
+
+	m.drainMu.Lock()
+	state.Complete = true
+	moved := state.Moved
+	m.drainMu.Unlock()
+	slog.Info("drain complete, chunkserver can be removed", "serverID", id, "moved", moved)
+}
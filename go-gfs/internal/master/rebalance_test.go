@@ -0,0 +1,214 @@
+package master
+
+import (
+	"testing"
+	"time"
+)
+
+// drainStatus returns the drain progress of one server
+func drainStatus(t *testing.T, m *Master, id string) DrainStatus {
+	t.Helper()
+	statuses, err := m.GetDrainStatus(ChunkServerID(id))
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("GetDrainStatus(%s) = %v, %v", id, statuses, err)
+	}
+	return statuses[0]
+}
+
+// completeMoves finishes every queued copy as its target would
+func completeMoves(m *Master, sources ...string) int {
+	n := 0
+	for _, id := range sources {
+		for _, task := range m.GetPendingReplications(ChunkServerID(id)) {
+			m.CompleteReplication(task.Source, task.Handle, task.Target.ServerID, true, "")
+			n++
+		}
+	}
+	return n
+}
+
+func TestDrainExcludesServerFromPlacement(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3", "cs4"} {
+		addTestServer(m, id, "")
+	}
+	if err := m.SetDraining("cs1", true); err != nil {
+		t.Fatalf("SetDraining: %v", err)
+	}
+	if err := m.SetDraining("missing", true); err == nil {
+		t.Error("draining an unknown server succeeded")
+	}
+
+	createTestFiles(t, m, "ns", "/f")
+	for i := 0; i < 10; i++ {
+		chunk, err := m.AddChunkToFile("/f", "ns")
+		if err != nil {
+			t.Fatalf("AddChunkToFile: %v", err)
+		}
+		for _, loc := range chunk.Locations {
+			if loc.ServerID == "cs1" {
+				t.Fatalf("chunk %s placed on draining cs1", chunk.Handle)
+			}
+		}
+	}
+
+	// Back in service, it takes replicas again
+	if err := m.SetDraining("cs1", false); err != nil {
+		t.Fatalf("SetDraining: %v", err)
+	}
+	placed := false
+	for i := 0; i < 10 && !placed; i++ {
+		chunk, err := m.AddChunkToFile("/f", "ns")
+		if err != nil {
+			t.Fatalf("AddChunkToFile: %v", err)
+		}
+		for _, loc := range chunk.Locations {
+			placed = placed || loc.ServerID == "cs1"
+		}
+	}
+	if !placed {
+		t.Error("undrained cs1 got no replicas")
+	}
+}
+
+func TestDrainCompletesWhenReplicasMoved(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		addTestServer(m, id, "")
+	}
+	addTestChunk(m, "c1", "cs1", "cs2", "cs3")
+	addTestChunk(m, "c2", "cs1", "cs2", "cs3")
+	if err := m.SetDraining("cs1", true); err != nil {
+		t.Fatalf("SetDraining: %v", err)
+	}
+
+	// Every other server already holds the chunks, so there is nowhere to move them
+	m.scheduleReplications()
+	if n := completeMoves(m, "cs1"); n != 0 {
+		t.Fatalf("%d moves with no target available", n)
+	}
+	if s := drainStatus(t, m, "cs1"); s.Complete || s.RemainingChunks != 2 {
+		t.Fatalf("drain = %+v, want 2 chunks remaining", s)
+	}
+
+	addTestServer(m, "cs4", "")
+	m.scheduleReplications()
+	if s := drainStatus(t, m, "cs1"); s.Complete || s.InflightMoves != 2 {
+		t.Fatalf("drain = %+v, want 2 moves in flight", s)
+	}
+	// A failed copy leaves the replica where it is
+	for _, task := range m.GetPendingReplications("cs1") {
+		m.CompleteReplication(task.Source, task.Handle, task.Target.ServerID, false, "disk full")
+	}
+	if s := drainStatus(t, m, "cs1"); s.Complete || s.RemainingChunks != 2 {
+		t.Fatalf("drain after failed copies = %+v, want 2 chunks remaining", s)
+	}
+
+	m.scheduleReplications()
+	if n := completeMoves(m, "cs1"); n != 2 {
+		t.Fatalf("completed %d moves, want 2", n)
+	}
+	s := drainStatus(t, m, "cs1")
+	if !s.Complete || s.RemainingChunks != 0 || s.MovedChunks != 2 {
+		t.Errorf("drain = %+v, want complete with 2 moved", s)
+	}
+	for _, handle := range []ChunkHandle{"c1", "c2"} {
+		chunk, err := m.GetChunkInfo(handle)
+		if err != nil {
+			t.Fatalf("GetChunkInfo: %v", err)
+		}
+		m.chunkMu.RLock()
+		servers := make(map[ChunkServerID]bool)
+		for _, loc := range chunk.Locations {
+			servers[loc.ServerID] = true
+		}
+		m.chunkMu.RUnlock()
+		if servers["cs1"] || len(servers) != 3 || !servers["cs4"] {
+			t.Errorf("%s on %v, want cs2, cs3 and cs4", handle, servers)
+		}
+	}
+	if deletes := m.GetPendingDeletes("cs1"); len(deletes) != 2 {
+		t.Errorf("cs1 has %d deletes queued, want 2", len(deletes))
+	}
+}
+
+// A dead draining server's replica is forgotten only once the chunk is fully
+// replicated without it
+func TestDrainDeadServer(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3", "cs4"} {
+		addTestServer(m, id, "")
+	}
+	// With a primary and no lease, the copy's report doesn't start a new lease
+	chunk := addTestChunk(m, "c1", "cs1", "cs2", "cs3")
+	chunk.Primary = &chunk.Locations[1]
+	if err := m.SetDraining("cs1", true); err != nil {
+		t.Fatalf("SetDraining: %v", err)
+	}
+	setHeartbeat(m, "cs1", HeartbeatTimeout+time.Second)
+
+	// The chunk has only two live replicas: heal first
+	m.scheduleReplications()
+	if s := drainStatus(t, m, "cs1"); s.Complete || s.IsAlive {
+		t.Fatalf("drain = %+v, want incomplete on a dead server", s)
+	}
+	if n := completeMoves(m, "cs2", "cs3"); n != 1 {
+		t.Fatalf("completed %d copies, want 1", n)
+	}
+
+	m.scheduleReplications()
+	if s := drainStatus(t, m, "cs1"); !s.Complete {
+		t.Errorf("drain = %+v, want complete once the chunk is healed", s)
+	}
+}
+
+func TestRebalancePicksByFill(t *testing.T) {
+	const gib = 1 << 30
+	m := newTestMaster(t)
+	fills := map[string]uint64{"cs1": 90, "cs2": 50, "cs3": 45, "cs4": 10} // Percent used
+	for id, used := range fills {
+		addTestServer(m, id, "")
+		m.UpdateChunkServerUsage(ChunkServerID(id), 0, DiskUsage{CapacityBytes: 100 * gib, FreeBytes: (100 - used) * gib})
+	}
+	// Chunks the emptiest server already holds can't move to it
+	addTestChunk(m, "on-cs4", "cs1", "cs2", "cs4")
+	for _, handle := range []string{"a", "b", "c"} {
+		chunk := addTestChunk(m, handle, "cs1", "cs2", "cs3")
+		chunk.Size = 10 * gib
+	}
+
+	if err := m.StartRebalance(0.5); err != nil {
+		t.Fatalf("StartRebalance: %v", err)
+	}
+	m.scheduleReplications()
+
+	// 90% vs 10%: moves run from cs1 to cs4 until the planned spread is within 50%
+	tasks := m.GetPendingReplications("cs1")
+	if len(tasks) != 2 {
+		t.Fatalf("%d moves from the fullest server, want 2: %+v", len(tasks), tasks)
+	}
+	for _, task := range tasks {
+		if task.Target.ServerID != "cs4" || !task.Rebalance || task.MoveFrom != "cs1" || task.Handle == "on-cs4" {
+			t.Errorf("move = %+v, want a rebalance of a chunk from cs1 to cs4", task)
+		}
+	}
+	for _, id := range []string{"cs2", "cs3", "cs4"} {
+		if tasks := m.GetPendingReplications(ChunkServerID(id)); len(tasks) != 0 {
+			t.Errorf("%s has %d moves, want none", id, len(tasks))
+		}
+	}
+	if status := m.GetRebalanceStatus(); !status.Active || status.InflightMoves != 2 {
+		t.Errorf("status = %+v, want active with 2 moves in flight", status)
+	}
+
+	// Once the moves land and the fills even out, the rebalance ends
+	for _, task := range tasks {
+		m.CompleteReplication(task.Source, task.Handle, task.Target.ServerID, true, "")
+	}
+	m.UpdateChunkServerUsage("cs1", 0, DiskUsage{CapacityBytes: 100 * gib, FreeBytes: 30 * gib})
+	m.UpdateChunkServerUsage("cs4", 0, DiskUsage{CapacityBytes: 100 * gib, FreeBytes: 70 * gib})
+	m.scheduleReplications()
+	if status := m.GetRebalanceStatus(); status.Active {
+		t.Errorf("rebalance still active at spread %.2f", status.Spread)
+	}
+}
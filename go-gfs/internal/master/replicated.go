@@ -0,0 +1,216 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/master/consensus"
+	"eddisonso.com/go-gfs/internal/master/wal"
+	"eddisonso.com/go-gfs/internal/masterconn"
+	"google.golang.org/grpc"
+)
+
+// ProposalTimeout bounds how long a metadata change waits for a majority of replicas
+const ProposalTimeout = 10 * time.Second
+
+// Methods any replica answers; everything else is served by the leader only
+var followerMethods = map[string]bool{
+	"/master.v1.Master/GetLeader": true,
+}
+
+// EnableReplication joins this master to a consensus group with its standby replicas.
+// From then on every WAL entry is committed by a majority before it is applied,
+// and only the leader serves clients and chunkservers.
+func (m *Master) EnableReplication(cfg consensus.Config) error {
+	node, err := consensus.Start(cfg, replicatedState{m}, m.appliedIndex.Load())
+	if err != nil {
+		return fmt.Errorf("failed to start consensus: %w", err)
+	}
+	m.raft = node
+	m.wal.SetReplicator(replicatedState{m})
+	m.watch.setHistory(watchHistory{floor: m.appliedIndex.Load(), replicated: true})
+
+	// State from a standalone master has never been through the log;
+	// replicate it to the group as one snapshot entry
+	if m.appliedIndex.Load() == 0 && m.hasMetadata() {
+		slog.Warn("local metadata predates replication, importing it into the replicated log")
+		go m.importLocalMetadata()
+	}
+	return nil
+}
+
+// Raft returns the consensus node, or nil for a standalone master
+func (m *Master) Raft() *consensus.Node {
+	return m.raft
+}
+
+// IsLeader reports whether this master serves requests.
+// A standalone master is always the leader.
+func (m *Master) IsLeader() bool {
+	return m.raft == nil || m.raft.IsLeader()
+}
+
+// LeaderInfo describes the consensus group for GetLeader
+type LeaderInfo struct {
+	Replicated    bool
+	LeaderID      uint64
+	LeaderAddress string
+	ReplicaID     uint64
+	Replicas      map[uint64]string
+}
+
+// GetLeader returns the current leader of the consensus group
+func (m *Master) GetLeader() LeaderInfo {
+	if m.raft == nil {
+		return LeaderInfo{}
+	}
+	id, addr := m.raft.Leader()
+	return LeaderInfo{
+		Replicated:    true,
+		LeaderID:      id,
+		LeaderAddress: addr,
+		ReplicaID:     m.raft.ID(),
+		Replicas:      m.raft.Peers(),
+	}
+}
+
+// UnaryLeaderInterceptor rejects calls on followers with a redirect to the leader
+func (m *Master) UnaryLeaderInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if m.servesMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		_, addr := m.raft.Leader()
+		return nil, masterconn.NotLeaderError(ctx, addr)
+	}
+}
+
+// StreamLeaderInterceptor rejects streams on followers with a redirect to the leader
+func (m *Master) StreamLeaderInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if m.servesMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		_, addr := m.raft.Leader()
+		return masterconn.NotLeaderError(ss.Context(), addr)
+	}
+}
+
+// servesMethod reports whether this replica handles a gRPC method right now
+func (m *Master) servesMethod(method string) bool {
+	if m.IsLeader() || followerMethods[method] {
+		return true
+	}
+	// Replica-to-replica traffic is always accepted
+	return strings.HasPrefix(method, "/master.v1.MasterPeer/")
+}
+
+// hasMetadata reports whether any files exist
+func (m *Master) hasMetadata() bool {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	return len(m.files) > 0
+}
+
+// importLocalMetadata submits this replica's standalone state to the group, retrying
+// until a leader accepts it. Followers forward the entry, so this works on any replica.
+func (m *Master) importLocalMetadata() {
+	for {
+		if id, _ := m.raft.Leader(); id == 0 {
+			time.Sleep(time.Second)
+			continue
+		}
+
+		snapshot := m.CreateSnapshot()
+		snapshotData, err := json.Marshal(snapshot)
+		if err != nil {
+			slog.Error("failed to encode local metadata for import", "error", err)
+			return
+		}
+		data, err := json.Marshal(wal.Entry{Op: wal.OpImportSnapshot, Data: snapshotData})
+		if err != nil {
+			slog.Error("failed to encode local metadata for import", "error", err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ProposalTimeout)
+		err = m.raft.Submit(ctx, data)
+		cancel()
+		if err == nil {
+			slog.Info("imported local metadata into replicated log", "files", len(snapshot.Files), "chunks", len(snapshot.Chunks))
+			return
+		}
+		slog.Warn("failed to import local metadata, retrying", "error", err)
+		time.Sleep(time.Second)
+	}
+}
+
+// replicatedState adapts the master to the consensus state machine and WAL replicator interfaces
+type replicatedState struct {
+	m *Master
+}
+
+// Replicate proposes a WAL entry and waits for it to commit
+func (r replicatedState) Replicate(data []byte) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ProposalTimeout)
+	defer cancel()
+	return r.m.raft.Propose(ctx, data)
+}
+
+// Apply records a committed entry in the local WAL and applies it unless a caller here will.
+// Entries proposed on this replica are applied to memory by the waiting caller, under the
+// locks it held to propose them; snapshots count them as applied once they hold every lock.
+func (r replicatedState) Apply(index uint64, data []byte, local bool) {
+	m := r.m
+	defer m.committedIndex.Store(index)
+
+	var entry wal.Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		slog.Error("skipping malformed replicated entry", "index", index, "error", err)
+		return
+	}
+
+	if err := m.wal.AppendCommitted(index, entry); err != nil {
+		// Memory is still updated below, so the next snapshot captures the entry
+		slog.Error("failed to write committed entry to local WAL", "index", index, "error", err)
+	}
+
+	// Imports are submitted rather than proposed, so they are applied here on every
+	// replica, including the one that sent them
+	if !local {
+		m.fileMu.Lock()
+		m.chunkMu.Lock()
+		m.applyEntry(entry)
+		m.chunkMu.Unlock()
+		m.fileMu.Unlock()
+		m.watch.applied(index)
+	}
+}
+
+// Restore replaces master state with a snapshot sent by the leader
+func (r replicatedState) Restore(index uint64, data []byte) error {
+	m := r.m
+
+	var snapshot wal.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	snapshot.Index = index
+
+	m.RestoreFromSnapshot(&snapshot)
+	m.appliedIndex.Store(index)
+	m.committedIndex.Store(index)
+	m.wal.SetSeq(index)
+	m.watch.reset(index)
+	m.watch.setHistory(watchHistory{floor: index, replicated: true})
+
+	// Persist it locally so a restart doesn't need the leader
+	if err := m.wal.WriteSnapshot(&snapshot); err != nil {
+		return err
+	}
+	return m.wal.TruncateThrough(index)
+}
@@ -0,0 +1,448 @@
+package master
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/erasure"
+)
+
+// ReplicationTaskTimeout is how long an issued copy may run before it is rescheduled
+const ReplicationTaskTimeout = 5 * time.Minute
+
+// DeadReplicaTimeout is how long a chunkserver may miss heartbeats before its replicas
+// are dropped from chunk locations
+const DeadReplicaTimeout = 10 * time.Minute
+
+// maxInflightReplications caps concurrent copies so healing doesn't saturate the cluster
+const maxInflightReplications = 16
+
+// ReplicationTask instructs Source to copy a chunk to Target
+type ReplicationTask struct {
+	Handle    ChunkHandle
+	Source    ChunkServerID
+	Target    ChunkLocation
+	IssuedAt  time.Time
+	MoveFrom  ChunkServerID // Replica dropped once the copy lands (drain and rebalance moves)
+	Rebalance bool          // Move issued by the rebalancer
+}
+
+// underReplicatedChunk is a candidate for re-replication
+type underReplicatedChunk struct {
+	handle  ChunkHandle
+	live    []ChunkServerID        // Replicas on servers that are heartbeating
+	holders map[ChunkServerID]bool // Every server listed for the chunk, dead or alive
+}
+
+// StartReplicationManager starts a goroutine that periodically heals under-replicated chunks
+func (m *Master) StartReplicationManager(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				slog.Info("stopping replication manager")
+				return
+			case <-ticker.C:
+				m.scheduleReplications()
+			}
+		}
+	}()
+	slog.Info("started replication manager", "interval", interval)
+}
+
+// liveChunkServers returns a snapshot of chunkservers that heartbeated within HeartbeatTimeout
+func (m *Master) liveChunkServers() map[ChunkServerID]ChunkLocation {
+	m.csMu.RLock()
+	defer m.csMu.RUnlock()
+
+	now := time.Now()
+	live := make(map[ChunkServerID]ChunkLocation, len(m.chunkservers))
+	for id, loc := range m.chunkservers {
+		if now.Sub(loc.LastHeartbeat) < HeartbeatTimeout {
+			live[id] = *loc
+		}
+	}
+	return live
+}
+
+// findUnderReplicated returns committed chunks with fewer than replicationFactor live replicas,
+// ordered so chunks with the fewest live replicas come first
+func (m *Master) findUnderReplicated(live map[ChunkServerID]ChunkLocation) []underReplicatedChunk {
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+
+	var candidates []underReplicatedChunk
+	for handle, chunk := range m.chunks {
+		// Pending chunks are still being written; their replicas are in flux.
+		// Encoded chunks are healed by fragment rebuilds instead
+		if chunk.Status != ChunkCommitted || chunk.Stripe != nil {
+			continue
+		}
+
+		c := underReplicatedChunk{
+			handle:  handle,
+			holders: make(map[ChunkServerID]bool, len(chunk.Locations)),
+		}
+		for _, loc := range chunk.Locations {
+			c.holders[loc.ServerID] = true
+			if _, ok := live[loc.ServerID]; ok {
+				c.live = append(c.live, loc.ServerID)
+			}
+		}
+
+		// Nothing to copy from if every replica is gone
+		if len(c.live) > 0 && len(c.live) < m.replicationFactor {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return len(candidates[i].live) < len(candidates[j].live)
+	})
+	return candidates
+}
+
+// scheduleReplications queues copy commands for under-replicated chunks, then
+// uses the remaining capacity for drain and rebalance moves.
+// Erasure coding work has its own budget: fragment rebuilds, then encodes of cold chunks.
+// Sealed chunks of compressed namespaces are compressed in place by their replicas.
+func (m *Master) scheduleReplications() {
+	m.pruneDeadReplicas()
+	live := m.liveChunkServers()
+	candidates := m.findUnderReplicated(live)
+	draining := m.drainingServers()
+	movable := m.findMovableChunks(draining, m.isRebalancing())
+	degraded, lost := m.findDegradedStripes(live, draining)
+	encodable := m.findEncodable(live)
+	compressible := m.findCompressible(live)
+
+	m.replicationMu.Lock()
+	defer m.replicationMu.Unlock()
+
+	// Drop copies that never reported back, or whose source or target died, so they can be retried
+	now := time.Now()
+	m.expireReplicationsLocked(live, now)
+
+	// Count copies already assigned so sources are spread out
+	load := make(map[ChunkServerID]int)
+	for _, task := range m.inflightReplications {
+		load[task.Source]++
+	}
+
+	scheduled := 0
+	for _, c := range candidates {
+		if len(m.inflightReplications) >= maxInflightReplications {
+			break
+		}
+		if _, busy := m.inflightReplications[c.handle]; busy {
+			continue
+		}
+
+		// Place the new replica with the normal policy, spreading away from the surviving replicas
+		existing := make([]ChunkLocation, 0, len(c.live))
+		for _, id := range c.live {
+			existing = append(existing, live[id])
+		}
+		// Don't copy back onto a server that is about to delete its bad replica
+		for id := range live {
+			if m.isPendingDelete(id, c.handle) {
+				c.holders[id] = true
+			}
+		}
+		targets := m.placeReplicas(1, existing, c.holders)
+		if len(targets) == 0 {
+			slog.Debug("no target available for re-replication", "chunk", c.handle, "liveReplicas", len(c.live))
+			continue
+		}
+		target := targets[0]
+
+		source := c.live[0]
+		for _, id := range c.live[1:] {
+			if load[id] < load[source] {
+				source = id
+			}
+		}
+
+		task := &ReplicationTask{
+			Handle:   c.handle,
+			Source:   source,
+			Target:   target,
+			IssuedAt: now,
+		}
+		m.inflightReplications[c.handle] = task
+		m.pendingReplications[source] = append(m.pendingReplications[source], *task)
+		load[source]++
+		scheduled++
+
+		slog.Info("scheduled re-replication",
+			"chunk", c.handle,
+			"liveReplicas", len(c.live),
+			"source", source,
+			"target", target.ServerID)
+	}
+
+	if scheduled > 0 {
+		slog.Info("re-replication round", "underReplicated", len(candidates), "scheduled", scheduled)
+	}
+
+	if moved := m.scheduleMovesLocked(live, draining, movable, now); moved > 0 {
+		slog.Info("chunk move round", "scheduled", moved)
+	}
+
+	if lost > 0 {
+		slog.Error("erasure coded chunks have too few fragments to rebuild", "chunks", lost)
+	}
+	if encodes := m.scheduleEncodesLocked(encodable, degraded, now); encodes > 0 {
+		slog.Info("erasure coding round", "degraded", len(degraded), "encodable", len(encodable), "scheduled", encodes)
+	}
+	if compresses := m.scheduleCompressesLocked(compressible); compresses > 0 {
+		slog.Info("compression round", "compressible", len(compressible), "scheduled", compresses)
+	}
+}
+
+// expireReplicationsLocked drops in-flight copies past ReplicationTaskTimeout or with a
+// dead source or target, along with their queued commands, so the chunks are rescheduled.
+// Must be called with replicationMu held
+func (m *Master) expireReplicationsLocked(live map[ChunkServerID]ChunkLocation, now time.Time) {
+	for handle, task := range m.inflightReplications {
+		_, sourceLive := live[task.Source]
+		_, targetLive := live[task.Target.ServerID]
+		switch {
+		case now.Sub(task.IssuedAt) > ReplicationTaskTimeout:
+			slog.Warn("re-replication timed out, rescheduling",
+				"chunk", handle, "source", task.Source, "target", task.Target.ServerID)
+		case !sourceLive || !targetLive:
+			slog.Warn("re-replication server died, rescheduling",
+				"chunk", handle, "source", task.Source, "target", task.Target.ServerID)
+		default:
+			continue
+		}
+		delete(m.inflightReplications, handle)
+	}
+
+	// A source that comes back must not run a copy that was rescheduled elsewhere
+	for id, tasks := range m.pendingReplications {
+		kept := tasks[:0]
+		for _, task := range tasks {
+			if current, ok := m.inflightReplications[task.Handle]; ok && current.Source == task.Source &&
+				current.Target.ServerID == task.Target.ServerID && current.IssuedAt.Equal(task.IssuedAt) {
+				kept = append(kept, task)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.pendingReplications, id)
+		} else {
+			m.pendingReplications[id] = kept
+		}
+	}
+}
+
+// pruneDeadReplicas drops the replicas of chunkservers silent for DeadReplicaTimeout, so
+// chunk locations and replica counts only list copies that can be read. A server that
+// comes back is asked to list its chunks again, which restores them
+func (m *Master) pruneDeadReplicas() {
+	now := time.Now()
+	var dead []ChunkServerID
+	m.csMu.RLock()
+	for id, loc := range m.chunkservers {
+		if now.Sub(loc.LastHeartbeat) >= DeadReplicaTimeout {
+			dead = append(dead, id)
+		}
+	}
+	m.csMu.RUnlock()
+
+	for _, id := range dead {
+		if dropped := m.dropServer(id); dropped > 0 {
+			m.requestFullReport(id)
+			slog.Warn("dropped replicas of dead chunkserver", "serverID", id, "dropped", dropped)
+		}
+	}
+}
+
+// dropServer removes serverID from the locations of every chunk and fragment,
+// reassigning primaries it held, and returns how many copies were dropped
+func (m *Master) dropServer(serverID ChunkServerID) int {
+	m.chunkMu.Lock()
+	defer m.chunkMu.Unlock()
+
+	dropped := 0
+	for handle, chunk := range m.chunks {
+		if chunk.Stripe != nil {
+			for i, fragment := range chunk.Stripe.Fragments {
+				if kept, removed := withoutServer(fragment, serverID); removed {
+					chunk.Stripe.Fragments[i] = kept
+					dropped++
+				}
+			}
+		}
+		kept, removed := withoutServer(chunk.Locations, serverID)
+		if !removed {
+			continue
+		}
+		chunk.Locations = kept
+		if chunk.Primary != nil && chunk.Primary.ServerID == serverID {
+			chunk.Primary = nil
+			m.reassignPrimaryLocked(chunk)
+		}
+		if len(kept) == 0 {
+			slog.Error("chunk lost its last replica", "chunk", handle, "serverID", serverID)
+		}
+		dropped++
+	}
+	return dropped
+}
+
+// GetPendingReplications returns and clears the copy commands queued for a chunkserver
+func (m *Master) GetPendingReplications(id ChunkServerID) []ReplicationTask {
+	m.replicationMu.Lock()
+	defer m.replicationMu.Unlock()
+
+	tasks := m.pendingReplications[id]
+	delete(m.pendingReplications, id)
+	return tasks
+}
+
+// CompleteReplication records the outcome of a copy reported by the source chunkserver
+func (m *Master) CompleteReplication(source ChunkServerID, handle ChunkHandle, target ChunkServerID, success bool, message string) {
+	var finished *ReplicationTask
+	m.replicationMu.Lock()
+	if task, ok := m.inflightReplications[handle]; ok && task.Target.ServerID == target {
+		delete(m.inflightReplications, handle)
+		finished = task
+	}
+	m.replicationMu.Unlock()
+
+	if !success {
+		slog.Warn("re-replication failed", "chunk", handle, "source", source, "target", target, "error", message)
+		return
+	}
+
+	// Record the new location now rather than waiting for the target's next heartbeat;
+	// the copy carries the source's version, which that heartbeat reports
+	m.ReportChunk(target, handle, 0)
+	if finished != nil && finished.MoveFrom != "" {
+		m.completeMove(*finished)
+		return
+	}
+	slog.Info("re-replication complete", "chunk", handle, "source", source, "target", target)
+}
+
+// DropCorruptReplica removes a replica that failed checksum verification and schedules
+// its deletion, so re-replication restores the chunk from a healthy copy.
+// The last remaining replica is never dropped.
+func (m *Master) DropCorruptReplica(serverID ChunkServerID, handle ChunkHandle, reason string) error {
+	if chunkHandle, index, ok := erasure.ParseFragmentHandle(string(handle)); ok {
+		return m.dropCorruptFragment(serverID, handle, ChunkHandle(chunkHandle), index, reason)
+	}
+
+	m.chunkMu.Lock()
+	chunk, exists := m.chunks[handle]
+	if !exists {
+		m.chunkMu.Unlock()
+		return fmt.Errorf("chunk not found: %s", handle)
+	}
+
+	kept := make([]ChunkLocation, 0, len(chunk.Locations))
+	for _, loc := range chunk.Locations {
+		if loc.ServerID != serverID {
+			kept = append(kept, loc)
+		}
+	}
+	if len(kept) == len(chunk.Locations) {
+		m.chunkMu.Unlock()
+		return fmt.Errorf("server %s does not hold chunk %s", serverID, handle)
+	}
+	if len(kept) == 0 {
+		m.chunkMu.Unlock()
+		slog.Error("only replica of chunk is corrupt, keeping it", "chunk", handle, "serverID", serverID, "reason", reason)
+		return fmt.Errorf("no other replica of chunk %s", handle)
+	}
+
+	chunk.Locations = kept
+	if chunk.Primary != nil && chunk.Primary.ServerID == serverID {
+		chunk.Primary = nil
+		m.reassignPrimaryLocked(chunk)
+	}
+	m.chunkMu.Unlock()
+
+	m.pendingDeletesMu.Lock()
+	m.pendingDeletes[serverID] = append(m.pendingDeletes[serverID], handle)
+	m.pendingDeletesMu.Unlock()
+
+	slog.Warn("dropped corrupt replica", "chunk", handle, "serverID", serverID, "remaining", len(kept), "reason", reason)
+	return nil
+}
+
+// DropLostReplicas removes the replicas and fragments a chunkserver lost with a failed disk.
+// Unlike a corrupt replica there is nothing left to delete, and even the last replica of a
+// chunk is dropped since it can no longer be read. Re-replication and fragment repair
+// restore the lost copies from the survivors.
+func (m *Master) DropLostReplicas(serverID ChunkServerID, handles []ChunkHandle) {
+	dropped := m.dropReplicas(serverID, handles)
+	slog.Warn("dropped replicas lost with a failed disk", "serverID", serverID, "reported", len(handles), "dropped", dropped)
+}
+
+// DropRemovedReplicas removes the replicas and fragments a chunkserver reports it no longer
+// has, such as copies deleted by hand or cleaned up after the master stopped tracking them
+func (m *Master) DropRemovedReplicas(serverID ChunkServerID, handles []ChunkHandle) {
+	if dropped := m.dropReplicas(serverID, handles); dropped > 0 {
+		slog.Warn("dropped replicas a chunkserver no longer has", "serverID", serverID, "reported", len(handles), "dropped", dropped)
+	}
+}
+
+// dropReplicas removes serverID from the locations of the given chunks and fragments,
+// reassigning primaries it held, and returns how many copies were dropped
+func (m *Master) dropReplicas(serverID ChunkServerID, handles []ChunkHandle) int {
+	m.chunkMu.Lock()
+	defer m.chunkMu.Unlock()
+
+	dropped := 0
+	for _, handle := range handles {
+		if chunkHandle, index, ok := erasure.ParseFragmentHandle(string(handle)); ok {
+			chunk, exists := m.chunks[ChunkHandle(chunkHandle)]
+			if !exists || chunk.Stripe == nil || index >= len(chunk.Stripe.Fragments) {
+				continue
+			}
+			if kept, removed := withoutServer(chunk.Stripe.Fragments[index], serverID); removed {
+				chunk.Stripe.Fragments[index] = kept
+				dropped++
+			}
+			continue
+		}
+
+		chunk, exists := m.chunks[handle]
+		if !exists {
+			continue
+		}
+		kept, removed := withoutServer(chunk.Locations, serverID)
+		if !removed {
+			continue
+		}
+		chunk.Locations = kept
+		if chunk.Primary != nil && chunk.Primary.ServerID == serverID {
+			chunk.Primary = nil
+			m.reassignPrimaryLocked(chunk)
+		}
+		if len(kept) == 0 {
+			slog.Error("chunk lost its last replica", "chunk", handle, "serverID", serverID)
+		}
+		dropped++
+	}
+	return dropped
+}
+
+// withoutServer returns locations minus any on serverID, and whether one was removed
+func withoutServer(locations []ChunkLocation, serverID ChunkServerID) ([]ChunkLocation, bool) {
+	kept := make([]ChunkLocation, 0, len(locations))
+	for _, loc := range locations {
+		if loc.ServerID != serverID {
+			kept = append(kept, loc)
+		}
+	}
+	return kept, len(kept) != len(locations)
+}
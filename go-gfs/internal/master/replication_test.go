@@ -0,0 +1,150 @@
+package master
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+func TestScheduleReplicationsReschedulesDeadSource(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3", "cs4"} {
+		addTestServer(m, id, "")
+	}
+	addTestChunk(m, "c1", "cs1", "cs2")
+
+	m.scheduleReplications()
+	m.replicationMu.Lock()
+	task := m.inflightReplications["c1"]
+	m.replicationMu.Unlock()
+	if task == nil {
+		t.Fatal("no copy scheduled for under-replicated chunk")
+	}
+	source := string(task.Source)
+
+	// The source dies before picking up its command
+	setHeartbeat(m, source, HeartbeatTimeout+time.Second)
+	m.scheduleReplications()
+
+	if tasks := m.GetPendingReplications(ChunkServerID(source)); len(tasks) != 0 {
+		t.Fatalf("dead source still has %d queued copies", len(tasks))
+	}
+	m.replicationMu.Lock()
+	task = m.inflightReplications["c1"]
+	m.replicationMu.Unlock()
+	if task == nil || string(task.Source) == source {
+		t.Fatalf("copy not rescheduled from a live source: %+v", task)
+	}
+	if tasks := m.GetPendingReplications(task.Source); len(tasks) != 1 || tasks[0].Handle != "c1" {
+		t.Fatalf("new source queue = %+v, want one copy of c1", tasks)
+	}
+}
+
+func TestScheduleReplicationsExpiresStaleTask(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		addTestServer(m, id, "")
+	}
+	addTestChunk(m, "c1", "cs1", "cs2")
+
+	m.scheduleReplications()
+	m.replicationMu.Lock()
+	first := *m.inflightReplications["c1"]
+	m.inflightReplications["c1"].IssuedAt = time.Now().Add(-ReplicationTaskTimeout - time.Second)
+	m.replicationMu.Unlock()
+
+	// The source picked the command up but never reported back
+	m.scheduleReplications()
+	m.replicationMu.Lock()
+	second := m.inflightReplications["c1"]
+	m.replicationMu.Unlock()
+	if second == nil || !second.IssuedAt.After(first.IssuedAt) {
+		t.Fatalf("timed out copy not rescheduled: %+v", second)
+	}
+	if tasks := m.GetPendingReplications(second.Source); len(tasks) != 1 || !tasks[0].IssuedAt.Equal(second.IssuedAt) {
+		t.Fatalf("queue holds %+v, want only the new copy", tasks)
+	}
+}
+
+func TestPruneDeadReplicas(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		addTestServer(m, id, "")
+	}
+	chunk := addTestChunk(m, "c1", "cs1", "cs2", "cs3")
+	chunk.Primary = &ChunkLocation{ServerID: "cs3"}
+
+	tests := []struct {
+		age     time.Duration
+		holders int
+	}{
+		{HeartbeatTimeout + time.Second, 3}, // Dead but may come back: keep listing it
+		{DeadReplicaTimeout + time.Second, 2},
+	}
+	for _, tt := range tests {
+		setHeartbeat(m, "cs3", tt.age)
+		m.pruneDeadReplicas()
+
+		m.chunkMu.RLock()
+		holders := len(chunk.Locations)
+		m.chunkMu.RUnlock()
+		if holders != tt.holders {
+			t.Fatalf("after %v silence chunk has %d locations, want %d", tt.age, holders, tt.holders)
+		}
+	}
+	for _, loc := range chunk.Locations {
+		if loc.ServerID == "cs3" {
+			t.Fatal("dead server still listed")
+		}
+	}
+	if chunk.Primary != nil && chunk.Primary.ServerID == "cs3" {
+		t.Fatal("dead server is still primary")
+	}
+	if !m.takeFullReportRequest("cs3") {
+		t.Fatal("dead server not asked to relist its chunks when it comes back")
+	}
+}
+
+// Chunks a heartbeat reports lost with a failed disk are dropped, even the last copy
+func TestHeartbeatDropsLostReplicas(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		addTestServer(m, id, "")
+	}
+	shared := addTestChunk(m, "c1", "cs1", "cs2")
+	shared.Primary = &shared.Locations[0]
+	only := addTestChunk(m, "c2", "cs1")
+
+	_, err := NewGRPCServer(m).Heartbeat(context.Background(), &pb.HeartbeatRequest{
+		ServerId:    "cs1",
+		LostChunks:  []string{"c1", "c2", "unknown"},
+		ChunkReport: &pb.ChunkReport{},
+		Disks:       []*pb.DiskStatus{{Dir: "/a"}, {Dir: "/b", Failed: true}},
+	})
+	if err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+
+	m.chunkMu.RLock()
+	if len(shared.Locations) != 1 || shared.Locations[0].ServerID != "cs2" {
+		t.Errorf("c1 locations = %v, want cs2 only", shared.Locations)
+	}
+	if shared.Primary == nil || shared.Primary.ServerID != "cs2" {
+		t.Errorf("c1 primary = %v, want cs2", shared.Primary)
+	}
+	if len(only.Locations) != 0 {
+		t.Errorf("c2 locations = %v, want none", only.Locations)
+	}
+	m.chunkMu.RUnlock()
+	if deletes := m.GetPendingDeletes("cs1"); len(deletes) != 0 {
+		t.Errorf("deletes queued for lost chunks: %v", deletes)
+	}
+
+	// The surviving copy is re-replicated
+	m.scheduleReplications()
+	if tasks := m.GetPendingReplications("cs2"); len(tasks) != 1 || tasks[0].Handle != "c1" {
+		t.Errorf("cs2 copies = %+v, want one of c1", tasks)
+	}
+}
@@ -0,0 +1,109 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/datatoken"
+	"eddisonso.com/go-gfs/internal/erasure"
+	"eddisonso.com/go-gfs/internal/mtls"
+)
+
+// ErrGrantsDisabled is returned when the master has no key to sign data-plane grants
+var ErrGrantsDisabled = errors.New("data-plane grants are not enabled")
+
+// SetTLS sets the credentials used to dial chunkservers
+func (m *Master) SetTLS(source *mtls.Source) {
+	m.tls = source
+}
+
+// SetGrantIssuer makes the master sign a data-plane grant into every chunk
+// location it hands out. Chunkservers with the matching public key accept
+// only requests carrying a grant for the chunk and operation.
+func (m *Master) SetGrantIssuer(issuer *datatoken.Issuer) {
+	m.grants = issuer
+}
+
+// IssueGrant signs a grant for op on a chunk, or for reading one fragment of
+// an erasure coded chunk. Clients use it to renew grants that are about to expire.
+func (m *Master) IssueGrant(handle ChunkHandle, op string) (string, time.Time, error) {
+	if m.grants == nil {
+		return "", time.Time{}, ErrGrantsDisabled
+	}
+	if op != datatoken.OpRead && op != datatoken.OpWrite {
+		return "", time.Time{}, fmt.Errorf("unknown operation: %s", op)
+	}
+
+	chunkHandle, index, isFragment := erasure.ParseFragmentHandle(string(handle))
+	if !isFragment {
+		chunkHandle = string(handle)
+	}
+
+	m.chunkMu.RLock()
+	chunk, ok := m.chunks[ChunkHandle(chunkHandle)]
+	fragments := -1
+	if ok && chunk.Stripe != nil {
+		fragments = len(chunk.Stripe.Fragments)
+	}
+	m.chunkMu.RUnlock()
+
+	switch {
+	case !ok:
+		return "", time.Time{}, fmt.Errorf("chunk not found: %s", chunkHandle)
+	case isFragment && index >= fragments:
+		return "", time.Time{}, fmt.Errorf("fragment not found: %s", handle)
+	case op == datatoken.OpWrite && fragments >= 0:
+		return "", time.Time{}, fmt.Errorf("erasure coded chunk %s is read-only", chunkHandle)
+	}
+	return m.grants.Issue(string(handle), op)
+}
+
+// grant signs a grant for a chunk the caller has just looked up,
+// returning "" when grants are disabled
+func (m *Master) grant(handle, op string) string {
+	if m.grants == nil {
+		return ""
+	}
+	token, _, err := m.grants.Issue(handle, op)
+	if err != nil {
+		// The client renews the grant through IssueDataToken
+		slog.Error("failed to sign data-plane grant", "chunk", handle, "operation", op, "error", err)
+		return ""
+	}
+	return token
+}
+
+// Master methods only chunkservers call, and methods that change cluster settings
+var (
+	chunkServerMethods = map[string]bool{
+		"/master.v1.Master/Register":           true,
+		"/master.v1.Master/Heartbeat":          true,
+		"/master.v1.Master/ReportCommit":       true,
+		"/master.v1.Master/RenewLease":         true,
+		"/master.v1.Master/ClaimPrimary":       true,
+		"/master.v1.Master/ReportReplication":  true,
+		"/master.v1.Master/ReportCorruptChunk": true,
+		"/master.v1.Master/ReportEncode":       true,
+	}
+	adminMethods = map[string]bool{
+		"/master.v1.Master/SetNamespaceQuota": true,
+		"/master.v1.Master/PurgeTrash":        true,
+		"/master.v1.Master/DrainChunkServer":  true,
+		"/master.v1.Master/Rebalance":         true,
+	}
+)
+
+// RequiredRole returns the certificate role a caller needs for a gRPC method,
+// or "" if any client may call it
+func RequiredRole(method string) string {
+	switch {
+	case chunkServerMethods[method] || strings.HasPrefix(method, "/master.v1.MasterPeer/"):
+		return mtls.RoleServer
+	case adminMethods[method]:
+		return mtls.RoleAdmin
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package master
+
+import (
+	"testing"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/mtls"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := map[string]string{
+		pb.Master_Register_FullMethodName:           mtls.RoleServer,
+		pb.Master_Heartbeat_FullMethodName:          mtls.RoleServer,
+		pb.Master_ReportCommit_FullMethodName:       mtls.RoleServer,
+		pb.Master_RenewLease_FullMethodName:         mtls.RoleServer,
+		pb.Master_ClaimPrimary_FullMethodName:       mtls.RoleServer,
+		pb.Master_ReportReplication_FullMethodName:  mtls.RoleServer,
+		pb.Master_ReportCorruptChunk_FullMethodName: mtls.RoleServer,
+		pb.Master_ReportEncode_FullMethodName:       mtls.RoleServer,
+		pb.MasterPeer_Step_FullMethodName:           mtls.RoleServer,
+		pb.Master_SetNamespaceQuota_FullMethodName:  mtls.RoleAdmin,
+		pb.Master_PurgeTrash_FullMethodName:         mtls.RoleAdmin,
+		pb.Master_DrainChunkServer_FullMethodName:   mtls.RoleAdmin,
+		pb.Master_Rebalance_FullMethodName:          mtls.RoleAdmin,
+		pb.Master_CreateFile_FullMethodName:         "",
+		pb.Master_GetChunkLocations_FullMethodName:  "",
+		pb.Master_GetDrainStatus_FullMethodName:     "",
+		pb.Master_GetLeader_FullMethodName:          "",
+	}
+	for method, want := range tests {
+		if got := RequiredRole(method); got != want {
+			t.Errorf("RequiredRole(%s) = %q, want %q", method, got, want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package master
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/master/wal"
+)
+
+// MaxTransactOps bounds the operations in one transaction, which share a WAL record
+const MaxTransactOps = 1000
+
+// ErrPreconditionFailed is returned when a conditional change finds a file not as expected
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrFileExists is returned when a create, rename or restore would overwrite a file
+var ErrFileExists = fmt.Errorf("%w: file already exists", ErrPreconditionFailed)
+
+// TxOpType is the kind of change made by a transaction op
+type TxOpType string
+
+const (
+	TxCreate        TxOpType = "create"         // Create Path with Attributes and StorageClass
+	TxDelete        TxOpType = "delete"         // Delete Path, or move it to the trash
+	TxRename        TxOpType = "rename"         // Move Path to NewPath, replacing it if Replace is set
+	TxSetAttributes TxOpType = "set_attributes" // Set Attributes on Path, then delete the keys in Remove
+)
+
+// TxCondition must hold for a transaction to apply. By default the file must exist
+type TxCondition struct {
+	Path      string
+	Absent    bool // The path must not exist
+	CheckSize bool // The file must be exactly Size bytes
+	Size      uint64
+}
+
+// TxOp is one change in a transaction
+type TxOp struct {
+	Type         TxOpType
+	Path         string
+	NewPath      string
+	Replace      bool
+	StorageClass string
+	Attributes   map[string]string
+	Remove       []string
+}
+
+// Transact checks every condition, then applies the ops in order as a single WAL
+// record: either all of them take effect or none do. Later ops see the files as
+// earlier ops left them
+func (m *Master) Transact(namespace string, conditions []TxCondition, ops []TxOp) error {
+	if err := checkUserNamespace(namespace); err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return fmt.Errorf("transaction has no operations")
+	}
+	if len(ops) > MaxTransactOps {
+		return fmt.Errorf("too many operations: %d (max %d)", len(ops), MaxTransactOps)
+	}
+	namespace = normalizeNamespace(namespace)
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	for _, cond := range conditions {
+		if err := m.checkConditionLocked(namespace, cond); err != nil {
+			return err
+		}
+	}
+
+	view := &txView{m: m, namespace: namespace, files: make(map[string]*txFile), deletedAt: time.Now()}
+	var entries []wal.Entry
+	for i, op := range ops {
+		opEntries, err := view.plan(op)
+		if err != nil {
+			return fmt.Errorf("op %d (%s %s): %w", i, op.Type, op.Path, err)
+		}
+		entries = append(entries, opEntries...)
+	}
+	if view.added > 0 {
+		if err := m.checkQuotaLocked(namespace, uint64(view.added), 0); err != nil {
+			return err
+		}
+	}
+
+	// Log to WAL before applying
+	seq, err := m.wal.LogTransact(entries)
+	if err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+
+	m.chunkMu.Lock()
+	released := m.applyTransactLocked(entries)
+	m.chunkMu.Unlock()
+
+	m.scheduleChunkDeletes(released)
+
+	slog.Debug("applied transaction", "namespace", namespace, "ops", len(ops), "entries", len(entries), "released", len(released))
+	return nil
+}
+
+// checkConditionLocked must be called with fileMu held
+func (m *Master) checkConditionLocked(namespace string, cond TxCondition) error {
+	file, exists := m.files[makeFileKey(namespace, cond.Path)]
+	switch {
+	case cond.Absent && exists:
+		return fmt.Errorf("%w: %s", ErrFileExists, cond.Path)
+	case cond.Absent:
+		return nil
+	case !exists:
+		return fmt.Errorf("%w: file not found: %s", ErrPreconditionFailed, cond.Path)
+	case cond.CheckSize && file.Size != cond.Size:
+		return fmt.Errorf("%w: %s is %d bytes, expected %d", ErrPreconditionFailed, cond.Path, file.Size, cond.Size)
+	}
+	return nil
+}
+
+// applyTransactLocked applies the entries of a transaction in order (no WAL logging)
+// and returns the chunks released by deletes.
+// Must be called with fileMu and chunkMu held
+func (m *Master) applyTransactLocked(entries []wal.Entry) []*ChunkInfo {
+	var released []*ChunkInfo
+	for _, entry := range entries {
+		if entry.Op != wal.OpDeleteFile {
+			m.applyEntry(entry)
+			continue
+		}
+		var data wal.DeleteFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			slog.Warn("failed to unmarshal DELETE_FILE", "error", err)
+			continue
+		}
+		released = append(released, m.replayDeleteFile(data.Path, data.Namespace)...)
+	}
+	return released
+}
+
+// txFile is a file as a transaction's earlier ops left it
+type txFile struct {
+	attributes map[string]string
+}
+
+// txView plans a transaction's ops against the namespace without changing it
+type txView struct {
+	m         *Master
+	namespace string
+	files     map[string]*txFile // Paths changed by earlier ops; nil once removed
+	added     int                // Files added less files removed
+	deletedAt time.Time
+}
+
+// lookup returns a file as earlier ops left it
+func (v *txView) lookup(path string) (*txFile, bool) {
+	if file, ok := v.files[path]; ok {
+		return file, file != nil
+	}
+	if file, ok := v.m.files[makeFileKey(v.namespace, path)]; ok {
+		return &txFile{attributes: file.Attributes}, true
+	}
+	return nil, false
+}
+
+// plan checks an op against the view, applies it there, and returns its WAL entries
+func (v *txView) plan(op TxOp) ([]wal.Entry, error) {
+	if op.Path == "" {
+		return nil, fmt.Errorf("path must not be empty")
+	}
+	file, exists := v.lookup(op.Path)
+
+	switch op.Type {
+	case TxCreate:
+		if err := validateStorageClass(op.StorageClass); err != nil {
+			return nil, err
+		}
+		attributes := cloneAttributes(op.Attributes)
+		if err := validateAttributes(attributes); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: %s", ErrFileExists, op.Path)
+		}
+		v.files[op.Path] = &txFile{attributes: attributes}
+		v.added++
+		return []wal.Entry{wal.NewEntry(wal.OpCreateFile, wal.CreateFileData{
+			Path:         op.Path,
+			Namespace:    v.namespace,
+			ChunkSize:    v.m.defaultChunkSize,
+			StorageClass: op.StorageClass,
+			Attributes:   attributes,
+		})}, nil
+
+	case TxDelete:
+		if !exists {
+			return nil, fmt.Errorf("file not found: %s", op.Path)
+		}
+		return []wal.Entry{v.remove(op.Path)}, nil
+
+	case TxRename:
+		if !exists {
+			return nil, fmt.Errorf("file not found: %s", op.Path)
+		}
+		if op.NewPath == "" || op.NewPath == op.Path {
+			return nil, fmt.Errorf("invalid destination: %q", op.NewPath)
+		}
+		var entries []wal.Entry
+		if _, taken := v.lookup(op.NewPath); taken {
+			if !op.Replace {
+				return nil, fmt.Errorf("%w: %s", ErrFileExists, op.NewPath)
+			}
+			entries = append(entries, v.remove(op.NewPath))
+		}
+		v.files[op.NewPath] = file
+		v.files[op.Path] = nil
+		return append(entries, wal.NewEntry(wal.OpRenameFile, wal.RenameFileData{
+			OldPath:   op.Path,
+			NewPath:   op.NewPath,
+			Namespace: v.namespace,
+		})), nil
+
+	case TxSetAttributes:
+		if !exists {
+			return nil, fmt.Errorf("file not found: %s", op.Path)
+		}
+		attributes := maps.Clone(file.attributes)
+		if attributes == nil {
+			attributes = make(map[string]string, len(op.Attributes))
+		}
+		maps.Copy(attributes, op.Attributes)
+		for _, key := range op.Remove {
+			delete(attributes, key)
+		}
+		if len(attributes) == 0 {
+			attributes = nil
+		}
+		if err := validateAttributes(attributes); err != nil {
+			return nil, err
+		}
+		v.files[op.Path] = &txFile{attributes: attributes}
+		return []wal.Entry{wal.NewEntry(wal.OpSetFileAttributes, wal.SetFileAttributesData{
+			Path:       op.Path,
+			Namespace:  v.namespace,
+			Attributes: attributes,
+		})}, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Type)
+}
+
+// remove takes a file out of the view and returns the entry that deletes it,
+// moving it to the trash while trash retention is enabled
+func (v *txView) remove(path string) wal.Entry {
+	v.files[path] = nil
+	v.added--
+	if v.m.trashRetention <= 0 {
+		return wal.NewEntry(wal.OpDeleteFile, wal.DeleteFileData{Path: path, Namespace: v.namespace})
+	}
+	// Each removal gets its own time, so a path removed twice gets two trash entries
+	v.deletedAt = v.deletedAt.Add(time.Nanosecond)
+	return wal.NewEntry(wal.OpTrashFile, wal.TrashFileData{Path: path, Namespace: v.namespace, DeletedAt: v.deletedAt.UnixNano()})
+}
+
+// AddChunkToFileIfSize adds a new chunk to a file only if the file is exactly size
+// bytes and doesn't already end in an empty chunk, so of several conditional appends
+// racing to extend the file only one gets the chunk
+func (m *Master) AddChunkToFileIfSize(path, namespace string, size uint64) (*ChunkInfo, error) {
+	if err := checkUserNamespace(namespace); err != nil {
+		return nil, err
+	}
+	namespace = normalizeNamespace(namespace)
+
+	// Select replicas BEFORE taking fileMu to avoid lock contention
+	handle := m.generateChunkHandle()
+	replicas := m.selectReplicas(m.replicationFactor)
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no chunkservers available")
+	}
+
+	// The check, the WAL write and the apply all happen under fileMu
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	file, exists := m.files[makeFileKey(namespace, path)]
+	if !exists {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if file.Size != size {
+		return nil, fmt.Errorf("%w: %s is %d bytes, expected %d", ErrPreconditionFailed, path, file.Size, size)
+	}
+	if n := len(file.Chunks); n > 0 {
+		m.chunkMu.RLock()
+		last, ok := m.chunks[file.Chunks[n-1]]
+		empty := ok && last.Size == 0
+		m.chunkMu.RUnlock()
+		if empty {
+			return nil, fmt.Errorf("%w: %s already ends in an empty chunk", ErrPreconditionFailed, path)
+		}
+	}
+	if err := m.checkQuotaLocked(namespace, 0, 0); err != nil {
+		return nil, err
+	}
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogAddChunk(path, namespace, string(handle)); err != nil {
+		return nil, fmt.Errorf("WAL write failed: %w", err)
+	}
+	chunkInfo := m.attachChunkLocked(file, handle, replicas)
+
+	slog.Debug("added chunk to file", "path", path, "namespace", namespace, "chunk", handle, "size", size)
+	return chunkInfo, nil
+}
@@ -0,0 +1,229 @@
+package master
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+// fileState returns each file in a namespace with its attributes
+func fileState(m *Master, namespace string) map[string]map[string]string {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+	state := make(map[string]map[string]string)
+	for _, file := range m.files {
+		if file.Namespace == namespace {
+			state[file.Path] = maps.Clone(file.Attributes)
+		}
+	}
+	return state
+}
+
+func sameState(a, b map[string]map[string]string) bool {
+	return maps.EqualFunc(a, b, func(x, y map[string]string) bool { return maps.Equal(x, y) })
+}
+
+func TestTransactPlanning(t *testing.T) {
+	attrs := func(kv ...string) map[string]string {
+		m := make(map[string]string)
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+
+	tests := []struct {
+		name       string
+		conditions []TxCondition
+		ops        []TxOp
+		wantErr    error // nil for success; errPlain for an error that isn't a precondition
+		want       map[string]map[string]string
+	}{
+		{
+			name: "create then delete the same path",
+			ops:  []TxOp{{Type: TxCreate, Path: "/new"}, {Type: TxDelete, Path: "/new"}},
+			want: map[string]map[string]string{"/a": attrs("k", "a"), "/b": attrs("k", "b")},
+		},
+		{
+			name: "delete then create the same path",
+			ops:  []TxOp{{Type: TxDelete, Path: "/a"}, {Type: TxCreate, Path: "/a", Attributes: attrs("k", "new")}},
+			want: map[string]map[string]string{"/a": attrs("k", "new"), "/b": attrs("k", "b")},
+		},
+		{
+			name: "rename chain",
+			ops: []TxOp{
+				{Type: TxRename, Path: "/a", NewPath: "/c"},
+				{Type: TxRename, Path: "/c", NewPath: "/d"},
+				{Type: TxSetAttributes, Path: "/d", Attributes: attrs("x", "1")},
+			},
+			want: map[string]map[string]string{"/b": attrs("k", "b"), "/d": attrs("k", "a", "x", "1")},
+		},
+		{
+			name: "swap through a temporary path",
+			ops: []TxOp{
+				{Type: TxRename, Path: "/a", NewPath: "/tmp"},
+				{Type: TxRename, Path: "/b", NewPath: "/a"},
+				{Type: TxRename, Path: "/tmp", NewPath: "/b"},
+			},
+			want: map[string]map[string]string{"/a": attrs("k", "b"), "/b": attrs("k", "a")},
+		},
+		{
+			name: "rename replacing the destination",
+			ops:  []TxOp{{Type: TxRename, Path: "/a", NewPath: "/b", Replace: true}},
+			want: map[string]map[string]string{"/b": attrs("k", "a")},
+		},
+		{
+			name: "create over a path an earlier op renamed away",
+			ops:  []TxOp{{Type: TxRename, Path: "/a", NewPath: "/c"}, {Type: TxCreate, Path: "/a"}},
+			want: map[string]map[string]string{"/a": nil, "/b": attrs("k", "b"), "/c": attrs("k", "a")},
+		},
+		{
+			name:    "create twice",
+			ops:     []TxOp{{Type: TxCreate, Path: "/new"}, {Type: TxCreate, Path: "/new"}},
+			wantErr: ErrFileExists,
+		},
+		{
+			name:    "rename onto an existing file",
+			ops:     []TxOp{{Type: TxCreate, Path: "/new"}, {Type: TxRename, Path: "/a", NewPath: "/b"}},
+			wantErr: ErrFileExists,
+		},
+		{
+			name: "rename from a path an earlier op renamed away",
+			ops: []TxOp{
+				{Type: TxRename, Path: "/a", NewPath: "/c"},
+				{Type: TxRename, Path: "/a", NewPath: "/d"},
+			},
+			wantErr: errPlain,
+		},
+		{
+			name:    "delete of a path created and deleted earlier",
+			ops:     []TxOp{{Type: TxCreate, Path: "/new"}, {Type: TxDelete, Path: "/new"}, {Type: TxDelete, Path: "/new"}},
+			wantErr: errPlain,
+		},
+		{
+			name:    "invalid last op",
+			ops:     []TxOp{{Type: TxDelete, Path: "/a"}, {Type: TxSetAttributes, Path: "/b", Attributes: attrs("", "v")}},
+			wantErr: errPlain,
+		},
+		{
+			name:       "failed condition",
+			conditions: []TxCondition{{Path: "/a", CheckSize: true, Size: 1}},
+			ops:        []TxOp{{Type: TxDelete, Path: "/a"}},
+			wantErr:    ErrPreconditionFailed,
+		},
+		{
+			name:       "absent condition on an existing file",
+			conditions: []TxCondition{{Path: "/b", Absent: true}},
+			ops:        []TxOp{{Type: TxCreate, Path: "/new"}},
+			wantErr:    ErrFileExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			m, err := NewMaster(path)
+			if err != nil {
+				t.Fatalf("NewMaster: %v", err)
+			}
+			m.SetTrashRetention(0)
+			if _, err := m.CreateFile("/a", "ns", "", attrs("k", "a")); err != nil {
+				t.Fatalf("CreateFile: %v", err)
+			}
+			if _, err := m.CreateFile("/b", "ns", "", attrs("k", "b")); err != nil {
+				t.Fatalf("CreateFile: %v", err)
+			}
+			before := fileState(m, "ns")
+
+			err = m.Transact("ns", tt.conditions, tt.ops)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Transact: %v", err)
+			case tt.wantErr == errPlain && (err == nil || errors.Is(err, ErrPreconditionFailed)):
+				t.Fatalf("Transact err = %v, want a plain error", err)
+			case tt.wantErr != nil && tt.wantErr != errPlain && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Transact err = %v, want %v", err, tt.wantErr)
+			}
+
+			// A failed transaction changes nothing
+			want := tt.want
+			if tt.wantErr != nil {
+				want = before
+			}
+			if got := fileState(m, "ns"); !sameState(got, want) {
+				t.Errorf("files = %v, want %v", got, want)
+			}
+
+			// Replaying the WAL gives the same files: the transaction was logged whole or not at all
+			m.Close()
+			m, err = NewMaster(path)
+			if err != nil {
+				t.Fatalf("NewMaster after restart: %v", err)
+			}
+			defer m.Close()
+			if got := fileState(m, "ns"); !sameState(got, want) {
+				t.Errorf("files after replay = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// errPlain stands for an error that isn't a failed precondition
+var errPlain = errors.New("plain error")
+
+// Deletes and replaced renames move files to the trash under distinct names
+func TestTransactTrash(t *testing.T) {
+	m := newTestMaster(t)
+	createTestFiles(t, m, "ns", "/a", "/b")
+
+	err := m.Transact("ns", nil, []TxOp{
+		{Type: TxDelete, Path: "/a"},
+		{Type: TxCreate, Path: "/a"},
+		{Type: TxRename, Path: "/a", NewPath: "/b", Replace: true},
+	})
+	if err != nil {
+		t.Fatalf("Transact: %v", err)
+	}
+	if got := fileState(m, "ns"); !sameState(got, map[string]map[string]string{"/b": nil}) {
+		t.Errorf("files = %v, want [/b]", got)
+	}
+	if got := len(fileState(m, TrashNamespace)); got != 2 {
+		t.Errorf("%d files in the trash, want 2", got)
+	}
+}
+
+func TestAddChunkToFileIfSize(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		addTestServer(m, id, "")
+	}
+	createTestFiles(t, m, "ns", "/f")
+
+	chunks := func() int {
+		m.fileMu.RLock()
+		defer m.fileMu.RUnlock()
+		return len(m.files[makeFileKey("ns", "/f")].Chunks)
+	}
+
+	if _, err := m.AddChunkToFileIfSize("/f", "ns", 10); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("wrong size: err = %v, want ErrPreconditionFailed", err)
+	}
+	if chunks() != 0 {
+		t.Fatalf("failed precondition added a chunk")
+	}
+
+	if _, err := m.AddChunkToFileIfSize("/f", "ns", 0); err != nil {
+		t.Fatalf("AddChunkToFileIfSize: %v", err)
+	}
+	// The racing appender that saw the same size loses: the file already ends in an empty chunk
+	if _, err := m.AddChunkToFileIfSize("/f", "ns", 0); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("second add at the same size: err = %v, want ErrPreconditionFailed", err)
+	}
+	if chunks() != 1 {
+		t.Errorf("file has %d chunks, want 1", chunks())
+	}
+
+	if _, err := m.AddChunkToFileIfSize("/missing", "ns", 0); err == nil || errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("missing file: err = %v, want a not found error", err)
+	}
+}
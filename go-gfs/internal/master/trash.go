@@ -0,0 +1,376 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// TrashNamespace is the hidden namespace deleted files are kept in until their retention expires
+const TrashNamespace = ".trash"
+
+// DefaultTrashRetention is how long deleted files can be restored, as in the GFS paper
+const DefaultTrashRetention = 72 * time.Hour
+
+// ErrReservedNamespace is returned for changes that name the trash namespace directly
+var ErrReservedNamespace = errors.New("namespace is reserved for the trash")
+
+// ErrTrashEntryNotFound is returned for a trash ID that is not in the trash
+var ErrTrashEntryNotFound = errors.New("trash entry not found")
+
+// TrashInfo records where a file in the trash was deleted from
+type TrashInfo struct {
+	Namespace string
+	Path      string
+	DeletedAt time.Time
+}
+
+// TrashEntry is a file in the trash
+type TrashEntry struct {
+	ID        string // Path of the file in the trash namespace
+	Namespace string // Namespace the file was deleted from
+	Path      string // Path the file was deleted from
+	Size      uint64
+	DeletedAt time.Time
+	ExpiresAt time.Time // When the collector purges it; zero while retention is disabled
+}
+
+// SetTrashRetention sets how long deleted files stay in the trash. Zero disables
+// the trash, so deletes release chunks right away; files already in the trash
+// are kept until purged.
+func (m *Master) SetTrashRetention(retention time.Duration) {
+	m.trashRetention = retention
+}
+
+// checkUserNamespace rejects changes made directly in the trash namespace
+func checkUserNamespace(namespace string) error {
+	if namespace == TrashNamespace {
+		return fmt.Errorf("%w: %s", ErrReservedNamespace, namespace)
+	}
+	return nil
+}
+
+// trashID names a deleted file in the trash. It is derived from the delete so
+// every replica replaying the WAL picks the same name.
+func trashID(namespace, path string, deletedAt time.Time) string {
+	h := fnv.New64a()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	return fmt.Sprintf("%d-%016x", deletedAt.UnixNano(), h.Sum64())
+}
+
+// trashFile moves a file to the trash
+func (m *Master) trashFile(path, namespace string) error {
+	namespace = normalizeNamespace(namespace)
+
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	file, exists := m.files[makeFileKey(namespace, path)]
+	if !exists {
+		return fmt.Errorf("file not found: %s", path)
+	}
+
+	// Log to WAL before applying
+	deletedAt := time.Now()
+	seq, err := m.wal.LogTrashFile(path, namespace, deletedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+
+	m.chunkMu.Lock()
+	m.moveToTrashLocked(file, deletedAt)
+	m.chunkMu.Unlock()
+
+	slog.Info("moved file to trash", "path", path, "namespace", namespace, "trashID", file.Path, "chunks", len(file.Chunks))
+	return nil
+}
+
+// trashNamespace moves every file in a namespace to the trash
+func (m *Master) trashNamespace(namespace string) (int, error) {
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	var files []*FileInfo
+	for _, file := range m.files {
+		if file.Namespace == namespace {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+
+	// Log to WAL before applying
+	deletedAt := time.Now()
+	seq, err := m.wal.LogTrashNamespace(namespace, deletedAt.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+
+	m.chunkMu.Lock()
+	for _, file := range files {
+		m.moveToTrashLocked(file, deletedAt)
+	}
+	m.chunkMu.Unlock()
+
+	slog.Info("moved namespace to trash", "namespace", namespace, "files", len(files))
+	return len(files), nil
+}
+
+// replayTrashFile moves a file to the trash from WAL (no WAL logging)
+func (m *Master) replayTrashFile(path, namespace string, deletedAt time.Time) {
+	if file, exists := m.files[makeFileKey(normalizeNamespace(namespace), path)]; exists {
+		m.moveToTrashLocked(file, deletedAt)
+	}
+}
+
+// replayTrashNamespace moves every file in a namespace to the trash from WAL (no WAL logging)
+func (m *Master) replayTrashNamespace(namespace string, deletedAt time.Time) {
+	namespace = normalizeNamespace(namespace)
+	var files []*FileInfo
+	for _, file := range m.files {
+		if file.Namespace == namespace {
+			files = append(files, file)
+		}
+	}
+	for _, file := range files {
+		m.moveToTrashLocked(file, deletedAt)
+	}
+}
+
+// moveToTrashLocked takes a file out of its namespace and files it in the trash,
+// keeping its chunks. Must be called with fileMu and chunkMu held
+func (m *Master) moveToTrashLocked(file *FileInfo, deletedAt time.Time) {
+	id := trashID(file.Namespace, file.Path, deletedAt)
+	trash := &TrashInfo{Namespace: file.Namespace, Path: file.Path, DeletedAt: deletedAt}
+	m.relocateFileLocked(file, id, TrashNamespace)
+	file.Trash = trash
+}
+
+// relocateFileLocked moves a file to a new path and namespace, moving its usage
+// and the back-references of the chunks it owns along with it.
+// Must be called with fileMu and chunkMu held
+func (m *Master) relocateFileLocked(file *FileInfo, path, namespace string) {
+	delete(m.files, makeFileKey(file.Namespace, file.Path))
+	m.untrackFileLocked(file)
+
+	for _, handle := range file.Chunks {
+		if chunk, ok := m.chunks[handle]; ok && chunk.FilePath == file.Path && chunk.Namespace == file.Namespace {
+			chunk.FilePath = path
+			chunk.Namespace = namespace
+		}
+	}
+
+	file.Path = path
+	file.Namespace = namespace
+	m.files[makeFileKey(namespace, path)] = file
+	m.trackFileLocked(file)
+}
+
+// ListTrash returns the files in the trash deleted from a namespace, or from
+// every namespace when namespace is empty, most recently deleted first
+func (m *Master) ListTrash(namespace string) []TrashEntry {
+	m.fileMu.RLock()
+	defer m.fileMu.RUnlock()
+
+	var entries []TrashEntry
+	for _, file := range m.files {
+		if file.Trash == nil || (namespace != "" && file.Trash.Namespace != namespace) {
+			continue
+		}
+		entries = append(entries, m.trashEntryLocked(file))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.After(entries[j].DeletedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// trashEntryLocked must be called with fileMu held
+func (m *Master) trashEntryLocked(file *FileInfo) TrashEntry {
+	entry := TrashEntry{
+		ID:        file.Path,
+		Namespace: file.Trash.Namespace,
+		Path:      file.Trash.Path,
+		Size:      file.Size,
+		DeletedAt: file.Trash.DeletedAt,
+	}
+	if m.trashRetention > 0 {
+		entry.ExpiresAt = file.Trash.DeletedAt.Add(m.trashRetention)
+	}
+	return entry
+}
+
+// RestoreFile moves a file out of the trash. An empty path or namespace restores
+// it where it was deleted from; the destination must not already exist.
+func (m *Master) RestoreFile(id, path, namespace string) (*FileInfo, error) {
+	m.fileMu.Lock()
+	defer m.fileMu.Unlock()
+
+	file, exists := m.files[makeFileKey(TrashNamespace, id)]
+	if !exists || file.Trash == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTrashEntryNotFound, id)
+	}
+	if path == "" {
+		path = file.Trash.Path
+	}
+	if namespace == "" {
+		namespace = file.Trash.Namespace
+	}
+	namespace = normalizeNamespace(namespace)
+	if err := checkUserNamespace(namespace); err != nil {
+		return nil, err
+	}
+	if _, exists := m.files[makeFileKey(namespace, path)]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrFileExists, path)
+	}
+	if err := m.checkQuotaLocked(namespace, 1, file.Size); err != nil {
+		return nil, err
+	}
+
+	// Log to WAL before applying
+	seq, err := m.wal.LogRestoreFile(id, path, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("WAL write failed: %w", err)
+	}
+	defer m.watch.applied(seq)
+
+	m.chunkMu.Lock()
+	m.restoreFileLocked(file, path, namespace)
+	m.chunkMu.Unlock()
+
+	slog.Info("restored file from trash", "trashID", id, "path", path, "namespace", namespace)
+	return file, nil
+}
+
+// replayRestoreFile moves a file out of the trash from WAL (no WAL logging)
+func (m *Master) replayRestoreFile(id, path, namespace string) {
+	file, exists := m.files[makeFileKey(TrashNamespace, id)]
+	if !exists || file.Trash == nil {
+		return
+	}
+	m.restoreFileLocked(file, path, normalizeNamespace(namespace))
+}
+
+// restoreFileLocked must be called with fileMu and chunkMu held
+func (m *Master) restoreFileLocked(file *FileInfo, path, namespace string) {
+	file.Trash = nil
+	m.relocateFileLocked(file, path, namespace)
+}
+
+// PurgeTrash permanently deletes files from the trash: the given IDs, or every
+// file deleted from namespace when ids is empty, or the whole trash when both
+// are empty. Returns how many files were purged.
+func (m *Master) PurgeTrash(ids []string, namespace string) (int, error) {
+	if len(ids) == 0 {
+		for _, entry := range m.ListTrash(namespace) {
+			ids = append(ids, entry.ID)
+		}
+	}
+	return m.purgeTrash(ids)
+}
+
+// purgeTrash deletes trash entries and schedules their chunks for deletion
+func (m *Master) purgeTrash(ids []string) (int, error) {
+	m.fileMu.Lock()
+
+	// Entries purged or restored meanwhile are skipped
+	var present []string
+	for _, id := range ids {
+		if file, ok := m.files[makeFileKey(TrashNamespace, id)]; ok && file.Trash != nil {
+			present = append(present, id)
+		}
+	}
+	if len(present) == 0 {
+		m.fileMu.Unlock()
+		return 0, nil
+	}
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogPurgeTrash(present); err != nil {
+		m.fileMu.Unlock()
+		return 0, fmt.Errorf("WAL write failed: %w", err)
+	}
+
+	m.chunkMu.Lock()
+	released := m.purgeTrashLocked(present)
+	m.chunkMu.Unlock()
+	m.fileMu.Unlock()
+
+	m.scheduleChunkDeletes(released)
+
+	slog.Info("purged trash", "files", len(present), "releasedChunks", len(released))
+	return len(present), nil
+}
+
+// replayPurgeTrash deletes trash entries from WAL (no WAL logging)
+func (m *Master) replayPurgeTrash(ids []string) {
+	m.purgeTrashLocked(ids)
+}
+
+// purgeTrashLocked removes trash entries and returns the chunks no file uses any more.
+// Must be called with fileMu and chunkMu held
+func (m *Master) purgeTrashLocked(ids []string) []*ChunkInfo {
+	var handles []ChunkHandle
+	for _, id := range ids {
+		key := makeFileKey(TrashNamespace, id)
+		file, ok := m.files[key]
+		if !ok || file.Trash == nil {
+			continue
+		}
+		delete(m.files, key)
+		m.untrackFileLocked(file)
+		handles = append(handles, file.Chunks...)
+	}
+	return m.releaseChunksLocked(handles)
+}
+
+// StartTrashCollector periodically purges files whose trash retention has expired
+func (m *Master) StartTrashCollector(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				slog.Info("stopping trash collector")
+				return
+			case <-ticker.C:
+				m.collectTrash()
+			}
+		}
+	}()
+	slog.Info("started trash collector", "interval", interval, "retention", m.trashRetention)
+}
+
+// collectTrash purges expired trash entries; standby masters leave it to the leader
+func (m *Master) collectTrash() {
+	if m.trashRetention <= 0 || !m.IsLeader() {
+		return
+	}
+
+	now := time.Now()
+	var expired []string
+	for _, entry := range m.ListTrash("") {
+		if now.After(entry.ExpiresAt) {
+			expired = append(expired, entry.ID)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	if _, err := m.purgeTrash(expired); err != nil {
+		slog.Error("failed to purge expired trash", "files", len(expired), "error", err)
+	}
+}
@@ -0,0 +1,199 @@
+package master
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// trashEntry returns the single trash entry of a file deleted from namespace
+func trashEntry(t *testing.T, m *Master, namespace, path string) TrashEntry {
+	t.Helper()
+	for _, entry := range m.ListTrash(namespace) {
+		if entry.Path == path {
+			return entry
+		}
+	}
+	t.Fatalf("%s is not in the trash", path)
+	return TrashEntry{}
+}
+
+// backdateTrash moves a trash entry's delete time into the past
+func backdateTrash(m *Master, id string, age time.Duration) {
+	m.fileMu.Lock()
+	m.files[makeFileKey(TrashNamespace, id)].Trash.DeletedAt = time.Now().Add(-age)
+	m.fileMu.Unlock()
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	m := newTestMaster(t)
+	addTestServer(m, "cs1", "")
+	writeTestChunks(t, m, "ns", "/a", 10, 20)
+	chunks := fileChunks(t, m, "ns", "/a")
+
+	if err := m.DeleteFile("/a", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if _, err := m.GetFile("/a", "ns"); err == nil {
+		t.Error("deleted file is still in its namespace")
+	}
+	entry := trashEntry(t, m, "ns", "/a")
+	if entry.Namespace != "ns" || entry.Size != 30 || !entry.ExpiresAt.Equal(entry.DeletedAt.Add(DefaultTrashRetention)) {
+		t.Errorf("trash entry = %+v", entry)
+	}
+	if got := m.ListTrash("other"); len(got) != 0 {
+		t.Errorf("trash of another namespace = %+v, want none", got)
+	}
+	// The chunks are kept until the file is purged
+	if deletes := m.GetPendingDeletes("cs1"); len(deletes) != 0 {
+		t.Errorf("chunks of a trashed file scheduled for deletion: %v", deletes)
+	}
+
+	// Restoring puts the same file back where it was
+	if _, err := m.RestoreFile(entry.ID, "", ""); err != nil {
+		t.Fatalf("RestoreFile: %v", err)
+	}
+	if got := fileChunks(t, m, "ns", "/a"); !slices.Equal(got, chunks) {
+		t.Errorf("restored chunks = %v, want %v", got, chunks)
+	}
+	if _, err := m.RestoreFile(entry.ID, "", ""); !errors.Is(err, ErrTrashEntryNotFound) {
+		t.Errorf("restoring twice: err = %v, want ErrTrashEntryNotFound", err)
+	}
+
+	// Once the path is taken again, the file has to go somewhere else
+	if err := m.DeleteFile("/a", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	entry = trashEntry(t, m, "ns", "/a")
+	createTestFiles(t, m, "ns", "/a")
+	if _, err := m.RestoreFile(entry.ID, "", ""); !errors.Is(err, ErrFileExists) {
+		t.Errorf("restore over an existing file: err = %v, want ErrFileExists", err)
+	}
+	if _, err := m.RestoreFile(entry.ID, "", TrashNamespace); !errors.Is(err, ErrReservedNamespace) {
+		t.Errorf("restore into the trash: err = %v, want ErrReservedNamespace", err)
+	}
+	if _, err := m.RestoreFile(entry.ID, "/a.restored", ""); err != nil {
+		t.Fatalf("RestoreFile to a new path: %v", err)
+	}
+	if got := fileChunks(t, m, "ns", "/a.restored"); !slices.Equal(got, chunks) {
+		t.Errorf("restored chunks = %v, want %v", got, chunks)
+	}
+	if got := fileChunks(t, m, "ns", "/a"); len(got) != 0 {
+		t.Errorf("new file at the old path has chunks %v", got)
+	}
+
+	// Deleting a namespace trashes every file in it
+	if n, err := m.DeleteNamespace("ns"); err != nil || n != 2 {
+		t.Fatalf("DeleteNamespace = %d, %v; want 2", n, err)
+	}
+	if got := m.ListTrash("ns"); len(got) != 2 {
+		t.Errorf("trash after deleting the namespace = %+v, want 2 files", got)
+	}
+	if _, err := m.DeleteNamespace(TrashNamespace); !errors.Is(err, ErrReservedNamespace) {
+		t.Errorf("deleting the trash: err = %v, want ErrReservedNamespace", err)
+	}
+}
+
+func TestTrashRetention(t *testing.T) {
+	m := newTestMaster(t)
+	m.SetTrashRetention(time.Hour)
+	addTestServer(m, "cs1", "")
+	writeTestChunks(t, m, "ns", "/old", 10)
+	writeTestChunks(t, m, "ns", "/new", 10)
+	old := fileChunks(t, m, "ns", "/old")
+	for _, path := range []string{"/old", "/new"} {
+		if err := m.DeleteFile(path, "ns"); err != nil {
+			t.Fatalf("DeleteFile: %v", err)
+		}
+	}
+	backdateTrash(m, trashEntry(t, m, "ns", "/old").ID, 2*time.Hour)
+
+	m.collectTrash()
+	if got := m.ListTrash("ns"); len(got) != 1 || got[0].Path != "/new" {
+		t.Errorf("trash after collection = %+v, want /new only", got)
+	}
+	if deletes := m.GetPendingDeletes("cs1"); !slices.Equal(deletes, old) {
+		t.Errorf("deletes = %v, want the expired file's chunks %v", deletes, old)
+	}
+	if chunkRefs(m, old[0]) != 0 {
+		t.Error("expired file's chunk still registered")
+	}
+
+	// Purging by hand doesn't wait for the retention
+	if n, err := m.PurgeTrash(nil, "ns"); err != nil || n != 1 {
+		t.Errorf("PurgeTrash = %d, %v; want 1", n, err)
+	}
+	if n, err := m.PurgeTrash([]string{"missing"}, ""); err != nil || n != 0 {
+		t.Errorf("PurgeTrash of a missing entry = %d, %v; want 0", n, err)
+	}
+
+	// Without retention, deletes skip the trash
+	m.SetTrashRetention(0)
+	writeTestChunks(t, m, "ns", "/now", 10)
+	now := fileChunks(t, m, "ns", "/now")
+	if err := m.DeleteFile("/now", "ns"); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if got := m.ListTrash(""); len(got) != 0 {
+		t.Errorf("trash with retention disabled = %+v", got)
+	}
+	if deletes := m.GetPendingDeletes("cs1"); !slices.Contains(deletes, now[0]) {
+		t.Errorf("deletes = %v, want %s", deletes, now[0])
+	}
+}
+
+// The trash namespace's usage follows files in and out of it, through a restart
+func TestTrashUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	m, err := NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	addTestServer(m, "cs1", "")
+	writeTestChunks(t, m, "a", "/f", 10, 20)
+	writeTestChunks(t, m, "a", "/g", 5)
+	writeTestChunks(t, m, "b", "/h", 7)
+
+	steps := []struct {
+		name       string
+		do         func() error
+		trashBytes uint64
+		trashFiles uint64
+	}{
+		{"delete file", func() error { return m.DeleteFile("/f", "a") }, 30, 1},
+		{"delete namespace", func() error { _, err := m.DeleteNamespace("b"); return err }, 37, 2},
+		{"restore", func() error {
+			_, err := m.RestoreFile(trashEntry(t, m, "b", "/h").ID, "/h", "a")
+			return err
+		}, 30, 1},
+		{"delete another", func() error { return m.DeleteFile("/g", "a") }, 35, 2},
+		{"purge", func() error { _, err := m.PurgeTrash([]string{trashEntry(t, m, "a", "/f").ID}, ""); return err }, 5, 1},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if bytes, files := usageOf(m, TrashNamespace); bytes != step.trashBytes || files != step.trashFiles {
+			t.Errorf("%s: trash usage = %d bytes, %d files; want %d, %d", step.name, bytes, files, step.trashBytes, step.trashFiles)
+		}
+	}
+	if bytes, files := usageOf(m, "a"); bytes != 7 || files != 1 {
+		t.Errorf("usage of a = %d bytes, %d files; want 7, 1", bytes, files)
+	}
+	trash := m.ListTrash("")
+	m.Close()
+
+	m, err = NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster after restart: %v", err)
+	}
+	defer m.Close()
+	if bytes, files := usageOf(m, TrashNamespace); bytes != 5 || files != 1 {
+		t.Errorf("trash usage after replay = %d bytes, %d files; want 5, 1", bytes, files)
+	}
+	if got := m.ListTrash(""); len(got) != len(trash) || got[0].ID != trash[0].ID || !got[0].DeletedAt.Equal(trash[0].DeletedAt) {
+		t.Errorf("trash after replay = %+v, want %+v", got, trash)
+	}
+}
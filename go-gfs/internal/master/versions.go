@@ -0,0 +1,89 @@
+package master
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// VersionReportGrace is how long after a commit raises a chunk's version that
+// older reported versions are still accepted; a chunk report gathered before
+// the commit can arrive just after it
+const VersionReportGrace = 10 * time.Second
+
+// bumpVersionLocked advances a chunk's version for a new lease and logs it.
+// The new primary stamps the version on the write, so replicas that miss it keep
+// an older version and are found stale once the write commits.
+// Must be called with chunkMu held
+func (m *Master) bumpVersionLocked(chunk *ChunkInfo) error {
+	version := chunk.Version + 1
+
+	// Log to WAL before applying
+	if _, err := m.wal.LogSetChunkVersion(string(chunk.Handle), version); err != nil {
+		return fmt.Errorf("WAL write failed: %w", err)
+	}
+	chunk.Version = version
+	return nil
+}
+
+// replaySetChunkVersion raises a chunk's lease version from WAL (no WAL logging)
+func (m *Master) replaySetChunkVersion(chunkHandle string, version uint64) {
+	if chunk, exists := m.chunks[ChunkHandle(chunkHandle)]; exists {
+		chunk.Version = max(chunk.Version, version)
+	}
+}
+
+// noteCommittedVersionLocked records that a write stamped with version committed
+// Must be called with chunkMu held
+func (m *Master) noteCommittedVersionLocked(chunk *ChunkInfo, version uint64) {
+	if version > chunk.CommittedVersion {
+		chunk.CommittedVersion = version
+		chunk.committedAt = time.Now()
+	}
+	chunk.Version = max(chunk.Version, version)
+}
+
+// isStaleReplicaLocked reports whether a replica at version missed committed writes.
+// A version above any the master knows of means a commit report was lost; it is adopted.
+// Replicas that report no version were written before versions existed and are trusted.
+// Must be called with chunkMu held
+func (m *Master) isStaleReplicaLocked(chunk *ChunkInfo, serverID ChunkServerID, version uint64) bool {
+	if version == 0 || version == chunk.CommittedVersion {
+		return false
+	}
+	if version > chunk.CommittedVersion {
+		slog.Info("adopting newer reported chunk version",
+			"chunk", chunk.Handle, "serverID", serverID, "version", version, "committedVersion", chunk.CommittedVersion)
+		m.noteCommittedVersionLocked(chunk, version)
+		return false
+	}
+	return time.Since(chunk.committedAt) >= VersionReportGrace
+}
+
+// dropStaleReplicaLocked stops serving a stale replica and schedules it for deletion;
+// re-replication restores the replica count from an up-to-date copy.
+// Must be called with chunkMu held
+func (m *Master) dropStaleReplicaLocked(chunk *ChunkInfo, serverID ChunkServerID, version uint64) {
+	kept := make([]ChunkLocation, 0, len(chunk.Locations))
+	for _, loc := range chunk.Locations {
+		if loc.ServerID != serverID {
+			kept = append(kept, loc)
+		}
+	}
+	chunk.Locations = kept
+	if chunk.Primary != nil && chunk.Primary.ServerID == serverID {
+		chunk.Primary = nil
+		m.reassignPrimaryLocked(chunk)
+	}
+
+	m.pendingDeletesMu.Lock()
+	m.pendingDeletes[serverID] = append(m.pendingDeletes[serverID], chunk.Handle)
+	m.pendingDeletesMu.Unlock()
+
+	slog.Warn("dropped stale replica",
+		"chunk", chunk.Handle,
+		"serverID", serverID,
+		"version", version,
+		"committedVersion", chunk.CommittedVersion,
+		"remaining", len(chunk.Locations))
+}
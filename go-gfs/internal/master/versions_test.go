@@ -0,0 +1,156 @@
+package master
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// expireLease ends a chunk's lease as if LeaseDuration had passed
+func expireLease(m *Master, handle ChunkHandle) {
+	m.chunkMu.Lock()
+	m.chunks[handle].LeaseExpiration = time.Now().Add(-time.Second)
+	m.chunkMu.Unlock()
+}
+
+func TestLeaseBumpsVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	m, err := NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		addTestServer(m, id, "")
+	}
+	createTestFiles(t, m, "ns", "/f")
+	chunk, err := m.AddChunkToFile("/f", "ns")
+	if err != nil {
+		t.Fatalf("AddChunkToFile: %v", err)
+	}
+	handle := chunk.Handle
+	primary := chunk.Locations[0].ServerID
+	other := chunk.Locations[1].ServerID
+	// Allocation grants the first lease
+	expireLease(m, handle)
+
+	steps := []struct {
+		name   string
+		server ChunkServerID
+		expire bool // End the lease first
+		want   uint64
+		ok     bool
+	}{
+		{name: "new lease", server: primary, want: 2, ok: true},
+		{name: "extend own lease", server: primary, want: 2, ok: true},
+		{name: "another server during the lease", server: other, ok: false},
+		{name: "another server after the lease", server: other, expire: true, want: 3, ok: true},
+		{name: "same server after its lease", server: other, expire: true, want: 4, ok: true},
+		{name: "server without the chunk", server: "cs-missing", expire: true, ok: false},
+	}
+	for _, step := range steps {
+		if step.expire {
+			expireLease(m, handle)
+		}
+		version, ok := m.ClaimPrimary(handle, step.server)
+		if ok != step.ok || (ok && version != step.want) {
+			t.Errorf("%s: ClaimPrimary = %d, %v; want %d, %v", step.name, version, ok, step.want, step.ok)
+		}
+	}
+
+	// Lease versions are logged, so a restarted master never hands one out twice
+	m.Close()
+	m, err = NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster after restart: %v", err)
+	}
+	defer m.Close()
+	info, err := m.GetChunkInfo(handle)
+	if err != nil {
+		t.Fatalf("GetChunkInfo: %v", err)
+	}
+	if info.Version != 4 {
+		t.Errorf("version after replay = %d, want 4", info.Version)
+	}
+}
+
+func TestStaleReplica(t *testing.T) {
+	tests := []struct {
+		name          string
+		reported      uint64
+		recent        bool // The commit raising the version just landed
+		wantKept      bool
+		wantCommitted uint64
+	}{
+		{name: "current version", reported: 3, wantKept: true, wantCommitted: 3},
+		{name: "older version", reported: 2, wantKept: false, wantCommitted: 3},
+		{name: "older version within the grace period", reported: 2, recent: true, wantKept: true, wantCommitted: 3},
+		{name: "no version", reported: 0, wantKept: true, wantCommitted: 3},
+		{name: "newer version", reported: 4, wantKept: true, wantCommitted: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMaster(t)
+			for _, id := range []string{"cs1", "cs2", "cs3"} {
+				addTestServer(m, id, "")
+			}
+			chunk := addTestChunk(m, "c1", "cs1", "cs2", "cs3")
+			chunk.Primary = &chunk.Locations[0]
+			m.chunkMu.Lock()
+			m.noteCommittedVersionLocked(chunk, 3)
+			if !tt.recent {
+				chunk.committedAt = time.Now().Add(-VersionReportGrace - time.Second)
+			}
+			m.chunkMu.Unlock()
+
+			m.ReportChunk("cs2", "c1", tt.reported)
+
+			m.chunkMu.RLock()
+			var servers []ChunkServerID
+			for _, loc := range chunk.Locations {
+				servers = append(servers, loc.ServerID)
+			}
+			committed := chunk.CommittedVersion
+			m.chunkMu.RUnlock()
+
+			if kept := slices.Contains(servers, "cs2"); kept != tt.wantKept {
+				t.Errorf("replica kept = %v, want %v (locations %v)", kept, tt.wantKept, servers)
+			}
+			deleting := slices.Contains(m.GetPendingDeletes("cs2"), "c1")
+			if deleting == tt.wantKept {
+				t.Errorf("replica scheduled for deletion = %v, want %v", deleting, !tt.wantKept)
+			}
+			if committed != tt.wantCommitted {
+				t.Errorf("committed version = %d, want %d", committed, tt.wantCommitted)
+			}
+			if len(servers) < 2 {
+				t.Errorf("other replicas dropped: %v", servers)
+			}
+		})
+	}
+}
+
+// A stale primary loses the primary role along with the replica
+func TestStalePrimaryReassigned(t *testing.T) {
+	m := newTestMaster(t)
+	for _, id := range []string{"cs1", "cs2"} {
+		addTestServer(m, id, "")
+	}
+	chunk := addTestChunk(m, "c1", "cs1", "cs2")
+	chunk.Primary = &chunk.Locations[0]
+	m.chunkMu.Lock()
+	m.noteCommittedVersionLocked(chunk, 5)
+	chunk.committedAt = time.Now().Add(-VersionReportGrace - time.Second)
+	m.chunkMu.Unlock()
+
+	m.ReportChunk("cs1", "c1", 4)
+
+	m.chunkMu.RLock()
+	defer m.chunkMu.RUnlock()
+	if chunk.Primary != nil && chunk.Primary.ServerID == "cs1" {
+		t.Error("stale replica is still the primary")
+	}
+	if len(chunk.Locations) != 1 || chunk.Locations[0].ServerID != "cs2" {
+		t.Errorf("locations = %v, want cs2 only", chunk.Locations)
+	}
+}
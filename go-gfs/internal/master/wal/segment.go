@@ -0,0 +1,239 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Segment files hold framed records after an 8-byte magic header:
+//
+//	+-------------+-------------+------------------+
+//	| length (4)  | crc32c (4)  | payload (length) |
+//	+-------------+-------------+------------------+
+//
+// Both header fields are little-endian and the CRC covers the payload. WAL
+// payloads are JSON-encoded entries, the same bytes replicated masters send
+// through the consensus log. Every record is synced before it is acknowledged,
+// so only the last record of the last segment can be torn by a crash.
+
+const (
+	segmentMagic     = "GFSWAL01"
+	recordHeaderSize = 8
+
+	// SegmentSize is the size at which the active segment is sealed and a new one started
+	SegmentSize = 16 << 20
+
+	// maxRecordSize bounds the length field; IMPORT_SNAPSHOT entries carry a whole namespace
+	maxRecordSize = 1 << 30
+)
+
+// ErrCorrupt is returned when a record fails its checksum or framing anywhere but the torn tail
+var ErrCorrupt = errors.New("WAL corrupt")
+
+// errTornTail marks an incomplete final record left by a crash mid-write
+var errTornTail = errors.New("torn WAL tail")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// segmentPath returns the file name of segment seq; segment 0 is the legacy JSON-lines file
+func segmentPath(path string, seq uint64) string {
+	if seq == 0 {
+		return path
+	}
+	return fmt.Sprintf("%s.%08d", path, seq)
+}
+
+// listSegments returns the numbers of the segments on disk in order,
+// including 0 when a legacy JSON-lines WAL is present
+func listSegments(path string) ([]uint64, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WAL segments: %w", err)
+	}
+
+	var segments []uint64
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		segments = append(segments, 0)
+	}
+	for _, match := range matches {
+		suffix := strings.TrimPrefix(match, path+".")
+		seq, err := strconv.ParseUint(suffix, 10, 64)
+		if err != nil || seq == 0 {
+			continue // .snapshot, .tmp and the like
+		}
+		segments = append(segments, seq)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+// appendRecord frames payload and appends it to buf
+func appendRecord(buf, payload []byte) []byte {
+	var header [recordHeaderSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.Checksum(payload, crcTable))
+	buf = append(buf, header[:]...)
+	return append(buf, payload...)
+}
+
+// recordReader reads framed records from one file
+type recordReader struct {
+	file   *os.File
+	r      *bufio.Reader
+	name   string
+	size   int64
+	offset int64 // End of the last good record
+}
+
+// openRecords opens a framed file and checks its magic header.
+// A file too short to hold the header is reported as a torn tail.
+func openRecords(name, magic string) (*recordReader, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	rr := &recordReader{file: file, r: bufio.NewReaderSize(file, 1<<20), name: name, size: info.Size()}
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(rr.r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return rr, errTornTail
+		}
+		file.Close()
+		return nil, err
+	}
+	if string(header) != magic {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s: bad header", ErrCorrupt, name)
+	}
+	rr.offset = int64(len(magic))
+	return rr, nil
+}
+
+// next returns the next record's payload, io.EOF after the last one, errTornTail
+// when the file ends in an incomplete record, or ErrCorrupt
+func (rr *recordReader) next() ([]byte, error) {
+	if rr.offset == rr.size {
+		return nil, io.EOF
+	}
+
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(rr.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTornTail
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", rr.name, err)
+	}
+	length := int64(binary.LittleEndian.Uint32(header[0:4]))
+	sum := binary.LittleEndian.Uint32(header[4:8])
+
+	end := rr.offset + recordHeaderSize + length
+	if end > rr.size {
+		// A record running past the end was still being written
+		return nil, errTornTail
+	}
+	if length > maxRecordSize {
+		return nil, fmt.Errorf("%w: %s: bad record length at offset %d", ErrCorrupt, rr.name, rr.offset)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(rr.r, payload); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rr.name, err)
+	}
+	if length == 0 || crc32.Checksum(payload, crcTable) != sum {
+		// Only the final record (or zero fill after it) may fail its checksum
+		if end == rr.size || (isZero(header[:]) && isZero(payload) && rr.restIsTail(end)) {
+			return nil, errTornTail
+		}
+		return nil, fmt.Errorf("%w: %s: checksum mismatch at offset %d", ErrCorrupt, rr.name, rr.offset)
+	}
+
+	rr.offset = end
+	return payload, nil
+}
+
+// restIsTail reports whether everything in the file from off on is zero fill,
+// which some filesystems leave behind a write that never completed
+func (rr *recordReader) restIsTail(off int64) bool {
+	if off >= rr.size {
+		return true
+	}
+	section := io.NewSectionReader(rr.file, off, rr.size-off)
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := section.Read(buf)
+		if !isZero(buf[:n]) {
+			return false
+		}
+		if err != nil {
+			return errors.Is(err, io.EOF)
+		}
+	}
+}
+
+func (rr *recordReader) close() error {
+	return rr.file.Close()
+}
+
+func isZero(b []byte) bool {
+	return len(bytes.Trim(b, "\x00")) == 0
+}
+
+// recoverSegment truncates a torn tail off the segment so appends continue after
+// the last complete record, and returns the segment's size afterwards
+func recoverSegment(name string) (int64, error) {
+	rr, err := openRecords(name, segmentMagic)
+	if errors.Is(err, errTornTail) {
+		// Crashed while starting the segment; rewrite the header
+		rr.close()
+		if err := os.WriteFile(name, []byte(segmentMagic), 0644); err != nil {
+			return 0, fmt.Errorf("failed to reset WAL segment: %w", err)
+		}
+		return int64(len(segmentMagic)), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer rr.close()
+
+	for {
+		_, err := rr.next()
+		if err == io.EOF {
+			return rr.size, nil
+		}
+		if errors.Is(err, errTornTail) {
+			if err := os.Truncate(name, rr.offset); err != nil {
+				return 0, fmt.Errorf("failed to truncate torn WAL tail: %w", err)
+			}
+			return rr.offset, errTornTail
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// syncDir makes file creations and removals in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
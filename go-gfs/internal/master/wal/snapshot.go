@@ -0,0 +1,261 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Snapshots use the segment record framing under their own magic: a header
+// record, then one record per file and per chunk, written and read as a stream
+// so neither side builds the whole namespace as one document.
+const snapshotMagic = "GFSSNP01"
+
+// Snapshot represents a point-in-time snapshot of master state
+type Snapshot struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Index     uint64          `json:"index,omitempty"`   // Last consensus log index included
+	Segment   uint64          `json:"segment,omitempty"` // First WAL segment not included (standalone masters only)
+	Seq       uint64          `json:"seq,omitempty"`     // Position of the last WAL entry included (standalone masters only)
+	Files     []SnapshotFile  `json:"files"`
+	Chunks    []SnapshotChunk `json:"chunks"`
+	Quotas    []SetQuotaData  `json:"quotas,omitempty"`
+	Draining  []string        `json:"draining,omitempty"` // Chunkserver IDs
+
+	StorageClasses []SetStorageClassData `json:"storage_classes,omitempty"` // Namespace defaults
+	Compression    []SetCompressionData  `json:"compression,omitempty"`     // Compressed namespaces
+}
+
+// SnapshotFile represents a file in the snapshot
+type SnapshotFile struct {
+	Path       string   `json:"path"`
+	Namespace  string   `json:"namespace"`
+	ChunkSize  uint64   `json:"chunk_size"`
+	Chunks     []string `json:"chunks"`
+	Size       uint64   `json:"size"`
+	CreatedAt  int64    `json:"created_at"`
+	ModifiedAt int64    `json:"modified_at"`
+
+	StorageClass string `json:"storage_class,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	Trash *SnapshotTrash `json:"trash,omitempty"` // Set for files in the trash
+}
+
+// SnapshotTrash is where a file in the trash was deleted from
+type SnapshotTrash struct {
+	Namespace string `json:"namespace"`
+	Path      string `json:"path"`
+	DeletedAt int64  `json:"deleted_at"` // Unix nanoseconds
+}
+
+// SnapshotChunk represents a chunk in the snapshot
+type SnapshotChunk struct {
+	Handle    string `json:"handle"`
+	FilePath  string `json:"file_path"`
+	Namespace string `json:"namespace"`
+	Size      uint64 `json:"size"`
+	Version   uint64 `json:"version"`
+	Status    string `json:"status"`
+
+	CommittedVersion uint64 `json:"committed_version,omitempty"`
+
+	Stripe *SetChunkStripeData `json:"stripe,omitempty"` // Erasure coded chunks only
+}
+
+// snapshotHeader is the first record of a snapshot file
+type snapshotHeader struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Index     uint64         `json:"index,omitempty"`
+	Segment   uint64         `json:"segment,omitempty"`
+	Seq       uint64         `json:"seq,omitempty"`
+	Quotas    []SetQuotaData `json:"quotas,omitempty"`
+	Draining  []string       `json:"draining,omitempty"`
+	Files     int            `json:"files"`
+	Chunks    int            `json:"chunks"`
+
+	StorageClasses []SetStorageClassData `json:"storage_classes,omitempty"`
+	Compression    []SetCompressionData  `json:"compression,omitempty"`
+}
+
+// WriteSnapshot writes a snapshot to disk. It doesn't hold the WAL lock, so
+// entries keep being logged while a large snapshot is written.
+func (w *WAL) WriteSnapshot(snapshot *Snapshot) error {
+	w.snapshotMu.Lock()
+	defer w.snapshotMu.Unlock()
+
+	snapshotPath := w.path + ".snapshot"
+	tempPath := snapshotPath + ".tmp"
+
+	// Write to temp file first
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+
+	if err := encodeSnapshot(file, snapshot); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	file.Close()
+
+	// Atomic rename
+	if err := os.Rename(tempPath, snapshotPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+	if err := syncDir(filepath.Dir(snapshotPath)); err != nil {
+		return fmt.Errorf("failed to sync snapshot directory: %w", err)
+	}
+
+	slog.Info("snapshot written", "path", snapshotPath, "files", len(snapshot.Files), "chunks", len(snapshot.Chunks))
+	return nil
+}
+
+// encodeSnapshot streams a snapshot as framed records
+func encodeSnapshot(out io.Writer, snapshot *Snapshot) error {
+	bw := bufio.NewWriterSize(out, 1<<20)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+
+	var record []byte
+	write := func(v any) error {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		record = appendRecord(record[:0], payload)
+		_, err = bw.Write(record)
+		return err
+	}
+
+	if err := write(snapshotHeader{
+		Timestamp: snapshot.Timestamp,
+		Index:     snapshot.Index,
+		Segment:   snapshot.Segment,
+		Seq:       snapshot.Seq,
+		Quotas:    snapshot.Quotas,
+		Draining:  snapshot.Draining,
+		Files:     len(snapshot.Files),
+		Chunks:    len(snapshot.Chunks),
+
+		StorageClasses: snapshot.StorageClasses,
+		Compression:    snapshot.Compression,
+	}); err != nil {
+		return err
+	}
+	for i := range snapshot.Files {
+		if err := write(&snapshot.Files[i]); err != nil {
+			return err
+		}
+	}
+	for i := range snapshot.Chunks {
+		if err := write(&snapshot.Chunks[i]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadSnapshot reads a snapshot from disk, including the single JSON document
+// written before snapshots were framed
+func (w *WAL) ReadSnapshot() (*Snapshot, error) {
+	snapshotPath := w.path + ".snapshot"
+
+	rr, err := openRecords(snapshotPath, snapshotMagic)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // No snapshot exists
+		}
+		if errors.Is(err, ErrCorrupt) {
+			return readLegacySnapshot(snapshotPath)
+		}
+		if errors.Is(err, errTornTail) {
+			rr.close()
+			return nil, fmt.Errorf("%w: %s: truncated snapshot", ErrCorrupt, snapshotPath)
+		}
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer rr.close()
+
+	// Snapshots are renamed into place after a sync, so any bad record is corruption
+	next := func(v any) error {
+		payload, err := rr.next()
+		if err == io.EOF || errors.Is(err, errTornTail) {
+			return fmt.Errorf("%w: %s: truncated snapshot", ErrCorrupt, snapshotPath)
+		}
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(payload, v); err != nil {
+			return fmt.Errorf("%w: %s: undecodable record: %v", ErrCorrupt, snapshotPath, err)
+		}
+		return nil
+	}
+
+	var header snapshotHeader
+	if err := next(&header); err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		Timestamp: header.Timestamp,
+		Index:     header.Index,
+		Segment:   header.Segment,
+		Seq:       header.Seq,
+		Quotas:    header.Quotas,
+		Draining:  header.Draining,
+		Files:     make([]SnapshotFile, header.Files),
+		Chunks:    make([]SnapshotChunk, header.Chunks),
+
+		StorageClasses: header.StorageClasses,
+		Compression:    header.Compression,
+	}
+	for i := range snapshot.Files {
+		if err := next(&snapshot.Files[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range snapshot.Chunks {
+		if err := next(&snapshot.Chunks[i]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := rr.next(); err != io.EOF {
+		return nil, fmt.Errorf("%w: %s: trailing data after snapshot", ErrCorrupt, snapshotPath)
+	}
+
+	slog.Info("snapshot loaded", "timestamp", snapshot.Timestamp, "files", len(snapshot.Files), "chunks", len(snapshot.Chunks))
+	return snapshot, nil
+}
+
+// readLegacySnapshot reads a snapshot written as one JSON document
+func readLegacySnapshot(snapshotPath string) (*Snapshot, error) {
+	file, err := os.Open(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(file).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	slog.Info("legacy snapshot loaded", "timestamp", snapshot.Timestamp, "files", len(snapshot.Files), "chunks", len(snapshot.Chunks))
+	return &snapshot, nil
+}
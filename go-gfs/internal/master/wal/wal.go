@@ -3,39 +3,67 @@ package wal
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
 )
 
 // OpType represents the type of operation in the WAL
 type OpType string
 
 const (
-	OpCreateFile      OpType = "CREATE_FILE"
-	OpDeleteFile      OpType = "DELETE_FILE"
-	OpDeleteNamespace OpType = "DELETE_NAMESPACE"
-	OpRenameFile      OpType = "RENAME_FILE"
-	OpAddChunk        OpType = "ADD_CHUNK"
-	OpCommitChunk     OpType = "COMMIT_CHUNK"
-	OpSetCounter      OpType = "SET_COUNTER"
+	OpCreateFile        OpType = "CREATE_FILE"
+	OpDeleteFile        OpType = "DELETE_FILE"
+	OpDeleteNamespace   OpType = "DELETE_NAMESPACE"
+	OpRenameFile        OpType = "RENAME_FILE"
+	OpAddChunk          OpType = "ADD_CHUNK"
+	OpCommitChunk       OpType = "COMMIT_CHUNK"
+	OpSetCounter        OpType = "SET_COUNTER"
+	OpImportSnapshot    OpType = "IMPORT_SNAPSHOT"
+	OpSnapshotFile      OpType = "SNAPSHOT_FILE"
+	OpSnapshotNamespace OpType = "SNAPSHOT_NAMESPACE"
+	OpReplaceChunk      OpType = "REPLACE_CHUNK"
+	OpSetQuota          OpType = "SET_QUOTA"
+	OpSetDraining       OpType = "SET_DRAINING"
+	OpSetChunkVersion   OpType = "SET_CHUNK_VERSION"
+	OpSetStorageClass   OpType = "SET_STORAGE_CLASS"
+	OpSetChunkStripe    OpType = "SET_CHUNK_STRIPE"
+	OpSetFileAttributes OpType = "SET_FILE_ATTRIBUTES"
+	OpTrashFile         OpType = "TRASH_FILE"
+	OpTrashNamespace    OpType = "TRASH_NAMESPACE"
+	OpRestoreFile       OpType = "RESTORE_FILE"
+	OpPurgeTrash        OpType = "PURGE_TRASH"
+	OpTransact          OpType = "TRANSACT"
+	OpSetCompression    OpType = "SET_COMPRESSION"
 )
 
-// Entry represents a single WAL entry
+// Entry represents a single WAL entry. Its position in the log is its consensus
+// index on replicated masters; on standalone masters entries are numbered in
+// order after the snapshot's Seq.
 type Entry struct {
-	Op   OpType          `json:"op"`
-	Data json.RawMessage `json:"data"`
+	Op    OpType          `json:"op"`
+	Data  json.RawMessage `json:"data"`
+	Index uint64          `json:"index,omitempty"` // Consensus log index (replicated masters only)
+}
+
+// NewEntry builds an entry for op with its data, for grouping in a TRANSACT
+func NewEntry(op OpType, data any) Entry {
+	raw, _ := json.Marshal(data)
+	return Entry{Op: op, Data: raw}
 }
 
 // CreateFileData represents data for CREATE_FILE operation
 type CreateFileData struct {
-	Path      string `json:"path"`
-	Namespace string `json:"namespace,omitempty"`
-	ChunkSize uint64 `json:"chunk_size"`
+	Path         string `json:"path"`
+	Namespace    string `json:"namespace,omitempty"`
+	ChunkSize    uint64 `json:"chunk_size"`
+	StorageClass string `json:"storage_class,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
 // DeleteFileData represents data for DELETE_FILE operation
@@ -67,6 +95,58 @@ type AddChunkData struct {
 type CommitChunkData struct {
 	ChunkHandle string `json:"chunk_handle"`
 	Size        uint64 `json:"size"`
+	Version     uint64 `json:"version,omitempty"` // Chunk version the write was stamped with
+
+	// File the chunk belongs to, for watchers; not needed to apply the entry
+	Path      string `json:"path,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// SetFileAttributesData represents data for SET_FILE_ATTRIBUTES operation
+// (the complete attributes of the file after the change)
+type SetFileAttributesData struct {
+	Path       string            `json:"path"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// TrashFileData represents data for TRASH_FILE operation
+// (a deleted file moved to the trash until its retention expires)
+type TrashFileData struct {
+	Path      string `json:"path"`
+	Namespace string `json:"namespace,omitempty"`
+	DeletedAt int64  `json:"deleted_at"` // Unix nanoseconds; also names the trash entry
+}
+
+// TrashNamespaceData represents data for TRASH_NAMESPACE operation
+type TrashNamespaceData struct {
+	Namespace string `json:"namespace"`
+	DeletedAt int64  `json:"deleted_at"` // Unix nanoseconds
+}
+
+// RestoreFileData represents data for RESTORE_FILE operation
+type RestoreFileData struct {
+	TrashID   string `json:"trash_id"`
+	Path      string `json:"path"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PurgeTrashData represents data for PURGE_TRASH operation
+type PurgeTrashData struct {
+	TrashIDs []string `json:"trash_ids"`
+}
+
+// TransactData represents data for TRANSACT operation
+// (file operations applied together, in order, or not at all)
+type TransactData struct {
+	Entries []Entry `json:"entries"`
+}
+
+// SetCompressionData represents data for SET_COMPRESSION operation
+// (how a namespace's sealed chunks are stored; empty for raw)
+type SetCompressionData struct {
+	Namespace   string `json:"namespace"`
+	Compression string `json:"compression,omitempty"`
 }
 
 // SetCounterData represents data for SET_COUNTER operation
@@ -74,14 +154,102 @@ type SetCounterData struct {
 	NextChunkHandle uint64 `json:"next_chunk_handle"`
 }
 
-// WAL is a write-ahead log for master persistence
+// SnapshotFileData represents data for SNAPSHOT_FILE operation
+type SnapshotFileData struct {
+	SourcePath    string `json:"source_path"`
+	DestPath      string `json:"dest_path"`
+	Namespace     string `json:"namespace,omitempty"`
+	DestNamespace string `json:"dest_namespace,omitempty"`
+}
+
+// SnapshotNamespaceData represents data for SNAPSHOT_NAMESPACE operation
+type SnapshotNamespaceData struct {
+	Namespace     string `json:"namespace"`
+	DestNamespace string `json:"dest_namespace"`
+}
+
+// ReplaceChunkData represents data for REPLACE_CHUNK operation
+// (a shared chunk swapped for its private copy before a write)
+type ReplaceChunkData struct {
+	Path        string `json:"path"`
+	Namespace   string `json:"namespace,omitempty"`
+	Index       int    `json:"index"`
+	OldHandle   string `json:"old_handle"`
+	ChunkHandle string `json:"chunk_handle"`
+}
+
+// SetQuotaData represents data for SET_QUOTA operation (zero limits mean unlimited)
+type SetQuotaData struct {
+	Namespace string `json:"namespace"`
+	MaxBytes  uint64 `json:"max_bytes,omitempty"`
+	MaxFiles  uint64 `json:"max_files,omitempty"`
+}
+
+// SetChunkVersionData represents data for SET_CHUNK_VERSION operation
+// (the version granted with a new lease)
+type SetChunkVersionData struct {
+	ChunkHandle string `json:"chunk_handle"`
+	Version     uint64 `json:"version"`
+}
+
+// SetStorageClassData represents data for SET_STORAGE_CLASS operation
+// (a namespace's default class when Path is empty)
+type SetStorageClassData struct {
+	Namespace    string `json:"namespace"`
+	Path         string `json:"path,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+}
+
+// SetChunkStripeData represents data for SET_CHUNK_STRIPE operation
+// (a chunk's replicas replaced by erasure coded fragments)
+type SetChunkStripeData struct {
+	ChunkHandle  string `json:"chunk_handle"`
+	DataShards   int    `json:"data_shards"`
+	ParityShards int    `json:"parity_shards"`
+	ShardSize    uint64 `json:"shard_size"`
+}
+
+// SetDrainingData represents data for SET_DRAINING operation
+type SetDrainingData struct {
+	ServerID string `json:"server_id"`
+	Draining bool   `json:"draining"`
+}
+
+// Replicator commits WAL entries through a consensus log before they are applied.
+// Committed entries come back through AppendCommitted on every replica.
+type Replicator interface {
+	Replicate(data []byte) (uint64, error) // Returns the entry's consensus index
+}
+
+// WAL is a write-ahead log for master persistence, kept as numbered segment
+// files next to path (wal.log.00000001, ...) holding checksummed records
 type WAL struct {
-	file *os.File
-	mu   sync.Mutex
-	path string
+	file    segmentFile // Active segment
+	mu      sync.Mutex
+	path    string
+	segment uint64 // Number of the active segment
+	size    int64  // Bytes in the active segment
+
+	snapshotMu sync.Mutex // Serializes snapshot writes, which don't hold mu
+
+	replicator Replicator
+
+	seq      uint64              // Position of the last entry written
+	observer func(uint64, Entry) // Called with every entry written and its position, in log order
+
+	broken error // Set when a failed write couldn't be undone; later writes fail with it
+}
+
+// segmentFile is the active segment as the WAL writes it
+type segmentFile interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
 }
 
-// New creates a new WAL at the given path
+// New opens the WAL at the given path, truncating a torn record left at the
+// end of the last segment by a crash
 func New(path string) (*WAL, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(path)
@@ -89,15 +257,36 @@ func New(path string) (*WAL, error) {
 		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
 	}
 
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	segments, err := listSegments(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open WAL file: %w", err)
+		return nil, err
 	}
 
-	return &WAL{
-		file: file,
-		path: path,
-	}, nil
+	w := &WAL{path: path}
+	if len(segments) == 0 || segments[len(segments)-1] == 0 {
+		// Fresh WAL, or a legacy JSON-lines one that is left for replay until the first snapshot
+		if err := w.createSegmentLocked(1); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	w.segment = segments[len(segments)-1]
+	name := segmentPath(path, w.segment)
+	size, err := recoverSegment(name)
+	if errors.Is(err, errTornTail) {
+		slog.Warn("truncated torn WAL tail", "segment", name, "size", size)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to recover WAL: %w", err)
+	}
+
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL file: %w", err)
+	}
+	w.file = file
+	w.size = size
+	return w, nil
 }
 
 // Close closes the WAL file
@@ -107,249 +296,602 @@ func (w *WAL) Close() error {
 	return w.file.Close()
 }
 
-// append writes an entry to the WAL
-func (w *WAL) append(entry Entry) error {
+// SetReplicator routes every logged entry through r instead of writing it directly
+func (w *WAL) SetReplicator(r Replicator) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.replicator = r
+}
+
+// SetObserver registers fn to be called with every entry and its position once it
+// is durable, in log order. The entry is not applied to memory yet. fn runs under
+// the WAL lock and must not block.
+func (w *WAL) SetObserver(fn func(uint64, Entry)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.observer = fn
+}
+
+// Seq returns the position of the last entry written
+func (w *WAL) Seq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// SetSeq moves the position forward to seq, the last entry recovered at startup;
+// it never moves backwards
+func (w *WAL) SetSeq(seq uint64) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	w.seq = max(w.seq, seq)
+}
+
+// append writes an entry to the WAL, or replicates it first when a replicator is set,
+// and returns its position
+func (w *WAL) append(entry Entry) (uint64, error) {
+	w.mu.Lock()
+	r := w.replicator
+	w.mu.Unlock()
+
+	if r != nil {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal WAL entry: %w", err)
+		}
+		return r.Replicate(data)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked(entry)
+}
+
+// AppendCommitted writes an entry committed by the consensus log at index
+func (w *WAL) AppendCommitted(index uint64, entry Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry.Index = index
+	_, err := w.writeLocked(entry)
+	return err
+}
+
+// writeLocked appends an entry to the active segment and syncs it, sealing the
+// segment once it reaches SegmentSize, and returns its position
+// Must be called with mu held
+func (w *WAL) writeLocked(entry Entry) (uint64, error) {
+	// Replicated entries are numbered by the consensus log so every replica agrees
+	pos := entry.Index
+	if pos == 0 {
+		pos = w.seq + 1
+	}
 
 	data, err := json.Marshal(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal WAL entry: %w", err)
+		return 0, fmt.Errorf("failed to marshal WAL entry: %w", err)
 	}
 
-	data = append(data, '\n')
-	if _, err := w.file.Write(data); err != nil {
-		return fmt.Errorf("failed to write WAL entry: %w", err)
+	record := appendRecord(nil, data)
+	if err := w.writeRecordLocked(record); err != nil {
+		return 0, err
+	}
+	w.size += int64(len(record))
+	w.seq = max(w.seq, pos)
+	if w.observer != nil {
+		w.observer(pos, entry)
+	}
+
+	if w.size >= SegmentSize {
+		// The entry is durable either way; keep appending to this segment on failure
+		if err := w.createSegmentLocked(w.segment + 1); err != nil {
+			slog.Warn("failed to rotate WAL segment", "segment", w.segment, "error", err)
+		}
+	}
+	return pos, nil
+}
+
+// writeRecordLocked appends a record to the active segment and syncs it. A record
+// that fails part way is cut off again, so the next one doesn't follow a partial
+// frame that replay would take for corruption in the middle of the segment. If the
+// cut fails too, the WAL takes no more writes; on restart the partial frame is the
+// torn tail and is truncated.
+// Must be called with mu held
+func (w *WAL) writeRecordLocked(record []byte) error {
+	if w.broken != nil {
+		return w.broken
 	}
 
-	// Sync to disk for durability
-	if err := w.file.Sync(); err != nil {
-		return fmt.Errorf("failed to sync WAL: %w", err)
+	n, err := w.file.Write(record)
+	if err == nil && n < len(record) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to write WAL entry: %w", err)
+	} else if err = w.file.Sync(); err != nil {
+		// Sync to disk for durability
+		err = fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if terr := w.file.Truncate(w.size); terr != nil {
+		w.broken = fmt.Errorf("WAL unusable after a failed write: %w", terr)
+		slog.Error("failed to cut a partial WAL record, refusing further writes", "segment", w.segment, "size", w.size, "error", terr)
+	}
+	return err
+}
+
+// Rotate seals the active segment and starts a new one, returning its number.
+// Every entry written before Rotate is in an earlier segment.
+func (w *WAL) Rotate() (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Sealing a segment that ends in a partial frame would make the frame corrupt
+	if w.broken != nil {
+		return 0, w.broken
+	}
+	if err := w.createSegmentLocked(w.segment + 1); err != nil {
+		return 0, err
+	}
+	return w.segment, nil
+}
+
+// createSegmentLocked makes seq the active segment; the previous one was synced
+// record by record, so it is complete on disk
+// Must be called with mu held
+func (w *WAL) createSegmentLocked(seq uint64) error {
+	name := segmentPath(w.path, seq)
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL segment: %w", err)
+	}
+	if _, err := file.Write([]byte(segmentMagic)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write WAL segment header: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync WAL segment: %w", err)
+	}
+	if err := syncDir(filepath.Dir(w.path)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync WAL directory: %w", err)
 	}
 
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file = file
+	w.segment = seq
+	w.size = int64(len(segmentMagic))
 	return nil
 }
 
 // LogCreateFile logs a CREATE_FILE operation
-func (w *WAL) LogCreateFile(path, namespace string, chunkSize uint64) error {
-	data, _ := json.Marshal(CreateFileData{Path: path, Namespace: namespace, ChunkSize: chunkSize})
+func (w *WAL) LogCreateFile(path, namespace string, chunkSize uint64, storageClass string, attributes map[string]string) (uint64, error) {
+	data, _ := json.Marshal(CreateFileData{Path: path, Namespace: namespace, ChunkSize: chunkSize, StorageClass: storageClass, Attributes: attributes})
 	return w.append(Entry{Op: OpCreateFile, Data: data})
 }
 
 // LogDeleteFile logs a DELETE_FILE operation
-func (w *WAL) LogDeleteFile(path, namespace string) error {
+func (w *WAL) LogDeleteFile(path, namespace string) (uint64, error) {
 	data, _ := json.Marshal(DeleteFileData{Path: path, Namespace: namespace})
 	return w.append(Entry{Op: OpDeleteFile, Data: data})
 }
 
 // LogDeleteNamespace logs a DELETE_NAMESPACE operation
-func (w *WAL) LogDeleteNamespace(namespace string) error {
+func (w *WAL) LogDeleteNamespace(namespace string) (uint64, error) {
 	data, _ := json.Marshal(DeleteNamespaceData{Namespace: namespace})
 	return w.append(Entry{Op: OpDeleteNamespace, Data: data})
 }
 
 // LogRenameFile logs a RENAME_FILE operation
-func (w *WAL) LogRenameFile(oldPath, newPath, namespace string) error {
+func (w *WAL) LogRenameFile(oldPath, newPath, namespace string) (uint64, error) {
 	data, _ := json.Marshal(RenameFileData{OldPath: oldPath, NewPath: newPath, Namespace: namespace})
 	return w.append(Entry{Op: OpRenameFile, Data: data})
 }
 
 // LogAddChunk logs an ADD_CHUNK operation
-func (w *WAL) LogAddChunk(path, namespace, chunkHandle string) error {
+func (w *WAL) LogAddChunk(path, namespace, chunkHandle string) (uint64, error) {
 	data, _ := json.Marshal(AddChunkData{Path: path, Namespace: namespace, ChunkHandle: chunkHandle})
 	return w.append(Entry{Op: OpAddChunk, Data: data})
 }
 
 // LogCommitChunk logs a COMMIT_CHUNK operation
-func (w *WAL) LogCommitChunk(chunkHandle string, size uint64) error {
-	data, _ := json.Marshal(CommitChunkData{ChunkHandle: chunkHandle, Size: size})
+func (w *WAL) LogCommitChunk(chunkHandle string, size, version uint64, path, namespace string) (uint64, error) {
+	data, _ := json.Marshal(CommitChunkData{ChunkHandle: chunkHandle, Size: size, Version: version, Path: path, Namespace: namespace})
 	return w.append(Entry{Op: OpCommitChunk, Data: data})
 }
 
+// LogSnapshotFile logs a SNAPSHOT_FILE operation
+func (w *WAL) LogSnapshotFile(sourcePath, destPath, namespace, destNamespace string) (uint64, error) {
+	data, _ := json.Marshal(SnapshotFileData{SourcePath: sourcePath, DestPath: destPath, Namespace: namespace, DestNamespace: destNamespace})
+	return w.append(Entry{Op: OpSnapshotFile, Data: data})
+}
+
+// LogSnapshotNamespace logs a SNAPSHOT_NAMESPACE operation
+func (w *WAL) LogSnapshotNamespace(namespace, destNamespace string) (uint64, error) {
+	data, _ := json.Marshal(SnapshotNamespaceData{Namespace: namespace, DestNamespace: destNamespace})
+	return w.append(Entry{Op: OpSnapshotNamespace, Data: data})
+}
+
+// LogReplaceChunk logs a REPLACE_CHUNK operation
+func (w *WAL) LogReplaceChunk(path, namespace string, index int, oldHandle, chunkHandle string) (uint64, error) {
+	data, _ := json.Marshal(ReplaceChunkData{Path: path, Namespace: namespace, Index: index, OldHandle: oldHandle, ChunkHandle: chunkHandle})
+	return w.append(Entry{Op: OpReplaceChunk, Data: data})
+}
+
+// LogSetQuota logs a SET_QUOTA operation
+func (w *WAL) LogSetQuota(namespace string, maxBytes, maxFiles uint64) (uint64, error) {
+	data, _ := json.Marshal(SetQuotaData{Namespace: namespace, MaxBytes: maxBytes, MaxFiles: maxFiles})
+	return w.append(Entry{Op: OpSetQuota, Data: data})
+}
+
+// LogSetDraining logs a SET_DRAINING operation
+func (w *WAL) LogSetDraining(serverID string, draining bool) (uint64, error) {
+	data, _ := json.Marshal(SetDrainingData{ServerID: serverID, Draining: draining})
+	return w.append(Entry{Op: OpSetDraining, Data: data})
+}
+
+// LogSetChunkVersion logs a SET_CHUNK_VERSION operation
+func (w *WAL) LogSetChunkVersion(chunkHandle string, version uint64) (uint64, error) {
+	data, _ := json.Marshal(SetChunkVersionData{ChunkHandle: chunkHandle, Version: version})
+	return w.append(Entry{Op: OpSetChunkVersion, Data: data})
+}
+
+// LogSetStorageClass logs a SET_STORAGE_CLASS operation
+func (w *WAL) LogSetStorageClass(namespace, path, storageClass string) (uint64, error) {
+	data, _ := json.Marshal(SetStorageClassData{Namespace: namespace, Path: path, StorageClass: storageClass})
+	return w.append(Entry{Op: OpSetStorageClass, Data: data})
+}
+
+// LogSetChunkStripe logs a SET_CHUNK_STRIPE operation
+func (w *WAL) LogSetChunkStripe(chunkHandle string, dataShards, parityShards int, shardSize uint64) (uint64, error) {
+	data, _ := json.Marshal(SetChunkStripeData{
+		ChunkHandle:  chunkHandle,
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		ShardSize:    shardSize,
+	})
+	return w.append(Entry{Op: OpSetChunkStripe, Data: data})
+}
+
+// LogSetFileAttributes logs a SET_FILE_ATTRIBUTES operation
+func (w *WAL) LogSetFileAttributes(path, namespace string, attributes map[string]string) (uint64, error) {
+	data, _ := json.Marshal(SetFileAttributesData{Path: path, Namespace: namespace, Attributes: attributes})
+	return w.append(Entry{Op: OpSetFileAttributes, Data: data})
+}
+
+// LogTrashFile logs a TRASH_FILE operation
+func (w *WAL) LogTrashFile(path, namespace string, deletedAt int64) (uint64, error) {
+	data, _ := json.Marshal(TrashFileData{Path: path, Namespace: namespace, DeletedAt: deletedAt})
+	return w.append(Entry{Op: OpTrashFile, Data: data})
+}
+
+// LogTrashNamespace logs a TRASH_NAMESPACE operation
+func (w *WAL) LogTrashNamespace(namespace string, deletedAt int64) (uint64, error) {
+	data, _ := json.Marshal(TrashNamespaceData{Namespace: namespace, DeletedAt: deletedAt})
+	return w.append(Entry{Op: OpTrashNamespace, Data: data})
+}
+
+// LogRestoreFile logs a RESTORE_FILE operation
+func (w *WAL) LogRestoreFile(trashID, path, namespace string) (uint64, error) {
+	data, _ := json.Marshal(RestoreFileData{TrashID: trashID, Path: path, Namespace: namespace})
+	return w.append(Entry{Op: OpRestoreFile, Data: data})
+}
+
+// LogPurgeTrash logs a PURGE_TRASH operation
+func (w *WAL) LogPurgeTrash(trashIDs []string) (uint64, error) {
+	data, _ := json.Marshal(PurgeTrashData{TrashIDs: trashIDs})
+	return w.append(Entry{Op: OpPurgeTrash, Data: data})
+}
+
+// LogTransact logs a TRANSACT operation
+func (w *WAL) LogTransact(entries []Entry) (uint64, error) {
+	data, _ := json.Marshal(TransactData{Entries: entries})
+	return w.append(Entry{Op: OpTransact, Data: data})
+}
+
+// LogSetCompression logs a SET_COMPRESSION operation
+func (w *WAL) LogSetCompression(namespace, compression string) (uint64, error) {
+	data, _ := json.Marshal(SetCompressionData{Namespace: namespace, Compression: compression})
+	return w.append(Entry{Op: OpSetCompression, Data: data})
+}
+
 // LogSetCounter logs the chunk handle counter
-func (w *WAL) LogSetCounter(nextChunkHandle uint64) error {
+func (w *WAL) LogSetCounter(nextChunkHandle uint64) (uint64, error) {
 	data, _ := json.Marshal(SetCounterData{NextChunkHandle: nextChunkHandle})
 	return w.append(Entry{Op: OpSetCounter, Data: data})
 }
 
-// Reader provides an interface for replaying WAL entries
+// Reader replays WAL entries in order, one segment at a time
 type Reader struct {
-	scanner *bufio.Scanner
-	file    *os.File
+	path     string
+	segments []uint64
+	current  *recordReader
+	legacy   *bufio.Scanner
+	file     *os.File // Open legacy file
 }
 
-// NewReader creates a reader to replay WAL entries
-func NewReader(path string) (*Reader, error) {
-	file, err := os.Open(path)
+// NewReader creates a reader over the segments numbered from fromSegment on.
+// A snapshot records the first segment it doesn't cover; 0 includes a legacy JSON-lines WAL.
+func NewReader(path string, fromSegment uint64) (*Reader, error) {
+	all, err := listSegments(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // No WAL file, nothing to replay
+		return nil, err
+	}
+
+	var segments []uint64
+	for _, seq := range all {
+		if seq >= fromSegment {
+			segments = append(segments, seq)
 		}
-		return nil, fmt.Errorf("failed to open WAL for reading: %w", err)
 	}
+	if len(segments) == 0 {
+		return nil, nil // No WAL file, nothing to replay
+	}
+	return &Reader{path: path, segments: segments}, nil
+}
+
+// Reader opens a reader over this WAL's segments numbered from fromSegment on
+func (w *WAL) Reader(fromSegment uint64) (*Reader, error) {
+	return NewReader(w.path, fromSegment)
+}
 
-	return &Reader{
-		scanner: bufio.NewScanner(file),
-		file:    file,
-	}, nil
+// Position returns the position of an entry read after the one at prev: its
+// consensus index, or the next position for an entry from a standalone master
+func Position(entry Entry, prev uint64) uint64 {
+	if entry.Index != 0 {
+		return entry.Index
+	}
+	return prev + 1
 }
 
 // Close closes the reader
 func (r *Reader) Close() error {
+	if r == nil {
+		return nil
+	}
+	if r.current != nil {
+		return r.current.close()
+	}
 	if r.file != nil {
 		return r.file.Close()
 	}
 	return nil
 }
 
-// ReadAll reads all entries from the WAL
-func (r *Reader) ReadAll() ([]Entry, error) {
-	if r == nil {
-		return nil, nil
-	}
-
-	var entries []Entry
-	for r.scanner.Scan() {
-		line := r.scanner.Bytes()
-		if len(line) == 0 {
+// Next returns the next entry, or io.EOF after the last one.
+// An incomplete record at the end of the last segment ends the log; a record
+// that fails its checksum anywhere else is reported as ErrCorrupt.
+func (r *Reader) Next() (Entry, error) {
+	for {
+		if r.current == nil && r.legacy == nil {
+			if len(r.segments) == 0 {
+				return Entry{}, io.EOF
+			}
+			if err := r.openNext(); err != nil {
+				return Entry{}, err
+			}
 			continue
 		}
 
-		var entry Entry
-		if err := json.Unmarshal(line, &entry); err != nil {
-			slog.Warn("skipping malformed WAL entry", "error", err)
+		if r.legacy != nil {
+			if entry, ok := r.nextLegacy(); ok {
+				return entry, nil
+			}
+			err := r.legacy.Err()
+			r.file.Close()
+			r.legacy, r.file = nil, nil
+			if err != nil {
+				return Entry{}, fmt.Errorf("error reading WAL: %w", err)
+			}
 			continue
 		}
-		entries = append(entries, entry)
-	}
 
-	if err := r.scanner.Err(); err != nil && err != io.EOF {
-		return nil, fmt.Errorf("error reading WAL: %w", err)
-	}
+		payload, err := r.current.next()
+		if err == nil {
+			var entry Entry
+			if err := json.Unmarshal(payload, &entry); err != nil {
+				// The checksum matched, so this was written malformed
+				return Entry{}, fmt.Errorf("%w: %s: undecodable entry at offset %d: %v", ErrCorrupt, r.current.name, r.current.offset, err)
+			}
+			return entry, nil
+		}
 
-	return entries, nil
+		name, offset := r.current.name, r.current.offset
+		r.current.close()
+		r.current = nil
+		switch {
+		case err == io.EOF:
+			continue
+		case errors.Is(err, errTornTail) && len(r.segments) == 0:
+			slog.Warn("ignoring torn WAL tail", "segment", name, "offset", offset)
+			return Entry{}, io.EOF
+		case errors.Is(err, errTornTail):
+			return Entry{}, fmt.Errorf("%w: %s: incomplete record at offset %d in sealed segment", ErrCorrupt, name, offset)
+		default:
+			return Entry{}, err
+		}
+	}
 }
 
-// Snapshot represents a point-in-time snapshot of master state
-type Snapshot struct {
-	Timestamp time.Time       `json:"timestamp"`
-	Files     []SnapshotFile  `json:"files"`
-	Chunks    []SnapshotChunk `json:"chunks"`
-}
+// openNext opens the next segment in order
+func (r *Reader) openNext() error {
+	seq := r.segments[0]
+	r.segments = r.segments[1:]
+	name := segmentPath(r.path, seq)
 
-// SnapshotFile represents a file in the snapshot
-type SnapshotFile struct {
-	Path       string   `json:"path"`
-	Namespace  string   `json:"namespace"`
-	ChunkSize  uint64   `json:"chunk_size"`
-	Chunks     []string `json:"chunks"`
-	Size       uint64   `json:"size"`
-	CreatedAt  int64    `json:"created_at"`
-	ModifiedAt int64    `json:"modified_at"`
-}
+	if seq == 0 {
+		file, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open WAL for reading: %w", err)
+		}
+		r.file = file
+		r.legacy = bufio.NewScanner(file)
+		r.legacy.Buffer(make([]byte, 64<<10), maxRecordSize)
+		return nil
+	}
 
-// SnapshotChunk represents a chunk in the snapshot
-type SnapshotChunk struct {
-	Handle    string `json:"handle"`
-	FilePath  string `json:"file_path"`
-	Namespace string `json:"namespace"`
-	Size      uint64 `json:"size"`
-	Version   uint64 `json:"version"`
-	Status    string `json:"status"`
+	rr, err := openRecords(name, segmentMagic)
+	if errors.Is(err, errTornTail) {
+		// A segment that was created but never got its header
+		rr.close()
+		if len(r.segments) > 0 {
+			return fmt.Errorf("%w: %s: missing header", ErrCorrupt, name)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open WAL for reading: %w", err)
+	}
+	r.current = rr
+	return nil
 }
 
-// WriteSnapshot writes a snapshot to disk
-func (w *WAL) WriteSnapshot(snapshot *Snapshot) error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
+// nextLegacy reads the next entry from a JSON-lines WAL written before segments
+// existed; it has no checksums, so malformed lines are skipped as before
+func (r *Reader) nextLegacy() (Entry, bool) {
+	for r.legacy.Scan() {
+		line := r.legacy.Bytes()
+		if len(line) == 0 {
+			continue
+		}
 
-	snapshotPath := w.path + ".snapshot"
-	tempPath := snapshotPath + ".tmp"
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			slog.Warn("skipping malformed WAL entry", "error", err)
+			continue
+		}
+		return entry, true
+	}
+	return Entry{}, false
+}
 
-	// Write to temp file first
-	file, err := os.Create(tempPath)
-	if err != nil {
-		return fmt.Errorf("failed to create snapshot file: %w", err)
+// ReadAll reads all entries from the WAL
+func (r *Reader) ReadAll() ([]Entry, error) {
+	if r == nil {
+		return nil, nil
 	}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(snapshot); err != nil {
-		file.Close()
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to encode snapshot: %w", err)
+	var entries []Entry
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
 	}
+}
 
-	if err := file.Sync(); err != nil {
-		file.Close()
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to sync snapshot: %w", err)
+// RemoveSegmentsBefore deletes the segments a snapshot covers, those numbered
+// below segment, including a legacy JSON-lines WAL
+func (w *WAL) RemoveSegmentsBefore(segment uint64) error {
+	segments, err := listSegments(w.path)
+	if err != nil {
+		return err
 	}
-	file.Close()
 
-	// Atomic rename
-	if err := os.Rename(tempPath, snapshotPath); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to rename snapshot: %w", err)
+	removed := 0
+	for _, seq := range segments {
+		if seq >= segment {
+			break
+		}
+		if err := os.Remove(segmentPath(w.path, seq)); err != nil {
+			return fmt.Errorf("failed to remove WAL segment: %w", err)
+		}
+		removed++
+	}
+	if err := syncDir(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to sync WAL directory: %w", err)
 	}
 
-	slog.Info("snapshot written", "path", snapshotPath, "files", len(snapshot.Files), "chunks", len(snapshot.Chunks))
+	slog.Info("WAL compacted", "segment", segment, "removed", removed)
 	return nil
 }
 
-// ReadSnapshot reads a snapshot from disk
-func (w *WAL) ReadSnapshot() (*Snapshot, error) {
-	snapshotPath := w.path + ".snapshot"
+// TruncateThrough drops entries covered by a snapshot at index, keeping any
+// entries committed after the snapshot was taken. Sealed segments are removed
+// once every entry in them is covered; replay skips covered entries in the rest.
+func (w *WAL) TruncateThrough(index uint64) error {
+	active, err := w.Rotate()
+	if err != nil {
+		return err
+	}
 
-	file, err := os.Open(snapshotPath)
+	segments, err := listSegments(w.path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // No snapshot exists
-		}
-		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	var snapshot Snapshot
-	if err := json.NewDecoder(file).Decode(&snapshot); err != nil {
-		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	removed := 0
+	for _, seq := range segments {
+		if seq >= active {
+			break
+		}
+		last, err := w.lastIndex(seq)
+		if err != nil {
+			return err
+		}
+		if last > index {
+			break
+		}
+		if err := os.Remove(segmentPath(w.path, seq)); err != nil {
+			return fmt.Errorf("failed to remove WAL segment: %w", err)
+		}
+		removed++
+	}
+	if err := syncDir(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to sync WAL directory: %w", err)
 	}
 
-	slog.Info("snapshot loaded", "timestamp", snapshot.Timestamp, "files", len(snapshot.Files), "chunks", len(snapshot.Chunks))
-	return &snapshot, nil
+	slog.Info("WAL truncated after snapshot", "index", index, "removed", removed)
+	return nil
 }
 
-// TruncateAfterSnapshot truncates the WAL after a successful snapshot
-func (w *WAL) TruncateAfterSnapshot() error {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	// Close current file
-	if err := w.file.Close(); err != nil {
-		return fmt.Errorf("failed to close WAL: %w", err)
-	}
+// lastIndex returns the highest consensus index in a sealed segment
+func (w *WAL) lastIndex(seq uint64) (uint64, error) {
+	r := &Reader{path: w.path, segments: []uint64{seq}}
+	defer r.Close()
 
-	// Truncate by recreating the file
-	file, err := os.Create(w.path)
-	if err != nil {
-		return fmt.Errorf("failed to truncate WAL: %w", err)
+	var last uint64
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			return last, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		last = max(last, entry.Index)
 	}
-	w.file = file
-
-	slog.Info("WAL truncated after snapshot")
-	return nil
 }
 
 // EntryCount returns the approximate number of entries in the WAL
 func (w *WAL) EntryCount() (int, error) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	info, err := w.file.Stat()
+	segments, err := listSegments(w.path)
 	if err != nil {
 		return 0, err
 	}
 
+	var total int64
+	for _, seq := range segments {
+		info, err := os.Stat(segmentPath(w.path, seq))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // Removed by a concurrent compaction
+			}
+			return 0, err
+		}
+		total += info.Size()
+	}
+
 	// Rough estimate: ~200 bytes per entry on average
-	return int(info.Size() / 200), nil
+	return int(total / 200), nil
 }
@@ -0,0 +1,507 @@
+package wal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// openTestWAL opens a WAL in a temporary directory
+func openTestWAL(t *testing.T, path string) *WAL {
+	t.Helper()
+	w, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+// logTestFiles logs a CREATE_FILE for each path
+func logTestFiles(t *testing.T, w *WAL, paths ...string) {
+	t.Helper()
+	for _, path := range paths {
+		if _, err := w.LogCreateFile(path, "", 64, "", nil); err != nil {
+			t.Fatalf("LogCreateFile %s: %v", path, err)
+		}
+	}
+}
+
+// readPaths replays the WAL from fromSegment and returns the created paths
+func readPaths(t *testing.T, path string, fromSegment uint64) ([]string, error) {
+	t.Helper()
+	r, err := NewReader(path, fromSegment)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	entries, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		var data CreateFileData
+		if err := json.Unmarshal(entry.Data, &data); err != nil {
+			t.Fatalf("decode %s: %v", entry.Op, err)
+		}
+		paths = append(paths, data.Path)
+	}
+	return paths, nil
+}
+
+// recordOffsets returns where each record of a segment starts, and the segment's size
+func recordOffsets(t *testing.T, name string) ([]int64, int64) {
+	t.Helper()
+	rr, err := openRecords(name, segmentMagic)
+	if err != nil {
+		t.Fatalf("openRecords: %v", err)
+	}
+	defer rr.close()
+	var offsets []int64
+	for {
+		start := rr.offset
+		if _, err := rr.next(); err != nil {
+			return offsets, rr.size
+		}
+		offsets = append(offsets, start)
+	}
+}
+
+func TestWALReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	w := openTestWAL(t, path)
+	logTestFiles(t, w, "/a", "/b")
+	w.Close()
+
+	w = openTestWAL(t, path)
+	logTestFiles(t, w, "/c")
+
+	got, err := readPaths(t, path, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := []string{"/a", "/b", "/c"}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+// Standalone entries are numbered in order and replicated ones by their index;
+// the observer and readers see the same positions
+func TestWALPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	w := openTestWAL(t, path)
+	w.SetSeq(10) // As after a snapshot at 10
+
+	var observed []uint64
+	w.SetObserver(func(pos uint64, entry Entry) { observed = append(observed, pos) })
+
+	var logged []uint64
+	for _, p := range []string{"/a", "/b"} {
+		pos, err := w.LogCreateFile(p, "", 64, "", nil)
+		if err != nil {
+			t.Fatalf("LogCreateFile: %v", err)
+		}
+		logged = append(logged, pos)
+	}
+	if err := w.AppendCommitted(20, Entry{Op: OpSetCounter}); err != nil {
+		t.Fatalf("AppendCommitted: %v", err)
+	}
+	if want := []uint64{11, 12}; !slices.Equal(logged, want) {
+		t.Errorf("logged at %v, want %v", logged, want)
+	}
+	if want := []uint64{11, 12, 20}; !slices.Equal(observed, want) {
+		t.Errorf("observed %v, want %v", observed, want)
+	}
+	if w.Seq() != 20 {
+		t.Errorf("Seq = %d, want 20", w.Seq())
+	}
+
+	r, err := w.Reader(0)
+	if err != nil {
+		t.Fatalf("Reader: %v", err)
+	}
+	defer r.Close()
+	entries, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	var read []uint64
+	pos := uint64(10)
+	for _, entry := range entries {
+		pos = Position(entry, pos)
+		read = append(read, pos)
+	}
+	if !slices.Equal(read, observed) {
+		t.Errorf("read back at %v, want %v", read, observed)
+	}
+}
+
+func TestWALTornTail(t *testing.T) {
+	tests := []struct {
+		name string
+		tear func(data []byte, last int64) []byte // Damages the segment; last is where its final record starts
+	}{
+		{name: "partial header", tear: func(data []byte, last int64) []byte { return data[:last+3] }},
+		{name: "partial payload", tear: func(data []byte, last int64) []byte { return data[:len(data)-5] }},
+		{name: "corrupt final record", tear: func(data []byte, last int64) []byte {
+			data[len(data)-2] ^= 0xff
+			return data
+		}},
+		{name: "zero fill", tear: func(data []byte, last int64) []byte {
+			return append(data[:last], make([]byte, 4096)...)
+		}},
+		{name: "missing segment header", tear: func(data []byte, last int64) []byte { return data[:3] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			w := openTestWAL(t, path)
+			logTestFiles(t, w, "/a", "/b", "/c")
+			w.Close()
+
+			name := segmentPath(path, 1)
+			offsets, _ := recordOffsets(t, name)
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(name, tt.tear(data, offsets[len(offsets)-1]), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{"/a", "/b"}
+			if tt.name == "missing segment header" {
+				want = nil
+			}
+			// Replay stops at the torn record before recovery truncates it
+			got, err := readPaths(t, path, 0)
+			if err != nil {
+				t.Fatalf("read torn WAL: %v", err)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+
+			// Opening truncates the tail, so new records follow the last good one
+			w = openTestWAL(t, path)
+			logTestFiles(t, w, "/d")
+			got, err = readPaths(t, path, 0)
+			if err != nil {
+				t.Fatalf("read recovered WAL: %v", err)
+			}
+			if want := append(want, "/d"); !slices.Equal(got, want) {
+				t.Errorf("after recovery replayed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// faultyFile fails the next write after writing only part of it, or fails the
+// next sync, and can refuse to truncate
+type faultyFile struct {
+	segmentFile
+	shortWrite   bool
+	failSync     bool
+	failTruncate bool
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.shortWrite {
+		f.shortWrite = false
+		n, _ := f.segmentFile.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return f.segmentFile.Write(p)
+}
+
+func (f *faultyFile) Sync() error {
+	if f.failSync {
+		f.failSync = false
+		return errors.New("I/O error")
+	}
+	return f.segmentFile.Sync()
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errors.New("read-only file system")
+	}
+	return f.segmentFile.Truncate(size)
+}
+
+// A failed write is cut off, so the entries after it replay and the WAL still opens
+func TestWALFailedWrite(t *testing.T) {
+	for _, fault := range []string{"short write", "failed sync"} {
+		t.Run(fault, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			w := openTestWAL(t, path)
+			logTestFiles(t, w, "/a")
+
+			f := &faultyFile{segmentFile: w.file, shortWrite: fault == "short write", failSync: fault == "failed sync"}
+			w.file = f
+			if _, err := w.LogCreateFile("/lost", "", 64, "", nil); err == nil {
+				t.Fatal("write succeeded")
+			}
+			if seq := w.Seq(); seq != 1 {
+				t.Errorf("position %d after the failed write, want 1", seq)
+			}
+			logTestFiles(t, w, "/b")
+			w.Close()
+
+			got, err := readPaths(t, path, 0)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if want := []string{"/a", "/b"}; !slices.Equal(got, want) {
+				t.Errorf("replayed %v, want %v", got, want)
+			}
+			w = openTestWAL(t, path)
+			logTestFiles(t, w, "/c")
+		})
+	}
+
+	// If the partial record can't be cut off, nothing more is written after it
+	path := filepath.Join(t.TempDir(), "wal.log")
+	w := openTestWAL(t, path)
+	logTestFiles(t, w, "/a")
+	w.file = &faultyFile{segmentFile: w.file, shortWrite: true, failTruncate: true}
+	if _, err := w.LogCreateFile("/lost", "", 64, "", nil); err == nil {
+		t.Fatal("write succeeded")
+	}
+	if _, err := w.LogCreateFile("/b", "", 64, "", nil); err == nil {
+		t.Error("write after an unrecoverable failure succeeded")
+	}
+	if _, err := w.Rotate(); err == nil {
+		t.Error("sealed a segment ending in a partial record")
+	}
+	w.Close()
+
+	// On restart the partial record is the torn tail
+	w = openTestWAL(t, path)
+	logTestFiles(t, w, "/c")
+	got, err := readPaths(t, path, 0)
+	if err != nil {
+		t.Fatalf("read after restart: %v", err)
+	}
+	if want := []string{"/a", "/c"}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestWALChecksumMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		segment uint64 // Segment to damage
+		record  int    // Record in it to damage
+		want    []string
+		wantErr error
+	}{
+		{name: "middle record", segment: 1, record: 1, wantErr: ErrCorrupt},
+		{name: "last record of a sealed segment", segment: 1, record: 2, wantErr: ErrCorrupt},
+		{name: "last record of the active segment", segment: 2, record: 0, want: []string{"/a", "/b", "/c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			w := openTestWAL(t, path)
+			logTestFiles(t, w, "/a", "/b", "/c")
+			if _, err := w.Rotate(); err != nil {
+				t.Fatalf("Rotate: %v", err)
+			}
+			logTestFiles(t, w, "/d")
+			w.Close()
+
+			name := segmentPath(path, tt.segment)
+			offsets, _ := recordOffsets(t, name)
+			data, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[offsets[tt.record]+recordHeaderSize+2] ^= 0xff
+			if err := os.WriteFile(name, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readPaths(t, path, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWALSegmentRollover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	w := openTestWAL(t, path)
+
+	// Large entries fill segments with few synced writes
+	big := map[string]string{"pad": strings.Repeat("x", 1<<20)}
+	var want []string
+	for i := range SegmentSize/(1<<20) + 2 {
+		p := fmt.Sprintf("/f%02d", i)
+		if _, err := w.LogCreateFile(p, "", 64, "", big); err != nil {
+			t.Fatalf("LogCreateFile: %v", err)
+		}
+		want = append(want, p)
+	}
+
+	segments, err := listSegments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("segments = %v, want 2", segments)
+	}
+	info, err := os.Stat(segmentPath(path, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() < SegmentSize {
+		t.Errorf("sealed segment holds %d bytes, want at least %d", info.Size(), SegmentSize)
+	}
+
+	got, err := readPaths(t, path, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+	if got, _ := readPaths(t, path, 2); len(got) == 0 || got[0] != want[len(want)-len(got)] {
+		t.Errorf("replay from segment 2 = %v, want the last entries", got)
+	}
+}
+
+func TestWALTruncateThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	w := openTestWAL(t, path)
+
+	// Segments 1-3 hold indexes 1-2, 3-4 and 5-6
+	index := uint64(0)
+	for segment := range 3 {
+		for range 2 {
+			index++
+			data, _ := json.Marshal(CreateFileData{Path: fmt.Sprintf("/i%d", index)})
+			if err := w.AppendCommitted(index, Entry{Op: OpCreateFile, Data: data}); err != nil {
+				t.Fatalf("AppendCommitted: %v", err)
+			}
+		}
+		if segment < 2 {
+			if _, err := w.Rotate(); err != nil {
+				t.Fatalf("Rotate: %v", err)
+			}
+		}
+	}
+
+	// Index 3 is in segment 2, which also holds 4, so it stays
+	if err := w.TruncateThrough(3); err != nil {
+		t.Fatalf("TruncateThrough: %v", err)
+	}
+	segments, _ := listSegments(path)
+	if want := []uint64{2, 3, 4}; !slices.Equal(segments, want) {
+		t.Errorf("segments after TruncateThrough(3) = %v, want %v", segments, want)
+	}
+	got, err := readPaths(t, path, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := []string{"/i3", "/i4", "/i5", "/i6"}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+
+	if err := w.TruncateThrough(6); err != nil {
+		t.Fatalf("TruncateThrough: %v", err)
+	}
+	segments, _ = listSegments(path)
+	if want := []uint64{5}; !slices.Equal(segments, want) {
+		t.Errorf("segments after TruncateThrough(6) = %v, want %v", segments, want)
+	}
+
+	logTestFiles(t, w, "/next")
+	if err := w.RemoveSegmentsBefore(5); err != nil {
+		t.Fatalf("RemoveSegmentsBefore: %v", err)
+	}
+	if got, _ := readPaths(t, path, 5); !slices.Equal(got, []string{"/next"}) {
+		t.Errorf("replayed %v, want [/next]", got)
+	}
+}
+
+func TestWALRemoveSegmentsBefore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	w := openTestWAL(t, path)
+	logTestFiles(t, w, "/a")
+	w.Rotate()
+	logTestFiles(t, w, "/b")
+	segment, _ := w.Rotate()
+	logTestFiles(t, w, "/c")
+
+	if err := w.RemoveSegmentsBefore(segment); err != nil {
+		t.Fatalf("RemoveSegmentsBefore: %v", err)
+	}
+	segments, _ := listSegments(path)
+	if !slices.Equal(segments, []uint64{segment}) {
+		t.Errorf("segments = %v, want [%d]", segments, segment)
+	}
+	if got, _ := readPaths(t, path, 0); !slices.Equal(got, []string{"/c"}) {
+		t.Errorf("replayed %v, want [/c]", got)
+	}
+}
+
+func TestWALLegacyMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	var legacy []byte
+	for _, p := range []string{"/old1", "/old2"} {
+		data, _ := json.Marshal(CreateFileData{Path: p})
+		line, _ := json.Marshal(Entry{Op: OpCreateFile, Data: data})
+		legacy = append(legacy, line...)
+		legacy = append(legacy, '\n')
+	}
+	// Lines were unchecked; a malformed one is skipped as before
+	legacy = append(legacy, "{not json\n\n"...)
+	if err := os.WriteFile(path, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := openTestWAL(t, path)
+	logTestFiles(t, w, "/new")
+
+	segments, _ := listSegments(path)
+	if want := []uint64{0, 1}; !slices.Equal(segments, want) {
+		t.Fatalf("segments = %v, want %v", segments, want)
+	}
+	got, err := readPaths(t, path, 0)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := []string{"/old1", "/old2", "/new"}; !slices.Equal(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+
+	// Reopening appends to the segment, leaving the legacy file for replay
+	w.Close()
+	w = openTestWAL(t, path)
+	logTestFiles(t, w, "/newer")
+	if got, _ := readPaths(t, path, 0); len(got) != 4 {
+		t.Errorf("replayed %v after reopen, want 4 entries", got)
+	}
+
+	// The first snapshot covers the legacy file and removes it
+	if err := w.RemoveSegmentsBefore(1); err != nil {
+		t.Fatalf("RemoveSegmentsBefore: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("legacy WAL still present: %v", err)
+	}
+	if got, _ := readPaths(t, path, 0); !slices.Equal(got, []string{"/new", "/newer"}) {
+		t.Errorf("replayed %v, want [/new /newer]", got)
+	}
+}
@@ -0,0 +1,488 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/master/wal"
+)
+
+// EventType is the kind of change a namespace watcher is told about
+type EventType string
+
+const (
+	EventCreate     EventType = "CREATE"     // File created, restored from the trash or snapshotted into place
+	EventCommit     EventType = "COMMIT"     // Data committed to one of the file's chunks
+	EventRename     EventType = "RENAME"     // File moved from Path to NewPath
+	EventDelete     EventType = "DELETE"     // File deleted or moved to the trash
+	EventAttributes EventType = "ATTRIBUTES" // File attributes changed
+	EventBookmark   EventType = "BOOKMARK"   // No change; the watcher's position moved on
+)
+
+// DefaultWatchHistory is how many events are kept in memory for watchers resuming
+// after a disconnect; older ones are read back from the WAL
+const DefaultWatchHistory = 10000
+
+// watchBookmarkInterval is how often a watcher whose filter matched nothing is
+// sent its position, so its resume token doesn't fall out of the history
+const watchBookmarkInterval = 30 * time.Second
+
+// ErrWatchExpired is returned when a resume token is from before the last snapshot or
+// import, or ahead of this master; the watcher must list the namespace again and start
+// a new watch
+var ErrWatchExpired = errors.New("resume token is outside the watch history")
+
+// ErrWatchClosed is returned to watchers when the master shuts down; they resume on
+// the restarted master or another replica
+var ErrWatchClosed = errors.New("master is shutting down")
+
+// NamespaceEvent is a change to the files of a namespace, numbered by the WAL
+// entry that made it
+type NamespaceEvent struct {
+	Seq         uint64
+	Type        EventType
+	Namespace   string
+	Path        string // Empty when the change covers the whole namespace
+	NewPath     string // Renames only
+	ChunkHandle string // Commits only
+	ChunkSize   uint64 // Commits only: committed size of the chunk
+}
+
+// matches reports whether a watcher of namespace and prefix should see the event.
+// Renames match on either side, so a watcher sees files leave its prefix
+func (e NamespaceEvent) matches(namespace, prefix string) bool {
+	if e.Namespace != namespace {
+		return false
+	}
+	return e.Path == "" || strings.HasPrefix(e.Path, prefix) ||
+		(e.Type == EventRename && strings.HasPrefix(e.NewPath, prefix))
+}
+
+// watchHub keeps the most recent events for watchers to read from, and reads older
+// ones back from the WAL on disk. Entries are published once applied to memory, in
+// log order, so a watcher that sees an event can read the change it describes.
+type watchHub struct {
+	mu      sync.Mutex
+	events  []NamespaceEvent // Oldest first
+	limit   int
+	floor   uint64         // Events at or below floor are not in memory
+	seq     uint64         // Every entry up to here is applied and its events published
+	written uint64         // Last WAL entry written
+	pending []pendingEntry // Entries written but not published yet, in log order
+	changed chan struct{}  // Closed and replaced whenever seq moves
+	closed  bool
+
+	wal     *wal.WAL
+	history watchHistory
+}
+
+// watchHistory says where the WAL on disk holds every entry after floor
+type watchHistory struct {
+	segment    uint64 // First segment to read
+	floor      uint64
+	replicated bool // Entries are numbered by consensus index; unnumbered ones predate replication
+}
+
+// pendingEntry is a WAL entry with events that isn't applied to memory yet
+type pendingEntry struct {
+	seq     uint64
+	events  []NamespaceEvent
+	reset   bool // Snapshot import: the whole namespace was replaced
+	applied bool
+}
+
+// closedChan is returned to watchers that should read again straight away
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+func newWatchHub(limit int) *watchHub {
+	return &watchHub{limit: limit, changed: make(chan struct{})}
+}
+
+// reset forgets every event; watchers from before seq must start over
+func (h *watchHub) reset(seq uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.resetLocked(seq)
+	h.pending = nil
+	h.written = seq
+	h.notifyLocked()
+}
+
+// resetLocked must be called with mu held
+func (h *watchHub) resetLocked(seq uint64) {
+	h.events = nil
+	h.floor = seq
+	h.seq = seq
+}
+
+// setHistory records where the WAL on disk starts, after a snapshot compacts it
+func (h *watchHub) setHistory(history watchHistory) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.history = history
+}
+
+// logged notes a durable WAL entry at seq. Its events wait for applied, and so
+// do the entries after it. Called under the WAL lock.
+func (h *watchHub) logged(seq uint64, entry wal.Entry) {
+	events := eventsFromEntry(entry)
+	reset := entry.Op == wal.OpImportSnapshot
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.written = seq
+	if len(events) == 0 && !reset {
+		if len(h.pending) == 0 {
+			h.seq = max(h.seq, seq)
+			h.notifyLocked()
+		}
+		return
+	}
+	h.pending = append(h.pending, pendingEntry{seq: seq, events: events, reset: reset})
+}
+
+// applied publishes the events of the entry at seq once it and every entry
+// before it are applied to memory
+func (h *watchHub) applied(seq uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	found := false
+	for i := range h.pending {
+		if h.pending[i].seq == seq {
+			h.pending[i].applied = true
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+
+	published := 0
+	for _, p := range h.pending {
+		if !p.applied {
+			break
+		}
+		published++
+		if p.reset {
+			h.resetLocked(p.seq)
+			continue
+		}
+		for i := range p.events {
+			p.events[i].Seq = p.seq
+		}
+		h.events = append(h.events, p.events...)
+		h.seq = max(h.seq, p.seq)
+	}
+	if published == 0 {
+		return
+	}
+	h.pending = h.pending[published:]
+	if len(h.pending) == 0 {
+		h.seq = max(h.seq, h.written)
+	} else {
+		h.seq = max(h.seq, h.pending[0].seq-1)
+	}
+	h.trimLocked()
+	h.notifyLocked()
+}
+
+// setLimit changes how many events are kept in memory
+func (h *watchHub) setLimit(limit int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.limit = limit
+	h.trimLocked()
+}
+
+// trimLocked drops the oldest events over the limit, whole entries at a time
+// Must be called with mu held
+func (h *watchHub) trimLocked() {
+	drop := 0
+	for len(h.events)-drop > h.limit {
+		h.floor = h.events[drop].Seq
+		for drop < len(h.events) && h.events[drop].Seq <= h.floor {
+			drop++
+		}
+	}
+	h.events = h.events[drop:]
+}
+
+// notifyLocked wakes every waiting watcher
+// Must be called with mu held
+func (h *watchHub) notifyLocked() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// close ends every watch
+func (h *watchHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	h.notifyLocked()
+}
+
+// current returns the position every published entry is at or below
+func (h *watchHub) current() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
+
+// since returns the events after the given sequence, the sequence they run up to,
+// and a channel closed when more arrive. Events no longer in memory are read from
+// the WAL, a batch at a time.
+func (h *watchHub) since(after uint64) ([]NamespaceEvent, uint64, <-chan struct{}, error) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, 0, nil, ErrWatchClosed
+	}
+	if after > h.seq {
+		h.mu.Unlock()
+		return nil, 0, nil, ErrWatchExpired
+	}
+	if after >= h.floor {
+		defer h.mu.Unlock()
+		i := sort.Search(len(h.events), func(i int) bool { return h.events[i].Seq > after })
+		events := append([]NamespaceEvent(nil), h.events[i:]...)
+		return events, h.seq, h.changed, nil
+	}
+	history, upTo, limit := h.history, h.floor, h.limit
+	h.mu.Unlock()
+
+	if after < history.floor || h.wal == nil {
+		return nil, 0, nil, ErrWatchExpired
+	}
+	events, seq, err := h.readHistory(history, after, upTo, limit)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return events, seq, closedChan, nil
+}
+
+// readHistory reads the events after the given sequence and up to upTo from the WAL
+// on disk, stopping at the first entry that makes limit events. Returns them and the
+// sequence they run up to.
+func (h *watchHub) readHistory(history watchHistory, after, upTo uint64, limit int) ([]NamespaceEvent, uint64, error) {
+	r, err := h.wal.Reader(history.segment)
+	if err != nil {
+		return nil, 0, err
+	}
+	if r == nil {
+		return nil, 0, ErrWatchExpired
+	}
+	defer r.Close()
+
+	var events []NamespaceEvent
+	prev := history.floor
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			return events, upTo, nil
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			// Compacted away by a snapshot since the read started
+			return nil, 0, ErrWatchExpired
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if history.replicated && entry.Index <= prev {
+			// Covered by the snapshot, written again after a restart, or from before replication
+			continue
+		}
+		seq := wal.Position(entry, prev)
+		prev = seq
+		if seq <= after {
+			continue
+		}
+		if seq > upTo {
+			return events, upTo, nil
+		}
+		if entry.Op == wal.OpImportSnapshot {
+			return nil, 0, ErrWatchExpired
+		}
+		for _, event := range eventsFromEntry(entry) {
+			event.Seq = seq
+			events = append(events, event)
+		}
+		if seq == upTo || (len(events) > 0 && len(events) >= limit) {
+			return events, seq, nil
+		}
+	}
+}
+
+// SetWatchHistory sets how many events are kept in memory for resuming watchers;
+// older ones are read back from the WAL
+func (m *Master) SetWatchHistory(limit int) {
+	m.watch.setLimit(limit)
+}
+
+// CloseWatches ends every WatchNamespace stream, so watchers don't hold a graceful
+// shutdown open
+func (m *Master) CloseWatches() {
+	m.watch.close()
+}
+
+// eventsFromEntry returns the namespace events a WAL entry makes
+func eventsFromEntry(entry wal.Entry) []NamespaceEvent {
+	event := func(t EventType, namespace, path string) []NamespaceEvent {
+		return []NamespaceEvent{{Type: t, Namespace: normalizeNamespace(namespace), Path: path}}
+	}
+
+	switch entry.Op {
+	case wal.OpCreateFile:
+		var data wal.CreateFileData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventCreate, data.Namespace, data.Path)
+		}
+
+	case wal.OpCommitChunk:
+		var data wal.CommitChunkData
+		if json.Unmarshal(entry.Data, &data) == nil && data.Path != "" {
+			events := event(EventCommit, data.Namespace, data.Path)
+			events[0].ChunkHandle = data.ChunkHandle
+			events[0].ChunkSize = data.Size
+			return events
+		}
+
+	case wal.OpRenameFile:
+		var data wal.RenameFileData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			events := event(EventRename, data.Namespace, data.OldPath)
+			events[0].NewPath = data.NewPath
+			return events
+		}
+
+	case wal.OpDeleteFile:
+		var data wal.DeleteFileData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventDelete, data.Namespace, data.Path)
+		}
+
+	case wal.OpTrashFile:
+		var data wal.TrashFileData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventDelete, data.Namespace, data.Path)
+		}
+
+	case wal.OpDeleteNamespace:
+		var data wal.DeleteNamespaceData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventDelete, data.Namespace, "")
+		}
+
+	case wal.OpTrashNamespace:
+		var data wal.TrashNamespaceData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventDelete, data.Namespace, "")
+		}
+
+	case wal.OpRestoreFile:
+		var data wal.RestoreFileData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventCreate, data.Namespace, data.Path)
+		}
+
+	case wal.OpSnapshotFile:
+		var data wal.SnapshotFileData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			namespace := data.DestNamespace
+			if namespace == "" {
+				namespace = data.Namespace
+			}
+			return event(EventCreate, namespace, data.DestPath)
+		}
+
+	case wal.OpSnapshotNamespace:
+		var data wal.SnapshotNamespaceData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventCreate, data.DestNamespace, "")
+		}
+
+	case wal.OpSetFileAttributes:
+		var data wal.SetFileAttributesData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			return event(EventAttributes, data.Namespace, data.Path)
+		}
+
+	case wal.OpTransact:
+		var data wal.TransactData
+		if json.Unmarshal(entry.Data, &data) == nil {
+			var events []NamespaceEvent
+			for _, sub := range data.Entries {
+				events = append(events, eventsFromEntry(sub)...)
+			}
+			return events
+		}
+	}
+	return nil
+}
+
+// WatchNamespace sends every change to files under prefix in a namespace until ctx ends
+// or send fails. Events after the resume token are sent first; a zero token starts
+// from now. The first event is a bookmark with the starting position, and later
+// bookmarks keep an idle watcher's position current.
+func (m *Master) WatchNamespace(ctx context.Context, namespace, prefix string, after uint64, send func(NamespaceEvent) error) error {
+	namespace = normalizeNamespace(namespace)
+	if after == 0 {
+		after = m.watch.current()
+	}
+	events, seq, changed, err := m.watch.since(after)
+	if err != nil {
+		return err
+	}
+	if err := send(NamespaceEvent{Seq: after, Type: EventBookmark, Namespace: namespace}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watchBookmarkInterval)
+	defer ticker.Stop()
+
+	sent := after
+	for {
+		for _, event := range events {
+			if !event.matches(namespace, prefix) {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+			sent = event.Seq
+		}
+		after = seq
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-ticker.C:
+			if after > sent {
+				if err := send(NamespaceEvent{Seq: after, Type: EventBookmark, Namespace: namespace}); err != nil {
+					return err
+				}
+				sent = after
+			}
+		}
+
+		events, seq, changed, err = m.watch.since(after)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/master/wal"
+)
+
+// watchPaths resumes a watch of namespace ns after a token and returns the paths
+// of the next n events
+func watchPaths(t *testing.T, m *Master, after uint64, n int) ([]string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var paths []string
+	err := m.WatchNamespace(ctx, "ns", "", after, func(e NamespaceEvent) error {
+		if e.Type == EventBookmark {
+			return nil
+		}
+		paths = append(paths, e.Path)
+		if len(paths) == n {
+			cancel()
+		}
+		return nil
+	})
+	if len(paths) == n {
+		return paths, nil
+	}
+	return paths, err
+}
+
+func TestWatchResume(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int // Events kept in memory
+		restart bool
+	}{
+		{name: "from memory", limit: DefaultWatchHistory},
+		{name: "from the WAL", limit: 1},
+		{name: "after a restart", limit: DefaultWatchHistory, restart: true},
+		{name: "from the WAL after a restart", limit: 1, restart: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			m, err := NewMaster(path)
+			if err != nil {
+				t.Fatalf("NewMaster: %v", err)
+			}
+			m.SetWatchHistory(tt.limit)
+			createTestFiles(t, m, "ns", "/f0")
+			token := m.watch.current()
+			var want []string
+			for i := 1; i < 6; i++ {
+				want = append(want, fmt.Sprintf("/f%d", i))
+			}
+			createTestFiles(t, m, "ns", want...)
+			createTestFiles(t, m, "other", "/x")
+
+			if tt.restart {
+				m.Close()
+				if m, err = NewMaster(path); err != nil {
+					t.Fatalf("NewMaster after restart: %v", err)
+				}
+				m.SetWatchHistory(tt.limit)
+			}
+			t.Cleanup(func() { m.Close() })
+
+			got, err := watchPaths(t, m, token, len(want))
+			if err != nil {
+				t.Fatalf("WatchNamespace: %v (got %v)", err, got)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("resumed watch got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWatchResumeAcrossSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	m, err := NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	createTestFiles(t, m, "ns", "/a")
+	before := m.watch.current()
+	createTestFiles(t, m, "ns", "/b")
+	if err := m.TakeSnapshot(); err != nil {
+		t.Fatalf("TakeSnapshot: %v", err)
+	}
+	after := m.watch.current()
+	createTestFiles(t, m, "ns", "/c")
+	m.Close()
+
+	m, err = NewMaster(path)
+	if err != nil {
+		t.Fatalf("NewMaster after restart: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	m.SetWatchHistory(0)
+
+	if _, err := watchPaths(t, m, before, 1); !errors.Is(err, ErrWatchExpired) {
+		t.Errorf("resume from before the snapshot: err = %v, want ErrWatchExpired", err)
+	}
+	got, err := watchPaths(t, m, after, 1)
+	if err != nil {
+		t.Fatalf("resume from the snapshot: %v", err)
+	}
+	if !slices.Equal(got, []string{"/c"}) {
+		t.Errorf("resume from the snapshot got %v, want [/c]", got)
+	}
+}
+
+// Entries are published once applied, in log order, even when applied out of order
+func TestWatchHubPublishesAppliedEntries(t *testing.T) {
+	create := func(path string) wal.Entry {
+		return wal.NewEntry(wal.OpCreateFile, wal.CreateFileData{Path: path, Namespace: "ns"})
+	}
+	paths := func(events []NamespaceEvent) []string {
+		var paths []string
+		for _, e := range events {
+			paths = append(paths, fmt.Sprintf("%d:%s", e.Seq, e.Path))
+		}
+		return paths
+	}
+
+	h := newWatchHub(DefaultWatchHistory)
+	h.logged(1, create("/a"))
+	h.logged(2, wal.NewEntry(wal.OpSetQuota, wal.SetQuotaData{Namespace: "ns"}))
+	h.logged(3, create("/b"))
+	h.applied(3)
+
+	events, seq, _, err := h.since(0)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+	if len(events) != 0 || seq != 0 {
+		t.Errorf("before /a is applied: events %v up to %d, want none up to 0", paths(events), seq)
+	}
+
+	h.applied(1)
+	events, seq, _, err = h.since(0)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+	if got, want := paths(events), []string{"1:/a", "3:/b"}; !slices.Equal(got, want) || seq != 3 {
+		t.Errorf("after both are applied: events %v up to %d, want %v up to 3", got, seq, want)
+	}
+
+	// Entries without events move the position once nothing is pending
+	h.logged(4, wal.NewEntry(wal.OpSetQuota, wal.SetQuotaData{Namespace: "ns"}))
+	if got := h.current(); got != 4 {
+		t.Errorf("current = %d, want 4", got)
+	}
+}
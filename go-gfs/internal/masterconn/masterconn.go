@@ -0,0 +1,199 @@
+// Package masterconn connects to a replicated master and follows its leader.
+//
+// A Conn takes one or more master addresses and implements grpc.ClientConnInterface,
+// so generated clients can use it in place of a *grpc.ClientConn. Calls go to the
+// current leader; when a replica answers that it is not the leader, the call is retried
+// against the leader it names, or the next known replica while an election is running.
+package masterconn
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LeaderKey is the trailer a follower sets to the leader's address when it rejects a call
+const LeaderKey = "gfs-master-leader"
+
+// maxAttempts and retryBackoff bound how long a call waits for a leader (about an election)
+const (
+	maxAttempts  = 20
+	retryBackoff = 250 * time.Millisecond
+)
+
+// NotLeaderError builds the error a follower returns, attaching the leader hint when known
+func NotLeaderError(ctx context.Context, leaderAddr string) error {
+	if leaderAddr != "" {
+		grpc.SetTrailer(ctx, metadata.Pairs(LeaderKey, leaderAddr))
+	}
+	return status.Error(codes.Unavailable, "not the master leader")
+}
+
+// Conn is a connection to whichever master replica is currently the leader
+type Conn struct {
+	dialOpts []grpc.DialOption
+
+	mu      sync.Mutex
+	addrs   []string
+	current string
+	conns   map[string]*grpc.ClientConn
+}
+
+// Dial creates a Conn for a comma-separated list of master addresses.
+// Connections are opened lazily; a single address behaves like a plain gRPC connection.
+func Dial(addrs string, opts ...grpc.DialOption) (*Conn, error) {
+	var list []string
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			list = append(list, addr)
+		}
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no master address given")
+	}
+
+	c := &Conn{
+		dialOpts: opts,
+		addrs:    list,
+		current:  list[0],
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+	if _, err := c.conn(list[0]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Leader returns the address calls are currently sent to
+func (c *Conn) Leader() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+// Close closes every underlying connection
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for addr, cc := range c.conns {
+		if err := cc.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.conns, addr)
+	}
+	return firstErr
+}
+
+// Invoke performs a unary call on the leader, following redirects
+func (c *Conn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		addr := c.Leader()
+		cc, dialErr := c.conn(addr)
+		if dialErr != nil {
+			return dialErr
+		}
+
+		var trailer metadata.MD
+		callOpts := append(opts[:len(opts):len(opts)], grpc.Trailer(&trailer))
+		err = cc.Invoke(ctx, method, args, reply, callOpts...)
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+
+		hint := ""
+		if v := trailer.Get(LeaderKey); len(v) > 0 {
+			hint = v[0]
+		}
+		c.redirect(addr, hint)
+
+		// Without a hint the group may be mid-election; give it a moment
+		if hint == "" {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(retryBackoff):
+			}
+		}
+	}
+	return err
+}
+
+// NewStream opens a stream on the current leader. Streams aren't redirected;
+// callers reopen them after an Unavailable error.
+func (c *Conn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	addr := c.Leader()
+	cc, err := c.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := cc.NewStream(ctx, desc, method, opts...)
+	if status.Code(err) == codes.Unavailable {
+		c.redirect(addr, "")
+	}
+	return stream, err
+}
+
+// redirect moves to the hinted leader, or to the replica after failed
+func (c *Conn) redirect(failed, hint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != failed {
+		// Another call already moved on
+		return
+	}
+
+	if hint != "" {
+		known := false
+		for _, addr := range c.addrs {
+			if addr == hint {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.addrs = append(c.addrs, hint)
+		}
+		c.current = hint
+	} else {
+		for i, addr := range c.addrs {
+			if addr == failed {
+				c.current = c.addrs[(i+1)%len(c.addrs)]
+				break
+			}
+		}
+	}
+
+	if c.current != failed {
+		slog.Debug("switching master", "from", failed, "to", c.current)
+	}
+}
+
+// conn returns the connection for addr, creating it on first use
+func (c *Conn) conn(addr string) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cc, ok := c.conns[addr]; ok {
+		return cc, nil
+	}
+	cc, err := grpc.NewClient(addr, c.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.conns[addr] = cc
+	return cc, nil
+}
+
+var _ grpc.ClientConnInterface = (*Conn)(nil)
@@ -0,0 +1,283 @@
+// Package mtls loads mutual TLS credentials for the master, chunkservers and
+// SDK clients.
+//
+// Every service has its own certificate and key, signed by a CA all of them
+// trust. Each side of a connection verifies the other against that CA.
+// The files are checked for changes at most every few seconds when a new
+// connection is made, so rotated certificates take effect without a restart;
+// existing connections keep the certificate they were opened with.
+//
+// A certificate's subject organizational units (OU) name the roles it was
+// issued for. Any certificate signed by the CA may connect, but only ones with
+// RoleServer may call the chunkserver and replica-to-replica methods, and only
+// ones with RoleAdmin may change cluster settings.
+//
+// A nil *Source stands for plaintext, so callers can pass one through
+// unconditionally and keep working when TLS is not configured.
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Roles a certificate can be issued for, as subject organizational units
+const (
+	RoleServer = "gfs-server" // Masters and chunkservers
+	RoleAdmin  = "gfs-admin"  // Operators changing cluster settings
+)
+
+// reloadCheckInterval bounds how often the files are stat'ed for changes
+const reloadCheckInterval = 5 * time.Second
+
+// Source is a certificate, key and CA bundle kept in sync with their files
+type Source struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes [3]time.Time
+	checked  time.Time
+}
+
+// Load reads the certificate, key and CA bundle. It returns nil, and no
+// error, when none of the files are given.
+func Load(certFile, keyFile, caFile string) (*Source, error) {
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, errors.New("TLS needs a certificate, a key and a CA file")
+	}
+
+	s := &Source{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	s.checked = time.Now()
+	return s, nil
+}
+
+// loadLocked reads all three files; on error the previous credentials are kept
+func (s *Source) loadLocked() error {
+	modTimes, err := s.statLocked()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(s.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in CA file %s", s.caFile)
+	}
+
+	s.cert = &cert
+	s.roots = roots
+	s.modTimes = modTimes
+	return nil
+}
+
+func (s *Source) statLocked() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, name := range []string{s.certFile, s.keyFile, s.caFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// current returns the credentials, reloading them first if the files changed
+func (s *Source) current() (*tls.Certificate, *x509.CertPool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checked) < reloadCheckInterval {
+		return s.cert, s.roots
+	}
+	s.checked = time.Now()
+
+	modTimes, err := s.statLocked()
+	if err != nil || modTimes == s.modTimes {
+		return s.cert, s.roots
+	}
+	// A rotation may be caught half written; the next check retries
+	if err := s.loadLocked(); err != nil {
+		slog.Error("failed to reload TLS credentials, keeping the old ones", "cert", s.certFile, "error", err)
+	} else {
+		slog.Info("reloaded TLS credentials", "cert", s.certFile)
+	}
+	return s.cert, s.roots
+}
+
+// ServerConfig returns a config for accepting one connection. Clients must
+// present a certificate signed by the CA.
+func (s *Source) ServerConfig() *tls.Config {
+	cert, roots := s.current()
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+		ClientCAs:    roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+}
+
+// ClientConfig returns a config for dialing serverName, which the server's
+// certificate must be valid for
+func (s *Source) ClientConfig(serverName string) *tls.Config {
+	cert, roots := s.current()
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+		RootCAs:      roots,
+		ServerName:   serverName,
+	}
+}
+
+// Listener wraps ln so accepted connections use TLS; a nil Source returns ln
+func (s *Source) Listener(ln net.Listener) net.Listener {
+	if s == nil {
+		return ln
+	}
+	return tls.NewListener(ln, &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.ServerConfig(), nil
+		},
+	})
+}
+
+// DialContext connects to addr and completes the TLS handshake; a nil Source
+// dials plain TCP
+func (s *Source) DialContext(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+	if s == nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsDialer := tls.Dialer{NetDialer: dialer, Config: s.ClientConfig(host)}
+	return tlsDialer.DialContext(ctx, "tcp", addr)
+}
+
+// ServerCredentials returns gRPC server credentials; a nil Source is insecure
+func (s *Source) ServerCredentials() credentials.TransportCredentials {
+	if s == nil {
+		return insecure.NewCredentials()
+	}
+	return &transportCredentials{TransportCredentials: credentials.NewTLS(s.ServerConfig()), source: s}
+}
+
+// ClientCredentials returns gRPC client credentials; a nil Source is insecure
+func (s *Source) ClientCredentials() credentials.TransportCredentials {
+	if s == nil {
+		return insecure.NewCredentials()
+	}
+	return &transportCredentials{TransportCredentials: credentials.NewTLS(s.ClientConfig("")), source: s}
+}
+
+// transportCredentials builds the TLS config per handshake, so gRPC picks up
+// reloaded files and checks the name of the server actually dialed
+type transportCredentials struct {
+	credentials.TransportCredentials
+	source *Source
+}
+
+func (c *transportCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	host, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		host = authority
+	}
+	return credentials.NewTLS(c.source.ClientConfig(host)).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *transportCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.source.ServerConfig()).ServerHandshake(conn)
+}
+
+func (c *transportCredentials) Clone() credentials.TransportCredentials {
+	return &transportCredentials{TransportCredentials: c.TransportCredentials.Clone(), source: c.source}
+}
+
+// HasRole reports whether a certificate was issued for role
+func HasRole(cert *x509.Certificate, role string) bool {
+	return slices.Contains(cert.Subject.OrganizationalUnit, role)
+}
+
+// peerHasRole reports whether the peer of a gRPC call presented a certificate issued for role
+func peerHasRole(ctx context.Context, role string) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return false
+	}
+	return HasRole(info.State.PeerCertificates[0], role)
+}
+
+// authorize checks a call against the role its method requires
+func (s *Source) authorize(ctx context.Context, method string, requiredRole func(method string) string) error {
+	// Plaintext peers have no identity to check
+	if s == nil {
+		return nil
+	}
+	role := requiredRole(method)
+	if role == "" || peerHasRole(ctx, role) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "%s requires a %s certificate", method, role)
+}
+
+// UnaryRoleInterceptor rejects calls from peers whose certificate lacks the role
+// requiredRole returns for the method; "" lets any peer call it. A nil Source
+// checks nothing.
+func (s *Source) UnaryRoleInterceptor(requiredRole func(method string) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := s.authorize(ctx, info.FullMethod, requiredRole); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRoleInterceptor is UnaryRoleInterceptor for streams
+func (s *Source) StreamRoleInterceptor(requiredRole func(method string) string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := s.authorize(ss.Context(), info.FullMethod, requiredRole); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,211 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/chunkreplication"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testCA signs certificates into a directory
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	ca := &testCA{dir: t.TempDir(), cert: cert, key: key}
+	writePEM(t, filepath.Join(ca.dir, "ca.crt"), "CERTIFICATE", der)
+	return ca
+}
+
+// issue signs a certificate for 127.0.0.1 with the given roles and loads it
+func (ca *testCA) issue(t *testing.T, name string, roles ...string) *Source {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name, OrganizationalUnit: roles},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	certFile := filepath.Join(ca.dir, name+".crt")
+	keyFile := filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	source, err := Load(certFile, keyFile, filepath.Join(ca.dir, "ca.crt"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return source
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+// replicator answers every call, so only authorization can fail one
+type replicator struct {
+	pb.UnimplementedReplicatorServer
+}
+
+func (replicator) RecvCommit(ctx context.Context, req *pb.Commit) (*pb.ReplicationResponse, error) {
+	return &pb.ReplicationResponse{Success: true}, nil
+}
+
+func (replicator) FetchChunk(req *pb.Fetch, stream pb.Replicator_FetchChunkServer) error {
+	return stream.Send(&pb.ReplicationData{})
+}
+
+// startReplicator serves the replication plane over TLS, requiring role for commits
+// and RoleServer for everything else
+func startReplicator(t *testing.T, source *Source, role string) string {
+	t.Helper()
+	requiredRole := func(method string) string {
+		if method == pb.Replicator_RecvCommit_FullMethodName {
+			return role
+		}
+		return RoleServer
+	}
+	server := grpc.NewServer(
+		grpc.Creds(source.ServerCredentials()),
+		grpc.UnaryInterceptor(source.UnaryRoleInterceptor(requiredRole)),
+		grpc.StreamInterceptor(source.StreamRoleInterceptor(requiredRole)),
+	)
+	pb.RegisterReplicatorServer(server, replicator{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+// call makes a unary and a streaming call with source's certificate
+func call(t *testing.T, source *Source, addr string) (unary, stream error) {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(source.ClientCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	client := pb.NewReplicatorClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, unary = client.RecvCommit(ctx, &pb.Commit{})
+	fetch, err := client.FetchChunk(ctx, &pb.Fetch{})
+	if err != nil {
+		return unary, err
+	}
+	_, stream = fetch.Recv()
+	return unary, stream
+}
+
+func TestRoleInterceptors(t *testing.T) {
+	ca := newTestCA(t)
+	server := ca.issue(t, "cs1", RoleServer)
+	other := ca.issue(t, "cs2", RoleServer)
+	client := ca.issue(t, "client")
+	admin := ca.issue(t, "admin", RoleAdmin)
+	both := ca.issue(t, "ops", RoleAdmin, RoleServer)
+
+	tests := []struct {
+		name       string
+		caller     *Source
+		commitRole string // Role RecvCommit requires
+		wantUnary  bool
+		wantStream bool
+	}{
+		{name: "server", caller: other, commitRole: RoleServer, wantUnary: true, wantStream: true},
+		{name: "client", caller: client, commitRole: RoleServer},
+		{name: "admin on a server method", caller: admin, commitRole: RoleServer},
+		{name: "client on an open method", caller: client, commitRole: "", wantUnary: true},
+		{name: "admin", caller: admin, commitRole: RoleAdmin, wantUnary: true},
+		{name: "server on an admin method", caller: other, commitRole: RoleAdmin, wantStream: true},
+		{name: "several roles", caller: both, commitRole: RoleAdmin, wantUnary: true, wantStream: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startReplicator(t, server, tt.commitRole)
+			unary, stream := call(t, tt.caller, addr)
+			for _, c := range []struct {
+				kind string
+				err  error
+				want bool
+			}{{"unary", unary, tt.wantUnary}, {"stream", stream, tt.wantStream}} {
+				if c.want && c.err != nil {
+					t.Errorf("%s call: %v", c.kind, c.err)
+				}
+				if !c.want && status.Code(c.err) != codes.PermissionDenied {
+					t.Errorf("%s call: err = %v, want PermissionDenied", c.kind, c.err)
+				}
+			}
+		})
+	}
+}
+
+// Without TLS there are no identities, so nothing is checked
+func TestRoleInterceptorsPlaintext(t *testing.T) {
+	var source *Source
+	addr := startReplicator(t, source, RoleAdmin)
+	if unary, stream := call(t, source, addr); unary != nil || stream != nil {
+		t.Errorf("plaintext calls failed: %v, %v", unary, stream)
+	}
+}
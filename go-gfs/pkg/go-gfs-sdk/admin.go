@@ -0,0 +1,83 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// DrainChunkServer stops placing new replicas on a chunkserver and moves its
+// existing replicas elsewhere. Poll GetDrainStatus until it reports Complete
+// before removing the server.
+func (c *Client) DrainChunkServer(ctx context.Context, serverID string) error {
+	return c.drainChunkServer(ctx, serverID, false)
+}
+
+// CancelDrain returns a draining chunkserver to service. Replicas already moved stay moved.
+func (c *Client) CancelDrain(ctx context.Context, serverID string) error {
+	return c.drainChunkServer(ctx, serverID, true)
+}
+
+func (c *Client) drainChunkServer(ctx context.Context, serverID string, cancel bool) error {
+	resp, err := c.master.DrainChunkServer(ctx, &pb.DrainChunkServerRequest{
+		ServerId: serverID,
+		Cancel:   cancel,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("drain chunkserver failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// GetDrainStatus returns drain progress for a chunkserver, or for every draining
+// chunkserver when serverID is empty.
+func (c *Client) GetDrainStatus(ctx context.Context, serverID string) ([]*pb.DrainStatus, error) {
+	resp, err := c.master.GetDrainStatus(ctx, &pb.GetDrainStatusRequest{
+		ServerId: serverID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("get drain status failed: %s", resp.Message)
+	}
+	return resp.Servers, nil
+}
+
+// StartRebalance moves replicas from the fullest chunkservers to the emptiest until
+// their disk fill ratios differ by at most threshold (0 uses the master default of 0.10).
+func (c *Client) StartRebalance(ctx context.Context, threshold float64) error {
+	resp, err := c.master.Rebalance(ctx, &pb.RebalanceRequest{
+		Threshold: threshold,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("start rebalance failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// StopRebalance stops scheduling rebalance moves. Moves already issued complete.
+func (c *Client) StopRebalance(ctx context.Context) error {
+	resp, err := c.master.Rebalance(ctx, &pb.RebalanceRequest{
+		Cancel: true,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("stop rebalance failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// GetRebalanceStatus returns rebalance progress and the current disk fill of each chunkserver.
+func (c *Client) GetRebalanceStatus(ctx context.Context) (*pb.GetRebalanceStatusResponse, error) {
+	return c.master.GetRebalanceStatus(ctx, &pb.GetRebalanceStatusRequest{})
+}
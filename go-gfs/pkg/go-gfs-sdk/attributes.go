@@ -0,0 +1,77 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// Well-known file attribute keys. Any other key can be used for custom tags.
+const (
+	// AttrContentType is the MIME type of the file's contents.
+	AttrContentType = "content-type"
+	// AttrOwner is the user or service that owns the file.
+	AttrOwner = "owner"
+	// AttrSHA256 is the hex-encoded SHA-256 digest of the file's contents.
+	AttrSHA256 = "sha256"
+	// AttrEncryption marks an encrypted file with its cipher and block size,
+	// such as "aes-256-gcm/65536". See WithEncryption.
+	AttrEncryption = "encryption"
+	// AttrEncryptionKey is an encrypted file's data key, wrapped by the key
+	// provider and base64-encoded.
+	AttrEncryptionKey = "encryption-key"
+	// AttrEncryptionKeyID names the key provider key that wrapped the data key.
+	AttrEncryptionKeyID = "encryption-key-id"
+)
+
+// CreateFileWithAttributes creates a new file entry with user-defined key/value attributes.
+// The master limits a file to 64 attributes and 16 KiB of keys and values.
+func (c *Client) CreateFileWithAttributes(ctx context.Context, path, namespace string, attributes map[string]string) (*pb.FileInfoResponse, error) {
+	attributes, err := c.encryptionAttributes(ctx, attributes)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.master.CreateFile(ctx, &pb.CreateFileRequest{
+		Path:       path,
+		Namespace:  normalizeNamespace(namespace),
+		Attributes: attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.QuotaExceeded {
+		return nil, fmt.Errorf("create file failed: %w", &quotaError{resp.Message})
+	}
+	if resp.PreconditionFailed {
+		return nil, fmt.Errorf("create file failed: %w", &preconditionError{resp.Message})
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("create file failed: %s", resp.Message)
+	}
+	return resp.File, nil
+}
+
+// SetFileAttributes adds or overwrites the attributes in set, then deletes the keys in remove.
+// Returns the file with its updated attributes.
+func (c *Client) SetFileAttributes(ctx context.Context, path string, set map[string]string, remove ...string) (*pb.FileInfoResponse, error) {
+	return c.SetFileAttributesWithNamespace(ctx, path, "", set, remove...)
+}
+
+// SetFileAttributesWithNamespace changes the attributes of a file in a namespace.
+func (c *Client) SetFileAttributesWithNamespace(ctx context.Context, path, namespace string, set map[string]string, remove ...string) (*pb.FileInfoResponse, error) {
+	resp, err := c.master.SetFileAttributes(ctx, &pb.SetFileAttributesRequest{
+		Path:      path,
+		Namespace: normalizeNamespace(namespace),
+		Set:       set,
+		Remove:    remove,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("set file attributes failed: %s", resp.Message)
+	}
+	c.showPlainSize(resp.File)
+	return resp.File, nil
+}
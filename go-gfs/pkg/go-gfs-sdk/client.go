@@ -2,10 +2,13 @@ package gfs
 
 import (
 	"context"
+	"crypto/cipher"
 	"sync"
 	"time"
 
 	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/masterconn"
+	"eddisonso.com/go-gfs/internal/mtls"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -40,9 +43,19 @@ type clientConfig struct {
 	enableConnPool   bool
 	connPoolMaxIdle  int
 	connPoolIdleTime time.Duration
+	tlsCertFile      string
+	tlsKeyFile       string
+	tlsCAFile        string
+	retryPolicy      RetryPolicy
+	hedgeDelay       time.Duration
+	tracer           Tracer
+	metrics          Metrics
+	keyProvider      KeyProvider
 }
 
 // New creates a new SDK client connected to the master gRPC endpoint.
+// masterAddr may list several master replicas separated by commas;
+// requests are sent to the current leader and follow it across failovers.
 func New(ctx context.Context, masterAddr string, opts ...Option) (*Client, error) {
 	cfg := clientConfig{
 		dialOptions:     []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
@@ -51,13 +64,22 @@ func New(ctx context.Context, masterAddr string, opts ...Option) (*Client, error
 		readConcurrency: defaultReadConcurrency,
 		secretProvider:  DefaultSecretProvider,
 		replicaPicker:   DefaultReplicaPicker,
+		retryPolicy:     DefaultRetryPolicy,
 	}
 
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
-	conn, err := grpc.DialContext(ctx, masterAddr, cfg.dialOptions...)
+	tlsSource, err := mtls.Load(cfg.tlsCertFile, cfg.tlsKeyFile, cfg.tlsCAFile)
+	if err != nil {
+		return nil, err
+	}
+	if tlsSource != nil {
+		cfg.dialOptions = append(cfg.dialOptions, grpc.WithTransportCredentials(tlsSource.ClientCredentials()))
+	}
+
+	conn, err := masterconn.Dial(masterAddr, cfg.dialOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,19 +87,28 @@ func New(ctx context.Context, masterAddr string, opts ...Option) (*Client, error
 	client := &Client{
 		masterAddr:       masterAddr,
 		conn:             conn,
-		master:           pb.NewMasterClient(conn),
 		chunkTimeout:     cfg.chunkTimeout,
 		maxChunkSize:     cfg.maxChunkSize,
 		uploadBufferSize: cfg.uploadBufferSize,
 		readConcurrency:  cfg.readConcurrency,
 		secretProvider:   cfg.secretProvider,
 		replicaPicker:    cfg.replicaPicker,
+		retryPolicy:      cfg.retryPolicy,
+		hedgeDelay:       cfg.hedgeDelay,
+		tracer:           cfg.tracer,
+		metrics:          cfg.metrics,
+		keyProvider:      cfg.keyProvider,
+		tls:              tlsSource,
 		chunkCache:       make(map[fileKey]*chunkCache),
 		knownFiles:       make(map[fileKey]struct{}),
+		fileCiphers:      make(map[fileKey]*fileCipher),
+		dataKeys:         make(map[string]cipher.AEAD),
 	}
+	client.master = pb.NewMasterClient(&masterInvoker{Conn: conn, client: client})
 
 	if cfg.enableConnPool {
 		client.connPool = NewConnPool(cfg.connPoolMaxIdle, cfg.connPoolIdleTime)
+		client.connPool.tls = tlsSource
 	}
 
 	return client, nil
@@ -86,7 +117,7 @@ func New(ctx context.Context, masterAddr string, opts ...Option) (*Client, error
 // Client is the SDK entry point for interacting with Go-GFS.
 type Client struct {
 	masterAddr       string
-	conn             *grpc.ClientConn
+	conn             *masterconn.Conn
 	master           pb.MasterClient
 	chunkTimeout     time.Duration
 	maxChunkSize     int64
@@ -94,6 +125,15 @@ type Client struct {
 	readConcurrency  int
 	secretProvider   SecretProvider
 	replicaPicker    ReplicaPicker
+	retryPolicy      RetryPolicy
+	hedgeDelay       time.Duration // 0 disables hedged reads
+	tracer           Tracer
+	metrics          Metrics
+	keyProvider      KeyProvider  // nil stores new files unencrypted
+	tls              *mtls.Source // nil talks to chunkservers over plain TCP
+
+	// Data-plane grants issued by the master
+	grants grantCache
 
 	chunkCacheMu sync.RWMutex
 	chunkCache   map[fileKey]*chunkCache
@@ -102,6 +142,11 @@ type Client struct {
 	knownFilesMu sync.RWMutex
 	knownFiles   map[fileKey]struct{}
 
+	// Ciphers of encrypted files, and unwrapped data keys by wrapped key
+	cipherMu    sync.Mutex
+	fileCiphers map[fileKey]*fileCipher
+	dataKeys    map[string]cipher.AEAD
+
 	// Connection pool for chunkserver TCP connections (optional)
 	connPool *ConnPool
 }
@@ -158,6 +203,18 @@ func WithSecretProvider(provider SecretProvider) Option {
 	}
 }
 
+// WithTLS enables mutual TLS to the master and chunkservers. certFile and
+// keyFile are this client's certificate and key; caFile is the CA bundle the
+// servers' certificates must chain to. The files are reloaded when they change,
+// so rotated certificates are used for new connections.
+func WithTLS(certFile, keyFile, caFile string) Option {
+	return func(cfg *clientConfig) {
+		cfg.tlsCertFile = certFile
+		cfg.tlsKeyFile = keyFile
+		cfg.tlsCAFile = caFile
+	}
+}
+
 // WithReplicaPicker overrides the replica selection strategy for reads.
 func WithReplicaPicker(picker ReplicaPicker) Option {
 	return func(cfg *clientConfig) {
@@ -188,6 +245,58 @@ func WithConnectionPool(maxIdlePerHost int, idleTimeout time.Duration) Option {
 	}
 }
 
+// WithRetryPolicy replaces DefaultRetryPolicy for master calls and chunk writes.
+// A MaxAttempts of 1 disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *clientConfig) {
+		if policy.MaxAttempts < 1 {
+			policy.MaxAttempts = 1
+		}
+		cfg.retryPolicy = policy
+	}
+}
+
+// WithHedgedReads sends a chunk read to a second replica when the first has not
+// started returning data after delay. Whichever replica answers first serves the
+// read and the other is cancelled, so one busy chunkserver doesn't stall it.
+func WithHedgedReads(delay time.Duration) Option {
+	return func(cfg *clientConfig) {
+		if delay > 0 {
+			cfg.hedgeDelay = delay
+		}
+	}
+}
+
+// WithTracer starts a span around every master call and chunk transfer.
+func WithTracer(tracer Tracer) Option {
+	return func(cfg *clientConfig) {
+		cfg.tracer = tracer
+	}
+}
+
+// WithMetrics reports the timing, replicas and outcome of every master call and
+// chunk transfer.
+func WithMetrics(metrics Metrics) Option {
+	return func(cfg *clientConfig) {
+		cfg.metrics = metrics
+	}
+}
+
+// WithEncryption encrypts every file the client creates with its own data key,
+// wrapped by provider and stored in the file's attributes. File data is sealed
+// with AES-256-GCM in 64 KiB blocks, so WriteAt and range reads only touch the
+// blocks they cover. Files created without encryption stay readable as before.
+//
+// The client decrypts encrypted files and reports their plaintext size; clients
+// without the provider see, and must not write, the stored ciphertext. An
+// encrypted file should have one writer at a time, since overwriting part of a
+// block rewrites the whole block, and it can't be appended to with AppendIfSize.
+func WithEncryption(provider KeyProvider) Option {
+	return func(cfg *clientConfig) {
+		cfg.keyProvider = provider
+	}
+}
+
 // effectiveUploadBufferSize returns the buffer size to use for double-buffered uploads.
 // Falls back to maxChunkSize when uploadBufferSize is not explicitly configured.
 func (c *Client) effectiveUploadBufferSize() int64 {
@@ -0,0 +1,45 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// CompressionZstd stores a namespace's sealed chunks compressed with zstd. Chunks
+// are compressed by their chunkservers once they take no more writes and are read
+// back decompressed, so reads and appends work as before.
+const CompressionZstd = "zstd"
+
+// SetNamespaceCompression sets how the sealed chunks of a namespace are stored.
+// An empty compression stores new chunks raw; chunks already compressed stay compressed.
+func (c *Client) SetNamespaceCompression(ctx context.Context, namespace, compression string) error {
+	resp, err := c.master.SetCompression(ctx, &pb.SetCompressionRequest{
+		Namespace:   normalizeNamespace(namespace),
+		Compression: compression,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("set compression failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// CompressionRatio returns the size of the chunks on the live servers before
+// compression divided by their size on disk, or 1 when nothing is stored.
+func CompressionRatio(servers []*pb.ChunkServerStatus) float64 {
+	var logical, used uint64
+	for _, s := range servers {
+		if s.IsAlive {
+			logical += s.LogicalBytes
+			used += s.UsedBytes
+		}
+	}
+	if used == 0 || logical == 0 {
+		return 1
+	}
+	return float64(logical) / float64(used)
+}
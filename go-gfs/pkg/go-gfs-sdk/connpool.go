@@ -6,6 +6,8 @@ import (
 	"net"
 	"sync"
 	"time"
+
+	"eddisonso.com/go-gfs/internal/mtls"
 )
 
 // ConnPool manages a pool of TCP connections to chunkservers.
@@ -16,6 +18,7 @@ type ConnPool struct {
 	maxIdlePerHost int
 	idleTimeout    time.Duration
 	dialer         net.Dialer
+	tls            *mtls.Source // nil dials plain TCP
 }
 
 // hostPool holds idle connections for a single host:port.
@@ -36,8 +39,8 @@ type pooledConn struct {
 // After calling CloseWrite, the connection cannot be reused.
 func (pc *pooledConn) CloseWrite() error {
 	pc.halfClosed = true
-	if tcpConn, ok := pc.Conn.(*net.TCPConn); ok {
-		return tcpConn.CloseWrite()
+	if cw, ok := pc.Conn.(closeWriter); ok {
+		return cw.CloseWrite()
 	}
 	return nil
 }
@@ -93,7 +96,7 @@ func (p *ConnPool) Get(ctx context.Context, host string, port int) (net.Conn, er
 	}
 
 	// Create new connection
-	conn, err := p.dialer.DialContext(ctx, "tcp", addr)
+	conn, err := p.tls.DialContext(ctx, &p.dialer, addr)
 	if err != nil {
 		return nil, err
 	}
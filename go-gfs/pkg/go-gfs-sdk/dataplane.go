@@ -7,13 +7,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	pb "eddisonso.com/go-gfs/gen/master"
 	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
-	"github.com/golang-jwt/jwt/v5"
+	"eddisonso.com/go-gfs/internal/datatoken"
 )
 
 // ReadFile reads the entire file into memory.
@@ -39,7 +41,8 @@ func (c *Client) ReadTo(ctx context.Context, path string, w io.Writer) (int64, e
 // FileSizeWithNamespace returns the total file size from cached chunk locations.
 // This avoids a separate GetFile gRPC call when chunk locations are already cached.
 func (c *Client) FileSizeWithNamespace(ctx context.Context, path, namespace string) (uint64, error) {
-	chunks, err := c.getCachedChunks(ctx, path, normalizeNamespace(namespace))
+	namespace = normalizeNamespace(namespace)
+	chunks, err := c.getCachedChunks(ctx, path, namespace)
 	if err != nil {
 		return 0, err
 	}
@@ -47,12 +50,27 @@ func (c *Client) FileSizeWithNamespace(ctx context.Context, path, namespace stri
 	for _, chunk := range chunks {
 		total += chunk.Size
 	}
+	f, err := c.fileCipher(ctx, path, namespace)
+	if err != nil {
+		return 0, err
+	}
+	if f != nil {
+		total = uint64(plainSize(int64(total), f.blockSize))
+	}
 	return total, nil
 }
 
 // ReadToWithNamespace streams file contents to the provided writer with a namespace.
 // Chunks are read sequentially and streamed directly to the writer without buffering.
 func (c *Client) ReadToWithNamespace(ctx context.Context, path, namespace string, w io.Writer) (int64, error) {
+	f, err := c.fileCipher(ctx, path, normalizeNamespace(namespace))
+	if err != nil {
+		return 0, err
+	}
+	if f != nil {
+		return c.readEncrypted(ctx, path, normalizeNamespace(namespace), f, 0, -1, w)
+	}
+
 	chunks, err := c.getCachedChunks(ctx, path, normalizeNamespace(namespace))
 	if err != nil {
 		return 0, err
@@ -65,6 +83,11 @@ func (c *Client) ReadToWithNamespace(ctx context.Context, path, namespace string
 	for _, chunk := range chunks {
 		chunkCtx, cancel := context.WithTimeout(ctx, c.chunkTimeout)
 		n, err := c.readChunkWithFailover(chunkCtx, chunk, w)
+		if err != nil && n == 0 {
+			if fresh := c.reloadEncodedChunk(chunkCtx, path, normalizeNamespace(namespace), chunk); fresh != nil {
+				n, err = c.readChunkWithFailover(chunkCtx, fresh, w)
+			}
+		}
 		cancel()
 		total += n
 		if err != nil {
@@ -102,6 +125,87 @@ func (c *Client) AppendFromWithNamespace(ctx context.Context, path, namespace st
 	return prep.AppendFrom(ctx, r)
 }
 
+// AppendIfSize appends data only if the file is exactly size bytes, so writers that
+// each read a file before extending it can't interleave. If another write got there
+// first it writes nothing and fails with an error matching ErrPreconditionFailed.
+// The data must fit in one chunk; it may start a new one, leaving the rest of the
+// last chunk unused.
+func (c *Client) AppendIfSize(ctx context.Context, path string, data []byte, size uint64) error {
+	return c.AppendIfSizeWithNamespace(ctx, path, "", data, size)
+}
+
+// AppendIfSizeWithNamespace appends data to a file in a namespace only if the file is exactly size bytes.
+func (c *Client) AppendIfSizeWithNamespace(ctx context.Context, path, namespace string, data []byte, size uint64) error {
+	namespace = normalizeNamespace(namespace)
+	if len(data) == 0 {
+		return fmt.Errorf("conditional append needs data")
+	}
+	if int64(len(data)) > c.maxChunkSize {
+		return fmt.Errorf("conditional append of %d bytes exceeds the %d byte chunk size", len(data), c.maxChunkSize)
+	}
+	// Its size check and single write can't cover resealing a partial block
+	f, err := c.fileCipher(ctx, path, namespace)
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		return fmt.Errorf("conditional append to %s: %w", path, ErrEncrypted)
+	}
+	defer c.invalidateChunkCache(path, namespace)
+
+	// Read the file fresh; a cached size could pass a stale check
+	chunks, err := c.GetChunkLocationsWithNamespace(ctx, path, namespace)
+	if err != nil {
+		return err
+	}
+	var total uint64
+	for _, chunk := range chunks {
+		total += chunk.Size
+	}
+	if total != size {
+		return fmt.Errorf("append failed: %w", &preconditionError{fmt.Sprintf("%s is %d bytes, expected %d", path, total, size)})
+	}
+
+	// Append to the last chunk if the data fits; its primary checks nothing was
+	// appended since. Otherwise the master only adds a chunk if the file is unchanged
+	var chunk *pb.ChunkLocationInfo
+	var offset uint64
+	if n := len(chunks); n > 0 {
+		last := chunks[n-1]
+		if last.Stripe == nil && c.maxChunkSize-int64(last.Size) >= int64(len(data)) {
+			chunk, offset = last, last.Size
+			if last.Shared {
+				// Shared with a snapshot: write to a private copy instead
+				if chunk, err = c.prepareChunkWrite(ctx, path, namespace, n-1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if chunk == nil {
+		chunk, err = c.allocateChunk(ctx, &pb.AllocateChunkRequest{
+			Path:      path,
+			Namespace: namespace,
+			CheckSize: true,
+			IfSize:    size,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	primary, replicas, err := c.buildWriteTargets(chunk)
+	if err != nil {
+		return err
+	}
+	writeCtx, cancel := context.WithTimeout(ctx, c.chunkTimeout)
+	defer cancel()
+	if _, err := c.writeChunkWithProgress(writeCtx, primary, replicas, chunk.ChunkHandle, data, -1, &offset, nil); err != nil {
+		return fmt.Errorf("append failed: %w", err)
+	}
+	return nil
+}
+
 // writeResult holds the result of an async write operation.
 type writeResult struct {
 	written int
@@ -114,6 +218,9 @@ type writeResult struct {
 // TODO: Add file-level locking to enable safe pipelining.
 func (p *PreparedUpload) AppendFrom(ctx context.Context, r io.Reader) (int64, error) {
 	c := p.client
+	if p.cipher != nil {
+		return c.appendEncryptedFrom(ctx, p.path, p.namespace, p.cipher, r, p.onProgress)
+	}
 
 	// Double buffer for pipelining: read next chunk while writing current
 	bufSize := c.effectiveUploadBufferSize()
@@ -253,6 +360,23 @@ func (p *PreparedUpload) appendData(ctx context.Context, data []byte) (int, erro
 		chunkIdx := p.index
 		chunk := p.chunks[chunkIdx]
 		spaceAvailable := c.maxChunkSize - int64(chunk.Size)
+		if chunk.Stripe != nil {
+			// Erasure coded chunks are sealed; continue in a new chunk
+			spaceAvailable = 0
+		}
+
+		if spaceAvailable > 0 && chunk.Shared {
+			// Shared with a snapshot: swap in a private copy, then look again
+			p.mu.Unlock()
+			writable, err := c.prepareChunkWrite(ctx, p.path, p.namespace, chunkIdx)
+			if err != nil {
+				return total, err
+			}
+			p.mu.Lock()
+			p.chunks[chunkIdx] = writable
+			p.mu.Unlock()
+			continue
+		}
 
 		if spaceAvailable <= 0 {
 			// Chunk is full, move to next
@@ -338,7 +462,7 @@ func (p *PreparedUpload) appendData(ctx context.Context, data []byte) (int, erro
 		}
 
 		writeCtx, writeCancel := context.WithTimeout(ctx, c.chunkTimeout)
-		_, err = c.writeChunkWithProgress(writeCtx, primary, replicas, chunk.ChunkHandle, chunkData, -1, progressCallback)
+		_, err = c.writeChunkWithProgress(writeCtx, primary, replicas, chunk.ChunkHandle, chunkData, -1, nil, progressCallback)
 		writeCancel()
 		if err != nil {
 			return total, fmt.Errorf("failed to write %d bytes to chunk %s (index %d): %w",
@@ -353,8 +477,15 @@ func (p *PreparedUpload) appendData(ctx context.Context, data []byte) (int, erro
 }
 
 func (c *Client) appendData(ctx context.Context, path, namespace string, data []byte) (int, error) {
+	if c.keyProvider != nil {
+		if n, encrypted, err := c.writeEncryptedAt(ctx, path, namespace, data, -1); encrypted {
+			return n, err
+		}
+	}
+
 	total := 0
 	remaining := data
+	retriedShared := false
 
 	for len(remaining) > 0 {
 		chunkCtx, cancel := context.WithTimeout(ctx, c.chunkTimeout)
@@ -434,6 +565,15 @@ func (c *Client) appendData(ctx context.Context, path, namespace string, data []
 		writeCtx, writeCancel := context.WithTimeout(ctx, c.chunkTimeout)
 		if _, err := c.writeChunk(writeCtx, primary, replicas, chunk.ChunkHandle, chunkData, -1); err != nil {
 			writeCancel()
+			c.invalidateChunkCache(path, namespace)
+			// A snapshot taken after the chunk was cached rejects the write; retry once on a copy.
+			// So does an encode of the cached last chunk; the retry appends to a new chunk
+			if !retriedShared && (c.isChunkShared(ctx, path, namespace, chunk.ChunkHandle) ||
+				c.isChunkEncoded(ctx, path, namespace, chunk.ChunkHandle)) {
+				retriedShared = true
+				remaining = data[total:]
+				continue
+			}
 			return total, err
 		}
 		writeCancel()
@@ -455,6 +595,7 @@ func (c *Client) writeAtData(ctx context.Context, path, namespace string, data [
 	total := 0
 	remaining := data
 	currentOffset := offset
+	retriedShared := false
 
 	for len(remaining) > 0 {
 		chunkIndex := int(currentOffset / c.maxChunkSize)
@@ -514,6 +655,12 @@ func (c *Client) writeAtData(ctx context.Context, path, namespace string, data [
 		writeCtx, writeCancel := context.WithTimeout(ctx, c.chunkTimeout)
 		if _, err := c.writeChunk(writeCtx, primary, replicas, chunk.ChunkHandle, chunkData, int64(offsetInChunk)); err != nil {
 			writeCancel()
+			// A snapshot taken since the locations were fetched rejects the write; retry once on a copy
+			if !retriedShared && c.isChunkShared(ctx, path, namespace, chunk.ChunkHandle) {
+				retriedShared = true
+				remaining = data[total:]
+				continue
+			}
 			return total, err
 		}
 		writeCancel()
@@ -534,6 +681,17 @@ func (c *Client) getChunkForAppend(ctx context.Context, path, namespace string)
 	if len(chunks) > 0 {
 		last := chunks[len(chunks)-1]
 		space := c.maxChunkSize - int64(last.Size)
+		if last.Stripe != nil {
+			// Erasure coded chunks are sealed; the append starts a new chunk
+			space = 0
+		}
+		if space > 0 && last.Shared {
+			// Shared with a snapshot: write to a private copy instead
+			last, err = c.prepareChunkWrite(ctx, path, namespace, len(chunks)-1)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
 		if space > 0 {
 			return last, space, nil
 		}
@@ -559,6 +717,13 @@ func (c *Client) getChunkByIndex(ctx context.Context, path, namespace string, in
 	}
 
 	if len(chunks) > index {
+		if chunks[index].Stripe != nil {
+			return nil, ErrErasureCoded
+		}
+		if chunks[index].Shared {
+			// Shared with a snapshot: write to a private copy instead
+			return c.prepareChunkWrite(ctx, path, namespace, index)
+		}
 		return chunks[index], nil
 	}
 
@@ -610,13 +775,52 @@ func (c *Client) buildWriteTargets(info *pb.ChunkLocationInfo) (csstructs.Replic
 type writeChunkProgress func(bytesSent int)
 
 func (c *Client) writeChunk(ctx context.Context, primary csstructs.ReplicaIdentifier, replicas []csstructs.ReplicaIdentifier, chunkHandle string, data []byte, offset int64) (uint64, error) {
-	return c.writeChunkWithProgress(ctx, primary, replicas, chunkHandle, data, offset, nil)
+	return c.writeChunkWithProgress(ctx, primary, replicas, chunkHandle, data, offset, nil, nil)
 }
 
-func (c *Client) writeChunkWithProgress(ctx context.Context, primary csstructs.ReplicaIdentifier, replicas []csstructs.ReplicaIdentifier, chunkHandle string, data []byte, offset int64, onProgress writeChunkProgress) (uint64, error) {
+// writeChunkWithProgress writes data to a chunk at offset, or appends it when offset is -1.
+// A non-nil ifOffset makes the write conditional on the chunk holding exactly that many bytes.
+// Failed writes are retried under the client's retry policy.
+func (c *Client) writeChunkWithProgress(ctx context.Context, primary csstructs.ReplicaIdentifier, replicas []csstructs.ReplicaIdentifier, chunkHandle string, data []byte, offset int64, ifOffset *uint64, onProgress writeChunkProgress) (uint64, error) {
+	name := "chunk.append"
+	if offset >= 0 {
+		name = "chunk.write"
+	}
+	ctx, done := c.observe(ctx, name)
+	op := Operation{Chunk: chunkHandle, Server: primary.ID}
+
+	var written uint64
+	var err error
+	for attempt := 1; ; attempt++ {
+		op.Attempts = attempt
+		written, err = c.writeChunkAttempt(ctx, primary, replicas, chunkHandle, data, offset, ifOffset, onProgress)
+		if err == nil || attempt >= c.retryPolicy.MaxAttempts || !c.retryWrite(ctx, err, offset >= 0 || ifOffset != nil) {
+			break
+		}
+		slog.Debug("retrying chunk write", "chunk", chunkHandle, "attempt", attempt, "error", err)
+		if !c.retryPolicy.wait(ctx, attempt) {
+			break
+		}
+	}
+	if err == nil {
+		op.Bytes = int64(len(data))
+	}
+	op.Err = err
+	done(&op)
+	return written, err
+}
+
+// writeChunkAttempt makes one try at a chunk write. Failures before the primary
+// has replied with the write offset are unacceptedErrors.
+func (c *Client) writeChunkAttempt(ctx context.Context, primary csstructs.ReplicaIdentifier, replicas []csstructs.ReplicaIdentifier, chunkHandle string, data []byte, offset int64, ifOffset *uint64, onProgress writeChunkProgress) (uint64, error) {
+	grant, err := c.grantFor(ctx, chunkHandle, datatoken.OpWrite)
+	if err != nil {
+		return 0, &unacceptedError{err}
+	}
+
 	conn, err := c.getConn(ctx, primary.Hostname, primary.DataPort)
 	if err != nil {
-		return 0, fmt.Errorf("failed to connect: %w", err)
+		return 0, &unacceptedError{fmt.Errorf("failed to connect: %w", err)}
 	}
 	success := false
 	defer func() {
@@ -624,13 +828,13 @@ func (c *Client) writeChunkWithProgress(ctx context.Context, primary csstructs.R
 	}()
 
 	if err := setDeadlineFromContext(ctx, conn); err != nil {
-		return 0, err
+		return 0, &unacceptedError{err}
 	}
 
 	actionBytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(actionBytes, uint32(csstructs.Download))
 	if _, err = conn.Write(actionBytes); err != nil {
-		return 0, fmt.Errorf("failed to send action: %w", err)
+		return 0, &unacceptedError{fmt.Errorf("failed to send action: %w", err)}
 	}
 
 	claims := csstructs.DownloadRequestClaims{
@@ -638,35 +842,35 @@ func (c *Client) writeChunkWithProgress(ctx context.Context, primary csstructs.R
 		Operation:   "download",
 		Filesize:    uint64(len(data)),
 		Offset:      offset,
+		IfOffset:    ifOffset,
 		Replicas:    replicas,
 		Primary:     primary,
+		Grant:       grant,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret, err := c.secretProvider(nil)
+	tokenString, err := c.signRequest(claims)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get secret: %w", err)
-	}
-
-	tokenString, err := token.SignedString(secret)
-	if err != nil {
-		return 0, fmt.Errorf("failed to sign token: %w", err)
+		return 0, &unacceptedError{err}
 	}
 
 	tokenLen := uint32(len(tokenString))
 	if err = binary.Write(conn, binary.BigEndian, tokenLen); err != nil {
-		return 0, fmt.Errorf("failed to send token length: %w", err)
+		return 0, &unacceptedError{fmt.Errorf("failed to send token length: %w", err)}
 	}
 
 	if _, err = conn.Write([]byte(tokenString)); err != nil {
-		return 0, fmt.Errorf("failed to send token: %w", err)
+		return 0, &unacceptedError{fmt.Errorf("failed to send token: %w", err)}
 	}
 
 	offsetBytes := make([]byte, 8)
 	if _, err = io.ReadFull(conn, offsetBytes); err != nil {
-		return 0, fmt.Errorf("failed to receive offset: %w", err)
+		// The primary closes without an offset when it can't take the write
+		return 0, &unacceptedError{fmt.Errorf("failed to receive offset: %w", err)}
 	}
 	offsetValue := binary.BigEndian.Uint64(offsetBytes)
+	if offsetValue == csstructs.OffsetMismatch {
+		return 0, &preconditionError{fmt.Sprintf("chunk %s holds more than %d bytes", chunkHandle, *ifOffset)}
+	}
 
 	// Write data in smaller chunks to enable progress reporting
 	const writeChunkSize = 1 << 20 // 1MB increments for progress updates
@@ -708,6 +912,17 @@ func (c *Client) writeChunkWithProgress(ctx context.Context, primary csstructs.R
 }
 
 func (c *Client) readChunk(ctx context.Context, server csstructs.ReplicaIdentifier, chunkHandle string, w io.Writer) (int64, error) {
+	return c.readChunkRange(ctx, server, chunkHandle, 0, 0, w)
+}
+
+// readChunkRange reads length bytes at offset within a chunk from one server.
+// A zero offset and length reads the whole chunk; a zero length reads to the end.
+func (c *Client) readChunkRange(ctx context.Context, server csstructs.ReplicaIdentifier, chunkHandle string, offset, length int64, w io.Writer) (int64, error) {
+	grant, err := c.grantFor(ctx, chunkHandle, datatoken.OpRead)
+	if err != nil {
+		return 0, err
+	}
+
 	conn, err := c.getConn(ctx, server.Hostname, server.DataPort)
 	if err != nil {
 		return 0, fmt.Errorf("failed to connect: %w", err)
@@ -720,6 +935,15 @@ func (c *Client) readChunk(ctx context.Context, server csstructs.ReplicaIdentifi
 	if err := setDeadlineFromContext(ctx, conn); err != nil {
 		return 0, err
 	}
+	// Unblock the read when ctx is canceled, as when a hedged read loses
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer func() {
+		if !stop() {
+			success = false
+		}
+	}()
 
 	actionBytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(actionBytes, uint32(csstructs.Upload))
@@ -730,17 +954,18 @@ func (c *Client) readChunk(ctx context.Context, server csstructs.ReplicaIdentifi
 	claims := csstructs.UploadRequestClaims{
 		ChunkHandle: chunkHandle,
 		Operation:   "upload",
+		Grant:       grant,
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secret, err := c.secretProvider(nil)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get secret: %w", err)
+	if offset != 0 || length != 0 {
+		// Chunkservers without range support reject this operation instead of sending the whole chunk
+		claims.Operation = "upload_range"
+		claims.Offset = offset
+		claims.Length = length
 	}
 
-	tokenString, err := token.SignedString(secret)
+	tokenString, err := c.signRequest(claims)
 	if err != nil {
-		return 0, fmt.Errorf("failed to sign token: %w", err)
+		return 0, err
 	}
 
 	tokenLen := uint32(len(tokenString))
@@ -792,9 +1017,8 @@ func (c *Client) readChunk(ctx context.Context, server csstructs.ReplicaIdentifi
 	return written, nil
 }
 
-func dialWithContext(ctx context.Context, host string, port int) (net.Conn, error) {
-	dialer := net.Dialer{}
-	return dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", host, port))
+func (c *Client) dialWithContext(ctx context.Context, host string, port int) (net.Conn, error) {
+	return c.tls.DialContext(ctx, &net.Dialer{}, net.JoinHostPort(host, strconv.Itoa(port)))
 }
 
 // closeWriter is implemented by connections that support half-close.
@@ -814,7 +1038,7 @@ func (c *Client) getConn(ctx context.Context, host string, port int) (net.Conn,
 	if c.connPool != nil {
 		return c.connPool.Get(ctx, host, port)
 	}
-	return dialWithContext(ctx, host, port)
+	return c.dialWithContext(ctx, host, port)
 }
 
 // putConn returns a connection to the pool or closes it.
@@ -869,21 +1093,67 @@ func (c *Client) buildReadTargets(chunk *pb.ChunkLocationInfo) []csstructs.Repli
 
 // readChunkWithFailover tries to read a chunk from each available replica until one succeeds.
 func (c *Client) readChunkWithFailover(ctx context.Context, chunk *pb.ChunkLocationInfo, w io.Writer) (int64, error) {
+	return c.readChunkRangeWithFailover(ctx, chunk, 0, 0, w)
+}
+
+// readChunkRangeWithFailover reads a byte range of a chunk, moving to the next replica on failure.
+// A replica that fails mid-stream is resumed from the next byte on the following replica,
+// so nothing is written to w twice.
+func (c *Client) readChunkRangeWithFailover(ctx context.Context, chunk *pb.ChunkLocationInfo, offset, length int64, w io.Writer) (int64, error) {
+	if chunk.Stripe != nil {
+		return c.readStripeRange(ctx, chunk, offset, length, w)
+	}
+
 	replicas := c.buildReadTargets(chunk)
 	if len(replicas) == 0 {
 		return 0, fmt.Errorf("no replicas available for chunk %s", chunk.ChunkHandle)
 	}
 
+	ctx, done := c.observe(ctx, "chunk.read")
+	op := Operation{Chunk: chunk.ChunkHandle}
+	defer func() { done(&op) }()
+
+	var total int64
 	var lastErr error
-	for _, replica := range replicas {
-		n, err := c.readChunk(ctx, replica, chunk.ChunkHandle, w)
+	for i := 0; i < len(replicas); {
+		remaining := length
+		if length > 0 {
+			remaining = length - total
+			if remaining <= 0 {
+				op.Bytes = total
+				return total, nil
+			}
+		}
+
+		var n int64
+		var err error
+		if c.hedgeDelay > 0 && i+1 < len(replicas) {
+			var used int
+			var server csstructs.ReplicaIdentifier
+			n, used, server, err = c.readChunkRangeHedged(ctx, chunk.ChunkHandle, replicas[i], replicas[i+1], offset+total, remaining, w)
+			for _, r := range replicas[i : i+used] {
+				op.Replicas = append(op.Replicas, r.ID)
+			}
+			op.Server, op.Hedged = server.ID, op.Hedged || used > 1
+			i += used
+		} else {
+			n, err = c.readChunkRange(ctx, replicas[i], chunk.ChunkHandle, offset+total, remaining, w)
+			op.Replicas = append(op.Replicas, replicas[i].ID)
+			op.Server = replicas[i].ID
+			i++
+		}
+		total += n
+		op.Attempts++
 		if err == nil {
-			return n, nil
+			op.Bytes = total
+			return total, nil
 		}
 		lastErr = err
 	}
 
-	return 0, fmt.Errorf("all %d replicas failed for chunk %s: %w", len(replicas), chunk.ChunkHandle, lastErr)
+	op.Bytes = total
+	op.Err = fmt.Errorf("all %d replicas failed for chunk %s: %w", len(replicas), chunk.ChunkHandle, lastErr)
+	return total, op.Err
 }
 
 func setDeadlineFromContext(ctx context.Context, conn net.Conn) error {
@@ -965,8 +1235,15 @@ func (c *Client) appendCachedChunk(path, namespace string, chunk *pb.ChunkLocati
 	cache.mu.Unlock()
 }
 
-// invalidateChunkCache removes cached chunks for a file.
+// invalidateChunkCache removes cached chunks and the cipher of a file.
 func (c *Client) invalidateChunkCache(path, namespace string) {
+	c.dropChunkLocations(path, namespace)
+	c.forgetCiphers(namespace, path)
+}
+
+// dropChunkLocations removes cached chunks for a file but keeps its cipher, for
+// writes that change its data and not its key.
+func (c *Client) dropChunkLocations(path, namespace string) {
 	key := fileKey{namespace: namespace, path: path}
 
 	c.chunkCacheMu.Lock()
@@ -983,4 +1260,5 @@ func (c *Client) invalidateNamespaceCache(namespace string) {
 		}
 	}
 	c.chunkCacheMu.Unlock()
+	c.forgetCiphers(namespace, "")
 }
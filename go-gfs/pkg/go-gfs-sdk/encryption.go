@@ -0,0 +1,639 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+const (
+	encryptionAlgorithm = "aes-256-gcm"
+	// encryptionBlockSize is the plaintext size of each sealed block of a new
+	// encrypted file. Every file records its own block size.
+	encryptionBlockSize    = 64 << 10
+	maxEncryptionBlockSize = 16 << 20
+	// blockOverhead is the nonce and authentication tag stored with each block.
+	blockOverhead = 12 + 16
+	// maxCachedKeys bounds the unwrapped data keys a client keeps.
+	maxCachedKeys = 4096
+)
+
+// fileCipher encrypts and decrypts the blocks of one encrypted file. Block i of
+// the plaintext is stored at offset i*(blockSize+blockOverhead) as a random nonce
+// followed by the sealed block, authenticated with its index so blocks can't be
+// reordered. Only the last block may be shorter.
+type fileCipher struct {
+	aead      cipher.AEAD
+	blockSize int64
+}
+
+func (f *fileCipher) sealedBlockSize() int64 { return f.blockSize + blockOverhead }
+
+// seal appends the sealed blocks of plain, numbered from index, to dst.
+func (f *fileCipher) seal(dst, plain []byte, index int64) []byte {
+	for len(plain) > 0 {
+		n := min(int64(len(plain)), f.blockSize)
+		nonce := make([]byte, f.aead.NonceSize())
+		rand.Read(nonce)
+		dst = append(dst, nonce...)
+		dst = f.aead.Seal(dst, nonce, plain[:n], blockIndex(index))
+		plain = plain[n:]
+		index++
+	}
+	return dst
+}
+
+// open appends the plaintext of sealed block number index to dst.
+func (f *fileCipher) open(dst, block []byte, index int64) ([]byte, error) {
+	if len(block) < blockOverhead {
+		return nil, fmt.Errorf("block %d is truncated: %w", index, ErrDecryptionFailed)
+	}
+	nonceSize := f.aead.NonceSize()
+	plain, err := f.aead.Open(dst, block[:nonceSize], block[nonceSize:], blockIndex(index))
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", index, ErrDecryptionFailed)
+	}
+	return plain, nil
+}
+
+// blockIndex is the additional data that binds a sealed block to its position.
+func blockIndex(index int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(index))
+}
+
+// plainSize returns the plaintext size of sealed bytes of blocks.
+func plainSize(sealed, blockSize int64) int64 {
+	full, rest := sealed/(blockSize+blockOverhead), sealed%(blockSize+blockOverhead)
+	return full*blockSize + max(rest-blockOverhead, 0)
+}
+
+// sealedSize returns the stored size of plain bytes of plaintext.
+func sealedSize(plain, blockSize int64) int64 {
+	full, rest := plain/blockSize, plain%blockSize
+	if rest > 0 {
+		rest += blockOverhead
+	}
+	return full*(blockSize+blockOverhead) + rest
+}
+
+// fileBlockSize returns the block size recorded in an encrypted file's
+// attributes, or 0 for a plaintext file.
+func fileBlockSize(attributes map[string]string) (int64, error) {
+	spec, ok := attributes[AttrEncryption]
+	if !ok {
+		return 0, nil
+	}
+	algorithm, size, _ := strings.Cut(spec, "/")
+	blockSize, err := strconv.ParseInt(size, 10, 64)
+	if algorithm != encryptionAlgorithm || err != nil || blockSize <= 0 || blockSize > maxEncryptionBlockSize {
+		return 0, fmt.Errorf("unsupported encryption %q", spec)
+	}
+	return blockSize, nil
+}
+
+// encryptionAttributes returns attributes with a new wrapped data key added, so
+// the file they create is encrypted. They are returned as they are if the client
+// doesn't encrypt or they already hold a key, as when a file is recreated.
+func (c *Client) encryptionAttributes(ctx context.Context, attributes map[string]string) (map[string]string, error) {
+	if c.keyProvider == nil || attributes[AttrEncryptionKey] != "" {
+		return attributes, nil
+	}
+	dataKey := make([]byte, 32)
+	rand.Read(dataKey)
+	wrapped, keyID, err := c.keyProvider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("wrap data key: %w", err)
+	}
+
+	attrs := maps.Clone(attributes)
+	if attrs == nil {
+		attrs = make(map[string]string, 3)
+	}
+	attrs[AttrEncryption] = encryptionAlgorithm + "/" + strconv.Itoa(encryptionBlockSize)
+	attrs[AttrEncryptionKey] = base64.StdEncoding.EncodeToString(wrapped)
+	attrs[AttrEncryptionKeyID] = keyID
+	return attrs, nil
+}
+
+// fileCipher returns the cipher of an encrypted file, or nil for a plaintext
+// file or a client without a key provider. Ciphers are cached alongside chunk
+// locations and dropped with them.
+func (c *Client) fileCipher(ctx context.Context, path, namespace string) (*fileCipher, error) {
+	if c.keyProvider == nil {
+		return nil, nil
+	}
+	key := fileKey{namespace: namespace, path: path}
+	c.cipherMu.Lock()
+	f, ok := c.fileCiphers[key]
+	c.cipherMu.Unlock()
+	if ok {
+		return f, nil
+	}
+
+	resp, err := c.master.GetFile(ctx, &pb.GetFileRequest{Path: path, Namespace: namespace})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("get file failed: %s", resp.Message)
+	}
+	if f, err = c.cipherFor(ctx, resp.File.Attributes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	c.cipherMu.Lock()
+	c.fileCiphers[key] = f
+	c.cipherMu.Unlock()
+	return f, nil
+}
+
+// cipherFor unwraps the data key in an encrypted file's attributes.
+func (c *Client) cipherFor(ctx context.Context, attributes map[string]string) (*fileCipher, error) {
+	blockSize, err := fileBlockSize(attributes)
+	if err != nil || blockSize == 0 {
+		return nil, err
+	}
+	wrapped := attributes[AttrEncryptionKey]
+
+	c.cipherMu.Lock()
+	aead, ok := c.dataKeys[wrapped]
+	c.cipherMu.Unlock()
+	if !ok {
+		sealed, err := base64.StdEncoding.DecodeString(wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s attribute: %w", AttrEncryptionKey, err)
+		}
+		dataKey, err := c.keyProvider.UnwrapKey(ctx, sealed, attributes[AttrEncryptionKeyID])
+		if err != nil {
+			return nil, fmt.Errorf("unwrap data key: %w", err)
+		}
+		if aead, err = newGCM(dataKey); err != nil {
+			return nil, err
+		}
+		c.cipherMu.Lock()
+		if len(c.dataKeys) >= maxCachedKeys {
+			clear(c.dataKeys)
+		}
+		c.dataKeys[wrapped] = aead
+		c.cipherMu.Unlock()
+	}
+	return &fileCipher{aead: aead, blockSize: blockSize}, nil
+}
+
+// forgetCiphers drops the cached ciphers of the files in a namespace, or of
+// one file if path is not empty.
+func (c *Client) forgetCiphers(namespace, path string) {
+	c.cipherMu.Lock()
+	defer c.cipherMu.Unlock()
+	if path != "" {
+		delete(c.fileCiphers, fileKey{namespace: namespace, path: path})
+		return
+	}
+	for key := range c.fileCiphers {
+		if key.namespace == namespace {
+			delete(c.fileCiphers, key)
+		}
+	}
+}
+
+// showPlainSize replaces the stored size of encrypted files with the size of
+// their plaintext, for clients that decrypt them.
+func (c *Client) showPlainSize(files ...*pb.FileInfoResponse) {
+	if c.keyProvider == nil {
+		return
+	}
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		if blockSize, err := fileBlockSize(file.Attributes); err == nil && blockSize > 0 {
+			file.Size = uint64(plainSize(int64(file.Size), blockSize))
+		}
+	}
+}
+
+// readEncrypted streams up to length bytes of an encrypted file's plaintext
+// from offset to w. Only the blocks the range covers are read. A negative
+// length reads to the end of the file.
+func (c *Client) readEncrypted(ctx context.Context, path, namespace string, f *fileCipher, offset, length int64, w io.Writer) (int64, error) {
+	first := offset / f.blockSize
+	sealedLength := int64(-1)
+	if length >= 0 {
+		last := (offset + length - 1) / f.blockSize
+		sealedLength = (last - first + 1) * f.sealedBlockSize()
+	}
+	d := &decryptWriter{
+		cipher: f,
+		w:      w,
+		index:  first,
+		skip:   offset - first*f.blockSize,
+		limit:  length,
+		block:  make([]byte, 0, f.sealedBlockSize()),
+	}
+	_, err := c.readRangeTo(ctx, path, namespace, first*f.sealedBlockSize(), sealedLength, d)
+	if err == nil {
+		err = d.flush()
+	}
+	return d.n, err
+}
+
+// decryptWriter decrypts the sealed blocks streamed to it, numbered from index,
+// and writes their plaintext to w, less the first skip bytes and anything
+// beyond limit bytes when limit is not negative.
+type decryptWriter struct {
+	cipher *fileCipher
+	w      io.Writer
+	index  int64
+	skip   int64
+	limit  int64
+	n      int64 // Plaintext bytes written to w
+	block  []byte
+	plain  []byte
+}
+
+func (d *decryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		k := copy(d.block[len(d.block):cap(d.block)], p[written:])
+		d.block = d.block[:len(d.block)+k]
+		written += k
+		if len(d.block) == cap(d.block) {
+			if err := d.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush decrypts the buffered block and writes it out. It runs for each full
+// block and once more at the end for a shorter last block.
+func (d *decryptWriter) flush() error {
+	if len(d.block) == 0 {
+		return nil
+	}
+	plain, err := d.cipher.open(d.plain[:0], d.block, d.index)
+	if err != nil {
+		return err
+	}
+	d.plain, d.block = plain, d.block[:0]
+	d.index++
+
+	skip := min(d.skip, int64(len(plain)))
+	plain, d.skip = plain[skip:], d.skip-skip
+	if d.limit >= 0 {
+		plain = plain[:min(int64(len(plain)), d.limit-d.n)]
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+	n, err := d.w.Write(plain)
+	d.n += int64(n)
+	return err
+}
+
+// readEncryptedFull reads len(p) bytes of an encrypted file's plaintext at offset.
+func (c *Client) readEncryptedFull(ctx context.Context, path, namespace string, f *fileCipher, p []byte, offset int64) error {
+	w := &sliceWriter{buf: p}
+	if _, err := c.readEncrypted(ctx, path, namespace, f, offset, int64(len(p)), w); err != nil {
+		return err
+	}
+	if w.n < len(p) {
+		return fmt.Errorf("read %s at %d: %w", path, offset, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// encryptedSize returns the plaintext size of an encrypted file, read fresh
+// from the master rather than from cached chunk locations.
+func (c *Client) encryptedSize(ctx context.Context, path, namespace string, f *fileCipher) (int64, error) {
+	chunks, err := c.GetChunkLocationsWithNamespace(ctx, path, namespace)
+	if err != nil {
+		return 0, err
+	}
+	var sealed int64
+	for _, chunk := range chunks {
+		sealed += int64(chunk.Size)
+	}
+	return plainSize(sealed, f.blockSize), nil
+}
+
+// writeEncrypted writes data at offset of an encrypted file holding size bytes
+// of plaintext, resealing every block the write touches. The rest of a block it
+// only partly covers is read back first, and a gap past the end of the file is
+// filled with zeros. It returns how many bytes of data were written.
+func (c *Client) writeEncrypted(ctx context.Context, path, namespace string, f *fileCipher, data []byte, offset, size int64) (int, error) {
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	defer c.dropChunkLocations(path, namespace)
+
+	start := min(offset, size) / f.blockSize * f.blockSize
+	end := offset + int64(len(data))
+	bufEnd := max(end, min((end+f.blockSize-1)/f.blockSize*f.blockSize, size))
+	buf := make([]byte, bufEnd-start)
+
+	// Read back the existing bytes of the first and last blocks
+	headRead := false
+	if min(offset, size) > start {
+		if err := c.readEncryptedFull(ctx, path, namespace, f, buf[:min(f.blockSize, size-start)], start); err != nil {
+			return 0, err
+		}
+		headRead = true
+	}
+	if tail := (end - 1) / f.blockSize * f.blockSize; end < bufEnd && !(headRead && tail == start) {
+		if err := c.readEncryptedFull(ctx, path, namespace, f, buf[tail-start:], tail); err != nil {
+			return 0, err
+		}
+	}
+	copy(buf[offset-start:], data)
+
+	first := start / f.blockSize
+	sealed := f.seal(make([]byte, 0, sealedSize(int64(len(buf)), f.blockSize)), buf, first)
+	written, err := c.writeAtData(ctx, path, namespace, sealed, first*f.sealedBlockSize())
+	if err != nil {
+		// Count the data in the blocks that were written whole
+		done := start + int64(written)/f.sealedBlockSize()*f.blockSize - offset
+		return int(min(max(done, 0), int64(len(data)))), err
+	}
+	return len(data), nil
+}
+
+// writeEncryptedAt writes data at offset if path is an encrypted file, or
+// appends it when offset is negative, creating the file like appendData does.
+// It reports false, having written nothing, if the file is not encrypted.
+func (c *Client) writeEncryptedAt(ctx context.Context, path, namespace string, data []byte, offset int64) (int, bool, error) {
+	f, err := c.fileCipher(ctx, path, namespace)
+	if offset < 0 && IsNotFound(err) && !c.isFileKnown(path, namespace) {
+		// Create the file here so it gets a data key
+		if _, err = c.CreateFileWithNamespace(ctx, path, namespace); err == nil || errors.Is(err, ErrPreconditionFailed) {
+			c.markFileKnown(path, namespace)
+			f, err = c.fileCipher(ctx, path, namespace)
+		}
+	}
+	if err != nil {
+		return 0, true, err
+	}
+	if f == nil {
+		return 0, false, nil
+	}
+
+	if offset < 0 {
+		n, err := c.appendEncrypted(ctx, path, namespace, f, data)
+		return n, true, err
+	}
+	size, err := c.encryptedSize(ctx, path, namespace, f)
+	if err != nil {
+		return 0, true, err
+	}
+	n, err := c.writeEncrypted(ctx, path, namespace, f, data, offset, size)
+	return n, true, err
+}
+
+// appendEncrypted appends data to an encrypted file. A partial last block is read
+// back and resealed with the start of data. The sealed bytes are written only
+// while the file still holds the sealed size they were built on, so concurrent
+// appenders can't overwrite each other's blocks: the one that loses reads the
+// file again and retries. A write within one chunk is staged by the chunkserver
+// and lands whole or not at all, so a crash keeps the block it was to replace.
+func (c *Client) appendEncrypted(ctx context.Context, path, namespace string, f *fileCipher, data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	defer c.dropChunkLocations(path, namespace)
+
+	for {
+		chunks, err := c.GetChunkLocationsWithNamespace(ctx, path, namespace)
+		if err != nil {
+			return 0, err
+		}
+		var sealed int64
+		for _, chunk := range chunks {
+			sealed += int64(chunk.Size)
+		}
+		size := plainSize(sealed, f.blockSize)
+		start := size / f.blockSize * f.blockSize
+
+		buf := make([]byte, size-start+int64(len(data)))
+		if size > start {
+			// Read through fresh locations; cached ones may predate the last append
+			c.dropChunkLocations(path, namespace)
+			if err := c.readEncryptedFull(ctx, path, namespace, f, buf[:size-start], start); err != nil {
+				// Another append may have resealed the block while it was read
+				if now, sizeErr := c.encryptedSize(ctx, path, namespace, f); sizeErr == nil && now != size && ctx.Err() == nil {
+					continue
+				}
+				return 0, err
+			}
+		}
+		copy(buf[size-start:], data)
+
+		first := start / f.blockSize
+		out := f.seal(make([]byte, 0, sealedSize(int64(len(buf)), f.blockSize)), buf, first)
+		written, err := c.writeIfSize(ctx, path, namespace, chunks, out, first*f.sealedBlockSize())
+		if errors.Is(err, ErrPreconditionFailed) && written == 0 {
+			// A write retried after it landed fails its own check; the file then
+			// holds out, whose random nonces no other append repeats
+			if c.holdsSealed(ctx, path, namespace, out, first*f.sealedBlockSize()) {
+				return len(data), nil
+			}
+			// Another append got there first
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			continue
+		}
+		if err != nil {
+			// Count the data in the blocks that were written whole
+			done := start + int64(written)/f.sealedBlockSize()*f.blockSize - size
+			return int(min(max(done, 0), int64(len(data)))), err
+		}
+		return len(data), nil
+	}
+}
+
+// holdsSealed reports whether a file holds sealed at offset.
+func (c *Client) holdsSealed(ctx context.Context, path, namespace string, sealed []byte, offset int64) bool {
+	c.dropChunkLocations(path, namespace)
+	var buf bytes.Buffer
+	_, err := c.readRangeTo(ctx, path, namespace, offset, int64(len(sealed)), &buf)
+	return err == nil && bytes.Equal(buf.Bytes(), sealed)
+}
+
+// writeIfSize writes data at offset of a file whose chunks are as given, only
+// while the file still ends where they do. offset may not be past that end. The
+// write to the last chunk is conditional on its length and chunks past it are
+// only added while the file has not grown, so either fails with
+// ErrPreconditionFailed if another write got there first. A last block that
+// starts in the chunk before takes an unconditional write there too, made after
+// the checked one. It returns how many bytes from the start of data were written.
+func (c *Client) writeIfSize(ctx context.Context, path, namespace string, chunks []*pb.ChunkLocationInfo, data []byte, offset int64) (int, error) {
+	type piece struct {
+		index int
+		start int64 // Offset of the piece in data
+		data  []byte
+	}
+	var pieces []piece
+	for pos := int64(0); pos < int64(len(data)); {
+		index := int((offset + pos) / c.maxChunkSize)
+		n := min(int64(len(data))-pos, int64(index+1)*c.maxChunkSize-offset-pos)
+		pieces = append(pieces, piece{index: index, start: pos, data: data[pos : pos+n]})
+		pos += n
+	}
+	last := len(chunks) - 1
+	order := make([]int, len(pieces))
+	for i := range order {
+		order[i] = i
+	}
+	if len(pieces) > 1 && pieces[0].index < last {
+		// Check the file hasn't grown before rewriting the block's first part
+		order[0], order[1] = 1, 0
+	}
+
+	var size uint64
+	for _, chunk := range chunks {
+		size += chunk.Size
+	}
+	done := make([]bool, len(pieces))
+	written := func() int {
+		n := 0
+		for i, p := range pieces {
+			if !done[i] {
+				break
+			}
+			n += len(p.data)
+		}
+		return n
+	}
+	for _, i := range order {
+		p := pieces[i]
+		var chunk *pb.ChunkLocationInfo
+		var ifOffset *uint64
+		var err error
+		switch {
+		case p.index < len(chunks):
+			chunk = chunks[p.index]
+			if p.index == last {
+				length := chunk.Size
+				ifOffset = &length
+			}
+			if chunk.Shared {
+				// Shared with a snapshot: write to a private copy instead
+				if chunk, err = c.prepareChunkWrite(ctx, path, namespace, p.index); err != nil {
+					return written(), err
+				}
+			}
+		default:
+			chunk, err = c.allocateChunk(ctx, &pb.AllocateChunkRequest{
+				Path:      path,
+				Namespace: namespace,
+				CheckSize: true,
+				IfSize:    size,
+			})
+			if err != nil {
+				return written(), err
+			}
+			var empty uint64
+			ifOffset = &empty
+		}
+
+		primary, replicas, err := c.buildWriteTargets(chunk)
+		if err != nil {
+			return written(), err
+		}
+		chunkOffset := offset + p.start - int64(p.index)*c.maxChunkSize
+		writeCtx, cancel := context.WithTimeout(ctx, c.chunkTimeout)
+		_, err = c.writeChunkWithProgress(writeCtx, primary, replicas, chunk.ChunkHandle, p.data, chunkOffset, ifOffset, nil)
+		cancel()
+		if err != nil {
+			return written(), err
+		}
+		done[i] = true
+		size = max(size, uint64(offset+p.start)+uint64(len(p.data)))
+	}
+	return written(), nil
+}
+
+// appendEncryptedFrom streams r to the end of an encrypted file, one
+// appendEncrypted per buffer. Unless another writer appends meanwhile, writes
+// after the first start on a block boundary, so no block is read back.
+func (c *Client) appendEncryptedFrom(ctx context.Context, path, namespace string, f *fileCipher, r io.Reader, onProgress ProgressFunc) (int64, error) {
+	size, err := c.encryptedSize(ctx, path, namespace, f)
+	if err != nil {
+		return 0, err
+	}
+	blocks := max(c.effectiveUploadBufferSize()/f.blockSize, 1)
+	buf := make([]byte, blocks*f.blockSize)
+
+	var total int64
+	next := buf[:int64(len(buf))-size%f.blockSize]
+	for {
+		n, readErr := io.ReadFull(r, next)
+		if n > 0 {
+			written, err := c.appendEncrypted(ctx, path, namespace, f, next[:n])
+			total += int64(written)
+			if onProgress != nil {
+				onProgress(total)
+			}
+			if err != nil {
+				return total, err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return total, nil
+		}
+		if readErr != nil {
+			return total, readErr
+		}
+		next = buf
+	}
+}
+
+// encryptTransaction gives the files a transaction creates data keys, and
+// converts its size conditions on encrypted files to their stored size.
+func (c *Client) encryptTransaction(ctx context.Context, namespace string, tx *Transaction) ([]*pb.TransactCondition, []*pb.TransactOp, error) {
+	if c.keyProvider == nil {
+		return tx.conditions, tx.ops, nil
+	}
+
+	conditions := make([]*pb.TransactCondition, 0, len(tx.conditions))
+	for _, cond := range tx.conditions {
+		if cond.CheckSize {
+			f, err := c.fileCipher(ctx, cond.Path, namespace)
+			if err != nil && !IsNotFound(err) {
+				return nil, nil, err
+			}
+			if f != nil {
+				cond = &pb.TransactCondition{Path: cond.Path, CheckSize: true, Size: uint64(sealedSize(int64(cond.Size), f.blockSize))}
+			}
+		}
+		conditions = append(conditions, cond)
+	}
+
+	ops := make([]*pb.TransactOp, 0, len(tx.ops))
+	for _, op := range tx.ops {
+		if op.Type == "create" {
+			attrs, err := c.encryptionAttributes(ctx, op.Attributes)
+			if err != nil {
+				return nil, nil, err
+			}
+			op = &pb.TransactOp{Type: op.Type, Path: op.Path, Attributes: attrs}
+		}
+		ops = append(ops, op)
+	}
+	return conditions, ops, nil
+}
@@ -0,0 +1,312 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func newTestCipher(t *testing.T, blockSize int64) *fileCipher {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+	aead, err := newGCM(key)
+	if err != nil {
+		t.Fatalf("newGCM: %v", err)
+	}
+	return &fileCipher{aead: aead, blockSize: blockSize}
+}
+
+func TestSealedSize(t *testing.T) {
+	tests := []struct {
+		plain, blockSize, sealed int64
+	}{
+		{plain: 0, blockSize: 16, sealed: 0},
+		{plain: 1, blockSize: 16, sealed: 1 + blockOverhead},
+		{plain: 15, blockSize: 16, sealed: 15 + blockOverhead},
+		{plain: 16, blockSize: 16, sealed: 16 + blockOverhead},
+		{plain: 17, blockSize: 16, sealed: 17 + 2*blockOverhead},
+		{plain: 3*16 + 5, blockSize: 16, sealed: 3*16 + 5 + 4*blockOverhead},
+		{plain: encryptionBlockSize, blockSize: encryptionBlockSize, sealed: encryptionBlockSize + blockOverhead},
+		{plain: 10*encryptionBlockSize - 1, blockSize: encryptionBlockSize, sealed: 10*encryptionBlockSize - 1 + 10*blockOverhead},
+	}
+	for _, tt := range tests {
+		if got := sealedSize(tt.plain, tt.blockSize); got != tt.sealed {
+			t.Errorf("sealedSize(%d, %d) = %d, want %d", tt.plain, tt.blockSize, got, tt.sealed)
+		}
+		if got := plainSize(tt.sealed, tt.blockSize); got != tt.plain {
+			t.Errorf("plainSize(%d, %d) = %d, want %d", tt.sealed, tt.blockSize, got, tt.plain)
+		}
+	}
+	// A last block too short to hold its overhead has no plaintext
+	if got := plainSize(16+blockOverhead+blockOverhead-1, 16); got != 16 {
+		t.Errorf("plainSize with a cut last block = %d, want 16", got)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	const blockSize = 16
+	f := newTestCipher(t, blockSize)
+	plain := []byte("three and a half blocks of plaintext, sealed one by one..")
+	const first = 5 // Blocks are numbered from where they sit in the file
+	sealed := f.seal(nil, plain, first)
+	if int64(len(sealed)) != sealedSize(int64(len(plain)), blockSize) {
+		t.Fatalf("sealed %d bytes, want %d", len(sealed), sealedSize(int64(len(plain)), blockSize))
+	}
+	sealedBlock := int(f.sealedBlockSize())
+	block := func(b []byte, i int) []byte {
+		start := i * sealedBlock
+		return b[start:min(start+sealedBlock, len(b))]
+	}
+	blocks := (len(sealed) + sealedBlock - 1) / sealedBlock
+
+	var opened []byte
+	for i := range blocks {
+		var err error
+		if opened, err = f.open(opened, block(sealed, i), int64(first+i)); err != nil {
+			t.Fatalf("open block %d: %v", i, err)
+		}
+	}
+	if !bytes.Equal(opened, plain) {
+		t.Fatalf("opened %q, want %q", opened, plain)
+	}
+
+	tests := []struct {
+		name  string
+		block []byte
+		index int64
+	}{
+		{name: "wrong index", block: block(sealed, 0), index: first + 1},
+		{name: "swapped blocks", block: block(sealed, 1), index: first},
+		{name: "index from 0", block: block(sealed, 0), index: 0},
+		{name: "truncated block", block: block(sealed, 1)[:sealedBlock-1], index: first + 1},
+		{name: "truncated last block", block: block(sealed, blocks-1)[:blockOverhead+1], index: first + int64(blocks) - 1},
+		{name: "shorter than the overhead", block: block(sealed, 0)[:blockOverhead-1], index: first},
+		{name: "flipped bit", block: func() []byte {
+			b := bytes.Clone(block(sealed, 2))
+			b[20] ^= 1
+			return b
+		}(), index: first + 2},
+	}
+	for _, tt := range tests {
+		if _, err := f.open(nil, tt.block, tt.index); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("%s: err = %v, want ErrDecryptionFailed", tt.name, err)
+		}
+	}
+}
+
+// newEncryptingClient starts a master and chunkserver, and returns a client
+// that encrypts with a new local key and cuts files into chunks of chunkSize
+func newEncryptingClient(t *testing.T, chunkSize int64) (*fakeChunkserver, *Client) {
+	t.Helper()
+	keyFile := filepath.Join(t.TempDir(), "key")
+	key := make([]byte, 32)
+	rand.Read(key)
+	if err := os.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	provider, err := NewLocalKeyProvider(keyFile)
+	if err != nil {
+		t.Fatalf("NewLocalKeyProvider: %v", err)
+	}
+	s := startFakeChunkserver(t)
+	_, c := startTestMaster(t, s, WithEncryption(provider), WithMaxChunkSize(chunkSize))
+	return s, c
+}
+
+// storedSize returns the bytes the master records for a file
+func storedSize(t *testing.T, c *Client, path string) int64 {
+	t.Helper()
+	chunks, err := c.GetChunkLocationsWithNamespace(context.Background(), path, "ns")
+	if err != nil {
+		t.Fatalf("GetChunkLocations: %v", err)
+	}
+	var size int64
+	for _, chunk := range chunks {
+		size += int64(chunk.Size)
+	}
+	return size
+}
+
+func TestEncryptedWrites(t *testing.T) {
+	// Chunks that no whole number of sealed blocks fills, so blocks span chunks
+	const chunkSize = 3*(encryptionBlockSize+blockOverhead) - 1000
+	type write struct {
+		offset int64 // Negative to append
+		size   int
+	}
+	tests := []struct {
+		name   string
+		writes []write
+	}{
+		{name: "appends within a block", writes: []write{{-1, 10}, {-1, 20}, {-1, 30}}},
+		{name: "appends across blocks", writes: []write{{-1, 70000}, {-1, 70000}, {-1, 5}}},
+		{name: "appends across chunks", writes: []write{{-1, 190000}, {-1, 10}, {-1, 3000}, {-1, 200000}}},
+		{name: "append of whole blocks", writes: []write{{-1, encryptionBlockSize}, {-1, 2 * encryptionBlockSize}}},
+		{name: "unaligned writes inside", writes: []write{{-1, 200000}, {encryptionBlockSize - 6, 20}, {1, 1}, {131000, 70000}}},
+		{name: "write over the tail", writes: []write{{-1, 100}, {90, 50}, {-1, 7}}},
+		{name: "write past the end", writes: []write{{-1, 10}, {150000, 10}, {-1, 3}}},
+		{name: "write at the end", writes: []write{{-1, 100}, {100, 100}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, c := newEncryptingClient(t, chunkSize)
+			ctx := context.Background()
+			rng := mathrand.New(mathrand.NewSource(1))
+
+			var want []byte
+			for i, w := range tt.writes {
+				data := make([]byte, w.size)
+				rng.Read(data)
+				var n int
+				var err error
+				if w.offset < 0 {
+					n, err = c.AppendWithNamespace(ctx, "/f", "ns", data)
+					want = append(want, data...)
+				} else {
+					n, err = c.WriteAtWithNamespace(ctx, "/f", "ns", data, w.offset)
+					if end := int(w.offset) + w.size; end > len(want) {
+						want = append(want, make([]byte, end-len(want))...)
+					}
+					copy(want[w.offset:], data)
+				}
+				if err != nil || n != w.size {
+					t.Fatalf("write %d = %d, %v; want %d", i, n, err, w.size)
+				}
+
+				got, err := c.ReadWithNamespace(ctx, "/f", "ns")
+				if err != nil {
+					t.Fatalf("read after write %d: %v", i, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("after write %d read %d bytes not matching the %d written", i, len(got), len(want))
+				}
+				if got, want := storedSize(t, c, "/f"), sealedSize(int64(len(want)), encryptionBlockSize); got != want {
+					t.Fatalf("after write %d the file stores %d bytes, want %d", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// Concurrent appenders each reseal the shared last block; none may lose another's data
+func TestEncryptedAppendConcurrent(t *testing.T) {
+	_, c := newEncryptingClient(t, 2*(encryptionBlockSize+blockOverhead)+5000)
+	ctx := context.Background()
+	const writers, records = 6, 15
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range records {
+				record := fmt.Sprintf("<writer %d record %02d %s>", w, r, bytes.Repeat([]byte{'x'}, 3000))
+				if _, err := c.AppendWithNamespace(ctx, "/log", "ns", []byte(record)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("append: %v", err)
+	}
+
+	got, err := c.ReadWithNamespace(ctx, "/log", "ns")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for w := range writers {
+		for r := range records {
+			record := fmt.Sprintf("<writer %d record %02d %s>", w, r, bytes.Repeat([]byte{'x'}, 3000))
+			if bytes.Count(got, []byte(record)) != 1 {
+				t.Errorf("record %d of writer %d appears %d times", r, w, bytes.Count(got, []byte(record)))
+			}
+		}
+	}
+	if len(got) != writers*records*len(fmt.Sprintf("<writer 0 record 00 %s>", bytes.Repeat([]byte{'x'}, 3000))) {
+		t.Errorf("file is %d bytes, want exactly the records", len(got))
+	}
+}
+
+// Appends keep the file's cipher instead of fetching its key again
+func TestEncryptedAppendKeepsCipher(t *testing.T) {
+	_, c := newEncryptingClient(t, 64<<20)
+	ctx := context.Background()
+	if _, err := c.AppendWithNamespace(ctx, "/f", "ns", []byte("first")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	key := fileKey{namespace: "ns", path: "/f"}
+	c.cipherMu.Lock()
+	f := c.fileCiphers[key]
+	c.cipherMu.Unlock()
+	if f == nil {
+		t.Fatal("no cipher cached after the first append")
+	}
+	for range 3 {
+		if _, err := c.AppendWithNamespace(ctx, "/f", "ns", []byte("more")); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	c.cipherMu.Lock()
+	kept := c.fileCiphers[key]
+	c.cipherMu.Unlock()
+	if kept != f {
+		t.Error("appends dropped the cached cipher")
+	}
+}
+
+// Blocks swapped or cut short on the chunkserver fail to decrypt
+func TestEncryptedTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(stored []byte) []byte
+	}{
+		{name: "swapped blocks", tamper: func(b []byte) []byte {
+			n := encryptionBlockSize + blockOverhead
+			swapped := bytes.Clone(b)
+			copy(swapped[:n], b[n:2*n])
+			copy(swapped[n:2*n], b[:n])
+			return swapped
+		}},
+		{name: "truncated last block", tamper: func(b []byte) []byte {
+			// Keep the size the master records; the last bytes are lost
+			cut := bytes.Clone(b)
+			clear(cut[len(cut)-3:])
+			return cut
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c := newEncryptingClient(t, 64<<20)
+			ctx := context.Background()
+			data := make([]byte, 2*encryptionBlockSize+100)
+			rand.Read(data)
+			if _, err := c.AppendWithNamespace(ctx, "/f", "ns", data); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			chunks, err := c.GetChunkLocationsWithNamespace(ctx, "/f", "ns")
+			if err != nil || len(chunks) != 1 {
+				t.Fatalf("GetChunkLocations = %d chunks, %v", len(chunks), err)
+			}
+			s.mu.Lock()
+			s.fragments[chunks[0].ChunkHandle] = tt.tamper(s.fragments[chunks[0].ChunkHandle])
+			s.mu.Unlock()
+
+			if _, err := c.ReadWithNamespace(ctx, "/f", "ns"); !errors.Is(err, ErrDecryptionFailed) {
+				t.Errorf("read err = %v, want ErrDecryptionFailed", err)
+			}
+		})
+	}
+}
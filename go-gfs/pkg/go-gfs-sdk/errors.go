@@ -1,6 +1,10 @@
 package gfs
 
-import "errors"
+import (
+	"errors"
+	"io/fs"
+	"strings"
+)
 
 var (
 	// ErrNoChunkLocations indicates the master returned no chunk locations.
@@ -11,4 +15,42 @@ var (
 	ErrNoReplica = errors.New("no replica available for read")
 	// ErrInvalidOffset indicates the provided offset is invalid.
 	ErrInvalidOffset = errors.New("invalid offset")
+	// ErrQuotaExceeded indicates the namespace quota rejected a new file or chunk.
+	ErrQuotaExceeded = errors.New("namespace quota exceeded")
+	// ErrErasureCoded indicates a write to a chunk that was erasure coded and is read-only.
+	ErrErasureCoded = errors.New("chunk is erasure coded and read-only")
+	// ErrPreconditionFailed indicates a conditional change found a file not as expected,
+	// including a create or rename onto a file that already exists.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrWatchExpired indicates a watch can't resume because the master no longer has the changes since its token.
+	ErrWatchExpired = errors.New("watch resume token expired")
+	// ErrEncrypted indicates an operation that isn't supported on encrypted files.
+	ErrEncrypted = errors.New("not supported on encrypted files")
+	// ErrDecryptionFailed indicates encrypted data or a wrapped key that failed
+	// authentication: it was altered, or belongs to another file or key.
+	ErrDecryptionFailed = errors.New("decryption failed")
 )
+
+// quotaError carries the master's quota message and matches ErrQuotaExceeded.
+type quotaError struct {
+	message string
+}
+
+func (e *quotaError) Error() string { return e.message }
+
+func (e *quotaError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+// preconditionError carries the master's message and matches ErrPreconditionFailed.
+type preconditionError struct {
+	message string
+}
+
+func (e *preconditionError) Error() string { return e.message }
+
+func (e *preconditionError) Is(target error) bool { return target == ErrPreconditionFailed }
+
+// IsNotFound reports whether err says a file doesn't exist, either from the
+// master or as fs.ErrNotExist.
+func IsNotFound(err error) bool {
+	return err != nil && (errors.Is(err, fs.ErrNotExist) || strings.Contains(err.Error(), "file not found"))
+}
@@ -0,0 +1,406 @@
+package gfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// readAheadWindow is how much a File fetches per read-ahead request.
+const readAheadWindow = 4 << 20
+
+// File is a GFS file opened for reading. It implements io.Reader, io.Seeker,
+// io.ReaderAt, io.WriterTo and io.Closer, so it works with io.Copy, bufio and the
+// archive packages. Sequential reads fetch the following bytes in the background,
+// up to the client's read concurrency in windows of 4 MiB, across chunk boundaries.
+//
+// Read and Seek share an offset and must not be called concurrently; ReadAt may be.
+type File struct {
+	client    *Client
+	ctx       context.Context
+	cancel    context.CancelFunc
+	path      string
+	namespace string
+	size      int64
+
+	mu      sync.Mutex
+	offset  int64
+	windows []*readWindow // Read-ahead in file order, the first holding or following offset
+	closed  bool
+}
+
+// readWindow is one read-ahead request.
+type readWindow struct {
+	off    int64
+	want   int64
+	data   []byte
+	err    error
+	done   chan struct{}
+	cancel context.CancelFunc // Stops the request once the window is discarded
+}
+
+func (w *readWindow) end() int64 { return w.off + w.want }
+
+// Open opens a file in a namespace for reading. The file's size is read when it is
+// opened; bytes appended later are not seen. ctx bounds every read of the file.
+func (c *Client) Open(ctx context.Context, namespace, path string) (*File, error) {
+	namespace = normalizeNamespace(namespace)
+
+	// Fetch the chunks fresh so the size includes appends by other clients
+	c.invalidateChunkCache(path, namespace)
+	chunks, err := c.getCachedChunks(ctx, path, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	for _, chunk := range chunks {
+		size += int64(chunk.Size)
+	}
+	cipher, err := c.fileCipher(ctx, path, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if cipher != nil {
+		size = plainSize(size, cipher.blockSize)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &File{
+		client:    c,
+		ctx:       ctx,
+		cancel:    cancel,
+		path:      path,
+		namespace: namespace,
+		size:      size,
+	}, nil
+}
+
+// Name returns the file's path.
+func (f *File) Name() string { return f.path }
+
+// Namespace returns the file's namespace.
+func (f *File) Namespace() string { return f.namespace }
+
+// Size returns the file's size when it was opened.
+func (f *File) Size() int64 { return f.size }
+
+// Read implements io.Reader.
+func (f *File) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	f.dropWindowsLocked()
+	f.fillWindowsLocked()
+
+	w := f.windows[0]
+	<-w.done
+	if w.err != nil {
+		f.cancelWindowsLocked()
+		return 0, w.err
+	}
+	start := f.offset - w.off
+	if start >= int64(len(w.data)) {
+		// The file was truncated or replaced since it was opened
+		f.cancelWindowsLocked()
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, w.data[start:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+// fillWindowsLocked starts read-ahead requests up to the client's read concurrency
+// Must be called with mu held
+func (f *File) fillWindowsLocked() {
+	next := f.offset
+	if n := len(f.windows); n > 0 {
+		next = f.windows[n-1].end()
+	}
+	for len(f.windows) < max(f.client.readConcurrency, 1) && next < f.size {
+		ctx, cancel := context.WithCancel(f.ctx)
+		w := &readWindow{off: next, want: min(readAheadWindow, f.size-next), done: make(chan struct{}), cancel: cancel}
+		go f.fetch(ctx, w)
+		f.windows = append(f.windows, w)
+		next = w.end()
+	}
+}
+
+func (f *File) fetch(ctx context.Context, w *readWindow) {
+	defer close(w.done)
+	defer w.cancel()
+	buf := make([]byte, w.want)
+	n, err := f.client.ReadAtWithNamespace(ctx, f.path, f.namespace, buf, w.off)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	w.data, w.err = buf[:n], err
+}
+
+// dropWindowsLocked discards the windows behind the offset, and all of them when
+// the offset is outside them, stopping their requests
+// Must be called with mu held
+func (f *File) dropWindowsLocked() {
+	for len(f.windows) > 0 && f.windows[0].end() <= f.offset {
+		f.windows[0].cancel()
+		f.windows = f.windows[1:]
+	}
+	if len(f.windows) > 0 && f.windows[0].off > f.offset {
+		f.cancelWindowsLocked()
+	}
+}
+
+// cancelWindowsLocked discards every window, stopping their requests
+// Must be called with mu held
+func (f *File) cancelWindowsLocked() {
+	for _, w := range f.windows {
+		w.cancel()
+	}
+	f.windows = nil
+}
+
+// Seek implements io.Seeker. Seeking past the end is allowed; reads there return io.EOF.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, fmt.Errorf("seek: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	f.offset = offset
+	// Read-ahead the new offset can't use would only hold a connection
+	f.dropWindowsLocked()
+	return offset, nil
+}
+
+// ReadAt implements io.ReaderAt. It reads directly, without read-ahead, and does
+// not move the offset used by Read.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if f.isClosed() {
+		return 0, fs.ErrClosed
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	if remaining := f.size - off; int64(len(p)) > remaining {
+		n, err := f.client.ReadAtWithNamespace(f.ctx, f.path, f.namespace, p[:remaining], off)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return f.client.ReadAtWithNamespace(f.ctx, f.path, f.namespace, p, off)
+}
+
+// WriteTo implements io.WriterTo, streaming the rest of the file straight from
+// the chunkservers to w. io.Copy uses it when copying from a File.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.offset >= f.size {
+		return 0, nil
+	}
+	f.cancelWindowsLocked()
+	n, err := f.client.ReadRangeToWithNamespace(f.ctx, f.path, f.namespace, f.offset, f.size-f.offset, w)
+	f.offset += n
+	return n, err
+}
+
+// Close stops any read-ahead. Later calls fail with fs.ErrClosed.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	f.cancelWindowsLocked()
+	f.cancel()
+	return nil
+}
+
+func (f *File) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// Writer appends to a GFS file through a buffer. Writes collect in the buffer and
+// are sent as one append each time it reaches the upload buffer size (a chunk by
+// default); Close sends the rest. Until Close returns, buffered data is not in the
+// file. After a failed append every call returns the same error.
+type Writer struct {
+	client    *Client
+	ctx       context.Context
+	path      string
+	namespace string
+	limit     int
+
+	mu      sync.Mutex
+	buf     []byte
+	written int64
+	err     error
+	closed  bool
+}
+
+// Create creates a file in a namespace and returns a Writer for it. It fails with
+// an error matching ErrPreconditionFailed if the file already exists.
+// ctx bounds every append made through the Writer.
+func (c *Client) Create(ctx context.Context, namespace, path string) (*Writer, error) {
+	namespace = normalizeNamespace(namespace)
+	if _, err := c.CreateFileWithNamespace(ctx, path, namespace); err != nil {
+		return nil, err
+	}
+	c.markFileKnown(path, namespace)
+	return c.newWriter(ctx, namespace, path), nil
+}
+
+// OpenAppend returns a Writer that appends to a file in a namespace, creating the
+// file if it doesn't exist. ctx bounds every append made through the Writer.
+func (c *Client) OpenAppend(ctx context.Context, namespace, path string) (*Writer, error) {
+	namespace = normalizeNamespace(namespace)
+	if _, err := c.CreateFileWithNamespace(ctx, path, namespace); err != nil && !errors.Is(err, ErrPreconditionFailed) {
+		return nil, err
+	}
+	c.markFileKnown(path, namespace)
+	return c.newWriter(ctx, namespace, path), nil
+}
+
+func (c *Client) newWriter(ctx context.Context, namespace, path string) *Writer {
+	return &Writer{
+		client:    c,
+		ctx:       ctx,
+		path:      path,
+		namespace: namespace,
+		limit:     int(c.effectiveUploadBufferSize()),
+	}
+}
+
+// Name returns the file's path.
+func (w *Writer) Name() string { return w.path }
+
+// Written returns how many bytes have been appended to the file so far, not
+// counting data still in the buffer.
+func (w *Writer) Written() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	total := 0
+	for len(p) > 0 {
+		n := min(len(p), w.limit-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		total += n
+		if len(w.buf) >= w.limit {
+			if err := w.flushLocked(); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// ReadFrom implements io.ReaderFrom. It sends any buffered data, then streams r
+// with double-buffered appends. io.Copy uses it when copying to a Writer.
+func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fs.ErrClosed
+	}
+	if err := w.flushLocked(); err != nil {
+		return 0, err
+	}
+	n, err := w.client.AppendFromWithNamespace(w.ctx, w.path, w.namespace, r)
+	w.written += n
+	if err != nil {
+		w.err = fmt.Errorf("append to %s failed: %w", w.path, err)
+		return n, w.err
+	}
+	return n, nil
+}
+
+// flushLocked appends the buffered data to the file
+// Must be called with mu held
+func (w *Writer) flushLocked() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	n, err := w.client.appendData(w.ctx, w.path, w.namespace, w.buf)
+	w.written += int64(n)
+	w.buf = w.buf[:0]
+	if err != nil {
+		w.err = fmt.Errorf("append to %s failed: %w", w.path, err)
+	}
+	return w.err
+}
+
+// Close appends any buffered data and reports the first append that failed.
+// Later calls fail with fs.ErrClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return fs.ErrClosed
+	}
+	w.closed = true
+	err := w.flushLocked()
+	w.buf = nil
+	return err
+}
+
+var (
+	_ io.ReadSeekCloser = (*File)(nil)
+	_ io.ReaderAt       = (*File)(nil)
+	_ io.WriterTo       = (*File)(nil)
+	_ io.WriteCloser    = (*Writer)(nil)
+	_ io.ReaderFrom     = (*Writer)(nil)
+)
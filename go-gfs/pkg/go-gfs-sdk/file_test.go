@@ -0,0 +1,320 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func openTestFile(t *testing.T, c *Client) *File {
+	t.Helper()
+	f, err := c.Open(context.Background(), "ns", "/f")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// Read, Seek and ReadAt agree with the file's bytes across its chunk boundaries
+func TestFileReader(t *testing.T) {
+	_, c, data := threeChunkFile(t)
+	if err := iotest.TestReader(openTestFile(t, c), data); err != nil {
+		t.Error(err)
+	}
+
+	for _, size := range []int{1, 7, 100, 149, 1000} {
+		t.Run(fmt.Sprintf("reads of %d", size), func(t *testing.T) {
+			f := openTestFile(t, c)
+			var got []byte
+			p := make([]byte, size)
+			for {
+				n, err := f.Read(p)
+				got = append(got, p[:n]...)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Read: %v", err)
+				}
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("read %d bytes not matching the file", len(got))
+			}
+		})
+	}
+}
+
+func TestFileSeek(t *testing.T) {
+	_, c, data := threeChunkFile(t)
+	size := int64(len(data))
+	f := openTestFile(t, c)
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{98, io.SeekStart, 98},
+		{4, io.SeekCurrent, 106}, // After reading 4 bytes at 98
+		{-30, io.SeekEnd, size - 30},
+		{-100, io.SeekCurrent, size - 30 + 4 - 100},
+		{0, io.SeekStart, 0},
+		{10, io.SeekEnd, size + 10},
+	}
+	for _, tt := range tests {
+		pos, err := f.Seek(tt.offset, tt.whence)
+		if err != nil || pos != tt.want {
+			t.Fatalf("Seek(%d, %d) = %d, %v; want %d", tt.offset, tt.whence, pos, err, tt.want)
+		}
+		p := make([]byte, 4)
+		n, err := io.ReadFull(f, p)
+		if pos >= size {
+			if n != 0 || err != io.EOF {
+				t.Errorf("read past the end = %d, %v; want EOF", n, err)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(p, data[pos:pos+4]) {
+			t.Errorf("read at %d = %q, %v; want %q", pos, p[:n], err, data[pos:pos+4])
+		}
+	}
+
+	if _, err := f.Seek(-1, io.SeekStart); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("negative seek: err = %v, want ErrInvalidOffset", err)
+	}
+	if _, err := f.Seek(0, 7); err == nil {
+		t.Error("seek with an invalid whence succeeded")
+	}
+}
+
+func TestFileReadAtAndWriteTo(t *testing.T) {
+	_, c, data := threeChunkFile(t)
+	f := openTestFile(t, c)
+
+	// ReadAt leaves the offset Read uses alone
+	if _, err := f.Seek(10, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	p := make([]byte, 60)
+	if n, err := f.ReadAt(p, 95); n != 60 || err != nil || !bytes.Equal(p, data[95:155]) {
+		t.Errorf("ReadAt across two boundaries = %d, %v", n, err)
+	}
+	if n, err := f.ReadAt(p, int64(len(data))-20); n != 20 || err != io.EOF || !bytes.Equal(p[:n], data[len(data)-20:]) {
+		t.Errorf("ReadAt over the end = %d, %v; want 20, EOF", n, err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := io.Copy(&buf, f); err != nil || n != int64(len(data)-10) || !bytes.Equal(buf.Bytes(), data[10:]) {
+		t.Errorf("WriteTo from 10 = %d, %v", n, err)
+	}
+	if n, err := f.WriteTo(&buf); n != 0 || err != nil {
+		t.Errorf("WriteTo at the end = %d, %v; want nothing", n, err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := f.Read(p); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read after Close: err = %v, want ErrClosed", err)
+	}
+	if _, err := f.ReadAt(p, 0); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("ReadAt after Close: err = %v, want ErrClosed", err)
+	}
+	if err := f.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("second Close: err = %v, want ErrClosed", err)
+	}
+}
+
+// Small reads are served from one read-ahead request per chunk, and seeks inside
+// the window reuse it
+func TestFileReadAhead(t *testing.T) {
+	s, c, data := threeChunkFile(t)
+	f := openTestFile(t, c)
+
+	p := make([]byte, 10)
+	for off := 0; off < len(data); off += len(p) {
+		if _, err := io.ReadFull(f, p[:min(len(p), len(data)-off)]); err != nil {
+			t.Fatalf("Read at %d: %v", off, err)
+		}
+	}
+	if got := s.readCount(); got != 3 {
+		t.Errorf("%d chunk reads for 18 small reads, want 3", got)
+	}
+
+	if _, err := f.Seek(50, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	if _, err := io.ReadFull(f, p); err != nil || !bytes.Equal(p, data[50:60]) {
+		t.Fatalf("Read after seeking back = %q, %v", p, err)
+	}
+	if got := s.readCount(); got != 3 {
+		t.Errorf("%d chunk reads after seeking back into the window, want 3", got)
+	}
+}
+
+// A seek that leaves read-ahead behind stops its requests
+func TestFileSeekCancelsReadAhead(t *testing.T) {
+	s := startFakeChunkserver(t)
+	m, c := startTestMaster(t, s, WithReadConcurrency(2))
+	data := make([]byte, 3*readAheadWindow+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	writeTestChunks(t, m, s, "ns", "/f", data)
+
+	// The second window never arrives
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	s.mu.Lock()
+	s.onRead = func(handle string, offset int64) {
+		if offset >= readAheadWindow && offset < 2*readAheadWindow {
+			<-release
+		}
+	}
+	s.mu.Unlock()
+
+	f := openTestFile(t, c)
+	p := make([]byte, 10)
+	if _, err := io.ReadFull(f, p); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	f.mu.Lock()
+	if len(f.windows) != 2 {
+		f.mu.Unlock()
+		t.Fatalf("%d windows in flight, want 2", len(f.windows))
+	}
+	stalled := f.windows[1]
+	f.mu.Unlock()
+
+	if _, err := f.Seek(2*readAheadWindow+50, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	select {
+	case <-stalled.done:
+		if stalled.err == nil {
+			t.Error("discarded window finished without an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("discarded window's request is still running")
+	}
+
+	// The file itself is still usable
+	if _, err := io.ReadFull(f, p); err != nil || !bytes.Equal(p, data[2*readAheadWindow+50:][:10]) {
+		t.Errorf("Read after the seek = %v, %v", p, err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	s := startFakeChunkserver(t)
+	_, c := startTestMaster(t, s, WithUploadBufferSize(100))
+	ctx := context.Background()
+
+	w, err := c.Create(ctx, "ns", "/w")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := c.Create(ctx, "ns", "/w"); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Create of an existing file: err = %v, want ErrPreconditionFailed", err)
+	}
+
+	var want []byte
+	steps := []struct {
+		size    int
+		written int64 // Bytes in the file after the write
+	}{
+		{30, 0},
+		{60, 0},
+		{15, 100}, // Fills the buffer
+		{250, 300},
+		{4, 300},
+	}
+	for i, step := range steps {
+		data := bytes.Repeat([]byte{byte('a' + i)}, step.size)
+		want = append(want, data...)
+		if n, err := w.Write(data); n != step.size || err != nil {
+			t.Fatalf("write %d = %d, %v", i, n, err)
+		}
+		if got := w.Written(); got != step.written {
+			t.Errorf("after write %d Written = %d, want %d", i, got, step.written)
+		}
+		if got := storedSize(t, c, "/w"); got != step.written {
+			t.Errorf("after write %d the file holds %d bytes, want %d", i, got, step.written)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got, err := c.ReadWithNamespace(ctx, "/w", "ns"); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("after Close read %d bytes, %v; want the %d written", len(got), err, len(want))
+	}
+	if _, err := w.Write([]byte("x")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Write after Close: err = %v, want ErrClosed", err)
+	}
+	if err := w.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("second Close: err = %v, want ErrClosed", err)
+	}
+
+	// OpenAppend continues the file
+	w, err = c.OpenAppend(ctx, "ns", "/w")
+	if err != nil {
+		t.Fatalf("OpenAppend: %v", err)
+	}
+	if _, err := w.Write([]byte("tail")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got, err := c.ReadWithNamespace(ctx, "/w", "ns"); err != nil || !bytes.Equal(got, append(want, "tail"...)) {
+		t.Errorf("after OpenAppend read %d bytes, %v", len(got), err)
+	}
+}
+
+// After a failed append every call returns the same error, even once appends would work
+func TestWriterStickyError(t *testing.T) {
+	s := startFakeChunkserver(t)
+	_, c := startTestMaster(t, s, WithUploadBufferSize(100))
+	ctx := context.Background()
+	w, err := c.Create(ctx, "ns", "/w")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := w.Write(make([]byte, 50)); err != nil {
+		t.Fatalf("buffered write: %v", err)
+	}
+
+	s.mu.Lock()
+	s.failWrites = true
+	s.mu.Unlock()
+	n, failed := w.Write(make([]byte, 80))
+	if failed == nil {
+		t.Fatal("write filling the buffer succeeded with appends failing")
+	}
+	if n != 50 {
+		t.Errorf("failed write reported %d bytes taken, want 50", n)
+	}
+
+	s.mu.Lock()
+	s.failWrites = false
+	s.mu.Unlock()
+	if _, err := w.Write([]byte("x")); err != failed {
+		t.Errorf("Write after a failure: err = %v, want %v", err, failed)
+	}
+	if _, err := w.ReadFrom(bytes.NewReader([]byte("x"))); err != failed {
+		t.Errorf("ReadFrom after a failure: err = %v, want %v", err, failed)
+	}
+	if err := w.Close(); err != failed {
+		t.Errorf("Close after a failure: err = %v, want %v", err, failed)
+	}
+	if got := storedSize(t, c, "/w"); got != 0 {
+		t.Errorf("file holds %d bytes, want none", got)
+	}
+}
@@ -0,0 +1,297 @@
+package gfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// FileSystem presents the files of a namespace as an io/fs file system. It
+// implements fs.FS, fs.ReadDirFS and fs.StatFS, so it works with fs.WalkDir,
+// fs.Glob, template.ParseFS and http.FS.
+//
+// The name "a/b.txt" is the GFS path "/a/b.txt"; paths without a leading slash
+// are not visible. Directories are derived from "/"-separated paths: one exists
+// wherever a file's path continues past it, so there are no empty directories.
+// A file shadows a directory with the same name.
+type FileSystem struct {
+	client    *Client
+	namespace string
+	ctx       context.Context
+}
+
+// FS returns a read-only file system over a namespace.
+func FS(c *Client, namespace string) *FileSystem {
+	return &FileSystem{client: c, namespace: normalizeNamespace(namespace), ctx: context.Background()}
+}
+
+// WithContext returns a copy of the file system whose calls, and the reads of
+// files it opens, use ctx.
+func (fsys *FileSystem) WithContext(ctx context.Context) *FileSystem {
+	copied := *fsys
+	copied.ctx = ctx
+	return &copied
+}
+
+// Open opens a file for reading, or a directory for ReadDir. Files are *File
+// handles and also implement io.Seeker, io.ReaderAt and io.WriterTo.
+func (fsys *FileSystem) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if info.IsDir() {
+		return &dirHandle{fsys: fsys, name: name, info: info}, nil
+	}
+
+	f, err := fsys.client.Open(fsys.ctx, fsys.namespace, gfsPath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fsError(err)}
+	}
+	return &fileHandle{File: f, info: info}, nil
+}
+
+// Stat returns information about a file or directory.
+func (fsys *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := fsys.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+func (fsys *FileSystem) stat(name string) (*fileInfo, error) {
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+
+	file, err := fsys.client.GetFileWithNamespace(fsys.ctx, gfsPath(name), fsys.namespace)
+	if err == nil {
+		return newFileInfo(file), nil
+	}
+	if !IsNotFound(err) {
+		return nil, err
+	}
+
+	// Not a file; a directory if any path continues past it
+	page, err := fsys.client.ListFilesPage(fsys.ctx, ListOptions{
+		Namespace: fsys.namespace,
+		Prefix:    gfsPath(name) + "/",
+		Delimiter: "/",
+		Limit:     1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Files) == 0 && len(page.CommonPrefixes) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// ReadDir lists a directory sorted by name.
+func (fsys *FileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := fsys.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+func (fsys *FileSystem) readDir(name string) ([]fs.DirEntry, error) {
+	prefix := "/"
+	if name != "." {
+		prefix = gfsPath(name) + "/"
+	}
+
+	var entries []fs.DirEntry
+	opts := ListOptions{Namespace: fsys.namespace, Prefix: prefix, Delimiter: "/"}
+	for {
+		page, err := fsys.client.ListFilesPage(fsys.ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range page.Files {
+			if base := strings.TrimPrefix(file.Path, prefix); validEntryName(base) {
+				entries = append(entries, fs.FileInfoToDirEntry(newFileInfo(file)))
+			}
+		}
+		for _, dir := range page.CommonPrefixes {
+			if base := strings.TrimSuffix(strings.TrimPrefix(dir, prefix), "/"); validEntryName(base) {
+				entries = append(entries, fs.FileInfoToDirEntry(&fileInfo{name: base, dir: true}))
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = page.NextPageToken
+	}
+
+	if len(entries) == 0 && name != "." {
+		// Empty means no such directory, unless name is a file
+		if _, err := fsys.client.GetFileWithNamespace(fsys.ctx, gfsPath(name), fsys.namespace); err == nil {
+			return nil, errors.New("not a directory")
+		}
+		return nil, fs.ErrNotExist
+	}
+
+	// A file and a directory can share a name; keep the file, as Stat does
+	slices.SortStableFunc(entries, func(a, b fs.DirEntry) int {
+		if c := strings.Compare(a.Name(), b.Name()); c != 0 {
+			return c
+		}
+		if !a.IsDir() && b.IsDir() {
+			return -1
+		}
+		if a.IsDir() && !b.IsDir() {
+			return 1
+		}
+		return 0
+	})
+	entries = slices.CompactFunc(entries, func(a, b fs.DirEntry) bool { return a.Name() == b.Name() })
+	return entries, nil
+}
+
+// gfsPath returns the GFS path of a valid fs name other than ".".
+func gfsPath(name string) string {
+	return "/" + name
+}
+
+// validEntryName reports whether one path element can appear in a directory
+// listing; paths with empty elements such as "/a//b" are skipped.
+func validEntryName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// fsError maps SDK errors to their io/fs equivalents where there is one.
+func fsError(err error) error {
+	switch {
+	case IsNotFound(err):
+		return fs.ErrNotExist
+	case errors.Is(err, ErrPreconditionFailed):
+		return fs.ErrExist
+	}
+	return err
+}
+
+// fileInfo describes a file or directory of a FileSystem.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	file    *pb.FileInfoResponse
+}
+
+func newFileInfo(file *pb.FileInfoResponse) *fileInfo {
+	return &fileInfo{
+		name:    path.Base(file.Path),
+		size:    int64(file.Size),
+		modTime: time.Unix(file.ModifiedAt, 0),
+		file:    file,
+	}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+
+// Sys returns the file's *pb.FileInfoResponse, or nil for a directory.
+func (fi *fileInfo) Sys() any {
+	if fi.file == nil {
+		return nil
+	}
+	return fi.file
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// fileHandle is a file opened through a FileSystem.
+type fileHandle struct {
+	*File
+	info *fileInfo
+}
+
+func (f *fileHandle) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// dirHandle is a directory opened through a FileSystem. Its entries are listed
+// on the first ReadDir.
+type dirHandle struct {
+	fsys    *FileSystem
+	name    string
+	info    *fileInfo
+	entries []fs.DirEntry
+	listed  bool
+	closed  bool
+}
+
+func (d *dirHandle) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dirHandle) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirHandle) Close() error {
+	if d.closed {
+		return fs.ErrClosed
+	}
+	d.closed = true
+	return nil
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dirHandle) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, fs.ErrClosed
+	}
+	if !d.listed {
+		entries, err := d.fsys.readDir(d.name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+		d.entries, d.listed = entries, true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+var (
+	_ fs.FS          = (*FileSystem)(nil)
+	_ fs.ReadDirFS   = (*FileSystem)(nil)
+	_ fs.StatFS      = (*FileSystem)(nil)
+	_ fs.ReadDirFile = (*dirHandle)(nil)
+	_ io.ReadSeeker  = (*fileHandle)(nil)
+)
@@ -0,0 +1,122 @@
+package gfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/master"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+)
+
+// startTestMaster serves a master whose three chunkservers are all s, and
+// returns a client connected to it
+func startTestMaster(t *testing.T, s *fakeChunkserver, opts ...Option) (*master.Master, *Client) {
+	t.Helper()
+	m, err := master.NewMaster(filepath.Join(t.TempDir(), "wal.log"))
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	s.master = m
+	loc := s.location()
+	for _, id := range []string{"cs1", "cs2", "cs3"} {
+		m.RegisterChunkServer(master.ChunkServerID(id), loc.Hostname, int(loc.DataPort), 0, id, nil)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterMasterServer(server, master.NewGRPCServer(m))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	opts = append([]Option{WithSecretProvider(func(*jwt.Token) (any, error) { return []byte("secret"), nil })}, opts...)
+	c, err := New(context.Background(), lis.Addr().String(), opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return m, c
+}
+
+// writeTestFile creates a file in one committed chunk held by s
+func writeTestFile(t *testing.T, m *master.Master, s *fakeChunkserver, namespace, path string, data []byte) {
+	t.Helper()
+	if len(data) == 0 {
+		writeTestChunks(t, m, s, namespace, path)
+		return
+	}
+	writeTestChunks(t, m, s, namespace, path, data)
+}
+
+// writeTestChunks creates a file with one committed chunk held by s per element of chunks
+func writeTestChunks(t *testing.T, m *master.Master, s *fakeChunkserver, namespace, path string, chunks ...[]byte) {
+	t.Helper()
+	if _, err := m.CreateFile(path, namespace, "", nil); err != nil {
+		t.Fatalf("CreateFile %s: %v", path, err)
+	}
+	for _, data := range chunks {
+		chunk, err := m.AddChunkToFile(path, namespace)
+		if err != nil {
+			t.Fatalf("AddChunkToFile %s: %v", path, err)
+		}
+		s.mu.Lock()
+		s.fragments[string(chunk.Handle)] = data
+		s.mu.Unlock()
+		for _, loc := range chunk.Locations {
+			if err := m.ConfirmChunkCommit(loc.ServerID, chunk.Handle, uint64(len(data)), 1); err != nil {
+				t.Fatalf("ConfirmChunkCommit %s: %v", path, err)
+			}
+		}
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	s := startFakeChunkserver(t)
+	m, c := startTestMaster(t, s)
+	files := map[string]string{
+		"/a.txt":           "hello",
+		"/dir/b.txt":       "in a directory",
+		"/dir/sub/c.txt":   "nested",
+		"/dir/sub/d/e.txt": "deeper",
+		"/dir/empty":       "",
+		"/z":               "last",
+	}
+	for path, data := range files {
+		writeTestFile(t, m, s, "ns", path, []byte(data))
+	}
+	// Neither visible: no leading slash, and an empty path element
+	writeTestFile(t, m, s, "ns", "relative", []byte("x"))
+	writeTestFile(t, m, s, "ns", "/dir//skipped", []byte("x"))
+	// Another namespace
+	writeTestFile(t, m, s, "other", "/other.txt", []byte("x"))
+
+	fsys := FS(c, "ns")
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c.txt", "dir/sub/d/e.txt", "dir/empty", "z"); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, data := range files {
+		got, err := fs.ReadFile(fsys, path[1:])
+		if err != nil || string(got) != data {
+			t.Errorf("ReadFile(%s) = %q, %v; want %q", path[1:], got, err, data)
+		}
+	}
+	for _, name := range []string{"other.txt", "missing", "dir/missing", "relative"} {
+		if _, err := fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(%s) err = %v, want ErrNotExist", name, err)
+		}
+	}
+	if _, err := fs.ReadDir(fsys, "a.txt"); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir of a file: err = %v, want not a directory", err)
+	}
+}
@@ -0,0 +1,124 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/datatoken"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// grantRenewMargin is how long before expiry a grant is renewed, so it does
+// not lapse while a request is in flight.
+const grantRenewMargin = time.Minute
+
+// grantSweepSize is the cache size above which expired grants are swept out.
+const grantSweepSize = 4096
+
+// grantKey identifies a cached grant.
+type grantKey struct {
+	handle string
+	op     string
+}
+
+type cachedGrant struct {
+	token   string
+	expires time.Time
+}
+
+// grantCache holds the data-plane grants the master has handed out with chunk locations.
+type grantCache struct {
+	mu      sync.Mutex
+	grants  map[grantKey]cachedGrant
+	enabled bool // Set once the master has issued a grant
+}
+
+// rememberGrants caches the grants attached to chunk locations from the master.
+func (c *Client) rememberGrants(chunks ...*pb.ChunkLocationInfo) {
+	for _, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+		c.storeGrant(chunk.ChunkHandle, datatoken.OpRead, chunk.ReadToken)
+		c.storeGrant(chunk.ChunkHandle, datatoken.OpWrite, chunk.WriteToken)
+		if chunk.Stripe != nil {
+			for _, f := range chunk.Stripe.Fragments {
+				c.storeGrant(f.Handle, datatoken.OpRead, f.ReadToken)
+			}
+		}
+	}
+}
+
+func (c *Client) storeGrant(handle, op, token string) {
+	if token == "" {
+		return
+	}
+	expires, err := datatoken.Expiry(token)
+	if err != nil {
+		return
+	}
+
+	c.grants.mu.Lock()
+	defer c.grants.mu.Unlock()
+	if c.grants.grants == nil {
+		c.grants.grants = make(map[grantKey]cachedGrant)
+	}
+	if len(c.grants.grants) >= grantSweepSize {
+		now := time.Now()
+		for key, g := range c.grants.grants {
+			if now.After(g.expires) {
+				delete(c.grants.grants, key)
+			}
+		}
+	}
+	c.grants.grants[grantKey{handle: handle, op: op}] = cachedGrant{token: token, expires: expires}
+	c.grants.enabled = true
+}
+
+// grantFor returns a grant for op on a chunk or fragment, renewing it from the
+// master when it is missing or about to expire. It returns "" while the master
+// has issued no grants, in which case requests rely on the shared secret.
+func (c *Client) grantFor(ctx context.Context, handle, op string) (string, error) {
+	key := grantKey{handle: handle, op: op}
+	c.grants.mu.Lock()
+	g, ok := c.grants.grants[key]
+	enabled := c.grants.enabled
+	c.grants.mu.Unlock()
+
+	if !enabled {
+		return "", nil
+	}
+	if ok && time.Until(g.expires) > grantRenewMargin {
+		return g.token, nil
+	}
+
+	resp, err := c.master.IssueDataToken(ctx, &pb.IssueDataTokenRequest{
+		ChunkHandle: handle,
+		Operation:   op,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to renew grant: %w", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("failed to renew grant: %s", resp.Message)
+	}
+	c.storeGrant(handle, op, resp.Token)
+	return resp.Token, nil
+}
+
+// signRequest signs a data-plane request token with the shared secret.
+// Chunkservers check this signature even when the token carries a grant.
+func (c *Client) signRequest(claims jwt.Claims) (string, error) {
+	secret, err := c.secretProvider(nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret: %w", err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
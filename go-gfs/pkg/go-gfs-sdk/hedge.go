@@ -0,0 +1,125 @@
+package gfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+)
+
+// errHedgeLost stops a hedged read once the other replica has started returning data.
+var errHedgeLost = errors.New("hedged read lost")
+
+// hedgeGate lets the first of two racing reads to produce data write to w;
+// the other's writes fail with errHedgeLost.
+type hedgeGate struct {
+	mu    sync.Mutex
+	owner int
+	w     io.Writer
+}
+
+// claim makes read id the winner unless the other already is.
+func (g *hedgeGate) claim(id int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.owner < 0 {
+		g.owner = id
+	}
+	return g.owner == id
+}
+
+func (g *hedgeGate) claimed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.owner >= 0
+}
+
+func (g *hedgeGate) owns(id int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.owner == id
+}
+
+// hedgeWriter is the writer given to one of the racing reads.
+type hedgeWriter struct {
+	gate *hedgeGate
+	id   int
+}
+
+func (w hedgeWriter) Write(p []byte) (int, error) {
+	if !w.gate.claim(w.id) {
+		return 0, errHedgeLost
+	}
+	return w.gate.w.Write(p)
+}
+
+type hedgeResult struct {
+	id  int
+	n   int64
+	err error
+}
+
+// readChunkRangeHedged reads a chunk range from first, and also from second if
+// first has returned no data within the hedge delay or has failed. The first
+// replica to return data is read to the end and the other is canceled. It
+// returns how many of the two replicas were used and which one served the read.
+func (c *Client) readChunkRangeHedged(ctx context.Context, chunkHandle string, first, second csstructs.ReplicaIdentifier, offset, length int64, w io.Writer) (n int64, used int, server csstructs.ReplicaIdentifier, err error) {
+	gate := &hedgeGate{owner: -1, w: w}
+	results := make(chan hedgeResult, 2)
+	replicas := []csstructs.ReplicaIdentifier{first, second}
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	start := func() {
+		id := len(cancels)
+		readCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		go func() {
+			n, err := c.readChunkRange(readCtx, replicas[id], chunkHandle, offset, length, hedgeWriter{gate, id})
+			if err == nil && !gate.claim(id) {
+				// An empty read can finish before the other replica's data arrives
+				err = errHedgeLost
+			}
+			results <- hedgeResult{id, n, err}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+	hedge := timer.C
+
+	var lastErr error
+	for pending := 1; pending > 0; {
+		select {
+		case <-hedge:
+			hedge = nil
+			if !gate.claimed() {
+				start()
+				pending++
+			}
+		case r := <-results:
+			pending--
+			if gate.owns(r.id) {
+				// This read wrote to w, so its outcome is the result
+				return r.n, len(cancels), replicas[r.id], r.err
+			}
+			if !errors.Is(r.err, errHedgeLost) {
+				lastErr = r.err
+			}
+			if len(cancels) == 1 {
+				hedge = nil
+				start()
+				pending++
+			}
+		}
+	}
+	return 0, len(cancels), replicas[len(cancels)-1], lastErr
+}
@@ -39,6 +39,14 @@ func (c *Client) WriteAt(ctx context.Context, path string, data []byte, offset i
 
 // WriteAtWithNamespace overwrites data starting at the provided offset in a namespace.
 func (c *Client) WriteAtWithNamespace(ctx context.Context, path, namespace string, data []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if c.keyProvider != nil {
+		if n, encrypted, err := c.writeEncryptedAt(ctx, path, normalizeNamespace(namespace), data, offset); encrypted {
+			return n, err
+		}
+	}
 	return c.writeAtData(ctx, path, normalizeNamespace(namespace), data, offset)
 }
 
@@ -62,7 +70,7 @@ func (c *Client) WriteFromFileAtWithNamespace(ctx context.Context, path, localPa
 	for {
 		n, err := file.Read(buf)
 		if n > 0 {
-			written, writeErr := c.writeAtData(ctx, path, normalizeNamespace(namespace), buf[:n], currentOffset)
+			written, writeErr := c.WriteAtWithNamespace(ctx, path, namespace, buf[:n], currentOffset)
 			total += int64(written)
 			currentOffset += int64(written)
 			if writeErr != nil {
@@ -0,0 +1,112 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+// KeyProvider wraps and unwraps the per-file data keys of encrypted files. Only
+// wrapped keys are stored, in the file's attributes, so the data can't be read
+// without the provider. Implementations can hold keys locally or call out to a KMS.
+type KeyProvider interface {
+	// WrapKey encrypts a data key. keyID names the key it was wrapped with and is
+	// stored next to the wrapped key, so keys can be rotated.
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey with the key named keyID.
+	UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error)
+}
+
+// LocalKeyProvider wraps data keys with AES-256-GCM under keys read from local
+// files. New data keys are wrapped with the current key; keys retired by a
+// rotation can still unwrap the files written under them.
+type LocalKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewLocalKeyProvider loads the key file at current, and the files in previous
+// for unwrapping only. A key file holds 32 random bytes, raw or hex-encoded, such
+// as the output of "openssl rand -hex 32".
+func NewLocalKeyProvider(current string, previous ...string) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{keys: make(map[string]cipher.AEAD)}
+	for i, path := range append([]string{current}, previous...) {
+		id, aead, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			p.current = id
+		}
+		p.keys[id] = aead
+	}
+	return p, nil
+}
+
+// loadKeyFile reads a key file and returns its key's ID and cipher.
+func loadKeyFile(path string) (string, cipher.AEAD, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("read key file: %w", err)
+	}
+	key := data
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 64 {
+		if key, err = hex.DecodeString(string(trimmed)); err != nil {
+			return "", nil, fmt.Errorf("key file %s: %w", path, err)
+		}
+	}
+	if len(key) != 32 {
+		return "", nil, fmt.Errorf("key file %s: want 32 bytes or 64 hex characters", path)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", nil, err
+	}
+	// The ID is derived from the key, so it matches wherever the key file is copied
+	sum := sha256.Sum256(key)
+	return "local:" + hex.EncodeToString(sum[:8]), aead, nil
+}
+
+// WrapKey implements KeyProvider.
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	aead := p.keys[p.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(p.current)), p.current, nil
+}
+
+// UnwrapKey implements KeyProvider.
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("unwrap key: %w", ErrDecryptionFailed)
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap key: %w", ErrDecryptionFailed)
+	}
+	return dataKey, nil
+}
+
+// newGCM returns AES-GCM with a 256-bit key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+var _ KeyProvider = (*LocalKeyProvider)(nil)
@@ -0,0 +1,81 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// ListOptions selects a page of a paginated listing.
+type ListOptions struct {
+	// Namespace to list. Empty uses the default namespace.
+	Namespace string
+	// Prefix limits the listing to paths that start with it.
+	Prefix string
+	// Delimiter, usually "/", rolls up paths that contain it after the prefix
+	// into common prefixes, like directories. Empty lists every path.
+	Delimiter string
+	// Limit is the maximum number of files plus common prefixes per page.
+	// Zero uses the master's default of 1000.
+	Limit int
+	// PageToken continues a listing from ListPage.NextPageToken.
+	PageToken string
+}
+
+// ListPage is one page of a listing, sorted by path.
+type ListPage struct {
+	Files          []*pb.FileInfoResponse
+	CommonPrefixes []string
+	// NextPageToken is empty on the last page.
+	NextPageToken string
+}
+
+// ListFilesPage returns one page of files and common prefixes.
+// Pass NextPageToken back in opts.PageToken to get the next page.
+func (c *Client) ListFilesPage(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	resp, err := c.master.ListFilesV2(ctx, &pb.ListFilesV2Request{
+		Namespace: normalizeNamespace(opts.Namespace),
+		Prefix:    opts.Prefix,
+		Delimiter: opts.Delimiter,
+		Limit:     int32(opts.Limit),
+		PageToken: opts.PageToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("list files failed: %s", resp.Message)
+	}
+	c.showPlainSize(resp.Files...)
+	return &ListPage{
+		Files:          resp.Files,
+		CommonPrefixes: resp.CommonPrefixes,
+		NextPageToken:  resp.NextPageToken,
+	}, nil
+}
+
+// IterFiles iterates over every file matching opts in path order, fetching one page at a time.
+// Common prefixes are skipped; use ListFilesPage to see them.
+// Iteration stops after the first error, which is yielded with a nil file.
+func (c *Client) IterFiles(ctx context.Context, opts ListOptions) iter.Seq2[*pb.FileInfoResponse, error] {
+	return func(yield func(*pb.FileInfoResponse, error) bool) {
+		for {
+			page, err := c.ListFilesPage(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, file := range page.Files {
+				if !yield(file, nil) {
+					return
+				}
+			}
+			if page.NextPageToken == "" {
+				return
+			}
+			opts.PageToken = page.NextPageToken
+		}
+	}
+}
@@ -18,20 +18,32 @@ func normalizeNamespace(namespace string) string {
 	return namespace
 }
 
-// CreateFile creates a new file entry.
+// CreateFile creates a new file entry. It fails with an error matching
+// ErrPreconditionFailed if the file already exists.
 func (c *Client) CreateFile(ctx context.Context, path string) (*pb.FileInfoResponse, error) {
 	return c.CreateFileWithNamespace(ctx, path, "")
 }
 
 // CreateFileWithNamespace creates a new file entry with a namespace.
 func (c *Client) CreateFileWithNamespace(ctx context.Context, path, namespace string) (*pb.FileInfoResponse, error) {
+	attributes, err := c.encryptionAttributes(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 	resp, err := c.master.CreateFile(ctx, &pb.CreateFileRequest{
-		Path:      path,
-		Namespace: normalizeNamespace(namespace),
+		Path:       path,
+		Namespace:  normalizeNamespace(namespace),
+		Attributes: attributes,
 	})
 	if err != nil {
 		return nil, err
 	}
+	if resp.QuotaExceeded {
+		return nil, fmt.Errorf("create file failed: %w", &quotaError{resp.Message})
+	}
+	if resp.PreconditionFailed {
+		return nil, fmt.Errorf("create file failed: %w", &preconditionError{resp.Message})
+	}
 	if !resp.Success {
 		return nil, fmt.Errorf("create file failed: %s", resp.Message)
 	}
@@ -55,10 +67,12 @@ func (c *Client) GetFileWithNamespace(ctx context.Context, path, namespace strin
 	if !resp.Success {
 		return nil, fmt.Errorf("get file failed: %s", resp.Message)
 	}
+	c.showPlainSize(resp.File)
 	return resp.File, nil
 }
 
-// DeleteFile removes a file entry.
+// DeleteFile removes a file entry. The master keeps deleted files in a trash
+// for its retention period; see ListTrash and RestoreFile.
 func (c *Client) DeleteFile(ctx context.Context, path string) error {
 	return c.DeleteFileWithNamespace(ctx, path, "")
 }
@@ -81,7 +95,7 @@ func (c *Client) DeleteFileWithNamespace(ctx context.Context, path, namespace st
 	return nil
 }
 
-// DeleteNamespace removes a namespace and all its files.
+// DeleteNamespace removes a namespace and all its files, moving them to the trash.
 func (c *Client) DeleteNamespace(ctx context.Context, namespace string) (int, error) {
 	resp, err := c.master.DeleteNamespace(ctx, &pb.DeleteNamespaceRequest{
 		Namespace: namespace,
@@ -97,24 +111,48 @@ func (c *Client) DeleteNamespace(ctx context.Context, namespace string) (int, er
 	return int(resp.FilesDeleted), nil
 }
 
-// RenameFile renames a file.
+// RenameFile renames a file. It fails with an error matching ErrPreconditionFailed
+// if a file already exists at newPath.
 func (c *Client) RenameFile(ctx context.Context, oldPath, newPath string) error {
 	return c.RenameFileWithNamespace(ctx, oldPath, newPath, "")
 }
 
 // RenameFileWithNamespace renames a file within a namespace.
 func (c *Client) RenameFileWithNamespace(ctx context.Context, oldPath, newPath, namespace string) error {
+	return c.renameFile(ctx, oldPath, newPath, normalizeNamespace(namespace), false)
+}
+
+// ReplaceFile renames a file, atomically replacing any file already at newPath.
+// Readers see either the old file or the new one, never a missing or partial file,
+// which makes it the way to publish a file written under a temporary name.
+func (c *Client) ReplaceFile(ctx context.Context, oldPath, newPath string) error {
+	return c.ReplaceFileWithNamespace(ctx, oldPath, newPath, "")
+}
+
+// ReplaceFileWithNamespace renames a file within a namespace, replacing any file at newPath.
+func (c *Client) ReplaceFileWithNamespace(ctx context.Context, oldPath, newPath, namespace string) error {
+	return c.renameFile(ctx, oldPath, newPath, normalizeNamespace(namespace), true)
+}
+
+func (c *Client) renameFile(ctx context.Context, oldPath, newPath, namespace string, replace bool) error {
 	resp, err := c.master.RenameFile(ctx, &pb.RenameFileRequest{
 		OldPath:   oldPath,
 		NewPath:   newPath,
-		Namespace: normalizeNamespace(namespace),
+		Namespace: namespace,
+		Replace:   replace,
 	})
 	if err != nil {
 		return err
 	}
+	if resp.PreconditionFailed {
+		return fmt.Errorf("rename file failed: %w", &preconditionError{resp.Message})
+	}
 	if !resp.Success {
 		return fmt.Errorf("rename file failed: %s", resp.Message)
 	}
+	c.invalidateChunkCache(oldPath, namespace)
+	c.invalidateChunkCache(newPath, namespace)
+	c.forgetFile(oldPath, namespace)
 	return nil
 }
 
@@ -132,6 +170,7 @@ func (c *Client) ListFilesWithNamespace(ctx context.Context, namespace, prefix s
 	if err != nil {
 		return nil, err
 	}
+	c.showPlainSize(resp.Files...)
 	return resp.Files, nil
 }
 
@@ -142,16 +181,27 @@ func (c *Client) AllocateChunk(ctx context.Context, path string) (*pb.ChunkLocat
 
 // AllocateChunkWithNamespace requests a new chunk for a file in a namespace.
 func (c *Client) AllocateChunkWithNamespace(ctx context.Context, path, namespace string) (*pb.ChunkLocationInfo, error) {
-	resp, err := c.master.AllocateChunk(ctx, &pb.AllocateChunkRequest{
+	return c.allocateChunk(ctx, &pb.AllocateChunkRequest{
 		Path:      path,
 		Namespace: normalizeNamespace(namespace),
 	})
+}
+
+func (c *Client) allocateChunk(ctx context.Context, req *pb.AllocateChunkRequest) (*pb.ChunkLocationInfo, error) {
+	resp, err := c.master.AllocateChunk(ctx, req)
 	if err != nil {
 		return nil, err
 	}
+	if resp.QuotaExceeded {
+		return nil, fmt.Errorf("allocate chunk failed: %w", &quotaError{resp.Message})
+	}
+	if resp.PreconditionFailed {
+		return nil, fmt.Errorf("allocate chunk failed: %w", &preconditionError{resp.Message})
+	}
 	if !resp.Success {
 		return nil, fmt.Errorf("allocate chunk failed: %s", resp.Message)
 	}
+	c.rememberGrants(resp.Chunk)
 	return resp.Chunk, nil
 }
 
@@ -172,6 +222,7 @@ func (c *Client) GetChunkLocationsWithNamespace(ctx context.Context, path, names
 	if !resp.Success {
 		return nil, fmt.Errorf("get chunk locations failed: %s", resp.Message)
 	}
+	c.rememberGrants(resp.Chunks...)
 	return resp.Chunks, nil
 }
 
@@ -196,6 +247,7 @@ type PreparedUpload struct {
 	index        int // current chunk index
 	bytesWritten int64
 	onProgress   ProgressFunc
+	cipher       *fileCipher // Set for an encrypted file
 	// Pre-fetch state for async chunk allocation
 	prefetchCh   chan *pb.ChunkLocationInfo
 	prefetchErr  error
@@ -225,12 +277,18 @@ func (c *Client) PrepareUploadWithNamespace(ctx context.Context, path, namespace
 	// Get existing chunks (we'll allocate more on-demand as needed)
 	existing, _ := c.GetChunkLocationsWithNamespace(ctx, path, normalizedNamespace)
 
+	cipher, err := c.fileCipher(ctx, path, normalizedNamespace)
+	if err != nil {
+		return nil, err
+	}
+
 	return &PreparedUpload{
 		client:    c,
 		path:      path,
 		namespace: normalizedNamespace,
 		chunks:    existing,
 		index:     0,
+		cipher:    cipher,
 	}, nil
 }
 
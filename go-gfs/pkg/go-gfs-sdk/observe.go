@@ -0,0 +1,63 @@
+package gfs
+
+import (
+	"context"
+	"time"
+)
+
+// Operation describes one finished master call or chunk transfer, as reported
+// to a Tracer or Metrics hook.
+type Operation struct {
+	// Name is "master.<Method>" for master calls, such as "master.GetFile", and
+	// "chunk.read", "chunk.write" or "chunk.append" for chunk transfers.
+	Name string
+	// Chunk is the chunk handle of a transfer.
+	Chunk string
+	// Server is the master address, or the ID of the chunkserver that served a
+	// read or was the primary for a write. For a failed read it is the last one tried.
+	Server string
+	// Replicas lists the chunkservers a read was sent to, in order, including
+	// failovers and a hedge.
+	Replicas []string
+	// Hedged is set when a read also went to a second replica because the first was slow.
+	Hedged bool
+	// Bytes is the data read or written.
+	Bytes int64
+	// Attempts counts tries under the retry policy, or for a read the replicas
+	// (or hedged pairs) read from in turn; 1 when the first succeeded.
+	Attempts int
+	Duration time.Duration
+	Err      error
+}
+
+// Tracer starts a span for an operation named as in Operation.Name. It returns
+// the context the operation runs with, so spans can nest, and a function that
+// ends the span with the finished operation.
+type Tracer func(ctx context.Context, name string) (context.Context, func(Operation))
+
+// Metrics receives every finished operation, for example to record latency per
+// chunkserver. It is called synchronously and should not block.
+type Metrics func(Operation)
+
+// observe starts timing an operation; call the returned function with its outcome.
+func (c *Client) observe(ctx context.Context, name string) (context.Context, func(*Operation)) {
+	if c.tracer == nil && c.metrics == nil {
+		return ctx, func(*Operation) {}
+	}
+
+	start := time.Now()
+	var end func(Operation)
+	if c.tracer != nil {
+		ctx, end = c.tracer(ctx, name)
+	}
+	return ctx, func(op *Operation) {
+		op.Name = name
+		op.Duration = time.Since(start)
+		if end != nil {
+			end(*op)
+		}
+		if c.metrics != nil {
+			c.metrics(*op)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// SetNamespaceQuota limits the committed bytes and file count of a namespace.
+// A zero limit is unlimited; zero for both clears the quota.
+// Creating files or allocating chunks beyond the quota fails with ErrQuotaExceeded.
+func (c *Client) SetNamespaceQuota(ctx context.Context, namespace string, maxBytes, maxFiles uint64) error {
+	resp, err := c.master.SetNamespaceQuota(ctx, &pb.SetNamespaceQuotaRequest{
+		Namespace: normalizeNamespace(namespace),
+		MaxBytes:  maxBytes,
+		MaxFiles:  maxFiles,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("set namespace quota failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// GetNamespaceUsage returns the live usage and quota of a namespace.
+func (c *Client) GetNamespaceUsage(ctx context.Context, namespace string) (*pb.NamespaceUsage, error) {
+	resp, err := c.master.GetNamespaceUsage(ctx, &pb.GetNamespaceUsageRequest{
+		Namespace: normalizeNamespace(namespace),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Namespaces) == 0 {
+		return nil, fmt.Errorf("get namespace usage failed: no usage for %s", namespace)
+	}
+	return resp.Namespaces[0], nil
+}
+
+// ListNamespaceUsage returns usage for every namespace that has files or a quota.
+func (c *Client) ListNamespaceUsage(ctx context.Context) ([]*pb.NamespaceUsage, error) {
+	resp, err := c.master.GetNamespaceUsage(ctx, &pb.GetNamespaceUsageRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Namespaces, nil
+}
@@ -0,0 +1,173 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// ReadRange reads up to length bytes starting at offset.
+// A negative length reads to the end of the file.
+func (c *Client) ReadRange(ctx context.Context, path string, offset, length int64) ([]byte, error) {
+	return c.ReadRangeWithNamespace(ctx, path, "", offset, length)
+}
+
+// ReadRangeWithNamespace reads up to length bytes starting at offset with a namespace.
+// A negative length reads to the end of the file.
+func (c *Client) ReadRangeWithNamespace(ctx context.Context, path, namespace string, offset, length int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if length > 0 {
+		buf.Grow(int(length))
+	}
+	if _, err := c.ReadRangeToWithNamespace(ctx, path, namespace, offset, length, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadRangeTo streams up to length bytes starting at offset to the provided writer.
+// A negative length reads to the end of the file.
+func (c *Client) ReadRangeTo(ctx context.Context, path string, offset, length int64, w io.Writer) (int64, error) {
+	return c.ReadRangeToWithNamespace(ctx, path, "", offset, length, w)
+}
+
+// ReadRangeToWithNamespace streams up to length bytes starting at offset to the provided writer with a namespace.
+// Only the chunks overlapping the range are contacted, and only the needed bytes are transferred.
+// A negative length reads to the end of the file.
+func (c *Client) ReadRangeToWithNamespace(ctx context.Context, path, namespace string, offset, length int64, w io.Writer) (int64, error) {
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	if length == 0 {
+		return 0, nil
+	}
+
+	f, err := c.fileCipher(ctx, path, normalizeNamespace(namespace))
+	if err != nil {
+		return 0, err
+	}
+	if f != nil {
+		return c.readEncrypted(ctx, path, normalizeNamespace(namespace), f, offset, length, w)
+	}
+	return c.readRangeTo(ctx, path, normalizeNamespace(namespace), offset, length, w)
+}
+
+// readRangeTo streams a range of the bytes stored for a file, decrypting nothing.
+func (c *Client) readRangeTo(ctx context.Context, path, namespace string, offset, length int64, w io.Writer) (int64, error) {
+	chunks, err := c.getCachedChunks(ctx, path, namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	end := int64(-1)
+	if length > 0 {
+		end = offset + length
+	}
+
+	var total int64
+	var chunkStart int64
+	for _, chunk := range chunks {
+		chunkEnd := chunkStart + int64(chunk.Size)
+		if end >= 0 && chunkStart >= end {
+			break
+		}
+		if chunkEnd <= offset {
+			chunkStart = chunkEnd
+			continue
+		}
+
+		from := int64(0)
+		if offset > chunkStart {
+			from = offset - chunkStart
+		}
+		to := int64(chunk.Size)
+		if end >= 0 && end < chunkEnd {
+			to = end - chunkStart
+		}
+
+		chunkCtx, cancel := context.WithTimeout(ctx, c.chunkTimeout)
+		n, err := c.readChunkRangeWithFailover(chunkCtx, chunk, from, to-from, w)
+		if err != nil && n == 0 {
+			if fresh := c.reloadEncodedChunk(chunkCtx, path, namespace, chunk); fresh != nil {
+				n, err = c.readChunkRangeWithFailover(chunkCtx, fresh, from, to-from, w)
+			}
+		}
+		cancel()
+		total += n
+		if err != nil {
+			return total, err
+		}
+		chunkStart = chunkEnd
+	}
+	return total, nil
+}
+
+// ReadAt reads len(p) bytes starting at offset off.
+// It follows io.ReaderAt semantics: fewer than len(p) bytes are only returned with an error,
+// which is io.EOF when the range runs past the end of the file.
+func (c *Client) ReadAt(ctx context.Context, path string, p []byte, off int64) (int, error) {
+	return c.ReadAtWithNamespace(ctx, path, "", p, off)
+}
+
+// ReadAtWithNamespace reads len(p) bytes starting at offset off with a namespace.
+func (c *Client) ReadAtWithNamespace(ctx context.Context, path, namespace string, p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w := &sliceWriter{buf: p}
+	n, err := c.ReadRangeToWithNamespace(ctx, path, namespace, off, int64(len(p)), w)
+	if err != nil {
+		return int(n), err
+	}
+	if int(n) < len(p) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// FileReaderAt adapts a GFS file to io.ReaderAt.
+type FileReaderAt struct {
+	client    *Client
+	ctx       context.Context
+	path      string
+	namespace string
+}
+
+// NewReaderAt returns an io.ReaderAt for the file at path in a namespace.
+// All reads use ctx; chunk locations are cached by the client.
+func (c *Client) NewReaderAt(ctx context.Context, path, namespace string) *FileReaderAt {
+	return &FileReaderAt{
+		client:    c,
+		ctx:       ctx,
+		path:      path,
+		namespace: namespace,
+	}
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *FileReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.client.ReadAtWithNamespace(r.ctx, r.path, r.namespace, p, off)
+}
+
+// Size returns the file size from cached chunk locations.
+func (r *FileReaderAt) Size() (int64, error) {
+	size, err := r.client.FileSizeWithNamespace(r.ctx, r.path, r.namespace)
+	return int64(size), err
+}
+
+var _ io.ReaderAt = (*FileReaderAt)(nil)
+
+// sliceWriter writes sequentially into a fixed buffer.
+type sliceWriter struct {
+	buf []byte
+	n   int
+}
+
+func (w *sliceWriter) Write(p []byte) (int, error) {
+	n := copy(w.buf[w.n:], p)
+	w.n += n
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
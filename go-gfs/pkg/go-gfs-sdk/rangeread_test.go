@@ -0,0 +1,107 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// threeChunkFile writes a file of three chunks of different sizes and returns its bytes
+func threeChunkFile(t *testing.T, opts ...Option) (*fakeChunkserver, *Client, []byte) {
+	t.Helper()
+	s := startFakeChunkserver(t)
+	m, c := startTestMaster(t, s, opts...)
+	// Bytes differ within and across chunks, so a read at the wrong offset shows
+	var chunks [][]byte
+	for i, size := range []int{100, 50, 30} {
+		chunk := make([]byte, size)
+		for j := range chunk {
+			chunk[j] = byte('a'+i) + byte(j%13)
+		}
+		chunks = append(chunks, chunk)
+	}
+	writeTestChunks(t, m, s, "ns", "/f", chunks...)
+	return s, c, bytes.Join(chunks, nil)
+}
+
+func TestReadRange(t *testing.T) {
+	_, c, data := threeChunkFile(t)
+	size := int64(len(data))
+
+	tests := []struct {
+		offset, length int64
+	}{
+		{0, -1},         // Whole file
+		{0, 10},         // Inside the first chunk
+		{95, 10},        // Across the first boundary
+		{100, 50},       // Exactly the second chunk
+		{99, 52},        // One byte either side of the second chunk
+		{10, 160},       // Through all three chunks
+		{140, 1000},     // Past the end
+		{150, -1},       // Third chunk to the end
+		{size - 1, 1},   // Last byte
+		{size, 10},      // At the end
+		{size + 10, 10}, // Past the end
+		{0, 0},          // Nothing
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d+%d", tt.offset, tt.length), func(t *testing.T) {
+			want := data[min(tt.offset, size):]
+			if tt.length >= 0 {
+				want = want[:min(tt.length, int64(len(want)))]
+			}
+			got, err := c.ReadRangeWithNamespace(context.Background(), "/f", "ns", tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("ReadRange: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ReadRange = %q, want %q", got, want)
+			}
+		})
+	}
+
+	if _, err := c.ReadRangeWithNamespace(context.Background(), "/f", "ns", -1, 10); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("negative offset: err = %v, want ErrInvalidOffset", err)
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	_, c, data := threeChunkFile(t)
+	size := int64(len(data))
+
+	tests := []struct {
+		off   int64
+		n     int
+		eof   bool
+		short int // Bytes read when eof is set
+	}{
+		{off: 0, n: int(size)},
+		{off: 98, n: 4},
+		{off: 100, n: 80},
+		{off: 149, n: 2},
+		{off: size - 5, n: 10, eof: true, short: 5},
+		{off: size, n: 1, eof: true},
+		{off: size + 3, n: 1, eof: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d+%d", tt.off, tt.n), func(t *testing.T) {
+			p := make([]byte, tt.n)
+			n, err := c.NewReaderAt(context.Background(), "/f", "ns").ReadAt(p, tt.off)
+			wantN := tt.n
+			if tt.eof {
+				wantN = tt.short
+				if err != io.EOF {
+					t.Errorf("err = %v, want EOF", err)
+				}
+			} else if err != nil {
+				t.Errorf("ReadAt: %v", err)
+			}
+			if n != wantN || !bytes.Equal(p[:n], data[min(tt.off, size):min(tt.off, size)+int64(wantN)]) {
+				t.Errorf("read %d bytes not matching the file, want %d", n, wantN)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package gfs
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/masterconn"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how the client retries master calls and chunk writes that
+// fail with transient errors. Retries are idempotency-aware:
+//
+//   - Master calls that only read, or that set a value, are retried on
+//     Unavailable, DeadlineExceeded, ResourceExhausted and Aborted. Calls that
+//     create, delete, rename, snapshot or allocate are not retried, since a retry
+//     after a lost reply would apply them twice. Calls a follower rejects are
+//     still redirected to the leader.
+//   - Writes at an offset and conditional appends (AppendIfSize) are retried on
+//     any failure: repeating them can't duplicate data. A conditional append that
+//     did land before its reply was lost fails the retry with ErrPreconditionFailed.
+//   - Other appends are retried only when they failed before the primary accepted
+//     them, unless RetryAmbiguousAppends is set.
+//
+// A retry never outlives the caller's context.
+type RetryPolicy struct {
+	// MaxAttempts is the most times an operation is tried, including the first.
+	// 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; each later wait doubles,
+	// up to MaxBackoff, with random jitter of up to half the wait.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// PerAttemptTimeout bounds each attempt of a retryable master call, so a
+	// stalled master is retried instead of waited out. 0 leaves only the caller's deadline.
+	PerAttemptTimeout time.Duration
+	// RetryAmbiguousAppends also retries appends that failed after the primary
+	// accepted them. The data may then be appended twice, as with GFS record appends.
+	RetryAmbiguousAppends bool
+}
+
+// DefaultRetryPolicy tries each operation up to three times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// backoff returns the wait before retry number attempt (1 for the first retry).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// wait sleeps before retry number attempt, returning false if ctx ends first.
+func (p RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryableMethods are the master calls that can be repeated without changing
+// the outcome: reads, and calls that set a value.
+var retryableMethods = map[string]bool{
+	pb.Master_GetFile_FullMethodName:            true,
+	pb.Master_ListFiles_FullMethodName:          true,
+	pb.Master_ListFilesV2_FullMethodName:        true,
+	pb.Master_GetChunkLocations_FullMethodName:  true,
+	pb.Master_PrepareChunkWrite_FullMethodName:  true,
+	pb.Master_IssueDataToken_FullMethodName:     true,
+	pb.Master_GetNamespaceUsage_FullMethodName:  true,
+	pb.Master_GetClusterStatus_FullMethodName:   true,
+	pb.Master_GetDrainStatus_FullMethodName:     true,
+	pb.Master_GetRebalanceStatus_FullMethodName: true,
+	pb.Master_GetLeader_FullMethodName:          true,
+	pb.Master_ListTrash_FullMethodName:          true,
+	pb.Master_SetFileAttributes_FullMethodName:  true,
+	pb.Master_SetNamespaceQuota_FullMethodName:  true,
+	pb.Master_SetStorageClass_FullMethodName:    true,
+	pb.Master_SetCompression_FullMethodName:     true,
+	pb.Master_DrainChunkServer_FullMethodName:   true,
+}
+
+// retryableCode reports whether a master call failing with code may succeed if repeated.
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// masterInvoker sends master calls through the leader-following connection,
+// retrying them under the client's policy and reporting each to its observers.
+type masterInvoker struct {
+	*masterconn.Conn
+	client *Client
+}
+
+// Invoke implements grpc.ClientConnInterface.
+func (m *masterInvoker) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	policy := m.client.retryPolicy
+	retryable := retryableMethods[method]
+
+	ctx, done := m.client.observe(ctx, "master."+method[strings.LastIndexByte(method, '/')+1:])
+	var op Operation
+	var err error
+	for attempt := 1; ; attempt++ {
+		op.Attempts = attempt
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if retryable && policy.PerAttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.PerAttemptTimeout)
+		}
+		err = m.Conn.Invoke(attemptCtx, method, args, reply, opts...)
+		cancel()
+
+		if err == nil || !retryable || attempt >= policy.MaxAttempts || ctx.Err() != nil || !retryableCode(status.Code(err)) {
+			break
+		}
+		if !policy.wait(ctx, attempt) {
+			break
+		}
+	}
+	op.Server, op.Err = m.Conn.Leader(), err
+	done(&op)
+	return err
+}
+
+// unacceptedError marks a chunk write that failed before the primary accepted
+// it, so nothing was written and the write can be retried.
+type unacceptedError struct {
+	err error
+}
+
+func (e *unacceptedError) Error() string { return e.err.Error() }
+
+func (e *unacceptedError) Unwrap() error { return e.err }
+
+// retryWrite reports whether a failed chunk write can be tried again. Writes at
+// an offset and conditional appends are idempotent.
+func (c *Client) retryWrite(ctx context.Context, err error, idempotent bool) bool {
+	var unaccepted *unacceptedError
+	switch {
+	case ctx.Err() != nil, errors.Is(err, ErrPreconditionFailed):
+		return false
+	case errors.As(err, &unaccepted), idempotent:
+		return true
+	}
+	return c.retryPolicy.RetryAmbiguousAppends
+}
@@ -0,0 +1,297 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// fastRetries retries quickly, so tests don't wait out backoffs
+var fastRetries = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// flakyTransport fails master calls before they reach the master, counting
+// every call by method
+type flakyTransport struct {
+	mu    sync.Mutex
+	fail  map[string]int // Calls left to fail, by method
+	code  codes.Code
+	calls map[string]int
+}
+
+func (f *flakyTransport) intercept(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	f.mu.Lock()
+	f.calls[method]++
+	failing := f.fail[method] > 0
+	if failing {
+		f.fail[method]--
+	}
+	f.mu.Unlock()
+	if failing {
+		return status.Error(f.code, "injected failure")
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func TestMasterCallRetries(t *testing.T) {
+	flaky := &flakyTransport{calls: make(map[string]int)}
+	s := startFakeChunkserver(t)
+	_, c := startTestMaster(t, s, WithRetryPolicy(fastRetries), WithDialOptions(
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(flaky.intercept),
+	))
+	ctx := context.Background()
+	if _, err := c.CreateFileWithNamespace(ctx, "/f", "ns"); err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+
+	// Unavailable is left out: the connection already resends those calls to
+	// whichever master leads, below the retry policy
+	getFile := func() error { _, err := c.GetFileWithNamespace(ctx, "/f", "ns"); return err }
+	tests := []struct {
+		name   string
+		method string
+		call   func() error
+		fails  int
+		code   codes.Code
+		calls  int
+		ok     bool
+	}{
+		{name: "read recovers", method: pb.Master_GetFile_FullMethodName, call: getFile, fails: 2, code: codes.Aborted, calls: 3, ok: true},
+		{name: "read gives up", method: pb.Master_GetFile_FullMethodName, call: getFile, fails: 3, code: codes.ResourceExhausted, calls: 3},
+		{name: "read with a lasting error", method: pb.Master_GetFile_FullMethodName, call: getFile, fails: 1, code: codes.InvalidArgument, calls: 1},
+		{
+			name: "setter recovers", method: pb.Master_SetFileAttributes_FullMethodName,
+			call: func() error {
+				_, err := c.SetFileAttributesWithNamespace(ctx, "/f", "ns", map[string]string{"k": "v"})
+				return err
+			},
+			fails: 1, code: codes.DeadlineExceeded, calls: 2, ok: true,
+		},
+		{
+			name: "create", method: pb.Master_CreateFile_FullMethodName,
+			call:  func() error { _, err := c.CreateFileWithNamespace(ctx, "/g", "ns"); return err },
+			fails: 1, code: codes.DeadlineExceeded, calls: 1,
+		},
+		{
+			name: "rename", method: pb.Master_RenameFile_FullMethodName,
+			call:  func() error { return c.RenameFileWithNamespace(ctx, "/f", "/h", "ns") },
+			fails: 1, code: codes.Aborted, calls: 1,
+		},
+		{
+			name: "delete", method: pb.Master_DeleteFile_FullMethodName,
+			call:  func() error { return c.DeleteFileWithNamespace(ctx, "/f", "ns") },
+			fails: 1, code: codes.DeadlineExceeded, calls: 1,
+		},
+	}
+	for _, tt := range tests {
+		flaky.mu.Lock()
+		flaky.fail = map[string]int{tt.method: tt.fails}
+		flaky.code = tt.code
+		flaky.calls = make(map[string]int)
+		flaky.mu.Unlock()
+
+		err := tt.call()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok %v", tt.name, err, tt.ok)
+		}
+		flaky.mu.Lock()
+		calls := flaky.calls[tt.method]
+		flaky.mu.Unlock()
+		if calls != tt.calls {
+			t.Errorf("%s: %d calls, want %d", tt.name, calls, tt.calls)
+		}
+	}
+}
+
+// Chunk writes are retried only when repeating them can't write the data twice
+func TestChunkWriteRetries(t *testing.T) {
+	base := []byte("base")
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		write     func(c *Client, path string) error
+		unstarted bool // Drop every write before accepting it
+		accepted  int  // Writes to drop after accepting them
+		writes    int
+		want      []byte // Contents afterwards; nil when the write fails
+	}{
+		{
+			name: "append lost after acceptance", policy: fastRetries,
+			write:    appendTo("tail"),
+			accepted: 1, writes: 1,
+		},
+		{
+			name: "append never accepted", policy: fastRetries,
+			write:     appendTo("tail"),
+			unstarted: true, writes: 3,
+		},
+		{
+			name: "ambiguous append with retries allowed", policy: RetryPolicy{MaxAttempts: 3, RetryAmbiguousAppends: true},
+			write:    appendTo("tail"),
+			accepted: 1, writes: 2, want: []byte("basetail"),
+		},
+		{
+			name: "conditional append", policy: fastRetries,
+			write: func(c *Client, path string) error {
+				return c.AppendIfSizeWithNamespace(context.Background(), path, "ns", []byte("tail"), uint64(len(base)))
+			},
+			accepted: 1, writes: 2, want: []byte("basetail"),
+		},
+		{
+			name: "write at an offset", policy: fastRetries,
+			write: func(c *Client, path string) error {
+				_, err := c.WriteAtWithNamespace(context.Background(), path, "ns", []byte("BA"), 0)
+				return err
+			},
+			accepted: 2, writes: 3, want: []byte("BAse"),
+		},
+		{
+			name: "write at an offset gives up", policy: fastRetries,
+			write: func(c *Client, path string) error {
+				_, err := c.WriteAtWithNamespace(context.Background(), path, "ns", []byte("BA"), 0)
+				return err
+			},
+			accepted: 3, writes: 3,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := startFakeChunkserver(t)
+			m, c := startTestMaster(t, s, WithRetryPolicy(tt.policy))
+			path := fmt.Sprintf("/f%d", i)
+			writeTestChunks(t, m, s, "ns", path, base)
+
+			s.mu.Lock()
+			s.failWrites, s.failAccepted = tt.unstarted, tt.accepted
+			s.mu.Unlock()
+			err := tt.write(c, path)
+			if (err == nil) != (tt.want != nil) {
+				t.Fatalf("err = %v, want ok %v", err, tt.want != nil)
+			}
+			s.mu.Lock()
+			writes := s.writes
+			s.failWrites = false
+			s.mu.Unlock()
+			if writes != tt.writes {
+				t.Errorf("%d write attempts, want %d", writes, tt.writes)
+			}
+			if tt.want == nil {
+				return
+			}
+			if got, err := c.ReadWithNamespace(context.Background(), path, "ns"); err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("file holds %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func appendTo(data string) func(c *Client, path string) error {
+	return func(c *Client, path string) error {
+		_, err := c.AppendWithNamespace(context.Background(), path, "ns", []byte(data))
+		return err
+	}
+}
+
+// A conditional append that lost the race isn't repeated
+func TestConditionalAppendNotRetried(t *testing.T) {
+	s := startFakeChunkserver(t)
+	m, c := startTestMaster(t, s, WithRetryPolicy(fastRetries))
+	writeTestChunks(t, m, s, "ns", "/f", []byte("base"))
+
+	err := c.AppendIfSizeWithNamespace(context.Background(), "/f", "ns", []byte("tail"), 3)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("err = %v, want ErrPreconditionFailed", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writes > 1 {
+		t.Errorf("%d write attempts, want at most 1", s.writes)
+	}
+}
+
+func TestHedgedRead(t *testing.T) {
+	data := []byte("hedged read data")
+	tests := []struct {
+		name     string
+		first    string // How the first replica answers: "fast", "slow" or "lost"
+		server   string // Replica that serves the read
+		hedged   bool
+		replicas int
+	}{
+		{name: "first answers in time", first: "fast", server: "first", replicas: 1},
+		{name: "first is slow", first: "slow", server: "second", hedged: true, replicas: 2},
+		{name: "first lost the chunk", first: "lost", server: "second", hedged: true, replicas: 2}, // Without waiting for the delay
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := startFakeChunkserver(t), startFakeChunkserver(t)
+			second.fragments["h"] = data
+			switch tt.first {
+			case "fast":
+				first.fragments["h"] = data
+			case "slow":
+				first.fragments["h"] = data
+				first.stallReads = true
+			}
+			chunk := &pb.ChunkLocationInfo{ChunkHandle: "h", Size: uint64(len(data))}
+			for _, s := range []struct {
+				id string
+				cs *fakeChunkserver
+			}{{"first", first}, {"second", second}} {
+				loc := s.cs.location()
+				loc.ServerId = s.id
+				chunk.Locations = append(chunk.Locations, loc)
+			}
+
+			var op Operation
+			_, c := startTestMaster(t, second,
+				WithHedgedReads(20*time.Millisecond),
+				WithReplicaPicker(func(chunk *pb.ChunkLocationInfo) *pb.ChunkServerInfo { return chunk.Locations[0] }),
+				WithMetrics(func(o Operation) {
+					if o.Name == "chunk.read" {
+						op = o
+					}
+				}),
+			)
+
+			var buf bytes.Buffer
+			n, err := c.readChunkRangeWithFailover(context.Background(), chunk, 0, 0, &buf)
+			if err != nil || n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+				t.Fatalf("read = %d, %q, %v; want the chunk", n, buf.Bytes(), err)
+			}
+			if op.Server != tt.server || op.Hedged != tt.hedged || len(op.Replicas) != tt.replicas {
+				t.Errorf("read from %s, hedged %v, replicas %v; want %s, %v, %d", op.Server, op.Hedged, op.Replicas, tt.server, tt.hedged, tt.replicas)
+			}
+			if tt.first == "fast" && second.readCount() != 0 {
+				t.Errorf("second replica read %d times, want none", second.readCount())
+			}
+
+			// The losing request is canceled, not left to finish
+			if tt.first == "slow" {
+				deadline := time.Now().Add(5 * time.Second)
+				for {
+					first.mu.Lock()
+					hangups := first.hangups
+					first.mu.Unlock()
+					if hangups == 1 {
+						break
+					}
+					if time.Now().After(deadline) {
+						t.Fatal("slow replica's request is still open")
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+		})
+	}
+}
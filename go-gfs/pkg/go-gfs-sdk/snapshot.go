@@ -0,0 +1,97 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// SnapshotFile makes a point-in-time copy of a file at destPath.
+// The copy shares chunks with the source, so it is cheap regardless of file size;
+// a shared chunk is duplicated only when either file next writes to it.
+func (c *Client) SnapshotFile(ctx context.Context, sourcePath, destPath string) (*pb.FileInfoResponse, error) {
+	return c.SnapshotFileWithNamespace(ctx, sourcePath, destPath, "", "")
+}
+
+// SnapshotFileWithNamespace makes a point-in-time copy of a file, optionally into another namespace.
+// An empty destNamespace keeps the copy in the source namespace.
+func (c *Client) SnapshotFileWithNamespace(ctx context.Context, sourcePath, destPath, namespace, destNamespace string) (*pb.FileInfoResponse, error) {
+	ns := normalizeNamespace(namespace)
+	if destNamespace == "" {
+		destNamespace = ns
+	}
+	resp, err := c.master.SnapshotFile(ctx, &pb.SnapshotFileRequest{
+		SourcePath:    sourcePath,
+		DestPath:      destPath,
+		Namespace:     ns,
+		DestNamespace: destNamespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.QuotaExceeded {
+		return nil, fmt.Errorf("snapshot file failed: %w", &quotaError{resp.Message})
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("snapshot file failed: %s", resp.Message)
+	}
+	// Cached chunks of the source are now shared and must not be written directly
+	c.invalidateChunkCache(sourcePath, ns)
+	return resp.File, nil
+}
+
+// SnapshotNamespace makes a point-in-time copy of every file in a namespace into destNamespace,
+// which must be empty. Returns the number of files copied.
+func (c *Client) SnapshotNamespace(ctx context.Context, namespace, destNamespace string) (int, error) {
+	ns := normalizeNamespace(namespace)
+	resp, err := c.master.SnapshotNamespace(ctx, &pb.SnapshotNamespaceRequest{
+		Namespace:     ns,
+		DestNamespace: destNamespace,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.QuotaExceeded {
+		return 0, fmt.Errorf("snapshot namespace failed: %w", &quotaError{resp.Message})
+	}
+	if !resp.Success {
+		return 0, fmt.Errorf("snapshot namespace failed: %s", resp.Message)
+	}
+	c.invalidateNamespaceCache(ns)
+	return int(resp.FilesCopied), nil
+}
+
+// prepareChunkWrite asks the master for a private copy of a shared chunk before writing to it.
+func (c *Client) prepareChunkWrite(ctx context.Context, path, namespace string, index int) (*pb.ChunkLocationInfo, error) {
+	resp, err := c.master.PrepareChunkWrite(ctx, &pb.PrepareChunkWriteRequest{
+		Path:       path,
+		Namespace:  normalizeNamespace(namespace),
+		ChunkIndex: int32(index),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("prepare chunk write failed: %s", resp.Message)
+	}
+	// The file now points at a different chunk
+	c.invalidateChunkCache(path, namespace)
+	c.rememberGrants(resp.Chunk)
+	return resp.Chunk, nil
+}
+
+// isChunkShared reports whether a chunk of a file is currently shared with a snapshot.
+// Used after a failed write to tell a stale cache apart from a real failure.
+func (c *Client) isChunkShared(ctx context.Context, path, namespace, chunkHandle string) bool {
+	chunks, err := c.GetChunkLocationsWithNamespace(ctx, path, namespace)
+	if err != nil {
+		return false
+	}
+	for _, chunk := range chunks {
+		if chunk.ChunkHandle == chunkHandle {
+			return chunk.Shared
+		}
+	}
+	return false
+}
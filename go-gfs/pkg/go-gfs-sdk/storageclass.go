@@ -0,0 +1,231 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/erasure"
+)
+
+// Storage classes for files and namespaces.
+const (
+	// StorageClassReplicated keeps every chunk as full replicas. It is the default.
+	StorageClassReplicated = "replicated"
+	// StorageClassCold erasure codes sealed chunks into Reed-Solomon stripes
+	// (4 data + 2 parity fragments), halving the storage of three replicas.
+	// Encoded chunks are read-only; appends continue in a new chunk.
+	StorageClassCold = "cold"
+)
+
+// CreateFileWithStorageClass creates a new file entry with an explicit storage class.
+// An empty class makes the file use its namespace's class.
+func (c *Client) CreateFileWithStorageClass(ctx context.Context, path, namespace, storageClass string) (*pb.FileInfoResponse, error) {
+	attributes, err := c.encryptionAttributes(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.master.CreateFile(ctx, &pb.CreateFileRequest{
+		Path:         path,
+		Namespace:    normalizeNamespace(namespace),
+		StorageClass: storageClass,
+		Attributes:   attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.QuotaExceeded {
+		return nil, fmt.Errorf("create file failed: %w", &quotaError{resp.Message})
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("create file failed: %s", resp.Message)
+	}
+	return resp.File, nil
+}
+
+// SetStorageClass sets the storage class of a file. An empty class makes the file
+// use its namespace's class again. Chunks already encoded stay encoded.
+func (c *Client) SetStorageClass(ctx context.Context, path, namespace, storageClass string) error {
+	if path == "" {
+		return fmt.Errorf("set storage class failed: empty path")
+	}
+	return c.setStorageClass(ctx, path, namespace, storageClass)
+}
+
+// SetNamespaceStorageClass sets the default storage class of a namespace's files.
+// An empty class returns the namespace to replicated.
+func (c *Client) SetNamespaceStorageClass(ctx context.Context, namespace, storageClass string) error {
+	return c.setStorageClass(ctx, "", namespace, storageClass)
+}
+
+func (c *Client) setStorageClass(ctx context.Context, path, namespace, storageClass string) error {
+	resp, err := c.master.SetStorageClass(ctx, &pb.SetStorageClassRequest{
+		Namespace:    normalizeNamespace(namespace),
+		Path:         path,
+		StorageClass: storageClass,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return fmt.Errorf("set storage class failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// isChunkEncoded reports whether a chunk of a file has been erasure coded.
+// Used after a failed append to tell a stale cache apart from a real failure.
+func (c *Client) isChunkEncoded(ctx context.Context, path, namespace, chunkHandle string) bool {
+	chunks, err := c.GetChunkLocationsWithNamespace(ctx, path, namespace)
+	if err != nil {
+		return false
+	}
+	for _, chunk := range chunks {
+		if chunk.ChunkHandle == chunkHandle {
+			return chunk.Stripe != nil
+		}
+	}
+	return false
+}
+
+// readStripeRange reads a byte range of an erasure coded chunk from its data fragments.
+// A fragment that can't be read is rebuilt from the same range of the other fragments.
+// Each piece is buffered before it is written, so a failure never leaves partial data in w.
+func (c *Client) readStripeRange(ctx context.Context, chunk *pb.ChunkLocationInfo, offset, length int64, w io.Writer) (int64, error) {
+	stripe := chunk.Stripe
+	shardSize := int64(stripe.ShardSize)
+	if shardSize <= 0 || len(stripe.Fragments) != int(stripe.DataShards+stripe.ParityShards) {
+		return 0, fmt.Errorf("invalid stripe for chunk %s", chunk.ChunkHandle)
+	}
+
+	end := int64(chunk.Size)
+	if length > 0 && offset+length < end {
+		end = offset + length
+	}
+
+	var total int64
+	for pos := offset; pos < end; {
+		index := int(pos / shardSize)
+		from := pos % shardSize
+		n := min(shardSize-from, end-pos)
+
+		data, err := c.readFragmentRange(ctx, stripe.Fragments[index], from, n)
+		if err != nil {
+			data, err = c.reconstructFragmentRange(ctx, chunk, index, from, n)
+			if err != nil {
+				return total, err
+			}
+		}
+
+		written, err := w.Write(data)
+		total += int64(written)
+		if err != nil {
+			return total, err
+		}
+		pos += n
+	}
+	return total, nil
+}
+
+// readFragmentRange reads length bytes at offset within a fragment, trying each copy in turn.
+func (c *Client) readFragmentRange(ctx context.Context, fragment *pb.StripeFragment, offset, length int64) ([]byte, error) {
+	if len(fragment.Locations) == 0 {
+		return nil, fmt.Errorf("no copies of fragment %s", fragment.Handle)
+	}
+
+	var buf bytes.Buffer
+	var lastErr error
+	for _, loc := range fragment.Locations {
+		buf.Reset()
+		n, err := c.readChunkRange(ctx, toReplicaIdentifier(loc), fragment.Handle, offset, length, &buf)
+		if err == nil && n != length {
+			err = fmt.Errorf("short read: %d of %d bytes", n, length)
+		}
+		if err == nil {
+			return buf.Bytes(), nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all %d copies failed for fragment %s: %w", len(fragment.Locations), fragment.Handle, lastErr)
+}
+
+// reconstructFragmentRange rebuilds a range of one fragment from the same range of
+// DataShards other fragments. Reed-Solomon codes each byte position independently,
+// so a range decodes on its own without reading whole fragments.
+func (c *Client) reconstructFragmentRange(ctx context.Context, chunk *pb.ChunkLocationInfo, missing int, offset, length int64) ([]byte, error) {
+	stripe := chunk.Stripe
+	dataShards := int(stripe.DataShards)
+	enc, err := erasure.NewCoder(dataShards, int(stripe.ParityShards))
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []int
+	for i := range stripe.Fragments {
+		if i != missing {
+			candidates = append(candidates, i)
+		}
+	}
+
+	// Read just enough fragments in parallel, topping up from the rest after failures
+	shards := make([][]byte, len(stripe.Fragments))
+	have := 0
+	var lastErr error
+	for have < dataShards && len(candidates) > 0 {
+		wave := candidates[:min(dataShards-have, len(candidates))]
+		candidates = candidates[len(wave):]
+
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+		)
+		for _, index := range wave {
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				data, err := c.readFragmentRange(ctx, stripe.Fragments[index], offset, length)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					lastErr = err
+					return
+				}
+				shards[index] = data
+				have++
+			}(index)
+		}
+		wg.Wait()
+	}
+
+	if have < dataShards {
+		return nil, fmt.Errorf("chunk %s has %d of %d fragments needed to rebuild fragment %d: %w",
+			chunk.ChunkHandle, have, dataShards, missing, lastErr)
+	}
+	if err := enc.ReconstructData(shards); err != nil {
+		return nil, fmt.Errorf("failed to rebuild fragment %d of chunk %s: %w", missing, chunk.ChunkHandle, err)
+	}
+	return shards[missing], nil
+}
+
+// reloadEncodedChunk refetches a file's chunks after a read of a replicated chunk failed.
+// The replicas of a chunk are deleted once it is erasure coded, so a cached copy of its
+// locations goes stale. Returns the chunk if it is now encoded, nil otherwise.
+func (c *Client) reloadEncodedChunk(ctx context.Context, path, namespace string, chunk *pb.ChunkLocationInfo) *pb.ChunkLocationInfo {
+	if chunk.Stripe != nil {
+		return nil
+	}
+	c.invalidateChunkCache(path, namespace)
+	chunks, err := c.getCachedChunks(ctx, path, namespace)
+	if err != nil {
+		return nil
+	}
+	for _, fresh := range chunks {
+		if fresh.ChunkHandle == chunk.ChunkHandle && fresh.Stripe != nil {
+			return fresh
+		}
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package gfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"eddisonso.com/go-gfs/internal/chunkserver/csstructs"
+	"eddisonso.com/go-gfs/internal/erasure"
+	"eddisonso.com/go-gfs/internal/master"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeChunkserver serves range reads of fragments from memory. Fragments not in
+// its map fail the way a chunkserver that lost them does. With a master it also
+// takes writes, committing them to the master as a primary would.
+type fakeChunkserver struct {
+	lis    net.Listener
+	master *master.Master
+
+	mu           sync.Mutex
+	fragments    map[string][]byte
+	reads        int                               // Read requests served
+	onRead       func(handle string, offset int64) // Runs before a read is answered
+	stallReads   bool                              // Hold reads unanswered until the client hangs up
+	hangups      int                               // Stalled reads the client gave up on
+	writes       int                               // Write requests received
+	failWrites   bool                              // Drop write connections unanswered
+	failAccepted int                               // Writes to drop after accepting their data
+}
+
+func startFakeChunkserver(t *testing.T) *fakeChunkserver {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeChunkserver{lis: lis, fragments: make(map[string][]byte)}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { lis.Close() })
+	return s
+}
+
+func (s *fakeChunkserver) serve(conn net.Conn) {
+	defer conn.Close()
+	var action, tokenLen uint32
+	if binary.Read(conn, binary.BigEndian, &action) != nil || binary.Read(conn, binary.BigEndian, &tokenLen) != nil {
+		return
+	}
+	token := make([]byte, tokenLen)
+	if _, err := io.ReadFull(conn, token); err != nil {
+		return
+	}
+	if action == uint32(csstructs.Download) {
+		s.serveWrite(conn, string(token))
+		return
+	}
+	var claims csstructs.UploadRequestClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(string(token), &claims); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	data, ok := s.fragments[claims.ChunkHandle]
+	s.reads++
+	onRead, stall := s.onRead, s.stallReads
+	s.mu.Unlock()
+	if onRead != nil {
+		onRead(claims.ChunkHandle, claims.Offset)
+	}
+	if stall {
+		io.Copy(io.Discard, conn)
+		s.mu.Lock()
+		s.hangups++
+		s.mu.Unlock()
+		return
+	}
+	if !ok || claims.Offset+claims.Length > int64(len(data)) {
+		msg := "chunk not found"
+		conn.Write([]byte{0})
+		binary.Write(conn, binary.BigEndian, uint32(1))
+		binary.Write(conn, binary.BigEndian, uint32(len(msg)))
+		conn.Write([]byte(msg))
+		return
+	}
+	data = data[claims.Offset:]
+	if claims.Length > 0 {
+		data = data[:claims.Length]
+	}
+	conn.Write([]byte{1})
+	binary.Write(conn, binary.BigEndian, uint64(len(data)))
+	conn.Write(data)
+}
+
+// serveWrite applies one write, conditional or not, and commits the chunk's new
+// size to the master
+func (s *fakeChunkserver) serveWrite(conn net.Conn, token string) {
+	var claims csstructs.DownloadRequestClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil || s.master == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes++
+	if s.failWrites {
+		return
+	}
+	data := s.fragments[claims.ChunkHandle]
+	length := uint64(len(data))
+	if claims.IfOffset != nil && *claims.IfOffset != length {
+		binary.Write(conn, binary.BigEndian, csstructs.OffsetMismatch)
+		return
+	}
+	offset := length
+	if claims.Offset >= 0 {
+		offset = uint64(claims.Offset)
+	}
+	binary.Write(conn, binary.BigEndian, offset)
+	buf := make([]byte, claims.Filesize)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	if s.failAccepted > 0 {
+		// The primary took the data but the client never hears whether it was written
+		s.failAccepted--
+		return
+	}
+	// Copy on write: reads in flight keep slices of the old data
+	data = append([]byte(nil), data...)
+	if end := offset + claims.Filesize; end > length {
+		data = append(data, make([]byte, end-length)...)
+	}
+	copy(data[offset:], buf)
+	s.fragments[claims.ChunkHandle] = data
+	if err := s.master.ConfirmChunkCommit("cs1", master.ChunkHandle(claims.ChunkHandle), uint64(len(data)), 1); err != nil {
+		conn.Write([]byte{0})
+		return
+	}
+	conn.Write([]byte{1})
+}
+
+// readCount returns how many read requests s has served
+func (s *fakeChunkserver) readCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads
+}
+
+func (s *fakeChunkserver) location() *pb.ChunkServerInfo {
+	addr := s.lis.Addr().(*net.TCPAddr)
+	return &pb.ChunkServerInfo{ServerId: "fake", Hostname: addr.IP.String(), DataPort: int32(addr.Port)}
+}
+
+// encodeTestChunk erasure codes size bytes of random data onto the server,
+// leaving out the dropped fragments
+func encodeTestChunk(t *testing.T, s *fakeChunkserver, size int, dropped []int) ([]byte, *pb.ChunkLocationInfo) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+
+	enc, err := erasure.NewCoder(erasure.DataShards, erasure.ParityShards)
+	if err != nil {
+		t.Fatalf("NewCoder: %v", err)
+	}
+	shardSize := erasure.ShardSize(uint64(size), erasure.DataShards)
+	shards := erasure.Split(data, erasure.DataShards, erasure.ParityShards, shardSize)
+	if err := enc.Encode(shards); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	chunk := &pb.ChunkLocationInfo{
+		ChunkHandle: fmt.Sprintf("chunk-%v", dropped),
+		Size:        uint64(size),
+		Stripe: &pb.ChunkStripe{
+			DataShards:   erasure.DataShards,
+			ParityShards: erasure.ParityShards,
+			ShardSize:    shardSize,
+		},
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, shard := range shards {
+		handle := erasure.FragmentHandle(chunk.ChunkHandle, i)
+		chunk.Stripe.Fragments = append(chunk.Stripe.Fragments, &pb.StripeFragment{
+			Index:     uint32(i),
+			Handle:    handle,
+			Locations: []*pb.ChunkServerInfo{s.location()},
+		})
+		s.fragments[handle] = shard
+	}
+	for _, i := range dropped {
+		delete(s.fragments, erasure.FragmentHandle(chunk.ChunkHandle, i))
+	}
+	return data, chunk
+}
+
+func newStripeTestClient() *Client {
+	return &Client{
+		secretProvider: func(*jwt.Token) (any, error) { return []byte("secret"), nil },
+	}
+}
+
+// TestReadStripeRangeDegraded reads ranges of a chunk with up to two of its six
+// fragments lost, including ranges that span fragments and rebuild both lost data fragments
+func TestReadStripeRangeDegraded(t *testing.T) {
+	const size = 4099 // Pads the last data fragment
+	s := startFakeChunkserver(t)
+	c := newStripeTestClient()
+	shardSize := int64(erasure.ShardSize(size, erasure.DataShards))
+
+	ranges := []struct{ offset, length int64 }{
+		{0, 0}, // Whole chunk
+		{0, 1},
+		{shardSize - 3, 7}, // Across the first fragment boundary
+		{shardSize + 10, 2*shardSize + 5},
+		{size - 5, 0}, // Tail within the padded fragment
+		{123, 3000},
+	}
+	for _, dropped := range [][]int{nil, {0}, {3}, {4}, {1, 2}, {0, 3}, {2, 5}, {4, 5}} {
+		data, chunk := encodeTestChunk(t, s, size, dropped)
+		for _, r := range ranges {
+			t.Run(fmt.Sprintf("drop %v at %d+%d", dropped, r.offset, r.length), func(t *testing.T) {
+				want := data[r.offset:]
+				if r.length > 0 {
+					want = want[:r.length]
+				}
+				var buf bytes.Buffer
+				n, err := c.readStripeRange(context.Background(), chunk, r.offset, r.length, &buf)
+				if err != nil {
+					t.Fatalf("readStripeRange: %v", err)
+				}
+				if n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("read %d bytes not matching the chunk, want %d", n, len(want))
+				}
+			})
+		}
+	}
+}
+
+func TestReconstructFragmentRange(t *testing.T) {
+	const size = 4096
+	s := startFakeChunkserver(t)
+	c := newStripeTestClient()
+	shardSize := int64(erasure.ShardSize(size, erasure.DataShards))
+
+	tests := []struct {
+		dropped        []int
+		missing        int
+		offset, length int64
+	}{
+		{dropped: []int{0}, missing: 0, offset: 0, length: shardSize},
+		{dropped: []int{2}, missing: 2, offset: 5, length: 1},
+		// The first wave reads the other lost fragment and tops up from parity
+		{dropped: []int{0, 1}, missing: 1, offset: 100, length: 200},
+		{dropped: []int{3, 4}, missing: 3, offset: shardSize - 9, length: 9},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("drop %v rebuild %d", tt.dropped, tt.missing), func(t *testing.T) {
+			data, chunk := encodeTestChunk(t, s, size, tt.dropped)
+			got, err := c.reconstructFragmentRange(context.Background(), chunk, tt.missing, tt.offset, tt.length)
+			if err != nil {
+				t.Fatalf("reconstructFragmentRange: %v", err)
+			}
+			start := int64(tt.missing)*shardSize + tt.offset
+			if !bytes.Equal(got, data[start:start+tt.length]) {
+				t.Errorf("rebuilt range doesn't match chunk bytes %d-%d", start, start+tt.length)
+			}
+		})
+	}
+
+	// With three fragments lost, too few are left to rebuild from
+	_, chunk := encodeTestChunk(t, s, size, []int{0, 1, 5})
+	if _, err := c.reconstructFragmentRange(context.Background(), chunk, 0, 0, 10); err == nil {
+		t.Error("rebuilt a fragment with 3 of 6 lost")
+	}
+	var buf bytes.Buffer
+	if _, err := c.readStripeRange(context.Background(), chunk, 0, 10, &buf); err == nil || buf.Len() != 0 {
+		t.Errorf("readStripeRange with 3 of 6 lost = %d bytes, %v; want an error and no data", buf.Len(), err)
+	}
+}
@@ -0,0 +1,101 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// Transaction is a batch of file operations in one namespace that the master
+// applies atomically: either every operation takes effect or none does. Build it
+// with the condition and operation methods, then pass it to Client.Transact.
+type Transaction struct {
+	Namespace  string
+	conditions []*pb.TransactCondition
+	ops        []*pb.TransactOp
+}
+
+// IfExists requires path to exist when the transaction applies.
+func (t *Transaction) IfExists(path string) *Transaction {
+	t.conditions = append(t.conditions, &pb.TransactCondition{Path: path})
+	return t
+}
+
+// IfAbsent requires path not to exist when the transaction applies.
+func (t *Transaction) IfAbsent(path string) *Transaction {
+	t.conditions = append(t.conditions, &pb.TransactCondition{Path: path, Absent: true})
+	return t
+}
+
+// IfSize requires path to exist and be exactly size bytes when the transaction applies.
+func (t *Transaction) IfSize(path string, size uint64) *Transaction {
+	t.conditions = append(t.conditions, &pb.TransactCondition{Path: path, CheckSize: true, Size: size})
+	return t
+}
+
+// Create adds an empty file with attributes, failing if path exists. A client
+// with encryption gives the file a new data key unless attributes already hold one.
+func (t *Transaction) Create(path string, attributes map[string]string) *Transaction {
+	t.ops = append(t.ops, &pb.TransactOp{Type: "create", Path: path, Attributes: attributes})
+	return t
+}
+
+// Delete removes a file, moving it to the trash while the master keeps one.
+func (t *Transaction) Delete(path string) *Transaction {
+	t.ops = append(t.ops, &pb.TransactOp{Type: "delete", Path: path})
+	return t
+}
+
+// Rename moves a file to newPath. With replace, a file already at newPath is
+// deleted; otherwise the transaction fails if one exists.
+func (t *Transaction) Rename(path, newPath string, replace bool) *Transaction {
+	t.ops = append(t.ops, &pb.TransactOp{Type: "rename", Path: path, NewPath: newPath, Replace: replace})
+	return t
+}
+
+// SetAttributes adds or overwrites the attributes in set on a file, then deletes the keys in remove.
+func (t *Transaction) SetAttributes(path string, set map[string]string, remove ...string) *Transaction {
+	t.ops = append(t.ops, &pb.TransactOp{Type: "set_attributes", Path: path, Attributes: set, Remove: remove})
+	return t
+}
+
+// Transact checks the transaction's conditions, then applies its operations in
+// order as a single change; later operations see the files as earlier ones left
+// them. A condition that doesn't hold, or an operation that would overwrite a file,
+// fails it with an error matching ErrPreconditionFailed and nothing is changed.
+func (c *Client) Transact(ctx context.Context, tx *Transaction) error {
+	namespace := normalizeNamespace(tx.Namespace)
+	conditions, ops, err := c.encryptTransaction(ctx, namespace, tx)
+	if err != nil {
+		return err
+	}
+	resp, err := c.master.Transact(ctx, &pb.TransactRequest{
+		Namespace:  namespace,
+		Conditions: conditions,
+		Ops:        ops,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.QuotaExceeded {
+		return fmt.Errorf("transaction failed: %w", &quotaError{resp.Message})
+	}
+	if resp.PreconditionFailed {
+		return fmt.Errorf("transaction failed: %w", &preconditionError{resp.Message})
+	}
+	if !resp.Success {
+		return fmt.Errorf("transaction failed: %s", resp.Message)
+	}
+
+	for _, op := range tx.ops {
+		c.invalidateChunkCache(op.Path, namespace)
+		if op.Type == "delete" || op.Type == "rename" {
+			c.forgetFile(op.Path, namespace)
+		}
+		if op.NewPath != "" {
+			c.invalidateChunkCache(op.NewPath, namespace)
+		}
+	}
+	return nil
+}
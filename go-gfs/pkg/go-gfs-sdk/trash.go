@@ -0,0 +1,63 @@
+package gfs
+
+import (
+	"context"
+	"fmt"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+)
+
+// ListTrash returns the deleted files that can still be restored from a namespace,
+// most recently deleted first. An empty namespace lists the trash of every namespace.
+func (c *Client) ListTrash(ctx context.Context, namespace string) ([]*pb.TrashEntry, error) {
+	resp, err := c.master.ListTrash(ctx, &pb.ListTrashRequest{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// RestoreFile moves a deleted file out of the trash to the path and namespace it was deleted from.
+func (c *Client) RestoreFile(ctx context.Context, trashID string) (*pb.FileInfoResponse, error) {
+	return c.RestoreFileTo(ctx, trashID, "", "")
+}
+
+// RestoreFileTo moves a deleted file out of the trash to path in namespace.
+// An empty path or namespace keeps the one the file was deleted from; the destination must not exist.
+func (c *Client) RestoreFileTo(ctx context.Context, trashID, path, namespace string) (*pb.FileInfoResponse, error) {
+	resp, err := c.master.RestoreFile(ctx, &pb.RestoreFileRequest{
+		TrashId:   trashID,
+		Path:      path,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.QuotaExceeded {
+		return nil, fmt.Errorf("restore file failed: %w", &quotaError{resp.Message})
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("restore file failed: %s", resp.Message)
+	}
+	c.invalidateChunkCache(resp.File.Path, resp.File.Namespace)
+	return resp.File, nil
+}
+
+// PurgeTrash permanently deletes files from the trash and releases their chunks.
+// With no trashIDs it purges everything deleted from namespace, or the whole trash
+// when namespace is also empty. Returns the number of files purged.
+func (c *Client) PurgeTrash(ctx context.Context, namespace string, trashIDs ...string) (int, error) {
+	resp, err := c.master.PurgeTrash(ctx, &pb.PurgeTrashRequest{
+		TrashIds:  trashIDs,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !resp.Success {
+		return 0, fmt.Errorf("purge trash failed: %s", resp.Message)
+	}
+	return int(resp.FilesPurged), nil
+}
@@ -0,0 +1,142 @@
+package gfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	pb "eddisonso.com/go-gfs/gen/master"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EventType is the kind of change reported by WatchNamespace.
+type EventType string
+
+// Event types reported by WatchNamespace.
+const (
+	// EventCreate reports a file created, restored from the trash or snapshotted into place.
+	EventCreate EventType = "CREATE"
+	// EventCommit reports data committed to one of the file's chunks.
+	EventCommit EventType = "COMMIT"
+	// EventRename reports a file moved from Path to NewPath.
+	EventRename EventType = "RENAME"
+	// EventDelete reports a file deleted or moved to the trash.
+	EventDelete EventType = "DELETE"
+	// EventAttributes reports a change to a file's attributes.
+	EventAttributes EventType = "ATTRIBUTES"
+	// EventBookmark carries no change, only a newer resume token.
+	EventBookmark EventType = "BOOKMARK"
+)
+
+// Retry delays for reopening a watch after the master goes away.
+const (
+	watchRetryMin = 250 * time.Millisecond
+	watchRetryMax = 10 * time.Second
+)
+
+// Event is a change to the files of a watched namespace.
+type Event struct {
+	Type      EventType
+	Namespace string
+	// Path is the file that changed. It is empty when the change covers the
+	// whole namespace, such as a namespace delete or snapshot; list it again.
+	Path string
+	// NewPath is where a renamed file moved to.
+	NewPath string
+	// ChunkHandle and ChunkSize describe the chunk a commit wrote to.
+	ChunkHandle string
+	ChunkSize   uint64
+	// ResumeToken restarts a watch right after this event.
+	ResumeToken uint64
+}
+
+// WatchOptions selects the changes WatchNamespace reports.
+type WatchOptions struct {
+	Namespace string
+	// Prefix limits events to paths starting with it. Renames match on either path.
+	Prefix string
+	// ResumeToken continues a previous watch after the event it came from.
+	// Zero starts from now.
+	ResumeToken uint64
+	// Bookmarks also yields EventBookmark events, which keep a saved resume token
+	// current while no matching changes happen.
+	Bookmarks bool
+}
+
+// WatchNamespace iterates over changes to files in a namespace as they happen.
+// When the connection to the master drops, the watch reopens from the last event
+// seen, so no changes are missed. If the master no longer has the changes since
+// the resume token, it yields an error matching ErrWatchExpired; list the
+// namespace again and start a new watch.
+// Iteration stops at the first error or when ctx is done.
+func (c *Client) WatchNamespace(ctx context.Context, opts WatchOptions) iter.Seq2[*Event, error] {
+	return func(yield func(*Event, error) bool) {
+		token := opts.ResumeToken
+		delay := watchRetryMin
+		for {
+			stream, err := c.master.WatchNamespace(ctx, &pb.WatchNamespaceRequest{
+				Namespace:   normalizeNamespace(opts.Namespace),
+				Prefix:      opts.Prefix,
+				ResumeToken: token,
+			})
+			for err == nil {
+				var msg *pb.NamespaceEvent
+				msg, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				delay = watchRetryMin
+				token = msg.ResumeToken
+
+				event := eventFromProto(msg)
+				if event.Type == EventBookmark && !opts.Bookmarks {
+					continue
+				}
+				if !yield(event, nil) {
+					return
+				}
+			}
+
+			if ctx.Err() != nil {
+				yield(nil, ctx.Err())
+				return
+			}
+			switch status.Code(err) {
+			case codes.OutOfRange:
+				yield(nil, fmt.Errorf("%w: %s", ErrWatchExpired, status.Convert(err).Message()))
+				return
+			case codes.Unavailable:
+			default:
+				if !errors.Is(err, io.EOF) {
+					yield(nil, fmt.Errorf("watch namespace failed: %w", err))
+					return
+				}
+			}
+
+			// The master restarted or lost leadership; reopen where we left off
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, watchRetryMax)
+		}
+	}
+}
+
+func eventFromProto(msg *pb.NamespaceEvent) *Event {
+	return &Event{
+		Type:        EventType(msg.Type),
+		Namespace:   msg.Namespace,
+		Path:        msg.Path,
+		NewPath:     msg.NewPath,
+		ChunkHandle: msg.ChunkHandle,
+		ChunkSize:   msg.ChunkSize,
+		ResumeToken: msg.ResumeToken,
+	}
+}
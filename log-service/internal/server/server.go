@@ -430,13 +430,13 @@ func (s *LogServer) FetchDayLogs(ctx context.Context, date string) ([]*pb.LogEnt
 	}
 
 	prefix := "/" + date + "/"
-	files, err := s.gfsClient.ListFilesWithNamespace(ctx, namespace, prefix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list log files for %s: %w", date, err)
-	}
+	opts := gfs.ListOptions{Namespace: namespace, Prefix: prefix}
 
 	var all []*pb.LogEntry
-	for _, f := range files {
+	for f, err := range s.gfsClient.IterFiles(ctx, opts) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list log files for %s: %w", date, err)
+		}
 		data, err := s.gfsClient.ReadWithNamespace(ctx, f.Path, namespace)
 		if err != nil {
 			slog.Warn("failed to read log file for download", "path", f.Path, "error", err)
@@ -5,12 +5,14 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 
+	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
 	"github.com/google/uuid"
 )
 
@@ -320,7 +322,7 @@ func (s *server) completeMonolithicUpload(w http.ResponseWriter, r *http.Request
 
 	finalPath := blobGFSPath(digest)
 	if err := s.gfs.RenameFileWithNamespace(r.Context(), gfsPath, finalPath, gfsNamespace); err != nil {
-		if strings.Contains(err.Error(), "already exists") {
+		if errors.Is(err, gfs.ErrPreconditionFailed) {
 			// Concurrent dedup: another upload finished first, clean up ours
 			_ = s.gfs.DeleteFileWithNamespace(r.Context(), gfsPath, gfsNamespace)
 		} else {
@@ -501,7 +503,7 @@ func (s *server) handleUploadComplete(w http.ResponseWriter, r *http.Request) {
 
 	finalPath := blobGFSPath(digest)
 	if err := s.gfs.RenameFileWithNamespace(r.Context(), gfsPath, finalPath, gfsNamespace); err != nil {
-		if strings.Contains(err.Error(), "already exists") {
+		if errors.Is(err, gfs.ErrPreconditionFailed) {
 			_ = s.gfs.DeleteFileWithNamespace(r.Context(), gfsPath, gfsNamespace)
 		} else {
 			slog.Error("handleUploadComplete: RenameFileWithNamespace", "err", err)
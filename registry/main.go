@@ -31,6 +31,7 @@ type server struct {
 func main() {
 	addr := flag.String("addr", "0.0.0.0:8080", "listen address")
 	master := flag.String("master", "gfs-master:9000", "GFS master address")
+	encryptionKeys := flag.String("encryption-key", "", "comma-separated key files to encrypt blobs with; the first wraps new blobs, the rest only decrypt older ones")
 	flag.Parse()
 
 	dbURL := os.Getenv("DATABASE_URL")
@@ -54,10 +55,18 @@ func main() {
 		log.Fatalf("failed to run migrations: %v", err)
 	}
 
+	opts := []gfs.Option{gfs.WithConnectionPool(8, 60*time.Second)}
+	if *encryptionKeys != "" {
+		keys := strings.Split(*encryptionKeys, ",")
+		provider, err := gfs.NewLocalKeyProvider(keys[0], keys[1:]...)
+		if err != nil {
+			log.Fatalf("failed to load encryption keys: %v", err)
+		}
+		opts = append(opts, gfs.WithEncryption(provider))
+	}
+
 	ctx := context.Background()
-	gfsClient, err := gfs.New(ctx, *master,
-		gfs.WithConnectionPool(8, 60*time.Second),
-	)
+	gfsClient, err := gfs.New(ctx, *master, opts...)
 	if err != nil {
 		log.Fatalf("failed to connect to gfs master: %v", err)
 	}
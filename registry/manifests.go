@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -15,6 +16,8 @@ import (
 	"strings"
 
 	"eddisonso.com/edd-cloud/pkg/auditlog"
+	gfs "eddisonso.com/go-gfs/pkg/go-gfs-sdk"
+	"github.com/google/uuid"
 )
 
 // ociManifest is used to decode and validate OCI/Docker image manifests.
@@ -248,21 +251,32 @@ func (s *server) handleManifestPut(w http.ResponseWriter, r *http.Request) {
 		mediaType = "application/vnd.docker.distribution.manifest.v2+json"
 	}
 
-	// Store manifest in GFS (skip if it already exists)
+	// Store manifest in GFS (skip if it already exists). It is written under a
+	// temporary name and renamed into place, so readers never see part of it
 	gfsPath := manifestGFSPath(repoName, digest)
 	if _, err := s.gfs.GetFileWithNamespace(r.Context(), gfsPath, gfsNamespace); err != nil {
-		// File doesn't exist; create and write it
-		if _, err := s.gfs.CreateFileWithNamespace(r.Context(), gfsPath, gfsNamespace); err != nil {
+		tmpPath := uploadGFSPath(uuid.New().String())
+		if _, err := s.gfs.CreateFileWithNamespace(r.Context(), tmpPath, gfsNamespace); err != nil {
 			slog.Error("handleManifestPut: CreateFileWithNamespace", "err", err)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
-		if _, err := s.gfs.AppendFromWithNamespace(r.Context(), gfsPath, gfsNamespace,
+		if _, err := s.gfs.AppendFromWithNamespace(r.Context(), tmpPath, gfsNamespace,
 			bytes.NewReader(body)); err != nil {
+			_ = s.gfs.DeleteFileWithNamespace(r.Context(), tmpPath, gfsNamespace)
 			slog.Error("handleManifestPut: AppendFromWithNamespace", "err", err)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+		if err := s.gfs.RenameFileWithNamespace(r.Context(), tmpPath, gfsPath, gfsNamespace); err != nil {
+			// A concurrent push of the same manifest published it first
+			_ = s.gfs.DeleteFileWithNamespace(r.Context(), tmpPath, gfsNamespace)
+			if !errors.Is(err, gfs.ErrPreconditionFailed) {
+				slog.Error("handleManifestPut: RenameFileWithNamespace", "err", err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
 	}
 
 	// Upsert manifest record in DB
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -152,6 +153,17 @@ func getJWTSecret() []byte {
 	return []byte(secret)
 }
 
+// logSlowOperation logs GFS operations slow enough to be noticed by users,
+// with the chunkservers they went to.
+func logSlowOperation(op gfs.Operation) {
+	if op.Duration < time.Second {
+		return
+	}
+	slog.Warn("slow gfs operation", "op", op.Name, "chunk", op.Chunk, "server", op.Server,
+		"replicas", op.Replicas, "hedged", op.Hedged, "attempts", op.Attempts,
+		"duration", op.Duration, "error", op.Err)
+}
+
 func main() {
 	addr := flag.String("addr", ":8080", "HTTP listen address")
 	master := flag.String("master", "127.0.0.1:50051", "GFS master gRPC address")
@@ -163,6 +175,8 @@ func main() {
 	sessionTTL := flag.Duration("session-ttl", 24*time.Hour, "session lifetime")
 	logServiceAddr := flag.String("log-service", "", "Log service address (e.g., log-service:50051)")
 	logSource := flag.String("log-source", "edd-storage", "Log source name (e.g., pod name)")
+	hedgeDelay := flag.Duration("hedge-delay", 50*time.Millisecond, "read chunks from a second replica when the first is slower than this (0 = off)")
+	encryptionKeys := flag.String("encryption-key", "", "comma-separated key files to encrypt uploads with; the first wraps new files, the rest only decrypt older ones")
 	flag.Parse()
 
 	// Initialize logger
@@ -181,11 +195,24 @@ func main() {
 		log.Fatal("prefix cannot be root")
 	}
 
-	ctx := context.Background()
-	client, err := gfs.New(ctx, *master,
+	opts := []gfs.Option{
 		// Enable connection pooling to chunkservers for better throughput
 		gfs.WithConnectionPool(8, 60*time.Second),
-	)
+		// Don't let one busy chunkserver stall downloads
+		gfs.WithHedgedReads(*hedgeDelay),
+		gfs.WithMetrics(logSlowOperation),
+	}
+	if *encryptionKeys != "" {
+		keys := strings.Split(*encryptionKeys, ",")
+		provider, err := gfs.NewLocalKeyProvider(keys[0], keys[1:]...)
+		if err != nil {
+			log.Fatalf("failed to load encryption keys: %v", err)
+		}
+		opts = append(opts, gfs.WithEncryption(provider))
+	}
+
+	ctx := context.Background()
+	client, err := gfs.New(ctx, *master, opts...)
 	if err != nil {
 		log.Fatalf("failed to connect to gfs master: %v", err)
 	}
@@ -916,6 +943,7 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var file io.Reader
+	var declaredType string
 	for {
 		part, err := mr.NextPart()
 		if err == io.EOF {
@@ -937,6 +965,7 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		}
 		name = filename
 		file = part
+		declaredType = part.Header.Get("Content-Type")
 		break
 	}
 
@@ -989,9 +1018,9 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		slog.Debug("upload overwrite", "namespace", namespace, "name", name, "transfer", transferID)
 	}
 
-	// Create new file
-	if _, err := s.client.CreateFileWithNamespace(ctx, fullPath, s.gfsNamespace(namespace)); err != nil {
-		fail(fmt.Sprintf("prepare file failed: %v", err), http.StatusBadGateway)
+	// Create new file, keeping the declared content type for downloads
+	if _, err := s.client.CreateFileWithAttributes(ctx, fullPath, s.gfsNamespace(namespace), contentTypeAttributes(declaredType)); err != nil {
+		fail(fmt.Sprintf("prepare file failed: %v", err), gfsWriteStatus(err))
 		return
 	}
 
@@ -1023,7 +1052,7 @@ func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
 			transferID,
 			err,
 		)
-		fail(fmt.Sprintf("upload failed: %v", err), http.StatusBadGateway)
+		fail(fmt.Sprintf("upload failed: %v", err), gfsWriteStatus(err))
 		return
 	}
 	reporter.Done()
@@ -1146,13 +1175,7 @@ func (s *server) handleFileGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Detect content type from extension
-	ext := filepath.Ext(file)
-	contentType := mime.TypeByExtension(ext)
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Type", s.fileContentType(ctx, file, namespace))
 	if fileSize > 0 {
 		w.Header().Set("Content-Length", strconv.FormatUint(fileSize, 10))
 	}
@@ -1206,12 +1229,7 @@ func (s *server) handleFileDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ext := filepath.Ext(file)
-	contentType := mime.TypeByExtension(ext)
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Type", s.fileContentType(ctx, file, namespace))
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(file)))
 	if fileSize > 0 {
 		w.Header().Set("Content-Length", strconv.FormatUint(fileSize, 10))
@@ -1389,16 +1407,22 @@ func (s *server) handleFilePost(w http.ResponseWriter, r *http.Request) {
 		slog.Debug("upload overwrite", "namespace", namespace, "name", name, "transfer", transferID)
 	}
 
-	// Create new file
-	if _, err := s.client.CreateFileWithNamespace(ctx, name, s.gfsNamespace(namespace)); err != nil {
-		http.Error(w, fmt.Sprintf("prepare file failed: %v", err), http.StatusBadGateway)
+	// Create new file, keeping the declared content type of a raw body for downloads
+	contentType := r.Header.Get("Content-Type")
+	multipart := strings.HasPrefix(contentType, "multipart/")
+	var attributes map[string]string
+	if !multipart {
+		attributes = contentTypeAttributes(contentType)
+	}
+	if _, err := s.client.CreateFileWithAttributes(ctx, name, s.gfsNamespace(namespace), attributes); err != nil {
+		http.Error(w, fmt.Sprintf("prepare file failed: %v", err), gfsWriteStatus(err))
 		return
 	}
 
 	// Read body directly (or multipart)
 	var body io.Reader
-	contentType := r.Header.Get("Content-Type")
-	if strings.HasPrefix(contentType, "multipart/") {
+	var partType string
+	if multipart {
 		mr, err := r.MultipartReader()
 		if err != nil {
 			http.Error(w, "invalid multipart upload", http.StatusBadRequest)
@@ -1415,6 +1439,7 @@ func (s *server) handleFilePost(w http.ResponseWriter, r *http.Request) {
 			}
 			if part.FormName() == "file" {
 				body = part
+				partType = part.Header.Get("Content-Type")
 				break
 			}
 			part.Close()
@@ -1436,13 +1461,20 @@ func (s *server) handleFilePost(w http.ResponseWriter, r *http.Request) {
 	counting := &countingReader{reader: body, reporter: reporter}
 	if _, err := s.client.AppendFromWithNamespace(ctx, name, s.gfsNamespace(namespace), counting); err != nil {
 		reporter.Error(err)
-		http.Error(w, fmt.Sprintf("upload failed: %v", err), http.StatusBadGateway)
+		http.Error(w, fmt.Sprintf("upload failed: %v", err), gfsWriteStatus(err))
 		return
 	}
 
 	reporter.Done()
 	log.Printf("upload ok namespace=%s name=%s size=%d transfer=%s", namespace, name, total, transferID)
 
+	// A multipart part's type is only known once the file exists
+	if attributes := contentTypeAttributes(partType); attributes != nil {
+		if _, err := s.client.SetFileAttributesWithNamespace(ctx, name, s.gfsNamespace(namespace), attributes); err != nil {
+			slog.Warn("failed to record content type", "namespace", namespace, "name", name, "error", err)
+		}
+	}
+
 	if s.notifier != nil {
 		if uid, ok := s.currentUserID(r); ok {
 			action := "uploaded"
@@ -1482,24 +1514,40 @@ func (s *server) handleStorageStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Count alive chunkservers
-	aliveCount := 0
+	type statusResponse struct {
+		ChunkserverCount int    `json:"chunkserver_count"`
+		TotalServers     int    `json:"total_servers"`
+		CapacityBytes    uint64 `json:"capacity_bytes"`
+		FreeBytes        uint64 `json:"free_bytes"`
+		UsedBytes        uint64  `json:"used_bytes"`
+		LogicalBytes     uint64  `json:"logical_bytes"`
+		CompressionRatio float64 `json:"compression_ratio"`
+		OpenConnections  int64   `json:"open_connections"`
+		ReadBytesPerSec  uint64  `json:"read_bytes_per_sec"`
+		WriteBytesPerSec uint64  `json:"write_bytes_per_sec"`
+	}
+
+	// Count alive chunkservers and total their capacity and load
+	resp := statusResponse{TotalServers: len(servers)}
 	for _, srv := range servers {
-		if srv.IsAlive {
-			aliveCount++
+		if !srv.IsAlive {
+			continue
+		}
+		resp.ChunkserverCount++
+		resp.CapacityBytes += srv.CapacityBytes
+		resp.FreeBytes += srv.FreeBytes
+		resp.UsedBytes += srv.UsedBytes
+		resp.LogicalBytes += srv.LogicalBytes
+		if load := srv.Load; load != nil {
+			resp.OpenConnections += load.OpenConnections
+			resp.ReadBytesPerSec += load.ReadBytesPerSec
+			resp.WriteBytesPerSec += load.WriteBytesPerSec
 		}
 	}
-
-	type statusResponse struct {
-		ChunkserverCount int `json:"chunkserver_count"`
-		TotalServers     int `json:"total_servers"`
-	}
+	resp.CompressionRatio = gfs.CompressionRatio(servers)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(statusResponse{
-		ChunkserverCount: aliveCount,
-		TotalServers:     len(servers),
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 var adminUsername = os.Getenv("ADMIN_USERNAME")
@@ -1947,6 +1995,39 @@ func (s *server) countNamespaceFiles(ctx context.Context, namespace string) (int
 	return count, nil
 }
 
+// contentTypeAttributes returns the file attributes recording an upload's declared
+// content type, or nil when it is missing or too generic to beat the extension
+func contentTypeAttributes(declared string) map[string]string {
+	mediaType, params, err := mime.ParseMediaType(declared)
+	if err != nil || mediaType == "application/octet-stream" || strings.HasPrefix(mediaType, "multipart/") {
+		return nil
+	}
+	return map[string]string{gfs.AttrContentType: mime.FormatMediaType(mediaType, params)}
+}
+
+// fileContentType returns the content type recorded when a file was uploaded,
+// falling back to a guess from its extension
+func (s *server) fileContentType(ctx context.Context, file, namespace string) string {
+	if info, err := s.client.GetFileWithNamespace(ctx, file, s.gfsNamespace(namespace)); err == nil {
+		if contentType := info.Attributes[gfs.AttrContentType]; contentType != "" {
+			return contentType
+		}
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(file)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// gfsWriteStatus maps a GFS write error to an HTTP status; a full namespace
+// quota is the caller's problem, not a gateway failure
+func gfsWriteStatus(err error) int {
+	if errors.Is(err, gfs.ErrQuotaExceeded) {
+		return http.StatusInsufficientStorage
+	}
+	return http.StatusBadGateway
+}
+
 func relativeNameWithPrefix(fullPath, prefix string) string {
 	if prefix == "" {
 		return strings.TrimPrefix(fullPath, "/")